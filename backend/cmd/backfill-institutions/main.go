@@ -0,0 +1,36 @@
+// Command backfill-institutions maps the free-text institution of existing
+// investments onto canonical institutions. It runs as a dry run unless
+// -apply is given and prints the report as JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"tgfinance/internal/institution"
+	"tgfinance/pkg/database"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "write the mappings instead of only reporting them")
+	flag.Parse()
+
+	db, err := database.Connect(database.NewConfig())
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	report, err := institution.Backfill(context.Background(), institution.NewPostgresStore(db.DB), !*apply)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	}
+	if err != nil {
+		log.Fatalf("backfill failed: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package activity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+// Page sizes of the activity feed
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// batchSize is the number of events read from the store at a time
+const batchSize = 200
+
+// ErrInvalidCursor is returned for cursors not issued by the feed
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in the feed: the time and key of the last event
+// included in a page
+type Cursor struct {
+	At  time.Time `json:"t"`
+	Key string    `json:"k"`
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode
+func DecodeCursor(raw string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Key == "" || cursor.At.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Query selects events from the store
+type Query struct {
+	// Before excludes events at or after the cursor position
+	Before *Cursor
+	// Types restricts the events to these feed item types
+	Types []string
+	Limit int
+}
+
+// Store reads activity events from the tables they are recorded in
+type Store interface {
+	// Events returns the user's events ordered newest first, by time and
+	// then key descending
+	Events(ctx context.Context, userID uuid.UUID, query Query) ([]models.ActivityEvent, error)
+}
+
+// Service builds the activity feed at query time from the events in the store
+type Service struct {
+	store Store
+}
+
+// NewService creates a new activity feed service
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// Feed returns a page of the user's activity, newest first. Consecutive
+// events of one burst, such as the expenses created by one import, are
+// collapsed into a single item. An empty types slice includes every type.
+func (s *Service) Feed(ctx context.Context, userID uuid.UUID, cursor string, types []string, limit int) (*models.ActivityFeed, error) {
+	if errs := ValidateTypes(types); errs.HasErrors() {
+		return nil, errs
+	}
+	if len(types) == 0 {
+		types = models.ActivityTypes.Strings()
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	query := Query{Types: types, Limit: batchSize}
+	if cursor != "" {
+		before, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query.Before = before
+	}
+
+	feed := &models.ActivityFeed{Items: []models.ActivityItem{}}
+	var last *models.ActivityEvent
+	for {
+		events, err := s.store.Events(ctx, userID, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load activity: %w", err)
+		}
+		for i := range events {
+			event := &events[i]
+			if last != nil && sameGroup(last, event) {
+				item := &feed.Items[len(feed.Items)-1]
+				item.Count++
+				item.Title = importTitle(item.Count)
+				last = event
+				continue
+			}
+			if len(feed.Items) == limit {
+				// Another item follows, so the page ends after last
+				next := Cursor{At: last.OccurredAt, Key: last.Key}.Encode()
+				feed.NextCursor = &next
+				return feed, nil
+			}
+			feed.Items = append(feed.Items, Normalize(*event))
+			last = event
+		}
+		if len(events) < query.Limit {
+			return feed, nil
+		}
+		query.Before = &Cursor{At: last.OccurredAt, Key: last.Key}
+	}
+}
+
+// sameGroup returns true if two consecutive events belong to the same burst
+func sameGroup(a, b *models.ActivityEvent) bool {
+	return a.GroupID != nil && b.GroupID != nil && *a.GroupID == *b.GroupID && a.Type == b.Type
+}
+
+// Normalize turns an event into a feed item. Privacy-sensitive events only
+// report that they happened and when.
+func Normalize(event models.ActivityEvent) models.ActivityItem {
+	item := models.ActivityItem{
+		ID:         event.Key,
+		Type:       event.Type,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		OccurredAt: event.OccurredAt,
+	}
+	label := ""
+	if event.Label != nil {
+		label = strings.TrimSpace(*event.Label)
+	}
+
+	switch event.Type {
+	case models.ActivityExpenseAdded:
+		item.Icon = "receipt"
+		item.Title = "Added expense"
+		if label != "" {
+			item.Title += " " + label
+		}
+	case models.ActivityImportCompleted:
+		// Items of an import reference the import rather than one expense
+		item.Icon = "upload"
+		item.Title = importTitle(1)
+		if event.GroupID != nil {
+			item.ID = "import:" + event.GroupID.String()
+			item.EntityType = "import"
+			item.EntityID = event.GroupID
+		}
+		item.Count = 1
+	case models.ActivityGoalMilestone:
+		item.Icon = "flag"
+		item.Title = "Goal reached a milestone"
+		if label != "" && event.Amount != nil {
+			item.Title = fmt.Sprintf("%s reached %.0f%%", label, *event.Amount)
+		}
+	case models.ActivityInvestmentMatured:
+		item.Icon = "trending-up"
+		item.Title = "Investment matured"
+		if label != "" {
+			item.Title = label + " matured"
+		}
+	case models.ActivityNewDeviceLogin:
+		item.Icon = "smartphone"
+		item.Title = "Signed in from a new device"
+		if label != "" {
+			item.Title = "Signed in from " + label
+		}
+	case models.ActivityPasswordChanged:
+		item.Icon = "lock"
+		item.Title = "Password changed"
+		item.EntityType = ""
+		item.EntityID = nil
+	default:
+		item.Icon = "activity"
+		item.Title = label
+	}
+	return item
+}
+
+// importTitle returns the title of an import item covering count expenses
+func importTitle(count int) string {
+	if count == 1 {
+		return "Imported 1 expense"
+	}
+	return fmt.Sprintf("Imported %d expenses", count)
+}
+
+// ValidateTypes validates an activity type filter
+func ValidateTypes(types []string) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	for _, t := range types {
+		if !models.ActivityTypes.Valid(t) {
+			errs.Add("type", models.ActivityTypes.Message("type"))
+			break
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,143 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+type memoryStore struct {
+	events  []models.ActivityEvent
+	queries int
+}
+
+func (s *memoryStore) Events(ctx context.Context, userID uuid.UUID, query Query) ([]models.ActivityEvent, error) {
+	s.queries++
+	sorted := append([]models.ActivityEvent(nil), s.events...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].OccurredAt.Equal(sorted[j].OccurredAt) {
+			return sorted[i].OccurredAt.After(sorted[j].OccurredAt)
+		}
+		return sorted[i].Key > sorted[j].Key
+	})
+
+	allowed := make(map[string]bool)
+	for _, t := range query.Types {
+		allowed[t] = true
+	}
+	var page []models.ActivityEvent
+	for _, event := range sorted {
+		if !allowed[event.Type] {
+			continue
+		}
+		if b := query.Before; b != nil && (event.OccurredAt.After(b.At) ||
+			(event.OccurredAt.Equal(b.At) && event.Key >= b.Key)) {
+			continue
+		}
+		page = append(page, event)
+		if len(page) == query.Limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+func label(s string) *string { return &s }
+
+func testEvents(now time.Time) []models.ActivityEvent {
+	importID := uuid.New()
+	sessionID := uuid.New()
+	events := []models.ActivityEvent{
+		{Key: "audit:1", Type: models.ActivityPasswordChanged, OccurredAt: now, EntityType: "user", EntityID: &sessionID},
+		{Key: "expense:single", Type: models.ActivityExpenseAdded, EntityType: "expense", Label: label("Coffee"), OccurredAt: now.Add(-2 * time.Hour)},
+		{Key: "session:1", Type: models.ActivityNewDeviceLogin, EntityType: "session", EntityID: &sessionID, Label: label("Firefox on Linux"), OccurredAt: now.Add(-3 * time.Hour)},
+	}
+	// An import writes all of its expenses in one transaction
+	for i := 0; i < 300; i++ {
+		events = append(events, models.ActivityEvent{
+			Key:        fmt.Sprintf("expense:import-%03d", i),
+			Type:       models.ActivityImportCompleted,
+			EntityType: "expense",
+			Label:      label("Imported row"),
+			OccurredAt: now.Add(-time.Hour),
+			GroupID:    &importID,
+		})
+	}
+	return events
+}
+
+func TestFeedCollapsesBurstsAndPaginates(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{events: testEvents(now)}
+	service := NewService(store)
+	ctx := context.Background()
+
+	first, err := service.Feed(ctx, uuid.New(), "", nil, 2)
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	if len(first.Items) != 2 || first.NextCursor == nil {
+		t.Fatalf("Expected a full first page with a cursor, got %+v", first)
+	}
+	password, imported := first.Items[0], first.Items[1]
+	if password.Title != "Password changed" || password.EntityID != nil || password.EntityType != "" {
+		t.Errorf("Password change should carry no detail, got %+v", password)
+	}
+	if imported.Title != "Imported 300 expenses" || imported.Count != 300 || imported.EntityType != "import" {
+		t.Errorf("Expected the import to collapse across store batches, got %+v", imported)
+	}
+
+	second, err := service.Feed(ctx, uuid.New(), *first.NextCursor, nil, 2)
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	if len(second.Items) != 2 || second.NextCursor != nil {
+		t.Fatalf("Expected a final page of two items, got %+v", second)
+	}
+	if second.Items[0].Title != "Added expense Coffee" || second.Items[1].Title != "Signed in from Firefox on Linux" {
+		t.Errorf("Unexpected second page %+v", second.Items)
+	}
+}
+
+func TestFeedTypeFilter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(&memoryStore{events: testEvents(now)})
+
+	feed, err := service.Feed(context.Background(), uuid.New(), "", []string{models.ActivityNewDeviceLogin}, 0)
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	if len(feed.Items) != 1 || feed.Items[0].Type != models.ActivityNewDeviceLogin {
+		t.Errorf("Expected only the login, got %+v", feed.Items)
+	}
+
+	var errs utils.ValidationErrors
+	if _, err := service.Feed(context.Background(), uuid.New(), "", []string{"logout"}, 0); !errors.As(err, &errs) {
+		t.Errorf("Expected a validation error for an unknown type, got %v", err)
+	}
+	if _, err := service.Feed(context.Background(), uuid.New(), "not-a-cursor", nil, 0); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestNormalizeGoalMilestone(t *testing.T) {
+	percent := 50.0
+	item := Normalize(models.ActivityEvent{
+		Key:        "goal_milestone:g:50",
+		Type:       models.ActivityGoalMilestone,
+		Label:      label("Emergency fund"),
+		Amount:     &percent,
+		OccurredAt: time.Now(),
+	})
+	if item.Title != "Emergency fund reached 50%" || item.Icon != "flag" {
+		t.Errorf("Unexpected milestone item %+v", item)
+	}
+}
@@ -0,0 +1,60 @@
+package activity
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the activity feed endpoint
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new activity handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetFeed handles GET /api/v1/activity. Types are filtered with a comma
+// separated type parameter and pages are followed with cursor.
+func (h *Handler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	var types []string
+	if raw := query.Get("type"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			types = append(types, strings.TrimSpace(t))
+		}
+	}
+	limit := 0
+	if raw := query.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid limit")
+			return
+		}
+	}
+
+	feed, err := h.service.Feed(r.Context(), userID, query.Get("cursor"), types, limit)
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrInvalidCursor):
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid cursor")
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to load activity")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, feed)
+	}
+}
@@ -0,0 +1,105 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"tgfinance/internal/models"
+)
+
+// feedQuery normalizes the tables behind the feed into one event stream.
+// Goal milestones are derived from running contribution totals, and a login
+// counts as a new device when the user never signed in with that user agent
+// before.
+const feedQuery = `
+SELECT key, type, entity_type, entity_id, label, amount, occurred_at, group_id FROM (
+	SELECT 'expense:' || e.id AS key,
+		CASE WHEN e.import_id IS NULL THEN 'expense_added' ELSE 'import_completed' END AS type,
+		'expense' AS entity_type, e.id AS entity_id, e.description AS label,
+		e.amount::float8 AS amount, e.created_at AS occurred_at, e.import_id AS group_id
+	FROM expenses e
+	WHERE e.user_id = $1
+
+	UNION ALL
+	SELECT 'goal_milestone:' || m.goal_id || ':' || m.threshold, 'goal_milestone', 'goal',
+		m.goal_id, m.name, m.threshold::float8, m.reached_at, NULL
+	FROM (
+		SELECT c.goal_id, c.name, t.threshold, MIN(c.created_at) AS reached_at
+		FROM (
+			SELECT gc.goal_id, g.name, g.target_amount, gc.created_at,
+				SUM(gc.amount) OVER (PARTITION BY gc.goal_id ORDER BY gc.created_at, gc.id) AS running
+			FROM goal_contributions gc
+			JOIN financial_goals g ON g.id = gc.goal_id
+			WHERE g.user_id = $1 AND g.target_amount > 0
+		) c
+		CROSS JOIN (VALUES (25), (50), (75), (100)) AS t(threshold)
+		WHERE c.running * 100 >= c.target_amount * t.threshold
+		GROUP BY c.goal_id, c.name, t.threshold
+	) m
+
+	UNION ALL
+	SELECT 'investment:' || i.id, 'investment_matured', 'investment', i.id, i.name, NULL,
+		i.end_date::timestamptz, NULL
+	FROM investments i
+	WHERE i.user_id = $1 AND i.status = 'matured' AND i.end_date IS NOT NULL
+
+	UNION ALL
+	SELECT 'session:' || s.id, 'new_device_login', 'session', s.id, s.device_name, NULL,
+		s.created_at, NULL
+	FROM user_sessions s
+	WHERE s.user_id = $1 AND NOT EXISTS (
+		SELECT 1 FROM user_sessions p
+		WHERE p.user_id = s.user_id AND p.user_agent IS NOT DISTINCT FROM s.user_agent
+			AND p.created_at < s.created_at
+	)
+
+	UNION ALL
+	SELECT 'audit:' || a.id, 'password_changed', NULL, NULL, NULL, NULL, a.created_at, NULL
+	FROM audit_logs a
+	WHERE a.user_id = $1 AND a.action = 'password_change'
+) feed
+WHERE type = ANY($2) AND ($3::timestamptz IS NULL OR (occurred_at, key) < ($3, $4))
+ORDER BY occurred_at DESC, key DESC
+LIMIT $5`
+
+// PostgresStore reads activity events from PostgreSQL
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL activity store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Events returns the user's events ordered newest first
+func (s *PostgresStore) Events(ctx context.Context, userID uuid.UUID, query Query) ([]models.ActivityEvent, error) {
+	var before *time.Time
+	key := ""
+	if query.Before != nil {
+		before, key = &query.Before.At, query.Before.Key
+	}
+
+	rows, err := s.db.QueryContext(ctx, feedQuery, userID, pq.Array(query.Types), before, key, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ActivityEvent
+	for rows.Next() {
+		var event models.ActivityEvent
+		var entityType sql.NullString
+		if err := rows.Scan(&event.Key, &event.Type, &entityType, &event.EntityID, &event.Label,
+			&event.Amount, &event.OccurredAt, &event.GroupID); err != nil {
+			return nil, err
+		}
+		event.EntityType = entityType.String
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,219 @@
+package allocation
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
+)
+
+// ContributionSource is recorded on contributions created by allocation rules
+const ContributionSource = "allocation_rule"
+
+// Skip reasons reported for rules that did not produce a contribution
+const (
+	SkipReasonInactive      = "rule is inactive"
+	SkipReasonGoalMissing   = "target goal not found"
+	SkipReasonGoalInactive  = "target goal is not active"
+	SkipReasonGoalCompleted = "target goal is already completed"
+	SkipReasonExhausted     = "income already fully allocated"
+	SkipReasonGoalLinked    = "target goal is funded by a linked investment"
+)
+
+// Plan computes how amount would be distributed by the rules matching trigger.
+// Rules are applied in ascending priority order (ties keep creation order) and
+// the total allocated never exceeds amount; a rule whose share no longer fits
+// is capped to what remains. Rules targeting goals that are not active or are
+// already completed are reported in Skipped so the caller can notify the user.
+func Plan(amount float64, trigger string, rules []models.AllocationRule, goals map[uuid.UUID]*models.FinancialGoal) *models.AllocationResult {
+	result := &models.AllocationResult{
+		IncomeAmount: amount,
+		Remaining:    amount,
+		Allocations:  []models.PlannedAllocation{},
+	}
+
+	ordered := make([]models.AllocationRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Trigger == trigger {
+			ordered = append(ordered, rule)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return ordered[i].Priority < ordered[j].Priority
+		}
+		return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+	})
+
+	for _, rule := range ordered {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, models.SkippedAllocation{
+				RuleID:   rule.ID,
+				RuleName: rule.Name,
+				GoalID:   rule.GoalID,
+				Reason:   reason,
+			})
+		}
+
+		if !rule.Active {
+			skip(SkipReasonInactive)
+			continue
+		}
+
+		goal, ok := goals[rule.GoalID]
+		if !ok || goal == nil {
+			skip(SkipReasonGoalMissing)
+			continue
+		}
+		if goal.Status == "completed" || goal.IsCompleted() {
+			skip(SkipReasonGoalCompleted)
+			continue
+		}
+		if goal.Status != "active" {
+			skip(SkipReasonGoalInactive)
+			continue
+		}
+		if goal.IsLinked() {
+			skip(SkipReasonGoalLinked)
+			continue
+		}
+
+		if result.Remaining <= 0 {
+			skip(SkipReasonExhausted)
+			continue
+		}
+
+		share := ruleShare(rule, amount)
+		capped := false
+		if share > result.Remaining {
+			share = result.Remaining
+			capped = true
+		}
+		if share <= 0 {
+			skip(SkipReasonExhausted)
+			continue
+		}
+
+		result.Allocations = append(result.Allocations, models.PlannedAllocation{
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			GoalID:   goal.ID,
+			GoalName: goal.Name,
+			Amount:   share,
+			Capped:   capped,
+		})
+		result.AllocatedAmount = finance.RoundCents(result.AllocatedAmount + share)
+		result.Remaining = finance.RoundCents(amount - result.AllocatedAmount)
+	}
+
+	return result
+}
+
+// EvaluateIncome plans the allocation of a newly recorded income and builds the
+// goal contributions to persist, each referencing the rule that produced it
+func EvaluateIncome(income *models.Income, rules []models.AllocationRule, goals map[uuid.UUID]*models.FinancialGoal) *models.AllocationResult {
+	result := Plan(income.Amount, models.AllocationTriggerIncomeCreated, rules, goals)
+
+	source := ContributionSource
+	for _, allocation := range result.Allocations {
+		ruleID := allocation.RuleID
+		incomeID := income.ID
+		notes := "Allocated by rule: " + allocation.RuleName
+		result.Contributions = append(result.Contributions, models.GoalContribution{
+			ID:               uuid.New(),
+			GoalID:           allocation.GoalID,
+			Amount:           allocation.Amount,
+			ContributionDate: income.IncomeDate,
+			Source:           &source,
+			Notes:            &notes,
+			RuleID:           &ruleID,
+			IncomeID:         &incomeID,
+			CreatedAt:        time.Now(),
+		})
+	}
+
+	return result
+}
+
+// ValidateCreateRequest validates an allocation rule create request
+func ValidateCreateRequest(req *models.AllocationRuleCreateRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if err := utils.ValidateRequired(req.Name, "name"); err != nil {
+		errs.Add("name", "name is required")
+	}
+	if req.GoalID == uuid.Nil {
+		errs.Add("goal_id", "goal_id is required")
+	}
+	if req.Priority < 0 {
+		errs.Add("priority", "priority must not be negative")
+	}
+
+	errs = append(errs, validateShape(req.Trigger, req.Schedule, req.Percentage, req.FixedAmount)...)
+	return errs
+}
+
+// ValidateRule validates a rule after an update request has been applied to it
+func ValidateRule(rule *models.AllocationRule) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if err := utils.ValidateRequired(rule.Name, "name"); err != nil {
+		errs.Add("name", "name is required")
+	}
+	if rule.Priority < 0 {
+		errs.Add("priority", "priority must not be negative")
+	}
+
+	errs = append(errs, validateShape(rule.Trigger, rule.Schedule, rule.Percentage, rule.FixedAmount)...)
+	return errs
+}
+
+// validateShape checks the trigger and amount fields shared by create and update
+func validateShape(trigger string, schedule *string, percentage, fixedAmount *float64) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	switch trigger {
+	case models.AllocationTriggerIncomeCreated:
+		if schedule != nil {
+			errs.Add("schedule", "schedule is only allowed for schedule triggers")
+		}
+	case models.AllocationTriggerSchedule:
+		if schedule == nil || *schedule == "" {
+			errs.Add("schedule", "schedule is required for schedule triggers")
+		}
+		if percentage != nil {
+			errs.Add("percentage", "scheduled rules must use a fixed_amount")
+		}
+	default:
+		errs.Add("trigger", models.AllocationTriggers.Message("trigger"))
+	}
+
+	if (percentage == nil) == (fixedAmount == nil) {
+		errs.Add("percentage", "exactly one of percentage or fixed_amount is required")
+	}
+	if percentage != nil && (*percentage <= 0 || *percentage > 100) {
+		errs.Add("percentage", "percentage must be greater than 0 and at most 100")
+	}
+	if fixedAmount != nil {
+		if err := utils.ValidateAmount(*fixedAmount, "fixed_amount"); err != nil {
+			errs.Add("fixed_amount", err.(*utils.ValidationError).Message)
+		}
+	}
+
+	return errs
+}
+
+// ruleShare returns the amount a rule asks for from the given income
+func ruleShare(rule models.AllocationRule, amount float64) float64 {
+	if rule.Percentage != nil {
+		return finance.RoundCents(amount * *rule.Percentage / 100)
+	}
+	if rule.FixedAmount != nil {
+		return finance.RoundCents(*rule.FixedAmount)
+	}
+	return 0
+}
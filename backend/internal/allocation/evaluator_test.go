@@ -0,0 +1,189 @@
+package allocation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func newGoal(name string, target, current float64, status string) *models.FinancialGoal {
+	return &models.FinancialGoal{
+		ID:            uuid.New(),
+		Name:          name,
+		TargetAmount:  target,
+		CurrentAmount: current,
+		Status:        status,
+	}
+}
+
+func newRule(name string, goal *models.FinancialGoal, priority int, percentage, fixed *float64) models.AllocationRule {
+	return models.AllocationRule{
+		ID:          uuid.New(),
+		GoalID:      goal.ID,
+		Name:        name,
+		Trigger:     models.AllocationTriggerIncomeCreated,
+		Percentage:  percentage,
+		FixedAmount: fixed,
+		Priority:    priority,
+		Active:      true,
+	}
+}
+
+func TestPlanRespectsPriorityAndCap(t *testing.T) {
+	emergency := newGoal("Emergency Fund", 10000, 0, "active")
+	house := newGoal("House", 50000, 0, "active")
+	car := newGoal("Car", 20000, 0, "active")
+	goals := map[uuid.UUID]*models.FinancialGoal{emergency.ID: emergency, house.ID: house, car.ID: car}
+
+	rules := []models.AllocationRule{
+		newRule("Car fund", car, 3, nil, floatPtr(500)),
+		newRule("Emergency 10%", emergency, 1, floatPtr(10), nil),
+		newRule("House 50%", house, 2, floatPtr(50), nil),
+	}
+
+	result := Plan(1000, models.AllocationTriggerIncomeCreated, rules, goals)
+
+	if len(result.Allocations) != 3 {
+		t.Fatalf("Expected 3 allocations, got %d", len(result.Allocations))
+	}
+
+	expected := []struct {
+		goal   uuid.UUID
+		amount float64
+		capped bool
+	}{
+		{emergency.ID, 100, false},
+		{house.ID, 500, false},
+		{car.ID, 400, true},
+	}
+	for i, want := range expected {
+		got := result.Allocations[i]
+		if got.GoalID != want.goal || got.Amount != want.amount || got.Capped != want.capped {
+			t.Errorf("Allocation %d = %+v, want goal %v amount %v capped %v", i, got, want.goal, want.amount, want.capped)
+		}
+	}
+
+	if result.AllocatedAmount != 1000 || result.Remaining != 0 {
+		t.Errorf("Expected full allocation of 1000, got allocated %v remaining %v", result.AllocatedAmount, result.Remaining)
+	}
+}
+
+func TestPlanSkipsUnavailableGoals(t *testing.T) {
+	completed := newGoal("Vacation", 1000, 1000, "active")
+	cancelled := newGoal("Boat", 1000, 0, "cancelled")
+	active := newGoal("Emergency Fund", 1000, 0, "active")
+	goals := map[uuid.UUID]*models.FinancialGoal{completed.ID: completed, cancelled.ID: cancelled, active.ID: active}
+
+	inactive := newRule("Paused", active, 0, floatPtr(5), nil)
+	inactive.Active = false
+	missing := newRule("Missing", newGoal("Deleted", 1, 0, "active"), 0, floatPtr(5), nil)
+
+	rules := []models.AllocationRule{
+		inactive,
+		missing,
+		newRule("Vacation", completed, 1, floatPtr(10), nil),
+		newRule("Boat", cancelled, 2, floatPtr(10), nil),
+		newRule("Emergency", active, 3, nil, floatPtr(2000)),
+		newRule("Emergency again", active, 4, nil, floatPtr(10)),
+	}
+
+	result := Plan(500, models.AllocationTriggerIncomeCreated, rules, goals)
+
+	if len(result.Allocations) != 1 || result.Allocations[0].Amount != 500 || !result.Allocations[0].Capped {
+		t.Fatalf("Expected a single capped allocation of 500, got %+v", result.Allocations)
+	}
+
+	reasons := map[string]string{}
+	for _, skipped := range result.Skipped {
+		reasons[skipped.RuleName] = skipped.Reason
+	}
+	expected := map[string]string{
+		"Paused":          SkipReasonInactive,
+		"Missing":         SkipReasonGoalMissing,
+		"Vacation":        SkipReasonGoalCompleted,
+		"Boat":            SkipReasonGoalInactive,
+		"Emergency again": SkipReasonExhausted,
+	}
+	for name, reason := range expected {
+		if reasons[name] != reason {
+			t.Errorf("Rule %q skip reason = %q, want %q", name, reasons[name], reason)
+		}
+	}
+}
+
+func TestPlanIgnoresOtherTriggers(t *testing.T) {
+	goal := newGoal("Emergency Fund", 1000, 0, "active")
+	scheduled := newRule("Monthly", goal, 1, nil, floatPtr(100))
+	scheduled.Trigger = models.AllocationTriggerSchedule
+
+	result := Plan(1000, models.AllocationTriggerIncomeCreated, []models.AllocationRule{scheduled}, map[uuid.UUID]*models.FinancialGoal{goal.ID: goal})
+	if len(result.Allocations) != 0 || len(result.Skipped) != 0 {
+		t.Errorf("Scheduled rules should not run on income, got %+v", result)
+	}
+}
+
+func TestEvaluateIncomeBuildsContributions(t *testing.T) {
+	goal := newGoal("Emergency Fund", 10000, 0, "active")
+	rule := newRule("Emergency 10%", goal, 1, floatPtr(10), nil)
+	income := &models.Income{
+		ID:         uuid.New(),
+		Amount:     2500,
+		IncomeDate: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	result := EvaluateIncome(income, []models.AllocationRule{rule}, map[uuid.UUID]*models.FinancialGoal{goal.ID: goal})
+	if len(result.Contributions) != 1 {
+		t.Fatalf("Expected 1 contribution, got %d", len(result.Contributions))
+	}
+
+	contribution := result.Contributions[0]
+	if contribution.Amount != 250 || contribution.GoalID != goal.ID {
+		t.Errorf("Unexpected contribution: %+v", contribution)
+	}
+	if contribution.RuleID == nil || *contribution.RuleID != rule.ID {
+		t.Error("Contribution should record the rule that produced it")
+	}
+	if contribution.IncomeID == nil || *contribution.IncomeID != income.ID {
+		t.Error("Contribution should record the income it came from")
+	}
+	if !contribution.ContributionDate.Equal(income.IncomeDate) {
+		t.Errorf("Contribution date = %v, want %v", contribution.ContributionDate, income.IncomeDate)
+	}
+}
+
+func TestValidateCreateRequest(t *testing.T) {
+	schedule := "0 0 1 * *"
+	goalID := uuid.New()
+
+	tests := []struct {
+		name    string
+		req     models.AllocationRuleCreateRequest
+		wantErr bool
+	}{
+		{"valid percentage", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "income_created", Percentage: floatPtr(10)}, false},
+		{"valid schedule", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "schedule", Schedule: &schedule, FixedAmount: floatPtr(100)}, false},
+		{"missing name", models.AllocationRuleCreateRequest{GoalID: goalID, Trigger: "income_created", Percentage: floatPtr(10)}, true},
+		{"missing goal", models.AllocationRuleCreateRequest{Name: "Save", Trigger: "income_created", Percentage: floatPtr(10)}, true},
+		{"unknown trigger", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "weekly", Percentage: floatPtr(10)}, true},
+		{"both amounts", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "income_created", Percentage: floatPtr(10), FixedAmount: floatPtr(10)}, true},
+		{"no amount", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "income_created"}, true},
+		{"percentage over 100", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "income_created", Percentage: floatPtr(150)}, true},
+		{"schedule without cron", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "schedule", FixedAmount: floatPtr(100)}, true},
+		{"schedule with percentage", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "schedule", Schedule: &schedule, Percentage: floatPtr(10)}, true},
+		{"negative priority", models.AllocationRuleCreateRequest{GoalID: goalID, Name: "Save", Trigger: "income_created", Percentage: floatPtr(10), Priority: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateCreateRequest(&tt.req)
+			if errs.HasErrors() != tt.wantErr {
+				t.Errorf("ValidateCreateRequest() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
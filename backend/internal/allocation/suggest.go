@@ -0,0 +1,232 @@
+package allocation
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
+)
+
+// SuggestionSource is recorded on contributions created from a confirmed suggestion
+const SuggestionSource = "allocation_suggestion"
+
+// priorityWeights weight goals by priority when suggesting an allocation
+var priorityWeights = map[string]float64{
+	"high":   3,
+	"medium": 2,
+	"low":    1,
+}
+
+// Urgency weights range from 1 for goals due in urgencyHorizonMonths or more
+// (or without a target date) up to maxUrgencyWeight for goals due now
+const (
+	urgencyHorizonMonths = 60
+	maxUrgencyWeight     = 3
+)
+
+// averageDaysPerMonth converts durations to months for urgency weighting
+const averageDaysPerMonth = 30.436875
+
+// suggestionCandidate is a goal eligible for a suggested contribution
+type suggestionCandidate struct {
+	goal       *models.FinancialGoal
+	factors    models.SuggestionFactors
+	needCents  int64
+	givenCents int64
+	capped     bool
+}
+
+// Suggest splits amount across the active goals by priority, target-date
+// urgency and remaining amount. A goal never receives more than it needs to
+// complete, and what a capped goal cannot take is shared among the others.
+// The result is deterministic: ties are broken by target date, name and ID,
+// and leftover cents go to the highest-weighted goals first.
+func Suggest(amount float64, goals []models.FinancialGoal, now time.Time) *models.AllocationSuggestion {
+	result := &models.AllocationSuggestion{
+		Amount:      finance.RoundCents(amount),
+		Remaining:   finance.RoundCents(amount),
+		Suggestions: []models.SuggestedAllocation{},
+	}
+
+	candidates := make([]*suggestionCandidate, 0, len(goals))
+	for i := range goals {
+		goal := &goals[i]
+		if goal.Status != "active" || goal.IsCompleted() || goal.IsLinked() {
+			continue
+		}
+		candidates = append(candidates, newSuggestionCandidate(goal, now))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.factors.Weight != b.factors.Weight {
+			return a.factors.Weight > b.factors.Weight
+		}
+		if !sameTargetDate(a.goal.TargetDate, b.goal.TargetDate) {
+			return targetDateBefore(a.goal.TargetDate, b.goal.TargetDate)
+		}
+		if a.goal.Name != b.goal.Name {
+			return a.goal.Name < b.goal.Name
+		}
+		return a.goal.ID.String() < b.goal.ID.String()
+	})
+
+	pool := int64(math.Round(amount * 100))
+	distribute(candidates, pool)
+
+	var allocated int64
+	for _, c := range candidates {
+		if c.givenCents == 0 {
+			continue
+		}
+		allocated += c.givenCents
+		result.Suggestions = append(result.Suggestions, models.SuggestedAllocation{
+			GoalID:   c.goal.ID,
+			GoalName: c.goal.Name,
+			Amount:   float64(c.givenCents) / 100,
+			Capped:   c.capped,
+			Factors:  c.factors,
+		})
+	}
+	result.AllocatedAmount = float64(allocated) / 100
+	result.Remaining = float64(pool-allocated) / 100
+
+	return result
+}
+
+// ConfirmSuggestion builds the goal contributions for a suggestion so they
+// can be created together in one transaction
+func ConfirmSuggestion(suggestion *models.AllocationSuggestion, date time.Time) []models.GoalContribution {
+	source := SuggestionSource
+	contributions := make([]models.GoalContribution, 0, len(suggestion.Suggestions))
+	for _, s := range suggestion.Suggestions {
+		contributions = append(contributions, models.GoalContribution{
+			ID:               uuid.New(),
+			GoalID:           s.GoalID,
+			Amount:           s.Amount,
+			ContributionDate: date,
+			Source:           &source,
+			CreatedAt:        time.Now(),
+		})
+	}
+	suggestion.Contributions = contributions
+	return contributions
+}
+
+// ValidateSuggestionRequest validates an allocation suggestion request
+func ValidateSuggestionRequest(req *models.AllocationSuggestionRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if err := utils.ValidateAmount(req.Amount, "amount"); err != nil {
+		errs.Add("amount", err.(*utils.ValidationError).Message)
+	}
+
+	return errs
+}
+
+// newSuggestionCandidate computes the weighting factors for a goal
+func newSuggestionCandidate(goal *models.FinancialGoal, now time.Time) *suggestionCandidate {
+	priorityWeight, ok := priorityWeights[goal.Priority]
+	if !ok {
+		priorityWeight = priorityWeights["medium"]
+	}
+
+	factors := models.SuggestionFactors{
+		Priority:        goal.Priority,
+		PriorityWeight:  priorityWeight,
+		UrgencyWeight:   1,
+		RemainingAmount: finance.RoundCents(goal.TargetAmount - goal.CurrentAmount),
+	}
+	if goal.TargetDate != nil {
+		months := math.Max(goal.TargetDate.Sub(now).Hours()/24/averageDaysPerMonth, 0)
+		months = math.Round(months*10) / 10
+		factors.MonthsRemaining = &months
+		clamped := math.Min(months, urgencyHorizonMonths)
+		factors.UrgencyWeight = roundWeight(1 + (maxUrgencyWeight-1)*(urgencyHorizonMonths-clamped)/urgencyHorizonMonths)
+	}
+	factors.Weight = roundWeight(factors.PriorityWeight * factors.UrgencyWeight)
+
+	return &suggestionCandidate{
+		goal:      goal,
+		factors:   factors,
+		needCents: int64(math.Round(factors.RemainingAmount * 100)),
+	}
+}
+
+// distribute shares pool cents among candidates in proportion to their
+// weights, capping each at what it needs and redistributing the excess
+func distribute(candidates []*suggestionCandidate, pool int64) {
+	open := candidates
+	for pool > 0 && len(open) > 0 {
+		var totalWeight float64
+		for _, c := range open {
+			totalWeight += c.factors.Weight
+		}
+
+		// Goals whose proportional share covers what they need are capped
+		// first, and the rest is shared again among the others
+		var stillOpen []*suggestionCandidate
+		cappedAny := false
+		for _, c := range open {
+			share := float64(pool) * c.factors.Weight / totalWeight
+			if share >= float64(c.needCents) {
+				c.givenCents = c.needCents
+				c.capped = true
+				cappedAny = true
+			} else {
+				stillOpen = append(stillOpen, c)
+			}
+		}
+		if cappedAny {
+			for _, c := range open {
+				if c.capped {
+					pool -= c.givenCents
+				}
+			}
+			open = stillOpen
+			continue
+		}
+
+		// No goal is capped: hand out whole cents, then leftover cents in order
+		var given int64
+		for _, c := range open {
+			c.givenCents = int64(math.Floor(float64(pool) * c.factors.Weight / totalWeight))
+			given += c.givenCents
+		}
+		for i := 0; given < pool; i = (i + 1) % len(open) {
+			if open[i].givenCents < open[i].needCents {
+				open[i].givenCents++
+				given++
+			}
+		}
+		return
+	}
+}
+
+// roundWeight rounds a weighting factor to three decimal places
+func roundWeight(weight float64) float64 {
+	return math.Round(weight*1000) / 1000
+}
+
+// sameTargetDate returns true if both goals have the same target date or neither has one
+func sameTargetDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// targetDateBefore orders goals by target date, with undated goals last
+func targetDateBefore(a, b *time.Time) bool {
+	if a == nil {
+		return false
+	}
+	if b == nil {
+		return true
+	}
+	return a.Before(*b)
+}
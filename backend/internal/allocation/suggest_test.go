@@ -0,0 +1,150 @@
+package allocation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+var suggestNow = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func suggestGoal(name, priority string, target, current float64, targetDate *time.Time) models.FinancialGoal {
+	return models.FinancialGoal{
+		ID:            uuid.New(),
+		Name:          name,
+		TargetAmount:  target,
+		CurrentAmount: current,
+		TargetDate:    targetDate,
+		Priority:      priority,
+		Status:        "active",
+	}
+}
+
+func monthsFromNow(months int) *time.Time {
+	t := suggestNow.AddDate(0, months, 0)
+	return &t
+}
+
+func suggestionFor(result *models.AllocationSuggestion, goalID uuid.UUID) *models.SuggestedAllocation {
+	for i := range result.Suggestions {
+		if result.Suggestions[i].GoalID == goalID {
+			return &result.Suggestions[i]
+		}
+	}
+	return nil
+}
+
+func TestSuggestAllHighPriority(t *testing.T) {
+	goals := []models.FinancialGoal{
+		suggestGoal("A", "high", 10000, 0, nil),
+		suggestGoal("B", "high", 10000, 0, nil),
+		suggestGoal("C", "high", 10000, 0, nil),
+	}
+
+	result := Suggest(500, goals, suggestNow)
+
+	if result.AllocatedAmount != 500 || result.Remaining != 0 {
+		t.Fatalf("Expected the full amount allocated, got %+v", result)
+	}
+	// Equal weights split evenly; the leftover cent goes to the first goal by name
+	want := map[string]float64{"A": 166.67, "B": 166.67, "C": 166.66}
+	for _, s := range result.Suggestions {
+		if s.Amount != want[s.GoalName] {
+			t.Errorf("Goal %s got %v, want %v", s.GoalName, s.Amount, want[s.GoalName])
+		}
+	}
+}
+
+func TestSuggestCapsNearlyCompleteGoal(t *testing.T) {
+	nearly := suggestGoal("Vacation", "high", 1000, 950, nil)
+	other := suggestGoal("House", "low", 50000, 0, nil)
+
+	result := Suggest(500, []models.FinancialGoal{nearly, other}, suggestNow)
+
+	got := suggestionFor(result, nearly.ID)
+	if got == nil || got.Amount != 50 || !got.Capped {
+		t.Fatalf("Expected the nearly complete goal capped at 50, got %+v", got)
+	}
+	if rest := suggestionFor(result, other.ID); rest == nil || rest.Amount != 450 {
+		t.Errorf("Expected the excess redistributed, got %+v", rest)
+	}
+	if result.AllocatedAmount != 500 {
+		t.Errorf("Expected 500 allocated, got %v", result.AllocatedAmount)
+	}
+}
+
+func TestSuggestUrgencyAndUndatedGoals(t *testing.T) {
+	soon := suggestGoal("Soon", "medium", 10000, 0, monthsFromNow(3))
+	later := suggestGoal("Later", "medium", 10000, 0, monthsFromNow(48))
+	undated := suggestGoal("Someday", "medium", 10000, 0, nil)
+
+	result := Suggest(1000, []models.FinancialGoal{undated, later, soon}, suggestNow)
+
+	s, l, u := suggestionFor(result, soon.ID), suggestionFor(result, later.ID), suggestionFor(result, undated.ID)
+	if !(s.Amount > l.Amount && l.Amount > u.Amount) {
+		t.Errorf("Expected less time remaining to get more, got soon %v later %v undated %v", s.Amount, l.Amount, u.Amount)
+	}
+	if u.Factors.MonthsRemaining != nil || u.Factors.UrgencyWeight != 1 {
+		t.Errorf("Undated goals should have neutral urgency, got %+v", u.Factors)
+	}
+	if result.Suggestions[0].GoalID != soon.ID {
+		t.Errorf("Expected suggestions ordered by weight, got %s first", result.Suggestions[0].GoalName)
+	}
+}
+
+func TestSuggestSkipsIneligibleAndLeavesRemainder(t *testing.T) {
+	investmentID := uuid.New()
+	linked := suggestGoal("Linked", "high", 1000, 0, nil)
+	linked.LinkedInvestmentID = &investmentID
+	paused := suggestGoal("Paused", "high", 1000, 0, nil)
+	paused.Status = "paused"
+	done := suggestGoal("Done", "high", 1000, 1000, nil)
+	small := suggestGoal("Small", "low", 100, 0, nil)
+
+	result := Suggest(500, []models.FinancialGoal{linked, paused, done, small}, suggestNow)
+
+	if len(result.Suggestions) != 1 || result.Suggestions[0].GoalID != small.ID {
+		t.Fatalf("Expected only the eligible goal, got %+v", result.Suggestions)
+	}
+	if result.AllocatedAmount != 100 || result.Remaining != 400 {
+		t.Errorf("Expected 100 allocated and 400 remaining, got %v and %v", result.AllocatedAmount, result.Remaining)
+	}
+
+	contributions := ConfirmSuggestion(result, suggestNow)
+	if len(contributions) != 1 || contributions[0].Amount != 100 || *contributions[0].Source != SuggestionSource {
+		t.Errorf("Unexpected contributions %+v", contributions)
+	}
+}
+
+func TestSuggestIsDeterministic(t *testing.T) {
+	goals := []models.FinancialGoal{
+		suggestGoal("A", "high", 700, 0, monthsFromNow(6)),
+		suggestGoal("B", "medium", 300, 100, monthsFromNow(2)),
+		suggestGoal("C", "low", 5000, 0, nil),
+	}
+
+	first := Suggest(333.33, goals, suggestNow)
+	for i := 0; i < 10; i++ {
+		again := Suggest(333.33, goals, suggestNow)
+		for j := range first.Suggestions {
+			if again.Suggestions[j].GoalID != first.Suggestions[j].GoalID || again.Suggestions[j].Amount != first.Suggestions[j].Amount {
+				t.Fatalf("Suggestion changed between runs: %+v vs %+v", again.Suggestions[j], first.Suggestions[j])
+			}
+		}
+	}
+	if first.AllocatedAmount != 333.33 {
+		t.Errorf("Expected all cents allocated, got %v", first.AllocatedAmount)
+	}
+}
+
+func TestValidateSuggestionRequest(t *testing.T) {
+	if errs := ValidateSuggestionRequest(&models.AllocationSuggestionRequest{Amount: 0}); !errs.HasErrors() {
+		t.Error("Expected zero amount to be rejected")
+	}
+	if errs := ValidateSuggestionRequest(&models.AllocationSuggestionRequest{Amount: 500}); errs.HasErrors() {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+}
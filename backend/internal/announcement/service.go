@@ -0,0 +1,248 @@
+package announcement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+// ErrNotFound is returned when an announcement does not exist or is not
+// visible to the caller
+var ErrNotFound = errors.New("announcement not found")
+
+// Store persists announcements and dismissals
+type Store interface {
+	// ListActive returns announcements whose schedule covers at
+	ListActive(ctx context.Context, at time.Time) ([]models.Announcement, error)
+	List(ctx context.Context) ([]models.Announcement, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Announcement, error)
+	Create(ctx context.Context, announcement *models.Announcement) error
+	Update(ctx context.Context, announcement *models.Announcement) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	DismissedIDs(ctx context.Context, userID uuid.UUID, announcementIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	// Dismiss records a dismissal; dismissing twice is not an error
+	Dismiss(ctx context.Context, dismissal models.AnnouncementDismissal) error
+}
+
+// Service schedules announcements and tracks per-user dismissals. All
+// scheduling uses the server clock in UTC.
+type Service struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewService creates a new announcement service
+func NewService(store Store) *Service {
+	return &Service{store: store, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// Active returns the announcements currently shown to a user with role,
+// each marked with whether the user dismissed it. Dismissed announcements
+// are included so clients can offer to show them again; banners should only
+// render those with Dismissed false.
+func (s *Service) Active(ctx context.Context, userID uuid.UUID, role string) ([]models.Announcement, error) {
+	now := s.now()
+	candidates, err := s.store.ListActive(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list announcements: %w", err)
+	}
+
+	active := make([]models.Announcement, 0, len(candidates))
+	ids := make([]uuid.UUID, 0, len(candidates))
+	for _, announcement := range candidates {
+		if announcement.IsActive(now) && announcement.IsVisibleTo(role) {
+			active = append(active, announcement)
+			ids = append(ids, announcement.ID)
+		}
+	}
+	if len(active) == 0 {
+		return active, nil
+	}
+
+	dismissed, err := s.store.DismissedIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dismissals: %w", err)
+	}
+	for i := range active {
+		active[i].Dismissed = dismissed[active[i].ID]
+	}
+	return active, nil
+}
+
+// Undismissed returns the active announcements the user has not dismissed,
+// as included in the dashboard
+func (s *Service) Undismissed(ctx context.Context, userID uuid.UUID, role string) ([]models.Announcement, error) {
+	active, err := s.Active(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	shown := active[:0]
+	for _, announcement := range active {
+		if !announcement.Dismissed {
+			shown = append(shown, announcement)
+		}
+	}
+	return shown, nil
+}
+
+// Dismiss hides an announcement for the user on every device
+func (s *Service) Dismiss(ctx context.Context, userID uuid.UUID, role string, announcementID uuid.UUID) error {
+	announcement, err := s.store.Get(ctx, announcementID)
+	if err != nil {
+		return fmt.Errorf("failed to get announcement: %w", err)
+	}
+	if announcement == nil || !announcement.IsVisibleTo(role) {
+		return ErrNotFound
+	}
+
+	return s.store.Dismiss(ctx, models.AnnouncementDismissal{
+		AnnouncementID: announcementID,
+		UserID:         userID,
+		DismissedAt:    s.now(),
+	})
+}
+
+// Create validates and stores a new announcement
+func (s *Service) Create(ctx context.Context, adminID uuid.UUID, req *models.AnnouncementCreateRequest) (*models.Announcement, error) {
+	if errs := ValidateCreateRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+
+	now := s.now()
+	announcement := &models.Announcement{
+		ID:           uuid.New(),
+		Title:        strings.TrimSpace(req.Title),
+		Body:         req.Body,
+		Severity:     req.Severity,
+		StartsAt:     now,
+		Audience:     req.Audience,
+		AudienceRole: req.AudienceRole,
+		CreatedBy:    &adminID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if req.StartsAt != nil {
+		announcement.StartsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil {
+		endsAt := req.EndsAt.UTC()
+		announcement.EndsAt = &endsAt
+	}
+	if announcement.Audience == models.AnnouncementAudienceAll {
+		announcement.AudienceRole = nil
+	}
+
+	if err := s.store.Create(ctx, announcement); err != nil {
+		return nil, fmt.Errorf("failed to create announcement: %w", err)
+	}
+	return announcement, nil
+}
+
+// Update applies changes to an announcement and validates the result
+func (s *Service) Update(ctx context.Context, id uuid.UUID, req *models.AnnouncementUpdateRequest) (*models.Announcement, error) {
+	announcement, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get announcement: %w", err)
+	}
+	if announcement == nil {
+		return nil, ErrNotFound
+	}
+
+	if req.Title != nil {
+		announcement.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Body != nil {
+		announcement.Body = *req.Body
+	}
+	if req.Severity != nil {
+		announcement.Severity = *req.Severity
+	}
+	if req.StartsAt != nil {
+		announcement.StartsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil {
+		endsAt := req.EndsAt.UTC()
+		announcement.EndsAt = &endsAt
+	}
+	if req.Audience != nil {
+		announcement.Audience = *req.Audience
+	}
+	if req.AudienceRole != nil {
+		announcement.AudienceRole = req.AudienceRole
+	}
+	if announcement.Audience == models.AnnouncementAudienceAll {
+		announcement.AudienceRole = nil
+	}
+
+	if errs := ValidateAnnouncement(announcement); errs.HasErrors() {
+		return nil, errs
+	}
+	announcement.UpdatedAt = s.now()
+	if err := s.store.Update(ctx, announcement); err != nil {
+		return nil, fmt.Errorf("failed to update announcement: %w", err)
+	}
+	return announcement, nil
+}
+
+// Delete removes an announcement and its dismissals
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.store.Delete(ctx, id)
+}
+
+// List returns every announcement for the admin view
+func (s *Service) List(ctx context.Context) ([]models.Announcement, error) {
+	return s.store.List(ctx)
+}
+
+// ValidateCreateRequest validates a request to create an announcement
+func ValidateCreateRequest(req *models.AnnouncementCreateRequest) utils.ValidationErrors {
+	errs := validateShape(req.Title, req.Body, req.Severity, req.Audience, req.AudienceRole)
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		errs.Add("ends_at", "ends_at must be after starts_at")
+	}
+	return errs
+}
+
+// ValidateAnnouncement validates a stored announcement after an update
+func ValidateAnnouncement(a *models.Announcement) utils.ValidationErrors {
+	errs := validateShape(a.Title, a.Body, a.Severity, a.Audience, a.AudienceRole)
+	if a.EndsAt != nil && !a.EndsAt.After(a.StartsAt) {
+		errs.Add("ends_at", "ends_at must be after starts_at")
+	}
+	return errs
+}
+
+// validateShape checks the fields shared by requests and stored announcements
+func validateShape(title, body, severity, audience string, audienceRole *string) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if err := utils.ValidateLength(strings.TrimSpace(title), "title", 1, 200); err != nil {
+		errs.Add("title", "title must be between 1 and 200 characters")
+	}
+	if strings.TrimSpace(body) == "" {
+		errs.Add("body", "body is required")
+	}
+
+	if !models.AnnouncementSeverities.Valid(severity) {
+		errs.Add("severity", models.AnnouncementSeverities.Message("severity"))
+	}
+
+	switch audience {
+	case models.AnnouncementAudienceAll:
+	case models.AnnouncementAudienceRole:
+		if audienceRole == nil || strings.TrimSpace(*audienceRole) == "" {
+			errs.Add("audience_role", "audience_role is required when audience is role")
+		}
+	default:
+		errs.Add("audience", models.AnnouncementAudiences.Message("audience"))
+	}
+
+	return errs
+}
@@ -0,0 +1,202 @@
+package announcement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+type memoryStore struct {
+	announcements map[uuid.UUID]models.Announcement
+	dismissals    map[[2]uuid.UUID]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		announcements: make(map[uuid.UUID]models.Announcement),
+		dismissals:    make(map[[2]uuid.UUID]bool),
+	}
+}
+
+// ListActive deliberately returns everything so the service's own schedule check is exercised
+func (m *memoryStore) ListActive(ctx context.Context, at time.Time) ([]models.Announcement, error) {
+	return m.List(ctx)
+}
+
+func (m *memoryStore) List(ctx context.Context) ([]models.Announcement, error) {
+	var all []models.Announcement
+	for _, a := range m.announcements {
+		all = append(all, a)
+	}
+	return all, nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, id uuid.UUID) (*models.Announcement, error) {
+	a, ok := m.announcements[id]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+func (m *memoryStore) Create(ctx context.Context, a *models.Announcement) error {
+	m.announcements[a.ID] = *a
+	return nil
+}
+
+func (m *memoryStore) Update(ctx context.Context, a *models.Announcement) error {
+	m.announcements[a.ID] = *a
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(m.announcements, id)
+	return nil
+}
+
+func (m *memoryStore) DismissedIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	dismissed := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		if m.dismissals[[2]uuid.UUID{id, userID}] {
+			dismissed[id] = true
+		}
+	}
+	return dismissed, nil
+}
+
+func (m *memoryStore) Dismiss(ctx context.Context, d models.AnnouncementDismissal) error {
+	m.dismissals[[2]uuid.UUID{d.AnnouncementID, d.UserID}] = true
+	return nil
+}
+
+func TestActiveAnnouncements(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	service := NewService(store)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	admin := uuid.New()
+
+	hour := time.Hour
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	adminRole := "admin"
+
+	current, err := service.Create(ctx, admin, &models.AnnouncementCreateRequest{
+		Title: "Maintenance tonight", Body: "**Downtime** 02:00-03:00 UTC", Severity: models.AnnouncementSeverityWarning,
+		EndsAt: at(2 * hour), Audience: models.AnnouncementAudienceAll,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	service.Create(ctx, admin, &models.AnnouncementCreateRequest{
+		Title: "Scheduled", Body: "later", Severity: models.AnnouncementSeverityInfo,
+		StartsAt: at(hour), Audience: models.AnnouncementAudienceAll,
+	})
+	service.Create(ctx, admin, &models.AnnouncementCreateRequest{
+		Title: "Expired", Body: "old", Severity: models.AnnouncementSeverityInfo,
+		StartsAt: at(-3 * hour), EndsAt: at(-hour), Audience: models.AnnouncementAudienceAll,
+	})
+	adminOnly, _ := service.Create(ctx, admin, &models.AnnouncementCreateRequest{
+		Title: "Admins", Body: "rotate keys", Severity: models.AnnouncementSeverityCritical,
+		Audience: models.AnnouncementAudienceRole, AudienceRole: &adminRole,
+	})
+
+	user := uuid.New()
+	active, err := service.Active(ctx, user, "user")
+	if err != nil {
+		t.Fatalf("Active() error = %v", err)
+	}
+	if len(active) != 1 || active[0].ID != current.ID || active[0].Dismissed {
+		t.Fatalf("active for user = %+v, want only the current announcement", active)
+	}
+
+	adminActive, _ := service.Active(ctx, admin, "admin")
+	if len(adminActive) != 2 {
+		t.Errorf("active for admin = %d, want 2", len(adminActive))
+	}
+
+	if err := service.Dismiss(ctx, user, "user", current.ID); err != nil {
+		t.Fatalf("Dismiss() error = %v", err)
+	}
+	active, _ = service.Active(ctx, user, "user")
+	if len(active) != 1 || !active[0].Dismissed {
+		t.Errorf("dismissed announcement should be marked: %+v", active)
+	}
+	if shown, _ := service.Undismissed(ctx, user, "user"); len(shown) != 0 {
+		t.Errorf("dashboard should hide dismissed announcements, got %d", len(shown))
+	}
+
+	if err := service.Dismiss(ctx, user, "user", adminOnly.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("dismissing another audience's announcement error = %v, want ErrNotFound", err)
+	}
+
+	// The scheduled announcement appears once its start passes, with no job involved
+	now = now.Add(90 * time.Minute)
+	active, _ = service.Active(ctx, user, "user")
+	if len(active) != 2 {
+		t.Errorf("active after start = %d, want 2", len(active))
+	}
+	now = now.Add(time.Hour)
+	active, _ = service.Active(ctx, user, "user")
+	if len(active) != 1 || active[0].Title != "Scheduled" {
+		t.Errorf("expired announcement should drop out: %+v", active)
+	}
+}
+
+func TestAnnouncementValidation(t *testing.T) {
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		req    models.AnnouncementCreateRequest
+		fields []string
+	}{
+		{"valid", models.AnnouncementCreateRequest{Title: "t", Body: "b", Severity: "info", Audience: "all"}, nil},
+		{"missing fields", models.AnnouncementCreateRequest{Severity: "loud", Audience: "everyone"}, []string{"title", "body", "severity", "audience"}},
+		{"role without role", models.AnnouncementCreateRequest{Title: "t", Body: "b", Severity: "info", Audience: "role"}, []string{"audience_role"}},
+		{"ends before start", models.AnnouncementCreateRequest{Title: "t", Body: "b", Severity: "info", Audience: "all", StartsAt: &start, EndsAt: &before}, []string{"ends_at"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateCreateRequest(&tt.req)
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("errors = %v, want fields %v", errs, tt.fields)
+			}
+			for i, field := range tt.fields {
+				if errs[i].Field != field {
+					t.Errorf("error %d field = %s, want %s", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateValidatesResult(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(newMemoryStore())
+	created, _ := service.Create(ctx, uuid.New(), &models.AnnouncementCreateRequest{
+		Title: "t", Body: "b", Severity: "info", Audience: "all",
+	})
+
+	role := models.AnnouncementAudienceRole
+	_, err := service.Update(ctx, created.ID, &models.AnnouncementUpdateRequest{Audience: &role})
+	var errs utils.ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Field != "audience_role" {
+		t.Errorf("Update() error = %v, want audience_role validation error", err)
+	}
+
+	if _, err := service.Update(ctx, uuid.New(), &models.AnnouncementUpdateRequest{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() of missing announcement error = %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package apitoken
+
+import (
+	"net/http"
+	"strings"
+
+	"tgfinance/internal/models"
+)
+
+// Endpoints integration tokens may be allowed to call. Only read-only routes
+// belong here; the allowlist of a token holds their names.
+var Endpoints = []models.IntegrationEndpoint{
+	{Name: "expenses.list", Method: http.MethodGet, Pattern: "/api/v1/expenses", Label: "List expenses"},
+	{Name: "expenses.summary", Method: http.MethodGet, Pattern: "/api/v1/expenses/summary", Label: "Expense summary"},
+	{Name: "investments.summary", Method: http.MethodGet, Pattern: "/api/v1/investments/summary", Label: "Investment summary"},
+	{Name: "goals.list", Method: http.MethodGet, Pattern: "/api/v1/goals", Label: "List goals"},
+	{Name: "goals.get", Method: http.MethodGet, Pattern: "/api/v1/goals/{id}", Label: "Goal details"},
+}
+
+// IsEndpoint determines if name is a known integration endpoint
+func IsEndpoint(name string) bool {
+	for _, e := range Endpoints {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWrite determines if method may modify data
+func IsWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// MatchEndpoint returns the integration endpoint serving method and path.
+// HEAD requests match GET endpoints.
+func MatchEndpoint(method, path string) (models.IntegrationEndpoint, bool) {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	for _, e := range Endpoints {
+		if e.Method == method && matchPattern(e.Pattern, path) {
+			return e, true
+		}
+	}
+	return models.IntegrationEndpoint{}, false
+}
+
+// matchPattern matches a path against a route pattern in which {name}
+// segments match any single non-empty segment
+func matchPattern(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
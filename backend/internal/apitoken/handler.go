@@ -0,0 +1,128 @@
+package apitoken
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the self-service integration token endpoints
+type Handler struct {
+	tokens *Service
+}
+
+// NewHandler creates a new integration token handler
+func NewHandler(tokens *Service) *Handler {
+	return &Handler{tokens: tokens}
+}
+
+// Endpoints handles GET /api/v1/integration-tokens/endpoints, listing the
+// endpoints a token may be allowed to call
+func (h *Handler) Endpoints(w http.ResponseWriter, r *http.Request) {
+	httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"endpoints": Endpoints})
+}
+
+// List handles GET /api/v1/integration-tokens
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	tokens, err := h.tokens.List(r.Context(), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to list integration tokens")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+// Create handles POST /api/v1/integration-tokens. The response is the only
+// time the plaintext token is shown.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	var req models.IntegrationTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	token, err := h.tokens.Create(r.Context(), userID, &req)
+	if !writeTokenError(w, err) {
+		httputil.WriteJSON(w, http.StatusCreated, token)
+	}
+}
+
+// Renew handles POST /api/v1/integration-tokens/{id}/renew
+func (h *Handler) Renew(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	tokenID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid token ID")
+		return
+	}
+	var req models.IntegrationTokenRenewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+			return
+		}
+	}
+
+	token, err := h.tokens.Renew(r.Context(), userID, tokenID, &req)
+	if !writeTokenError(w, err) {
+		httputil.WriteJSON(w, http.StatusOK, token)
+	}
+}
+
+// Revoke handles DELETE /api/v1/integration-tokens/{id}
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	tokenID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid token ID")
+		return
+	}
+
+	if err := h.tokens.Revoke(r.Context(), userID, tokenID); !writeTokenError(w, err) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeTokenError writes the response for a token service error and returns
+// false if there was none
+func writeTokenError(w http.ResponseWriter, err error) bool {
+	var errs utils.ValidationErrors
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrNotFound):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Integration token not found")
+	case errors.Is(err, ErrNotRenewable):
+		httputil.WriteError(w, http.StatusConflict, httputil.CodeForStatus(http.StatusConflict), "Revoked or disabled integration tokens cannot be renewed")
+	default:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to process integration token")
+	}
+	return true
+}
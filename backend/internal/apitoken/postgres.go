@@ -0,0 +1,112 @@
+package apitoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"tgfinance/internal/models"
+)
+
+// tokenColumns selects an integration token
+const tokenColumns = `id, user_id, name, token_prefix, token_hash, endpoints, rate_limit_per_minute, expires_at,
+	last_used_at, last_used_ip, write_attempts, disabled_at, revoked_at, created_at, updated_at`
+
+// PostgresStore persists integration tokens in PostgreSQL
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL integration token store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Create stores a new token
+func (s *PostgresStore) Create(ctx context.Context, t *models.IntegrationToken) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO integration_tokens (id, user_id, name, token_prefix, token_hash, endpoints,
+			rate_limit_per_minute, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		t.ID, t.UserID, t.Name, t.Prefix, t.TokenHash, pq.Array(t.Endpoints),
+		t.RateLimitPerMinute, t.ExpiresAt, t.CreatedAt, t.UpdatedAt)
+	return err
+}
+
+// Get returns a token by ID, or nil
+func (s *PostgresStore) Get(ctx context.Context, id uuid.UUID) (*models.IntegrationToken, error) {
+	return s.scanOne(s.db.QueryRowContext(ctx, `SELECT `+tokenColumns+` FROM integration_tokens WHERE id = $1`, id))
+}
+
+// FindByHash returns the token with the hash, or nil
+func (s *PostgresStore) FindByHash(ctx context.Context, hash string) (*models.IntegrationToken, error) {
+	return s.scanOne(s.db.QueryRowContext(ctx, `SELECT `+tokenColumns+` FROM integration_tokens WHERE token_hash = $1`, hash))
+}
+
+// ListByUser returns the user's tokens that have not been revoked, newest first
+func (s *PostgresStore) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.IntegrationToken, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+tokenColumns+` FROM integration_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.IntegrationToken{}
+	for rows.Next() {
+		t, err := s.scanOne(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// Touch records the last use of a token
+func (s *PostgresStore) Touch(ctx context.Context, id uuid.UUID, at time.Time, ip string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE integration_tokens SET last_used_at = $2, last_used_ip = $3 WHERE id = $1`, id, at, ip)
+	return err
+}
+
+// SetExpiry sets a token's expiry
+func (s *PostgresStore) SetExpiry(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE integration_tokens SET expires_at = $2 WHERE id = $1`, id, expiresAt)
+	return err
+}
+
+// Revoke revokes a token
+func (s *PostgresStore) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE integration_tokens SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id, at)
+	return err
+}
+
+// RecordWriteAttempt increments the token's write attempts and disables it
+// at disableAfter attempts, returning the new count
+func (s *PostgresStore) RecordWriteAttempt(ctx context.Context, id uuid.UUID, at time.Time, disableAfter int) (int, error) {
+	var attempts int
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE integration_tokens SET write_attempts = write_attempts + 1,
+			disabled_at = CASE WHEN disabled_at IS NULL AND write_attempts + 1 >= $3 THEN $2 ELSE disabled_at END
+		WHERE id = $1
+		RETURNING write_attempts`, id, at, disableAfter).Scan(&attempts)
+	return attempts, err
+}
+
+// scanOne scans a token row, returning nil when there is none
+func (s *PostgresStore) scanOne(row interface{ Scan(...any) error }) (*models.IntegrationToken, error) {
+	var t models.IntegrationToken
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.TokenHash, pq.Array(&t.Endpoints), &t.RateLimitPerMinute,
+		&t.ExpiresAt, &t.LastUsedAt, &t.LastUsedIP, &t.WriteAttempts, &t.DisabledAt, &t.RevokedAt, &t.CreatedAt, &t.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
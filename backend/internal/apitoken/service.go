@@ -0,0 +1,248 @@
+package apitoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+const (
+	// DefaultRateLimitPerMinute applies to tokens created without a rate limit
+	DefaultRateLimitPerMinute = 30
+	// MaxRateLimitPerMinute keeps integration tokens below interactive limits
+	MaxRateLimitPerMinute = 60
+	// DefaultExpiryDays applies to tokens created or renewed without an expiry
+	DefaultExpiryDays = 90
+	// MaxExpiryDays is the longest a token may be valid for before renewal
+	MaxExpiryDays = 365
+	// MaxWriteAttempts is how many write attempts disable a token
+	MaxWriteAttempts = 5
+)
+
+// touchInterval throttles last-used updates so reads don't write on every request
+const touchInterval = time.Minute
+
+// displayPrefixLength is how much of a token is kept to identify it in lists
+const displayPrefixLength = len(middleware.IntegrationTokenPrefix) + 6
+
+// maxNameLength matches the name column
+const maxNameLength = 100
+
+// ErrNotFound is returned when a token does not exist or belongs to another user
+var ErrNotFound = errors.New("integration token not found")
+
+// ErrNotRenewable is returned when renewing a revoked or disabled token
+var ErrNotRenewable = errors.New("integration token is revoked or disabled")
+
+// Store persists integration tokens
+type Store interface {
+	Create(ctx context.Context, token *models.IntegrationToken) error
+	Get(ctx context.Context, id uuid.UUID) (*models.IntegrationToken, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.IntegrationToken, error)
+	// FindByHash returns the token with the hash, or nil
+	FindByHash(ctx context.Context, hash string) (*models.IntegrationToken, error)
+	Touch(ctx context.Context, id uuid.UUID, at time.Time, ip string) error
+	SetExpiry(ctx context.Context, id uuid.UUID, expiresAt time.Time) error
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	// RecordWriteAttempt increments the token's write attempts and disables it
+	// at disableAfter attempts, returning the new count
+	RecordWriteAttempt(ctx context.Context, id uuid.UUID, at time.Time, disableAfter int) (int, error)
+}
+
+// Service manages integration tokens and authorizes requests made with them
+type Service struct {
+	store Store
+	clock clock.Clock
+}
+
+// NewService creates a new integration token service
+func NewService(store Store) *Service {
+	return &Service{store: store, clock: clock.Real()}
+}
+
+// Create creates a token for the user. The plaintext token is only returned
+// here; just its hash is stored.
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, req *models.IntegrationTokenRequest) (*models.IntegrationTokenCreated, error) {
+	if errs := ValidateTokenRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+
+	secret, err := auth.GenerateDeviceToken()
+	if err != nil {
+		return nil, err
+	}
+	plaintext := middleware.IntegrationTokenPrefix + secret
+
+	now := s.clock.Now().UTC()
+	token := models.IntegrationToken{
+		ID:                 uuid.New(),
+		UserID:             userID,
+		Name:               strings.TrimSpace(req.Name),
+		Prefix:             plaintext[:displayPrefixLength],
+		Endpoints:          uniqueEndpoints(req.Endpoints),
+		RateLimitPerMinute: orDefault(req.RateLimitPerMinute, DefaultRateLimitPerMinute),
+		ExpiresAt:          now.AddDate(0, 0, orDefault(req.ExpiresInDays, DefaultExpiryDays)),
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		TokenHash:          auth.HashDeviceToken(plaintext),
+	}
+	if err := s.store.Create(ctx, &token); err != nil {
+		return nil, fmt.Errorf("failed to create integration token: %w", err)
+	}
+	return &models.IntegrationTokenCreated{IntegrationToken: token, Token: plaintext}, nil
+}
+
+// List returns the user's tokens with their last use
+func (s *Service) List(ctx context.Context, userID uuid.UUID) ([]models.IntegrationToken, error) {
+	tokens, err := s.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list integration tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Renew extends a token's expiry from now. Expired tokens can be renewed;
+// revoked and disabled ones cannot.
+func (s *Service) Renew(ctx context.Context, userID, tokenID uuid.UUID, req *models.IntegrationTokenRenewRequest) (*models.IntegrationToken, error) {
+	if errs := validateExpiry(req.ExpiresInDays); errs.HasErrors() {
+		return nil, errs
+	}
+	token, err := s.owned(ctx, userID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil || token.DisabledAt != nil {
+		return nil, ErrNotRenewable
+	}
+
+	token.ExpiresAt = s.clock.Now().UTC().AddDate(0, 0, orDefault(req.ExpiresInDays, DefaultExpiryDays))
+	if err := s.store.SetExpiry(ctx, tokenID, token.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to renew integration token: %w", err)
+	}
+	return token, nil
+}
+
+// Revoke revokes a token
+func (s *Service) Revoke(ctx context.Context, userID, tokenID uuid.UUID) error {
+	if _, err := s.owned(ctx, userID, tokenID); err != nil {
+		return err
+	}
+	if err := s.store.Revoke(ctx, tokenID, s.clock.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to revoke integration token: %w", err)
+	}
+	return nil
+}
+
+// AuthorizeIntegrationToken resolves a token for a request. Write requests
+// are refused and counted; a token reaching MaxWriteAttempts is disabled.
+// Read requests must match an endpoint on the token's allowlist.
+func (s *Service) AuthorizeIntegrationToken(ctx context.Context, plaintext, method, path, clientIP string) (*middleware.IntegrationPrincipal, error) {
+	token, err := s.store.FindByHash(ctx, auth.HashDeviceToken(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up integration token: %w", err)
+	}
+	now := s.clock.Now().UTC()
+	if token == nil || !auth.VerifyDeviceToken(token.TokenHash, plaintext) || !token.IsActive(now) {
+		return nil, middleware.ErrIntegrationTokenInvalid
+	}
+
+	if IsWrite(method) {
+		if _, err := s.store.RecordWriteAttempt(ctx, token.ID, now, MaxWriteAttempts); err != nil {
+			return nil, fmt.Errorf("failed to record write attempt: %w", err)
+		}
+		return nil, middleware.ErrIntegrationWriteDenied
+	}
+	endpoint, ok := MatchEndpoint(method, path)
+	if !ok || !token.Allows(endpoint.Name) {
+		return nil, middleware.ErrIntegrationEndpointDenied
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= touchInterval {
+		if err := s.store.Touch(ctx, token.ID, now, clientIP); err != nil {
+			return nil, fmt.Errorf("failed to record integration token use: %w", err)
+		}
+	}
+	return &middleware.IntegrationPrincipal{
+		TokenID:            token.ID,
+		UserID:             token.UserID,
+		RateLimitPerMinute: token.RateLimitPerMinute,
+	}, nil
+}
+
+// owned returns a token if it belongs to userID
+func (s *Service) owned(ctx context.Context, userID, tokenID uuid.UUID) (*models.IntegrationToken, error) {
+	token, err := s.store.Get(ctx, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integration token: %w", err)
+	}
+	if token == nil || token.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return token, nil
+}
+
+// ValidateTokenRequest validates an integration token request
+func ValidateTokenRequest(req *models.IntegrationTokenRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errs.Add("name", "name is required")
+	} else if len(name) > maxNameLength {
+		errs.Add("name", fmt.Sprintf("name must be at most %d characters", maxNameLength))
+	}
+
+	if len(req.Endpoints) == 0 {
+		errs.Add("endpoints", "at least one endpoint is required")
+	}
+	for _, e := range req.Endpoints {
+		if !IsEndpoint(e) {
+			errs.Add("endpoints", fmt.Sprintf("%q is not an endpoint available to integration tokens", e))
+		}
+	}
+
+	if req.RateLimitPerMinute < 0 || req.RateLimitPerMinute > MaxRateLimitPerMinute {
+		errs.Add("rate_limit_per_minute", fmt.Sprintf("rate_limit_per_minute must be between 1 and %d", MaxRateLimitPerMinute))
+	}
+	return append(errs, validateExpiry(req.ExpiresInDays)...)
+}
+
+// validateExpiry validates a requested token lifetime
+func validateExpiry(days int) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+	if days < 0 || days > MaxExpiryDays {
+		errs.Add("expires_in_days", fmt.Sprintf("expires_in_days must be between 1 and %d", MaxExpiryDays))
+	}
+	return errs
+}
+
+// uniqueEndpoints returns names without duplicates, in order
+func uniqueEndpoints(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var unique []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// orDefault returns value, or fallback when it is unset
+func orDefault(value, fallback int) int {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,327 @@
+package apitoken
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/config"
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+type memoryStore struct {
+	mu     sync.Mutex
+	tokens map[uuid.UUID]*models.IntegrationToken
+	writes int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{tokens: make(map[uuid.UUID]*models.IntegrationToken)}
+}
+
+func (s *memoryStore) Create(ctx context.Context, token *models.IntegrationToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := *token
+	s.tokens[t.ID] = &t
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, id uuid.UUID) (*models.IntegrationToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tokens[id]; ok {
+		copied := *t
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.IntegrationToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []models.IntegrationToken
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			tokens = append(tokens, *t)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *memoryStore) FindByHash(ctx context.Context, hash string) (*models.IntegrationToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.TokenHash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) Touch(ctx context.Context, id uuid.UUID, at time.Time, ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	s.tokens[id].LastUsedAt, s.tokens[id].LastUsedIP = &at, &ip
+	return nil
+}
+
+func (s *memoryStore) SetExpiry(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[id].ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *memoryStore) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[id].RevokedAt = &at
+	return nil
+}
+
+func (s *memoryStore) RecordWriteAttempt(ctx context.Context, id uuid.UUID, at time.Time, disableAfter int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tokens[id]
+	t.WriteAttempts++
+	if t.DisabledAt == nil && t.WriteAttempts >= disableAfter {
+		t.DisabledAt = &at
+	}
+	return t.WriteAttempts, nil
+}
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService() (*Service, *memoryStore, *clock.Fake) {
+	store := newMemoryStore()
+	service := NewService(store)
+	fake := clock.NewFake(testNow)
+	service.clock = fake
+	return service, store, fake
+}
+
+func newAuthMiddleware() *middleware.AuthMiddleware {
+	cfg := config.Load()
+	return middleware.NewAuthMiddleware(cfg, middleware.NewDeps(cfg))
+}
+
+// serve sends a request with token through the auth middleware and returns
+// the response and the user the handler saw
+func serve(m *middleware.AuthMiddleware, method, path, token string) (*httptest.ResponseRecorder, uuid.UUID) {
+	var seen uuid.UUID
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = middleware.GetUserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, seen
+}
+
+func errorReason(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Reason string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid error body %q: %v", rec.Body.String(), err)
+	}
+	return body.Error.Reason
+}
+
+func TestValidateTokenRequest(t *testing.T) {
+	errs := ValidateTokenRequest(&models.IntegrationTokenRequest{
+		Endpoints:          []string{"expenses.list", "expenses.create"},
+		RateLimitPerMinute: MaxRateLimitPerMinute + 1,
+		ExpiresInDays:      -1,
+	})
+	for _, field := range []string{"name", "endpoints", "rate_limit_per_minute", "expires_in_days"} {
+		found := false
+		for _, e := range errs {
+			found = found || e.Field == field
+		}
+		if !found {
+			t.Errorf("Expected an error on %s, got %v", field, errs)
+		}
+	}
+}
+
+func TestMatchEndpoint(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"GET", "/api/v1/expenses", "expenses.list"},
+		{"GET", "/api/v1/expenses/", "expenses.list"},
+		{"HEAD", "/api/v1/expenses/summary", "expenses.summary"},
+		{"GET", "/api/v1/goals/" + uuid.NewString(), "goals.get"},
+		{"GET", "/api/v1/goals/1/contributions", ""},
+		{"GET", "/api/v1/expenses/export", ""},
+		{"POST", "/api/v1/expenses", ""},
+	}
+	for _, tt := range tests {
+		endpoint, _ := MatchEndpoint(tt.method, tt.path)
+		if endpoint.Name != tt.want {
+			t.Errorf("MatchEndpoint(%s %s) = %q, want %q", tt.method, tt.path, endpoint.Name, tt.want)
+		}
+	}
+}
+
+func TestIntegrationTokenAllowlist(t *testing.T) {
+	service, store, fake := newTestService()
+	userID := uuid.New()
+	created, err := service.Create(context.Background(), userID, &models.IntegrationTokenRequest{
+		Name:      "Spreadsheet",
+		Endpoints: []string{"expenses.list", "expenses.summary", "expenses.list"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Endpoints) != 2 || created.RateLimitPerMinute != DefaultRateLimitPerMinute ||
+		!created.ExpiresAt.Equal(testNow.AddDate(0, 0, DefaultExpiryDays)) {
+		t.Errorf("Unexpected token %+v", created.IntegrationToken)
+	}
+
+	m := newAuthMiddleware()
+	if rec, _ := serve(m, "GET", "/api/v1/expenses", created.Token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Integration tokens should be refused until enabled, got %d", rec.Code)
+	}
+	m.EnableIntegrationTokens(service, middleware.NewMemoryRateLimitStore())
+
+	rec, seen := serve(m, "GET", "/api/v1/expenses", created.Token)
+	if rec.Code != http.StatusOK || seen != userID {
+		t.Fatalf("Allowed endpoint = %d as %s, want 200 as %s", rec.Code, seen, userID)
+	}
+	tokens, _ := service.List(context.Background(), userID)
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil || !tokens[0].LastUsedAt.Equal(testNow) {
+		t.Errorf("Expected last use to be listed, got %+v", tokens)
+	}
+
+	// Last use is only written once per interval
+	serve(m, "GET", "/api/v1/expenses/summary", created.Token)
+	if store.writes != 1 {
+		t.Errorf("Expected one last-used write, got %d", store.writes)
+	}
+	fake.Advance(2 * touchInterval)
+	serve(m, "GET", "/api/v1/expenses", created.Token)
+	if store.writes != 2 {
+		t.Errorf("Expected last use to be refreshed, got %d writes", store.writes)
+	}
+
+	rec, _ = serve(m, "GET", "/api/v1/goals", created.Token)
+	if rec.Code != http.StatusForbidden || errorReason(t, rec) != middleware.ReasonIntegrationEndpointDenied {
+		t.Errorf("Endpoint outside the allowlist = %d %s", rec.Code, rec.Body.String())
+	}
+	rec, _ = serve(m, "GET", "/api/v1/integration-tokens", created.Token)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Tokens should not manage tokens, got %d", rec.Code)
+	}
+	if rec, _ := serve(m, "GET", "/api/v1/expenses", "tgfi_unknown"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Unknown token = %d, want 401", rec.Code)
+	}
+}
+
+func TestIntegrationTokenWriteAttemptsDisableToken(t *testing.T) {
+	service, _, _ := newTestService()
+	userID := uuid.New()
+	created, _ := service.Create(context.Background(), userID, &models.IntegrationTokenRequest{
+		Name: "Spreadsheet", Endpoints: []string{"expenses.list"},
+	})
+	m := newAuthMiddleware()
+	m.EnableIntegrationTokens(service, middleware.NewMemoryRateLimitStore())
+
+	for i := 0; i < MaxWriteAttempts; i++ {
+		rec, _ := serve(m, "POST", "/api/v1/expenses", created.Token)
+		if rec.Code != http.StatusForbidden || errorReason(t, rec) != middleware.ReasonIntegrationReadOnly {
+			t.Fatalf("Write attempt %d = %d %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+	if rec, _ := serve(m, "GET", "/api/v1/expenses", created.Token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Token should be disabled after %d write attempts, got %d", MaxWriteAttempts, rec.Code)
+	}
+
+	tokens, _ := service.List(context.Background(), userID)
+	if tokens[0].DisabledAt == nil || tokens[0].WriteAttempts != MaxWriteAttempts {
+		t.Errorf("Unexpected token state %+v", tokens[0])
+	}
+	if _, err := service.Renew(context.Background(), userID, created.ID, &models.IntegrationTokenRenewRequest{}); !errors.Is(err, ErrNotRenewable) {
+		t.Errorf("Disabled tokens should not be renewable, got %v", err)
+	}
+}
+
+func TestIntegrationTokenRateLimit(t *testing.T) {
+	service, _, _ := newTestService()
+	created, _ := service.Create(context.Background(), uuid.New(), &models.IntegrationTokenRequest{
+		Name: "Spreadsheet", Endpoints: []string{"goals.list"}, RateLimitPerMinute: 2,
+	})
+	// Instances sharing a store share the token's limit
+	limits := middleware.NewMemoryRateLimitStore()
+	m, other := newAuthMiddleware(), newAuthMiddleware()
+	m.EnableIntegrationTokens(service, limits)
+	other.EnableIntegrationTokens(service, limits)
+
+	for i, instance := range []*middleware.AuthMiddleware{m, other} {
+		if rec, _ := serve(instance, "GET", "/api/v1/goals", created.Token); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec, _ := serve(m, "GET", "/api/v1/goals", created.Token)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Request over the token's limit = %d, want 429 with Retry-After", rec.Code)
+	}
+}
+
+func TestIntegrationTokenExpiryAndRenewal(t *testing.T) {
+	service, _, fake := newTestService()
+	userID := uuid.New()
+	ctx := context.Background()
+	created, _ := service.Create(ctx, userID, &models.IntegrationTokenRequest{
+		Name: "Spreadsheet", Endpoints: []string{"investments.summary"}, ExpiresInDays: 7,
+	})
+	m := newAuthMiddleware()
+	m.EnableIntegrationTokens(service, middleware.NewMemoryRateLimitStore())
+
+	fake.Advance(8 * 24 * time.Hour)
+	if rec, _ := serve(m, "GET", "/api/v1/investments/summary", created.Token); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expired token = %d, want 401", rec.Code)
+	}
+
+	var errs utils.ValidationErrors
+	if _, err := service.Renew(ctx, userID, created.ID, &models.IntegrationTokenRenewRequest{ExpiresInDays: MaxExpiryDays + 1}); !errors.As(err, &errs) {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+	if _, err := service.Renew(ctx, uuid.New(), created.ID, &models.IntegrationTokenRenewRequest{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Other users should not renew the token, got %v", err)
+	}
+	renewed, err := service.Renew(ctx, userID, created.ID, &models.IntegrationTokenRenewRequest{ExpiresInDays: 30})
+	if err != nil || !renewed.ExpiresAt.Equal(fake.Now().AddDate(0, 0, 30)) {
+		t.Fatalf("Renew = %+v, %v", renewed, err)
+	}
+	if rec, _ := serve(m, "GET", "/api/v1/investments/summary", created.Token); rec.Code != http.StatusOK {
+		t.Errorf("Renewed token = %d, want 200", rec.Code)
+	}
+
+	if err := service.Revoke(ctx, userID, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := serve(m, "GET", "/api/v1/investments/summary", created.Token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Revoked token = %d, want 401", rec.Code)
+	}
+}
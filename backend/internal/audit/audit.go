@@ -0,0 +1,211 @@
+// Package audit records security-relevant events: sign-ins, failed
+// authentication, password changes and every mutating API call.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tgfinance/internal/metrics"
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+)
+
+// DefaultQueueSize bounds queued events before new ones are dropped
+const DefaultQueueSize = 4096
+
+// BatchSize is the most events written in one insert
+const BatchSize = 100
+
+// FlushInterval is how long a partial batch waits before it is written
+const FlushInterval = time.Second
+
+// drainTimeout bounds writing the remaining events on shutdown
+const drainTimeout = 10 * time.Second
+
+// Store persists audit events. Insert must not retain the slice.
+type Store interface {
+	Insert(ctx context.Context, events []models.AuditEvent) error
+}
+
+// AuditLogger writes audit events in the background. Recording never blocks
+// a request: events are queued and dropped with a warning metric when the
+// queue is full.
+type AuditLogger struct {
+	store   Store
+	logger  *logger.Logger
+	clock   clock.Clock
+	events  chan models.AuditEvent
+	dropped atomic.Int64
+
+	droppedTotal prometheus.Counter
+	writeErrors  prometheus.Counter
+}
+
+// NewAuditLogger creates an audit logger writing to store
+func NewAuditLogger(store Store, log *logger.Logger, queueSize int) *AuditLogger {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	return &AuditLogger{
+		store:  store,
+		logger: log,
+		clock:  clock.Real(),
+		events: make(chan models.AuditEvent, queueSize),
+		droppedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "audit",
+			Name:      "events_dropped_total",
+			Help:      "Number of audit events dropped because the queue was full.",
+		}),
+		writeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "audit",
+			Name:      "write_errors_total",
+			Help:      "Number of audit event batches that failed to be written.",
+		}),
+	}
+}
+
+// SetClock replaces the clock used for timestamps and flushing. It must be
+// called before Run.
+func (a *AuditLogger) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// Register registers the audit metrics
+func (a *AuditLogger) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{a.droppedTotal, a.writeErrors} {
+		if err := reg.Register(c); err != nil {
+			return fmt.Errorf("failed to register audit metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Record queues an event. Missing IDs, timestamps and outcomes are filled in.
+func (a *AuditLogger) Record(event models.AuditEvent) {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = a.clock.Now().UTC()
+	}
+	if event.Outcome == "" {
+		event.Outcome = models.AuditOutcomeSuccess
+	}
+
+	select {
+	case a.events <- event:
+	default:
+		a.dropped.Add(1)
+		a.droppedTotal.Inc()
+	}
+}
+
+// RecordRequestEvent records a domain event such as "goal_deleted" made by
+// the request's user. Handlers call it once the change has been committed.
+func (a *AuditLogger) RecordRequestEvent(r *http.Request, eventType string, resourceID uuid.UUID, detail string) {
+	event := requestEvent(r, eventType)
+	if resourceID != uuid.Nil {
+		event.ResourceID = &resourceID
+	}
+	event.Detail = detail
+	a.Record(event)
+}
+
+// RecordAuthEvent records an event reported by the auth middleware
+func (a *AuditLogger) RecordAuthEvent(ctx context.Context, event middleware.AuthEvent) {
+	outcome := models.AuditOutcomeSuccess
+	if !event.Success {
+		outcome = models.AuditOutcomeFailure
+	}
+	a.Record(models.AuditEvent{
+		Type:      event.Type,
+		UserID:    optionalID(event.UserID),
+		Method:    event.Method,
+		Route:     metrics.NormalizeRoute(event.Path),
+		IPAddress: event.IP,
+		Outcome:   outcome,
+		Detail:    event.Reason,
+	})
+}
+
+// Dropped returns how many events were dropped because the queue was full
+func (a *AuditLogger) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+// Run writes queued events in batches until ctx is cancelled, then drains
+// the queue so events recorded before shutdown are not lost
+func (a *AuditLogger) Run(ctx context.Context) {
+	ticker := a.clock.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditEvent, 0, BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			a.drain(context.WithoutCancel(ctx), batch)
+			return
+		case event := <-a.events:
+			batch = append(batch, event)
+			if len(batch) >= BatchSize {
+				batch = a.flush(ctx, batch)
+			}
+		case <-ticker.C():
+			batch = a.flush(ctx, batch)
+		}
+	}
+}
+
+// drain writes the pending batch and everything still queued
+func (a *AuditLogger) drain(ctx context.Context, batch []models.AuditEvent) {
+	ctx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case event := <-a.events:
+			batch = append(batch, event)
+			if len(batch) >= BatchSize {
+				batch = a.flush(ctx, batch)
+			}
+		default:
+			a.flush(ctx, batch)
+			if dropped := a.Dropped(); dropped > 0 {
+				a.logger.WithField("dropped", dropped).Warn("Audit events were dropped because the queue was full")
+			}
+			return
+		}
+	}
+}
+
+// flush writes batch and returns it emptied. Failed batches are logged and
+// discarded so a database outage cannot grow memory without bound.
+func (a *AuditLogger) flush(ctx context.Context, batch []models.AuditEvent) []models.AuditEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := a.store.Insert(ctx, batch); err != nil {
+		a.writeErrors.Inc()
+		a.logger.WithError(err).WithField("events", len(batch)).Error("Failed to write audit events")
+	}
+	return batch[:0]
+}
+
+// optionalID returns nil for the zero UUID
+func optionalID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+)
+
+// memoryStore collects inserted batches
+type memoryStore struct {
+	mu      sync.Mutex
+	batches [][]models.AuditEvent
+	written chan struct{}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{written: make(chan struct{}, 100)}
+}
+
+func (s *memoryStore) Insert(ctx context.Context, events []models.AuditEvent) error {
+	s.mu.Lock()
+	s.batches = append(s.batches, append([]models.AuditEvent(nil), events...))
+	s.mu.Unlock()
+	s.written <- struct{}{}
+	return nil
+}
+
+func (s *memoryStore) events() []models.AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var all []models.AuditEvent
+	for _, batch := range s.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func (s *memoryStore) waitForWrite(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.written:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a write")
+	}
+}
+
+func newTestLogger(queueSize int) (*AuditLogger, *memoryStore, *bytes.Buffer) {
+	var logs bytes.Buffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	store := newMemoryStore()
+	return NewAuditLogger(store, log, queueSize), store, &logs
+}
+
+func TestAuditLoggerFlushes(t *testing.T) {
+	a, store, _ := newTestLogger(0)
+	fake := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	a.SetClock(fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+	fake.BlockUntil(1)
+
+	// A full batch is written without waiting for the interval
+	for i := 0; i < BatchSize; i++ {
+		a.Record(models.AuditEvent{Type: models.AuditEventLogin})
+	}
+	store.waitForWrite(t)
+	if got := len(store.events()); got != BatchSize {
+		t.Fatalf("wrote %d events, want %d", got, BatchSize)
+	}
+
+	// A partial batch waits for the flush interval
+	recordedAt := fake.Now()
+	a.Record(models.AuditEvent{Type: models.AuditEventLogout})
+	for len(a.events) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(FlushInterval)
+	store.waitForWrite(t)
+
+	events := store.events()
+	last := events[len(events)-1]
+	if len(events) != BatchSize+1 || last.Type != models.AuditEventLogout {
+		t.Fatalf("events = %d, last = %+v", len(events), last)
+	}
+	if last.ID == uuid.Nil || !last.CreatedAt.Equal(recordedAt) || last.Outcome != models.AuditOutcomeSuccess {
+		t.Errorf("defaults not filled in: %+v", last)
+	}
+}
+
+func TestAuditLoggerDrainsOnShutdown(t *testing.T) {
+	a, store, _ := newTestLogger(0)
+	a.SetClock(clock.NewFake(time.Now()))
+
+	// Events queued before Run starts are still written when it stops
+	for i := 0; i < BatchSize+5; i++ {
+		a.Record(models.AuditEvent{Type: models.AuditEventAPIMutation})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.Run(ctx)
+
+	if got := len(store.events()); got != BatchSize+5 {
+		t.Errorf("drained %d events, want %d", got, BatchSize+5)
+	}
+}
+
+func TestAuditLoggerDropsWhenFull(t *testing.T) {
+	a, store, logs := newTestLogger(2)
+	reg := prometheus.NewRegistry()
+	if err := a.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		a.Record(models.AuditEvent{Type: models.AuditEventLogin})
+	}
+	if a.Dropped() != 3 {
+		t.Errorf("dropped = %d, want 3", a.Dropped())
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == "tgfinance_audit_events_dropped_total" && f.GetMetric()[0].GetCounter().GetValue() != 3 {
+			t.Errorf("dropped metric = %v", f.GetMetric()[0].GetCounter().GetValue())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.Run(ctx)
+	if len(store.events()) != 2 {
+		t.Errorf("wrote %d events, want 2", len(store.events()))
+	}
+	if !strings.Contains(logs.String(), "Audit events were dropped") {
+		t.Errorf("drops not logged: %s", logs.String())
+	}
+}
+
+func TestMiddlewareRecordsMutations(t *testing.T) {
+	a, _, _ := newTestLogger(0)
+	userID := uuid.New()
+	goalID := uuid.New()
+
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			http.Error(w, "invalid", http.StatusBadRequest)
+		}
+	}))
+	serve := func(method, target string) {
+		req := httptest.NewRequest(method, target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", userID.String()))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(http.MethodGet, "/api/v1/goals/"+goalID.String())
+	serve(http.MethodDelete, "/api/v1/goals/"+goalID.String())
+	serve(http.MethodPut, "/api/v1/goals/"+goalID.String())
+	serve(http.MethodPost, "/api/v1/goals")
+
+	if len(a.events) != 3 {
+		t.Fatalf("queued %d events, want 3", len(a.events))
+	}
+	deleted := <-a.events
+	if deleted.Type != models.AuditEventAPIMutation || deleted.Method != http.MethodDelete ||
+		deleted.Route != "/api/v1/goals/:id" || *deleted.ResourceID != goalID || *deleted.UserID != userID ||
+		deleted.IPAddress != "192.0.2.1" || deleted.StatusCode != http.StatusOK || deleted.Outcome != models.AuditOutcomeSuccess {
+		t.Errorf("delete event = %+v", deleted)
+	}
+	if updated := <-a.events; updated.Outcome != models.AuditOutcomeFailure || updated.StatusCode != http.StatusBadRequest {
+		t.Errorf("failed update event = %+v", updated)
+	}
+	if created := <-a.events; created.ResourceID != nil {
+		t.Errorf("create event has a resource ID: %+v", created)
+	}
+}
+
+func TestRecordRequestEvent(t *testing.T) {
+	a, _, _ := newTestLogger(0)
+	userID := uuid.New()
+	goalID := uuid.New()
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/goals/"+goalID.String(), nil)
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", userID.String()))
+	a.RecordRequestEvent(req, "goal_deleted", goalID, "Emergency fund")
+
+	event := <-a.events
+	if event.Type != "goal_deleted" || *event.UserID != userID || *event.ResourceID != goalID || event.Detail != "Emergency fund" {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestRecordAuthEvent(t *testing.T) {
+	a, _, _ := newTestLogger(0)
+	var recorder middleware.AuthEventRecorder = a
+
+	recorder.RecordAuthEvent(context.Background(), middleware.AuthEvent{
+		Type:   middleware.AuthEventFailed,
+		Method: http.MethodGet,
+		Path:   "/api/v1/goals/" + uuid.NewString(),
+		IP:     "203.0.113.9",
+		Reason: "invalid_token",
+	})
+
+	event := <-a.events
+	if event.Type != models.AuditEventAuthFailed || event.Outcome != models.AuditOutcomeFailure ||
+		event.UserID != nil || event.Route != "/api/v1/goals/:id" || event.IPAddress != "203.0.113.9" {
+		t.Errorf("event = %+v", event)
+	}
+}
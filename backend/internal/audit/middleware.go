@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/metrics"
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+)
+
+// Middleware records every mutating request once it has been served. It
+// belongs after Authenticate so the user is known.
+func (a *AuditLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		event := requestEvent(r, models.AuditEventAPIMutation)
+		event.ResourceID = resourceID(r.URL.Path)
+		event.StatusCode = sw.statusCode()
+		if event.StatusCode >= 400 {
+			event.Outcome = models.AuditOutcomeFailure
+		}
+		a.Record(event)
+	})
+}
+
+// requestEvent returns an event of eventType describing the request
+func requestEvent(r *http.Request, eventType string) models.AuditEvent {
+	event := models.AuditEvent{
+		Type:      eventType,
+		Method:    r.Method,
+		Route:     metrics.NormalizeRoute(r.URL.Path),
+		IPAddress: middleware.ClientIP(r),
+	}
+	// Advisors acting for a client are recorded as themselves
+	if userID, err := middleware.GetActorUserIDFromContext(r.Context()); err == nil {
+		event.UserID = &userID
+	}
+	return event
+}
+
+// resourceID returns the last ID in path, which identifies the resource
+// being changed, or nil
+func resourceID(path string) *uuid.UUID {
+	segments := strings.Split(path, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if len(segments[i]) != 36 {
+			continue
+		}
+		if id, err := uuid.Parse(segments[i]); err == nil {
+			return &id
+		}
+	}
+	return nil
+}
+
+// isMutating returns true for methods that change state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return true
+}
+
+// statusWriter captures the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode returns the status sent, which is 200 when nothing was written
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// eventColumns selects an audit event
+const eventColumns = `id, event_type, user_id, method, route, resource_id, ip_address, outcome,
+	status_code, detail, created_at`
+
+// DefaultListLimit caps events returned by List when no limit is given
+const DefaultListLimit = 100
+
+// Query filters audit events. Zero fields are not filtered on.
+type Query struct {
+	UserID *uuid.UUID
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// PostgresStore persists audit events in PostgreSQL
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL audit event store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Insert writes events in a single statement
+func (s *PostgresStore) Insert(ctx context.Context, events []models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	const fields = 11
+	values := make([]string, 0, len(events))
+	args := make([]any, 0, len(events)*fields)
+	for i, e := range events {
+		placeholders := make([]string, fields)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*fields+j+1)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		args = append(args, e.ID, e.Type, e.UserID, nullString(e.Method), nullString(e.Route), e.ResourceID,
+			nullString(e.IPAddress), e.Outcome, nullInt(e.StatusCode), nullString(e.Detail), e.CreatedAt)
+	}
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO audit_events (`+eventColumns+`) VALUES `+strings.Join(values, ", "), args...)
+	return err
+}
+
+// List returns events matching q, newest first
+func (s *PostgresStore) List(ctx context.Context, q Query) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []any
+	if q.UserID != nil {
+		args = append(args, *q.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if !q.From.IsZero() {
+		args = append(args, q.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !q.To.IsZero() {
+		args = append(args, q.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	args = append(args, limit)
+
+	query := `SELECT ` + eventColumns + ` FROM audit_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(` ORDER BY created_at DESC LIMIT $%d`, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		var method, route, ip, detail sql.NullString
+		var statusCode sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &method, &route, &e.ResourceID, &ip, &e.Outcome,
+			&statusCode, &detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Method, e.Route, e.IPAddress, e.Detail = method.String, route.String, ip.String, detail.String
+		e.StatusCode = int(statusCode.Int64)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullInt stores zero as NULL
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"tgfinance/pkg/httputil"
+)
+
+// Handler serves the admin backup and restore endpoints
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new backup handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateBackup handles POST /api/v1/admin/backup
+func (h *Handler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	manifest, err := h.service.Backup(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to create backup")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusCreated, manifest)
+}
+
+// ListBackups handles GET /api/v1/admin/backup
+func (h *Handler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	manifests, err := h.service.List(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to list backups")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"backups": manifests})
+}
+
+// Restore handles POST /api/v1/admin/restore/{id}
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	job, err := h.service.StartRestore(r.Context(), r.PathValue("id"), force)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Backup not found")
+	case errors.Is(err, ErrNotEmpty):
+		httputil.WriteErrorFrom(w, http.StatusConflict, httputil.CodeForStatus(http.StatusConflict), err)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to start restore")
+	default:
+		w.Header().Set("Location", "/api/v1/admin/jobs/"+job.ID)
+		httputil.WriteJSON(w, http.StatusAccepted, job)
+	}
+}
+
+// GetJob handles GET /api/v1/admin/jobs/{id}
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.service.Job(r.PathValue("id"))
+	if !ok {
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Job not found")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, job)
+}
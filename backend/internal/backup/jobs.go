@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// Job reports the progress of a restore
+type Job struct {
+	ID           string     `json:"id"`
+	BackupID     string     `json:"backup_id"`
+	Status       string     `json:"status"`
+	TablesDone   int        `json:"tables_done"`
+	TablesTotal  int        `json:"tables_total"`
+	RowsRestored int64      `json:"rows_restored"`
+	RowsTotal    int64      `json:"rows_total"`
+	CurrentTable string     `json:"current_table,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// jobs tracks restore jobs in memory
+type jobs struct {
+	mu   sync.Mutex
+	byID map[string]*Job
+}
+
+func newJobs() *jobs {
+	return &jobs{byID: make(map[string]*Job)}
+}
+
+// start registers a running job for a backup
+func (j *jobs) start(manifest *Manifest, now time.Time) *Job {
+	job := &Job{
+		ID:          uuid.New().String(),
+		BackupID:    manifest.ID,
+		Status:      JobStatusRunning,
+		TablesTotal: len(manifest.Tables),
+		RowsTotal:   manifest.TotalRows,
+		StartedAt:   now,
+	}
+	j.mu.Lock()
+	j.byID[job.ID] = job
+	j.mu.Unlock()
+	return job
+}
+
+// update applies fn to a job under the lock
+func (j *jobs) update(id string, fn func(job *Job)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if job, ok := j.byID[id]; ok {
+		fn(job)
+	}
+}
+
+// get returns a copy of a job
+func (j *jobs) get(id string) (Job, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.byID[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
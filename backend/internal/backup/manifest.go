@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// FormatVersion is the archive layout version written to manifests
+const FormatVersion = 1
+
+// Archive layout and storage keys
+const (
+	keyPrefix        = "backups/"
+	archiveSuffix    = ".zip"
+	manifestSuffix   = ".manifest.json"
+	manifestEntry    = "manifest.json"
+	tableEntryPrefix = "tables/"
+	tableEntrySuffix = ".jsonl"
+)
+
+// idPattern matches backup IDs, which are also used in storage keys
+var idPattern = regexp.MustCompile(`^backup-\d{8}T\d{6}Z-[0-9a-f]{8}$`)
+
+// Manifest describes a backup archive. Every table in the database is
+// listed with its row count, in the order rows must be restored.
+type Manifest struct {
+	ID            string          `json:"id"`
+	FormatVersion int             `json:"format_version"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Tables        []TableManifest `json:"tables"`
+	TotalRows     int64           `json:"total_rows"`
+	SizeBytes     int64           `json:"size_bytes,omitempty"`
+}
+
+// TableManifest describes one table in a backup
+type TableManifest struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+	// SHA256 is the hex checksum of the table's JSON lines entry
+	SHA256 string `json:"sha256"`
+}
+
+// newID returns a backup ID for a backup taken at t
+func newID(t time.Time, suffix string) string {
+	return fmt.Sprintf("backup-%s-%s", t.UTC().Format("20060102T150405Z"), suffix)
+}
+
+// ValidID returns true if id is a well-formed backup ID
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// archiveKey returns the storage key of a backup's archive
+func archiveKey(id string) string {
+	return keyPrefix + id + archiveSuffix
+}
+
+// manifestKey returns the storage key of a backup's manifest
+func manifestKey(id string) string {
+	return keyPrefix + id + manifestSuffix
+}
+
+// tableEntry returns the archive entry name holding a table's rows
+func tableEntry(table string) string {
+	return tableEntryPrefix + table + tableEntrySuffix
+}
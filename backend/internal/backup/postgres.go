@@ -0,0 +1,215 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PostgresDatabase backs up and restores the public schema of a PostgreSQL database
+type PostgresDatabase struct {
+	db *sql.DB
+}
+
+// NewPostgresDatabase creates a new PostgreSQL backup database
+func NewPostgresDatabase(db *sql.DB) *PostgresDatabase {
+	return &PostgresDatabase{db: db}
+}
+
+// Snapshot starts a read-only repeatable read transaction, so every table
+// is read from the same point in time
+func (p *PostgresDatabase) Snapshot(ctx context.Context) (Snapshot, error) {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	return &postgresSnapshot{tx: tx}, nil
+}
+
+// BeginRestore starts the transaction a restore runs in. Period locks are
+// not enforced inside it, since restored expenses may belong to closed
+// periods that are restored alongside them.
+func (p *PostgresDatabase) BeginRestore(ctx context.Context) (RestoreTx, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('tgfinance.skip_period_lock', 'on', true)`); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &postgresRestore{tx: tx}, nil
+}
+
+type postgresSnapshot struct {
+	tx *sql.Tx
+}
+
+// Tables returns the base tables of the public schema, parents before the
+// tables referencing them
+func (s *postgresSnapshot) Tables(ctx context.Context) ([]string, error) {
+	rows, err := s.tx.QueryContext(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'`)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.tx.QueryContext(ctx, `
+		SELECT child.relname, parent.relname
+		FROM pg_constraint c
+		JOIN pg_class child ON child.oid = c.conrelid
+		JOIN pg_class parent ON parent.oid = c.confrelid
+		JOIN pg_namespace n ON n.oid = child.relnamespace
+		WHERE c.contype = 'f' AND n.nspname = 'public'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dependencies := make(map[string][]string)
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, err
+		}
+		if child != parent {
+			dependencies[child] = append(dependencies[child], parent)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orderTables(tables, dependencies)
+}
+
+// Dump streams the rows of a table as JSON objects
+func (s *postgresSnapshot) Dump(ctx context.Context, table string, fn func(row []byte) error) error {
+	rows, err := s.tx.QueryContext(ctx, fmt.Sprintf("SELECT row_to_json(t)::text FROM %s t", pq.QuoteIdentifier(table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var row []byte
+	for rows.Next() {
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Close ends the snapshot transaction
+func (s *postgresSnapshot) Close() error {
+	return s.tx.Rollback()
+}
+
+type postgresRestore struct {
+	tx *sql.Tx
+}
+
+func (r *postgresRestore) RowCount(ctx context.Context, table string) (int64, error) {
+	var count int64
+	err := r.tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", pq.QuoteIdentifier(table))).Scan(&count)
+	return count, err
+}
+
+func (r *postgresRestore) Truncate(ctx context.Context, tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = pq.QuoteIdentifier(table)
+	}
+	_, err := r.tx.ExecContext(ctx, "TRUNCATE "+strings.Join(quoted, ", ")+" CASCADE")
+	return err
+}
+
+// Insert converts the JSON rows back to the table's column types in the database
+func (r *postgresRestore) Insert(ctx context.Context, table string, rows []json.RawMessage) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	quoted := pq.QuoteIdentifier(table)
+	_, err = r.tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s SELECT * FROM json_populate_recordset(NULL::%s, $1)", quoted, quoted), string(data))
+	return err
+}
+
+func (r *postgresRestore) Commit() error {
+	return r.tx.Commit()
+}
+
+func (r *postgresRestore) Rollback() error {
+	return r.tx.Rollback()
+}
+
+// orderTables sorts tables so each comes after the tables it references,
+// breaking ties alphabetically
+func orderTables(tables []string, dependencies map[string][]string) ([]string, error) {
+	sort.Strings(tables)
+	known := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		known[table] = true
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(tables))
+	ordered := make([]string, 0, len(tables))
+
+	var visit func(table string) error
+	visit = func(table string) error {
+		switch state[table] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("foreign key cycle involving table %s", table)
+		}
+		state[table] = visiting
+		parents := append([]string(nil), dependencies[table]...)
+		sort.Strings(parents)
+		for _, parent := range parents {
+			if known[parent] {
+				if err := visit(parent); err != nil {
+					return err
+				}
+			}
+		}
+		state[table] = done
+		ordered = append(ordered, table)
+		return nil
+	}
+
+	for _, table := range tables {
+		if err := visit(table); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
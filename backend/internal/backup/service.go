@@ -0,0 +1,508 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+	"tgfinance/pkg/storage"
+)
+
+// Errors returned by the backup service
+var (
+	ErrNotFound = errors.New("backup not found")
+	ErrNotEmpty = errors.New("database is not empty; restore with force to replace its data")
+)
+
+// ReferenceTables are seeded by migrations, so a freshly migrated database
+// still counts as empty when they hold rows
+var ReferenceTables = map[string]bool{
+	"expense_categories": true,
+	"investment_types":   true,
+	"institutions":       true,
+}
+
+// insertBatchSize is the number of rows inserted per statement on restore
+const insertBatchSize = 500
+
+// Snapshot is a consistent, read-only view of the database
+type Snapshot interface {
+	// Tables returns every table, ordered so referenced tables come first
+	Tables(ctx context.Context) ([]string, error)
+	// Dump calls fn with each row of table encoded as a JSON object
+	Dump(ctx context.Context, table string, fn func(row []byte) error) error
+	Close() error
+}
+
+// RestoreTx restores rows within a single transaction
+type RestoreTx interface {
+	RowCount(ctx context.Context, table string) (int64, error)
+	// Truncate removes all rows from tables
+	Truncate(ctx context.Context, tables []string) error
+	// Insert inserts rows encoded as JSON objects into table
+	Insert(ctx context.Context, table string, rows []json.RawMessage) error
+	Commit() error
+	Rollback() error
+}
+
+// Database opens snapshots and restore transactions
+type Database interface {
+	Snapshot(ctx context.Context) (Snapshot, error)
+	BeginRestore(ctx context.Context) (RestoreTx, error)
+}
+
+// Service takes application-level backups into storage and restores them
+type Service struct {
+	db     Database
+	store  storage.Store
+	jobs   *jobs
+	logger *logger.Logger
+	clock  clock.Clock
+}
+
+// NewService creates a new backup service
+func NewService(db Database, store storage.Store, log *logger.Logger) *Service {
+	return &Service{
+		db:     db,
+		store:  store,
+		jobs:   newJobs(),
+		logger: log,
+		clock:  clock.Real(),
+	}
+}
+
+// WithClock sets the clock used for backup times and the schedule
+func (s *Service) WithClock(c clock.Clock) *Service {
+	s.clock = c
+	return s
+}
+
+// now returns the current time in UTC
+func (s *Service) now() time.Time {
+	return s.clock.Now().UTC()
+}
+
+// Backup streams a consistent snapshot of every table into a compressed
+// archive in storage, followed by a manifest with row counts and checksums
+func (s *Service) Backup(ctx context.Context) (*Manifest, error) {
+	snapshot, err := s.db.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	tables, err := snapshot.Tables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	createdAt := s.now()
+	manifest := &Manifest{
+		ID:            newID(createdAt, uuid.New().String()[:8]),
+		FormatVersion: FormatVersion,
+		CreatedAt:     createdAt,
+		Tables:        make([]TableManifest, 0, len(tables)),
+	}
+
+	pr, pw := io.Pipe()
+	counter := &countingWriter{w: pw}
+	go func() {
+		pw.CloseWithError(writeArchive(ctx, counter, snapshot, tables, manifest))
+	}()
+	if err := s.store.Put(ctx, archiveKey(manifest.ID), pr); err != nil {
+		pr.CloseWithError(err)
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	manifest.SizeBytes = counter.n
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, manifestKey(manifest.ID), bytes.NewReader(data)); err != nil {
+		s.store.Delete(ctx, archiveKey(manifest.ID))
+		return nil, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// writeArchive writes each table as a JSON lines entry and the manifest last
+func writeArchive(ctx context.Context, w io.Writer, snapshot Snapshot, tables []string, manifest *Manifest) error {
+	zw := zip.NewWriter(w)
+
+	for _, table := range tables {
+		entry, err := zw.Create(tableEntry(table))
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		out := bufio.NewWriter(io.MultiWriter(entry, hash))
+
+		var rows int64
+		err = snapshot.Dump(ctx, table, func(row []byte) error {
+			rows++
+			if _, err := out.Write(row); err != nil {
+				return err
+			}
+			return out.WriteByte('\n')
+		})
+		if err != nil {
+			return fmt.Errorf("failed to dump %s: %w", table, err)
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+
+		manifest.Tables = append(manifest.Tables, TableManifest{Name: table, Rows: rows, SHA256: hex.EncodeToString(hash.Sum(nil))})
+		manifest.TotalRows += rows
+	}
+
+	entry, err := zw.Create(manifestEntry)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(entry).Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// List returns the available backups, newest first
+func (s *Service) List(ctx context.Context) ([]Manifest, error) {
+	objects, err := s.store.List(ctx, keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	manifests := []Manifest{}
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, manifestSuffix) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(object.Key, keyPrefix), manifestSuffix)
+		manifest, err := s.manifest(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, *manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].CreatedAt.After(manifests[j].CreatedAt) })
+	return manifests, nil
+}
+
+// manifest loads the manifest of a backup
+func (s *Service) manifest(ctx context.Context, id string) (*Manifest, error) {
+	if !ValidID(id) {
+		return nil, ErrNotFound
+	}
+	r, err := s.store.Open(ctx, manifestKey(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer r.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to read manifest of %s: %w", id, err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("backup %s has unsupported format version %d", id, manifest.FormatVersion)
+	}
+	return &manifest, nil
+}
+
+// StartRestore restores a backup in the background and returns the job
+// reporting its progress. The database must be empty apart from the
+// reference tables unless force is set. The whole restore runs in one
+// transaction, so a failed restore leaves the database unchanged. The
+// transaction outlives ctx, which is usually a request that ends as soon as
+// the job is returned.
+func (s *Service) StartRestore(ctx context.Context, id string, force bool) (*Job, error) {
+	manifest, err := s.manifest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	tx, err := s.db.BeginRestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start restore: %w", err)
+	}
+	if !force {
+		for _, table := range manifest.Tables {
+			if ReferenceTables[table.Name] {
+				continue
+			}
+			count, err := tx.RowCount(ctx, table.Name)
+			if err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to count rows in %s: %w", table.Name, err)
+			}
+			if count > 0 {
+				tx.Rollback()
+				return nil, ErrNotEmpty
+			}
+		}
+	}
+
+	job := s.jobs.start(manifest, s.now())
+	started := *job
+	go s.restore(ctx, tx, manifest, job.ID)
+	return &started, nil
+}
+
+// Job returns the progress of a restore job
+func (s *Service) Job(id string) (*Job, bool) {
+	job, ok := s.jobs.get(id)
+	if !ok {
+		return nil, false
+	}
+	return &job, true
+}
+
+// restore runs a restore job to completion
+func (s *Service) restore(ctx context.Context, tx RestoreTx, manifest *Manifest, jobID string) {
+	err := s.restoreTables(ctx, tx, manifest, jobID)
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+
+	finished := s.now()
+	s.jobs.update(jobID, func(job *Job) {
+		job.FinishedAt = &finished
+		job.CurrentTable = ""
+		if err != nil {
+			job.Status = JobStatusFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = JobStatusSucceeded
+		}
+	})
+	if err != nil && s.logger != nil {
+		s.logger.WithError(err).Errorf("Restore of backup %s failed", manifest.ID)
+	}
+}
+
+// restoreTables replaces the data of every table in the manifest, verifying
+// row counts and checksums as it goes
+func (s *Service) restoreTables(ctx context.Context, tx RestoreTx, manifest *Manifest, jobID string) error {
+	archive, cleanup, err := s.openArchive(ctx, manifest.ID)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	entries := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		entries[f.Name] = f
+	}
+
+	names := make([]string, len(manifest.Tables))
+	for i, table := range manifest.Tables {
+		names[i] = table.Name
+	}
+	if err := tx.Truncate(ctx, names); err != nil {
+		return fmt.Errorf("failed to clear tables: %w", err)
+	}
+
+	for _, table := range manifest.Tables {
+		s.jobs.update(jobID, func(job *Job) { job.CurrentTable = table.Name })
+
+		f, ok := entries[tableEntry(table.Name)]
+		if !ok {
+			return fmt.Errorf("backup is missing table %s", table.Name)
+		}
+		if err := s.restoreTable(ctx, tx, f, table, jobID); err != nil {
+			return err
+		}
+		s.jobs.update(jobID, func(job *Job) { job.TablesDone++ })
+	}
+	return nil
+}
+
+// restoreTable inserts the rows of one archive entry in batches
+func (s *Service) restoreTable(ctx context.Context, tx RestoreTx, f *zip.File, table TableManifest, jobID string) error {
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read table %s: %w", table.Name, err)
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(r, hash))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var rows int64
+	batch := make([]json.RawMessage, 0, insertBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Insert(ctx, table.Name, batch); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", table.Name, err)
+		}
+		done := int64(len(batch))
+		s.jobs.update(jobID, func(job *Job) { job.RowsRestored += done })
+		batch = batch[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		row := make(json.RawMessage, len(scanner.Bytes()))
+		copy(row, scanner.Bytes())
+		batch = append(batch, row)
+		rows++
+		if len(batch) == insertBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read table %s: %w", table.Name, err)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if rows != table.Rows {
+		return fmt.Errorf("table %s has %d rows, manifest lists %d", table.Name, rows, table.Rows)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != table.SHA256 {
+		return fmt.Errorf("checksum mismatch for table %s", table.Name)
+	}
+	return nil
+}
+
+// openArchive opens a backup archive for random access, spooling it to a
+// temporary file when storage does not provide one
+func (s *Service) openArchive(ctx context.Context, id string) (*zip.Reader, func(), error) {
+	r, err := s.store.Open(ctx, archiveKey(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+
+	f, ok := r.(*os.File)
+	cleanup := func() { r.Close() }
+	if !ok {
+		defer r.Close()
+		tmp, err := os.CreateTemp("", "tgfinance-restore-*.zip")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+		if _, err := io.Copy(tmp, r); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		f = tmp
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	archive, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	return archive, cleanup, nil
+}
+
+// Prune deletes all but the newest keep backups and returns the deleted IDs
+func (s *Service) Prune(ctx context.Context, keep int) ([]string, error) {
+	manifests, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keep < 1 {
+		keep = 1
+	}
+
+	var deleted []string
+	for i := keep; i < len(manifests); i++ {
+		id := manifests[i].ID
+		if err := s.store.Delete(ctx, manifestKey(id)); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return deleted, fmt.Errorf("failed to delete backup %s: %w", id, err)
+		}
+		if err := s.store.Delete(ctx, archiveKey(id)); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return deleted, fmt.Errorf("failed to delete backup %s: %w", id, err)
+		}
+		deleted = append(deleted, id)
+	}
+	return deleted, nil
+}
+
+// RunScheduled takes a backup every interval and keeps the newest keep,
+// using the same code path as the admin endpoint, until ctx is cancelled
+func (s *Service) RunScheduled(ctx context.Context, interval time.Duration, keep int) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := s.scheduledBackup(ctx, keep); err != nil && s.logger != nil {
+				s.logger.WithError(err).Error("Scheduled backup failed")
+			}
+		}
+	}
+}
+
+// scheduledBackup takes one backup and prunes old ones
+func (s *Service) scheduledBackup(ctx context.Context, keep int) error {
+	manifest, err := s.Backup(ctx)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.Prune(ctx, keep)
+	if err != nil {
+		return err
+	}
+	if s.logger != nil {
+		s.logger.Infof("Scheduled backup %s written with %d rows, pruned %d old backups", manifest.ID, manifest.TotalRows, len(deleted))
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
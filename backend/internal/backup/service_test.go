@@ -0,0 +1,360 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/storage"
+)
+
+// fakeDatabase holds tables as ordered JSON rows
+type fakeDatabase struct {
+	order  []string
+	tables map[string][]string
+	// gate, when set, holds restores at Truncate until it is closed
+	gate chan struct{}
+}
+
+func newFakeDatabase() *fakeDatabase {
+	return &fakeDatabase{tables: make(map[string][]string)}
+}
+
+func (f *fakeDatabase) add(table string, rows ...string) {
+	if _, ok := f.tables[table]; !ok {
+		f.order = append(f.order, table)
+	}
+	f.tables[table] = append(f.tables[table], rows...)
+}
+
+func (f *fakeDatabase) Snapshot(ctx context.Context) (Snapshot, error) {
+	copied := make(map[string][]string, len(f.tables))
+	for table, rows := range f.tables {
+		copied[table] = append([]string(nil), rows...)
+	}
+	return &fakeSnapshot{order: append([]string(nil), f.order...), tables: copied}, nil
+}
+
+func (f *fakeDatabase) BeginRestore(ctx context.Context) (RestoreTx, error) {
+	return &fakeRestore{ctx: ctx, db: f, pending: make(map[string][]string)}, nil
+}
+
+type fakeSnapshot struct {
+	order  []string
+	tables map[string][]string
+}
+
+func (s *fakeSnapshot) Tables(ctx context.Context) ([]string, error) { return s.order, nil }
+
+func (s *fakeSnapshot) Dump(ctx context.Context, table string, fn func(row []byte) error) error {
+	for _, row := range s.tables[table] {
+		if err := fn([]byte(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeSnapshot) Close() error { return nil }
+
+// fakeRestore fails once the context it was begun with is done, as
+// database/sql rolls back such transactions
+type fakeRestore struct {
+	ctx       context.Context
+	db        *fakeDatabase
+	pending   map[string][]string
+	truncated []string
+}
+
+func (r *fakeRestore) RowCount(ctx context.Context, table string) (int64, error) {
+	return int64(len(r.db.tables[table])), nil
+}
+
+func (r *fakeRestore) Truncate(ctx context.Context, tables []string) error {
+	if r.db.gate != nil {
+		<-r.db.gate
+	}
+	r.truncated = tables
+	return r.ctx.Err()
+}
+
+func (r *fakeRestore) Insert(ctx context.Context, table string, rows []json.RawMessage) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		r.pending[table] = append(r.pending[table], string(row))
+	}
+	return nil
+}
+
+func (r *fakeRestore) Commit() error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	for _, table := range r.truncated {
+		r.db.tables[table] = nil
+	}
+	for table, rows := range r.pending {
+		r.db.add(table, rows...)
+	}
+	return nil
+}
+
+func (r *fakeRestore) Rollback() error { return nil }
+
+func newTestService(t *testing.T, db Database) (*Service, *storage.LocalStore) {
+	t.Helper()
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db, store, nil), store
+}
+
+func waitForJob(t *testing.T, s *Service, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := s.Job(id)
+		if !ok {
+			t.Fatalf("Job %s not found", id)
+		}
+		if job.Status != JobStatusRunning {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return nil
+}
+
+func seed() *fakeDatabase {
+	db := newFakeDatabase()
+	db.add("users", `{"id":"u1","email":"a@example.com"}`, `{"id":"u2","email":"b@example.com"}`)
+	db.add("expense_categories", `{"id":"c1","name":"Food"}`)
+	db.add("expenses", `{"id":"e1","user_id":"u1","amount":12.5}`)
+	db.add("audit_logs")
+	return db
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	source := seed()
+	service, store := newTestService(t, source)
+
+	manifest, err := service.Backup(ctx)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if !ValidID(manifest.ID) || manifest.TotalRows != 4 || len(manifest.Tables) != 4 {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+	if manifest.Tables[3].Name != "audit_logs" || manifest.Tables[3].Rows != 0 {
+		t.Errorf("Empty tables must still be listed, got %+v", manifest.Tables[3])
+	}
+
+	listed, err := service.List(ctx)
+	if err != nil || len(listed) != 1 || listed[0].ID != manifest.ID {
+		t.Fatalf("Expected the backup to be listed, got %+v, %v", listed, err)
+	}
+
+	// Restore into a database holding only seeded reference data
+	target := newFakeDatabase()
+	target.add("expense_categories", `{"id":"seed","name":"Seeded"}`)
+	restorer := NewService(target, store, nil)
+
+	job, err := restorer.StartRestore(ctx, manifest.ID, false)
+	if err != nil {
+		t.Fatalf("StartRestore failed: %v", err)
+	}
+	finished := waitForJob(t, restorer, job.ID)
+	if finished.Status != JobStatusSucceeded || finished.TablesDone != 4 || finished.RowsRestored != 4 {
+		t.Fatalf("Unexpected job %+v", finished)
+	}
+	for table, rows := range source.tables {
+		if len(rows) == 0 && len(target.tables[table]) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(target.tables[table], rows) {
+			t.Errorf("Table %s restored as %v, want %v", table, target.tables[table], rows)
+		}
+	}
+}
+
+func TestRestoreRefusesNonEmptyDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := seed()
+	service, _ := newTestService(t, db)
+
+	manifest, err := service.Backup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.StartRestore(ctx, manifest.ID, false); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("Expected ErrNotEmpty, got %v", err)
+	}
+
+	db.add("users", `{"id":"u3","email":"c@example.com"}`)
+	job, err := service.StartRestore(ctx, manifest.ID, true)
+	if err != nil {
+		t.Fatalf("Forced restore failed to start: %v", err)
+	}
+	if finished := waitForJob(t, service, job.ID); finished.Status != JobStatusSucceeded {
+		t.Fatalf("Forced restore failed: %+v", finished)
+	}
+	if len(db.tables["users"]) != 2 {
+		t.Errorf("Expected forced restore to replace users, got %v", db.tables["users"])
+	}
+
+	if _, err := service.StartRestore(ctx, "backup-unknown", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRestoreOutlivesRequestContext(t *testing.T) {
+	service, store := newTestService(t, seed())
+	manifest, err := service.Backup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := newFakeDatabase()
+	target.gate = make(chan struct{})
+	restorer := NewService(target, store, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job, err := restorer.StartRestore(ctx, manifest.ID, false)
+	if err != nil {
+		t.Fatalf("StartRestore failed: %v", err)
+	}
+	// The handler returns and its request context is cancelled before the
+	// restore gets going
+	cancel()
+	close(target.gate)
+
+	if finished := waitForJob(t, restorer, job.ID); finished.Status != JobStatusSucceeded {
+		t.Fatalf("Expected the restore to survive the request, got %+v", finished)
+	}
+	if len(target.tables["users"]) != 2 {
+		t.Errorf("Expected users to be restored, got %v", target.tables["users"])
+	}
+}
+
+func TestRestoreDetectsTamperedArchive(t *testing.T) {
+	ctx := context.Background()
+	service, store := newTestService(t, seed())
+	manifest, err := service.Backup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewrite the archive with a modified users table
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, table := range manifest.Tables {
+		w, _ := zw.Create(tableEntry(table.Name))
+		if table.Name == "users" {
+			io.WriteString(w, `{"id":"u1","email":"evil@example.com"}`+"\n"+`{"id":"u2","email":"b@example.com"}`+"\n")
+		}
+	}
+	zw.Close()
+	if err := store.Put(ctx, archiveKey(manifest.ID), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	target := newFakeDatabase()
+	restorer := NewService(target, store, nil)
+	job, err := restorer.StartRestore(ctx, manifest.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finished := waitForJob(t, restorer, job.ID)
+	if finished.Status != JobStatusFailed || finished.Error == "" {
+		t.Fatalf("Expected the restore to fail, got %+v", finished)
+	}
+	if len(target.tables["users"]) != 0 {
+		t.Errorf("A failed restore must not commit rows, got %v", target.tables["users"])
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	service, store := newTestService(t, seed())
+	fake := clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	service.WithClock(fake)
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		manifest, err := service.Backup(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, manifest.ID)
+		fake.Advance(time.Minute)
+	}
+
+	// The scheduled job takes a backup each interval and prunes to keep
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		service.RunScheduled(runCtx, time.Hour, 2)
+		close(done)
+	}()
+	fake.BlockUntil(1)
+	fake.Advance(time.Hour)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		listed, _ := service.List(ctx)
+		if len(listed) == 2 && listed[1].ID == ids[3] {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Scheduled backup did not prune, have %d backups", len(listed))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	listed, _ := service.List(ctx)
+	if len(listed) != 2 {
+		t.Fatalf("Expected 2 backups kept, got %d", len(listed))
+	}
+	if listed[1].ID != ids[3] {
+		t.Errorf("Expected the newest backups kept, got %s", listed[1].ID)
+	}
+	objects, _ := store.List(ctx, keyPrefix)
+	if len(objects) != 4 {
+		t.Errorf("Expected archives and manifests of pruned backups deleted, got %d objects", len(objects))
+	}
+}
+
+func TestOrderTables(t *testing.T) {
+	ordered, err := orderTables(
+		[]string{"expenses", "users", "expense_categories", "expense_splits"},
+		map[string][]string{
+			"expenses":       {"users", "expense_categories"},
+			"expense_splits": {"expenses", "users"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"expense_categories", "users", "expenses", "expense_splits"}
+	if !reflect.DeepEqual(ordered, want) {
+		t.Errorf("orderTables() = %v, want %v", ordered, want)
+	}
+
+	if _, err := orderTables([]string{"a", "b"}, map[string][]string{"a": {"b"}, "b": {"a"}}); err == nil {
+		t.Error("Expected a foreign key cycle to be reported")
+	}
+}
@@ -0,0 +1,135 @@
+package categorize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	fuel      = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	groceries = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	dining    = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+	transport = uuid.MustParse("00000000-0000-0000-0000-000000000004")
+)
+
+func TestNormalizeDescription(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"SHELL #4411 Oct-12", "shell"},
+		{"POS Purchase - Whole Foods Market 123", "whole foods market"},
+		{"  Café   Müller  ", "café müller"},
+		{"1234 5678", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeDescription(tt.input); got != tt.want {
+			t.Errorf("NormalizeDescription(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestIndexSuggest(t *testing.T) {
+	index := NewIndex([]Sample{
+		{"Shell #4411", fuel},
+		{"SHELL OIL 5521", fuel},
+		{"Shell station", fuel},
+		{"Whole Foods Market", groceries},
+		{"Shellfish Shack", dining},
+		{"Uber trip", transport},
+	})
+
+	suggestions := index.Suggest("Shell", DefaultLimit)
+	if len(suggestions) == 0 {
+		t.Fatal("Expected suggestions for Shell")
+	}
+	if suggestions[0].CategoryID != fuel {
+		t.Errorf("Expected Fuel first, got %+v", suggestions)
+	}
+	if suggestions[0].Source != SourceHistory {
+		t.Errorf("Expected history source, got %s", suggestions[0].Source)
+	}
+	if len(suggestions) > DefaultLimit {
+		t.Errorf("Expected at most %d suggestions, got %d", DefaultLimit, len(suggestions))
+	}
+	for i := 1; i < len(suggestions); i++ {
+		if suggestions[i].Confidence > suggestions[i-1].Confidence {
+			t.Errorf("Suggestions not ranked by confidence: %+v", suggestions)
+		}
+	}
+
+	if got := index.Suggest("Netflix subscription", DefaultLimit); len(got) != 0 {
+		t.Errorf("Unrelated description should have no history match, got %+v", got)
+	}
+}
+
+func TestPriorSuggest(t *testing.T) {
+	prior := NewPrior(map[uuid.UUID]int{groceries: 50, dining: 30, fuel: 15, transport: 5})
+	suggestions := prior.Suggest(DefaultLimit)
+
+	if len(suggestions) != 3 {
+		t.Fatalf("Expected 3 suggestions, got %d", len(suggestions))
+	}
+	if suggestions[0].CategoryID != groceries || suggestions[0].Confidence != 0.5 || suggestions[0].Source != SourceGlobal {
+		t.Errorf("Unexpected top prior suggestion: %+v", suggestions[0])
+	}
+}
+
+type fakeLoader struct {
+	history     map[uuid.UUID][]Sample
+	counts      map[uuid.UUID]int
+	historyLoad int
+}
+
+func (f *fakeLoader) LoadHistory(ctx context.Context, userID uuid.UUID) ([]Sample, error) {
+	f.historyLoad++
+	return f.history[userID], nil
+}
+
+func (f *fakeLoader) LoadCategoryCounts(ctx context.Context) (map[uuid.UUID]int, error) {
+	return f.counts, nil
+}
+
+func TestServiceFallbackAndInvalidation(t *testing.T) {
+	user := uuid.New()
+	newUser := uuid.New()
+	loader := &fakeLoader{
+		history: map[uuid.UUID][]Sample{user: {{"Shell", groceries}}},
+		counts:  map[uuid.UUID]int{dining: 10, fuel: 5},
+	}
+	service := NewService(loader)
+	ctx := context.Background()
+
+	// Brand-new users get the global prior
+	suggestions, err := service.Suggest(ctx, newUser, "Shell")
+	if err != nil {
+		t.Fatalf("Suggest() error = %v", err)
+	}
+	if len(suggestions) == 0 || suggestions[0].CategoryID != dining || suggestions[0].Source != SourceGlobal {
+		t.Errorf("Expected global prior for new user, got %+v", suggestions)
+	}
+
+	suggestions, _ = service.Suggest(ctx, user, "Shell")
+	if suggestions[0].CategoryID != groceries {
+		t.Errorf("Expected history-based suggestion, got %+v", suggestions)
+	}
+
+	// Cached until invalidated
+	loader.history[user] = []Sample{{"Shell", fuel}, {"Shell", fuel}}
+	suggestions, _ = service.Suggest(ctx, user, "Shell")
+	if suggestions[0].CategoryID != groceries {
+		t.Errorf("Expected cached suggestion before invalidation, got %+v", suggestions)
+	}
+
+	service.Invalidate(user)
+	suggestions, _ = service.Suggest(ctx, user, "Shell")
+	if suggestions[0].CategoryID != fuel {
+		t.Errorf("Expected recategorized suggestion after invalidation, got %+v", suggestions)
+	}
+	if loader.historyLoad != 3 {
+		t.Errorf("Expected 3 history loads (new user, user, after invalidation), got %d", loader.historyLoad)
+	}
+}
@@ -0,0 +1,197 @@
+package categorize
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the categorization rule endpoints
+type Handler struct {
+	rules *RuleService
+}
+
+// NewHandler creates a new categorization rule handler
+func NewHandler(rules *RuleService) *Handler {
+	return &Handler{rules: rules}
+}
+
+// List handles GET /api/v1/categorization-rules
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	rules, err := h.rules.List(r.Context(), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to list categorization rules")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"rules": rules})
+}
+
+// Create handles POST /api/v1/categorization-rules
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	var req models.CategorizationRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	rule, err := h.rules.Create(r.Context(), userID, &req)
+	if !writeRuleError(w, err) {
+		httputil.WriteJSON(w, http.StatusCreated, rule)
+	}
+}
+
+// Update handles PUT /api/v1/categorization-rules/{id}
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	ruleID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid rule ID")
+		return
+	}
+	var req models.CategorizationRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	rule, err := h.rules.Update(r.Context(), userID, ruleID, &req)
+	if !writeRuleError(w, err) {
+		httputil.WriteJSON(w, http.StatusOK, rule)
+	}
+}
+
+// Delete handles DELETE /api/v1/categorization-rules/{id}
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	ruleID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid rule ID")
+		return
+	}
+
+	if err := h.rules.Delete(r.Context(), userID, ruleID); !writeRuleError(w, err) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Preview handles POST /api/v1/categorization-rules/preview
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	var req models.CategorizationRuleApplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	previews, err := h.rules.Preview(r.Context(), userID, req.RuleIDs)
+	if !writeRuleError(w, err) {
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"previews": previews})
+	}
+}
+
+// Apply handles POST /api/v1/categorization-rules/apply
+func (h *Handler) Apply(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	var req models.CategorizationRuleApplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	job, err := h.rules.StartApply(r.Context(), userID, &req)
+	if !writeRuleError(w, err) {
+		httputil.WriteJSON(w, http.StatusAccepted, job)
+	}
+}
+
+// Revert handles POST /api/v1/categorization-rules/applies/{id}/revert
+func (h *Handler) Revert(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	applyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid apply ID")
+		return
+	}
+
+	job, err := h.rules.StartRevert(r.Context(), userID, applyID)
+	if !writeRuleError(w, err) {
+		httputil.WriteJSON(w, http.StatusAccepted, job)
+	}
+}
+
+// Job handles GET /api/v1/categorization-rules/jobs/{id}
+func (h *Handler) Job(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	jobID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid job ID")
+		return
+	}
+
+	job, ok := h.rules.Job(userID, jobID)
+	if !ok {
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Job not found")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, job)
+}
+
+// writeRuleError writes the response for a rule service error and returns
+// false if there was none
+func writeRuleError(w http.ResponseWriter, err error) bool {
+	var errs utils.ValidationErrors
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrRuleNotFound):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Categorization rule not found")
+	case errors.Is(err, ErrRuleConflict):
+		httputil.WriteErrorFrom(w, http.StatusConflict, httputil.CodeForStatus(http.StatusConflict), err)
+	default:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to process categorization rules")
+	}
+	return true
+}
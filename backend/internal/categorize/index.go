@@ -0,0 +1,240 @@
+package categorize
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// Suggestion sources
+const (
+	SourceHistory = "history"
+	SourceGlobal  = "global"
+)
+
+// DefaultLimit is the number of suggestions returned
+const DefaultLimit = 3
+
+// minSimilarity is the lowest trigram similarity treated as a match
+const minSimilarity = 0.3
+
+// Sample is a previously categorized expense description
+type Sample struct {
+	Description string
+	CategoryID  uuid.UUID
+}
+
+// entry is a normalized sample with its trigram set
+type entry struct {
+	normalized string
+	trigrams   map[string]struct{}
+	categoryID uuid.UUID
+	count      int
+}
+
+// Index suggests categories from a user's categorization history
+type Index struct {
+	entries []entry
+}
+
+// NewIndex builds an index from samples, merging identical normalized descriptions
+func NewIndex(samples []Sample) *Index {
+	byKey := make(map[string]int)
+	index := &Index{}
+
+	for _, sample := range samples {
+		normalized := NormalizeDescription(sample.Description)
+		if normalized == "" {
+			continue
+		}
+		key := normalized + "|" + sample.CategoryID.String()
+		if i, ok := byKey[key]; ok {
+			index.entries[i].count++
+			continue
+		}
+		byKey[key] = len(index.entries)
+		index.entries = append(index.entries, entry{
+			normalized: normalized,
+			trigrams:   trigrams(normalized),
+			categoryID: sample.CategoryID,
+			count:      1,
+		})
+	}
+
+	return index
+}
+
+// Len returns the number of distinct description/category pairs in the index
+func (idx *Index) Len() int {
+	return len(idx.entries)
+}
+
+// Suggest returns up to limit categories for description, ranked by the
+// similarity-weighted frequency of matching historical descriptions
+func (idx *Index) Suggest(description string, limit int) []models.CategorySuggestion {
+	normalized := NormalizeDescription(description)
+	if normalized == "" || len(idx.entries) == 0 {
+		return nil
+	}
+	query := trigrams(normalized)
+
+	scores := make(map[uuid.UUID]float64)
+	total := 0.0
+	for _, e := range idx.entries {
+		similarity := jaccard(query, e.trigrams)
+		if strings.Contains(e.normalized, normalized) || strings.Contains(normalized, e.normalized) {
+			similarity = maxFloat(similarity, 0.8)
+		}
+		if similarity < minSimilarity {
+			continue
+		}
+		weight := similarity * float64(e.count)
+		scores[e.categoryID] += weight
+		total += weight
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	// Confidence is the category's share of the matching weight, scaled by how
+	// close the best match was so weak matches never look certain
+	best := 0.0
+	for _, e := range idx.entries {
+		best = maxFloat(best, jaccard(query, e.trigrams))
+	}
+	best = maxFloat(best, minSimilarity)
+
+	suggestions := make([]models.CategorySuggestion, 0, len(scores))
+	for categoryID, score := range scores {
+		suggestions = append(suggestions, models.CategorySuggestion{
+			CategoryID: categoryID,
+			Confidence: roundConfidence(score / total * best),
+			Source:     SourceHistory,
+		})
+	}
+	return rank(suggestions, limit)
+}
+
+// Prior suggests categories from overall category frequencies, used for users
+// without relevant history
+type Prior struct {
+	counts map[uuid.UUID]int
+	total  int
+}
+
+// NewPrior builds a prior from category usage counts across all users
+func NewPrior(counts map[uuid.UUID]int) *Prior {
+	prior := &Prior{counts: make(map[uuid.UUID]int, len(counts))}
+	for categoryID, count := range counts {
+		if count > 0 {
+			prior.counts[categoryID] = count
+			prior.total += count
+		}
+	}
+	return prior
+}
+
+// Suggest returns the most frequently used categories
+func (p *Prior) Suggest(limit int) []models.CategorySuggestion {
+	if p == nil || p.total == 0 {
+		return nil
+	}
+
+	suggestions := make([]models.CategorySuggestion, 0, len(p.counts))
+	for categoryID, count := range p.counts {
+		suggestions = append(suggestions, models.CategorySuggestion{
+			CategoryID: categoryID,
+			Confidence: roundConfidence(float64(count) / float64(p.total)),
+			Source:     SourceGlobal,
+		})
+	}
+	return rank(suggestions, limit)
+}
+
+// NormalizeDescription lowercases a description and strips digits, punctuation
+// and reference noise so "SHELL #4411 Oct-12" and "shell" compare equal
+func NormalizeDescription(description string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(description) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+
+	tokens := strings.Fields(b.String())
+	kept := tokens[:0]
+	for _, token := range tokens {
+		if len([]rune(token)) < 2 || noiseTokens[token] {
+			continue
+		}
+		kept = append(kept, token)
+	}
+	return strings.Join(kept, " ")
+}
+
+// noiseTokens are common bank-statement words that carry no category signal
+var noiseTokens = map[string]bool{
+	"pos": true, "purchase": true, "payment": true, "card": true, "debit": true,
+	"credit": true, "txn": true, "ref": true, "the": true, "and": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "may": true, "jun": true,
+	"jul": true, "aug": true, "sep": true, "oct": true, "nov": true, "dec": true,
+}
+
+// trigrams returns the padded character trigrams of each token
+func trigrams(normalized string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, token := range strings.Fields(normalized) {
+		runes := []rune("  " + token + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// jaccard returns the Jaccard similarity of two trigram sets
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for gram := range a {
+		if _, ok := b[gram]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// rank sorts suggestions by confidence (ties by category ID for determinism)
+// and truncates them to limit
+func rank(suggestions []models.CategorySuggestion, limit int) []models.CategorySuggestion {
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].CategoryID.String() < suggestions[j].CategoryID.String()
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+func roundConfidence(value float64) float64 {
+	return float64(int(value*1000+0.5)) / 1000
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
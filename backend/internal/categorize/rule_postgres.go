@@ -0,0 +1,215 @@
+package categorize
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// ruleColumns selects a categorization rule
+const ruleColumns = `id, user_id, name, description_pattern, merchant, min_amount::float8, max_amount::float8,
+	payment_method, category_id, priority, is_active, created_at, updated_at`
+
+// unlockedExpenses restricts expenses to those outside locked periods, which
+// rules must not rewrite
+const unlockedExpenses = `NOT EXISTS (
+	SELECT 1 FROM period_closes pc
+	WHERE pc.user_id = e.user_id AND pc.status = 'locked'
+		AND pc.period = date_trunc('month', e.expense_date)::date)`
+
+// PostgresRuleStore persists categorization rules in PostgreSQL
+type PostgresRuleStore struct {
+	db *sql.DB
+}
+
+// NewPostgresRuleStore creates a new PostgreSQL categorization rule store
+func NewPostgresRuleStore(db *sql.DB) *PostgresRuleStore {
+	return &PostgresRuleStore{db: db}
+}
+
+// ListRules returns the user's rules, oldest first
+func (s *PostgresRuleStore) ListRules(ctx context.Context, userID uuid.UUID) ([]models.CategorizationRule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+ruleColumns+` FROM categorization_rules
+		WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.CategorizationRule{}
+	for rows.Next() {
+		var r models.CategorizationRule
+		if err := rows.Scan(&r.ID, &r.UserID, &r.Name, &r.DescriptionPattern, &r.Merchant, &r.MinAmount, &r.MaxAmount,
+			&r.PaymentMethod, &r.CategoryID, &r.Priority, &r.IsActive, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// CreateRule stores a new rule
+func (s *PostgresRuleStore) CreateRule(ctx context.Context, r *models.CategorizationRule) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO categorization_rules (id, user_id, name, description_pattern, merchant, min_amount, max_amount,
+			payment_method, category_id, priority, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		r.ID, r.UserID, r.Name, r.DescriptionPattern, r.Merchant, r.MinAmount, r.MaxAmount,
+		r.PaymentMethod, r.CategoryID, r.Priority, r.IsActive, r.CreatedAt, r.UpdatedAt)
+	return err
+}
+
+// UpdateRule replaces a rule
+func (s *PostgresRuleStore) UpdateRule(ctx context.Context, r *models.CategorizationRule) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE categorization_rules
+		SET name = $3, description_pattern = $4, merchant = $5, min_amount = $6, max_amount = $7,
+			payment_method = $8, category_id = $9, priority = $10, is_active = $11
+		WHERE id = $1 AND user_id = $2`,
+		r.ID, r.UserID, r.Name, r.DescriptionPattern, r.Merchant, r.MinAmount, r.MaxAmount,
+		r.PaymentMethod, r.CategoryID, r.Priority, r.IsActive)
+	return err
+}
+
+// DeleteRule removes a rule
+func (s *PostgresRuleStore) DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM categorization_rules WHERE id = $1 AND user_id = $2`, ruleID, userID)
+	return err
+}
+
+// CountExpenses returns the number of the user's expenses outside locked periods
+func (s *PostgresRuleStore) CountExpenses(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM expenses e WHERE e.user_id = $1 AND `+unlockedExpenses, userID).Scan(&count)
+	return count, err
+}
+
+// ListExpenses returns a page of the user's expenses outside locked periods
+func (s *PostgresRuleStore) ListExpenses(ctx context.Context, userID, after uuid.UUID, limit int) ([]models.Expense, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id, e.user_id, e.category_id, e.amount::float8, e.description, e.payment_method
+		FROM expenses e
+		WHERE e.user_id = $1 AND e.id > $2 AND `+unlockedExpenses+`
+		ORDER BY e.id
+		LIMIT $3`, userID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []models.Expense
+	for rows.Next() {
+		var e models.Expense
+		if err := rows.Scan(&e.ID, &e.UserID, &e.CategoryID, &e.Amount, &e.Description, &e.PaymentMethod); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, rows.Err()
+}
+
+// Recategorize updates the expenses, records the changes and writes the
+// audit entries in one transaction
+func (s *PostgresRuleStore) Recategorize(ctx context.Context, changes []models.RuleChange, audit []models.AuditLog) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range changes {
+			if _, err := tx.ExecContext(ctx, `UPDATE expenses SET category_id = $2, version = version + 1 WHERE id = $1`, c.ExpenseID, c.NewCategoryID); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO categorization_rule_changes (id, apply_id, user_id, rule_id, expense_id, old_category_id, new_category_id, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+				c.ID, c.ApplyID, c.UserID, c.RuleID, c.ExpenseID, c.OldCategoryID, c.NewCategoryID, c.CreatedAt); err != nil {
+				return err
+			}
+		}
+		return insertAudit(ctx, tx, audit)
+	})
+}
+
+// CountChanges returns the number of changes of an apply not yet reverted
+func (s *PostgresRuleStore) CountChanges(ctx context.Context, userID, applyID uuid.UUID) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM categorization_rule_changes
+		WHERE user_id = $1 AND apply_id = $2 AND reverted_at IS NULL`, userID, applyID).Scan(&count)
+	return count, err
+}
+
+// ListChanges returns a page of the changes of an apply not yet reverted,
+// with whether each expense still has the category the rule gave it
+func (s *PostgresRuleStore) ListChanges(ctx context.Context, userID, applyID, after uuid.UUID, limit int) ([]models.RuleChange, []bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.id, c.apply_id, c.user_id, COALESCE(c.rule_id, '00000000-0000-0000-0000-000000000000'), c.expense_id,
+			c.old_category_id, c.new_category_id, c.created_at, e.category_id = c.new_category_id
+		FROM categorization_rule_changes c
+		JOIN expenses e ON e.id = c.expense_id
+		WHERE c.user_id = $1 AND c.apply_id = $2 AND c.id > $3 AND c.reverted_at IS NULL
+		ORDER BY c.id
+		LIMIT $4`, userID, applyID, after, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.RuleChange
+	var current []bool
+	for rows.Next() {
+		var c models.RuleChange
+		var unchanged bool
+		if err := rows.Scan(&c.ID, &c.ApplyID, &c.UserID, &c.RuleID, &c.ExpenseID,
+			&c.OldCategoryID, &c.NewCategoryID, &c.CreatedAt, &unchanged); err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, c)
+		current = append(current, unchanged)
+	}
+	return changes, current, rows.Err()
+}
+
+// Revert restores the old categories of changes, marks them reverted and
+// writes the audit entries in one transaction
+func (s *PostgresRuleStore) Revert(ctx context.Context, changes []models.RuleChange, audit []models.AuditLog, at time.Time) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range changes {
+			if _, err := tx.ExecContext(ctx, `UPDATE expenses SET category_id = $2, version = version + 1 WHERE id = $1`, c.ExpenseID, c.OldCategoryID); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE categorization_rule_changes SET reverted_at = $2 WHERE id = $1`, c.ID, at); err != nil {
+				return err
+			}
+		}
+		return insertAudit(ctx, tx, audit)
+	})
+}
+
+// inTx runs fn in a transaction, committing if it succeeds
+func (s *PostgresRuleStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertAudit writes audit log entries
+func insertAudit(ctx context.Context, tx *sql.Tx, entries []models.AuditLog) error {
+	for _, a := range entries {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO audit_logs (id, user_id, actor_user_id, action, entity_type, entity_id, old_value, new_value, reason, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			a.ID, a.UserID, a.ActorUserID, a.Action, a.EntityType, a.EntityID,
+			[]byte(a.OldValue), []byte(a.NewValue), a.Reason, a.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
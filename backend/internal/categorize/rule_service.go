@@ -0,0 +1,494 @@
+package categorize
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+)
+
+// ApplyBatchSize is the number of expenses re-categorized per transaction
+const ApplyBatchSize = 200
+
+// Errors returned by the rule service
+var (
+	ErrRuleNotFound = errors.New("categorization rule not found")
+	ErrRuleConflict = errors.New("categorization rule conflicts with an existing rule")
+)
+
+// ConflictError names the rule a new or changed rule conflicts with
+type ConflictError struct {
+	Rule models.CategorizationRule
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("rule overlaps %q with the same priority and a different category", e.Rule.Name)
+}
+
+// Is makes errors.Is(err, ErrRuleConflict) match
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrRuleConflict
+}
+
+// RuleStore persists categorization rules and the changes made applying them
+type RuleStore interface {
+	ListRules(ctx context.Context, userID uuid.UUID) ([]models.CategorizationRule, error)
+	CreateRule(ctx context.Context, rule *models.CategorizationRule) error
+	UpdateRule(ctx context.Context, rule *models.CategorizationRule) error
+	DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error
+
+	CountExpenses(ctx context.Context, userID uuid.UUID) (int, error)
+	// ListExpenses returns up to limit of the user's expenses with IDs above
+	// after, ordered by ID
+	ListExpenses(ctx context.Context, userID, after uuid.UUID, limit int) ([]models.Expense, error)
+	// Recategorize updates the expenses, records the changes and writes the
+	// audit entries in one transaction
+	Recategorize(ctx context.Context, changes []models.RuleChange, audit []models.AuditLog) error
+
+	// CountChanges returns the number of changes of an apply not yet reverted
+	CountChanges(ctx context.Context, userID, applyID uuid.UUID) (int, error)
+	// ListChanges returns up to limit changes of an apply with IDs above
+	// after that are not reverted, ordered by ID, along with whether each
+	// expense still has the category the rule gave it
+	ListChanges(ctx context.Context, userID, applyID, after uuid.UUID, limit int) ([]models.RuleChange, []bool, error)
+	// Revert restores the old categories of changes, marks them reverted and
+	// writes the audit entries in one transaction
+	Revert(ctx context.Context, changes []models.RuleChange, audit []models.AuditLog, at time.Time) error
+}
+
+type ruleJob struct {
+	userID uuid.UUID
+	job    models.RuleJob
+}
+
+// RuleService manages a user's categorization rules and applies them to
+// existing expenses. Applying and reverting run as background jobs in
+// batched transactions, and every affected expense is audit-logged with the
+// apply it belongs to so the whole apply can be reverted.
+type RuleService struct {
+	store       RuleStore
+	suggestions *Service
+	clock       clock.Clock
+
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*ruleJob
+}
+
+// NewRuleService creates a new categorization rule service. suggestions may
+// be nil; otherwise its per-user index is invalidated after re-categorizing.
+func NewRuleService(store RuleStore, suggestions *Service) *RuleService {
+	return &RuleService{
+		store:       store,
+		suggestions: suggestions,
+		clock:       clock.Real(),
+		jobs:        make(map[uuid.UUID]*ruleJob),
+	}
+}
+
+// List returns the user's rules
+func (s *RuleService) List(ctx context.Context, userID uuid.UUID) ([]models.CategorizationRule, error) {
+	rules, err := s.store.ListRules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categorization rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Engine returns the engine over the user's active rules
+func (s *RuleService) Engine(ctx context.Context, userID uuid.UUID) (*Engine, error) {
+	rules, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(rules), nil
+}
+
+// Create validates and stores a new rule
+func (s *RuleService) Create(ctx context.Context, userID uuid.UUID, req *models.CategorizationRuleRequest) (*models.CategorizationRule, error) {
+	if errs := ValidateRuleRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+	now := s.clock.Now().UTC()
+	rule := &models.CategorizationRule{ID: uuid.New(), UserID: userID, IsActive: true, CreatedAt: now}
+	applyRequest(rule, req, now)
+
+	if err := s.checkConflicts(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create categorization rule: %w", err)
+	}
+	return rule, nil
+}
+
+// Update replaces a rule
+func (s *RuleService) Update(ctx context.Context, userID, ruleID uuid.UUID, req *models.CategorizationRuleRequest) (*models.CategorizationRule, error) {
+	if errs := ValidateRuleRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+	rule, err := s.get(ctx, userID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	applyRequest(rule, req, s.clock.Now().UTC())
+
+	if err := s.checkConflicts(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := s.store.UpdateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to update categorization rule: %w", err)
+	}
+	return rule, nil
+}
+
+// Delete removes a rule. Changes it already made stay revertible.
+func (s *RuleService) Delete(ctx context.Context, userID, ruleID uuid.UUID) error {
+	if _, err := s.get(ctx, userID, ruleID); err != nil {
+		return err
+	}
+	if err := s.store.DeleteRule(ctx, userID, ruleID); err != nil {
+		return fmt.Errorf("failed to delete categorization rule: %w", err)
+	}
+	return nil
+}
+
+// Preview reports how many existing expenses each selected rule matches and
+// would re-categorize, taking higher-priority rules into account
+func (s *RuleService) Preview(ctx context.Context, userID uuid.UUID, ruleIDs []uuid.UUID) ([]models.RulePreview, error) {
+	engine, selected, err := s.selectRules(ctx, userID, ruleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]models.RulePreview, len(selected))
+	index := make(map[uuid.UUID]int, len(selected))
+	for i, rule := range selected {
+		previews[i].RuleID = rule.ID
+		index[rule.ID] = i
+	}
+
+	err = s.eachBatch(ctx, userID, func(expenses []models.Expense) error {
+		for i := range expenses {
+			expense := &expenses[i]
+			candidate := candidateOf(expense)
+			for j := range selected {
+				if Matches(&selected[j], candidate) {
+					previews[j].Matches++
+				}
+			}
+			if winner := engine.Match(candidate); winner != nil && winner.CategoryID != expense.CategoryID {
+				if k, ok := index[winner.ID]; ok {
+					previews[k].Changes++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previews, nil
+}
+
+// StartApply re-categorizes the user's existing expenses with the selected
+// rules, or every active rule, in the background. Where rules overlap the
+// highest priority wins, even if it was not selected.
+func (s *RuleService) StartApply(ctx context.Context, userID uuid.UUID, req *models.CategorizationRuleApplyRequest) (*models.RuleJob, error) {
+	engine, selected, err := s.selectRules(ctx, userID, req.RuleIDs)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.store.CountExpenses(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count expenses: %w", err)
+	}
+
+	applying := make(map[uuid.UUID]bool, len(selected))
+	for _, rule := range selected {
+		applying[rule.ID] = true
+	}
+	job := s.startJob(userID, models.RuleJobApply, uuid.New(), total)
+	go s.finish(userID, job.ID, func(ctx context.Context) error {
+		return s.apply(ctx, userID, job, engine, applying)
+	})
+	return job, nil
+}
+
+// StartRevert restores the categories an apply changed, in the background.
+// Expenses whose category was changed again since are left alone.
+func (s *RuleService) StartRevert(ctx context.Context, userID, applyID uuid.UUID) (*models.RuleJob, error) {
+	total, err := s.store.CountChanges(ctx, userID, applyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rule changes: %w", err)
+	}
+	if total == 0 {
+		return nil, ErrRuleNotFound
+	}
+
+	job := s.startJob(userID, models.RuleJobRevert, applyID, total)
+	go s.finish(userID, job.ID, func(ctx context.Context) error {
+		return s.revert(ctx, userID, job)
+	})
+	return job, nil
+}
+
+// Job returns the progress of one of the user's rule jobs
+func (s *RuleService) Job(userID, jobID uuid.UUID) (*models.RuleJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.jobs[jobID]
+	if !ok || entry.userID != userID {
+		return nil, false
+	}
+	job := entry.job
+	return &job, true
+}
+
+// apply re-categorizes the user's expenses batch by batch
+func (s *RuleService) apply(ctx context.Context, userID uuid.UUID, job *models.RuleJob, engine *Engine, applying map[uuid.UUID]bool) error {
+	return s.eachBatch(ctx, userID, func(expenses []models.Expense) error {
+		now := s.clock.Now().UTC()
+		var changes []models.RuleChange
+		var audit []models.AuditLog
+		for i := range expenses {
+			expense := &expenses[i]
+			rule := engine.Match(candidateOf(expense))
+			if rule == nil || !applying[rule.ID] || rule.CategoryID == expense.CategoryID {
+				continue
+			}
+			change := models.RuleChange{
+				ID:            uuid.New(),
+				ApplyID:       job.ApplyID,
+				UserID:        userID,
+				RuleID:        rule.ID,
+				ExpenseID:     expense.ID,
+				OldCategoryID: expense.CategoryID,
+				NewCategoryID: rule.CategoryID,
+				CreatedAt:     now,
+			}
+			changes = append(changes, change)
+			audit = append(audit, changeAudit(change, models.AuditActionRuleRecategorize, change.OldCategoryID, change.NewCategoryID,
+				fmt.Sprintf("rule %q (apply %s)", rule.Name, job.ApplyID), now))
+		}
+
+		if len(changes) > 0 {
+			if err := s.store.Recategorize(ctx, changes, audit); err != nil {
+				return fmt.Errorf("failed to re-categorize expenses: %w", err)
+			}
+		}
+		s.progress(job.ID, len(expenses), len(changes))
+		return nil
+	})
+}
+
+// revert restores the categories of an apply batch by batch
+func (s *RuleService) revert(ctx context.Context, userID uuid.UUID, job *models.RuleJob) error {
+	after := uuid.Nil
+	for {
+		changes, current, err := s.store.ListChanges(ctx, userID, job.ApplyID, after, ApplyBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list rule changes: %w", err)
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		after = changes[len(changes)-1].ID
+
+		now := s.clock.Now().UTC()
+		var reverting []models.RuleChange
+		var audit []models.AuditLog
+		for i, change := range changes {
+			if !current[i] {
+				continue
+			}
+			reverting = append(reverting, change)
+			audit = append(audit, changeAudit(change, models.AuditActionRuleRevert, change.NewCategoryID, change.OldCategoryID,
+				fmt.Sprintf("revert of apply %s", job.ApplyID), now))
+		}
+		if len(reverting) > 0 {
+			if err := s.store.Revert(ctx, reverting, audit, now); err != nil {
+				return fmt.Errorf("failed to revert rule changes: %w", err)
+			}
+		}
+		s.progress(job.ID, len(changes), len(reverting))
+	}
+}
+
+// eachBatch calls fn with the user's expenses, ApplyBatchSize at a time
+func (s *RuleService) eachBatch(ctx context.Context, userID uuid.UUID, fn func([]models.Expense) error) error {
+	after := uuid.Nil
+	for {
+		expenses, err := s.store.ListExpenses(ctx, userID, after, ApplyBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list expenses: %w", err)
+		}
+		if len(expenses) == 0 {
+			return nil
+		}
+		if err := fn(expenses); err != nil {
+			return err
+		}
+		if len(expenses) < ApplyBatchSize {
+			return nil
+		}
+		after = expenses[len(expenses)-1].ID
+	}
+}
+
+// selectRules returns the engine over the user's active rules and the
+// selected active rules, all of them when ruleIDs is empty
+func (s *RuleService) selectRules(ctx context.Context, userID uuid.UUID, ruleIDs []uuid.UUID) (*Engine, []models.CategorizationRule, error) {
+	rules, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	engine := NewEngine(rules)
+	if len(ruleIDs) == 0 {
+		return engine, engine.rules, nil
+	}
+
+	byID := make(map[uuid.UUID]models.CategorizationRule, len(rules))
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+	}
+	selected := make([]models.CategorizationRule, 0, len(ruleIDs))
+	for _, id := range ruleIDs {
+		rule, ok := byID[id]
+		if !ok || !rule.IsActive {
+			return nil, nil, ErrRuleNotFound
+		}
+		selected = append(selected, rule)
+	}
+	return engine, selected, nil
+}
+
+// get returns one of the user's rules
+func (s *RuleService) get(ctx context.Context, userID, ruleID uuid.UUID) (*models.CategorizationRule, error) {
+	rules, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].ID == ruleID {
+			return &rules[i], nil
+		}
+	}
+	return nil, ErrRuleNotFound
+}
+
+// checkConflicts rejects a rule overlapping another active rule of the same
+// priority that assigns a different category
+func (s *RuleService) checkConflicts(ctx context.Context, rule *models.CategorizationRule) error {
+	rules, err := s.List(ctx, rule.UserID)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if Conflicts(rule, &rules[i]) {
+			return &ConflictError{Rule: rules[i]}
+		}
+	}
+	return nil
+}
+
+// startJob registers a running job
+func (s *RuleService) startJob(userID uuid.UUID, kind string, applyID uuid.UUID, total int) *models.RuleJob {
+	job := models.RuleJob{
+		ID:        uuid.New(),
+		Kind:      kind,
+		ApplyID:   applyID,
+		Status:    models.RuleJobRunning,
+		Total:     total,
+		StartedAt: s.clock.Now().UTC(),
+	}
+	s.mu.Lock()
+	s.jobs[job.ID] = &ruleJob{userID: userID, job: job}
+	s.mu.Unlock()
+	return &job
+}
+
+// progress adds processed and changed expenses to a job
+func (s *RuleService) progress(jobID uuid.UUID, processed, changed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.jobs[jobID]; ok {
+		entry.job.Processed += processed
+		entry.job.Changed += changed
+	}
+}
+
+// finish runs a job to completion and records its outcome. Committed batches
+// stay applied when a later batch fails.
+func (s *RuleService) finish(userID, jobID uuid.UUID, run func(ctx context.Context) error) {
+	err := run(context.Background())
+	if s.suggestions != nil {
+		s.suggestions.Invalidate(userID)
+	}
+
+	finished := s.clock.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.jobs[jobID]
+	entry.job.FinishedAt = &finished
+	if err != nil {
+		entry.job.Status = models.RuleJobFailed
+		entry.job.Error = err.Error()
+	} else {
+		entry.job.Status = models.RuleJobSucceeded
+	}
+}
+
+// applyRequest copies a request onto a rule
+func applyRequest(rule *models.CategorizationRule, req *models.CategorizationRuleRequest, now time.Time) {
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.DescriptionPattern = trimmed(req.DescriptionPattern)
+	rule.Merchant = trimmed(req.Merchant)
+	rule.MinAmount = req.MinAmount
+	rule.MaxAmount = req.MaxAmount
+	rule.PaymentMethod = trimmed(req.PaymentMethod)
+	rule.CategoryID = req.CategoryID
+	rule.Priority = req.Priority
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	rule.UpdatedAt = now
+}
+
+// changeAudit returns the audit entry of one expense's category change
+func changeAudit(change models.RuleChange, action string, from, to uuid.UUID, reason string, at time.Time) models.AuditLog {
+	oldValue, _ := json.Marshal(from)
+	newValue, _ := json.Marshal(to)
+	return models.AuditLog{
+		ID:          uuid.New(),
+		UserID:      &change.UserID,
+		ActorUserID: &change.UserID,
+		Action:      action,
+		EntityType:  "expense",
+		EntityID:    &change.ExpenseID,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		Reason:      &reason,
+		CreatedAt:   at,
+	}
+}
+
+// candidateOf returns the rule candidate of an expense
+func candidateOf(expense *models.Expense) Candidate {
+	return Candidate{Description: expense.Description, Amount: expense.Amount, PaymentMethod: expense.PaymentMethod}
+}
+
+func trimmed(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*value)
+	return &v
+}
@@ -0,0 +1,224 @@
+package categorize
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+// SourceRule marks suggestions made by a user's categorization rule
+const SourceRule = "rule"
+
+// Candidate is the part of an expense categorization rules match on
+type Candidate struct {
+	Description   string
+	Amount        float64
+	PaymentMethod *string
+}
+
+// Matches returns true if the candidate meets every criterion of the rule
+func Matches(rule *models.CategorizationRule, c Candidate) bool {
+	if rule.DescriptionPattern != nil && !globMatch(strings.ToLower(*rule.DescriptionPattern), strings.ToLower(strings.TrimSpace(c.Description))) {
+		return false
+	}
+	if rule.Merchant != nil && NormalizeDescription(*rule.Merchant) != NormalizeDescription(c.Description) {
+		return false
+	}
+	if rule.MinAmount != nil && c.Amount < *rule.MinAmount {
+		return false
+	}
+	if rule.MaxAmount != nil && c.Amount > *rule.MaxAmount {
+		return false
+	}
+	if rule.PaymentMethod != nil && (c.PaymentMethod == nil || !strings.EqualFold(*rule.PaymentMethod, *c.PaymentMethod)) {
+		return false
+	}
+	return true
+}
+
+// Overlaps returns true if some expense could match both rules. Description
+// patterns are compared exactly; a pattern and a merchant are assumed to
+// overlap.
+func Overlaps(a, b *models.CategorizationRule) bool {
+	if a.DescriptionPattern != nil && b.DescriptionPattern != nil &&
+		!globsIntersect(strings.ToLower(*a.DescriptionPattern), strings.ToLower(*b.DescriptionPattern)) {
+		return false
+	}
+	if a.Merchant != nil && b.Merchant != nil && NormalizeDescription(*a.Merchant) != NormalizeDescription(*b.Merchant) {
+		return false
+	}
+	if a.PaymentMethod != nil && b.PaymentMethod != nil && !strings.EqualFold(*a.PaymentMethod, *b.PaymentMethod) {
+		return false
+	}
+	if a.MinAmount != nil && b.MaxAmount != nil && *a.MinAmount > *b.MaxAmount {
+		return false
+	}
+	if b.MinAmount != nil && a.MaxAmount != nil && *b.MinAmount > *a.MaxAmount {
+		return false
+	}
+	return true
+}
+
+// Conflicts returns true if the rules overlap with the same priority but
+// assign different categories, so neither could be said to win
+func Conflicts(a, b *models.CategorizationRule) bool {
+	return a.ID != b.ID && a.IsActive && b.IsActive && a.Priority == b.Priority &&
+		a.CategoryID != b.CategoryID && Overlaps(a, b)
+}
+
+// Engine picks the rule categorizing an expense from a user's active rules
+type Engine struct {
+	rules []models.CategorizationRule
+}
+
+// NewEngine creates an engine over rules, ignoring inactive ones. Higher
+// priorities are tried first, then older rules.
+func NewEngine(rules []models.CategorizationRule) *Engine {
+	active := make([]models.CategorizationRule, 0, len(rules))
+	for _, r := range rules {
+		if r.IsActive {
+			active = append(active, r)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[i].Priority != active[j].Priority {
+			return active[i].Priority > active[j].Priority
+		}
+		return active[i].CreatedAt.Before(active[j].CreatedAt)
+	})
+	return &Engine{rules: active}
+}
+
+// Match returns the winning rule for the candidate, or nil
+func (e *Engine) Match(c Candidate) *models.CategorizationRule {
+	if e == nil {
+		return nil
+	}
+	for i := range e.rules {
+		if Matches(&e.rules[i], c) {
+			return &e.rules[i]
+		}
+	}
+	return nil
+}
+
+// Len returns the number of active rules
+func (e *Engine) Len() int {
+	if e == nil {
+		return 0
+	}
+	return len(e.rules)
+}
+
+// ValidateRuleRequest validates a categorization rule request
+func ValidateRuleRequest(req *models.CategorizationRuleRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if strings.TrimSpace(req.Name) == "" {
+		errs.Add("name", "name is required")
+	} else if len(req.Name) > 100 {
+		errs.Add("name", "name must be at most 100 characters")
+	}
+	if req.CategoryID == uuid.Nil {
+		errs.Add("category_id", "category_id is required")
+	}
+
+	criteria := 0
+	if req.DescriptionPattern != nil {
+		criteria++
+		if pattern := strings.TrimSpace(*req.DescriptionPattern); pattern == "" || strings.Trim(pattern, "*") == "" {
+			errs.Add("description_pattern", "description_pattern must contain more than wildcards")
+		} else if len(pattern) > 255 {
+			errs.Add("description_pattern", "description_pattern must be at most 255 characters")
+		}
+	}
+	if req.Merchant != nil {
+		criteria++
+		if NormalizeDescription(*req.Merchant) == "" {
+			errs.Add("merchant", "merchant must contain letters")
+		}
+	}
+	if req.PaymentMethod != nil {
+		criteria++
+		if strings.TrimSpace(*req.PaymentMethod) == "" {
+			errs.Add("payment_method", "payment_method must not be empty")
+		}
+	}
+	if req.MinAmount != nil {
+		criteria++
+		if *req.MinAmount < 0 {
+			errs.Add("min_amount", "min_amount must not be negative")
+		}
+	}
+	if req.MaxAmount != nil {
+		criteria++
+		if req.MinAmount != nil && *req.MaxAmount < *req.MinAmount {
+			errs.Add("max_amount", "max_amount must not be below min_amount")
+		}
+	}
+	if criteria == 0 {
+		errs.Add("match", "at least one of description_pattern, merchant, min_amount, max_amount or payment_method is required")
+	}
+	return errs
+}
+
+// globMatch reports whether s matches pattern, where * matches any run of
+// characters and everything else matches itself
+func globMatch(pattern, s string) bool {
+	p, str := []rune(pattern), []rune(s)
+	pi, si := 0, 0
+	star, mark := -1, 0
+	for si < len(str) {
+		switch {
+		case pi < len(p) && p[pi] == '*':
+			star, mark = pi, si
+			pi++
+		case pi < len(p) && p[pi] == str[si]:
+			pi++
+			si++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			si = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// globsIntersect reports whether some string matches both patterns
+func globsIntersect(a, b string) bool {
+	x, y := []rune(a), []rune(b)
+	memo := make(map[[2]int]bool)
+	var visit func(i, j int) bool
+	visit = func(i, j int) bool {
+		key := [2]int{i, j}
+		if result, ok := memo[key]; ok {
+			return result
+		}
+		memo[key] = false
+
+		var result bool
+		switch {
+		case i == len(x) && j == len(y):
+			result = true
+		case i < len(x) && x[i] == '*':
+			result = visit(i+1, j) || (j < len(y) && visit(i, j+1))
+		case j < len(y) && y[j] == '*':
+			result = visit(i, j+1) || (i < len(x) && visit(i+1, j))
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			result = visit(i+1, j+1)
+		}
+		memo[key] = result
+		return result
+	}
+	return visit(0, 0)
+}
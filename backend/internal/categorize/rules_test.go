@@ -0,0 +1,379 @@
+package categorize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+func strPtr(v string) *string     { return &v }
+func floatPtr(v float64) *float64 { return &v }
+
+func TestRuleMatches(t *testing.T) {
+	uber := &models.CategorizationRule{DescriptionPattern: strPtr("UBER*")}
+	card := strPtr("card")
+	tests := []struct {
+		name      string
+		rule      *models.CategorizationRule
+		candidate Candidate
+		want      bool
+	}{
+		{"pattern prefix", uber, Candidate{Description: "Uber *trip 1234"}, true},
+		{"pattern anchored", uber, Candidate{Description: "Paid UBER eats"}, false},
+		{"pattern inner", &models.CategorizationRule{DescriptionPattern: strPtr("*swiggy*")}, Candidate{Description: "UPI/SWIGGY/Bangalore"}, true},
+		{"merchant normalized", &models.CategorizationRule{Merchant: strPtr("Shell")}, Candidate{Description: "SHELL #4411"}, true},
+		{"amount in range", &models.CategorizationRule{MinAmount: floatPtr(100), MaxAmount: floatPtr(500)}, Candidate{Amount: 500}, true},
+		{"amount below range", &models.CategorizationRule{MinAmount: floatPtr(100)}, Candidate{Amount: 99.99}, false},
+		{"payment method", &models.CategorizationRule{PaymentMethod: strPtr("Card")}, Candidate{PaymentMethod: card}, true},
+		{"payment method missing", &models.CategorizationRule{PaymentMethod: strPtr("card")}, Candidate{}, false},
+		{"all criteria", &models.CategorizationRule{DescriptionPattern: strPtr("uber*"), MaxAmount: floatPtr(50)}, Candidate{Description: "UBER", Amount: 80}, false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.rule, tt.candidate); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRuleOverlapsAndConflicts(t *testing.T) {
+	rule := func(pattern string, min, max *float64, category uuid.UUID) *models.CategorizationRule {
+		r := &models.CategorizationRule{ID: uuid.New(), CategoryID: category, IsActive: true, MinAmount: min, MaxAmount: max}
+		if pattern != "" {
+			r.DescriptionPattern = strPtr(pattern)
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		a, b *models.CategorizationRule
+		want bool
+	}{
+		{"prefix and contains", rule("uber*", nil, nil, transport), rule("*eats*", nil, nil, dining), true},
+		{"disjoint prefixes", rule("uber*", nil, nil, transport), rule("ola*", nil, nil, dining), false},
+		{"disjoint amounts", rule("", nil, floatPtr(100), transport), rule("", floatPtr(200), nil, dining), false},
+		{"pattern and amount", rule("uber*", nil, nil, transport), rule("", floatPtr(200), nil, dining), true},
+		{"same suffix", rule("*.com", nil, nil, transport), rule("amazon*", nil, nil, dining), true},
+	}
+	for _, tt := range tests {
+		if got := Conflicts(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Conflicts = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	a, b := rule("uber*", nil, nil, transport), rule("uber eats*", nil, nil, dining)
+	b.Priority = 1
+	if Conflicts(a, b) {
+		t.Error("Rules with different priorities should not conflict")
+	}
+	b.Priority, b.CategoryID = 0, transport
+	if Conflicts(a, b) {
+		t.Error("Rules assigning the same category should not conflict")
+	}
+}
+
+func TestValidateRuleRequest(t *testing.T) {
+	errs := ValidateRuleRequest(&models.CategorizationRuleRequest{Name: "x", CategoryID: transport})
+	if !hasField(errs, "match") {
+		t.Errorf("Expected a missing criteria error, got %v", errs)
+	}
+	errs = ValidateRuleRequest(&models.CategorizationRuleRequest{
+		DescriptionPattern: strPtr("**"), MinAmount: floatPtr(10), MaxAmount: floatPtr(5),
+	})
+	for _, field := range []string{"name", "category_id", "description_pattern", "max_amount"} {
+		if !hasField(errs, field) {
+			t.Errorf("Expected an error on %s, got %v", field, errs)
+		}
+	}
+}
+
+func hasField(errs utils.ValidationErrors, field string) bool {
+	for _, e := range errs {
+		if e.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryRuleStore struct {
+	mu       sync.Mutex
+	rules    []models.CategorizationRule
+	expenses map[uuid.UUID]*models.Expense
+	changes  []models.RuleChange
+	audit    []models.AuditLog
+	batches  int
+}
+
+func newMemoryRuleStore() *memoryRuleStore {
+	return &memoryRuleStore{expenses: make(map[uuid.UUID]*models.Expense)}
+}
+
+func (s *memoryRuleStore) addExpense(description string, amount float64, category uuid.UUID) *models.Expense {
+	e := &models.Expense{ID: uuid.New(), Description: description, Amount: amount, CategoryID: category}
+	s.expenses[e.ID] = e
+	return e
+}
+
+func (s *memoryRuleStore) ListRules(ctx context.Context, userID uuid.UUID) ([]models.CategorizationRule, error) {
+	return append([]models.CategorizationRule(nil), s.rules...), nil
+}
+
+func (s *memoryRuleStore) CreateRule(ctx context.Context, rule *models.CategorizationRule) error {
+	s.rules = append(s.rules, *rule)
+	return nil
+}
+
+func (s *memoryRuleStore) UpdateRule(ctx context.Context, rule *models.CategorizationRule) error {
+	for i := range s.rules {
+		if s.rules[i].ID == rule.ID {
+			s.rules[i] = *rule
+		}
+	}
+	return nil
+}
+
+func (s *memoryRuleStore) DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error {
+	for i := range s.rules {
+		if s.rules[i].ID == ruleID {
+			s.rules = append(s.rules[:i], s.rules[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memoryRuleStore) CountExpenses(ctx context.Context, userID uuid.UUID) (int, error) {
+	return len(s.expenses), nil
+}
+
+func (s *memoryRuleStore) ListExpenses(ctx context.Context, userID, after uuid.UUID, limit int) ([]models.Expense, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var page []models.Expense
+	for _, e := range s.expenses {
+		if e.ID.String() > after.String() {
+			page = append(page, *e)
+		}
+	}
+	sort.Slice(page, func(i, j int) bool { return page[i].ID.String() < page[j].ID.String() })
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page, nil
+}
+
+func (s *memoryRuleStore) Recategorize(ctx context.Context, changes []models.RuleChange, audit []models.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range changes {
+		s.expenses[c.ExpenseID].CategoryID = c.NewCategoryID
+	}
+	s.changes = append(s.changes, changes...)
+	s.audit = append(s.audit, audit...)
+	s.batches++
+	return nil
+}
+
+func (s *memoryRuleStore) CountChanges(ctx context.Context, userID, applyID uuid.UUID) (int, error) {
+	changes, _, _ := s.ListChanges(ctx, userID, applyID, uuid.Nil, len(s.changes)+1)
+	return len(changes), nil
+}
+
+func (s *memoryRuleStore) ListChanges(ctx context.Context, userID, applyID, after uuid.UUID, limit int) ([]models.RuleChange, []bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changes []models.RuleChange
+	for _, c := range s.changes {
+		if c.ApplyID == applyID && c.RevertedAt == nil && c.ID.String() > after.String() {
+			changes = append(changes, c)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID.String() < changes[j].ID.String() })
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	current := make([]bool, len(changes))
+	for i, c := range changes {
+		current[i] = s.expenses[c.ExpenseID].CategoryID == c.NewCategoryID
+	}
+	return changes, current, nil
+}
+
+func (s *memoryRuleStore) Revert(ctx context.Context, changes []models.RuleChange, audit []models.AuditLog, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range changes {
+		s.expenses[c.ExpenseID].CategoryID = c.OldCategoryID
+		for i := range s.changes {
+			if s.changes[i].ID == c.ID {
+				s.changes[i].RevertedAt = &at
+			}
+		}
+	}
+	s.audit = append(s.audit, audit...)
+	return nil
+}
+
+func waitForRuleJob(t *testing.T, s *RuleService, userID, jobID uuid.UUID) *models.RuleJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := s.Job(userID, jobID)
+		if !ok {
+			t.Fatal("job not found")
+		}
+		if job.Status != models.RuleJobRunning {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("job did not finish")
+	return nil
+}
+
+func TestRuleServiceRejectsConflicts(t *testing.T) {
+	service := NewRuleService(newMemoryRuleStore(), nil)
+	userID := uuid.New()
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, userID, &models.CategorizationRuleRequest{Name: "Uber", DescriptionPattern: strPtr("UBER*"), CategoryID: transport}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := service.Create(ctx, userID, &models.CategorizationRuleRequest{Name: "Uber Eats", DescriptionPattern: strPtr("uber eats*"), CategoryID: dining})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Rule.Name != "Uber" || !errors.Is(err, ErrRuleConflict) {
+		t.Fatalf("Expected a conflict with the Uber rule, got %v", err)
+	}
+
+	eats, err := service.Create(ctx, userID, &models.CategorizationRuleRequest{Name: "Uber Eats", DescriptionPattern: strPtr("uber eats*"), CategoryID: dining, Priority: 10})
+	if err != nil {
+		t.Fatalf("A higher priority rule should be accepted, got %v", err)
+	}
+	_, err = service.Update(ctx, userID, eats.ID, &models.CategorizationRuleRequest{Name: "Uber Eats", DescriptionPattern: strPtr("uber eats*"), CategoryID: dining})
+	if !errors.Is(err, ErrRuleConflict) {
+		t.Errorf("Lowering the priority into a conflict should be rejected, got %v", err)
+	}
+	if _, err := service.Update(ctx, userID, uuid.New(), &models.CategorizationRuleRequest{Name: "x", Merchant: strPtr("shell"), CategoryID: fuel}); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound, got %v", err)
+	}
+}
+
+func TestRulePreviewApplyAndRevert(t *testing.T) {
+	store := newMemoryRuleStore()
+	service := NewRuleService(store, nil)
+	userID := uuid.New()
+	ctx := context.Background()
+
+	uber, _ := service.Create(ctx, userID, &models.CategorizationRuleRequest{Name: "Uber", DescriptionPattern: strPtr("UBER*"), CategoryID: transport})
+	eats, _ := service.Create(ctx, userID, &models.CategorizationRuleRequest{Name: "Uber Eats", DescriptionPattern: strPtr("uber eats*"), CategoryID: dining, Priority: 5})
+
+	// More rides than one batch, so the apply spans several transactions
+	for i := 0; i < ApplyBatchSize+50; i++ {
+		store.addExpense(fmt.Sprintf("UBER TRIP %d", i), 200, groceries)
+	}
+	store.addExpense("UBER TRIP already", 150, transport)
+	meal := store.addExpense("Uber Eats order", 400, groceries)
+	store.addExpense("Shell", 1500, fuel)
+
+	previews, err := service.Preview(ctx, userID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byRule := make(map[uuid.UUID]models.RulePreview)
+	for _, p := range previews {
+		byRule[p.RuleID] = p
+	}
+	if p := byRule[uber.ID]; p.Matches != ApplyBatchSize+52 || p.Changes != ApplyBatchSize+50 {
+		t.Errorf("Unexpected Uber preview %+v", p)
+	}
+	if p := byRule[eats.ID]; p.Matches != 1 || p.Changes != 1 {
+		t.Errorf("Unexpected Uber Eats preview %+v", p)
+	}
+
+	job, err := service.StartApply(ctx, userID, &models.CategorizationRuleApplyRequest{RuleIDs: []uuid.UUID{uuid.New()}})
+	if !errors.Is(err, ErrRuleNotFound) {
+		t.Fatalf("Expected unknown rules to be rejected, got %v %v", job, err)
+	}
+	job, err = service.StartApply(ctx, userID, &models.CategorizationRuleApplyRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finished := waitForRuleJob(t, service, userID, job.ID)
+	if finished.Status != models.RuleJobSucceeded || finished.Processed != finished.Total || finished.Changed != ApplyBatchSize+51 {
+		t.Fatalf("Unexpected job %+v", finished)
+	}
+	if store.batches < 2 {
+		t.Errorf("Expected several batches, got %d", store.batches)
+	}
+	if store.expenses[meal.ID].CategoryID != dining {
+		t.Error("The higher priority rule should win")
+	}
+	if len(store.audit) != finished.Changed || store.audit[0].Action != models.AuditActionRuleRecategorize || store.audit[0].EntityType != "expense" {
+		t.Errorf("Expected one audit entry per changed expense, got %d", len(store.audit))
+	}
+
+	// A later manual edit is kept when the apply is reverted
+	store.expenses[meal.ID].CategoryID = fuel
+	revert, err := service.StartRevert(ctx, userID, job.ApplyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverted := waitForRuleJob(t, service, userID, revert.ID)
+	if reverted.Status != models.RuleJobSucceeded || reverted.Changed != ApplyBatchSize+50 {
+		t.Fatalf("Unexpected revert job %+v", reverted)
+	}
+	for _, e := range store.expenses {
+		if e.Description != "UBER TRIP already" && e.Description != "Shell" && e.ID != meal.ID && e.CategoryID != groceries {
+			t.Fatalf("Expense %q was not reverted", e.Description)
+		}
+	}
+	if store.expenses[meal.ID].CategoryID != fuel {
+		t.Error("A manually changed expense should not be reverted")
+	}
+	if _, ok := service.Job(uuid.New(), revert.ID); ok {
+		t.Error("Jobs should only be visible to their user")
+	}
+	if _, err := service.StartRevert(ctx, userID, uuid.New()); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Reverting an unknown apply should fail, got %v", err)
+	}
+}
+
+type staticRules struct{ engine *Engine }
+
+func (s staticRules) Engine(ctx context.Context, userID uuid.UUID) (*Engine, error) {
+	return s.engine, nil
+}
+
+func TestSuggestionsStartWithMatchingRule(t *testing.T) {
+	user := uuid.New()
+	loader := &fakeLoader{history: map[uuid.UUID][]Sample{user: {{"Uber trip", groceries}}}}
+	engine := NewEngine([]models.CategorizationRule{
+		{ID: uuid.New(), DescriptionPattern: strPtr("uber*"), CategoryID: transport, IsActive: true},
+		{ID: uuid.New(), DescriptionPattern: strPtr("uber*"), CategoryID: fuel, IsActive: false, Priority: 99},
+	})
+	service := NewService(loader).WithRules(staticRules{engine: engine})
+
+	suggestions, err := service.Suggest(context.Background(), user, "Uber trip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 2 || suggestions[0].CategoryID != transport || suggestions[0].Source != SourceRule || suggestions[1].CategoryID != groceries {
+		t.Errorf("Expected the rule first and history after, got %+v", suggestions)
+	}
+
+	suggestions, _ = service.Suggest(context.Background(), user, "Netflix")
+	for _, s := range suggestions {
+		if s.Source == SourceRule {
+			t.Errorf("Unmatched description should not get a rule suggestion, got %+v", suggestions)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package categorize
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// HistoryLoader loads the data the suggestion indexes are built from
+type HistoryLoader interface {
+	// LoadHistory returns the user's categorized expense descriptions
+	LoadHistory(ctx context.Context, userID uuid.UUID) ([]Sample, error)
+	// LoadCategoryCounts returns how often each category is used across all users
+	LoadCategoryCounts(ctx context.Context) (map[uuid.UUID]int, error)
+}
+
+// RuleSource provides the engine over a user's active categorization rules
+type RuleSource interface {
+	Engine(ctx context.Context, userID uuid.UUID) (*Engine, error)
+}
+
+// Service suggests categories for expense descriptions. It is shared by the
+// suggest-category endpoint and the CSV, OFX and email import pipelines so
+// every entry point guesses the same way. The user's categorization rules
+// are consulted first. Per-user indexes are built lazily and cached until
+// Invalidate is called after the user recategorizes expenses.
+type Service struct {
+	loader HistoryLoader
+	rules  RuleSource
+
+	mu      sync.RWMutex
+	indexes map[uuid.UUID]*Index
+	prior   *Prior
+}
+
+// NewService creates a new category suggestion service
+func NewService(loader HistoryLoader) *Service {
+	return &Service{
+		loader:  loader,
+		indexes: make(map[uuid.UUID]*Index),
+	}
+}
+
+// WithRules makes suggestions start with the category of the user's matching
+// categorization rule
+func (s *Service) WithRules(rules RuleSource) *Service {
+	s.rules = rules
+	return s
+}
+
+// Suggest returns up to three categories for description. Users without
+// matching history get suggestions from the global category frequency prior.
+func (s *Service) Suggest(ctx context.Context, userID uuid.UUID, description string) ([]models.CategorySuggestion, error) {
+	return s.SuggestFor(ctx, userID, Candidate{Description: description})
+}
+
+// SuggestFor returns up to three categories for an expense. A matching
+// categorization rule comes first with full confidence, followed by the
+// history and global prior suggestions.
+func (s *Service) SuggestFor(ctx context.Context, userID uuid.UUID, candidate Candidate) ([]models.CategorySuggestion, error) {
+	if s.rules == nil {
+		return s.suggest(ctx, userID, candidate.Description)
+	}
+	engine, err := s.rules.Engine(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categorization rules: %w", err)
+	}
+	rule := engine.Match(candidate)
+	if rule == nil {
+		return s.suggest(ctx, userID, candidate.Description)
+	}
+
+	fallback, err := s.suggest(ctx, userID, candidate.Description)
+	if err != nil {
+		return nil, err
+	}
+	suggestions := []models.CategorySuggestion{{CategoryID: rule.CategoryID, Confidence: 1, Source: SourceRule}}
+	for _, suggestion := range fallback {
+		if len(suggestions) == DefaultLimit {
+			break
+		}
+		if suggestion.CategoryID != rule.CategoryID {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// suggest returns the history or global prior suggestions for description
+func (s *Service) suggest(ctx context.Context, userID uuid.UUID, description string) ([]models.CategorySuggestion, error) {
+	index, err := s.index(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if suggestions := index.Suggest(description, DefaultLimit); len(suggestions) > 0 {
+		return suggestions, nil
+	}
+
+	prior, err := s.globalPrior(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prior.Suggest(DefaultLimit), nil
+}
+
+// Invalidate drops the cached index for a user so the next suggestion
+// reflects their latest categorizations
+func (s *Service) Invalidate(userID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.indexes, userID)
+}
+
+// InvalidatePrior drops the cached global prior, e.g. from a periodic job
+func (s *Service) InvalidatePrior() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prior = nil
+}
+
+// index returns the cached index for a user, building it on first use
+func (s *Service) index(ctx context.Context, userID uuid.UUID) (*Index, error) {
+	s.mu.RLock()
+	index, ok := s.indexes[userID]
+	s.mu.RUnlock()
+	if ok {
+		return index, nil
+	}
+
+	samples, err := s.loader.LoadHistory(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categorization history: %w", err)
+	}
+	index = NewIndex(samples)
+
+	s.mu.Lock()
+	s.indexes[userID] = index
+	s.mu.Unlock()
+	return index, nil
+}
+
+// globalPrior returns the cached prior, loading it on first use
+func (s *Service) globalPrior(ctx context.Context) (*Prior, error) {
+	s.mu.RLock()
+	prior := s.prior
+	s.mu.RUnlock()
+	if prior != nil {
+		return prior, nil
+	}
+
+	counts, err := s.loader.LoadCategoryCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load category counts: %w", err)
+	}
+	prior = NewPrior(counts)
+
+	s.mu.Lock()
+	s.prior = prior
+	s.mu.Unlock()
+	return prior, nil
+}
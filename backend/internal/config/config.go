@@ -1,123 +1,666 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"tgfinance/pkg/logger"
+	"tgfinance/pkg/mailer"
+	"tgfinance/pkg/storage"
 )
 
-// Config holds all configuration for the application
+// Config holds all configuration for the application. Fields holding
+// secrets are tagged secret:"true" so Redacted can hide them; fields whose
+// names merely look secret are tagged secret:"false".
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
-	Redis    RedisConfig
-	Log      LogConfig
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Redis       RedisConfig       `yaml:"redis"`
+	Log         LogConfig         `yaml:"log"`
+	OCR         OCRConfig         `yaml:"ocr"`
+	Analytics   AnalyticsConfig   `yaml:"analytics"`
+	Backup      BackupConfig      `yaml:"backup"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	SMTP        SMTPConfig        `yaml:"smtp"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Features    FeatureFlags      `yaml:"features"`
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port         string
-	Host         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Port         string        `yaml:"port"`
+	Host         string        `yaml:"host"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	// RequestTimeout bounds how long a handler may take to respond
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// MaxBodyBytes limits request bodies; MaxUploadBytes applies to uploads
+	MaxBodyBytes   int64 `yaml:"max_body_bytes"`
+	MaxUploadBytes int64 `yaml:"max_upload_bytes"`
+	// CORSAllowedOrigins may call the API from browsers; "*" allows any
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"`
+	// ReceiptURLHosts restricts expense receipt links to these hosts, such
+	// as the object storage domain; ".example.com" allows subdomains
+	ReceiptURLHosts []string `yaml:"receipt_url_hosts"`
+	// DefaultPhoneRegion is the ISO 3166 region of phone numbers given
+	// without a + and country code
+	DefaultPhoneRegion string `yaml:"default_phone_region"`
+
+	// Maintenance mode answers 503 except to health checks and requests
+	// carrying MaintenanceBypassToken; it starts on with MaintenanceMode
+	MaintenanceMode        bool          `yaml:"maintenance_mode"`
+	MaintenanceBypassToken string        `yaml:"maintenance_bypass_token" secret:"true"`
+	MaintenanceRetryAfter  time.Duration `yaml:"maintenance_retry_after"`
 }
 
 // DatabaseConfig holds database-related configuration
 type DatabaseConfig struct {
-	Host            string
-	Port            string
-	User            string
-	Password        string
-	DBName          string
-	SSLMode         string
-	MaxOpenConns    int
-	MaxIdleConns    int
-	ConnMaxLifetime time.Duration
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password" secret:"true"`
+	DBName   string `yaml:"db_name"`
+	SSLMode  string `yaml:"ssl_mode"`
+	// Params are further libpq connection parameters, such as options or
+	// application_name
+	Params map[string]string `yaml:"params"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
 }
 
 // AuthConfig holds authentication-related configuration
 type AuthConfig struct {
-	JWTSecret         string
-	JWTExpiration     time.Duration
-	RefreshExpiration time.Duration
-	PasswordMinLength int
+	JWTSecret         string            `yaml:"jwt_secret" secret:"true"`
+	JWTKeys           map[string]string `yaml:"jwt_keys" secret:"true"`
+	JWTKeyID          string            `yaml:"jwt_key_id" secret:"false"`
+	JWTIssuer         string            `yaml:"jwt_issuer"`
+	JWTAudience       string            `yaml:"jwt_audience"`
+	AllowedIssuers    []string          `yaml:"allowed_issuers"`
+	AllowedAudiences  []string          `yaml:"allowed_audiences"`
+	JWTExpiration     time.Duration     `yaml:"jwt_expiration"`
+	RefreshExpiration time.Duration     `yaml:"refresh_expiration"`
+	ClockSkew         time.Duration     `yaml:"clock_skew"`
+	PasswordMinLength int               `yaml:"password_min_length" secret:"false"`
+
+	// PasswordHashAlgorithm is "bcrypt" or "argon2id"; Argon2 memory is in KiB
+	PasswordHashAlgorithm string `yaml:"password_hash_algorithm" secret:"false"`
+	BcryptCost            int    `yaml:"bcrypt_cost"`
+	Argon2Memory          int    `yaml:"argon2_memory"`
+	Argon2Iterations      int    `yaml:"argon2_iterations"`
+	Argon2Parallelism     int    `yaml:"argon2_parallelism"`
+
+	// PasswordPeppers are HMAC keys applied before hashing, current first
+	PasswordPeppers []string `yaml:"password_peppers" secret:"true"`
+
+	// Login lockout: LockoutMaxFailures failures within LockoutWindow lock
+	// the account for LockoutDuration
+	LockoutMaxFailures int           `yaml:"lockout_max_failures"`
+	LockoutWindow      time.Duration `yaml:"lockout_window"`
+	LockoutDuration    time.Duration `yaml:"lockout_duration"`
+
+	// PublicPaths are extra routes served without authentication, each
+	// "[METHOD[|METHOD...]] /path" where the path may use * wildcards
+	PublicPaths []string `yaml:"public_paths"`
+
+	// Browser clients get the access token in an httpOnly cookie; SameSite
+	// is "lax", "strict" or "none"
+	CookieName     string `yaml:"cookie_name"`
+	CookieDomain   string `yaml:"cookie_domain"`
+	CookieSecure   bool   `yaml:"cookie_secure"`
+	CookieSameSite string `yaml:"cookie_same_site"`
+	CSRFCookieName string `yaml:"csrf_cookie_name"`
+
+	// AdminAllowedNetworks are the IPs and CIDR ranges admin routes may be
+	// called from, such as the office VPN
+	AdminAllowedNetworks []string `yaml:"admin_allowed_networks"`
 }
 
 // RedisConfig holds Redis-related configuration
 type RedisConfig struct {
-	Host     string
-	Port     string
-	Password string
-	DB       int
+	// Mode is "single", "sentinel" or "cluster". Single mode connects to
+	// Host:Port; sentinel and cluster modes use Addrs, the sentinel or
+	// cluster node addresses, and sentinel mode asks for MasterName.
+	Mode       string   `yaml:"mode"`
+	Host       string   `yaml:"host"`
+	Port       string   `yaml:"port"`
+	Addrs      []string `yaml:"addrs"`
+	MasterName string   `yaml:"master_name"`
+
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+	DB       int    `yaml:"db"`
+	// TLS connects over TLS, as rediss:// URLs do
+	TLS bool `yaml:"tls"`
+
+	DialTimeout  time.Duration `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// PoolSize is the most connections per node; zero uses the go-redis
+	// default of 10 per CPU
+	PoolSize int `yaml:"pool_size"`
 }
 
 // LogConfig holds logging-related configuration
 type LogConfig struct {
-	Level      string
-	Format     string
-	Output     string
-	TimeFormat string
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+	// Output is "stdout", "stderr", "file" or "both" for stdout and the file
+	Output     string `yaml:"output"`
+	TimeFormat string `yaml:"time_format"`
+	// AccessLogSkipPaths are request paths left out of the access log
+	AccessLogSkipPaths []string `yaml:"access_log_skip_paths"`
+
+	// File is the log file path. It is rotated before growing past
+	// MaxSizeMB, keeping MaxBackups rotated files for MaxAgeDays,
+	// compressed with Compress; zero limits are off.
+	File       string `yaml:"file"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// OCRConfig holds receipt OCR-related configuration
+type OCRConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Provider      string        `yaml:"provider"`
+	Endpoint      string        `yaml:"endpoint"`
+	APIKey        string        `yaml:"api_key" secret:"true"`
+	Timeout       time.Duration `yaml:"timeout"`
+	MinConfidence float64       `yaml:"min_confidence"`
+}
+
+// AnalyticsConfig holds first-party usage analytics configuration
+type AnalyticsConfig struct {
+	// UsageEnabled is the global opt-out for per-route usage counting
+	UsageEnabled bool `yaml:"usage_enabled"`
+}
+
+// BackupConfig holds application-level backup configuration
+type BackupConfig struct {
+	StorageDir string        `yaml:"storage_dir"`
+	Interval   time.Duration `yaml:"interval"`
+	// Retain is how many backups the scheduled job keeps
+	Retain int `yaml:"retain"`
+}
+
+// RateLimitRule allows Requests per Window; zero Requests disables the limit
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// RateLimitConfig holds request rate limiting configuration
+type RateLimitConfig struct {
+	// Store is "memory" or "redis"; Redis shares limits across instances
+	Store string `yaml:"store"`
+	// TrustedProxies are IPs or CIDRs whose X-Forwarded-For is honored
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// Auth limits login and registration per client IP
+	Auth RateLimitRule `yaml:"auth"`
+	// API limits authenticated routes per user
+	API RateLimitRule `yaml:"api"`
+	// Status limits the public status endpoint per client IP
+	Status RateLimitRule `yaml:"status"`
+}
+
+// IdempotencyConfig holds Idempotency-Key replay configuration
+type IdempotencyConfig struct {
+	// TTL is how long responses are kept for replay
+	TTL time.Duration `yaml:"ttl"`
+	// LockTTL bounds how long an in-flight request holds its key
+	LockTTL time.Duration `yaml:"lock_ttl"`
+	// Wait makes retries wait for an in-flight request instead of getting 409
+	Wait        bool          `yaml:"wait"`
+	WaitTimeout time.Duration `yaml:"wait_timeout"`
+	// MaxResponseBytes caps stored response bodies; retries of requests with
+	// larger responses get 409 instead of a replay
+	MaxResponseBytes int `yaml:"max_response_bytes"`
+}
+
+// SMTPConfig holds outbound email configuration. Mail is not sent when
+// Host is empty.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+	// From is the sender address, such as "TG Finance <no-reply@example.com>"
+	From string `yaml:"from"`
+	// TLSMode is "starttls", "tls" for implicit TLS as on port 465, or
+	// "none" for local relays
+	TLSMode string        `yaml:"tls_mode"`
+	Timeout time.Duration `yaml:"timeout"`
 }
 
-// Load loads configuration from environment variables
+// SMTP TLS modes
+const (
+	SMTPTLSNone     = mailer.TLSNone
+	SMTPTLSStartTLS = mailer.TLSStartTLS
+	SMTPTLSImplicit = mailer.TLSImplicit
+)
+
+// smtpTLSModes are the values SMTPConfig.TLSMode may take
+var smtpTLSModes = []string{SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit}
+
+// StorageConfig holds object storage configuration for uploaded receipts
+type StorageConfig struct {
+	// Provider is "s3", "minio" or "filesystem" for development
+	Provider string `yaml:"provider"`
+	Bucket   string `yaml:"bucket"`
+	Region   string `yaml:"region"`
+	// Endpoint is the S3 API URL, required for MinIO; empty uses AWS
+	Endpoint  string `yaml:"endpoint"`
+	AccessKey string `yaml:"access_key" secret:"false"`
+	SecretKey string `yaml:"secret_key" secret:"true"`
+	// BaseURL prefixes the links handed to clients: the public S3 endpoint
+	// when it differs from Endpoint, or where the filesystem store is served
+	BaseURL string `yaml:"base_url"`
+	// PresignTTL is how long download links stay valid
+	PresignTTL time.Duration `yaml:"presign_ttl"`
+	// Dir is the root directory of the filesystem provider
+	Dir string `yaml:"dir"`
+}
+
+// Storage providers
+const (
+	StorageS3         = storage.ProviderS3
+	StorageMinIO      = storage.ProviderMinIO
+	StorageFilesystem = storage.ProviderFilesystem
+)
+
+// storageProviders are the values StorageConfig.Provider may take
+var storageProviders = []string{StorageS3, StorageMinIO, StorageFilesystem}
+
+// Load loads configuration from environment variables. If CONFIG_FILE is
+// set, its file supplies the values the environment does not. Load cannot
+// fail: a config file that cannot be loaded is skipped, and values that
+// cannot be read, such as an unreadable _FILE secret or a malformed
+// DATABASE_URL, keep their defaults. Both are logged; LoadAndValidate
+// returns them as the error instead.
 func Load() *Config {
+	cfg, err := load()
+	if err != nil {
+		log.Printf("Config: %v; continuing with the remaining configuration", err)
+	}
+	return cfg
+}
+
+// load reads CONFIG_FILE if set and applies environment variables. The
+// configuration is returned along with the error unless nothing could be
+// loaded, falling back to the environment alone when the file fails.
+func load() (*Config, error) {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return applyEnv(defaults())
+	}
+	cfg, err := LoadFromFile(path)
+	if cfg != nil {
+		return cfg, err
+	}
+	cfg, envErr := applyEnv(defaults())
+	return cfg, errors.Join(err, envErr)
+}
+
+// defaults returns the configuration used where neither a config file nor
+// the environment sets a value
+func defaults() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVICE_PORT", "8001"),
-			Host:         getEnv("SERVER_HOST", "0.0.0.0"),
-			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 30*time.Second),
-			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second),
-			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			Port:         "8001",
+			Host:         "0.0.0.0",
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  60 * time.Second,
+
+			RequestTimeout: 25 * time.Second,
+			MaxBodyBytes:   1 << 20,
+			MaxUploadBytes: 10 << 20,
+
+			DefaultPhoneRegion: "US",
+
+			MaintenanceRetryAfter: 2 * time.Minute,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			User:            "postgres",
+			DBName:          "tgfinance",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Auth: AuthConfig{
+			JWTSecret:         DefaultJWTSecret,
+			JWTIssuer:         "tgfinance",
+			JWTAudience:       "tgfinance-api",
+			JWTExpiration:     24 * time.Hour,
+			RefreshExpiration: 7 * 24 * time.Hour,
+			PasswordMinLength: 8,
+
+			PasswordHashAlgorithm: "bcrypt",
+			BcryptCost:            10,
+			Argon2Memory:          64 * 1024,
+			Argon2Iterations:      3,
+			Argon2Parallelism:     2,
+
+			LockoutMaxFailures: 5,
+			LockoutWindow:      15 * time.Minute,
+			LockoutDuration:    15 * time.Minute,
+
+			CookieName:     "access_token",
+			CookieSecure:   true,
+			CookieSameSite: "lax",
+			CSRFCookieName: "csrf_token",
+		},
+		Redis: RedisConfig{
+			Mode: RedisModeSingle,
+			Host: "localhost",
+			Port: "6379",
+
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+		},
+		Log: LogConfig{
+			Level:      "info",
+			Format:     "json",
+			Output:     "stdout",
+			TimeFormat: "2006-01-02T15:04:05Z07:00",
+
+			AccessLogSkipPaths: []string{"/health", "/metrics"},
+
+			File:       logger.DefaultFileOptions.Path,
+			MaxSizeMB:  logger.DefaultFileOptions.MaxSizeMB,
+			MaxBackups: logger.DefaultFileOptions.MaxBackups,
+			MaxAgeDays: logger.DefaultFileOptions.MaxAgeDays,
+			Compress:   logger.DefaultFileOptions.Compress,
+		},
+		OCR: OCRConfig{
+			Provider:      "cloud_vision",
+			Timeout:       30 * time.Second,
+			MinConfidence: 0.8,
+		},
+		Analytics: AnalyticsConfig{
+			UsageEnabled: true,
+		},
+		Backup: BackupConfig{
+			StorageDir: "./data/backups",
+			Interval:   24 * time.Hour,
+			Retain:     7,
+		},
+		RateLimit: RateLimitConfig{
+			Store: "memory",
+			Auth: RateLimitRule{
+				Requests: 10,
+				Window:   time.Minute,
+			},
+			API: RateLimitRule{
+				Requests: 300,
+				Window:   time.Minute,
+			},
+			Status: RateLimitRule{
+				Requests: 60,
+				Window:   time.Minute,
+			},
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:              24 * time.Hour,
+			LockTTL:          time.Minute,
+			WaitTimeout:      10 * time.Second,
+			MaxResponseBytes: 64 << 10,
+		},
+		SMTP: SMTPConfig{
+			Port:    "587",
+			TLSMode: SMTPTLSStartTLS,
+			Timeout: 10 * time.Second,
+		},
+		Storage: StorageConfig{
+			Provider:   StorageFilesystem,
+			Region:     "us-east-1",
+			PresignTTL: 15 * time.Minute,
+			Dir:        "./data/receipts",
+		},
+	}
+}
+
+// applyEnv returns d with the values set by environment variables replaced.
+// The error reports secret files named by _FILE variables that cannot be
+// read and malformed URLs; the configuration is returned with it, those
+// values left unchanged.
+func applyEnv(d *Config) (*Config, error) {
+	secrets := &secretEnv{}
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         getEnv("SERVICE_PORT", d.Server.Port),
+			Host:         getEnv("SERVER_HOST", d.Server.Host),
+			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", d.Server.ReadTimeout),
+			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", d.Server.WriteTimeout),
+			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", d.Server.IdleTimeout),
+
+			RequestTimeout: getDurationEnv("SERVER_REQUEST_TIMEOUT", d.Server.RequestTimeout),
+			MaxBodyBytes:   int64(getIntEnv("SERVER_MAX_BODY_BYTES", int(d.Server.MaxBodyBytes))),
+			MaxUploadBytes: int64(getIntEnv("SERVER_MAX_UPLOAD_BYTES", int(d.Server.MaxUploadBytes))),
+
+			CORSAllowedOrigins: getListEnvDefault("CORS_ALLOWED_ORIGINS", d.Server.CORSAllowedOrigins),
+			ReceiptURLHosts:    getListEnvDefault("RECEIPT_URL_HOSTS", d.Server.ReceiptURLHosts),
+			DefaultPhoneRegion: getEnv("DEFAULT_PHONE_REGION", d.Server.DefaultPhoneRegion),
+
+			MaintenanceMode:        getBoolEnv("MAINTENANCE_MODE", d.Server.MaintenanceMode),
+			MaintenanceBypassToken: secrets.get("MAINTENANCE_BYPASS_TOKEN", d.Server.MaintenanceBypassToken),
+			MaintenanceRetryAfter:  getDurationEnv("MAINTENANCE_RETRY_AFTER", d.Server.MaintenanceRetryAfter),
 		},
 		Database: DatabaseConfig{
-			Host:            getEnv("DB_HOST", "localhost"),
-			Port:            getEnv("DB_PORT", "5432"),
-			User:            getEnv("DB_USER", "postgres"),
-			Password:        getEnv("DB_PASSWORD", ""),
-			DBName:          getEnv("DB_NAME", "tgfinance"),
-			SSLMode:         getEnv("DB_SSLMODE", "disable"),
-			MaxOpenConns:    getIntEnv("DB_MAX_OPEN_CONNS", 25),
-			MaxIdleConns:    getIntEnv("DB_MAX_IDLE_CONNS", 5),
-			ConnMaxLifetime: getDurationEnv("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			Host:            getEnv("DB_HOST", d.Database.Host),
+			Port:            getEnv("DB_PORT", d.Database.Port),
+			User:            getEnv("DB_USER", d.Database.User),
+			Password:        secrets.get("DB_PASSWORD", d.Database.Password),
+			DBName:          getEnv("DB_NAME", d.Database.DBName),
+			SSLMode:         getEnv("DB_SSLMODE", d.Database.SSLMode),
+			Params:          d.Database.Params,
+			MaxOpenConns:    getIntEnv("DB_MAX_OPEN_CONNS", d.Database.MaxOpenConns),
+			MaxIdleConns:    getIntEnv("DB_MAX_IDLE_CONNS", d.Database.MaxIdleConns),
+			ConnMaxLifetime: getDurationEnv("DB_CONN_MAX_LIFETIME", d.Database.ConnMaxLifetime),
 		},
 		Auth: AuthConfig{
-			JWTSecret:         getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
-			JWTExpiration:     getDurationEnv("JWT_EXPIRATION", 24*time.Hour),
-			RefreshExpiration: getDurationEnv("JWT_REFRESH_EXPIRATION", 7*24*time.Hour),
-			PasswordMinLength: getIntEnv("PASSWORD_MIN_LENGTH", 8),
+			JWTSecret:         secrets.get("JWT_SECRET", d.Auth.JWTSecret),
+			JWTKeys:           secrets.getMap("JWT_KEYS", d.Auth.JWTKeys),
+			JWTKeyID:          getEnv("JWT_KEY_ID", d.Auth.JWTKeyID),
+			JWTIssuer:         getEnv("JWT_ISSUER", d.Auth.JWTIssuer),
+			JWTAudience:       getEnv("JWT_AUDIENCE", d.Auth.JWTAudience),
+			AllowedIssuers:    getListEnvDefault("JWT_ALLOWED_ISSUERS", d.Auth.AllowedIssuers),
+			AllowedAudiences:  getListEnvDefault("JWT_ALLOWED_AUDIENCES", d.Auth.AllowedAudiences),
+			JWTExpiration:     getDurationEnv("JWT_EXPIRATION", d.Auth.JWTExpiration),
+			RefreshExpiration: getDurationEnv("JWT_REFRESH_EXPIRATION", d.Auth.RefreshExpiration),
+			ClockSkew:         getDurationEnv("JWT_CLOCK_SKEW", d.Auth.ClockSkew),
+			PasswordMinLength: getIntEnv("PASSWORD_MIN_LENGTH", d.Auth.PasswordMinLength),
+
+			PasswordHashAlgorithm: getEnv("PASSWORD_HASH_ALGORITHM", d.Auth.PasswordHashAlgorithm),
+			BcryptCost:            getIntEnv("PASSWORD_BCRYPT_COST", d.Auth.BcryptCost),
+			Argon2Memory:          getIntEnv("ARGON2_MEMORY", d.Auth.Argon2Memory),
+			Argon2Iterations:      getIntEnv("ARGON2_ITERATIONS", d.Auth.Argon2Iterations),
+			Argon2Parallelism:     getIntEnv("ARGON2_PARALLELISM", d.Auth.Argon2Parallelism),
+
+			PasswordPeppers: secrets.getList("PASSWORD_PEPPERS", d.Auth.PasswordPeppers),
+
+			LockoutMaxFailures: getIntEnv("LOGIN_MAX_FAILURES", d.Auth.LockoutMaxFailures),
+			LockoutWindow:      getDurationEnv("LOGIN_FAILURE_WINDOW", d.Auth.LockoutWindow),
+			LockoutDuration:    getDurationEnv("LOGIN_LOCKOUT_DURATION", d.Auth.LockoutDuration),
+
+			PublicPaths: getListEnvDefault("AUTH_PUBLIC_PATHS", d.Auth.PublicPaths),
+
+			CookieName:     getEnv("AUTH_COOKIE_NAME", d.Auth.CookieName),
+			CookieDomain:   getEnv("AUTH_COOKIE_DOMAIN", d.Auth.CookieDomain),
+			CookieSecure:   getBoolEnv("AUTH_COOKIE_SECURE", d.Auth.CookieSecure),
+			CookieSameSite: getEnv("AUTH_COOKIE_SAMESITE", d.Auth.CookieSameSite),
+			CSRFCookieName: getEnv("CSRF_COOKIE_NAME", d.Auth.CSRFCookieName),
+
+			AdminAllowedNetworks: getListEnvDefault("ADMIN_ALLOWED_NETWORKS", d.Auth.AdminAllowedNetworks),
 		},
 		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnv("REDIS_PORT", "6379"),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getIntEnv("REDIS_DB", 0),
+			Mode:       getEnv("REDIS_MODE", d.Redis.Mode),
+			Host:       getEnv("REDIS_HOST", d.Redis.Host),
+			Port:       getEnv("REDIS_PORT", d.Redis.Port),
+			Addrs:      getListEnvDefault("REDIS_ADDRS", d.Redis.Addrs),
+			MasterName: getEnv("REDIS_MASTER_NAME", d.Redis.MasterName),
+
+			Username: getEnv("REDIS_USERNAME", d.Redis.Username),
+			Password: secrets.get("REDIS_PASSWORD", d.Redis.Password),
+			DB:       getIntEnv("REDIS_DB", d.Redis.DB),
+			TLS:      getBoolEnv("REDIS_TLS", d.Redis.TLS),
+
+			DialTimeout:  getDurationEnv("REDIS_DIAL_TIMEOUT", d.Redis.DialTimeout),
+			ReadTimeout:  getDurationEnv("REDIS_READ_TIMEOUT", d.Redis.ReadTimeout),
+			WriteTimeout: getDurationEnv("REDIS_WRITE_TIMEOUT", d.Redis.WriteTimeout),
+			PoolSize:     getIntEnv("REDIS_POOL_SIZE", d.Redis.PoolSize),
 		},
 		Log: LogConfig{
-			Level:      getEnv("LOG_LEVEL", "info"),
-			Format:     getEnv("LOG_FORMAT", "json"),
-			Output:     getEnv("LOG_OUTPUT", "stdout"),
-			TimeFormat: getEnv("LOG_TIME_FORMAT", "2006-01-02T15:04:05Z07:00"),
+			Level:      getEnv("LOG_LEVEL", d.Log.Level),
+			Format:     getEnv("LOG_FORMAT", d.Log.Format),
+			Output:     getEnv("LOG_OUTPUT", d.Log.Output),
+			TimeFormat: getEnv("LOG_TIME_FORMAT", d.Log.TimeFormat),
+
+			AccessLogSkipPaths: getListEnvDefault("ACCESS_LOG_SKIP_PATHS", d.Log.AccessLogSkipPaths),
+
+			File:       getEnv("LOG_FILE", d.Log.File),
+			MaxSizeMB:  getIntEnv("LOG_MAX_SIZE_MB", d.Log.MaxSizeMB),
+			MaxBackups: getIntEnv("LOG_MAX_BACKUPS", d.Log.MaxBackups),
+			MaxAgeDays: getIntEnv("LOG_MAX_AGE_DAYS", d.Log.MaxAgeDays),
+			Compress:   getBoolEnv("LOG_COMPRESS", d.Log.Compress),
+		},
+		OCR: OCRConfig{
+			Enabled:       getBoolEnv("OCR_ENABLED", d.OCR.Enabled),
+			Provider:      getEnv("OCR_PROVIDER", d.OCR.Provider),
+			Endpoint:      getEnv("OCR_ENDPOINT", d.OCR.Endpoint),
+			APIKey:        secrets.get("OCR_API_KEY", d.OCR.APIKey),
+			Timeout:       getDurationEnv("OCR_TIMEOUT", d.OCR.Timeout),
+			MinConfidence: getFloatEnv("OCR_MIN_CONFIDENCE", d.OCR.MinConfidence),
+		},
+		Analytics: AnalyticsConfig{
+			UsageEnabled: getBoolEnv("USAGE_ANALYTICS_ENABLED", d.Analytics.UsageEnabled),
+		},
+		Backup: BackupConfig{
+			StorageDir: getEnv("BACKUP_STORAGE_DIR", d.Backup.StorageDir),
+			Interval:   getDurationEnv("BACKUP_INTERVAL", d.Backup.Interval),
+			Retain:     getIntEnv("BACKUP_RETAIN", d.Backup.Retain),
+		},
+		RateLimit: RateLimitConfig{
+			Store:          getEnv("RATE_LIMIT_STORE", d.RateLimit.Store),
+			TrustedProxies: getListEnvDefault("RATE_LIMIT_TRUSTED_PROXIES", d.RateLimit.TrustedProxies),
+			Auth: RateLimitRule{
+				Requests: getIntEnv("RATE_LIMIT_AUTH_REQUESTS", d.RateLimit.Auth.Requests),
+				Window:   getDurationEnv("RATE_LIMIT_AUTH_WINDOW", d.RateLimit.Auth.Window),
+			},
+			API: RateLimitRule{
+				Requests: getIntEnv("RATE_LIMIT_API_REQUESTS", d.RateLimit.API.Requests),
+				Window:   getDurationEnv("RATE_LIMIT_API_WINDOW", d.RateLimit.API.Window),
+			},
+			Status: RateLimitRule{
+				Requests: getIntEnv("RATE_LIMIT_STATUS_REQUESTS", d.RateLimit.Status.Requests),
+				Window:   getDurationEnv("RATE_LIMIT_STATUS_WINDOW", d.RateLimit.Status.Window),
+			},
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:              getDurationEnv("IDEMPOTENCY_TTL", d.Idempotency.TTL),
+			LockTTL:          getDurationEnv("IDEMPOTENCY_LOCK_TTL", d.Idempotency.LockTTL),
+			Wait:             getBoolEnv("IDEMPOTENCY_WAIT", d.Idempotency.Wait),
+			WaitTimeout:      getDurationEnv("IDEMPOTENCY_WAIT_TIMEOUT", d.Idempotency.WaitTimeout),
+			MaxResponseBytes: getIntEnv("IDEMPOTENCY_MAX_RESPONSE_BYTES", d.Idempotency.MaxResponseBytes),
 		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", d.SMTP.Host),
+			Port:     getEnv("SMTP_PORT", d.SMTP.Port),
+			Username: getEnv("SMTP_USERNAME", d.SMTP.Username),
+			Password: secrets.get("SMTP_PASSWORD", d.SMTP.Password),
+			From:     getEnv("SMTP_FROM", d.SMTP.From),
+			TLSMode:  getEnv("SMTP_TLS_MODE", d.SMTP.TLSMode),
+			Timeout:  getDurationEnv("SMTP_TIMEOUT", d.SMTP.Timeout),
+		},
+		Storage: StorageConfig{
+			Provider:   getEnv("STORAGE_PROVIDER", d.Storage.Provider),
+			Bucket:     getEnv("STORAGE_BUCKET", d.Storage.Bucket),
+			Region:     getEnv("STORAGE_REGION", d.Storage.Region),
+			Endpoint:   getEnv("STORAGE_ENDPOINT", d.Storage.Endpoint),
+			AccessKey:  secrets.get("STORAGE_ACCESS_KEY", d.Storage.AccessKey),
+			SecretKey:  secrets.get("STORAGE_SECRET_KEY", d.Storage.SecretKey),
+			BaseURL:    getEnv("STORAGE_BASE_URL", d.Storage.BaseURL),
+			PresignTTL: getDurationEnv("STORAGE_PRESIGN_TTL", d.Storage.PresignTTL),
+			Dir:        getEnv("STORAGE_DIR", d.Storage.Dir),
+		},
+		Features: getFeaturesEnv("FEATURES", d.Features),
+	}
+
+	// Connection URLs, as Heroku-style platforms provide, take precedence
+	// over the individual variables
+	var errs []error
+	if raw := secrets.get("DATABASE_URL", ""); raw != "" {
+		if db, err := ParseDatabaseURL(raw); err != nil {
+			errs = append(errs, fmt.Errorf("DATABASE_URL: %w", err))
+		} else {
+			cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName = db.Host, db.Port, db.DBName
+			cfg.Database.User, cfg.Database.Password = db.User, db.Password
+			if db.SSLMode != "" {
+				cfg.Database.SSLMode = db.SSLMode
+			}
+			cfg.Database.Params = db.Params
+		}
 	}
+	if raw := secrets.get("REDIS_URL", ""); raw != "" {
+		if redis, err := ParseRedisURL(raw); err != nil {
+			errs = append(errs, fmt.Errorf("REDIS_URL: %w", err))
+		} else {
+			cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.DB = redis.Host, redis.Port, redis.DB
+			cfg.Redis.Username, cfg.Redis.Password, cfg.Redis.TLS = redis.Username, redis.Password, redis.TLS
+		}
+	}
+
+	return cfg, errors.Join(append(errs, secrets.err())...)
 }
 
-// GetDSN returns the database connection string
+// GetDSN returns the database connection string, quoting values that
+// contain spaces, quotes or backslashes
 func (c *DatabaseConfig) GetDSN() string {
-	return "host=" + c.Host +
-		" port=" + c.Port +
-		" user=" + c.User +
-		" password=" + c.Password +
-		" dbname=" + c.DBName +
-		" sslmode=" + c.SSLMode
+	dsn := "host=" + dsnValue(c.Host) +
+		" port=" + dsnValue(c.Port) +
+		" user=" + dsnValue(c.User) +
+		" password=" + dsnValue(c.Password) +
+		" dbname=" + dsnValue(c.DBName) +
+		" sslmode=" + dsnValue(c.SSLMode)
+	for _, key := range sortedKeys(c.Params) {
+		dsn += " " + key + "=" + dsnValue(c.Params[key])
+	}
+	return dsn
 }
 
-// GetRedisAddr returns the Redis address
+// GetRedisAddr returns the Redis address in single mode
 func (c *RedisConfig) GetRedisAddr() string {
 	return c.Host + ":" + c.Port
 }
 
+// FileOptions returns the logger's file output settings
+func (c *LogConfig) FileOptions() logger.FileOptions {
+	return logger.FileOptions{
+		Path:       c.File,
+		MaxSizeMB:  c.MaxSizeMB,
+		MaxBackups: c.MaxBackups,
+		MaxAgeDays: c.MaxAgeDays,
+		Compress:   c.Compress,
+	}
+}
+
 // GetServerAddr returns the server address
 func (c *ServerConfig) GetServerAddr() string {
 	return c.Host + ":" + c.Port
@@ -142,6 +685,57 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// secretEnv reads secret-bearing variables. KEY_FILE, if set, names a file
+// holding the value, as Docker and Kubernetes mount secrets, and takes
+// precedence over KEY so the secret need not sit in the environment.
+type secretEnv struct {
+	errs []error
+}
+
+// get returns the secret for key, or defaultValue if neither key nor
+// key_FILE is set. A _FILE that cannot be read or is empty is recorded as an
+// error rather than falling back to the default.
+func (s *secretEnv) get(key, defaultValue string) string {
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return getEnv(key, defaultValue)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s_FILE: %w", key, err))
+		return defaultValue
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		s.errs = append(s.errs, fmt.Errorf("%s_FILE: %s is empty", key, path))
+		return defaultValue
+	}
+	return value
+}
+
+// getMap is get for a map of secrets in getMapEnv's format, with the
+// default for unset or malformed values
+func (s *secretEnv) getMap(key string, defaultValue map[string]string) map[string]string {
+	if values := parseMap(s.get(key, "")); values != nil {
+		return values
+	}
+	return defaultValue
+}
+
+// getList is get for a comma-separated list of secrets, with the default
+// when neither key nor key_FILE is set
+func (s *secretEnv) getList(key string, defaultValue []string) []string {
+	if _, ok := os.LookupEnv(key); !ok && os.Getenv(key+"_FILE") == "" {
+		return defaultValue
+	}
+	return parseList(s.get(key, ""))
+}
+
+// err joins the errors from reading secret files
+func (s *secretEnv) err() error {
+	return errors.Join(s.errs...)
+}
+
 func getIntEnv(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -151,6 +745,15 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -168,3 +771,57 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getListEnv parses a comma-separated list, dropping empty entries
+func getListEnv(key string) []string {
+	return parseList(os.Getenv(key))
+}
+
+// parseList parses value in getListEnv's format
+func parseList(value string) []string {
+	var values []string
+	for _, value := range strings.Split(value, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getListEnvDefault is getListEnv with a default for unset variables
+func getListEnvDefault(key string, defaultValue []string) []string {
+	if _, ok := os.LookupEnv(key); !ok {
+		return defaultValue
+	}
+	return getListEnv(key)
+}
+
+// getMapEnv parses a JSON object or a comma-separated list of key:value
+// pairs. Malformed values yield nil.
+func getMapEnv(key string) map[string]string {
+	return parseMap(os.Getenv(key))
+}
+
+// parseMap parses value in getMapEnv's format
+func parseMap(value string) map[string]string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	values := map[string]string{}
+	if strings.HasPrefix(value, "{") {
+		if err := json.Unmarshal([]byte(value), &values); err != nil {
+			return nil
+		}
+		return values
+	}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || k == "" || v == "" {
+			return nil
+		}
+		values[k] = v
+	}
+	return values
+}
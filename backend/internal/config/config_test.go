@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -186,3 +189,357 @@ func TestIntParsing(t *testing.T) {
 	// Clean up
 	os.Unsetenv("DB_MAX_OPEN_CONNS")
 }
+
+func TestGetMapEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+	}{
+		{"", nil},
+		{"k1:secret1, k2:secret2", map[string]string{"k1": "secret1", "k2": "secret2"}},
+		{`{"k1":"secret1"}`, map[string]string{"k1": "secret1"}},
+		{"k1:secret:with:colons", map[string]string{"k1": "secret:with:colons"}},
+		{"k1:secret1,broken", nil},
+		{`{"k1":`, nil},
+	}
+	for _, tt := range tests {
+		t.Setenv("JWT_KEYS", tt.value)
+		got := getMapEnv("JWT_KEYS")
+		if len(got) != len(tt.want) {
+			t.Errorf("getMapEnv(%q) = %v, want %v", tt.value, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("getMapEnv(%q)[%s] = %q, want %q", tt.value, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestGetListEnv(t *testing.T) {
+	t.Setenv("JWT_ALLOWED_AUDIENCES", " web, mobile ,,")
+	got := getListEnv("JWT_ALLOWED_AUDIENCES")
+	if len(got) != 2 || got[0] != "web" || got[1] != "mobile" {
+		t.Errorf("getListEnv = %v, want [web mobile]", got)
+	}
+
+	t.Setenv("JWT_ALLOWED_AUDIENCES", "")
+	if got := getListEnv("JWT_ALLOWED_AUDIENCES"); got != nil {
+		t.Errorf("getListEnv of empty value = %v, want nil", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	strongSecret := strings.Repeat("s", MinJWTSecretBytes)
+
+	tests := []struct {
+		name   string
+		env    string
+		mutate func(*Config)
+		// wantKey is the environment variable the error names; empty means valid
+		wantKey string
+	}{
+		{"defaults in development", "development", func(c *Config) {}, ""},
+		{"default secret allowed in development", "development", func(c *Config) { c.Auth.JWTSecret = DefaultJWTSecret }, ""},
+		{"short secret allowed in development", "development", func(c *Config) { c.Auth.JWTSecret = "dev" }, ""},
+		{"short secret allowed in staging", "staging", func(c *Config) { c.Auth.JWTSecret = "dev" }, ""},
+		{"strong secret in production", "production", func(c *Config) { c.Auth.JWTSecret = strongSecret }, ""},
+		{"default secret in production", "production", func(c *Config) { c.Auth.JWTSecret = DefaultJWTSecret }, "JWT_SECRET"},
+		{"short secret in production", "production", func(c *Config) { c.Auth.JWTSecret = strongSecret[1:] }, "JWT_SECRET"},
+		{"short rotation key in production", "production", func(c *Config) {
+			c.Auth.JWTSecret = strongSecret
+			c.Auth.JWTKeys, c.Auth.JWTKeyID = map[string]string{"k1": strongSecret, "k2": "short"}, "k1"
+		}, "JWT_KEYS"},
+		{"rotation keys", "development", func(c *Config) { c.Auth.JWTKeys, c.Auth.JWTKeyID = map[string]string{"k1": "one", "k2": "two"}, "k2" }, ""},
+		{"rotation keys without key ID", "development", func(c *Config) { c.Auth.JWTKeys = map[string]string{"k1": "one"} }, "JWT_KEY_ID"},
+		{"key ID missing from keys", "development", func(c *Config) { c.Auth.JWTKeys, c.Auth.JWTKeyID = map[string]string{"k1": "one"}, "k2" }, "JWT_KEY_ID"},
+		{"key ID without keys", "development", func(c *Config) { c.Auth.JWTKeyID = "k1" }, "JWT_KEY_ID"},
+
+		{"no password with ssl disabled", "development", func(c *Config) { c.Database.SSLMode, c.Database.Password = "disable", "" }, ""},
+		{"no password with ssl required", "development", func(c *Config) { c.Database.SSLMode, c.Database.Password = "require", "" }, "DB_PASSWORD"},
+		{"password with ssl required", "development", func(c *Config) { c.Database.SSLMode, c.Database.Password = "verify-full", "pw" }, ""},
+
+		{"non-numeric server port", "development", func(c *Config) { c.Server.Port = "http" }, "SERVICE_PORT"},
+		{"zero database port", "development", func(c *Config) { c.Database.Port = "0" }, "DB_PORT"},
+		{"redis port out of range", "development", func(c *Config) { c.Redis.Port = "65536" }, "REDIS_PORT"},
+		{"highest port", "development", func(c *Config) { c.Redis.Port = "65535" }, ""},
+
+		{"zero read timeout", "development", func(c *Config) { c.Server.ReadTimeout = 0 }, "SERVER_READ_TIMEOUT"},
+		{"negative connection lifetime", "development", func(c *Config) { c.Database.ConnMaxLifetime = -time.Second }, "DB_CONN_MAX_LIFETIME"},
+		{"zero JWT expiration", "development", func(c *Config) { c.Auth.JWTExpiration = 0 }, "JWT_EXPIRATION"},
+		{"zero rate limit window", "development", func(c *Config) { c.RateLimit.API.Window = 0 }, "RATE_LIMIT_API_WINDOW"},
+		{"zero clock skew", "development", func(c *Config) { c.Auth.ClockSkew = 0 }, ""},
+		{"negative clock skew", "development", func(c *Config) { c.Auth.ClockSkew = -time.Second }, "JWT_CLOCK_SKEW"},
+
+		{"sentinel mode", "development", func(c *Config) { c.Redis.Mode, c.Redis.MasterName = RedisModeSentinel, "mymaster" }, ""},
+		{"sentinel mode without master name", "development", func(c *Config) { c.Redis.Mode = RedisModeSentinel }, "REDIS_MASTER_NAME"},
+		{"cluster mode", "development", func(c *Config) { c.Redis.Mode = RedisModeCluster }, ""},
+		{"unknown redis mode", "development", func(c *Config) { c.Redis.Mode = "replica" }, "REDIS_MODE"},
+		{"zero redis dial timeout", "development", func(c *Config) { c.Redis.DialTimeout = 0 }, "REDIS_DIAL_TIMEOUT"},
+		{"negative redis pool size", "development", func(c *Config) { c.Redis.PoolSize = -1 }, "REDIS_POOL_SIZE"},
+
+		{"no smtp host", "development", func(c *Config) { c.SMTP.From = "" }, ""},
+		{"smtp host", "development", func(c *Config) { c.SMTP.Host, c.SMTP.From = "smtp.example.com", "TG Finance <no-reply@example.com>" }, ""},
+		{"smtp host without from", "development", func(c *Config) { c.SMTP.Host, c.SMTP.From = "smtp.example.com", "" }, "SMTP_FROM"},
+		{"unknown smtp tls mode", "development", func(c *Config) {
+			c.SMTP.Host, c.SMTP.From, c.SMTP.TLSMode = "smtp.example.com", "no-reply@example.com", "ssl"
+		}, "SMTP_TLS_MODE"},
+		{"bad smtp port", "development", func(c *Config) {
+			c.SMTP.Host, c.SMTP.From, c.SMTP.Port = "smtp.example.com", "no-reply@example.com", "smtp"
+		}, "SMTP_PORT"},
+		{"zero smtp timeout", "development", func(c *Config) { c.SMTP.Timeout = 0 }, "SMTP_TIMEOUT"},
+
+		{"filesystem storage", "development", func(c *Config) {}, ""},
+		{"filesystem storage without dir", "development", func(c *Config) { c.Storage.Dir = "" }, "STORAGE_DIR"},
+		{"unknown storage provider", "development", func(c *Config) { c.Storage.Provider = "gcs" }, "STORAGE_PROVIDER"},
+		{"s3 storage", "development", func(c *Config) {
+			c.Storage.Provider, c.Storage.Bucket, c.Storage.AccessKey, c.Storage.SecretKey = StorageS3, "receipts", "key", "secret"
+		}, ""},
+		{"s3 storage without bucket", "development", func(c *Config) {
+			c.Storage.Provider, c.Storage.AccessKey, c.Storage.SecretKey = StorageS3, "key", "secret"
+		}, "STORAGE_BUCKET"},
+		{"s3 storage without credentials", "development", func(c *Config) {
+			c.Storage.Provider, c.Storage.Bucket = StorageS3, "receipts"
+		}, "STORAGE_SECRET_KEY"},
+		{"minio storage without endpoint", "development", func(c *Config) {
+			c.Storage.Provider, c.Storage.Bucket, c.Storage.AccessKey, c.Storage.SecretKey = StorageMinIO, "receipts", "key", "secret"
+		}, "STORAGE_ENDPOINT"},
+		{"s3 presign ttl over seven days", "development", func(c *Config) {
+			c.Storage.Provider, c.Storage.Bucket, c.Storage.AccessKey, c.Storage.SecretKey = StorageS3, "receipts", "key", "secret"
+			c.Storage.PresignTTL = 8 * 24 * time.Hour
+		}, "STORAGE_PRESIGN_TTL"},
+		{"storage endpoint without scheme", "development", func(c *Config) { c.Storage.Endpoint = "minio:9000" }, "STORAGE_ENDPOINT"},
+		{"relative storage base url", "development", func(c *Config) { c.Storage.BaseURL = "files" }, "STORAGE_BASE_URL"},
+		{"storage base path", "development", func(c *Config) { c.Storage.BaseURL = "/files" }, ""},
+
+		{"unknown log level", "development", func(c *Config) { c.Log.Level = "verbose" }, "LOG_LEVEL"},
+		{"log level is case sensitive", "development", func(c *Config) { c.Log.Level = "INFO" }, "LOG_LEVEL"},
+		{"text log format", "development", func(c *Config) { c.Log.Format = "text" }, ""},
+		{"unknown log format", "development", func(c *Config) { c.Log.Format = "xml" }, "LOG_FORMAT"},
+		{"both log output", "development", func(c *Config) { c.Log.Output = "both" }, ""},
+		{"unknown log output", "development", func(c *Config) { c.Log.Output = "syslog" }, "LOG_OUTPUT"},
+		{"file output without a file", "development", func(c *Config) { c.Log.Output, c.Log.File = "file", "" }, "LOG_FILE"},
+		{"stdout output without a file", "development", func(c *Config) { c.Log.File = "" }, ""},
+		{"unlimited log rotation", "development", func(c *Config) { c.Log.MaxSizeMB, c.Log.MaxBackups, c.Log.MaxAgeDays = 0, 0, 0 }, ""},
+		{"negative log max size", "development", func(c *Config) { c.Log.MaxSizeMB = -1 }, "LOG_MAX_SIZE_MB"},
+		{"negative log max backups", "development", func(c *Config) { c.Log.MaxBackups = -1 }, "LOG_MAX_BACKUPS"},
+
+		{"idle conns equal open conns", "development", func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 10, 10 }, ""},
+		{"idle conns above open conns", "development", func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 10, 11 }, "DB_MAX_IDLE_CONNS"},
+		{"unlimited open conns", "development", func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 0, 50 }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENV", tt.env)
+			cfg := Load()
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if tt.wantKey == "" {
+				if err != nil {
+					t.Errorf("Expected valid config, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantKey+":") {
+				t.Errorf("Expected an error naming %s, got %v", tt.wantKey, err)
+			}
+		})
+	}
+}
+
+func TestConfigValidateReportsEveryProblem(t *testing.T) {
+	cfg := Load()
+	cfg.Server.Port = "x"
+	cfg.Log.Level = "loud"
+	cfg.Idempotency.TTL = 0
+
+	err := cfg.Validate()
+	for _, key := range []string{"SERVICE_PORT", "LOG_LEVEL", "IDEMPOTENCY_TTL"} {
+		if err == nil || !strings.Contains(err.Error(), key+":") {
+			t.Errorf("Expected an error naming %s, got %v", key, err)
+		}
+	}
+}
+
+func TestLoadAndValidate(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("JWT_SECRET", "")
+	if cfg, err := LoadAndValidate(); err == nil || cfg != nil {
+		t.Errorf("Expected the default secret to fail in production, got %v, %v", cfg, err)
+	}
+
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinJWTSecretBytes))
+	cfg, err := LoadAndValidate()
+	if err != nil {
+		t.Fatalf("Expected a valid production config, got %v", err)
+	}
+	if cfg.Auth.JWTSecret != strings.Repeat("s", MinJWTSecretBytes) {
+		t.Errorf("Expected the configured JWT secret, got %q", cfg.Auth.JWTSecret)
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents *string // nil leaves the file missing
+		plain    string
+		want     string
+		wantErr  bool
+	}{
+		{"file", str("file-secret"), "", "file-secret", false},
+		{"trailing newline is trimmed", str("file-secret\n"), "", "file-secret", false},
+		{"trailing CRLF is trimmed", str("file-secret\r\n"), "", "file-secret", false},
+		{"inner whitespace is kept", str(" file secret \n"), "", " file secret ", false},
+		{"file wins over the plain variable", str("file-secret\n"), "env-secret", "file-secret", false},
+		{"0-byte file", str(""), "env-secret", "", true},
+		{"only a newline", str("\n"), "", "", true},
+		{"missing file", nil, "env-secret", "", true},
+	}
+
+	for _, key := range []string{"DB_PASSWORD", "JWT_SECRET", "REDIS_PASSWORD", "STORAGE_ACCESS_KEY", "STORAGE_SECRET_KEY"} {
+		for _, tt := range tests {
+			t.Run(key+"/"+tt.name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "secret")
+				if tt.contents != nil {
+					path = writeConfigFile(t, "secret", *tt.contents)
+				}
+				t.Setenv(key+"_FILE", path)
+				t.Setenv(key, tt.plain)
+
+				cfg, err := LoadAndValidate()
+				if tt.wantErr {
+					if err == nil || !strings.Contains(err.Error(), key+"_FILE") {
+						t.Errorf("Expected an error naming %s_FILE, got %v", key, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("LoadAndValidate() error = %v", err)
+				}
+				got := map[string]string{
+					"DB_PASSWORD":        cfg.Database.Password,
+					"JWT_SECRET":         cfg.Auth.JWTSecret,
+					"REDIS_PASSWORD":     cfg.Redis.Password,
+					"STORAGE_ACCESS_KEY": cfg.Storage.AccessKey,
+					"STORAGE_SECRET_KEY": cfg.Storage.SecretKey,
+				}[key]
+				if got != tt.want {
+					t.Errorf("%s = %q, want %q", key, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadKeepsDefaultsForUnreadableValues(t *testing.T) {
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("DATABASE_URL", "mysql://localhost/tgfinance")
+	t.Setenv("SERVICE_PORT", "9500")
+
+	if _, err := LoadAndValidate(); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD_FILE") || !strings.Contains(err.Error(), "DATABASE_URL") {
+		t.Errorf("Expected both errors from LoadAndValidate, got %v", err)
+	}
+	cfg := Load()
+	if cfg.Database.Password != defaults().Database.Password || cfg.Database.Host != defaults().Database.Host {
+		t.Errorf("Expected unreadable values to keep their defaults, got %+v", cfg.Database)
+	}
+	if cfg.Server.Port != "9500" {
+		t.Errorf("Expected the rest of the environment to apply, got port %s", cfg.Server.Port)
+	}
+}
+
+func TestJWTKeysFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents *string // nil leaves the file missing
+		plain    string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{"JSON", str("{\n  \"k1\": \"secret1\",\n  \"k2\": \"secret2\"\n}\n"), "", map[string]string{"k1": "secret1", "k2": "secret2"}, false},
+		{"key:value pairs", str("k1:secret1,k2:secret2\n"), "", map[string]string{"k1": "secret1", "k2": "secret2"}, false},
+		{"file wins over the plain variable", str("k1:file-secret\n"), "k1:env-secret", map[string]string{"k1": "file-secret"}, false},
+		{"malformed file keeps the default", str("k1:secret1,broken\n"), "k1:env-secret", nil, false},
+		{"0-byte file", str(""), "k1:env-secret", nil, true},
+		{"missing file", nil, "k1:env-secret", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jwt_keys")
+			if tt.contents != nil {
+				path = writeConfigFile(t, "jwt_keys", *tt.contents)
+			}
+			t.Setenv("JWT_KEYS_FILE", path)
+			t.Setenv("JWT_KEYS", tt.plain)
+			if tt.want != nil {
+				t.Setenv("JWT_KEY_ID", "k1")
+			}
+
+			cfg, err := LoadAndValidate()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "JWT_KEYS_FILE") {
+					t.Errorf("Expected an error naming JWT_KEYS_FILE, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAndValidate() error = %v", err)
+			}
+			if len(cfg.Auth.JWTKeys) != len(tt.want) {
+				t.Fatalf("JWTKeys = %v, want %v", cfg.Auth.JWTKeys, tt.want)
+			}
+			for kid, secret := range tt.want {
+				if cfg.Auth.JWTKeys[kid] != secret {
+					t.Errorf("JWTKeys[%s] = %q, want %q", kid, cfg.Auth.JWTKeys[kid], secret)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordPeppersFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents *string // nil leaves the file missing
+		plain    string
+		want     []string
+		wantErr  bool
+	}{
+		{"single pepper", str("pepper-1\n"), "", []string{"pepper-1"}, false},
+		{"current first", str("pepper-2, pepper-1\n"), "", []string{"pepper-2", "pepper-1"}, false},
+		{"file wins over the plain variable", str("file-pepper\n"), "env-pepper", []string{"file-pepper"}, false},
+		{"0-byte file", str(""), "env-pepper", nil, true},
+		{"missing file", nil, "env-pepper", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "peppers")
+			if tt.contents != nil {
+				path = writeConfigFile(t, "peppers", *tt.contents)
+			}
+			t.Setenv("PASSWORD_PEPPERS_FILE", path)
+			t.Setenv("PASSWORD_PEPPERS", tt.plain)
+
+			cfg, err := LoadAndValidate()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "PASSWORD_PEPPERS_FILE") {
+					t.Errorf("Expected an error naming PASSWORD_PEPPERS_FILE, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAndValidate() error = %v", err)
+			}
+			if !slices.Equal(cfg.Auth.PasswordPeppers, tt.want) {
+				t.Errorf("PasswordPeppers = %q, want %q", cfg.Auth.PasswordPeppers, tt.want)
+			}
+		})
+	}
+}
+
+func str(s string) *string { return &s }
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Feature names a flag gating functionality that is not yet generally
+// available
+type Feature string
+
+// Known features
+const (
+	// FeatureMultiCurrency allows accounts and expenses in currencies other
+	// than the user's base currency
+	FeatureMultiCurrency Feature = "multi_currency"
+	// FeatureBudgets enables the budgets API
+	FeatureBudgets Feature = "budgets"
+)
+
+// knownFeatures are the features the application checks
+var knownFeatures = []Feature{FeatureMultiCurrency, FeatureBudgets}
+
+// KnownFeatures returns the names of the features the application checks
+func KnownFeatures() []string {
+	names := make([]string, len(knownFeatures))
+	for i, f := range knownFeatures {
+		names[i] = string(f)
+	}
+	return names
+}
+
+// FeatureFlags maps feature names to whether they are on; features not
+// listed are off. A loaded FeatureFlags is never modified, so it may be read
+// concurrently; reloads replace it.
+type FeatureFlags map[Feature]bool
+
+// IsEnabled reports whether the named feature is on
+func (f FeatureFlags) IsEnabled(name Feature) bool {
+	return f[name]
+}
+
+// MultiCurrency reports whether FeatureMultiCurrency is on
+func (f FeatureFlags) MultiCurrency() bool {
+	return f.IsEnabled(FeatureMultiCurrency)
+}
+
+// Budgets reports whether FeatureBudgets is on
+func (f FeatureFlags) Budgets() bool {
+	return f.IsEnabled(FeatureBudgets)
+}
+
+// Unknown returns the sorted flag names the application does not check,
+// usually typos
+func (f FeatureFlags) Unknown() []string {
+	var unknown []string
+	for name := range f {
+		if !slices.Contains(knownFeatures, name) {
+			unknown = append(unknown, string(name))
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// getFeaturesEnv parses a comma-separated list of enabled features. When the
+// variable is set it replaces defaultValue entirely, so FEATURES= turns every
+// feature off.
+func getFeaturesEnv(key string, defaultValue FeatureFlags) FeatureFlags {
+	if _, ok := os.LookupEnv(key); !ok {
+		return defaultValue
+	}
+	flags := FeatureFlags{}
+	for _, name := range getListEnv(key) {
+		flags[Feature(strings.ToLower(name))] = true
+	}
+	return flags
+}
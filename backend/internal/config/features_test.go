@@ -0,0 +1,97 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFeaturesEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *string
+		want  FeatureFlags
+	}{
+		{"unset", nil, nil},
+		{"empty", str(""), FeatureFlags{}},
+		{"one", str("budgets"), FeatureFlags{FeatureBudgets: true}},
+		{"list with spaces and case", str(" Multi_Currency , budgets,"), FeatureFlags{FeatureMultiCurrency: true, FeatureBudgets: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != nil {
+				t.Setenv("FEATURES", *tt.value)
+			}
+			cfg, err := applyEnv(defaults())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.Features, tt.want) {
+				t.Errorf("Features = %v, want %v", cfg.Features, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeaturesFromFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "features:\n  multi_currency: true\n  budgets: false\n")
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Features.MultiCurrency() || cfg.Features.Budgets() {
+		t.Errorf("Expected multi_currency on and budgets off, got %v", cfg.Features)
+	}
+
+	// FEATURES replaces the file's flags rather than merging with them
+	t.Setenv("FEATURES", "budgets")
+	if cfg, err = LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Features.MultiCurrency() || !cfg.Features.Budgets() {
+		t.Errorf("Expected only budgets on, got %v", cfg.Features)
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	var none FeatureFlags
+	if none.IsEnabled(FeatureBudgets) || none.MultiCurrency() {
+		t.Error("Expected features to be off without flags")
+	}
+
+	flags := FeatureFlags{FeatureBudgets: true, "bugdets": true, "dark_mode": false}
+	if !flags.IsEnabled("budgets") || !flags.Budgets() || flags.MultiCurrency() {
+		t.Errorf("Unexpected accessors for %v", flags)
+	}
+	if got, want := flags.Unknown(), []string{"bugdets", "dark_mode"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unknown() = %v, want %v", got, want)
+	}
+	if got, want := KnownFeatures(), []string{"multi_currency", "budgets"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("KnownFeatures() = %v, want %v", got, want)
+	}
+}
+
+func TestWatcherReloadsFeatures(t *testing.T) {
+	loaded := defaults()
+	loaded.Features = FeatureFlags{FeatureBudgets: true}
+	w, _, _ := newTestWatcher(func() (*Config, error) { return loaded, nil })
+
+	var got FeatureFlags
+	calls := 0
+	w.OnChange(SectionFeatures, func(cfg *Config) { got, calls = cfg.Features, calls+1 })
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Budgets() || !w.Current().Features.Budgets() {
+		t.Errorf("Expected budgets to be enabled by the reload, got %v", got)
+	}
+
+	// Turning every flag off is a change
+	loaded = defaults()
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || got.Budgets() || w.Current().Features.Budgets() {
+		t.Errorf("Expected budgets to be disabled by the reload, got %v", got)
+	}
+}
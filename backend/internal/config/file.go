@@ -0,0 +1,72 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFromFile loads configuration from a YAML or JSON file, then applies
+// environment variables over it, so precedence is defaults < file < env.
+// Keys follow the yaml struct tags, such as database.max_open_conns, and
+// durations are strings such as "30s" or "5m". Keys the file sets that
+// Config does not have are an error listing every one of them.
+func LoadFromFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	cfg := defaults()
+	if len(bytes.TrimSpace(data)) > 0 {
+		// JSON is a subset of YAML, so one decoder reads both
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+		if unknown := unknownKeys(&doc, reflect.TypeOf(*cfg), ""); len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("config file %s has unknown keys: %s", path, strings.Join(unknown, ", "))
+		}
+		if err := doc.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+	return applyEnv(cfg)
+}
+
+// unknownKeys returns the dotted paths of keys in node that have no field
+// with a matching yaml tag in the struct type t
+func unknownKeys(node *yaml.Node, t reflect.Type, prefix string) []string {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return unknownKeys(node.Content[0], t, prefix)
+	}
+	if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	var unknown []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		fieldType, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+		unknown = append(unknown, unknownKeys(node.Content[i+1], fieldType, prefix+key+".")...)
+	}
+	return unknown
+}
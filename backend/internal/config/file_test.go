@@ -0,0 +1,235 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfigFile writes contents to a file named name in a temporary
+// directory and returns its path
+func writeConfigFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFromFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: "9100"
+  read_timeout: 45s
+  cors_allowed_origins: [https://app.example.com]
+database:
+  host: db.internal
+  max_open_conns: 40
+  conn_max_lifetime: 10m
+auth:
+  jwt_keys:
+    k1: secret-one
+rate_limit:
+  api:
+    requests: 500
+    window: 2m
+`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if cfg.Server.Port != "9100" || cfg.Server.ReadTimeout != 45*time.Second {
+		t.Errorf("Expected server port 9100 and read timeout 45s, got %s and %v", cfg.Server.Port, cfg.Server.ReadTimeout)
+	}
+	if !reflect.DeepEqual(cfg.Server.CORSAllowedOrigins, []string{"https://app.example.com"}) {
+		t.Errorf("Expected CORS origins from the file, got %v", cfg.Server.CORSAllowedOrigins)
+	}
+	if cfg.Database.Host != "db.internal" || cfg.Database.MaxOpenConns != 40 || cfg.Database.ConnMaxLifetime != 10*time.Minute {
+		t.Errorf("Expected database settings from the file, got %+v", cfg.Database)
+	}
+	if cfg.Auth.JWTKeys["k1"] != "secret-one" {
+		t.Errorf("Expected JWT key k1 from the file, got %v", cfg.Auth.JWTKeys)
+	}
+	if cfg.RateLimit.API != (RateLimitRule{Requests: 500, Window: 2 * time.Minute}) {
+		t.Errorf("Expected API rate limit from the file, got %+v", cfg.RateLimit.API)
+	}
+
+	// Keys the file leaves out keep their defaults
+	if cfg.Server.WriteTimeout != 30*time.Second || cfg.Database.DBName != "tgfinance" || cfg.RateLimit.Auth.Requests != 10 {
+		t.Errorf("Expected defaults for keys not in the file, got %+v", cfg)
+	}
+}
+
+func TestLoadFromFileJSON(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{
+	"server": {"port": "9200", "idle_timeout": "90s"},
+	"log": {"level": "debug", "format": "text"},
+	"idempotency": {"ttl": "1h", "wait": true}
+}`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if cfg.Server.Port != "9200" || cfg.Server.IdleTimeout != 90*time.Second {
+		t.Errorf("Expected server port 9200 and idle timeout 90s, got %s and %v", cfg.Server.Port, cfg.Server.IdleTimeout)
+	}
+	if cfg.Log.Level != "debug" || cfg.Log.Format != "text" {
+		t.Errorf("Expected debug text logging, got %s %s", cfg.Log.Level, cfg.Log.Format)
+	}
+	if cfg.Idempotency.TTL != time.Hour || !cfg.Idempotency.Wait {
+		t.Errorf("Expected idempotency TTL 1h with wait, got %+v", cfg.Idempotency)
+	}
+}
+
+func TestLoadFromFileRoundTrip(t *testing.T) {
+	want := defaults()
+	want.Server.Port = "9300"
+	want.Server.MaintenanceRetryAfter = 90 * time.Second
+	want.Server.CORSAllowedOrigins = []string{"https://app.example.com"}
+	want.Server.ReceiptURLHosts = []string{".storage.example.com"}
+	want.Database.Password = "db-password"
+	want.Database.Params = map[string]string{"application_name": "tgfinance"}
+	want.Auth.JWTKeys = map[string]string{"k1": "secret-one", "k2": "secret-two"}
+	want.Auth.JWTKeyID = "k2"
+	want.Auth.AllowedIssuers = []string{"tgfinance"}
+	want.Auth.AllowedAudiences = []string{"web", "mobile"}
+	want.Auth.ClockSkew = 30 * time.Second
+	want.Auth.PasswordPeppers = []string{"pepper"}
+	want.Auth.PublicPaths = []string{"GET /status"}
+	want.Auth.AdminAllowedNetworks = []string{"10.0.0.0/8"}
+	want.OCR.MinConfidence = 0.65
+	want.RateLimit.TrustedProxies = []string{"127.0.0.1"}
+	want.RateLimit.Auth.Window = 90 * time.Second
+
+	data, err := yaml.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "maintenance_retry_after: 1m30s") {
+		t.Errorf("Expected durations to be written as strings, got:\n%s", data)
+	}
+
+	got, err := LoadFromFile(writeConfigFile(t, "config.yaml", string(data)))
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	// Compare as YAML, where unset and empty lists are the same
+	again, err := yaml.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("Round trip changed the config:\ngot\n%s\nwant\n%s", again, data)
+	}
+	if got.Server.MaintenanceRetryAfter != 90*time.Second || got.Auth.JWTKeys["k2"] != "secret-two" {
+		t.Errorf("Expected values from the file, got %+v", got)
+	}
+}
+
+func TestLoadFromFileUnknownKeys(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: "9100"
+  read_timout: 45s
+databse:
+  host: db.internal
+rate_limit:
+  api:
+    request: 500
+`)
+
+	_, err := LoadFromFile(path)
+	if err == nil {
+		t.Fatal("Expected unknown keys to be rejected")
+	}
+	for _, key := range []string{"databse", "rate_limit.api.request", "server.read_timout"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected the error to name %s, got %v", key, err)
+		}
+	}
+}
+
+func TestLoadFromFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"malformed YAML", "server: [port"},
+		{"malformed JSON", `{"server": {"port": "9100"`},
+		{"bad duration", "server:\n  read_timeout: soon\n"},
+		{"numeric duration", "server:\n  read_timeout: 30\n"},
+		{"wrong type", "database:\n  max_open_conns: many\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadFromFile(writeConfigFile(t, "config.yaml", tt.contents)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if _, err := LoadFromFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected a missing file to be an error")
+	}
+	if cfg, err := LoadFromFile(writeConfigFile(t, "empty.yaml", "\n")); err != nil || cfg.Server.Port != "8001" {
+		t.Errorf("Expected an empty file to give the defaults, got %v", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: "9100"
+  host: 127.0.0.1
+database:
+  max_open_conns: 40
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("SERVICE_PORT", "9500")
+
+	for name, load := range map[string]func() (*Config, error){
+		"LoadFromFile":    func() (*Config, error) { return LoadFromFile(path) },
+		"Load":            func() (*Config, error) { return Load(), nil },
+		"LoadAndValidate": LoadAndValidate,
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := load()
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			// Environment wins over the file
+			if cfg.Server.Port != "9500" {
+				t.Errorf("Expected port 9500 from the environment, got %s", cfg.Server.Port)
+			}
+			// The file wins over the defaults
+			if cfg.Server.Host != "127.0.0.1" || cfg.Database.MaxOpenConns != 40 {
+				t.Errorf("Expected host and max open conns from the file, got %s and %d", cfg.Server.Host, cfg.Database.MaxOpenConns)
+			}
+			// Defaults fill in the rest
+			if cfg.Database.MaxIdleConns != 5 {
+				t.Errorf("Expected default max idle conns 5, got %d", cfg.Database.MaxIdleConns)
+			}
+		})
+	}
+}
+
+func TestLoadAndValidateBadConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "config.yaml", "sever:\n  port: \"9100\"\n"))
+
+	if _, err := LoadAndValidate(); err == nil || !strings.Contains(err.Error(), "sever") {
+		t.Errorf("Expected the unknown key to be reported, got %v", err)
+	}
+	// Load skips the file and keeps the environment
+	t.Setenv("SERVICE_PORT", "9500")
+	if cfg := Load(); cfg.Server.Port != "9500" || cfg.Server.Host != defaults().Server.Host {
+		t.Errorf("Expected the environment alone, got port %s and host %s", cfg.Server.Port, cfg.Server.Host)
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"net/http"
+
+	"tgfinance/pkg/httputil"
+)
+
+// Handler serves the admin configuration endpoint
+type Handler struct {
+	current func() *Config
+}
+
+// NewHandler creates a handler reporting the configuration current returns,
+// such as Watcher.Current so reloads show up
+func NewHandler(current func() *Config) *Handler {
+	return &Handler{current: current}
+}
+
+// GetConfig handles GET /api/v1/admin/config, returning the running
+// configuration with secrets redacted. It belongs behind RequireAdmin.
+func (h *Handler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	httputil.WriteJSON(w, http.StatusOK, h.current())
+}
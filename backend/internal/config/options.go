@@ -0,0 +1,72 @@
+package config
+
+import (
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/mailer"
+	"tgfinance/pkg/storage"
+)
+
+// JWTOptions returns the JWT manager's options for the configuration
+func (c *AuthConfig) JWTOptions() auth.JWTOptions {
+	return auth.JWTOptions{
+		Secret:           c.JWTSecret,
+		Keys:             c.JWTKeys,
+		KeyID:            c.JWTKeyID,
+		Issuer:           c.JWTIssuer,
+		Audience:         c.JWTAudience,
+		AllowedIssuers:   c.AllowedIssuers,
+		AllowedAudiences: c.AllowedAudiences,
+		AccessExpiry:     c.JWTExpiration,
+		RefreshExpiry:    c.RefreshExpiration,
+		ClockSkew:        c.ClockSkew,
+	}
+}
+
+// PasswordOptions returns the password manager's options for the
+// configuration
+func (c *AuthConfig) PasswordOptions() auth.PasswordOptions {
+	return auth.PasswordOptions{
+		Algorithm:         c.PasswordHashAlgorithm,
+		BcryptCost:        c.BcryptCost,
+		Argon2Memory:      c.Argon2Memory,
+		Argon2Iterations:  c.Argon2Iterations,
+		Argon2Parallelism: c.Argon2Parallelism,
+		Peppers:           c.PasswordPeppers,
+	}
+}
+
+// LockoutPolicy returns the login lockout policy for the configuration
+func (c *AuthConfig) LockoutPolicy() auth.LockoutPolicy {
+	return auth.LockoutPolicy{
+		MaxFailures: c.LockoutMaxFailures,
+		Window:      c.LockoutWindow,
+		Duration:    c.LockoutDuration,
+	}
+}
+
+// Options returns the SMTP sender's options for the configuration
+func (c *SMTPConfig) Options() mailer.SMTPOptions {
+	return mailer.SMTPOptions{
+		Host:     c.Host,
+		Port:     c.Port,
+		Username: c.Username,
+		Password: c.Password,
+		From:     c.From,
+		TLSMode:  c.TLSMode,
+		Timeout:  c.Timeout,
+	}
+}
+
+// Options returns the object store's options for the configuration
+func (c *StorageConfig) Options() storage.Options {
+	return storage.Options{
+		Provider:  c.Provider,
+		Bucket:    c.Bucket,
+		Region:    c.Region,
+		Endpoint:  c.Endpoint,
+		AccessKey: c.AccessKey,
+		SecretKey: c.SecretKey,
+		BaseURL:   c.BaseURL,
+		Dir:       c.Dir,
+	}
+}
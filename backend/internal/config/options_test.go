@@ -0,0 +1,76 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/mailer"
+	"tgfinance/pkg/storage"
+)
+
+func TestPasswordOptions(t *testing.T) {
+	cfg := Load()
+	cfg.Auth.PasswordHashAlgorithm = "argon2id"
+	cfg.Auth.PasswordPeppers = []string{"pepper"}
+	hash, err := auth.NewPasswordManagerWithOptions(cfg.Auth.PasswordOptions()).HashPassword("Correct-Horse-9")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		t.Errorf("hash = %q, want argon2id", hash)
+	}
+	if ok, _ := auth.NewPasswordManagerWithOptions(auth.PasswordOptions{Algorithm: "argon2id"}).CheckPassword(hash, "Correct-Horse-9"); ok {
+		t.Error("Expected the configured pepper to be applied")
+	}
+}
+
+func TestLockoutPolicy(t *testing.T) {
+	cfg := Load()
+	if policy := cfg.Auth.LockoutPolicy(); policy != auth.DefaultLockoutPolicy {
+		t.Errorf("default policy = %+v, want %+v", policy, auth.DefaultLockoutPolicy)
+	}
+	cfg.Auth.LockoutMaxFailures, cfg.Auth.LockoutWindow, cfg.Auth.LockoutDuration = 3, time.Minute, time.Hour
+	if policy := cfg.Auth.LockoutPolicy(); policy != (auth.LockoutPolicy{MaxFailures: 3, Window: time.Minute, Duration: time.Hour}) {
+		t.Errorf("policy = %+v", policy)
+	}
+}
+
+func TestSMTPOptions(t *testing.T) {
+	cfg := Load()
+	cfg.SMTP.Host = ""
+	if sender, err := mailer.New(cfg.SMTP.Options()); err != nil {
+		t.Fatalf("New() error = %v", err)
+	} else if _, ok := sender.(mailer.NoopSender); !ok {
+		t.Errorf("sender without host = %T, want mailer.NoopSender", sender)
+	}
+
+	cfg.SMTP.Host, cfg.SMTP.From, cfg.SMTP.TLSMode = "smtp.example.com", "no-reply@example.com", SMTPTLSStartTLS
+	if sender, err := mailer.New(cfg.SMTP.Options()); err != nil {
+		t.Errorf("New() error = %v", err)
+	} else if _, ok := sender.(*mailer.SMTPSender); !ok {
+		t.Errorf("sender = %T, want *mailer.SMTPSender", sender)
+	}
+}
+
+func TestStorageOptions(t *testing.T) {
+	cfg := Load()
+	cfg.Storage.Dir = t.TempDir()
+	store, err := storage.NewObjectStore(cfg.Storage.Options())
+	if err != nil {
+		t.Fatalf("NewObjectStore() error = %v", err)
+	}
+	if _, ok := store.(*storage.FileStore); !ok {
+		t.Errorf("default provider store = %T, want *storage.FileStore", store)
+	}
+
+	cfg.Storage.Provider = StorageMinIO
+	cfg.Storage.Bucket, cfg.Storage.Endpoint = "receipts", "http://minio:9000"
+	cfg.Storage.AccessKey, cfg.Storage.SecretKey = "access", "secret"
+	if store, err := storage.NewObjectStore(cfg.Storage.Options()); err != nil {
+		t.Errorf("minio store error = %v", err)
+	} else if _, ok := store.(*storage.S3Store); !ok {
+		t.Errorf("minio store = %T, want *storage.S3Store", store)
+	}
+}
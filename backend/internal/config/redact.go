@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedMark replaces secret values
+const redactedMark = "***"
+
+// Redacted returns a deep copy of the configuration with every field tagged
+// secret:"true" replaced by "***(N chars)", so the length still shows whether
+// a secret is set and roughly what it is. Empty secrets stay empty. Lists and
+// maps of secrets have each value replaced; map keys, such as JWT key IDs,
+// are kept.
+func (c Config) Redacted() *Config {
+	redacted := redactValue(reflect.ValueOf(c), false).Interface().(Config)
+	return &redacted
+}
+
+// String returns the redacted configuration as JSON, so formatting a Config
+// for a log never leaks secrets
+func (c Config) String() string {
+	data, err := c.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("config: %v", err)
+	}
+	return string(data)
+}
+
+// MarshalJSON encodes the redacted configuration under its config file keys,
+// with durations as strings such as "30s"
+func (c Config) MarshalJSON() ([]byte, error) {
+	// Going through YAML reuses the yaml tags for the key names
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return json.Marshal(values)
+}
+
+// redactValue returns a copy of v, replacing strings with their redacted
+// form when secret is set and descending into structs, lists and maps
+func redactValue(v reflect.Value, secret bool) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			out.Field(i).Set(redactValue(v.Field(i), field.Tag.Get("secret") == "true"))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return out
+		}
+		out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(redactValue(v.Index(i), secret))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return out
+		}
+		out.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), redactValue(iter.Value(), secret))
+		}
+	case reflect.String:
+		if secret && v.Len() > 0 {
+			out.SetString(fmt.Sprintf("%s(%d chars)", redactedMark, utf8.RuneCountInString(v.String())))
+		} else {
+			out.Set(v)
+		}
+	default:
+		out.Set(v)
+	}
+	return out
+}
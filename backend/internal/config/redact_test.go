@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/pkg/logger"
+)
+
+// secretConfig returns the defaults with every secret set to a recognizable
+// value
+func secretConfig() *Config {
+	cfg := defaults()
+	cfg.Server.MaintenanceBypassToken = "leak-bypass-token"
+	cfg.Database.Password = "leak-db-password"
+	cfg.Auth.JWTSecret = "leak-jwt-secret-0123456789abcdef0123456789"
+	cfg.Auth.JWTKeys = map[string]string{"k1": "leak-jwt-key-one", "k2": "leak-jwt-key-two"}
+	cfg.Auth.PasswordPeppers = []string{"leak-pepper-1", "leak-pepper-2"}
+	cfg.Redis.Password = "leak-redis-password"
+	cfg.OCR.APIKey = "leak-ocr-key"
+	cfg.SMTP.Password = "leak-smtp-password"
+	return cfg
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := secretConfig()
+	redacted := cfg.Redacted()
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"JWT secret", redacted.Auth.JWTSecret, "***(42 chars)"},
+		{"database password", redacted.Database.Password, "***(16 chars)"},
+		{"redis password", redacted.Redis.Password, "***(19 chars)"},
+		{"SMTP password", redacted.SMTP.Password, "***(18 chars)"},
+		{"OCR key", redacted.OCR.APIKey, "***(12 chars)"},
+		{"bypass token", redacted.Server.MaintenanceBypassToken, "***(17 chars)"},
+		{"JWT keys", redacted.Auth.JWTKeys, map[string]string{"k1": "***(16 chars)", "k2": "***(16 chars)"}},
+		{"peppers", redacted.Auth.PasswordPeppers, []string{"***(13 chars)", "***(13 chars)"}},
+		{"plain values kept", redacted.Database.User, "postgres"},
+		{"key ID kept", redacted.Auth.JWTKeyID, cfg.Auth.JWTKeyID},
+		{"lists kept", redacted.Log.AccessLogSkipPaths, []string{"/health", "/metrics"}},
+		{"unset secret stays empty", (&Config{}).Redacted().Database.Password, ""},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// The copy is deep, so changing it leaves the original alone
+	redacted.Auth.JWTKeys["k1"] = "changed"
+	redacted.Log.AccessLogSkipPaths[0] = "changed"
+	if cfg.Auth.JWTKeys["k1"] != "leak-jwt-key-one" || cfg.Log.AccessLogSkipPaths[0] != "/health" {
+		t.Error("Expected Redacted to copy lists and maps")
+	}
+	if cfg.Database.Password != "leak-db-password" {
+		t.Error("Expected Redacted to leave the original secrets alone")
+	}
+}
+
+func TestConfigFormattingHidesSecrets(t *testing.T) {
+	cfg := secretConfig()
+
+	var logs strings.Builder
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	log.WithFields(logrus.Fields{"config": cfg, "value": *cfg}).Info("Starting")
+
+	jsonData, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := map[string]string{
+		"%v":      fmt.Sprintf("%v", cfg),
+		"%+v":     fmt.Sprintf("%+v", *cfg),
+		"%s":      fmt.Sprintf("%s", cfg),
+		"String":  cfg.String(),
+		"JSON":    string(jsonData),
+		"logrus":  logs.String(),
+		"handler": serveConfig(t, cfg),
+	}
+	for name, out := range outputs {
+		if strings.Contains(out, "leak-") {
+			t.Errorf("%s output leaks a secret: %s", name, out)
+		}
+		if !strings.Contains(out, "***(16 chars)") {
+			t.Errorf("%s output lacks the redacted database password: %s", name, out)
+		}
+	}
+
+	var decoded map[string]map[string]interface{}
+	if err := json.Unmarshal(jsonData, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded["server"]["read_timeout"]; got != "30s" {
+		t.Errorf("Expected config file keys and duration strings, got server.read_timeout = %v", got)
+	}
+	if got := decoded["database"]["password"]; got != "***(16 chars)" {
+		t.Errorf("database.password = %v", got)
+	}
+}
+
+// serveConfig returns the admin config endpoint's response for cfg
+func serveConfig(t *testing.T, cfg *Config) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	NewHandler(func() *Config { return cfg }).GetConfig(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected an uncached 200, got %d with Cache-Control %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+	return rec.Body.String()
+}
+
+// TestSecretFieldsTagged fails when a field whose name suggests a secret has
+// no secret tag, so new secrets cannot be forgotten by Redacted. Fields that
+// only look secret, such as PasswordMinLength, are tagged secret:"false".
+func TestSecretFieldsTagged(t *testing.T) {
+	var check func(typ reflect.Type, path string)
+	check = func(typ reflect.Type, path string) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := path + field.Name
+			if field.Type.Kind() == reflect.Struct && field.Type.PkgPath() == typ.PkgPath() {
+				check(field.Type, name+".")
+				continue
+			}
+
+			tag, tagged := field.Tag.Lookup("secret")
+			lower := strings.ToLower(field.Name)
+			for _, word := range []string{"password", "secret", "token", "key", "pepper"} {
+				if strings.Contains(lower, word) && !tagged {
+					t.Errorf("%s looks like a secret but has no secret tag", name)
+					break
+				}
+			}
+			if tag == "true" && !redactable(field.Type) {
+				t.Errorf("%s is tagged secret but is a %s, which Redacted cannot hide", name, field.Type)
+			}
+		}
+	}
+	check(reflect.TypeOf(Config{}), "")
+}
+
+// redactable reports whether Redacted replaces values of typ: strings and
+// lists or maps of them
+func redactable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Slice, reflect.Map:
+		return typ.Elem().Kind() == reflect.String
+	}
+	return false
+}
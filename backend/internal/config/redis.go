@@ -0,0 +1,60 @@
+package config
+
+import (
+	"crypto/tls"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis deployment modes
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// redisModes are the values RedisConfig.Mode may take
+var redisModes = []string{RedisModeSingle, RedisModeSentinel, RedisModeCluster}
+
+// BuildOptions returns the go-redis options for the configured mode. Use
+// Simple, Failover or Cluster on the result for the options struct of a
+// single, sentinel or cluster client, or NewClient to connect. Sentinel and
+// cluster modes fall back to Host:Port if Addrs is empty.
+func (c *RedisConfig) BuildOptions() *redis.UniversalOptions {
+	opts := &redis.UniversalOptions{
+		Addrs:        c.Addrs,
+		Username:     c.Username,
+		Password:     c.Password,
+		DB:           c.DB,
+		DialTimeout:  c.DialTimeout,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		PoolSize:     c.PoolSize,
+	}
+	if c.Mode == RedisModeSentinel {
+		opts.MasterName = c.MasterName
+	}
+	if c.Mode == "" || c.Mode == RedisModeSingle || len(opts.Addrs) == 0 {
+		opts.Addrs = []string{c.GetRedisAddr()}
+	}
+	if c.TLS {
+		// Leaving ServerName empty verifies each node against its own host
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return opts
+}
+
+// NewClient returns a client for the configured mode: a plain client, a
+// Sentinel failover client or a cluster client. Connections are made on
+// first use.
+func (c *RedisConfig) NewClient() redis.UniversalClient {
+	opts := c.BuildOptions()
+	switch c.Mode {
+	case RedisModeSentinel:
+		return redis.NewFailoverClient(opts.Failover())
+	case RedisModeCluster:
+		return redis.NewClusterClient(opts.Cluster())
+	default:
+		return redis.NewClient(opts.Simple())
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisConfigBuildOptions(t *testing.T) {
+	base := RedisConfig{
+		Host: "cache.example.com", Port: "6380", Addrs: []string{"10.0.0.1:26379", "10.0.0.2:26379"}, MasterName: "mymaster",
+		Username: "app", Password: "secret", DB: 2,
+		DialTimeout: 2 * time.Second, ReadTimeout: time.Second, WriteTimeout: 1500 * time.Millisecond, PoolSize: 20,
+	}
+
+	t.Run("single", func(t *testing.T) {
+		cfg := base
+		cfg.Mode = RedisModeSingle
+		opts := cfg.BuildOptions().Simple()
+		if opts.Addr != "cache.example.com:6380" || opts.Username != "app" || opts.Password != "secret" || opts.DB != 2 {
+			t.Errorf("Unexpected connection options %+v", opts)
+		}
+		if opts.DialTimeout != 2*time.Second || opts.ReadTimeout != time.Second || opts.WriteTimeout != 1500*time.Millisecond || opts.PoolSize != 20 {
+			t.Errorf("Unexpected timeouts or pool size %+v", opts)
+		}
+		if opts.TLSConfig != nil {
+			t.Error("Expected no TLS unless enabled")
+		}
+	})
+
+	t.Run("sentinel", func(t *testing.T) {
+		cfg := base
+		cfg.Mode = RedisModeSentinel
+		opts := cfg.BuildOptions().Failover()
+		if opts.MasterName != "mymaster" || !reflect.DeepEqual(opts.SentinelAddrs, base.Addrs) {
+			t.Errorf("Expected master mymaster via %v, got %s via %v", base.Addrs, opts.MasterName, opts.SentinelAddrs)
+		}
+		if opts.Password != "secret" || opts.DB != 2 || opts.PoolSize != 20 || opts.ReadTimeout != time.Second {
+			t.Errorf("Unexpected failover options %+v", opts)
+		}
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		cfg := base
+		cfg.Mode = RedisModeCluster
+		cfg.MasterName = ""
+		opts := cfg.BuildOptions().Cluster()
+		if !reflect.DeepEqual(opts.Addrs, base.Addrs) {
+			t.Errorf("Expected cluster nodes %v, got %v", base.Addrs, opts.Addrs)
+		}
+		if opts.Password != "secret" || opts.PoolSize != 20 || opts.DialTimeout != 2*time.Second {
+			t.Errorf("Unexpected cluster options %+v", opts)
+		}
+	})
+
+	t.Run("single ignores addrs and master name", func(t *testing.T) {
+		cfg := base
+		cfg.Mode = RedisModeSingle
+		opts := cfg.BuildOptions()
+		if !reflect.DeepEqual(opts.Addrs, []string{"cache.example.com:6380"}) || opts.MasterName != "" {
+			t.Errorf("Expected only Host:Port, got %v and master %q", opts.Addrs, opts.MasterName)
+		}
+	})
+
+	t.Run("cluster without addrs uses host and port", func(t *testing.T) {
+		cfg := base
+		cfg.Mode, cfg.Addrs = RedisModeCluster, nil
+		if addrs := cfg.BuildOptions().Addrs; !reflect.DeepEqual(addrs, []string{"cache.example.com:6380"}) {
+			t.Errorf("Expected Host:Port, got %v", addrs)
+		}
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		for _, mode := range redisModes {
+			cfg := base
+			cfg.Mode, cfg.TLS = mode, true
+			if tlsConfig := cfg.BuildOptions().TLSConfig; tlsConfig == nil || tlsConfig.ServerName != "" {
+				t.Errorf("%s: expected TLS verified against each node's host, got %+v", mode, tlsConfig)
+			}
+		}
+	})
+}
+
+func TestRedisConfigNewClient(t *testing.T) {
+	tests := []struct {
+		mode string
+		want interface{}
+	}{
+		{RedisModeSingle, &redis.Client{}},
+		{"", &redis.Client{}},
+		{RedisModeSentinel, &redis.Client{}},
+		{RedisModeCluster, &redis.ClusterClient{}},
+	}
+	for _, tt := range tests {
+		cfg := RedisConfig{Mode: tt.mode, Host: "localhost", Port: "6379", Addrs: []string{"localhost:26379"}, MasterName: "mymaster"}
+		client := cfg.NewClient()
+		if reflect.TypeOf(client) != reflect.TypeOf(tt.want) {
+			t.Errorf("%q: NewClient() = %T, want %T", tt.mode, client, tt.want)
+		}
+		client.Close()
+	}
+}
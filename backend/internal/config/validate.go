@@ -0,0 +1,205 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tgfinance/pkg/storage"
+)
+
+// DefaultJWTSecret is the placeholder JWT_SECRET used when none is set. It
+// is public, so production refuses to start with it.
+const DefaultJWTSecret = "your-super-secret-jwt-key-change-in-production"
+
+// MinJWTSecretBytes is the shortest JWT signing secret allowed in production
+const MinJWTSecretBytes = 32
+
+// logLevels, logFormats and logOutputs are the values the logger
+// understands
+var (
+	logLevels  = []string{"debug", "info", "warn", "error", "fatal", "panic"}
+	logFormats = []string{"json", "text"}
+	logOutputs = []string{"stdout", "stderr", "file", "both"}
+)
+
+// LoadAndValidate loads configuration as Load does and validates it, so the
+// server can refuse to start with a bad configuration or config file
+func LoadAndValidate() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the configuration for values that would fail or be unsafe
+// at runtime. Each problem names its environment variable, and all of them
+// are joined into one error. JWT secret strength is only enforced in
+// production, so development keeps working with the default secret.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.IsProduction() {
+		switch {
+		case c.Auth.JWTSecret == DefaultJWTSecret:
+			add("JWT_SECRET", "must be changed from the default in production")
+		case len(c.Auth.JWTSecret) < MinJWTSecretBytes:
+			add("JWT_SECRET", "must be at least %d bytes in production", MinJWTSecretBytes)
+		}
+		for kid, secret := range c.Auth.JWTKeys {
+			if len(secret) < MinJWTSecretBytes {
+				add("JWT_KEYS", "key %q must be at least %d bytes in production", kid, MinJWTSecretBytes)
+			}
+		}
+	}
+
+	if len(c.Auth.JWTKeys) > 0 && c.Auth.JWTKeyID == "" {
+		add("JWT_KEY_ID", "is required when JWT_KEYS is set")
+	} else if _, ok := c.Auth.JWTKeys[c.Auth.JWTKeyID]; c.Auth.JWTKeyID != "" && !ok {
+		add("JWT_KEY_ID", "%q is not one of the JWT_KEYS", c.Auth.JWTKeyID)
+	}
+
+	if c.Database.SSLMode != "disable" && c.Database.Password == "" {
+		add("DB_PASSWORD", "is required unless DB_SSLMODE is disable")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		add("DB_MAX_IDLE_CONNS", "must not exceed DB_MAX_OPEN_CONNS (%d)", c.Database.MaxOpenConns)
+	}
+
+	if !slices.Contains(redisModes, c.Redis.Mode) {
+		add("REDIS_MODE", "must be one of %v, got %q", redisModes, c.Redis.Mode)
+	}
+	if c.Redis.Mode == RedisModeSentinel && c.Redis.MasterName == "" {
+		add("REDIS_MASTER_NAME", "is required in sentinel mode")
+	}
+	if c.Redis.PoolSize < 0 {
+		add("REDIS_POOL_SIZE", "must not be negative, got %d", c.Redis.PoolSize)
+	}
+
+	if c.SMTP.Host != "" {
+		if !slices.Contains(smtpTLSModes, c.SMTP.TLSMode) {
+			add("SMTP_TLS_MODE", "must be one of %v, got %q", smtpTLSModes, c.SMTP.TLSMode)
+		}
+		if _, err := mail.ParseAddress(c.SMTP.From); err != nil {
+			add("SMTP_FROM", "must be an email address when SMTP_HOST is set, got %q", c.SMTP.From)
+		}
+		if n, err := strconv.Atoi(c.SMTP.Port); err != nil || n < 1 || n > 65535 {
+			add("SMTP_PORT", "must be a port number from 1 to 65535, got %q", c.SMTP.Port)
+		}
+	}
+
+	switch c.Storage.Provider {
+	case StorageS3, StorageMinIO:
+		if c.Storage.Bucket == "" {
+			add("STORAGE_BUCKET", "is required for the %s provider", c.Storage.Provider)
+		}
+		if c.Storage.AccessKey == "" || c.Storage.SecretKey == "" {
+			add("STORAGE_SECRET_KEY", "STORAGE_ACCESS_KEY and STORAGE_SECRET_KEY are required for the %s provider", c.Storage.Provider)
+		}
+		if c.Storage.Provider == StorageMinIO && c.Storage.Endpoint == "" {
+			add("STORAGE_ENDPOINT", "is required for the minio provider")
+		}
+		if c.Storage.PresignTTL > storage.MaxPresignTTL {
+			add("STORAGE_PRESIGN_TTL", "must not exceed %s, the longest S3 allows, got %s", storage.MaxPresignTTL, c.Storage.PresignTTL)
+		}
+	case StorageFilesystem:
+		if c.Storage.Dir == "" {
+			add("STORAGE_DIR", "is required for the filesystem provider")
+		}
+	default:
+		add("STORAGE_PROVIDER", "must be one of %v, got %q", storageProviders, c.Storage.Provider)
+	}
+	if u, err := url.Parse(c.Storage.Endpoint); c.Storage.Endpoint != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		add("STORAGE_ENDPOINT", "must be a URL such as http://minio:9000, got %q", c.Storage.Endpoint)
+	}
+	if u, err := url.Parse(c.Storage.BaseURL); c.Storage.BaseURL != "" && (err != nil || u.Host == "" && !strings.HasPrefix(u.Path, "/")) {
+		add("STORAGE_BASE_URL", "must be a URL or a path starting with /, got %q", c.Storage.BaseURL)
+	}
+
+	for _, port := range []struct {
+		key, value string
+	}{
+		{"SERVICE_PORT", c.Server.Port},
+		{"DB_PORT", c.Database.Port},
+		{"REDIS_PORT", c.Redis.Port},
+	} {
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			add(port.key, "must be a port number from 1 to 65535, got %q", port.value)
+		}
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SERVER_REQUEST_TIMEOUT", c.Server.RequestTimeout},
+		{"MAINTENANCE_RETRY_AFTER", c.Server.MaintenanceRetryAfter},
+		{"DB_CONN_MAX_LIFETIME", c.Database.ConnMaxLifetime},
+		{"REDIS_DIAL_TIMEOUT", c.Redis.DialTimeout},
+		{"REDIS_READ_TIMEOUT", c.Redis.ReadTimeout},
+		{"REDIS_WRITE_TIMEOUT", c.Redis.WriteTimeout},
+		{"JWT_EXPIRATION", c.Auth.JWTExpiration},
+		{"JWT_REFRESH_EXPIRATION", c.Auth.RefreshExpiration},
+		{"LOGIN_FAILURE_WINDOW", c.Auth.LockoutWindow},
+		{"LOGIN_LOCKOUT_DURATION", c.Auth.LockoutDuration},
+		{"OCR_TIMEOUT", c.OCR.Timeout},
+		{"BACKUP_INTERVAL", c.Backup.Interval},
+		{"RATE_LIMIT_AUTH_WINDOW", c.RateLimit.Auth.Window},
+		{"RATE_LIMIT_API_WINDOW", c.RateLimit.API.Window},
+		{"RATE_LIMIT_STATUS_WINDOW", c.RateLimit.Status.Window},
+		{"IDEMPOTENCY_TTL", c.Idempotency.TTL},
+		{"IDEMPOTENCY_LOCK_TTL", c.Idempotency.LockTTL},
+		{"IDEMPOTENCY_WAIT_TIMEOUT", c.Idempotency.WaitTimeout},
+		{"SMTP_TIMEOUT", c.SMTP.Timeout},
+		{"STORAGE_PRESIGN_TTL", c.Storage.PresignTTL},
+	} {
+		if d.value <= 0 {
+			add(d.key, "must be a positive duration, got %s", d.value)
+		}
+	}
+	if c.Auth.ClockSkew < 0 {
+		add("JWT_CLOCK_SKEW", "must not be negative, got %s", c.Auth.ClockSkew)
+	}
+
+	if !slices.Contains(logLevels, c.Log.Level) {
+		add("LOG_LEVEL", "must be one of %v, got %q", logLevels, c.Log.Level)
+	}
+	if !slices.Contains(logFormats, c.Log.Format) {
+		add("LOG_FORMAT", "must be one of %v, got %q", logFormats, c.Log.Format)
+	}
+	if !slices.Contains(logOutputs, c.Log.Output) {
+		add("LOG_OUTPUT", "must be one of %v, got %q", logOutputs, c.Log.Output)
+	}
+	if (c.Log.Output == "file" || c.Log.Output == "both") && c.Log.File == "" {
+		add("LOG_FILE", "is required when LOG_OUTPUT is %s", c.Log.Output)
+	}
+	for _, limit := range []struct {
+		key   string
+		value int
+	}{
+		{"LOG_MAX_SIZE_MB", c.Log.MaxSizeMB},
+		{"LOG_MAX_BACKUPS", c.Log.MaxBackups},
+		{"LOG_MAX_AGE_DAYS", c.Log.MaxAgeDays},
+	} {
+		if limit.value < 0 {
+			add(limit.key, "must not be negative, got %d", limit.value)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+)
+
+// Section names a part of the configuration that can change without a
+// restart
+type Section string
+
+// Reloadable sections
+const (
+	// SectionLog is the log level and format
+	SectionLog Section = "log"
+	// SectionRateLimit is the whole rate limit configuration
+	SectionRateLimit Section = "rate_limit"
+	// SectionCORS is the allowed CORS origins
+	SectionCORS Section = "cors"
+	// SectionMaintenance is maintenance mode
+	SectionMaintenance Section = "maintenance"
+	// SectionFeatures is the feature flags
+	SectionFeatures Section = "features"
+)
+
+// sections are the reloadable sections in the order reloads apply them
+var sections = []Section{SectionLog, SectionRateLimit, SectionCORS, SectionMaintenance, SectionFeatures}
+
+// reloadable copies the fields of each section from src to dst
+var reloadable = map[Section]func(dst, src *Config){
+	SectionLog: func(dst, src *Config) {
+		dst.Log.Level, dst.Log.Format = src.Log.Level, src.Log.Format
+	},
+	SectionRateLimit: func(dst, src *Config) {
+		dst.RateLimit = src.RateLimit
+	},
+	SectionCORS: func(dst, src *Config) {
+		dst.Server.CORSAllowedOrigins = src.Server.CORSAllowedOrigins
+	},
+	SectionMaintenance: func(dst, src *Config) {
+		dst.Server.MaintenanceMode = src.Server.MaintenanceMode
+	},
+	SectionFeatures: func(dst, src *Config) {
+		dst.Features = src.Features
+	},
+}
+
+// Watcher reloads configuration on SIGHUP or at a poll interval. Changes to
+// reloadable sections are applied and passed to the callbacks registered for
+// them; the logger's level and format follow the log section. Other changes,
+// such as database credentials or ports, need a restart: they are ignored
+// with a warning naming the keys but not their values.
+type Watcher struct {
+	// reloading serializes reloads; mu guards the fields below it
+	reloading sync.Mutex
+	mu        sync.Mutex
+	current   *Config
+	ignored   string
+	load      func() (*Config, error)
+	callbacks map[Section][]func(*Config)
+	logger    *logger.Logger
+	clock     clock.Clock
+}
+
+// NewWatcher creates a watcher starting from cfg that reloads as
+// LoadAndValidate does, so CONFIG_FILE and the environment are re-read
+func NewWatcher(cfg *Config, log *logger.Logger) *Watcher {
+	return &Watcher{
+		current:   cfg,
+		load:      LoadAndValidate,
+		callbacks: make(map[Section][]func(*Config)),
+		logger:    log,
+		clock:     clock.Real(),
+	}
+}
+
+// WithClock sets the clock driving the poll interval
+func (w *Watcher) WithClock(c clock.Clock) *Watcher {
+	w.clock = c
+	return w
+}
+
+// Current returns the configuration with the reloads applied so far. It must
+// not be modified.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// OnChange registers fn to be called with the new configuration whenever a
+// reload changes section. Callbacks run in registration order on the
+// reloading goroutine.
+func (w *Watcher) OnChange(section Section, fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks[section] = append(w.callbacks[section], fn)
+}
+
+// Watch reloads on every SIGHUP and, if interval is positive, every interval
+// until ctx is cancelled
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := w.clock.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			w.reload("SIGHUP")
+		case <-tick:
+			w.reload("poll")
+		}
+	}
+}
+
+// Reload re-reads the configuration and applies its reloadable changes. An
+// invalid configuration is returned as the error and nothing is applied.
+func (w *Watcher) Reload() error {
+	return w.reload("manual")
+}
+
+func (w *Watcher) reload(source string) error {
+	w.reloading.Lock()
+	defer w.reloading.Unlock()
+
+	loaded, err := w.load()
+	if err != nil {
+		w.logger.WithError(err).WithField("source", source).Error("Config reload failed; keeping the current configuration")
+		return err
+	}
+
+	old := w.Current()
+	next := *old
+	var changed []Section
+	for _, section := range sections {
+		reloadable[section](&next, loaded)
+		if len(diffKeys(sectionOf(section, &next), sectionOf(section, old), "")) > 0 {
+			changed = append(changed, section)
+		}
+	}
+
+	// Warn once per distinct set of ignored keys, not on every poll
+	ignored := strings.Join(diffKeys(reflect.ValueOf(next), reflect.ValueOf(*loaded), ""), ", ")
+	w.mu.Lock()
+	warn := ignored != "" && ignored != w.ignored
+	w.ignored = ignored
+	if len(changed) > 0 {
+		w.current = &next
+	}
+	callbacks := make(map[Section][]func(*Config), len(changed))
+	for _, section := range changed {
+		callbacks[section] = w.callbacks[section]
+	}
+	w.mu.Unlock()
+
+	if warn {
+		w.logger.WithFields(logrus.Fields{"source": source, "keys": ignored}).Warn("Config changes need a restart and were not applied")
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	for _, section := range changed {
+		if section == SectionLog {
+			w.applyLog(&next)
+		}
+		for _, fn := range callbacks[section] {
+			fn(&next)
+		}
+	}
+	w.logger.WithFields(logrus.Fields{"source": source, "sections": changed}).Info("Config reloaded")
+	return nil
+}
+
+// applyLog switches the logger to the configured level and format
+func (w *Watcher) applyLog(cfg *Config) {
+	if err := w.logger.SetLevelByName(cfg.Log.Level); err != nil {
+		w.logger.WithError(err).Warn("Log level not changed")
+	}
+	if err := w.logger.SetFormatByName(cfg.Log.Format); err != nil {
+		w.logger.WithError(err).Warn("Log format not changed")
+	}
+}
+
+// sectionOf returns the values of cfg that make up section, for comparison
+func sectionOf(section Section, cfg *Config) reflect.Value {
+	var values Config
+	reloadable[section](&values, cfg)
+	return reflect.ValueOf(values)
+}
+
+// diffKeys returns the dotted yaml keys whose values differ between a and b.
+// Nil and empty lists and maps are equal, as a config file cannot tell them
+// apart.
+func diffKeys(a, b reflect.Value, prefix string) []string {
+	switch a.Kind() {
+	case reflect.Struct:
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 || reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{strings.TrimSuffix(prefix, ".")}
+	default:
+		if a.Interface() == b.Interface() {
+			return nil
+		}
+		return []string{strings.TrimSuffix(prefix, ".")}
+	}
+
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+		keys = append(keys, diffKeys(a.Field(i), b.Field(i), prefix+name+".")...)
+	}
+	return keys
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+)
+
+// newTestWatcher returns a watcher over the defaults whose reloads return
+// the result of next, and the buffer it logs to
+func newTestWatcher(next func() (*Config, error)) (*Watcher, *logger.Logger, *bytes.Buffer) {
+	var logs bytes.Buffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	w := NewWatcher(defaults(), log)
+	w.load = next
+	return w, log, &logs
+}
+
+func TestWatcherReloadsSections(t *testing.T) {
+	loaded := defaults()
+	loaded.Log.Level = "debug"
+	loaded.RateLimit.API.Requests = 50
+	loaded.Server.MaintenanceMode = true
+	w, log, _ := newTestWatcher(func() (*Config, error) { return loaded, nil })
+
+	var calls []Section
+	for _, section := range []Section{SectionLog, SectionRateLimit, SectionCORS, SectionMaintenance} {
+		section := section
+		w.OnChange(section, func(cfg *Config) {
+			calls = append(calls, section)
+			if cfg != w.Current() {
+				t.Errorf("%s callback got a config other than the current one", section)
+			}
+		})
+	}
+
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got, want := calls, []Section{SectionLog, SectionRateLimit, SectionMaintenance}; !equalSections(got, want) {
+		t.Errorf("callbacks = %v, want %v", got, want)
+	}
+	cfg := w.Current()
+	if cfg.Log.Level != "debug" || cfg.RateLimit.API.Requests != 50 || !cfg.Server.MaintenanceMode {
+		t.Errorf("Expected reloaded values, got %+v", cfg)
+	}
+	if log.GetLevel() != logrus.DebugLevel {
+		t.Errorf("logger level = %v, want debug", log.GetLevel())
+	}
+
+	// Reloading the same configuration changes nothing
+	calls = nil
+	if err := w.Reload(); err != nil || len(calls) != 0 {
+		t.Errorf("Expected no callbacks for an unchanged config, got %v (%v)", calls, err)
+	}
+}
+
+func TestWatcherIgnoresRestartOnlyChanges(t *testing.T) {
+	loaded := defaults()
+	loaded.Database.Password = "new-db-password"
+	loaded.Server.Port = "9999"
+	loaded.Server.CORSAllowedOrigins = []string{"https://app.example.com"}
+	w, _, logs := newTestWatcher(func() (*Config, error) { return loaded, nil })
+
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	cfg := w.Current()
+	if cfg.Database.Password != "" || cfg.Server.Port != "8001" {
+		t.Errorf("Expected restart-only changes to be ignored, got password %q and port %s", cfg.Database.Password, cfg.Server.Port)
+	}
+	if len(cfg.Server.CORSAllowedOrigins) != 1 {
+		t.Errorf("Expected the CORS change to apply, got %v", cfg.Server.CORSAllowedOrigins)
+	}
+	if !strings.Contains(logs.String(), "server.port, database.password") {
+		t.Errorf("Expected a warning naming the ignored keys, got %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "new-db-password") {
+		t.Error("The warning must not log the new values")
+	}
+
+	// The same ignored changes are not warned about again
+	logs.Reset()
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), "need a restart") {
+		t.Errorf("Expected one warning per set of ignored keys, got %s", logs.String())
+	}
+}
+
+func TestWatcherNilAndEmptyListsAreEqual(t *testing.T) {
+	loaded := defaults()
+	loaded.Server.CORSAllowedOrigins = []string{}
+	loaded.Auth.JWTKeys = map[string]string{}
+	w, _, logs := newTestWatcher(func() (*Config, error) { return loaded, nil })
+	w.OnChange(SectionCORS, func(*Config) { t.Error("Expected no CORS change") })
+
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected nothing logged, got %s", logs.String())
+	}
+}
+
+func TestWatcherKeepsConfigOnError(t *testing.T) {
+	w, _, logs := newTestWatcher(func() (*Config, error) { return nil, errors.New("LOG_LEVEL: must be one of") })
+	before := w.Current()
+	w.OnChange(SectionLog, func(*Config) { t.Error("Expected no callbacks after a failed reload") })
+
+	if err := w.Reload(); err == nil {
+		t.Fatal("Expected the load error")
+	}
+	if w.Current() != before {
+		t.Error("Expected the current config to be kept")
+	}
+	if !strings.Contains(logs.String(), "Config reload failed") {
+		t.Errorf("Expected the failure to be logged, got %s", logs.String())
+	}
+}
+
+func TestWatcherPolls(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC))
+	loaded := defaults()
+	w, _, _ := newTestWatcher(func() (*Config, error) { return loaded, nil })
+	w.WithClock(fake)
+
+	changes := make(chan *Config, 1)
+	w.OnChange(SectionRateLimit, func(cfg *Config) { changes <- cfg })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Watch(ctx, time.Minute)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	fake.BlockUntil(1)
+	next := defaults()
+	next.RateLimit.Auth.Requests = 3
+	loaded = next
+	fake.Advance(time.Minute)
+
+	select {
+	case cfg := <-changes:
+		if cfg.RateLimit.Auth.Requests != 3 {
+			t.Errorf("Expected 3 auth requests, got %d", cfg.RateLimit.Auth.Requests)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a reload on the poll interval")
+	}
+}
+
+func equalSections(a, b []Section) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+//go:build unix
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/pkg/logger"
+)
+
+// lockedBuffer is a log output safe to read while goroutines are logging
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatcherSIGHUP(t *testing.T) {
+	var logs lockedBuffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	w := NewWatcher(Load(), log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Watch(ctx, 0)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Log from several goroutines while the level changes
+	const writers, lines = 4, 200
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				log.WithField("line", j).Warn("concurrent write")
+			}
+		}()
+	}
+
+	// Signals sent before Notify is registered would kill the process
+	time.Sleep(50 * time.Millisecond)
+	t.Setenv("LOG_LEVEL", "debug")
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for log.GetLevel() != logrus.DebugLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("logger level = %v, want debug", log.GetLevel())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	if got := strings.Count(logs.String(), `"msg":"concurrent write"`); got != writers*lines {
+		t.Errorf("Expected %d log lines, got %d", writers*lines, got)
+	}
+	if w.Current().Log.Level != "debug" {
+		t.Errorf("Current().Log.Level = %q, want debug", w.Current().Log.Level)
+	}
+}
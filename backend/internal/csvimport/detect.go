@@ -0,0 +1,189 @@
+package csvimport
+
+import (
+	"fmt"
+	"strings"
+
+	"tgfinance/internal/models"
+)
+
+// SampleSize bounds the rows inspected to detect a file's conventions
+const SampleSize = 200
+
+// minConfidence is the confidence below which a detection is flagged as
+// ambiguous so the user confirms it
+const minConfidence = 0.9
+
+// delimiters are the CSV delimiters considered by detection, in order of preference
+var delimiters = []string{",", ";", "\t", "|"}
+
+// DetectDelimiter returns the delimiter that splits the header line into the
+// most columns
+func DetectDelimiter(header string) string {
+	best, bestCount := delimiters[0], 0
+	for _, delimiter := range delimiters {
+		if count := strings.Count(header, delimiter); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+// Detect infers the decimal separator and date order from sampled amount and
+// date cells. Each cell that can only be read one way votes for that
+// convention; cells readable either way, such as 1,234 or 03/04/2024, do not
+// vote. A file without any deciding cell is flagged as ambiguous.
+func Detect(amounts, dates []string) models.CSVDetection {
+	detection := models.CSVDetection{SampledRows: max(len(amounts), len(dates))}
+	detectDecimal(&detection, amounts)
+	detectDateOrder(&detection, dates)
+	return detection
+}
+
+// detectDecimal votes on the decimal separator of amounts
+func detectDecimal(detection *models.CSVDetection, amounts []string) {
+	votes := map[string]int{}
+	undecided := 0
+	for _, raw := range amounts {
+		switch vote := decimalVote(raw); vote {
+		case "":
+		case "?":
+			undecided++
+		default:
+			votes[vote]++
+		}
+	}
+
+	detection.Decimal = models.DecimalPoint
+	points, commas := votes[models.DecimalPoint], votes[models.DecimalComma]
+	if commas > points {
+		detection.Decimal = models.DecimalComma
+	}
+	switch decisive := points + commas; {
+	case decisive > 0:
+		detection.DecimalConfidence = float64(max(points, commas)) / float64(decisive)
+	case undecided > 0:
+		detection.Warnings = append(detection.Warnings,
+			"amounts such as 1,234 can be read with either decimal separator; confirm which one the file uses")
+	default:
+		// No amount has a separator, so either convention reads them the same
+		detection.DecimalConfidence = 1
+	}
+	if points > 0 && commas > 0 {
+		detection.Warnings = append(detection.Warnings, fmt.Sprintf(
+			"amounts mix decimal separators (%d with a point, %d with a comma); rows not matching the chosen separator will fail",
+			points, commas))
+	}
+	if detection.DecimalConfidence < minConfidence {
+		detection.Ambiguous = true
+	}
+}
+
+// decimalVote returns the decimal separator an amount can only be read with,
+// "?" if it reads either way and "" if it has no separator
+func decimalVote(raw string) string {
+	number, _ := stripAmount(raw)
+	lastPoint := strings.LastIndex(number, models.DecimalPoint)
+	lastComma := strings.LastIndex(number, models.DecimalComma)
+
+	switch {
+	case lastPoint < 0 && lastComma < 0:
+		return ""
+	case lastPoint >= 0 && lastComma >= 0:
+		// With both present the later one is the decimal separator
+		if lastPoint > lastComma {
+			return models.DecimalPoint
+		}
+		return models.DecimalComma
+	}
+
+	separator, last := models.DecimalPoint, lastPoint
+	if lastComma >= 0 {
+		separator, last = models.DecimalComma, lastComma
+	}
+	if strings.Count(number, separator) > 1 {
+		// Only a grouping separator repeats
+		return groupingSeparator(separator)
+	}
+	integer := strings.TrimSpace(number[:last])
+	if len(number)-last-1 != 3 || integer == "" || integer == "0" {
+		return separator
+	}
+	return "?"
+}
+
+// detectDateOrder votes on the field order of dates
+func detectDateOrder(detection *models.CSVDetection, dates []string) {
+	votes := map[string]int{}
+	undecided := 0
+	for _, raw := range dates {
+		switch vote := dateOrderVote(raw); vote {
+		case "":
+		case "?":
+			undecided++
+		default:
+			votes[vote]++
+		}
+	}
+
+	detection.DateOrder = models.DateOrderDMY
+	decisive := 0
+	for _, order := range []string{models.DateOrderDMY, models.DateOrderMDY, models.DateOrderYMD} {
+		decisive += votes[order]
+		if votes[order] > votes[detection.DateOrder] {
+			detection.DateOrder = order
+		}
+	}
+
+	if decisive > 0 {
+		detection.DateConfidence = float64(votes[detection.DateOrder]) / float64(decisive)
+	} else if undecided > 0 {
+		detection.Warnings = append(detection.Warnings,
+			"no date has a day above 12, so DD/MM and MM/DD cannot be told apart; confirm the date order")
+	}
+	if votes[models.DateOrderDMY] > 0 && votes[models.DateOrderMDY] > 0 {
+		detection.Warnings = append(detection.Warnings, fmt.Sprintf(
+			"dates mix DD/MM (%d rows) and MM/DD (%d rows); rows not matching the chosen order will fail",
+			votes[models.DateOrderDMY], votes[models.DateOrderMDY]))
+	}
+	if detection.DateConfidence < minConfidence {
+		detection.Ambiguous = true
+	}
+}
+
+// dateOrderVote returns the order a date can only be read in, "?" if it
+// reads as both DMY and MDY and "" if it is not a date
+func dateOrderVote(raw string) string {
+	fields := dateFields(raw)
+	if len(fields) != 3 {
+		return ""
+	}
+	if len(fields[0]) == 4 && isDigits(fields[0]) {
+		return models.DateOrderYMD
+	}
+
+	if !isDigits(fields[0]) {
+		if _, err := parseMonth(fields[0]); err == nil {
+			return models.DateOrderMDY
+		}
+		return ""
+	}
+	if !isDigits(fields[1]) {
+		if _, err := parseMonth(fields[1]); err == nil {
+			return models.DateOrderDMY
+		}
+		return ""
+	}
+
+	_, dmyErr := ParseDate(raw, models.DateOrderDMY)
+	_, mdyErr := ParseDate(raw, models.DateOrderMDY)
+	switch {
+	case dmyErr == nil && mdyErr == nil:
+		return "?"
+	case dmyErr == nil:
+		return models.DateOrderDMY
+	case mdyErr == nil:
+		return models.DateOrderMDY
+	}
+	return ""
+}
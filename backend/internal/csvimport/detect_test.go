@@ -0,0 +1,172 @@
+package csvimport
+
+import (
+	"testing"
+
+	"tgfinance/internal/models"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name          string
+		amounts       []string
+		dates         []string
+		wantDecimal   string
+		wantOrder     string
+		wantAmbiguous bool
+	}{
+		{
+			name:        "German bank",
+			amounts:     []string{"-1.234,56", "-12,50", "2.500,00", "-0,99"},
+			dates:       []string{"02.01.2024", "15.01.2024", "31.01.2024"},
+			wantDecimal: models.DecimalComma,
+			wantOrder:   models.DateOrderDMY,
+		},
+		{
+			name:        "French bank with space grouping",
+			amounts:     []string{"-1 234,56 €", "-45,00 €", "12 000,00 €"},
+			dates:       []string{"03/02/2024", "28/02/2024"},
+			wantDecimal: models.DecimalComma,
+			wantOrder:   models.DateOrderDMY,
+		},
+		{
+			name:        "US bank",
+			amounts:     []string{"-1,234.56", "($12.50)", "2,500.00", "-0.99"},
+			dates:       []string{"01/02/2024", "01/15/2024", "12/31/2023"},
+			wantDecimal: models.DecimalPoint,
+			wantOrder:   models.DateOrderMDY,
+		},
+		{
+			name:        "Indian bank with lakh grouping",
+			amounts:     []string{"1,23,456.78", "12,34,567.00", "450.00", "₹ 2,500"},
+			dates:       []string{"05-Jan-2024", "18-Jan-2024", "29-02-2024"},
+			wantDecimal: models.DecimalPoint,
+			wantOrder:   models.DateOrderDMY,
+		},
+		{
+			name:        "ISO dates",
+			amounts:     []string{"12.50", "7.25"},
+			dates:       []string{"2024-01-02", "2024-01-03"},
+			wantDecimal: models.DecimalPoint,
+			wantOrder:   models.DateOrderYMD,
+		},
+		{
+			name:          "days never exceed 12",
+			amounts:       []string{"12.50", "7.25"},
+			dates:         []string{"01/02/2024", "03/04/2024", "12/11/2024"},
+			wantDecimal:   models.DecimalPoint,
+			wantOrder:     models.DateOrderDMY,
+			wantAmbiguous: true,
+		},
+		{
+			name:          "amounts readable either way",
+			amounts:       []string{"1,234", "5,000", "12"},
+			dates:         []string{"2024-01-02"},
+			wantDecimal:   models.DecimalPoint,
+			wantOrder:     models.DateOrderYMD,
+			wantAmbiguous: true,
+		},
+		{
+			name:          "mixed date formats",
+			amounts:       []string{"10.00", "20.00", "30.00", "40.00"},
+			dates:         []string{"13/01/2024", "25/01/2024", "01/28/2024", "30/01/2024"},
+			wantDecimal:   models.DecimalPoint,
+			wantOrder:     models.DateOrderDMY,
+			wantAmbiguous: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.amounts, tt.dates)
+			if got.Decimal != tt.wantDecimal || got.DateOrder != tt.wantOrder || got.Ambiguous != tt.wantAmbiguous {
+				t.Errorf("Detect() = decimal %q order %q ambiguous %v, want %q %q %v (warnings %v)",
+					got.Decimal, got.DateOrder, got.Ambiguous, tt.wantDecimal, tt.wantOrder, tt.wantAmbiguous, got.Warnings)
+			}
+			if tt.wantAmbiguous && len(got.Warnings) == 0 {
+				t.Error("Expected a warning explaining the ambiguity")
+			}
+		})
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := map[string]string{
+		"Date,Amount,Description":             ",",
+		"Buchungstag;Betrag;Verwendungszweck": ";",
+		"Date\tAmount\tDescription":           "\t",
+		`"Date";"Amount, EUR";"Description"`:  ";",
+	}
+	for header, want := range tests {
+		if got := DetectDelimiter(header); got != want {
+			t.Errorf("DetectDelimiter(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		raw     string
+		decimal string
+		want    float64
+		wantErr bool
+	}{
+		{raw: "1.234,56", decimal: models.DecimalComma, want: 1234.56},
+		{raw: "-12,5", decimal: models.DecimalComma, want: -12.5},
+		{raw: "1 234,56 €", decimal: models.DecimalComma, want: 1234.56},
+		{raw: "1.234", decimal: models.DecimalComma, want: 1234},
+		{raw: "1,234.56", decimal: models.DecimalPoint, want: 1234.56},
+		{raw: "($12.50)", decimal: models.DecimalPoint, want: -12.5},
+		{raw: "12.50-", decimal: models.DecimalPoint, want: -12.5},
+		{raw: "1,23,456.78", decimal: models.DecimalPoint, want: 123456.78},
+		{raw: "1'234.50", decimal: models.DecimalPoint, want: 1234.5},
+		{raw: ".75", decimal: models.DecimalPoint, want: 0.75},
+		// Values that only fit the other convention fail instead of misparsing
+		{raw: "1,234.56", decimal: models.DecimalComma, wantErr: true},
+		{raw: "12.50", decimal: models.DecimalComma, wantErr: true},
+		{raw: "1.234,56", decimal: models.DecimalPoint, wantErr: true},
+		{raw: "12,5", decimal: models.DecimalPoint, wantErr: true},
+		{raw: "1,2345", decimal: models.DecimalPoint, wantErr: true},
+		{raw: "", decimal: models.DecimalPoint, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.raw, tt.decimal)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAmount(%q, %q) error = %v, wantErr %v", tt.raw, tt.decimal, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseAmount(%q, %q) = %v, want %v", tt.raw, tt.decimal, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		raw     string
+		order   string
+		want    string
+		wantErr bool
+	}{
+		{raw: "31.01.2024", order: models.DateOrderDMY, want: "2024-01-31"},
+		{raw: "05-Jan-24", order: models.DateOrderDMY, want: "2024-01-05"},
+		{raw: "01/31/2024", order: models.DateOrderMDY, want: "2024-01-31"},
+		{raw: "Jan 5, 2024", order: models.DateOrderMDY, want: "2024-01-05"},
+		{raw: "2024-01-31T14:30:00Z", order: models.DateOrderYMD, want: "2024-01-31"},
+		{raw: "01/31/2024", order: models.DateOrderDMY, wantErr: true},
+		{raw: "31/02/2024", order: models.DateOrderDMY, wantErr: true},
+		{raw: "2024", order: models.DateOrderYMD, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDate(tt.raw, tt.order)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDate(%q, %q) error = %v, wantErr %v", tt.raw, tt.order, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.Format("2006-01-02") != tt.want {
+			t.Errorf("ParseDate(%q, %q) = %v, want %s", tt.raw, tt.order, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package csvimport
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/internal/period"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the CSV expense import endpoint
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new CSV import handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Import handles POST /api/v1/expenses/import/csv. The multipart form holds
+// the file in "file" and the JSON import options in "options". With
+// dry_run=true nothing is stored and the detected settings are returned.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxFileSize+1<<20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "A csv file is required")
+		return
+	}
+	defer file.Close()
+
+	var req models.CSVImportRequest
+	if err := json.Unmarshal([]byte(r.FormValue("options")), &req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid import options")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	var body interface{}
+	if dryRun {
+		body, err = h.service.DryRun(r.Context(), userID, file, &req)
+	} else {
+		body, err = h.service.Commit(r.Context(), userID, file, &req)
+	}
+
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrFileTooLarge):
+		httputil.WriteErrorFrom(w, http.StatusRequestEntityTooLarge, httputil.CodeForStatus(http.StatusRequestEntityTooLarge), err)
+	case errors.Is(period.MapError(err), period.ErrPeriodLocked):
+		period.WriteLockedError(w, err)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to import csv file")
+	case dryRun:
+		httputil.WriteJSON(w, http.StatusOK, body)
+	default:
+		httputil.WriteJSON(w, http.StatusCreated, body)
+	}
+}
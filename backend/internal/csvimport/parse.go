@@ -0,0 +1,235 @@
+package csvimport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"tgfinance/internal/models"
+)
+
+// monthNames maps English month abbreviations to months
+var monthNames = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// ParseAmount parses a bank export amount written with the given decimal
+// separator. The other of '.' and ',' is accepted as a digit grouping
+// separator, in groups of three or Indian lakh grouping, along with spaces
+// and apostrophes. Amounts that only fit the other convention, such as
+// 12.50 when the decimal separator is a comma, are rejected rather than
+// misread. Parentheses and leading or trailing minus signs mark negatives.
+func ParseAmount(raw, decimal string) (float64, error) {
+	number, negative := stripAmount(raw)
+	if number == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+
+	integer, fraction := number, ""
+	if i := strings.Index(number, decimal); i >= 0 {
+		integer, fraction = number[:i], number[i+1:]
+		if !isDigits(fraction) {
+			return 0, fmt.Errorf("amount %q does not use %s as its decimal separator", raw, describeDecimal(decimal))
+		}
+	}
+	if integer == "" {
+		integer = "0"
+	}
+	digits, ok := ungroup(integer, groupingSeparator(decimal))
+	if !ok {
+		return 0, fmt.Errorf("amount %q does not use %s as its decimal separator", raw, describeDecimal(decimal))
+	}
+
+	value, err := strconv.ParseFloat(digits+"."+fraction+"0", 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is not a number", raw)
+	}
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
+
+// stripAmount removes currency symbols, codes and sign markers, returning
+// the bare number and whether it was negative
+func stripAmount(raw string) (string, bool) {
+	s := strings.TrimSpace(raw)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',', r == '\'':
+			b.WriteRune(r)
+		case r == ' ' || r == '\u00a0' || r == '\u202f':
+			// Spaces group thousands in many locales
+			if b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+		case r == '-' || r == '\u2212':
+			negative = true
+		}
+	}
+	return strings.TrimSpace(b.String()), negative
+}
+
+// ungroup removes grouping separators from the integer part of an amount,
+// returning false if they are misplaced
+func ungroup(integer, group string) (string, bool) {
+	isSeparator := func(r rune) bool { return string(r) == group || r == ' ' || r == '\'' }
+	parts := strings.FieldsFunc(integer, isSeparator)
+	if len(parts) == 0 || !isDigits(strings.Join(parts, "")) {
+		return "", false
+	}
+	if strings.IndexFunc(integer, func(r rune) bool { return !isSeparator(r) && (r < '0' || r > '9') }) >= 0 {
+		return "", false
+	}
+	separators := 0
+	for _, r := range integer {
+		if isSeparator(r) {
+			separators++
+		}
+	}
+	if separators != len(parts)-1 {
+		return "", false
+	}
+	if len(parts) == 1 {
+		return parts[0], true
+	}
+
+	// The leading group has 1-3 digits, the last group 3, and the groups in
+	// between all 3 or, for lakh grouping, all 2
+	if len(parts[0]) > 3 || len(parts[len(parts)-1]) != 3 {
+		return "", false
+	}
+	if middle := parts[1 : len(parts)-1]; len(middle) > 0 {
+		size := len(middle[0])
+		if size != 2 && size != 3 {
+			return "", false
+		}
+		for _, part := range middle {
+			if len(part) != size {
+				return "", false
+			}
+		}
+		if size == 2 && len(parts[0]) > 2 {
+			return "", false
+		}
+	}
+	return strings.Join(parts, ""), true
+}
+
+// ParseDate parses a bank export date in the given field order. Fields may
+// be separated by any non-alphanumeric character, months may be written as
+// English abbreviations, two-digit years are taken as 20xx, and a trailing
+// time of day is ignored.
+func ParseDate(raw, order string) (time.Time, error) {
+	fields := dateFields(raw)
+	if len(fields) != 3 {
+		return time.Time{}, fmt.Errorf("date %q is not a valid %s date", raw, describeOrder(order))
+	}
+
+	var dayField, monthField, yearField string
+	switch order {
+	case models.DateOrderDMY:
+		dayField, monthField, yearField = fields[0], fields[1], fields[2]
+	case models.DateOrderMDY:
+		monthField, dayField, yearField = fields[0], fields[1], fields[2]
+	case models.DateOrderYMD:
+		yearField, monthField, dayField = fields[0], fields[1], fields[2]
+	default:
+		return time.Time{}, fmt.Errorf("unknown date order %q", order)
+	}
+
+	day, dayErr := strconv.Atoi(dayField)
+	month, monthErr := parseMonth(monthField)
+	year, yearErr := strconv.Atoi(yearField)
+	if dayErr != nil || monthErr != nil || yearErr != nil || (len(yearField) != 2 && len(yearField) != 4) {
+		return time.Time{}, fmt.Errorf("date %q is not a valid %s date", raw, describeOrder(order))
+	}
+	if len(yearField) == 2 {
+		year += 2000
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || date.Month() != month {
+		return time.Time{}, fmt.Errorf("date %q is not a valid %s date", raw, describeOrder(order))
+	}
+	return date, nil
+}
+
+// dateFields splits the date part of raw into its fields
+func dateFields(raw string) []string {
+	s := strings.TrimSpace(raw)
+	// Drop a trailing time such as "14:30" or "T14:30:00Z"
+	if i := strings.IndexAny(s, "T "); i > 0 && strings.Contains(s[i:], ":") {
+		s = s[:i]
+	}
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// parseMonth parses a numeric or abbreviated month
+func parseMonth(field string) (time.Month, error) {
+	if n, err := strconv.Atoi(field); err == nil {
+		if n < 1 || n > 12 {
+			return 0, fmt.Errorf("month %d out of range", n)
+		}
+		return time.Month(n), nil
+	}
+	if len(field) >= 3 {
+		if month, ok := monthNames[strings.ToLower(field[:3])]; ok {
+			return month, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown month %q", field)
+}
+
+// groupingSeparator returns the digit grouping separator used alongside decimal
+func groupingSeparator(decimal string) string {
+	if decimal == models.DecimalComma {
+		return models.DecimalPoint
+	}
+	return models.DecimalComma
+}
+
+// describeDecimal names a decimal separator for error messages
+func describeDecimal(decimal string) string {
+	if decimal == models.DecimalComma {
+		return "a comma"
+	}
+	return "a point"
+}
+
+// describeOrder names a date order for error messages
+func describeOrder(order string) string {
+	switch order {
+	case models.DateOrderMDY:
+		return "MM/DD/YYYY"
+	case models.DateOrderYMD:
+		return "YYYY-MM-DD"
+	}
+	return "DD/MM/YYYY"
+}
+
+// isDigits returns true if s is a non-empty string of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
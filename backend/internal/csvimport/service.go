@@ -0,0 +1,388 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/categorize"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
+)
+
+// Import limits
+const (
+	MaxFileSize = 10 << 20
+	MaxRows     = 10000
+)
+
+// previewRows bounds the parsed rows returned by a dry run
+const previewRows = 20
+
+// maxReportedErrors bounds the row errors kept per import
+const maxReportedErrors = 100
+
+// ErrFileTooLarge is returned for files over MaxFileSize or MaxRows
+var ErrFileTooLarge = errors.New("csv file is too large")
+
+// Sink stores imported expenses
+type Sink interface {
+	// CreateExpenses stores the expenses of one import in a single
+	// transaction, tagged with the import ID
+	CreateExpenses(ctx context.Context, userID, importID uuid.UUID, expenses []models.ExpenseCreateRequest) error
+}
+
+// CategoryResolver maps a category name from the file onto one of the user's
+// categories
+type CategoryResolver func(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error)
+
+// Service imports expenses from bank CSV exports
+type Service struct {
+	sink            Sink
+	resolveCategory CategoryResolver
+	rules           categorize.RuleSource
+}
+
+// NewService creates a new CSV import service. resolveCategory may be nil,
+// in which case every row uses the request's category.
+func NewService(sink Sink, resolveCategory CategoryResolver) *Service {
+	return &Service{sink: sink, resolveCategory: resolveCategory}
+}
+
+// WithRules categorizes rows without a category in the file with the user's
+// matching categorization rule before falling back to the request's category
+func (s *Service) WithRules(rules categorize.RuleSource) *Service {
+	s.rules = rules
+	return s
+}
+
+// DryRun parses the file without storing anything. Conventions not given in
+// the request are detected from the first SampleSize rows and returned with
+// their confidence so the user can confirm them before committing.
+func (s *Service) DryRun(ctx context.Context, userID uuid.UUID, file io.Reader, req *models.CSVImportRequest) (*models.CSVImportPreview, error) {
+	if errs := ValidateRequest(req, false); errs.HasErrors() {
+		return nil, errs
+	}
+	data, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.CSVImportPreview{Rows: []models.ExpenseCreateRequest{}}
+	if req.Settings != nil {
+		preview.Settings = *req.Settings
+	} else {
+		detection, err := detect(data, req.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		preview.Detection = detection
+		preview.Settings = detection.Settings()
+	}
+
+	parsed, err := s.parse(ctx, userID, data, req, preview.Settings)
+	if err != nil {
+		return nil, err
+	}
+	preview.TotalRows = parsed.total
+	preview.ValidRows = len(parsed.expenses)
+	preview.Errors = parsed.errors
+	if len(parsed.expenses) > previewRows {
+		parsed.expenses = parsed.expenses[:previewRows]
+	}
+	preview.Rows = append(preview.Rows, parsed.expenses...)
+	return preview, nil
+}
+
+// Commit parses the file with the settings pinned in the request and stores
+// every row that parses. Rows that do not match the settings are reported
+// and skipped rather than guessed at.
+func (s *Service) Commit(ctx context.Context, userID uuid.UUID, file io.Reader, req *models.CSVImportRequest) (*models.CSVImportResult, error) {
+	if errs := ValidateRequest(req, true); errs.HasErrors() {
+		return nil, errs
+	}
+	data, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := s.parse(ctx, userID, data, req, *req.Settings)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.CSVImportResult{Settings: *req.Settings, TotalRows: parsed.total, Errors: parsed.errors}
+	if len(parsed.expenses) == 0 {
+		return result, nil
+	}
+	importID := uuid.New()
+	if err := s.sink.CreateExpenses(ctx, userID, importID, parsed.expenses); err != nil {
+		return nil, fmt.Errorf("failed to store imported expenses: %w", err)
+	}
+	result.ImportID = &importID
+	result.Imported = len(parsed.expenses)
+	return result, nil
+}
+
+// parsedFile holds the rows of a file parsed with fixed settings
+type parsedFile struct {
+	total    int
+	expenses []models.ExpenseCreateRequest
+	errors   []models.CSVRowError
+}
+
+// addError records a row error, keeping at most maxReportedErrors
+func (p *parsedFile) addError(line int, column, message string) {
+	if len(p.errors) < maxReportedErrors {
+		p.errors = append(p.errors, models.CSVRowError{Line: line, Column: column, Message: message})
+	}
+}
+
+// parse converts every data row into an expense, recording per-row errors
+func (s *Service) parse(ctx context.Context, userID uuid.UUID, data []byte, req *models.CSVImportRequest, settings models.CSVImportSettings) (*parsedFile, error) {
+	reader := newReader(data, settings.Delimiter)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, invalidFile(err)
+	}
+	columns, err := resolveColumns(header, req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	var engine *categorize.Engine
+	if s.rules != nil {
+		if engine, err = s.rules.Engine(ctx, userID); err != nil {
+			return nil, fmt.Errorf("failed to load categorization rules: %w", err)
+		}
+	}
+
+	parsed := &parsedFile{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidFile(err)
+		}
+		parsed.total++
+		if parsed.total > MaxRows {
+			return nil, ErrFileTooLarge
+		}
+		line, _ := reader.FieldPos(0)
+
+		expense, column, err := s.parseRow(ctx, userID, record, columns, req.CategoryID, settings, engine)
+		if err != nil {
+			parsed.addError(line, column, err.Error())
+			continue
+		}
+		parsed.expenses = append(parsed.expenses, expense)
+	}
+	return parsed, nil
+}
+
+// parseRow converts one data row, returning the column at fault on error
+func (s *Service) parseRow(ctx context.Context, userID uuid.UUID, record []string, columns columnIndexes, categoryID uuid.UUID, settings models.CSVImportSettings, engine *categorize.Engine) (models.ExpenseCreateRequest, string, error) {
+	var expense models.ExpenseCreateRequest
+
+	date, err := ParseDate(columns.value(record, columns.date), settings.DateOrder)
+	if err != nil {
+		return expense, "date", err
+	}
+	amount, err := ParseAmount(columns.value(record, columns.amount), settings.Decimal)
+	if err != nil {
+		return expense, "amount", err
+	}
+	// Bank exports usually write spending as debits
+	amount = finance.RoundCents(math.Abs(amount))
+	if amount == 0 {
+		return expense, "amount", fmt.Errorf("amount must not be zero")
+	}
+	description := strings.TrimSpace(columns.value(record, columns.description))
+	if description == "" {
+		return expense, "description", fmt.Errorf("description is required")
+	}
+
+	expense = models.ExpenseCreateRequest{
+		CategoryID:  categoryID,
+		Amount:      amount,
+		Description: description,
+		ExpenseDate: date,
+	}
+	if columns.category >= 0 && s.resolveCategory != nil {
+		if name := strings.TrimSpace(columns.value(record, columns.category)); name != "" {
+			resolved, err := s.resolveCategory(ctx, userID, name)
+			if err != nil {
+				return expense, "category", fmt.Errorf("category %q: %v", name, err)
+			}
+			expense.CategoryID = resolved
+			return expense, "", nil
+		}
+	}
+	if rule := engine.Match(categorize.Candidate{Description: description, Amount: amount}); rule != nil {
+		expense.CategoryID = rule.CategoryID
+	}
+	return expense, "", nil
+}
+
+// detect samples the file and infers its conventions
+func detect(data []byte, mapping models.CSVColumnMapping) (*models.CSVDetection, error) {
+	headerLine, _, _ := bytes.Cut(data, []byte("\n"))
+	delimiter := DetectDelimiter(string(headerLine))
+
+	reader := newReader(data, delimiter)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, invalidFile(err)
+	}
+	columns, err := resolveColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	var amounts, dates []string
+	for len(amounts) < SampleSize {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidFile(err)
+		}
+		amounts = append(amounts, columns.value(record, columns.amount))
+		dates = append(dates, columns.value(record, columns.date))
+	}
+
+	detection := Detect(amounts, dates)
+	detection.Delimiter = delimiter
+	return &detection, nil
+}
+
+// columnIndexes are the positions of the mapped columns, -1 if unmapped
+type columnIndexes struct {
+	date, amount, description, category int
+}
+
+// value returns the cell at index, or "" for short rows and unmapped columns
+func (c columnIndexes) value(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return record[index]
+}
+
+// resolveColumns finds the mapped columns in the header, ignoring case
+func resolveColumns(header []string, mapping models.CSVColumnMapping) (columnIndexes, error) {
+	find := func(name string) int {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	columns := columnIndexes{
+		date:        find(mapping.Date),
+		amount:      find(mapping.Amount),
+		description: find(mapping.Description),
+		category:    -1,
+	}
+	var errs utils.ValidationErrors
+	for _, mapped := range []struct {
+		field string
+		index int
+	}{{"date", columns.date}, {"amount", columns.amount}, {"description", columns.description}} {
+		if mapped.index < 0 {
+			errs.Add("mapping."+mapped.field, fmt.Sprintf("column for %s not found in the file header", mapped.field))
+		}
+	}
+	if mapping.Category != nil {
+		if columns.category = find(*mapping.Category); columns.category < 0 {
+			errs.Add("mapping.category", "column for category not found in the file header")
+		}
+	}
+	if errs.HasErrors() {
+		return columns, errs
+	}
+	return columns, nil
+}
+
+// readFile reads an uploaded file, rejecting files over MaxFileSize
+func readFile(file io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(file, MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv file: %w", err)
+	}
+	if len(data) > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	// Spreadsheet exports often start with a byte order mark
+	return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), nil
+}
+
+// newReader creates a lenient CSV reader for bank exports
+func newReader(data []byte, delimiter string) *csv.Reader {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = []rune(delimiter)[0]
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+// invalidFile reports a file that is not valid CSV
+func invalidFile(err error) error {
+	var errs utils.ValidationErrors
+	errs.Add("file", fmt.Sprintf("file is not a valid csv file: %v", err))
+	return errs
+}
+
+// ValidateRequest validates the options of a CSV import. Settings are
+// required to commit.
+func ValidateRequest(req *models.CSVImportRequest, commit bool) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if strings.TrimSpace(req.Mapping.Date) == "" {
+		errs.Add("mapping.date", "date column is required")
+	}
+	if strings.TrimSpace(req.Mapping.Amount) == "" {
+		errs.Add("mapping.amount", "amount column is required")
+	}
+	if strings.TrimSpace(req.Mapping.Description) == "" {
+		errs.Add("mapping.description", "description column is required")
+	}
+	if req.CategoryID == uuid.Nil {
+		errs.Add("category_id", "category_id is required")
+	}
+
+	if req.Settings == nil {
+		if commit {
+			errs.Add("settings", "settings are required to commit; use the settings returned by the dry run")
+		}
+		return errs
+	}
+	validDelimiter := false
+	for _, delimiter := range delimiters {
+		validDelimiter = validDelimiter || req.Settings.Delimiter == delimiter
+	}
+	if !validDelimiter {
+		errs.Add("settings.delimiter", "delimiter must be one of comma, semicolon, tab, pipe")
+	}
+	if !models.CSVDecimalSeparators.Valid(req.Settings.Decimal) {
+		errs.Add("settings.decimal", models.CSVDecimalSeparators.Message("decimal"))
+	}
+	if !models.CSVDateOrders.Valid(req.Settings.DateOrder) {
+		errs.Add("settings.date_order", models.CSVDateOrders.Message("date_order"))
+	}
+
+	return errs
+}
@@ -0,0 +1,146 @@
+package csvimport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/categorize"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+type recordingSink struct {
+	importID uuid.UUID
+	expenses []models.ExpenseCreateRequest
+}
+
+func (s *recordingSink) CreateExpenses(ctx context.Context, userID, importID uuid.UUID, expenses []models.ExpenseCreateRequest) error {
+	s.importID = importID
+	s.expenses = append(s.expenses, expenses...)
+	return nil
+}
+
+const germanExport = "\xef\xbb\xbfBuchungstag;Verwendungszweck;Betrag;Kategorie\n" +
+	"02.01.2024;Supermarkt;-54,20;Groceries\n" +
+	"15.01.2024;\"Miete; Januar\";-1.250,00;\n" +
+	"31.01.2024;Tankstelle;-1,234.56;\n" +
+	"1/2/2024;Bäckerei;-3,10;\n"
+
+func germanRequest() *models.CSVImportRequest {
+	category := "Kategorie"
+	return &models.CSVImportRequest{
+		Mapping: models.CSVColumnMapping{
+			Date:        "buchungstag",
+			Amount:      "Betrag",
+			Description: "Verwendungszweck",
+			Category:    &category,
+		},
+		CategoryID: uuid.New(),
+	}
+}
+
+func TestDryRunDetectsAndReportsRowErrors(t *testing.T) {
+	groceries := uuid.New()
+	service := NewService(&recordingSink{}, func(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error) {
+		return groceries, nil
+	})
+	req := germanRequest()
+
+	preview, err := service.DryRun(context.Background(), uuid.New(), strings.NewReader(germanExport), req)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	want := models.CSVImportSettings{Delimiter: ";", Decimal: models.DecimalComma, DateOrder: models.DateOrderDMY}
+	if preview.Detection == nil || preview.Settings != want {
+		t.Fatalf("Detected %+v, want %+v", preview.Settings, want)
+	}
+	if preview.TotalRows != 4 || preview.ValidRows != 3 || len(preview.Rows) != 3 {
+		t.Errorf("Unexpected preview counts %+v", preview)
+	}
+	if len(preview.Errors) != 1 || preview.Errors[0].Line != 4 || preview.Errors[0].Column != "amount" {
+		t.Errorf("Expected the point-decimal row on line 4 to fail, got %+v", preview.Errors)
+	}
+	if preview.Rows[0].CategoryID != groceries || preview.Rows[1].CategoryID != req.CategoryID {
+		t.Error("Expected the category column to be resolved and the default used when empty")
+	}
+	if preview.Rows[1].Amount != 1250 || preview.Rows[1].Description != "Miete; Januar" {
+		t.Errorf("Unexpected row %+v", preview.Rows[1])
+	}
+}
+
+func TestCommitRequiresPinnedSettings(t *testing.T) {
+	sink := &recordingSink{}
+	service := NewService(sink, nil)
+	req := germanRequest()
+
+	var errs utils.ValidationErrors
+	if _, err := service.Commit(context.Background(), uuid.New(), strings.NewReader(germanExport), req); !errors.As(err, &errs) {
+		t.Fatalf("Expected commit without settings to fail validation, got %v", err)
+	}
+
+	// Pinned settings are applied as given: day-first rows that cannot be
+	// read month-first fail rather than being reinterpreted
+	req.Settings = &models.CSVImportSettings{Delimiter: ";", Decimal: models.DecimalComma, DateOrder: models.DateOrderMDY}
+	result, err := service.Commit(context.Background(), uuid.New(), strings.NewReader(germanExport), req)
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if result.Imported != 2 || len(sink.expenses) != 2 || sink.expenses[0].ExpenseDate.Month() != 2 || sink.expenses[1].ExpenseDate.Day() != 2 {
+		t.Errorf("Expected 02.01.2024 and 1/2/2024 to import month-first, got %+v", sink.expenses)
+	}
+	if result.ImportID == nil || *result.ImportID != sink.importID {
+		t.Error("Expected the result to reference the import")
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Line != 3 || result.Errors[1].Line != 4 {
+		t.Errorf("Expected lines 3 and 4 to fail, got %+v", result.Errors)
+	}
+}
+
+func TestImportRejectsUnknownColumns(t *testing.T) {
+	service := NewService(&recordingSink{}, nil)
+	req := germanRequest()
+	req.Mapping.Amount = "Amount"
+
+	var errs utils.ValidationErrors
+	_, err := service.DryRun(context.Background(), uuid.New(), strings.NewReader(germanExport), req)
+	if !errors.As(err, &errs) || !strings.Contains(errs.Error(), "amount") {
+		t.Errorf("Expected a missing column error, got %v", err)
+	}
+}
+
+type staticRules struct{ engine *categorize.Engine }
+
+func (s staticRules) Engine(ctx context.Context, userID uuid.UUID) (*categorize.Engine, error) {
+	return s.engine, nil
+}
+
+func TestDryRunAppliesCategorizationRules(t *testing.T) {
+	groceries, fuel := uuid.New(), uuid.New()
+	pattern := "tank*"
+	engine := categorize.NewEngine([]models.CategorizationRule{
+		{ID: uuid.New(), DescriptionPattern: &pattern, CategoryID: fuel, IsActive: true},
+	})
+	service := NewService(&recordingSink{}, func(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error) {
+		return groceries, nil
+	}).WithRules(staticRules{engine: engine})
+
+	csv := "Buchungstag;Verwendungszweck;Betrag;Kategorie\n" +
+		"02.01.2024;Tankstelle Nord;-54,20;Groceries\n" +
+		"03.01.2024;Tankstelle Süd;-61,00;\n" +
+		"04.01.2024;Miete;-900,00;\n"
+	req := germanRequest()
+	preview, err := service.DryRun(context.Background(), uuid.New(), strings.NewReader(csv), req)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if len(preview.Rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %+v", preview)
+	}
+	if preview.Rows[0].CategoryID != groceries || preview.Rows[1].CategoryID != fuel || preview.Rows[2].CategoryID != req.CategoryID {
+		t.Error("Expected the file category to win, then the matching rule, then the default")
+	}
+}
@@ -0,0 +1,50 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+
+	"tgfinance/internal/models"
+)
+
+// Classify returns the error class of a failed delivery. statusCode is the
+// HTTP status the endpoint answered with, or 0 if there was no response.
+func Classify(err error, statusCode int) string {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return models.ErrorClassRateLimited
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return models.ErrorClassTimeout
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		return models.ErrorClassPayload
+	case statusCode >= 500:
+		return models.ErrorClassServer
+	case statusCode >= 400:
+		return models.ErrorClassClient
+	}
+
+	if err == nil {
+		return models.ErrorClassUnknown
+	}
+
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnsupportedTypeError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.ErrorClassTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return models.ErrorClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return models.ErrorClassConnection
+	case errors.As(err, new(*net.OpError)), errors.As(err, new(*net.DNSError)):
+		return models.ErrorClassConnection
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return models.ErrorClassPayload
+	}
+	return models.ErrorClassUnknown
+}
@@ -0,0 +1,147 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the admin dead letter endpoints
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new dead letter handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List handles GET /api/v1/admin/dlq
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.DeadLetterFilter{Status: query.Get("status")}
+	if eventType := query.Get("event_type"); eventType != "" {
+		filter.EventType = &eventType
+	}
+	if errorClass := query.Get("error_class"); errorClass != "" {
+		filter.ErrorClass = &errorClass
+	}
+	if raw := query.Get("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid user_id")
+			return
+		}
+		filter.UserID = &userID
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid offset")
+			return
+		}
+		filter.Offset = offset
+	}
+
+	letters, total, err := h.service.List(r.Context(), filter)
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to list dead letters")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"dead_letters": letters, "total": total})
+	}
+}
+
+// Retry handles POST /api/v1/admin/dlq/{id}/retry
+func (h *Handler) Retry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid dead letter ID")
+		return
+	}
+
+	letter, err := h.service.Retry(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Dead letter not found")
+	case errors.Is(err, ErrNotRetryable):
+		httputil.WriteErrorFrom(w, http.StatusConflict, httputil.CodeForStatus(http.StatusConflict), err)
+	case err != nil:
+		httputil.WriteError(w, http.StatusBadGateway, httputil.CodeForStatus(http.StatusBadGateway), "Failed to requeue dead letter")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, letter)
+	}
+}
+
+// RetryClass handles POST /api/v1/admin/dlq/retry
+func (h *Handler) RetryClass(w http.ResponseWriter, r *http.Request) {
+	var req models.DeadLetterBulkRetryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	result, err := h.service.RetryClass(r.Context(), req.ErrorClass)
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to retry dead letters")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, result)
+	}
+}
+
+// Discard handles DELETE /api/v1/admin/dlq/{id}
+func (h *Handler) Discard(w http.ResponseWriter, r *http.Request) {
+	actorID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid dead letter ID")
+		return
+	}
+	var req models.DeadLetterDiscardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	letter, err := h.service.Discard(r.Context(), id, actorID, &req)
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrNotFound):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Dead letter not found")
+	case errors.Is(err, ErrNotRetryable):
+		httputil.WriteErrorFrom(w, http.StatusConflict, httputil.CodeForStatus(http.StatusConflict), err)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to discard dead letter")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, letter)
+	}
+}
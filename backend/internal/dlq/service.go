@@ -0,0 +1,257 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+// Pagination bounds for listing dead letters
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Errors returned by the dead letter service
+var (
+	ErrNotFound     = errors.New("dead letter not found")
+	ErrNotRetryable = errors.New("dead letter has already been requeued or discarded")
+)
+
+// Store persists dead letters
+type Store interface {
+	List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, int, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error)
+	// ListDeadByClass returns the dead letters of an error class still awaiting action
+	ListDeadByClass(ctx context.Context, errorClass string) ([]models.DeadLetter, error)
+	MarkRequeued(ctx context.Context, id uuid.UUID, at time.Time) error
+	MarkDiscarded(ctx context.Context, id, actorID uuid.UUID, reason string, at time.Time) error
+	// CountDeadByEventType returns the number of dead letters per event type
+	CountDeadByEventType(ctx context.Context) (map[string]int, error)
+}
+
+// Requeuer puts an event back on the outbox for delivery
+type Requeuer interface {
+	// Requeue enqueues the event under its original event ID and ordering
+	// key, so consumers can deduplicate and per-key order is kept
+	Requeue(ctx context.Context, event Event) error
+}
+
+// Auditor records operator actions
+type Auditor interface {
+	RecordAudit(ctx context.Context, entry models.AuditLog) error
+}
+
+// Event is a dead-lettered event handed back to the outbox
+type Event struct {
+	EventID     uuid.UUID
+	EventType   string
+	UserID      *uuid.UUID
+	OrderingKey string
+	Payload     json.RawMessage
+}
+
+// Service lets operators browse, retry and discard dead letters
+type Service struct {
+	store    Store
+	requeuer Requeuer
+	auditor  Auditor
+	clock    clock.Clock
+}
+
+// NewService creates a new dead letter service
+func NewService(store Store, requeuer Requeuer, auditor Auditor) *Service {
+	return &Service{store: store, requeuer: requeuer, auditor: auditor, clock: clock.Real()}
+}
+
+// List returns dead letters matching filter and the total number of matches.
+// Without a status filter only dead letters awaiting action are listed.
+func (s *Service) List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, int, error) {
+	if errs := ValidateFilter(&filter); errs.HasErrors() {
+		return nil, 0, errs
+	}
+	letters, total, err := s.store.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return letters, total, nil
+}
+
+// Retry requeues a single dead letter
+func (s *Service) Retry(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error) {
+	letter, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if letter == nil {
+		return nil, ErrNotFound
+	}
+	if err := s.requeue(ctx, letter); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// RetryClass requeues every dead letter of an error class, oldest first so
+// events sharing an ordering key are redelivered in their original order
+func (s *Service) RetryClass(ctx context.Context, errorClass string) (*models.DeadLetterRetryResult, error) {
+	if !models.ErrorClasses.Valid(errorClass) {
+		var errs utils.ValidationErrors
+		errs.Add("error_class", models.ErrorClasses.Message("error_class"))
+		return nil, errs
+	}
+
+	letters, err := s.store.ListDeadByClass(ctx, errorClass)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	sort.SliceStable(letters, func(i, j int) bool { return letters[i].DeadAt.Before(letters[j].DeadAt) })
+
+	result := &models.DeadLetterRetryResult{}
+	blocked := make(map[string]bool)
+	for i := range letters {
+		letter := &letters[i]
+		// Once an event of a key fails to requeue, later events of that key
+		// stay dead so they are not delivered ahead of it
+		if blocked[letter.OrderingKey] {
+			result.Failed = append(result.Failed, models.DeadLetterFailure{ID: letter.ID, Error: "an earlier event with the same ordering key could not be requeued"})
+			continue
+		}
+		if err := s.requeue(ctx, letter); err != nil {
+			blocked[letter.OrderingKey] = true
+			result.Failed = append(result.Failed, models.DeadLetterFailure{ID: letter.ID, Error: err.Error()})
+			continue
+		}
+		result.Requeued++
+	}
+	return result, nil
+}
+
+// requeue hands a dead letter back to the outbox. The event keeps its ID, so
+// if marking it requeued fails and it is retried again, consumers still see
+// a duplicate they can ignore rather than a new event.
+func (s *Service) requeue(ctx context.Context, letter *models.DeadLetter) error {
+	if !letter.IsRetryable() {
+		return ErrNotRetryable
+	}
+	event := Event{
+		EventID:     letter.EventID,
+		EventType:   letter.EventType,
+		UserID:      letter.UserID,
+		OrderingKey: letter.OrderingKey,
+		Payload:     letter.Payload,
+	}
+	if err := s.requeuer.Requeue(ctx, event); err != nil {
+		return fmt.Errorf("failed to requeue event %s: %w", letter.EventID, err)
+	}
+
+	now := s.clock.Now().UTC()
+	if err := s.store.MarkRequeued(ctx, letter.ID, now); err != nil {
+		return fmt.Errorf("failed to mark dead letter requeued: %w", err)
+	}
+	letter.Status = models.DeadLetterStatusRequeued
+	letter.RequeuedAt = &now
+	return nil
+}
+
+// Discard drops a dead letter for good, recording who did it and why in the
+// audit log
+func (s *Service) Discard(ctx context.Context, id, actorID uuid.UUID, req *models.DeadLetterDiscardRequest) (*models.DeadLetter, error) {
+	if errs := ValidateDiscardRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+	letter, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if letter == nil {
+		return nil, ErrNotFound
+	}
+	if !letter.IsRetryable() {
+		return nil, ErrNotRetryable
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	now := s.clock.Now().UTC()
+	if err := s.store.MarkDiscarded(ctx, id, actorID, reason, now); err != nil {
+		return nil, fmt.Errorf("failed to discard dead letter: %w", err)
+	}
+
+	oldValue, _ := json.Marshal(letter.Status)
+	newValue, _ := json.Marshal(models.DeadLetterStatusDiscarded)
+	entry := models.AuditLog{
+		ID:          uuid.New(),
+		UserID:      letter.UserID,
+		ActorUserID: &actorID,
+		Action:      models.AuditActionDeadLetterDiscard,
+		EntityType:  "dead_letter",
+		EntityID:    &letter.ID,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		Reason:      &reason,
+		CreatedAt:   now,
+	}
+	if err := s.auditor.RecordAudit(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record discard: %w", err)
+	}
+
+	letter.Status = models.DeadLetterStatusDiscarded
+	letter.DiscardedAt = &now
+	letter.DiscardedBy = &actorID
+	letter.DiscardReason = &reason
+	return letter, nil
+}
+
+// DeadLetterCounts returns the number of dead letters per event type, for
+// the alerting gauges
+func (s *Service) DeadLetterCounts(ctx context.Context) (map[string]int, error) {
+	return s.store.CountDeadByEventType(ctx)
+}
+
+// ValidateFilter validates a dead letter filter and applies defaults
+func ValidateFilter(filter *models.DeadLetterFilter) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	switch filter.Status {
+	case "":
+		filter.Status = models.DeadLetterStatusDead
+	case models.DeadLetterStatusDead, models.DeadLetterStatusRequeued, models.DeadLetterStatusDiscarded:
+	default:
+		errs.Add("status", "status must be one of dead, requeued, discarded")
+	}
+	if filter.ErrorClass != nil && !models.ErrorClasses.Valid(*filter.ErrorClass) {
+		errs.Add("error_class", models.ErrorClasses.Message("error_class"))
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		errs.Add("limit", "limit and offset must not be negative")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultLimit
+	}
+	if filter.Limit > MaxLimit {
+		filter.Limit = MaxLimit
+	}
+
+	return errs
+}
+
+// ValidateDiscardRequest validates a dead letter discard request
+func ValidateDiscardRequest(req *models.DeadLetterDiscardRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if err := utils.ValidateRequired(strings.TrimSpace(req.Reason), "reason"); err != nil {
+		errs.Add("reason", "reason is required")
+	}
+
+	return errs
+}
@@ -0,0 +1,280 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type memoryStore struct {
+	letters map[uuid.UUID]*models.DeadLetter
+}
+
+func newMemoryStore(letters ...models.DeadLetter) *memoryStore {
+	s := &memoryStore{letters: make(map[uuid.UUID]*models.DeadLetter)}
+	for i := range letters {
+		s.letters[letters[i].ID] = &letters[i]
+	}
+	return s
+}
+
+func (s *memoryStore) List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, int, error) {
+	var matched []models.DeadLetter
+	for _, letter := range s.letters {
+		if letter.Status == filter.Status && (filter.ErrorClass == nil || *filter.ErrorClass == letter.ErrorClass) {
+			matched = append(matched, *letter)
+		}
+	}
+	return matched, len(matched), nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error) {
+	letter, ok := s.letters[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *letter
+	return &copied, nil
+}
+
+func (s *memoryStore) ListDeadByClass(ctx context.Context, errorClass string) ([]models.DeadLetter, error) {
+	var matched []models.DeadLetter
+	for _, letter := range s.letters {
+		if letter.Status == models.DeadLetterStatusDead && letter.ErrorClass == errorClass {
+			matched = append(matched, *letter)
+		}
+	}
+	return matched, nil
+}
+
+func (s *memoryStore) MarkRequeued(ctx context.Context, id uuid.UUID, at time.Time) error {
+	s.letters[id].Status = models.DeadLetterStatusRequeued
+	s.letters[id].RequeuedAt = &at
+	return nil
+}
+
+func (s *memoryStore) MarkDiscarded(ctx context.Context, id, actorID uuid.UUID, reason string, at time.Time) error {
+	s.letters[id].Status = models.DeadLetterStatusDiscarded
+	s.letters[id].DiscardReason = &reason
+	return nil
+}
+
+func (s *memoryStore) CountDeadByEventType(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, letter := range s.letters {
+		if letter.Status == models.DeadLetterStatusDead {
+			counts[letter.EventType]++
+		}
+	}
+	return counts, nil
+}
+
+type recordingRequeuer struct {
+	events []Event
+	failOn map[uuid.UUID]bool
+}
+
+func (r *recordingRequeuer) Requeue(ctx context.Context, event Event) error {
+	if r.failOn[event.EventID] {
+		return errors.New("outbox unavailable")
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+type recordingAuditor struct{ entries []models.AuditLog }
+
+func (a *recordingAuditor) RecordAudit(ctx context.Context, entry models.AuditLog) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func deadLetter(key, class string, age time.Duration) models.DeadLetter {
+	return models.DeadLetter{
+		ID:          uuid.New(),
+		EventID:     uuid.New(),
+		EventType:   "webhook.expense_created",
+		OrderingKey: key,
+		Payload:     []byte(`{"amount":12.5}`),
+		ErrorClass:  class,
+		Status:      models.DeadLetterStatusDead,
+		DeadAt:      testNow.Add(-age),
+	}
+}
+
+func newTestService(store Store, requeuer Requeuer, auditor Auditor) *Service {
+	s := NewService(store, requeuer, auditor)
+	s.clock = clock.NewFake(testNow)
+	return s
+}
+
+func TestRetryPreservesEventIdentity(t *testing.T) {
+	letter := deadLetter("user-1", models.ErrorClassTimeout, time.Hour)
+	store := newMemoryStore(letter)
+	requeuer := &recordingRequeuer{}
+	service := newTestService(store, requeuer, &recordingAuditor{})
+
+	retried, err := service.Retry(context.Background(), letter.ID)
+	if err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if retried.Status != models.DeadLetterStatusRequeued || retried.RequeuedAt == nil || !retried.RequeuedAt.Equal(testNow) {
+		t.Errorf("Unexpected retried letter %+v", retried)
+	}
+	if len(requeuer.events) != 1 {
+		t.Fatalf("Expected one requeued event, got %d", len(requeuer.events))
+	}
+	event := requeuer.events[0]
+	if event.EventID != letter.EventID || event.OrderingKey != letter.OrderingKey || string(event.Payload) != string(letter.Payload) {
+		t.Errorf("Requeued event %+v does not match the dead letter", event)
+	}
+
+	if _, err := service.Retry(context.Background(), letter.ID); !errors.Is(err, ErrNotRetryable) {
+		t.Errorf("Expected ErrNotRetryable on second retry, got %v", err)
+	}
+	if _, err := service.Retry(context.Background(), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRetryClassKeepsOrderPerKey(t *testing.T) {
+	first := deadLetter("user-1", models.ErrorClassServer, 3*time.Hour)
+	second := deadLetter("user-1", models.ErrorClassServer, 2*time.Hour)
+	third := deadLetter("user-1", models.ErrorClassServer, time.Hour)
+	other := deadLetter("user-2", models.ErrorClassServer, 90*time.Minute)
+	skipped := deadLetter("user-3", models.ErrorClassTimeout, time.Hour)
+	store := newMemoryStore(third, other, first, second, skipped)
+	requeuer := &recordingRequeuer{failOn: map[uuid.UUID]bool{second.EventID: true}}
+	service := newTestService(store, requeuer, &recordingAuditor{})
+
+	result, err := service.RetryClass(context.Background(), models.ErrorClassServer)
+	if err != nil {
+		t.Fatalf("RetryClass failed: %v", err)
+	}
+	if result.Requeued != 2 || len(result.Failed) != 2 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+
+	var order []uuid.UUID
+	for _, event := range requeuer.events {
+		order = append(order, event.EventID)
+	}
+	if len(order) != 2 || order[0] != first.EventID || order[1] != other.EventID {
+		t.Errorf("Requeued %v, want the oldest user-1 event then user-2", order)
+	}
+	// The event after a failed one stays dead so it is not delivered out of order
+	if store.letters[third.ID].Status != models.DeadLetterStatusDead {
+		t.Error("Expected the later event of a blocked key to stay dead")
+	}
+	if store.letters[skipped.ID].Status != models.DeadLetterStatusDead {
+		t.Error("Expected other error classes to be left alone")
+	}
+
+	var errs utils.ValidationErrors
+	if _, err := service.RetryClass(context.Background(), "bogus"); !errors.As(err, &errs) {
+		t.Errorf("Expected validation error for unknown class, got %v", err)
+	}
+}
+
+func TestDiscardRequiresReasonAndAudits(t *testing.T) {
+	userID := uuid.New()
+	letter := deadLetter("user-1", models.ErrorClassPayload, time.Hour)
+	letter.UserID = &userID
+	store := newMemoryStore(letter)
+	auditor := &recordingAuditor{}
+	service := newTestService(store, &recordingRequeuer{}, auditor)
+	adminID := uuid.New()
+
+	var errs utils.ValidationErrors
+	if _, err := service.Discard(context.Background(), letter.ID, adminID, &models.DeadLetterDiscardRequest{Reason: "  "}); !errors.As(err, &errs) {
+		t.Fatalf("Expected validation error without a reason, got %v", err)
+	}
+
+	discarded, err := service.Discard(context.Background(), letter.ID, adminID, &models.DeadLetterDiscardRequest{Reason: "payload references a deleted expense"})
+	if err != nil {
+		t.Fatalf("Discard failed: %v", err)
+	}
+	if discarded.Status != models.DeadLetterStatusDiscarded || *discarded.DiscardedBy != adminID {
+		t.Errorf("Unexpected discarded letter %+v", discarded)
+	}
+	if len(auditor.entries) != 1 {
+		t.Fatalf("Expected one audit entry, got %d", len(auditor.entries))
+	}
+	entry := auditor.entries[0]
+	if entry.Action != models.AuditActionDeadLetterDiscard || *entry.ActorUserID != adminID ||
+		*entry.UserID != userID || *entry.Reason != "payload references a deleted expense" {
+		t.Errorf("Unexpected audit entry %+v", entry)
+	}
+
+	counts, _ := service.DeadLetterCounts(context.Background())
+	if len(counts) != 0 {
+		t.Errorf("Expected no dead letters left, got %v", counts)
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	bogus := "bogus"
+	tests := []struct {
+		name      string
+		filter    models.DeadLetterFilter
+		wantErr   bool
+		wantLimit int
+	}{
+		{name: "defaults", filter: models.DeadLetterFilter{}, wantLimit: DefaultLimit},
+		{name: "capped", filter: models.DeadLetterFilter{Limit: 1000}, wantLimit: MaxLimit},
+		{name: "unknown status", filter: models.DeadLetterFilter{Status: "gone"}, wantErr: true},
+		{name: "unknown class", filter: models.DeadLetterFilter{ErrorClass: &bogus}, wantErr: true},
+		{name: "negative offset", filter: models.DeadLetterFilter{Offset: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			errs := ValidateFilter(&filter)
+			if errs.HasErrors() != tt.wantErr {
+				t.Fatalf("ValidateFilter() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if !tt.wantErr && (filter.Limit != tt.wantLimit || filter.Status != models.DeadLetterStatusDead) {
+				t.Errorf("Unexpected defaults %+v", filter)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		want       string
+	}{
+		{name: "rate limited", statusCode: http.StatusTooManyRequests, want: models.ErrorClassRateLimited},
+		{name: "gateway timeout", statusCode: http.StatusGatewayTimeout, want: models.ErrorClassTimeout},
+		{name: "server error", statusCode: http.StatusBadGateway, want: models.ErrorClassServer},
+		{name: "unprocessable", statusCode: http.StatusUnprocessableEntity, want: models.ErrorClassPayload},
+		{name: "forbidden", statusCode: http.StatusForbidden, want: models.ErrorClassClient},
+		{name: "deadline", err: fmt.Errorf("post: %w", context.DeadlineExceeded), want: models.ErrorClassTimeout},
+		{name: "dial", err: &net.OpError{Op: "dial", Err: errors.New("refused")}, want: models.ErrorClassConnection},
+		{name: "other", err: errors.New("boom"), want: models.ErrorClassUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err, tt.statusCode); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
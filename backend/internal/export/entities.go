@@ -0,0 +1,126 @@
+package export
+
+import (
+	"io"
+
+	"tgfinance/internal/models"
+)
+
+// investmentColumns returns the exported investment columns
+func investmentColumns(currency string) []Column[models.Investment] {
+	return []Column[models.Investment]{
+		{"id", "Investment ID", func(i *models.Investment) string { return i.ID.String() }},
+		{"name", "Investment name", func(i *models.Investment) string { return i.Name }},
+		{"type_id", "Investment type ID", func(i *models.Investment) string { return i.TypeID.String() }},
+		{"status", "active, matured, cancelled or closed", func(i *models.Investment) string { return i.Status }},
+		{"amount", "Net invested amount", func(i *models.Investment) string { return formatAmount(i.Amount) }},
+		{"current_value", "Current value (0 once closed)", func(i *models.Investment) string { return formatAmount(i.GetCurrentValue()) }},
+		{"realized_gain", "Gain realized when the position was closed", func(i *models.Investment) string { return formatOptionalAmount(i.RealizedGain) }},
+		{"start_date", "Start date (YYYY-MM-DD)", func(i *models.Investment) string { return formatDate(i.StartDate) }},
+		{"end_date", "Maturity or end date (YYYY-MM-DD)", func(i *models.Investment) string { return formatOptionalDate(i.EndDate) }},
+		{"closed_at", "Close date (YYYY-MM-DD)", func(i *models.Investment) string { return formatOptionalDate(i.ClosedAt) }},
+		{"interest_rate", "Annual interest rate in percent", func(i *models.Investment) string { return formatRate(i.InterestRate) }},
+		{"expense_ratio", "Annual expense ratio in percent", func(i *models.Investment) string { return formatRate(i.ExpenseRatio) }},
+		{"institution", "Institution", func(i *models.Investment) string { return formatOptional(i.Institution) }},
+		{"currency", "Currency of all amounts", func(i *models.Investment) string { return currency }},
+	}
+}
+
+// transactionColumns returns the exported investment transaction columns
+func transactionColumns(currency string) []Column[models.InvestmentTransaction] {
+	return []Column[models.InvestmentTransaction]{
+		{"id", "Transaction ID", func(t *models.InvestmentTransaction) string { return t.ID.String() }},
+		{"investment_id", "Investment ID (investments file)", func(t *models.InvestmentTransaction) string { return t.InvestmentID.String() }},
+		{"transaction_type", "deposit, withdrawal, interest, dividend or fee", func(t *models.InvestmentTransaction) string { return t.TransactionType }},
+		{"amount", "Transaction amount", func(t *models.InvestmentTransaction) string { return formatAmount(t.Amount) }},
+		{"transaction_date", "Transaction date (YYYY-MM-DD)", func(t *models.InvestmentTransaction) string { return formatDate(t.TransactionDate) }},
+		{"description", "Description", func(t *models.InvestmentTransaction) string { return formatOptional(t.Description) }},
+		{"currency", "Currency of the amount", func(t *models.InvestmentTransaction) string { return currency }},
+	}
+}
+
+// goalColumns returns the exported goal columns
+func goalColumns(currency string) []Column[models.FinancialGoal] {
+	return []Column[models.FinancialGoal]{
+		{"id", "Goal ID", func(g *models.FinancialGoal) string { return g.ID.String() }},
+		{"name", "Goal name", func(g *models.FinancialGoal) string { return g.Name }},
+		{"goal_type", "Goal type", func(g *models.FinancialGoal) string { return g.GoalType }},
+		{"priority", "low, medium or high", func(g *models.FinancialGoal) string { return g.Priority }},
+		{"status", "Goal status", func(g *models.FinancialGoal) string { return g.Status }},
+		{"target_amount", "Target amount", func(g *models.FinancialGoal) string { return formatAmount(g.TargetAmount) }},
+		{"current_amount", "Amount saved so far", func(g *models.FinancialGoal) string { return formatAmount(g.CurrentAmount) }},
+		{"target_date", "Target date (YYYY-MM-DD)", func(g *models.FinancialGoal) string { return formatOptionalDate(g.TargetDate) }},
+		{"linked_investment_id", "Investment funding the goal", func(g *models.FinancialGoal) string {
+			if g.LinkedInvestmentID == nil {
+				return ""
+			}
+			return g.LinkedInvestmentID.String()
+		}},
+		{"currency", "Currency of all amounts", func(g *models.FinancialGoal) string { return currency }},
+	}
+}
+
+// contributionColumns returns the exported goal contribution columns
+func contributionColumns(currency string) []Column[models.GoalContribution] {
+	return []Column[models.GoalContribution]{
+		{"id", "Contribution ID", func(c *models.GoalContribution) string { return c.ID.String() }},
+		{"goal_id", "Goal ID (goals file)", func(c *models.GoalContribution) string { return c.GoalID.String() }},
+		{"amount", "Contribution amount (negative for sync decreases)", func(c *models.GoalContribution) string { return formatAmount(c.Amount) }},
+		{"contribution_date", "Contribution date (YYYY-MM-DD)", func(c *models.GoalContribution) string { return formatDate(c.ContributionDate) }},
+		{"source", "Source of the contribution", func(c *models.GoalContribution) string { return formatOptional(c.Source) }},
+		{"notes", "Notes", func(c *models.GoalContribution) string { return formatOptional(c.Notes) }},
+		{"currency", "Currency of the amount", func(c *models.GoalContribution) string { return currency }},
+	}
+}
+
+// Investments writes investments, and their transactions as a second file in
+// a ZIP when opts.IncludeNested is set
+func Investments(w io.Writer, opts Options, investments Source[models.Investment], transactions Source[models.InvestmentTransaction]) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	currency := opts.currency()
+
+	writeInvestments := func(out io.Writer) error {
+		return writeRows(out, &opts, investmentColumns(currency), investments)
+	}
+	if !opts.IncludeNested {
+		return writeInvestments(w)
+	}
+
+	return writeBundle(w, &opts, "investments", writeInvestments, "transactions", func(out io.Writer) error {
+		return writeRows(out, &opts, transactionColumns(currency), transactions)
+	})
+}
+
+// Goals writes goals, and their contributions as a second file in a ZIP when
+// opts.IncludeNested is set
+func Goals(w io.Writer, opts Options, goals Source[models.FinancialGoal], contributions Source[models.GoalContribution]) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	currency := opts.currency()
+
+	writeGoals := func(out io.Writer) error {
+		return writeRows(out, &opts, goalColumns(currency), goals)
+	}
+	if !opts.IncludeNested {
+		return writeGoals(w)
+	}
+
+	return writeBundle(w, &opts, "goals", writeGoals, "contributions", func(out io.Writer) error {
+		return writeRows(out, &opts, contributionColumns(currency), contributions)
+	})
+}
+
+// SliceSource streams the elements of a slice
+func SliceSource[T any](rows []T) Source[T] {
+	return func(yield func(row *T) error) error {
+		for i := range rows {
+			if err := yield(&rows[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+func sampleInvestments() []models.Investment {
+	rate := 7.25
+	institution := "Vanguard"
+	return []models.Investment{
+		{
+			ID:           uuid.New(),
+			TypeID:       uuid.New(),
+			Name:         "Index fund, total market",
+			Amount:       1000.5,
+			StartDate:    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			InterestRate: &rate,
+			Institution:  &institution,
+			Status:       models.InvestmentStatusActive,
+		},
+	}
+}
+
+func TestInvestmentsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	investments := sampleInvestments()
+	opts := Options{Format: FormatCSV, HeaderComment: true, Currency: "EUR"}
+	if err := Investments(&buf, opts, SliceSource(investments), nil); err != nil {
+		t.Fatalf("Investments() error = %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows = %d, want header, comment and one record", len(rows))
+	}
+	if rows[0][0] != "id" || !strings.HasPrefix(rows[1][0], "# ") {
+		t.Errorf("unexpected header rows: %v / %v", rows[0], rows[1])
+	}
+
+	record := make(map[string]string)
+	for i, name := range rows[0] {
+		record[name] = rows[2][i]
+	}
+	want := map[string]string{
+		"name":          "Index fund, total market",
+		"amount":        "1000.50",
+		"start_date":    "2025-03-01",
+		"end_date":      "",
+		"interest_rate": "7.25",
+		"currency":      "EUR",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %q, want %q", key, record[key], value)
+		}
+	}
+}
+
+func TestGoalsJSON(t *testing.T) {
+	linked := uuid.New()
+	goals := []models.FinancialGoal{
+		{ID: uuid.New(), Name: "House", TargetAmount: 50000, CurrentAmount: 1234.5, LinkedInvestmentID: &linked},
+		{ID: uuid.New(), Name: "Car", TargetAmount: 8000},
+	}
+
+	var buf bytes.Buffer
+	if err := Goals(&buf, Options{Format: FormatJSON}, SliceSource(goals), nil); err != nil {
+		t.Fatalf("Goals() error = %v", err)
+	}
+
+	var decoded []map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("decoded %d goals, want 2", len(decoded))
+	}
+	if decoded[0]["current_amount"] != "1234.50" || decoded[0]["linked_investment_id"] != linked.String() {
+		t.Errorf("unexpected first goal: %v", decoded[0])
+	}
+	if decoded[1]["currency"] != DefaultCurrency {
+		t.Errorf("currency = %q, want %q", decoded[1]["currency"], DefaultCurrency)
+	}
+	if !strings.HasPrefix(buf.String(), `[{"id":`) {
+		t.Errorf("keys should follow column order, got %q", buf.String()[:20])
+	}
+}
+
+func TestEmptyJSONExport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Goals(&buf, Options{Format: FormatJSON}, SliceSource[models.FinancialGoal](nil), nil); err != nil {
+		t.Fatalf("Goals() error = %v", err)
+	}
+	if buf.String() != "[]" {
+		t.Errorf("empty export = %q, want []", buf.String())
+	}
+}
+
+func TestInvestmentsBundle(t *testing.T) {
+	investments := sampleInvestments()
+	transactions := []models.InvestmentTransaction{
+		{ID: uuid.New(), InvestmentID: investments[0].ID, TransactionType: "deposit", Amount: 1000.5, TransactionDate: investments[0].StartDate},
+	}
+
+	var buf bytes.Buffer
+	opts := Options{Format: FormatCSV, IncludeNested: true}
+	if err := Investments(&buf, opts, SliceSource(investments), SliceSource(transactions)); err != nil {
+		t.Fatalf("Investments() error = %v", err)
+	}
+	if opts.ContentType() != "application/zip" || opts.FileName("investments") != "investments.zip" {
+		t.Errorf("unexpected content type or file name for bundle")
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	files := make(map[string][][]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: invalid csv: %v", file.Name, err)
+		}
+		files[file.Name] = rows
+	}
+
+	if len(files["investments.csv"]) != 2 || len(files["transactions.csv"]) != 2 {
+		t.Fatalf("unexpected bundle contents: %v", files)
+	}
+	if files["transactions.csv"][0][1] != "investment_id" || files["transactions.csv"][1][1] != investments[0].ID.String() {
+		t.Errorf("transactions should reference the investment: %v", files["transactions.csv"])
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"csv", Options{Format: FormatCSV}, false},
+		{"json", Options{Format: FormatJSON}, false},
+		{"unknown format", Options{Format: "xml"}, true},
+		{"header comment on json", Options{Format: FormatJSON, HeaderComment: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// DefaultCurrency is used when the user has no base currency configured
+const DefaultCurrency = "USD"
+
+// flushEvery bounds how many rows are buffered before flushing to the client
+const flushEvery = 100
+
+// Options controls an export
+type Options struct {
+	Format string
+	// IncludeNested adds child records (transactions, contributions) as a second file in a ZIP
+	IncludeNested bool
+	// HeaderComment writes a leading CSV row describing each column
+	HeaderComment bool
+	// Currency is the user's base currency that amounts are expressed in
+	Currency string
+}
+
+// Validate checks the export options
+func (o *Options) Validate() error {
+	if o.Format != FormatCSV && o.Format != FormatJSON {
+		return fmt.Errorf("format must be csv or json")
+	}
+	if o.HeaderComment && o.Format != FormatCSV {
+		return fmt.Errorf("header comments are only supported for csv")
+	}
+	return nil
+}
+
+// ContentType returns the response content type for the export
+func (o *Options) ContentType() string {
+	switch {
+	case o.IncludeNested:
+		return "application/zip"
+	case o.Format == FormatCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// FileName returns the download file name for the export
+func (o *Options) FileName(base string) string {
+	if o.IncludeNested {
+		return base + ".zip"
+	}
+	return base + "." + o.Format
+}
+
+func (o *Options) currency() string {
+	if o.Currency == "" {
+		return DefaultCurrency
+	}
+	return o.Currency
+}
+
+// Column describes one exported field
+type Column[T any] struct {
+	Name        string
+	Description string
+	Value       func(row *T) string
+}
+
+// Source streams rows to yield one at a time, typically from a database cursor,
+// so exports never hold the full result set in memory
+type Source[T any] func(yield func(row *T) error) error
+
+// writeRows writes the rows of source in the requested format
+func writeRows[T any](w io.Writer, opts *Options, columns []Column[T], source Source[T]) error {
+	if opts.Format == FormatCSV {
+		return writeCSV(w, columns, source, opts.HeaderComment)
+	}
+	return writeJSON(w, columns, source)
+}
+
+// writeCSV writes a header row, an optional description row and one row per record
+func writeCSV[T any](w io.Writer, columns []Column[T], source Source[T], headerComment bool) error {
+	out := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	if headerComment {
+		comment := make([]string, len(columns))
+		for i, column := range columns {
+			comment[i] = column.Description
+		}
+		comment[0] = "# " + comment[0]
+		if err := out.Write(comment); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(columns))
+	count := 0
+	err := source(func(row *T) error {
+		for i, column := range columns {
+			record[i] = column.Value(row)
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+		count++
+		if count%flushEvery == 0 {
+			out.Flush()
+			return out.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// writeJSON writes a JSON array of objects with keys in column order
+func writeJSON[T any](w io.Writer, columns []Column[T], source Source[T]) error {
+	out := bufio.NewWriter(w)
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		key, _ := json.Marshal(column.Name)
+		keys[i] = key
+	}
+
+	if _, err := out.WriteString("["); err != nil {
+		return err
+	}
+
+	count := 0
+	err := source(func(row *T) error {
+		if count > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("{")
+		for i, column := range columns {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			out.Write(keys[i])
+			out.WriteString(":")
+			value, err := json.Marshal(column.Value(row))
+			if err != nil {
+				return err
+			}
+			out.Write(value)
+		}
+		out.WriteString("}")
+
+		count++
+		if count%flushEvery == 0 {
+			return out.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.WriteString("]"); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// writeBundle writes a parent and child export as two files of a ZIP archive
+func writeBundle(w io.Writer, opts *Options, parentName string, writeParent func(io.Writer) error, childName string, writeChild func(io.Writer) error) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{parentName + "." + opts.Format, writeParent},
+		{childName + "." + opts.Format, writeChild},
+	} {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if err := file.write(entry); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+// formatAmount formats an amount as a decimal string
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// formatOptionalAmount formats an optional amount, leaving missing values empty
+func formatOptionalAmount(amount *float64) string {
+	if amount == nil {
+		return ""
+	}
+	return formatAmount(*amount)
+}
+
+// formatRate formats an optional percentage without rounding it to cents
+func formatRate(rate *float64) string {
+	if rate == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*rate, 'f', -1, 64)
+}
+
+// formatDate formats a calendar date in ISO 8601
+func formatDate(date time.Time) string {
+	return date.Format("2006-01-02")
+}
+
+// formatOptionalDate formats an optional calendar date in ISO 8601
+func formatOptionalDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return formatDate(*date)
+}
+
+// formatOptional returns the string value or empty
+func formatOptional(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(*value)
+}
@@ -0,0 +1,263 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/money"
+)
+
+// Rate bases recorded in conversion metadata
+const (
+	BasisPeriodEnd = "period_end"
+	BasisCurrent   = "current"
+)
+
+// DisplayCurrencyParam is the query parameter selecting the summary currency
+const DisplayCurrencyParam = "display_currency"
+
+// RateSource provides exchange rates
+type RateSource interface {
+	// Rate returns how many units of to one unit of from buys on the given day
+	Rate(ctx context.Context, from, to string, on time.Time) (float64, error)
+}
+
+// AppliedRate records an exchange rate used for a conversion
+type AppliedRate struct {
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Rate  float64   `json:"rate"`
+	Date  time.Time `json:"date"`
+	Basis string    `json:"basis"`
+}
+
+// Conversion is the response metadata describing a summary conversion
+type Conversion struct {
+	BaseCurrency    string        `json:"base_currency"`
+	DisplayCurrency string        `json:"display_currency"`
+	Converted       bool          `json:"converted"`
+	Rates           []AppliedRate `json:"rates,omitempty"`
+	Warning         string        `json:"warning,omitempty"`
+}
+
+// ParseDisplayCurrency validates the display_currency parameter. An empty
+// value means the summary is returned in the base currency.
+func ParseDisplayCurrency(raw, base string) (string, error) {
+	code := strings.TrimSpace(raw)
+	if code == "" {
+		return base, nil
+	}
+	if err := money.ValidateCurrency(code); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", DisplayCurrencyParam, err)
+	}
+	return code, nil
+}
+
+// CacheKey scopes a summary cache key to the display currency so converted
+// and unconverted summaries are never served for each other
+func CacheKey(key, displayCurrency string) string {
+	return key + ":" + displayCurrency
+}
+
+// Converter converts summaries into a display currency
+type Converter struct {
+	rates RateSource
+	now   func() time.Time
+}
+
+// NewConverter creates a new summary converter
+func NewConverter(rates RateSource) *Converter {
+	return &Converter{rates: rates, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// rateSet fetches and remembers the rates needed for one conversion
+type rateSet struct {
+	ctx        context.Context
+	source     RateSource
+	conversion *Conversion
+	seen       map[string]float64
+}
+
+// get returns the rate for day, recording it in the metadata once
+func (s *rateSet) get(day time.Time, basis string) (float64, error) {
+	day = truncateDay(day)
+	key := day.Format("2006-01-02") + "/" + basis
+	if rate, ok := s.seen[key]; ok {
+		return rate, nil
+	}
+	rate, err := s.source.Rate(s.ctx, s.conversion.BaseCurrency, s.conversion.DisplayCurrency, day)
+	if err != nil {
+		return 0, fmt.Errorf("no %s to %s rate for %s: %w", s.conversion.BaseCurrency, s.conversion.DisplayCurrency, day.Format("2006-01-02"), err)
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("invalid %s to %s rate %v for %s", s.conversion.BaseCurrency, s.conversion.DisplayCurrency, rate, day.Format("2006-01-02"))
+	}
+	s.seen[key] = rate
+	s.conversion.Rates = append(s.conversion.Rates, AppliedRate{
+		From:  s.conversion.BaseCurrency,
+		To:    s.conversion.DisplayCurrency,
+		Rate:  rate,
+		Date:  day,
+		Basis: basis,
+	})
+	return rate, nil
+}
+
+// begin starts a conversion; it returns nil rates when no conversion is needed
+func (c *Converter) begin(ctx context.Context, base, display string) (*Conversion, *rateSet) {
+	conversion := &Conversion{BaseCurrency: base, DisplayCurrency: display}
+	if base == display {
+		return conversion, nil
+	}
+	return conversion, &rateSet{ctx: ctx, source: c.rates, conversion: conversion, seen: make(map[string]float64)}
+}
+
+// fail leaves the summary unconverted and reports why in the metadata
+func fail(conversion *Conversion, err error) *Conversion {
+	conversion.DisplayCurrency = conversion.BaseCurrency
+	conversion.Rates = nil
+	conversion.Warning = fmt.Sprintf("amounts are shown in %s: %v", conversion.BaseCurrency, err)
+	return conversion
+}
+
+// ExpenseSummary converts an expense summary for a period ending at
+// periodEnd. Monthly amounts use each month's closing rate and totals use
+// the rate at the end of the period. On failure the summary is left
+// unconverted and the returned metadata carries a warning.
+func (c *Converter) ExpenseSummary(ctx context.Context, summary *models.ExpenseSummary, base, display string, periodEnd time.Time) *Conversion {
+	conversion, rates := c.begin(ctx, base, display)
+	if rates == nil {
+		return conversion
+	}
+
+	now := c.now()
+	if periodEnd.IsZero() || periodEnd.After(now) {
+		periodEnd = now
+	}
+
+	// Fetch every rate before touching the summary so a failure leaves it intact
+	periodRate, err := rates.get(periodEnd, BasisPeriodEnd)
+	if err != nil {
+		return fail(conversion, err)
+	}
+	monthRates := make([]float64, len(summary.ByMonth))
+	for i, month := range summary.ByMonth {
+		monthEnd := time.Date(month.Year, time.Month(month.Month)+1, 0, 0, 0, 0, 0, time.UTC)
+		if monthEnd.After(periodEnd) {
+			monthEnd = periodEnd
+		}
+		if monthRates[i], err = rates.get(monthEnd, BasisPeriodEnd); err != nil {
+			return fail(conversion, err)
+		}
+	}
+
+	summary.TotalAmount = finance.RoundCents(summary.TotalAmount * periodRate)
+	summary.TotalAmountMoney = summary.TotalAmountMoney.MulRate(periodRate)
+	summary.AverageAmount = finance.RoundCents(summary.AverageAmount * periodRate)
+	for i := range summary.ByCategory {
+		summary.ByCategory[i].Amount = finance.RoundCents(summary.ByCategory[i].Amount * periodRate)
+	}
+	for i := range summary.ByPaymentMethod {
+		summary.ByPaymentMethod[i].Amount = finance.RoundCents(summary.ByPaymentMethod[i].Amount * periodRate)
+	}
+	for i := range summary.ByMonth {
+		summary.ByMonth[i].Amount = finance.RoundCents(summary.ByMonth[i].Amount * monthRates[i])
+	}
+
+	conversion.Converted = true
+	return conversion
+}
+
+// InvestmentSummary converts an investment summary at the current rate
+func (c *Converter) InvestmentSummary(ctx context.Context, summary *models.InvestmentSummary, base, display string) *Conversion {
+	conversion, rates := c.begin(ctx, base, display)
+	if rates == nil {
+		return conversion
+	}
+	rate, err := rates.get(c.now(), BasisCurrent)
+	if err != nil {
+		return fail(conversion, err)
+	}
+
+	convert := func(amount float64) float64 { return finance.RoundCents(amount * rate) }
+	summary.TotalInvested = convert(summary.TotalInvested)
+	summary.TotalCurrentValue = convert(summary.TotalCurrentValue)
+	summary.TotalGain = convert(summary.TotalGain)
+	summary.TotalRealizedGain = convert(summary.TotalRealizedGain)
+	summary.TotalInvestedMoney = summary.TotalInvestedMoney.MulRate(rate)
+	summary.TotalCurrentValueMoney = summary.TotalCurrentValueMoney.MulRate(rate)
+	summary.TotalGainMoney = summary.TotalCurrentValueMoney.Sub(summary.TotalInvestedMoney)
+	summary.TotalRealizedGainMoney = summary.TotalRealizedGainMoney.MulRate(rate)
+	for i := range summary.ByType {
+		entry := &summary.ByType[i]
+		entry.InvestedAmount, entry.CurrentValue, entry.Gain = convert(entry.InvestedAmount), convert(entry.CurrentValue), convert(entry.Gain)
+	}
+	for i := range summary.ByStatus {
+		entry := &summary.ByStatus[i]
+		entry.InvestedAmount, entry.CurrentValue, entry.Gain = convert(entry.InvestedAmount), convert(entry.CurrentValue), convert(entry.Gain)
+	}
+	for i := range summary.ByInstitution {
+		entry := &summary.ByInstitution[i]
+		entry.InvestedAmount, entry.CurrentValue, entry.Gain = convert(entry.InvestedAmount), convert(entry.CurrentValue), convert(entry.Gain)
+	}
+
+	conversion.Converted = true
+	return conversion
+}
+
+// GoalSummary converts a goal summary at the current rate. Progress
+// percentages are unaffected by conversion.
+func (c *Converter) GoalSummary(ctx context.Context, summary *models.GoalSummary, base, display string) *Conversion {
+	conversion, rates := c.begin(ctx, base, display)
+	if rates == nil {
+		return conversion
+	}
+	rate, err := rates.get(c.now(), BasisCurrent)
+	if err != nil {
+		return fail(conversion, err)
+	}
+
+	convert := func(amount float64) float64 { return finance.RoundCents(amount * rate) }
+	summary.TotalTargetAmount = convert(summary.TotalTargetAmount)
+	summary.TotalCurrentAmount = convert(summary.TotalCurrentAmount)
+	for i := range summary.ByType {
+		entry := &summary.ByType[i]
+		entry.TargetAmount, entry.CurrentAmount = convert(entry.TargetAmount), convert(entry.CurrentAmount)
+	}
+	for i := range summary.ByPriority {
+		entry := &summary.ByPriority[i]
+		entry.TargetAmount, entry.CurrentAmount = convert(entry.TargetAmount), convert(entry.CurrentAmount)
+	}
+	for i := range summary.ByStatus {
+		entry := &summary.ByStatus[i]
+		entry.TargetAmount, entry.CurrentAmount = convert(entry.TargetAmount), convert(entry.CurrentAmount)
+	}
+
+	conversion.Converted = true
+	return conversion
+}
+
+// Amount converts a single current value such as a net worth figure at the
+// current rate. On failure the amount is returned unconverted.
+func (c *Converter) Amount(ctx context.Context, amount float64, base, display string) (float64, *Conversion) {
+	conversion, rates := c.begin(ctx, base, display)
+	if rates == nil {
+		return amount, conversion
+	}
+	rate, err := rates.get(c.now(), BasisCurrent)
+	if err != nil {
+		return amount, fail(conversion, err)
+	}
+	conversion.Converted = true
+	return finance.RoundCents(amount * rate), conversion
+}
+
+// truncateDay returns the UTC calendar day containing t
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,136 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tgfinance/internal/models"
+)
+
+type fakeRates struct {
+	byDay map[string]float64
+	calls int
+}
+
+func (f *fakeRates) Rate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	f.calls++
+	rate, ok := f.byDay[on.Format("2006-01-02")]
+	if !ok {
+		return 0, errors.New("rate unavailable")
+	}
+	return rate, nil
+}
+
+func newTestConverter(rates map[string]float64) (*Converter, *fakeRates) {
+	source := &fakeRates{byDay: rates}
+	c := NewConverter(source)
+	c.now = func() time.Time { return time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC) }
+	return c, source
+}
+
+func testExpenseSummary() models.ExpenseSummary {
+	return models.ExpenseSummary{
+		TotalAmount:   300,
+		TotalCount:    3,
+		AverageAmount: 100,
+		ByCategory:    []models.CategoryExpenseSummary{{CategoryName: "Food", Amount: 300, Count: 3, Percentage: 100}},
+		ByMonth: []models.MonthlyExpenseSummary{
+			{Year: 2024, Month: 1, Amount: 100, Count: 1},
+			{Year: 2024, Month: 2, Amount: 200, Count: 2},
+		},
+		ByPaymentMethod: []models.PaymentMethodSummary{{PaymentMethod: "card", Amount: 300, Count: 3, Percentage: 100}},
+	}
+}
+
+func TestExpenseSummaryUsesPeriodEndRates(t *testing.T) {
+	c, _ := newTestConverter(map[string]float64{
+		"2024-01-31": 0.9,
+		"2024-02-20": 0.8,
+	})
+	summary := testExpenseSummary()
+	periodEnd := time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)
+
+	conversion := c.ExpenseSummary(context.Background(), &summary, "USD", "EUR", periodEnd)
+	if !conversion.Converted || conversion.Warning != "" {
+		t.Fatalf("Expected conversion to succeed, got %+v", conversion)
+	}
+	if summary.TotalAmount != 240 || summary.AverageAmount != 80 || summary.ByCategory[0].Amount != 240 {
+		t.Errorf("Expected totals at the period-end rate, got %+v", summary)
+	}
+	if summary.ByMonth[0].Amount != 90 || summary.ByMonth[1].Amount != 160 {
+		t.Errorf("Expected months at their closing rates, got %+v", summary.ByMonth)
+	}
+	if summary.ByCategory[0].Percentage != 100 {
+		t.Errorf("Percentages should not change, got %v", summary.ByCategory[0].Percentage)
+	}
+	if len(conversion.Rates) != 2 {
+		t.Errorf("Expected each distinct rate recorded once, got %+v", conversion.Rates)
+	}
+}
+
+func TestExpenseSummaryFailureLeavesSummaryUnconverted(t *testing.T) {
+	c, _ := newTestConverter(map[string]float64{"2024-02-20": 0.8})
+	summary := testExpenseSummary()
+
+	conversion := c.ExpenseSummary(context.Background(), &summary, "USD", "EUR", time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC))
+	if conversion.Converted || conversion.Warning == "" || conversion.DisplayCurrency != "USD" || len(conversion.Rates) != 0 {
+		t.Errorf("Expected an unconverted result with a warning, got %+v", conversion)
+	}
+	if summary.TotalAmount != 300 || summary.ByMonth[1].Amount != 200 {
+		t.Errorf("Summary should be untouched on failure, got %+v", summary)
+	}
+}
+
+func TestSameCurrencySkipsRates(t *testing.T) {
+	c, source := newTestConverter(nil)
+	summary := testExpenseSummary()
+
+	conversion := c.ExpenseSummary(context.Background(), &summary, "USD", "USD", time.Time{})
+	if conversion.Converted || conversion.Warning != "" || source.calls != 0 {
+		t.Errorf("Expected no conversion for the base currency, got %+v after %d calls", conversion, source.calls)
+	}
+}
+
+func TestInvestmentAndGoalSummariesUseCurrentRate(t *testing.T) {
+	c, _ := newTestConverter(map[string]float64{"2024-06-15": 2})
+
+	investments := models.InvestmentSummary{
+		TotalInvested:     100,
+		TotalCurrentValue: 150,
+		TotalGain:         50,
+		TotalGainPercent:  50,
+		ByInstitution:     []models.InstitutionSummary{{Institution: "Vanguard", InvestedAmount: 100, CurrentValue: 150, Gain: 50}},
+	}
+	conversion := c.InvestmentSummary(context.Background(), &investments, "USD", "INR")
+	if !conversion.Converted || conversion.Rates[0].Basis != BasisCurrent {
+		t.Fatalf("Expected a current-rate conversion, got %+v", conversion)
+	}
+	if investments.TotalCurrentValue != 300 || investments.TotalGainPercent != 50 || investments.ByInstitution[0].Gain != 100 {
+		t.Errorf("Unexpected converted investments %+v", investments)
+	}
+
+	goals := models.GoalSummary{TotalTargetAmount: 1000, TotalCurrentAmount: 250, TotalProgress: 25}
+	c.GoalSummary(context.Background(), &goals, "USD", "INR")
+	if goals.TotalTargetAmount != 2000 || goals.TotalCurrentAmount != 500 || goals.TotalProgress != 25 {
+		t.Errorf("Unexpected converted goals %+v", goals)
+	}
+}
+
+func TestParseDisplayCurrency(t *testing.T) {
+	if code, err := ParseDisplayCurrency("", "USD"); err != nil || code != "USD" {
+		t.Errorf("Expected base currency by default, got %q, %v", code, err)
+	}
+	if code, err := ParseDisplayCurrency("EUR", "USD"); err != nil || code != "EUR" {
+		t.Errorf("Expected EUR, got %q, %v", code, err)
+	}
+	for _, raw := range []string{"eur", "EURO", "XYZ"} {
+		if _, err := ParseDisplayCurrency(raw, "USD"); err == nil {
+			t.Errorf("Expected %q to be rejected", raw)
+		}
+	}
+	if CacheKey("summary:u1", "EUR") == CacheKey("summary:u1", "USD") {
+		t.Error("Cache keys must differ by display currency")
+	}
+}
@@ -0,0 +1,93 @@
+package household
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the household sharing review endpoints
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new household sharing handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Review handles GET /api/v1/households/{id}/sharing
+func (h *Handler) Review(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	householdID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid household ID")
+		return
+	}
+
+	review, err := h.service.Review(r.Context(), householdID, userID)
+	switch {
+	case errors.Is(err, ErrNotMember):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Household not found")
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to load sharing review")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, review)
+	}
+}
+
+// Update handles PUT /api/v1/households/{id}/sharing
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.service.SetLevel)
+}
+
+// Tighten handles POST /api/v1/households/{id}/sharing/tighten
+func (h *Handler) Tighten(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.service.Tighten)
+}
+
+// change decodes a sharing update for the authenticated member and applies it
+func (h *Handler) change(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, householdID, userID uuid.UUID, req *models.SharingUpdateRequest) (*models.HouseholdSharing, error)) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	householdID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid household ID")
+		return
+	}
+	var req models.SharingUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	sharing, err := apply(r.Context(), householdID, userID, &req)
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrNotMember):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Household not found")
+	case errors.Is(err, ErrNotTighter):
+		httputil.WriteErrorFrom(w, http.StatusConflict, httputil.CodeForStatus(http.StatusConflict), err)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to update sharing")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, sharing)
+	}
+}
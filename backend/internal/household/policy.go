@@ -0,0 +1,122 @@
+package household
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"tgfinance/internal/models"
+)
+
+// Household-scope reads. Every access except AccessSummary returns individual
+// records and only sees owners sharing in full; summaries also aggregate the
+// records of owners sharing totals only.
+const (
+	AccessList     = "list"
+	AccessDetail   = "detail"
+	AccessSearch   = "search"
+	AccessExport   = "export"
+	AccessActivity = "activity"
+	AccessSummary  = "summary"
+)
+
+type sharingKey struct {
+	userID     uuid.UUID
+	entityType string
+}
+
+// Policy answers which members' records a household member may read. It is a
+// snapshot of the household's memberships and sharing levels.
+type Policy struct {
+	householdID uuid.UUID
+	members     []uuid.UUID
+	levels      map[sharingKey]string
+}
+
+// NewPolicy builds the policy of a household from its members and their
+// sharing levels. Members without a sharing row for an entity type share
+// nothing of it.
+func NewPolicy(householdID uuid.UUID, members []models.HouseholdMembership, sharing []models.HouseholdSharing) *Policy {
+	p := &Policy{
+		householdID: householdID,
+		members:     make([]uuid.UUID, 0, len(members)),
+		levels:      make(map[sharingKey]string, len(sharing)),
+	}
+	for _, m := range members {
+		p.members = append(p.members, m.UserID)
+	}
+	for _, s := range sharing {
+		p.levels[sharingKey{userID: s.UserID, entityType: s.EntityType}] = s.Level
+	}
+	return p
+}
+
+// IsMember returns true if the user belongs to the household
+func (p *Policy) IsMember(userID uuid.UUID) bool {
+	for _, m := range p.members {
+		if m == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Level returns what the owner shares of an entity type
+func (p *Policy) Level(ownerID uuid.UUID, entityType string) string {
+	if level, ok := p.levels[sharingKey{userID: ownerID, entityType: entityType}]; ok {
+		return level
+	}
+	return models.SharingNone
+}
+
+// Visible returns true if the viewer may read the owner's records of an
+// entity type through access. Members always see their own records.
+func (p *Policy) Visible(viewerID, ownerID uuid.UUID, entityType, access string) bool {
+	if !p.IsMember(viewerID) || !p.IsMember(ownerID) {
+		return false
+	}
+	if viewerID == ownerID {
+		return true
+	}
+	switch p.Level(ownerID, entityType) {
+	case models.SharingFull:
+		return true
+	case models.SharingTotalsOnly:
+		return access == AccessSummary
+	default:
+		return false
+	}
+}
+
+// Owners returns the members whose records of an entity type the viewer may
+// read through access, for the household-scope queries. It is empty when the
+// viewer is not a member.
+func (p *Policy) Owners(viewerID uuid.UUID, entityType, access string) []uuid.UUID {
+	owners := []uuid.UUID{}
+	for _, m := range p.members {
+		if p.Visible(viewerID, m, entityType, access) {
+			owners = append(owners, m)
+		}
+	}
+	return owners
+}
+
+// SharedWith returns the other members who can see the owner's records of an
+// entity type in any form
+func (p *Policy) SharedWith(ownerID uuid.UUID, entityType string) []uuid.UUID {
+	viewers := []uuid.UUID{}
+	for _, m := range p.members {
+		if m != ownerID && p.Visible(m, ownerID, entityType, AccessSummary) {
+			viewers = append(viewers, m)
+		}
+	}
+	return viewers
+}
+
+// ScopeCondition returns the WHERE condition restricting a household-scope
+// query to owners, and its argument for placeholder $n. An empty owner list
+// matches no rows.
+func ScopeCondition(column string, n int, owners []uuid.UUID) (string, interface{}) {
+	return fmt.Sprintf("%s = ANY($%d)", column, n), pq.Array(owners)
+}
@@ -0,0 +1,227 @@
+package household
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+// DefaultPolicyCacheTTL bounds how long a household policy is reused. Changes
+// made through the service invalidate it at once; the TTL only bounds how
+// long other instances serve a stale policy.
+const DefaultPolicyCacheTTL = time.Minute
+
+// Household sharing errors
+var (
+	ErrNotMember  = errors.New("user is not a member of the household")
+	ErrNotTighter = errors.New("level does not share less than the current level")
+)
+
+// Store persists household memberships and sharing levels
+type Store interface {
+	ListMembers(ctx context.Context, householdID uuid.UUID) ([]models.HouseholdMembership, error)
+	ListSharing(ctx context.Context, householdID uuid.UUID) ([]models.HouseholdSharing, error)
+	// UpsertSharing creates or replaces a member's level for an entity type
+	UpsertSharing(ctx context.Context, sharing *models.HouseholdSharing) error
+}
+
+// Auditor records sharing changes
+type Auditor interface {
+	RecordAudit(ctx context.Context, entry models.AuditLog) error
+}
+
+type cachedPolicy struct {
+	policy   *Policy
+	cachedAt time.Time
+}
+
+// Service manages household sharing levels and serves the policies enforced
+// by household-scope queries. Policies are cached briefly; every change
+// invalidates the household's policy so it applies to the next request.
+type Service struct {
+	store   Store
+	auditor Auditor
+	ttl     time.Duration
+	clock   clock.Clock
+
+	mu       sync.RWMutex
+	policies map[uuid.UUID]cachedPolicy
+}
+
+// NewService creates a new household sharing service
+func NewService(store Store, auditor Auditor, ttl time.Duration) *Service {
+	if ttl <= 0 {
+		ttl = DefaultPolicyCacheTTL
+	}
+	return &Service{
+		store:    store,
+		auditor:  auditor,
+		ttl:      ttl,
+		clock:    clock.Real(),
+		policies: make(map[uuid.UUID]cachedPolicy),
+	}
+}
+
+// Policy returns the sharing policy of a household
+func (s *Service) Policy(ctx context.Context, householdID uuid.UUID) (*Policy, error) {
+	now := s.clock.Now()
+
+	s.mu.RLock()
+	entry, ok := s.policies[householdID]
+	s.mu.RUnlock()
+	if ok && now.Sub(entry.cachedAt) < s.ttl {
+		return entry.policy, nil
+	}
+
+	members, err := s.store.ListMembers(ctx, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list household members: %w", err)
+	}
+	sharing, err := s.store.ListSharing(ctx, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list household sharing: %w", err)
+	}
+	policy := NewPolicy(householdID, members, sharing)
+
+	s.mu.Lock()
+	s.policies[householdID] = cachedPolicy{policy: policy, cachedAt: now}
+	s.mu.Unlock()
+	return policy, nil
+}
+
+// Invalidate drops the cached policy of a household
+func (s *Service) Invalidate(householdID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.policies, householdID)
+}
+
+// Review returns what the member shares of each entity type and with whom
+func (s *Service) Review(ctx context.Context, householdID, userID uuid.UUID) (*models.SharingReview, error) {
+	policy, err := s.Policy(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.IsMember(userID) {
+		return nil, ErrNotMember
+	}
+
+	review := &models.SharingReview{HouseholdID: householdID, UserID: userID}
+	for _, entityType := range models.SharedEntityTypes.Strings() {
+		level := policy.Level(userID, entityType)
+		entry := models.SharingReviewEntry{
+			EntityType: entityType,
+			Level:      level,
+			Summary:    reviewSummary(level),
+			SharedWith: policy.SharedWith(userID, entityType),
+			TightenTo:  []string{},
+		}
+		for _, stricter := range models.SharingLevels.Strings() {
+			if models.SharingRank(stricter) < models.SharingRank(level) {
+				entry.TightenTo = append(entry.TightenTo, stricter)
+			}
+		}
+		review.Entries = append(review.Entries, entry)
+	}
+	return review, nil
+}
+
+// SetLevel changes what the member shares of an entity type
+func (s *Service) SetLevel(ctx context.Context, householdID, userID uuid.UUID, req *models.SharingUpdateRequest) (*models.HouseholdSharing, error) {
+	return s.change(ctx, householdID, userID, req, false)
+}
+
+// Tighten lowers what the member shares of an entity type. Levels that would
+// share as much or more are rejected with ErrNotTighter.
+func (s *Service) Tighten(ctx context.Context, householdID, userID uuid.UUID, req *models.SharingUpdateRequest) (*models.HouseholdSharing, error) {
+	return s.change(ctx, householdID, userID, req, true)
+}
+
+// change stores a new sharing level, invalidates the household policy and
+// records the change in the audit log
+func (s *Service) change(ctx context.Context, householdID, userID uuid.UUID, req *models.SharingUpdateRequest, tighten bool) (*models.HouseholdSharing, error) {
+	if errs := ValidateSharingUpdate(req); errs.HasErrors() {
+		return nil, errs
+	}
+
+	// Read the current level from the store, not the cache, so a tightening
+	// is judged against what is actually shared
+	s.Invalidate(householdID)
+	policy, err := s.Policy(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.IsMember(userID) {
+		return nil, ErrNotMember
+	}
+	previous := policy.Level(userID, req.EntityType)
+	if tighten && models.SharingRank(req.Level) >= models.SharingRank(previous) {
+		return nil, ErrNotTighter
+	}
+
+	sharing := &models.HouseholdSharing{
+		HouseholdID: householdID,
+		UserID:      userID,
+		EntityType:  req.EntityType,
+		Level:       req.Level,
+		UpdatedAt:   s.clock.Now(),
+	}
+	if err := s.store.UpsertSharing(ctx, sharing); err != nil {
+		return nil, fmt.Errorf("failed to update sharing: %w", err)
+	}
+	s.Invalidate(householdID)
+
+	if previous == req.Level {
+		return sharing, nil
+	}
+	oldValue, _ := json.Marshal(map[string]string{"entity_type": req.EntityType, "level": previous})
+	newValue, _ := json.Marshal(map[string]string{"entity_type": req.EntityType, "level": req.Level})
+	entry := models.AuditLog{
+		ID:          uuid.New(),
+		UserID:      &userID,
+		ActorUserID: &userID,
+		Action:      models.AuditActionSharingChange,
+		EntityType:  "household",
+		EntityID:    &householdID,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		CreatedAt:   sharing.UpdatedAt,
+	}
+	if err := s.auditor.RecordAudit(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record sharing change: %w", err)
+	}
+	return sharing, nil
+}
+
+// ValidateSharingUpdate validates a sharing level change
+func ValidateSharingUpdate(req *models.SharingUpdateRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+	if !models.SharedEntityTypes.Valid(req.EntityType) {
+		errs.Add("entity_type", models.SharedEntityTypes.Message("entity_type"))
+	}
+	if !models.SharingLevels.Valid(req.Level) {
+		errs.Add("level", models.SharingLevels.Message("level"))
+	}
+	return errs
+}
+
+// reviewSummary describes what other members see at a sharing level
+func reviewSummary(level string) string {
+	switch level {
+	case models.SharingFull:
+		return "Other members see every record, including descriptions"
+	case models.SharingTotalsOnly:
+		return "Other members see totals in household summaries but no individual records"
+	default:
+		return "Other members see nothing"
+	}
+}
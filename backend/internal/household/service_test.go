@@ -0,0 +1,232 @@
+package household
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+type memoryStore struct {
+	members []models.HouseholdMembership
+	sharing map[sharingKey]models.HouseholdSharing
+	reads   int
+}
+
+func newMemoryStore(householdID uuid.UUID, users ...uuid.UUID) *memoryStore {
+	store := &memoryStore{sharing: make(map[sharingKey]models.HouseholdSharing)}
+	for _, u := range users {
+		store.members = append(store.members, models.HouseholdMembership{ID: uuid.New(), HouseholdID: householdID, UserID: u})
+	}
+	return store
+}
+
+func (s *memoryStore) ListMembers(ctx context.Context, householdID uuid.UUID) ([]models.HouseholdMembership, error) {
+	s.reads++
+	return s.members, nil
+}
+
+func (s *memoryStore) ListSharing(ctx context.Context, householdID uuid.UUID) ([]models.HouseholdSharing, error) {
+	var sharing []models.HouseholdSharing
+	for _, row := range s.sharing {
+		sharing = append(sharing, row)
+	}
+	return sharing, nil
+}
+
+func (s *memoryStore) UpsertSharing(ctx context.Context, sharing *models.HouseholdSharing) error {
+	s.sharing[sharingKey{userID: sharing.UserID, entityType: sharing.EntityType}] = *sharing
+	return nil
+}
+
+type recordingAuditor struct{ entries []models.AuditLog }
+
+func (a *recordingAuditor) RecordAudit(ctx context.Context, entry models.AuditLog) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+// record is an expense as seen by the household-scope queries
+type record struct {
+	ownerID     uuid.UUID
+	description string
+	amount      float64
+}
+
+// query mimics a household-scope repository query: it keeps the records
+// whose owner the policy allows for the access
+func query(policy *Policy, viewerID uuid.UUID, access string, records []record) []record {
+	owners := make(map[uuid.UUID]bool)
+	for _, o := range policy.Owners(viewerID, models.SharedExpenses, access) {
+		owners[o] = true
+	}
+	var visible []record
+	for _, r := range records {
+		if owners[r.ownerID] {
+			visible = append(visible, r)
+		}
+	}
+	return visible
+}
+
+func TestTotalsOnlyRowsNeverListed(t *testing.T) {
+	householdID := uuid.New()
+	me, partner, sharer, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := newMemoryStore(householdID, me, partner, sharer)
+	service := NewService(store, &recordingAuditor{}, time.Minute)
+	ctx := context.Background()
+
+	for user, level := range map[uuid.UUID]string{me: models.SharingTotalsOnly, sharer: models.SharingFull} {
+		if _, err := service.SetLevel(ctx, householdID, user, &models.SharingUpdateRequest{EntityType: models.SharedExpenses, Level: level}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records := []record{
+		{ownerID: me, description: "Anniversary gift", amount: 120},
+		{ownerID: sharer, description: "Groceries", amount: 40},
+		{ownerID: partner, description: "Books", amount: 15},
+		{ownerID: outsider, description: "Rent", amount: 900},
+	}
+
+	policy, err := service.Policy(ctx, householdID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, access := range []string{AccessList, AccessDetail, AccessSearch, AccessExport, AccessActivity} {
+		for _, r := range query(policy, partner, access, records) {
+			if r.ownerID == me || r.ownerID == outsider {
+				t.Errorf("%s returned %q to another member", access, r.description)
+			}
+		}
+		if got := query(policy, me, access, records); len(got) != 2 {
+			t.Errorf("%s returned %d records to the owner, want own and fully shared", access, len(got))
+		}
+	}
+
+	total := 0.0
+	for _, r := range query(policy, partner, AccessSummary, records) {
+		total += r.amount
+	}
+	if total != 175 {
+		t.Errorf("summary total = %v, want totals-only records aggregated", total)
+	}
+	if got := query(policy, outsider, AccessList, records); len(got) != 0 {
+		t.Errorf("non-member saw %d records", len(got))
+	}
+}
+
+func TestTightenTakesEffectImmediately(t *testing.T) {
+	householdID := uuid.New()
+	me, partner := uuid.New(), uuid.New()
+	store := newMemoryStore(householdID, me, partner)
+	auditor := &recordingAuditor{}
+	service := NewService(store, auditor, time.Hour)
+	service.clock = clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	ctx := context.Background()
+
+	full := &models.SharingUpdateRequest{EntityType: models.SharedExpenses, Level: models.SharingFull}
+	if _, err := service.SetLevel(ctx, householdID, me, full); err != nil {
+		t.Fatal(err)
+	}
+	policy, _ := service.Policy(ctx, householdID)
+	if !policy.Visible(partner, me, models.SharedExpenses, AccessList) {
+		t.Fatal("fully shared expenses should be listed")
+	}
+
+	review, err := service.Review(ctx, householdID, me)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := review.Entries[0]
+	if entry.EntityType != models.SharedExpenses || entry.Level != models.SharingFull || len(entry.SharedWith) != 1 || len(entry.TightenTo) != 2 {
+		t.Errorf("Unexpected review entry %+v", entry)
+	}
+	for _, other := range review.Entries[1:] {
+		if other.Level != models.SharingNone || len(other.SharedWith) != 0 || len(other.TightenTo) != 0 {
+			t.Errorf("Unshared %s should share nothing, got %+v", other.EntityType, other)
+		}
+	}
+
+	totals := &models.SharingUpdateRequest{EntityType: models.SharedExpenses, Level: models.SharingTotalsOnly}
+	if _, err := service.Tighten(ctx, householdID, me, totals); err != nil {
+		t.Fatal(err)
+	}
+	policy, _ = service.Policy(ctx, householdID)
+	if policy.Visible(partner, me, models.SharedExpenses, AccessList) {
+		t.Error("tightened expenses should not be listed before the cache expires")
+	}
+	if !policy.Visible(partner, me, models.SharedExpenses, AccessSummary) {
+		t.Error("totals-only expenses should still be summarised")
+	}
+
+	if _, err := service.Tighten(ctx, householdID, me, full); !errors.Is(err, ErrNotTighter) {
+		t.Errorf("Expected ErrNotTighter when loosening, got %v", err)
+	}
+	if _, err := service.Tighten(ctx, householdID, me, totals); !errors.Is(err, ErrNotTighter) {
+		t.Errorf("Expected ErrNotTighter for the same level, got %v", err)
+	}
+
+	if len(auditor.entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(auditor.entries))
+	}
+	last := auditor.entries[1]
+	if last.Action != models.AuditActionSharingChange || *last.EntityID != householdID ||
+		string(last.OldValue) != `{"entity_type":"expenses","level":"full"}` ||
+		string(last.NewValue) != `{"entity_type":"expenses","level":"totals_only"}` {
+		t.Errorf("Unexpected audit entry %+v", last)
+	}
+}
+
+func TestSharingChangeErrors(t *testing.T) {
+	householdID := uuid.New()
+	store := newMemoryStore(householdID, uuid.New())
+	service := NewService(store, &recordingAuditor{}, time.Minute)
+	ctx := context.Background()
+
+	_, err := service.SetLevel(ctx, householdID, uuid.New(), &models.SharingUpdateRequest{EntityType: models.SharedGoals, Level: models.SharingFull})
+	if !errors.Is(err, ErrNotMember) {
+		t.Errorf("Expected ErrNotMember, got %v", err)
+	}
+	if _, err := service.Review(ctx, householdID, uuid.New()); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Expected ErrNotMember from review, got %v", err)
+	}
+
+	_, err = service.SetLevel(ctx, householdID, store.members[0].UserID, &models.SharingUpdateRequest{EntityType: "budgets", Level: "partial"})
+	var errs utils.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("Expected 2 validation errors, got %v", err)
+	}
+}
+
+func TestPolicyCache(t *testing.T) {
+	householdID := uuid.New()
+	store := newMemoryStore(householdID, uuid.New())
+	service := NewService(store, &recordingAuditor{}, time.Minute)
+	fake := clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	service.clock = fake
+	ctx := context.Background()
+
+	service.Policy(ctx, householdID)
+	service.Policy(ctx, householdID)
+	if store.reads != 1 {
+		t.Errorf("Expected the cached policy to be reused, got %d reads", store.reads)
+	}
+	fake.Advance(2 * time.Minute)
+	service.Policy(ctx, householdID)
+	if store.reads != 2 {
+		t.Errorf("Expected an expired policy to be reloaded, got %d reads", store.reads)
+	}
+}
+
+func TestScopeCondition(t *testing.T) {
+	condition, arg := ScopeCondition("e.user_id", 3, []uuid.UUID{uuid.New()})
+	if condition != "e.user_id = ANY($3)" || arg == nil {
+		t.Errorf("Unexpected scope condition %q %v", condition, arg)
+	}
+}
@@ -0,0 +1,148 @@
+package institution
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+func seed() []models.Institution {
+	return []models.Institution{
+		{ID: uuid.New(), Name: "HDFC Bank", Aliases: []string{"HDFC", "HDFC Securities"}},
+		{ID: uuid.New(), Name: "ICICI Bank", Aliases: []string{"ICICI Direct"}},
+		{ID: uuid.New(), Name: "State Bank of India", Aliases: []string{"SBI"}},
+		{ID: uuid.New(), Name: "Charles Schwab", Aliases: []string{"Schwab"}},
+		{ID: uuid.New(), Name: "E*TRADE", Aliases: []string{"E Trade"}},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	matcher := NewMatcher(seed())
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"HDFC", "HDFC Bank"},
+		{"HDFC Bank", "HDFC Bank"},
+		{"hdfc bank ltd", "HDFC Bank"},
+		{"  H.D.F.C. Bank Limited ", "HDFC Bank"},
+		{"icici direct", "ICICI Bank"},
+		{"SBI", "State Bank of India"},
+		{"charles schwab corp", "Charles Schwab"},
+		{"Charles Schwabb", "Charles Schwab"},
+		{"etrade", "E*TRADE"},
+		{"My Local Credit Union", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			match := matcher.Match(tt.text)
+			got := ""
+			if match != nil {
+				got = match.Institution.Name
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	matcher := NewMatcher(seed())
+
+	results := matcher.Autocomplete("hd", 5)
+	if len(results) == 0 || results[0].Institution.Name != "HDFC Bank" {
+		t.Fatalf("Autocomplete(hd) = %+v, want HDFC Bank first", results)
+	}
+
+	// A partially typed generic word is kept so the query still narrows
+	results = matcher.Autocomplete("state ba", 5)
+	if len(results) == 0 || results[0].Institution.Name != "State Bank of India" {
+		t.Errorf("Autocomplete(state ba) = %+v", results)
+	}
+
+	if results := matcher.Autocomplete("  ", 5); len(results) != 0 {
+		t.Errorf("empty query returned %d results", len(results))
+	}
+	if results := matcher.Autocomplete("b", 1); len(results) > 1 {
+		t.Errorf("limit not applied: %d results", len(results))
+	}
+}
+
+type fakeStore struct {
+	institutions []models.Institution
+	unmapped     []RawInstitution
+	mapped       map[string]uuid.UUID
+}
+
+func (f *fakeStore) ListInstitutions(ctx context.Context) ([]models.Institution, error) {
+	return f.institutions, nil
+}
+
+func (f *fakeStore) ListUnmapped(ctx context.Context) ([]RawInstitution, error) {
+	return f.unmapped, nil
+}
+
+func (f *fakeStore) MapInstitution(ctx context.Context, text string, institutionID uuid.UUID) (int64, error) {
+	f.mapped[text] = institutionID
+	for _, raw := range f.unmapped {
+		if raw.Text == text {
+			return int64(raw.Investments), nil
+		}
+	}
+	return 0, nil
+}
+
+func TestBackfill(t *testing.T) {
+	store := &fakeStore{
+		institutions: seed(),
+		unmapped: []RawInstitution{
+			{Text: "hdfc bank ltd", Investments: 3},
+			{Text: "HDFC", Investments: 2},
+			{Text: "Grandma's mattress", Investments: 1},
+		},
+		mapped: make(map[string]uuid.UUID),
+	}
+
+	report, err := Backfill(context.Background(), store, true)
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+	if len(store.mapped) != 0 {
+		t.Error("dry run must not write mappings")
+	}
+	if len(report.Mapped) != 2 || len(report.Unmatched) != 1 || report.MappedInvestments != 5 {
+		t.Errorf("dry run report = %+v", report)
+	}
+
+	report, err = Backfill(context.Background(), store, false)
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+	hdfc := store.institutions[0].ID
+	if store.mapped["hdfc bank ltd"] != hdfc || store.mapped["HDFC"] != hdfc {
+		t.Errorf("mappings = %v, want both HDFC spellings on %s", store.mapped, hdfc)
+	}
+	if report.Mapped[0].Updated == 0 {
+		t.Errorf("applied report should count updated rows: %+v", report.Mapped)
+	}
+}
+
+func TestServiceResolve(t *testing.T) {
+	store := &fakeStore{institutions: seed()}
+	service := NewService(store)
+
+	id, err := service.Resolve(context.Background(), "Schwab")
+	if err != nil || id == nil || *id != store.institutions[3].ID {
+		t.Errorf("Resolve(Schwab) = %v, %v", id, err)
+	}
+	if id, _ := service.Resolve(context.Background(), "Unknown Broker"); id != nil {
+		t.Errorf("Resolve(Unknown Broker) = %v, want nil", id)
+	}
+}
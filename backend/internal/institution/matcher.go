@@ -0,0 +1,197 @@
+package institution
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"tgfinance/internal/models"
+)
+
+// DefaultLimit is the number of autocomplete results returned
+const DefaultLimit = 10
+
+// MatchThreshold is the lowest score at which free text is mapped to an institution
+const MatchThreshold = 0.6
+
+// suffixTokens are legal and generic words that do not distinguish institutions
+var suffixTokens = map[string]bool{
+	"bank": true, "ltd": true, "limited": true, "inc": true, "corp": true,
+	"corporation": true, "co": true, "plc": true, "llc": true, "the": true,
+	"group": true, "pvt": true, "private": true, "na": true,
+}
+
+// name is a normalized name or alias of an institution
+type name struct {
+	normalized string
+	trigrams   map[string]struct{}
+	index      int
+}
+
+// Matcher maps free-text institution names onto canonical institutions
+type Matcher struct {
+	institutions []models.Institution
+	names        []name
+}
+
+// NewMatcher builds a matcher over institutions and their aliases
+func NewMatcher(institutions []models.Institution) *Matcher {
+	m := &Matcher{institutions: institutions}
+	for i, institution := range institutions {
+		for _, raw := range append([]string{institution.Name}, institution.Aliases...) {
+			normalized := Normalize(raw)
+			if normalized == "" {
+				continue
+			}
+			m.names = append(m.names, name{normalized: normalized, trigrams: trigrams(normalized), index: i})
+		}
+	}
+	return m
+}
+
+// Match returns the canonical institution for free text, or nil when nothing
+// scores at least MatchThreshold. "HDFC", "HDFC Bank" and "hdfc bank ltd"
+// all normalize to the same name.
+func (m *Matcher) Match(text string) *models.InstitutionMatch {
+	matches := m.rank(Normalize(text), false)
+	if len(matches) == 0 || matches[0].Score < MatchThreshold {
+		return nil
+	}
+	return &matches[0]
+}
+
+// Autocomplete returns institutions whose names start with or resemble q,
+// prefix matches first
+func (m *Matcher) Autocomplete(q string, limit int) []models.InstitutionMatch {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	normalized := normalizeQuery(q)
+	if normalized == "" {
+		return []models.InstitutionMatch{}
+	}
+
+	matches := m.rank(normalized, true)
+	kept := matches[:0]
+	for _, match := range matches {
+		if match.Score >= minSimilarity {
+			kept = append(kept, match)
+		}
+	}
+	if len(kept) > limit {
+		kept = kept[:limit]
+	}
+	return kept
+}
+
+// minSimilarity is the lowest trigram similarity suggested by autocomplete
+const minSimilarity = 0.3
+
+// rank scores every institution by its best matching name
+func (m *Matcher) rank(normalized string, prefix bool) []models.InstitutionMatch {
+	if normalized == "" {
+		return nil
+	}
+	query := trigrams(normalized)
+
+	best := make(map[int]float64)
+	for _, n := range m.names {
+		score := jaccard(query, n.trigrams)
+		switch {
+		case n.normalized == normalized:
+			score = 1
+		case prefix && strings.HasPrefix(n.normalized, normalized):
+			score = max(score, 0.9)
+		}
+		if score > best[n.index] {
+			best[n.index] = score
+		}
+	}
+
+	matches := make([]models.InstitutionMatch, 0, len(best))
+	for i, score := range best {
+		if score > 0 {
+			matches = append(matches, models.InstitutionMatch{Institution: m.institutions[i], Score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Institution.Name < matches[j].Institution.Name
+	})
+	return matches
+}
+
+// Normalize lowercases an institution name, strips punctuation and drops
+// generic suffixes such as "bank" and "ltd". A name made only of generic
+// words is kept as is.
+func Normalize(text string) string {
+	tokens := tokenize(text)
+	kept := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !suffixTokens[token] {
+			kept = append(kept, token)
+		}
+	}
+	if len(kept) == 0 {
+		return strings.Join(tokens, " ")
+	}
+	return strings.Join(kept, " ")
+}
+
+// normalizeQuery normalizes autocomplete input without dropping a partially
+// typed last word
+func normalizeQuery(q string) string {
+	tokens := tokenize(q)
+	if len(tokens) == 0 {
+		return ""
+	}
+	last := tokens[len(tokens)-1]
+	normalized := Normalize(strings.Join(tokens[:len(tokens)-1], " "))
+	if len(tokens) == 1 || !suffixTokens[last] {
+		normalized = strings.TrimSpace(normalized + " " + last)
+	}
+	return normalized
+}
+
+// tokenize splits text into lowercase alphanumeric words. Symbols inside a
+// word, as in E*TRADE or J.P., are dropped rather than splitting it.
+func tokenize(text string) []string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == ',' || r == '/':
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// trigrams returns the padded character trigrams of each token
+func trigrams(normalized string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, token := range strings.Fields(normalized) {
+		runes := []rune("  " + token + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// jaccard returns the Jaccard similarity of two trigram sets
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for gram := range a {
+		if _, ok := b[gram]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,75 @@
+package institution
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"tgfinance/internal/models"
+)
+
+// PostgresStore implements Store on the institutions and investments tables
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a Postgres-backed institution store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ListInstitutions returns every canonical institution
+func (s *PostgresStore) ListInstitutions(ctx context.Context) ([]models.Institution, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, aliases, logo_url, created_at, updated_at FROM institutions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var institutions []models.Institution
+	for rows.Next() {
+		var institution models.Institution
+		if err := rows.Scan(&institution.ID, &institution.Name, pq.Array(&institution.Aliases),
+			&institution.LogoURL, &institution.CreatedAt, &institution.UpdatedAt); err != nil {
+			return nil, err
+		}
+		institutions = append(institutions, institution)
+	}
+	return institutions, rows.Err()
+}
+
+// ListUnmapped returns raw institution text of investments without an institution ID
+func (s *PostgresStore) ListUnmapped(ctx context.Context) ([]RawInstitution, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT institution, COUNT(*) FROM investments
+		WHERE institution_id IS NULL AND institution IS NOT NULL AND TRIM(institution) <> ''
+		GROUP BY institution`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var raws []RawInstitution
+	for rows.Next() {
+		var raw RawInstitution
+		if err := rows.Scan(&raw.Text, &raw.Investments); err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	}
+	return raws, rows.Err()
+}
+
+// MapInstitution sets the institution ID of unmapped investments with the given raw text
+func (s *PostgresStore) MapInstitution(ctx context.Context, text string, institutionID uuid.UUID) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE investments SET institution_id = $1 WHERE institution_id IS NULL AND institution = $2`,
+		institutionID, text)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,153 @@
+package institution
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// RawInstitution is a distinct free-text institution and how many
+// investments use it
+type RawInstitution struct {
+	Text        string `json:"text"`
+	Investments int    `json:"investments"`
+}
+
+// Store loads institutions and maps investments onto them
+type Store interface {
+	ListInstitutions(ctx context.Context) ([]models.Institution, error)
+	// ListUnmapped returns raw institution text of investments without an institution ID
+	ListUnmapped(ctx context.Context) ([]RawInstitution, error)
+	// MapInstitution sets the institution ID of unmapped investments with the
+	// given raw text, keeping the text, and returns how many were updated
+	MapInstitution(ctx context.Context, text string, institutionID uuid.UUID) (int64, error)
+}
+
+// Service answers autocomplete and mapping queries from a cached matcher
+type Service struct {
+	store Store
+
+	mu      sync.RWMutex
+	matcher *Matcher
+}
+
+// NewService creates an institution service
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// Autocomplete returns institutions for the create-investment form
+func (s *Service) Autocomplete(ctx context.Context, q string, limit int) ([]models.InstitutionMatch, error) {
+	matcher, err := s.loadMatcher(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matcher.Autocomplete(q, limit), nil
+}
+
+// Resolve returns the canonical institution ID for free text, or nil when
+// it does not match. Callers keep the original text on the investment.
+func (s *Service) Resolve(ctx context.Context, text string) (*uuid.UUID, error) {
+	matcher, err := s.loadMatcher(ctx)
+	if err != nil {
+		return nil, err
+	}
+	match := matcher.Match(text)
+	if match == nil {
+		return nil, nil
+	}
+	return &match.Institution.ID, nil
+}
+
+// Invalidate drops the cached matcher after institutions change
+func (s *Service) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matcher = nil
+}
+
+func (s *Service) loadMatcher(ctx context.Context) (*Matcher, error) {
+	s.mu.RLock()
+	matcher := s.matcher
+	s.mu.RUnlock()
+	if matcher != nil {
+		return matcher, nil
+	}
+
+	institutions, err := s.store.ListInstitutions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load institutions: %w", err)
+	}
+	matcher = NewMatcher(institutions)
+
+	s.mu.Lock()
+	s.matcher = matcher
+	s.mu.Unlock()
+	return matcher, nil
+}
+
+// BackfillMapping reports raw text mapped onto a canonical institution
+type BackfillMapping struct {
+	Text            string    `json:"text"`
+	InstitutionID   uuid.UUID `json:"institution_id"`
+	InstitutionName string    `json:"institution_name"`
+	Score           float64   `json:"score"`
+	Investments     int       `json:"investments"`
+	Updated         int64     `json:"updated"`
+}
+
+// BackfillReport reports the outcome of a backfill
+type BackfillReport struct {
+	DryRun            bool              `json:"dry_run"`
+	Mapped            []BackfillMapping `json:"mapped"`
+	Unmatched         []RawInstitution  `json:"unmatched"`
+	MappedInvestments int               `json:"mapped_investments"`
+}
+
+// Backfill maps existing unmapped investments onto canonical institutions.
+// A dry run reports the mappings without writing them.
+func Backfill(ctx context.Context, store Store, dryRun bool) (*BackfillReport, error) {
+	institutions, err := store.ListInstitutions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load institutions: %w", err)
+	}
+	matcher := NewMatcher(institutions)
+
+	raws, err := store.ListUnmapped(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unmapped investments: %w", err)
+	}
+	sort.Slice(raws, func(i, j int) bool { return raws[i].Text < raws[j].Text })
+
+	report := &BackfillReport{DryRun: dryRun, Mapped: []BackfillMapping{}, Unmatched: []RawInstitution{}}
+	for _, raw := range raws {
+		match := matcher.Match(raw.Text)
+		if match == nil {
+			report.Unmatched = append(report.Unmatched, raw)
+			continue
+		}
+
+		mapping := BackfillMapping{
+			Text:            raw.Text,
+			InstitutionID:   match.Institution.ID,
+			InstitutionName: match.Institution.Name,
+			Score:           match.Score,
+			Investments:     raw.Investments,
+		}
+		if !dryRun {
+			updated, err := store.MapInstitution(ctx, raw.Text, match.Institution.ID)
+			if err != nil {
+				return report, fmt.Errorf("failed to map %q: %w", raw.Text, err)
+			}
+			mapping.Updated = updated
+		}
+		report.Mapped = append(report.Mapped, mapping)
+		report.MappedInvestments += raw.Investments
+	}
+	return report, nil
+}
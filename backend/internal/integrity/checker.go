@@ -0,0 +1,172 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report scopes
+const (
+	ScopeUser   = "user"
+	ScopeGlobal = "global"
+)
+
+// Loader loads the data inspected by the checks
+type Loader interface {
+	UserIDs(ctx context.Context) ([]uuid.UUID, error)
+	LoadUser(ctx context.Context, userID uuid.UUID) (*UserData, error)
+}
+
+// AuditEntry records a change made by a repair
+type AuditEntry struct {
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	OldValue   float64   `json:"old_value"`
+	NewValue   float64   `json:"new_value"`
+	Reason     string    `json:"reason"`
+}
+
+// Repairer applies fixes for recomputable values. Implementations must apply
+// the update and write the audit entry in the same transaction.
+type Repairer interface {
+	SetGoalCurrentAmount(ctx context.Context, goalID uuid.UUID, amount float64, audit AuditEntry) error
+}
+
+// ReportStore persists integrity reports so drift can be tracked over time
+type ReportStore interface {
+	SaveReport(ctx context.Context, report *Report) error
+}
+
+// Options controls an integrity run
+type Options struct {
+	UserID *uuid.UUID
+	Checks []string
+	Repair bool
+}
+
+// Report is the outcome of an integrity run
+type Report struct {
+	ID            uuid.UUID      `json:"id"`
+	Scope         string         `json:"scope"`
+	UserID        *uuid.UUID     `json:"user_id,omitempty"`
+	Checks        []string       `json:"checks"`
+	Repair        bool           `json:"repair"`
+	UsersChecked  int            `json:"users_checked"`
+	Discrepancies []Discrepancy  `json:"discrepancies"`
+	BySeverity    map[string]int `json:"by_severity"`
+	Repaired      int            `json:"repaired"`
+	StartedAt     time.Time      `json:"started_at"`
+	FinishedAt    time.Time      `json:"finished_at"`
+}
+
+// Checker runs integrity checks and optionally repairs what it finds
+type Checker struct {
+	loader   Loader
+	repairer Repairer
+	store    ReportStore
+	now      func() time.Time
+}
+
+// NewChecker creates a new integrity checker
+func NewChecker(loader Loader, repairer Repairer, store ReportStore) *Checker {
+	return &Checker{loader: loader, repairer: repairer, store: store, now: time.Now}
+}
+
+// Run executes the selected checks for one user, or every user when no user
+// is given, and persists the resulting report. Repairs are only attempted
+// when opts.Repair is set.
+func (c *Checker) Run(ctx context.Context, opts Options) (*Report, error) {
+	checks, err := SelectChecks(opts.Checks)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Repair && c.repairer == nil {
+		return nil, fmt.Errorf("repair requested but no repairer is configured")
+	}
+
+	report := &Report{
+		ID:            uuid.New(),
+		Scope:         ScopeGlobal,
+		UserID:        opts.UserID,
+		Repair:        opts.Repair,
+		Discrepancies: []Discrepancy{},
+		BySeverity:    make(map[string]int),
+		StartedAt:     c.now(),
+	}
+	for _, check := range checks {
+		report.Checks = append(report.Checks, check.Name)
+	}
+
+	var userIDs []uuid.UUID
+	if opts.UserID != nil {
+		report.Scope = ScopeUser
+		userIDs = []uuid.UUID{*opts.UserID}
+	} else {
+		userIDs, err = c.loader.UserIDs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list users: %w", err)
+		}
+	}
+
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		data, err := c.loader.LoadUser(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load data for user %s: %w", userID, err)
+		}
+		report.UsersChecked++
+
+		for _, check := range checks {
+			for _, discrepancy := range check.Run(data) {
+				if opts.Repair && discrepancy.Repairable {
+					if err := c.repair(ctx, &discrepancy); err != nil {
+						return nil, err
+					}
+					if discrepancy.Repaired {
+						report.Repaired++
+					}
+				}
+				report.Discrepancies = append(report.Discrepancies, discrepancy)
+				report.BySeverity[discrepancy.Severity]++
+			}
+		}
+	}
+
+	report.FinishedAt = c.now()
+	if c.store != nil {
+		if err := c.store.SaveReport(ctx, report); err != nil {
+			return nil, fmt.Errorf("failed to save integrity report: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// repair fixes a repairable discrepancy in place
+func (c *Checker) repair(ctx context.Context, discrepancy *Discrepancy) error {
+	switch discrepancy.Check {
+	case CheckGoalContributions:
+		audit := AuditEntry{
+			Action:     "integrity_repair",
+			EntityType: discrepancy.EntityType,
+			EntityID:   *discrepancy.EntityID,
+			UserID:     discrepancy.UserID,
+			OldValue:   *discrepancy.Actual,
+			NewValue:   *discrepancy.Expected,
+			Reason:     "recalculated current amount from contributions",
+		}
+		if err := c.repairer.SetGoalCurrentAmount(ctx, audit.EntityID, audit.NewValue, audit); err != nil {
+			return fmt.Errorf("failed to repair goal %s: %w", audit.EntityID, err)
+		}
+		discrepancy.Repaired = true
+	}
+	return nil
+}
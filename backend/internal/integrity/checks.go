@@ -0,0 +1,281 @@
+package integrity
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/finance"
+)
+
+// Discrepancy severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Check names
+const (
+	CheckGoalContributions      = "goal_amount_vs_contributions"
+	CheckInvestmentTransactions = "investment_amount_vs_transactions"
+	CheckOrphanedContributions  = "orphaned_contributions"
+	CheckOrphanedTransactions   = "orphaned_transactions"
+	CheckSummarySnapshot        = "summary_snapshot_vs_live"
+	CheckNegativeBalances       = "negative_balances"
+)
+
+// tolerance is the largest difference treated as rounding noise
+const tolerance = 0.005
+
+// SummarySnapshot is a stored aggregate of a user's data
+type SummarySnapshot struct {
+	ExpenseTotal     float64   `json:"expense_total"`
+	InvestmentTotal  float64   `json:"investment_total"`
+	GoalSavingsTotal float64   `json:"goal_savings_total"`
+	ComputedAt       time.Time `json:"computed_at"`
+}
+
+// UserData holds the records of one user inspected by the checks
+type UserData struct {
+	UserID        uuid.UUID
+	Expenses      []models.Expense
+	Goals         []models.FinancialGoal
+	Contributions []models.GoalContribution
+	Investments   []models.Investment
+	Transactions  []models.InvestmentTransaction
+	Summary       *SummarySnapshot
+}
+
+// Discrepancy describes one integrity violation
+type Discrepancy struct {
+	Check      string     `json:"check"`
+	Severity   string     `json:"severity"`
+	UserID     uuid.UUID  `json:"user_id"`
+	EntityType string     `json:"entity_type"`
+	EntityID   *uuid.UUID `json:"entity_id,omitempty"`
+	Expected   *float64   `json:"expected,omitempty"`
+	Actual     *float64   `json:"actual,omitempty"`
+	Message    string     `json:"message"`
+	Repairable bool       `json:"repairable"`
+	Repaired   bool       `json:"repaired"`
+}
+
+// Check is a named integrity check
+type Check struct {
+	Name string
+	Run  func(data *UserData) []Discrepancy
+}
+
+// DefaultChecks returns every built-in check
+func DefaultChecks() []Check {
+	return []Check{
+		{Name: CheckGoalContributions, Run: checkGoalContributions},
+		{Name: CheckInvestmentTransactions, Run: checkInvestmentTransactions},
+		{Name: CheckOrphanedContributions, Run: checkOrphanedContributions},
+		{Name: CheckOrphanedTransactions, Run: checkOrphanedTransactions},
+		{Name: CheckSummarySnapshot, Run: checkSummarySnapshot},
+		{Name: CheckNegativeBalances, Run: checkNegativeBalances},
+	}
+}
+
+// SelectChecks returns the default checks with the given names, or all of them if names is empty
+func SelectChecks(names []string) ([]Check, error) {
+	checks := DefaultChecks()
+	if len(names) == 0 {
+		return checks, nil
+	}
+
+	byName := make(map[string]Check, len(checks))
+	for _, check := range checks {
+		byName[check.Name] = check
+	}
+
+	selected := make([]Check, 0, len(names))
+	for _, name := range names {
+		check, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown integrity check %q", name)
+		}
+		selected = append(selected, check)
+	}
+	return selected, nil
+}
+
+// ContributionTotals sums contributions per goal
+func ContributionTotals(contributions []models.GoalContribution) map[uuid.UUID]float64 {
+	totals := make(map[uuid.UUID]float64)
+	for _, contribution := range contributions {
+		totals[contribution.GoalID] += contribution.Amount
+	}
+	return totals
+}
+
+func checkGoalContributions(data *UserData) []Discrepancy {
+	totals := ContributionTotals(data.Contributions)
+
+	var found []Discrepancy
+	for _, goal := range data.Goals {
+		expected := finance.RoundCents(totals[goal.ID])
+		if differs(goal.CurrentAmount, expected) {
+			found = append(found, newDiscrepancy(data.UserID, CheckGoalContributions, SeverityCritical, "goal", goal.ID,
+				&expected, goal.CurrentAmount,
+				fmt.Sprintf("goal %q current amount %.2f does not match contributions %.2f", goal.Name, goal.CurrentAmount, expected), true))
+		}
+	}
+	return found
+}
+
+func checkInvestmentTransactions(data *UserData) []Discrepancy {
+	net := make(map[uuid.UUID]float64)
+	hasDeposits := make(map[uuid.UUID]bool)
+	for _, tx := range data.Transactions {
+		switch tx.TransactionType {
+		case models.TransactionTypeDeposit:
+			net[tx.InvestmentID] += tx.Amount
+			hasDeposits[tx.InvestmentID] = true
+		case models.TransactionTypeWithdrawal:
+			net[tx.InvestmentID] -= tx.Amount
+		}
+	}
+
+	var found []Discrepancy
+	for _, investment := range data.Investments {
+		// Investments opened without a deposit carry an initial amount the ledger cannot explain,
+		// and closed positions keep their invested amount after the closing withdrawal
+		if !hasDeposits[investment.ID] || investment.IsClosed() {
+			continue
+		}
+		expected := finance.RoundCents(net[investment.ID])
+		if differs(investment.Amount, expected) {
+			found = append(found, newDiscrepancy(data.UserID, CheckInvestmentTransactions, SeverityWarning, "investment", investment.ID,
+				&expected, investment.Amount,
+				fmt.Sprintf("investment %q amount %.2f does not match deposits less withdrawals %.2f", investment.Name, investment.Amount, expected), false))
+		}
+	}
+	return found
+}
+
+func checkOrphanedContributions(data *UserData) []Discrepancy {
+	goals := make(map[uuid.UUID]bool, len(data.Goals))
+	for _, goal := range data.Goals {
+		goals[goal.ID] = true
+	}
+
+	var found []Discrepancy
+	for _, contribution := range data.Contributions {
+		if !goals[contribution.GoalID] {
+			found = append(found, newDiscrepancy(data.UserID, CheckOrphanedContributions, SeverityWarning, "goal_contribution", contribution.ID,
+				nil, contribution.Amount,
+				fmt.Sprintf("contribution references missing goal %s", contribution.GoalID), false))
+		}
+	}
+	return found
+}
+
+func checkOrphanedTransactions(data *UserData) []Discrepancy {
+	investments := make(map[uuid.UUID]bool, len(data.Investments))
+	for _, investment := range data.Investments {
+		investments[investment.ID] = true
+	}
+
+	var found []Discrepancy
+	for _, tx := range data.Transactions {
+		if !investments[tx.InvestmentID] {
+			found = append(found, newDiscrepancy(data.UserID, CheckOrphanedTransactions, SeverityWarning, "investment_transaction", tx.ID,
+				nil, tx.Amount,
+				fmt.Sprintf("transaction references missing investment %s", tx.InvestmentID), false))
+		}
+	}
+	return found
+}
+
+func checkSummarySnapshot(data *UserData) []Discrepancy {
+	if data.Summary == nil {
+		return nil
+	}
+
+	live := LiveSummary(data)
+	compare := []struct {
+		name     string
+		snapshot float64
+		live     float64
+	}{
+		{"expense_total", data.Summary.ExpenseTotal, live.ExpenseTotal},
+		{"investment_total", data.Summary.InvestmentTotal, live.InvestmentTotal},
+		{"goal_savings_total", data.Summary.GoalSavingsTotal, live.GoalSavingsTotal},
+	}
+
+	var found []Discrepancy
+	for _, c := range compare {
+		if differs(c.snapshot, c.live) {
+			expected := c.live
+			actual := c.snapshot
+			found = append(found, Discrepancy{
+				Check:      CheckSummarySnapshot,
+				Severity:   SeverityInfo,
+				UserID:     data.UserID,
+				EntityType: "summary",
+				Expected:   &expected,
+				Actual:     &actual,
+				Message:    fmt.Sprintf("summary %s %.2f does not match live aggregate %.2f", c.name, c.snapshot, c.live),
+			})
+		}
+	}
+	return found
+}
+
+func checkNegativeBalances(data *UserData) []Discrepancy {
+	var found []Discrepancy
+	for _, goal := range data.Goals {
+		if goal.CurrentAmount < 0 {
+			found = append(found, newDiscrepancy(data.UserID, CheckNegativeBalances, SeverityCritical, "goal", goal.ID,
+				nil, goal.CurrentAmount, fmt.Sprintf("goal %q has a negative current amount", goal.Name), false))
+		}
+	}
+	for _, investment := range data.Investments {
+		if investment.Amount < 0 || investment.GetCurrentValue() < 0 {
+			found = append(found, newDiscrepancy(data.UserID, CheckNegativeBalances, SeverityCritical, "investment", investment.ID,
+				nil, investment.GetCurrentValue(), fmt.Sprintf("investment %q has a negative balance", investment.Name), false))
+		}
+	}
+	return found
+}
+
+// LiveSummary computes the aggregates a summary snapshot should hold
+func LiveSummary(data *UserData) SummarySnapshot {
+	summary := SummarySnapshot{
+		ExpenseTotal: models.SummarizeExpenses(data.Expenses, time.UTC).TotalAmount,
+	}
+	for _, investment := range data.Investments {
+		summary.InvestmentTotal += investment.GetCurrentValue()
+	}
+	for _, goal := range data.Goals {
+		summary.GoalSavingsTotal += goal.CurrentAmount
+	}
+	summary.InvestmentTotal = finance.RoundCents(summary.InvestmentTotal)
+	summary.GoalSavingsTotal = finance.RoundCents(summary.GoalSavingsTotal)
+	return summary
+}
+
+func newDiscrepancy(userID uuid.UUID, check, severity, entityType string, entityID uuid.UUID, expected *float64, actual float64, message string, repairable bool) Discrepancy {
+	id := entityID
+	return Discrepancy{
+		Check:      check,
+		Severity:   severity,
+		UserID:     userID,
+		EntityType: entityType,
+		EntityID:   &id,
+		Expected:   expected,
+		Actual:     &actual,
+		Message:    message,
+		Repairable: repairable,
+	}
+}
+
+func differs(a, b float64) bool {
+	return math.Abs(a-b) > tolerance
+}
@@ -0,0 +1,151 @@
+package integrity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+type fakeLoader struct {
+	users map[uuid.UUID]*UserData
+}
+
+func (l *fakeLoader) UserIDs(ctx context.Context) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(l.users))
+	for id := range l.users {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (l *fakeLoader) LoadUser(ctx context.Context, userID uuid.UUID) (*UserData, error) {
+	return l.users[userID], nil
+}
+
+type fakeRepairer struct {
+	audits []AuditEntry
+	goals  map[uuid.UUID]float64
+}
+
+func (r *fakeRepairer) SetGoalCurrentAmount(ctx context.Context, goalID uuid.UUID, amount float64, audit AuditEntry) error {
+	r.goals[goalID] = amount
+	r.audits = append(r.audits, audit)
+	return nil
+}
+
+type fakeStore struct {
+	reports []*Report
+}
+
+func (s *fakeStore) SaveReport(ctx context.Context, report *Report) error {
+	s.reports = append(s.reports, report)
+	return nil
+}
+
+func driftedUser() *UserData {
+	userID := uuid.New()
+	goal := models.FinancialGoal{ID: uuid.New(), UserID: userID, Name: "Holiday", CurrentAmount: 150}
+	investment := models.Investment{ID: uuid.New(), UserID: userID, Name: "Index fund", Amount: 900}
+
+	return &UserData{
+		UserID: userID,
+		Goals:  []models.FinancialGoal{goal},
+		Contributions: []models.GoalContribution{
+			{ID: uuid.New(), GoalID: goal.ID, Amount: 100},
+			{ID: uuid.New(), GoalID: goal.ID, Amount: 25},
+			{ID: uuid.New(), GoalID: uuid.New(), Amount: 10},
+		},
+		Investments: []models.Investment{investment},
+		Transactions: []models.InvestmentTransaction{
+			{ID: uuid.New(), InvestmentID: investment.ID, TransactionType: models.TransactionTypeDeposit, Amount: 1000},
+			{ID: uuid.New(), InvestmentID: investment.ID, TransactionType: models.TransactionTypeWithdrawal, Amount: 50},
+		},
+		Summary: &SummarySnapshot{InvestmentTotal: 900, GoalSavingsTotal: 100},
+	}
+}
+
+func TestChecks(t *testing.T) {
+	data := driftedUser()
+
+	tests := []struct {
+		check string
+		want  int
+	}{
+		{CheckGoalContributions, 1},
+		{CheckInvestmentTransactions, 1},
+		{CheckOrphanedContributions, 1},
+		{CheckOrphanedTransactions, 0},
+		{CheckSummarySnapshot, 1},
+		{CheckNegativeBalances, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.check, func(t *testing.T) {
+			checks, err := SelectChecks([]string{tt.check})
+			if err != nil {
+				t.Fatalf("SelectChecks() error = %v", err)
+			}
+			found := checks[0].Run(data)
+			if len(found) != tt.want {
+				t.Errorf("Expected %d discrepancies, got %d: %+v", tt.want, len(found), found)
+			}
+		})
+	}
+
+	if _, err := SelectChecks([]string{"unknown"}); err == nil {
+		t.Error("Expected an error for an unknown check")
+	}
+}
+
+func TestRunReportsWithoutRepair(t *testing.T) {
+	data := driftedUser()
+	repairer := &fakeRepairer{goals: make(map[uuid.UUID]float64)}
+	store := &fakeStore{}
+	checker := NewChecker(&fakeLoader{users: map[uuid.UUID]*UserData{data.UserID: data}}, repairer, store)
+
+	report, err := checker.Run(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Scope != ScopeGlobal || report.UsersChecked != 1 {
+		t.Errorf("Unexpected report scope: %+v", report)
+	}
+	if len(report.Discrepancies) != 4 || report.BySeverity[SeverityCritical] != 1 {
+		t.Errorf("Unexpected discrepancies: %+v", report.Discrepancies)
+	}
+	if report.Repaired != 0 || len(repairer.audits) != 0 {
+		t.Error("Nothing should be repaired without the repair flag")
+	}
+	if len(store.reports) != 1 {
+		t.Error("Report should be persisted")
+	}
+}
+
+func TestRunRepairsGoalAmounts(t *testing.T) {
+	data := driftedUser()
+	repairer := &fakeRepairer{goals: make(map[uuid.UUID]float64)}
+	checker := NewChecker(&fakeLoader{users: map[uuid.UUID]*UserData{data.UserID: data}}, repairer, &fakeStore{})
+
+	report, err := checker.Run(context.Background(), Options{UserID: &data.UserID, Repair: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Scope != ScopeUser || report.Repaired != 1 {
+		t.Errorf("Expected one repair in a user-scoped report, got %+v", report)
+	}
+
+	goalID := data.Goals[0].ID
+	if repairer.goals[goalID] != 125 {
+		t.Errorf("Expected goal amount recalculated to 125, got %v", repairer.goals[goalID])
+	}
+	if len(repairer.audits) != 1 || repairer.audits[0].OldValue != 150 || repairer.audits[0].NewValue != 125 {
+		t.Errorf("Unexpected audit entries: %+v", repairer.audits)
+	}
+
+	if _, err := NewChecker(&fakeLoader{}, nil, nil).Run(context.Background(), Options{Repair: true}); err == nil {
+		t.Error("Repair without a repairer should fail")
+	}
+}
@@ -0,0 +1,69 @@
+package investment
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the admin investment type profile endpoints
+type Handler struct {
+	profiles *ProfileService
+}
+
+// NewHandler creates a new investment type handler
+func NewHandler(profiles *ProfileService) *Handler {
+	return &Handler{profiles: profiles}
+}
+
+// GetProfile handles GET /api/v1/admin/investment-types/{id}/profile
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	typeID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid investment type ID")
+		return
+	}
+
+	profile, err := h.profiles.GetProfile(r.Context(), typeID)
+	switch {
+	case errors.Is(err, ErrTypeNotFound):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Investment type not found")
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to get validation profile")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"validation_profile": profile})
+	}
+}
+
+// UpdateProfile handles PUT /api/v1/admin/investment-types/{id}/profile. A
+// null body removes the profile.
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	typeID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid investment type ID")
+		return
+	}
+	var profile *utils.ValidationProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	investmentType, err := h.profiles.UpdateProfile(r.Context(), typeID, profile)
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrTypeNotFound):
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Investment type not found")
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to update validation profile")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, investmentType)
+	}
+}
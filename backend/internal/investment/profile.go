@@ -0,0 +1,109 @@
+package investment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+// ErrTypeNotFound is returned when an investment type does not exist
+var ErrTypeNotFound = errors.New("investment type not found")
+
+// TypeStore persists investment types
+type TypeStore interface {
+	GetType(ctx context.Context, id uuid.UUID) (*models.InvestmentType, error)
+	// UpdateProfile replaces the validation profile of a type; nil removes it
+	UpdateProfile(ctx context.Context, id uuid.UUID, profile *utils.ValidationProfile) error
+}
+
+// ProfileService manages the validation profiles of investment types
+type ProfileService struct {
+	store TypeStore
+}
+
+// NewProfileService creates a new profile service
+func NewProfileService(store TypeStore) *ProfileService {
+	return &ProfileService{store: store}
+}
+
+// GetProfile returns the validation profile of a type, nil if it has none
+func (s *ProfileService) GetProfile(ctx context.Context, typeID uuid.UUID) (*utils.ValidationProfile, error) {
+	investmentType, err := s.getType(ctx, typeID)
+	if err != nil {
+		return nil, err
+	}
+	return investmentType.ValidationProfile, nil
+}
+
+// UpdateProfile validates and stores the validation profile of a type.
+// Tightening a profile does not touch existing investments; the new rules
+// apply to them only as their fields change.
+func (s *ProfileService) UpdateProfile(ctx context.Context, typeID uuid.UUID, profile *utils.ValidationProfile) (*models.InvestmentType, error) {
+	investmentType, err := s.getType(ctx, typeID)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil {
+		if errs := profile.Validate(models.InvestmentProfileFields); errs.HasErrors() {
+			return nil, errs
+		}
+	}
+
+	if err := s.store.UpdateProfile(ctx, typeID, profile); err != nil {
+		return nil, fmt.Errorf("failed to update validation profile: %w", err)
+	}
+	investmentType.ValidationProfile = profile
+	return investmentType, nil
+}
+
+// getType loads a type, mapping a missing type to ErrTypeNotFound
+func (s *ProfileService) getType(ctx context.Context, typeID uuid.UUID) (*models.InvestmentType, error) {
+	investmentType, err := s.store.GetType(ctx, typeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investment type: %w", err)
+	}
+	if investmentType == nil {
+		return nil, ErrTypeNotFound
+	}
+	return investmentType, nil
+}
+
+// ValidateCreate checks a new investment against the profile of its type
+func ValidateCreate(investmentType *models.InvestmentType, req *models.InvestmentCreateRequest) utils.ValidationErrors {
+	if investmentType.ValidationProfile == nil {
+		return nil
+	}
+	investment := models.Investment{
+		Symbol:        req.Symbol,
+		CurrentValue:  req.CurrentValue,
+		EndDate:       req.EndDate,
+		InterestRate:  req.InterestRate,
+		ExpenseRatio:  req.ExpenseRatio,
+		Institution:   req.Institution,
+		AccountNumber: req.AccountNumber,
+		Notes:         req.Notes,
+	}
+	return investmentType.ValidationProfile.Evaluate(investment.ProfileValues(), nil)
+}
+
+// ValidateUpdate checks an update against the profile of the investment's
+// type. Only the fields the update changes are checked, so an investment
+// that predates a tightened profile can still update its other fields.
+func ValidateUpdate(investmentType *models.InvestmentType, existing *models.Investment, req *models.InvestmentUpdateRequest) utils.ValidationErrors {
+	if investmentType.ValidationProfile == nil {
+		return nil
+	}
+	changed := make(map[string]bool)
+	for column := range req.Changes() {
+		changed[column] = true
+	}
+
+	updated := *existing
+	req.Apply(&updated)
+	return investmentType.ValidationProfile.Evaluate(updated.ProfileValues(), changed)
+}
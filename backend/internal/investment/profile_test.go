@@ -0,0 +1,120 @@
+package investment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+type memoryTypeStore struct {
+	types map[uuid.UUID]*models.InvestmentType
+}
+
+func (s *memoryTypeStore) GetType(ctx context.Context, id uuid.UUID) (*models.InvestmentType, error) {
+	return s.types[id], nil
+}
+
+func (s *memoryTypeStore) UpdateProfile(ctx context.Context, id uuid.UUID, profile *utils.ValidationProfile) error {
+	s.types[id].ValidationProfile = profile
+	return nil
+}
+
+func fixedDepositType() *models.InvestmentType {
+	maxRate := 25.0
+	return &models.InvestmentType{
+		ID:   uuid.New(),
+		Name: "Bank Fixed Deposit",
+		ValidationProfile: &utils.ValidationProfile{
+			Required:    []string{"interest_rate", "end_date"},
+			Forbidden:   []string{"symbol"},
+			Constraints: map[string]utils.FieldConstraint{"interest_rate": {Max: &maxRate}},
+		},
+	}
+}
+
+func decodeUpdate(t *testing.T, body string) *models.InvestmentUpdateRequest {
+	t.Helper()
+	var req models.InvestmentUpdateRequest
+	if err := utils.DecodeJSON(strings.NewReader(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	return &req
+}
+
+func TestValidateCreate(t *testing.T) {
+	fd := fixedDepositType()
+	rate := 7.25
+	end := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	symbol := "HDFC"
+
+	valid := &models.InvestmentCreateRequest{TypeID: fd.ID, Name: "FD", Amount: 1000, InterestRate: &rate, EndDate: &end}
+	if errs := ValidateCreate(fd, valid); errs.HasErrors() {
+		t.Errorf("Expected valid fixed deposit, got %v", errs)
+	}
+
+	invalid := &models.InvestmentCreateRequest{TypeID: fd.ID, Name: "FD", Amount: 1000, Symbol: &symbol}
+	errs := ValidateCreate(fd, invalid)
+	if len(errs) != 3 {
+		t.Errorf("Expected missing rate, missing end date and forbidden symbol, got %v", errs)
+	}
+
+	if errs := ValidateCreate(&models.InvestmentType{}, invalid); errs.HasErrors() {
+		t.Errorf("Types without a profile should accept anything, got %v", errs)
+	}
+}
+
+func TestValidateUpdateOnlyChecksChangedFields(t *testing.T) {
+	fd := fixedDepositType()
+	// Created before the profile required an end date
+	rate := 7.0
+	legacy := &models.Investment{ID: uuid.New(), TypeID: fd.ID, Name: "Old FD", Amount: 1000, InterestRate: &rate}
+
+	if errs := ValidateUpdate(fd, legacy, decodeUpdate(t, `{"notes": "renewed"}`)); errs.HasErrors() {
+		t.Errorf("Updating untouched fields of a legacy investment should pass, got %v", errs)
+	}
+	if errs := ValidateUpdate(fd, legacy, decodeUpdate(t, `{"interest_rate": 30}`)); len(errs) != 1 || errs[0].Field != "interest_rate" {
+		t.Errorf("Expected the changed rate to be checked, got %v", errs)
+	}
+	if errs := ValidateUpdate(fd, legacy, decodeUpdate(t, `{"interest_rate": null}`)); len(errs) != 1 {
+		t.Errorf("Expected clearing a required field to fail, got %v", errs)
+	}
+	if errs := ValidateUpdate(fd, legacy, decodeUpdate(t, `{"symbol": "FD1"}`)); len(errs) != 1 || errs[0].Field != "symbol" {
+		t.Errorf("Expected setting a forbidden field to fail, got %v", errs)
+	}
+	if legacy.InterestRate == nil || legacy.Symbol != nil {
+		t.Error("Validation must not modify the existing investment")
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	fd := fixedDepositType()
+	store := &memoryTypeStore{types: map[uuid.UUID]*models.InvestmentType{fd.ID: fd}}
+	service := NewProfileService(store)
+	ctx := context.Background()
+
+	var errs utils.ValidationErrors
+	bad := &utils.ValidationProfile{Required: []string{"ticker"}}
+	if _, err := service.UpdateProfile(ctx, fd.ID, bad); !errors.As(err, &errs) {
+		t.Fatalf("Expected meta-validation to reject unknown fields, got %v", err)
+	}
+
+	tightened := &utils.ValidationProfile{Required: []string{"interest_rate", "end_date", "institution"}}
+	updated, err := service.UpdateProfile(ctx, fd.ID, tightened)
+	if err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+	if len(updated.ValidationProfile.Required) != 3 || store.types[fd.ID].ValidationProfile != tightened {
+		t.Errorf("Expected the profile to be stored, got %+v", updated.ValidationProfile)
+	}
+
+	if _, err := service.UpdateProfile(ctx, uuid.New(), tightened); !errors.Is(err, ErrTypeNotFound) {
+		t.Errorf("Expected ErrTypeNotFound, got %v", err)
+	}
+}
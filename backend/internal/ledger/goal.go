@@ -0,0 +1,75 @@
+package ledger
+
+import (
+	"time"
+
+	"tgfinance/internal/models"
+)
+
+// GoalEvents converts contributions and integrity repairs of a goal into
+// sorted ledger events. Negative contributions are withdrawals.
+func GoalEvents(contributions []models.GoalContribution, audits []models.AuditLog) []Event {
+	events := make([]Event, 0, len(contributions)+len(audits))
+
+	for i := range contributions {
+		c := &contributions[i]
+		event := Event{
+			At:          c.ContributionDate,
+			RecordedAt:  c.CreatedAt,
+			Kind:        KindContribution,
+			ActorID:     c.CreatedBy,
+			ReferenceID: c.ID,
+			Delta:       c.Amount,
+		}
+		if c.Source != nil {
+			event.Source = *c.Source
+		}
+		if c.Notes != nil {
+			event.Note = *c.Notes
+		}
+		switch {
+		case event.Source == models.ContributionSourceSync:
+			event.Kind = KindSync
+		case c.RuleID != nil:
+			event.Kind = KindAllocation
+		case c.Amount < 0:
+			event.Kind = KindWithdrawal
+		}
+		events = append(events, event)
+	}
+
+	for i := range audits {
+		audit := &audits[i]
+		if audit.Action != models.AuditActionIntegrityRepair {
+			continue
+		}
+		// A repair rewrites the stored amount to the sum of contributions, so
+		// it never moves the recomputed balance. The drifted amount it replaced
+		// is expected to differ and is not compared.
+		event := auditEvent(audit, KindRepair)
+		event.RecordedAfter = audit.NewAmount()
+		events = append(events, event)
+	}
+
+	SortEvents(events)
+	return events
+}
+
+// auditEvent builds an event for an audit log entry, dated when it was recorded
+func auditEvent(audit *models.AuditLog, kind string) Event {
+	event := Event{
+		At:          audit.CreatedAt.Truncate(24 * time.Hour),
+		RecordedAt:  audit.CreatedAt,
+		Kind:        kind,
+		Source:      audit.Action,
+		ActorID:     audit.ActorUserID,
+		ReferenceID: audit.ID,
+	}
+	if audit.ActorUserID == nil {
+		event.ActorID = audit.UserID
+	}
+	if audit.Reason != nil {
+		event.Note = *audit.Reason
+	}
+	return event
+}
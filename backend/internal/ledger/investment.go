@@ -0,0 +1,87 @@
+package ledger
+
+import (
+	"tgfinance/internal/models"
+)
+
+// KindOpening is principal recorded on the investment without a deposit
+const KindOpening = "opening"
+
+// InvestmentEvents converts the transactions and value updates of an
+// investment into sorted ledger events tracking its current value.
+// Principal not explained by deposits and withdrawals is an opening deposit
+// on the start date, as in PerformanceCashflows.
+func InvestmentEvents(investment *models.Investment, transactions []models.InvestmentTransaction, audits []models.AuditLog) []Event {
+	events := make([]Event, 0, len(transactions)+len(audits)+1)
+
+	opening := investment.Amount
+	for i := range transactions {
+		tx := &transactions[i]
+		if isClosing(investment, tx) {
+			continue
+		}
+		switch tx.TransactionType {
+		case models.TransactionTypeDeposit:
+			opening -= tx.Amount
+		case models.TransactionTypeWithdrawal:
+			opening += tx.Amount
+		}
+	}
+	if opening >= divergenceTolerance {
+		events = append(events, Event{
+			At:          investment.StartDate,
+			RecordedAt:  investment.CreatedAt,
+			Kind:        KindOpening,
+			ReferenceID: investment.ID,
+			Delta:       opening,
+		})
+	}
+
+	for i := range transactions {
+		tx := &transactions[i]
+		event := Event{
+			At:          tx.TransactionDate,
+			RecordedAt:  tx.CreatedAt,
+			Kind:        tx.TransactionType,
+			Source:      tx.TransactionType,
+			ActorID:     tx.CreatedBy,
+			ReferenceID: tx.ID,
+		}
+		if tx.Description != nil {
+			event.Note = *tx.Description
+		}
+		switch tx.TransactionType {
+		case models.TransactionTypeDeposit, models.TransactionTypeInterest, models.TransactionTypeDividend:
+			event.Delta = tx.Amount
+		case models.TransactionTypeWithdrawal, models.TransactionTypeFee:
+			event.Delta = -tx.Amount
+		}
+		// Closing realizes the position at any value, leaving nothing behind
+		if isClosing(investment, tx) {
+			zero := 0.0
+			event.Delta = 0
+			event.Reset = &zero
+		}
+		events = append(events, event)
+	}
+
+	for i := range audits {
+		audit := &audits[i]
+		if audit.Action != models.AuditActionValueUpdate {
+			continue
+		}
+		event := auditEvent(audit, KindValueUpdate)
+		event.RecordedBefore = audit.OldAmount()
+		event.Reset = audit.NewAmount()
+		event.RecordedAfter = audit.NewAmount()
+		events = append(events, event)
+	}
+
+	SortEvents(events)
+	return events
+}
+
+// isClosing reports whether tx is the withdrawal that closed the investment
+func isClosing(investment *models.Investment, tx *models.InvestmentTransaction) bool {
+	return investment.ClosingTransactionID != nil && *investment.ClosingTransactionID == tx.ID
+}
@@ -0,0 +1,165 @@
+package ledger
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+)
+
+// Entry kinds
+const (
+	KindContribution = "contribution"
+	KindWithdrawal   = "withdrawal"
+	KindAllocation   = "allocation"
+	KindSync         = "investment_sync"
+	KindRepair       = "integrity_repair"
+	KindDeposit      = "deposit"
+	KindInterest     = "interest"
+	KindDividend     = "dividend"
+	KindFee          = "fee"
+	KindValueUpdate  = "value_update"
+)
+
+// DefaultLimit and MaxLimit bound the page size
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// divergenceTolerance ignores sub-cent differences between balances
+const divergenceTolerance = 0.005
+
+// Event is one recorded mutation of a balance
+type Event struct {
+	At          time.Time
+	RecordedAt  time.Time
+	Kind        string
+	Source      string
+	ActorID     *uuid.UUID
+	ReferenceID uuid.UUID
+	Note        string
+
+	// Delta is added to the running balance
+	Delta float64
+	// Reset replaces the running balance, as a revaluation does
+	Reset *float64
+	// RecordedBefore and RecordedAfter are the stored balances around the
+	// event, when the mutation recorded them
+	RecordedBefore *float64
+	RecordedAfter  *float64
+}
+
+// Entry is one step of a ledger with the recomputed running balance
+type Entry struct {
+	Sequence        int        `json:"sequence"`
+	At              time.Time  `json:"at"`
+	Kind            string     `json:"kind"`
+	Source          string     `json:"source,omitempty"`
+	ActorID         *uuid.UUID `json:"actor_id,omitempty"`
+	ReferenceID     uuid.UUID  `json:"reference_id"`
+	Note            string     `json:"note,omitempty"`
+	Amount          float64    `json:"amount"`
+	Balance         float64    `json:"balance"`
+	RecordedBalance *float64   `json:"recorded_balance,omitempty"`
+	Diverges        bool       `json:"diverges"`
+}
+
+// Ledger is a page of entries with the totals of the full history
+type Ledger struct {
+	EntityType      string    `json:"entity_type"`
+	EntityID        uuid.UUID `json:"entity_id"`
+	Entries         []Entry   `json:"entries"`
+	Total           int       `json:"total"`
+	Offset          int       `json:"offset"`
+	Limit           int       `json:"limit"`
+	Balance         float64   `json:"balance"`
+	RecordedBalance float64   `json:"recorded_balance"`
+	Diverges        bool      `json:"diverges"`
+	DivergentSteps  int       `json:"divergent_steps"`
+}
+
+// NormalizePage clamps the requested offset and limit
+func NormalizePage(offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return offset, limit
+}
+
+// SortEvents orders events by effective date, then by when they were recorded
+func SortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].At.Equal(events[j].At) {
+			return events[i].At.Before(events[j].At)
+		}
+		return events[i].RecordedAt.Before(events[j].RecordedAt)
+	})
+}
+
+// Build replays sorted events from a zero balance and returns the requested
+// page. Every event is replayed so the running balance and divergence count
+// cover the full history, but only the page's entries are kept.
+func Build(events []Event, recorded float64, offset, limit int) Ledger {
+	offset, limit = NormalizePage(offset, limit)
+	ledger := Ledger{
+		Total:           len(events),
+		Offset:          offset,
+		Limit:           limit,
+		RecordedBalance: finance.RoundCents(recorded),
+		Entries:         []Entry{},
+	}
+
+	balance := 0.0
+	for i := range events {
+		event := &events[i]
+		diverges := event.RecordedBefore != nil && differs(*event.RecordedBefore, balance)
+
+		before := balance
+		if event.Reset != nil {
+			balance = *event.Reset
+		}
+		balance = finance.RoundCents(balance + event.Delta)
+
+		if event.RecordedAfter != nil && differs(*event.RecordedAfter, balance) {
+			diverges = true
+		}
+		if diverges {
+			ledger.DivergentSteps++
+		}
+
+		if i >= offset && i < offset+limit {
+			ledger.Entries = append(ledger.Entries, Entry{
+				Sequence:        i + 1,
+				At:              event.At,
+				Kind:            event.Kind,
+				Source:          event.Source,
+				ActorID:         event.ActorID,
+				ReferenceID:     event.ReferenceID,
+				Note:            event.Note,
+				Amount:          finance.RoundCents(balance - before),
+				Balance:         balance,
+				RecordedBalance: event.RecordedAfter,
+				Diverges:        diverges,
+			})
+		}
+	}
+
+	ledger.Balance = balance
+	ledger.Diverges = differs(ledger.RecordedBalance, balance)
+	return ledger
+}
+
+// differs reports whether two balances differ by at least a cent
+func differs(a, b float64) bool {
+	return math.Abs(a-b) >= divergenceTolerance
+}
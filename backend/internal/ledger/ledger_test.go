@@ -0,0 +1,204 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func contribution(amount float64, d int, source string) models.GoalContribution {
+	c := models.GoalContribution{ID: uuid.New(), Amount: amount, ContributionDate: day(d), CreatedAt: day(d).Add(time.Hour)}
+	if source != "" {
+		c.Source = &source
+	}
+	return c
+}
+
+func repair(d int, oldValue, newValue float64) models.AuditLog {
+	oldRaw, _ := json.Marshal(oldValue)
+	newRaw, _ := json.Marshal(newValue)
+	return models.AuditLog{
+		ID:        uuid.New(),
+		Action:    models.AuditActionIntegrityRepair,
+		OldValue:  oldRaw,
+		NewValue:  newRaw,
+		CreatedAt: day(d).Add(12 * time.Hour),
+	}
+}
+
+func TestGoalLedgerRunningBalance(t *testing.T) {
+	rule := uuid.New()
+	allocated := contribution(300, 3, "")
+	allocated.RuleID = &rule
+
+	events := GoalEvents([]models.GoalContribution{
+		contribution(500, 5, models.ContributionSourceSync),
+		contribution(1000, 1, models.ContributionSourceManual),
+		allocated,
+		contribution(-200, 7, models.ContributionSourceManual),
+	}, []models.AuditLog{repair(6, 1500, 1800)})
+
+	ledger := Build(events, 1600, 0, 0)
+
+	wantKinds := []string{KindContribution, KindAllocation, KindSync, KindRepair, KindWithdrawal}
+	wantBalances := []float64{1000, 1300, 1800, 1800, 1600}
+	if len(ledger.Entries) != len(wantKinds) {
+		t.Fatalf("entries = %d, want %d", len(ledger.Entries), len(wantKinds))
+	}
+	for i, entry := range ledger.Entries {
+		if entry.Kind != wantKinds[i] || entry.Balance != wantBalances[i] {
+			t.Errorf("entry %d = %s %.2f, want %s %.2f", i, entry.Kind, entry.Balance, wantKinds[i], wantBalances[i])
+		}
+		if entry.Sequence != i+1 {
+			t.Errorf("entry %d sequence = %d", i, entry.Sequence)
+		}
+	}
+
+	// The repair restored the stored amount to the recomputed balance
+	if ledger.Entries[3].Amount != 0 || ledger.Entries[3].Diverges {
+		t.Errorf("repair entry = %+v, want no change and no divergence", ledger.Entries[3])
+	}
+	if ledger.Diverges || ledger.Balance != 1600 {
+		t.Errorf("ledger balance = %.2f diverges = %v", ledger.Balance, ledger.Diverges)
+	}
+}
+
+func TestGoalLedgerFlagsDivergence(t *testing.T) {
+	events := GoalEvents([]models.GoalContribution{
+		contribution(1000, 1, ""),
+		contribution(250, 2, ""),
+	}, []models.AuditLog{repair(3, 1500, 1200)})
+
+	ledger := Build(events, 4200, 0, 0)
+
+	if !ledger.Entries[2].Diverges {
+		t.Error("repair to 1200 should diverge from the recomputed 1250")
+	}
+	if ledger.DivergentSteps != 1 {
+		t.Errorf("divergent steps = %d, want 1", ledger.DivergentSteps)
+	}
+	if !ledger.Diverges || ledger.RecordedBalance != 4200 || ledger.Balance != 1250 {
+		t.Errorf("ledger = %.2f recorded %.2f diverges %v", ledger.Balance, ledger.RecordedBalance, ledger.Diverges)
+	}
+}
+
+func TestLedgerPagination(t *testing.T) {
+	contributions := make([]models.GoalContribution, 2500)
+	for i := range contributions {
+		contributions[i] = contribution(10, 1, "")
+		contributions[i].CreatedAt = day(1).Add(time.Duration(i) * time.Second)
+	}
+	events := GoalEvents(contributions, nil)
+
+	page := Build(events, 25000, 1200, 50)
+	if page.Total != 2500 || len(page.Entries) != 50 {
+		t.Fatalf("total = %d entries = %d", page.Total, len(page.Entries))
+	}
+	if page.Entries[0].Sequence != 1201 || page.Entries[0].Balance != 12010 {
+		t.Errorf("first entry = %+v", page.Entries[0])
+	}
+	if page.Balance != 25000 || page.Diverges {
+		t.Errorf("full balance = %.2f, want 25000", page.Balance)
+	}
+
+	if _, limit := NormalizePage(0, 100000); limit != MaxLimit {
+		t.Errorf("limit = %d, want %d", limit, MaxLimit)
+	}
+	if past := Build(events, 25000, 5000, 10); len(past.Entries) != 0 {
+		t.Errorf("page past the end has %d entries", len(past.Entries))
+	}
+}
+
+func TestInvestmentLedger(t *testing.T) {
+	investment := models.Investment{ID: uuid.New(), Amount: 1500, StartDate: day(1)}
+	transactions := []models.InvestmentTransaction{
+		{ID: uuid.New(), TransactionType: models.TransactionTypeDeposit, Amount: 500, TransactionDate: day(2)},
+		{ID: uuid.New(), TransactionType: models.TransactionTypeFee, Amount: 5, TransactionDate: day(3)},
+		{ID: uuid.New(), TransactionType: models.TransactionTypeDividend, Amount: 20, TransactionDate: day(4)},
+	}
+	oldValue, _ := json.Marshal(1515.0)
+	newValue, _ := json.Marshal(1600.0)
+	audits := []models.AuditLog{{
+		ID: uuid.New(), Action: models.AuditActionValueUpdate,
+		OldValue: oldValue, NewValue: newValue, CreatedAt: day(5),
+	}}
+	value := 1600.0
+	investment.CurrentValue = &value
+
+	ledger := Build(InvestmentEvents(&investment, transactions, audits), investment.GetCurrentValue(), 0, 0)
+
+	wantBalances := []float64{1000, 1500, 1495, 1515, 1600}
+	for i, entry := range ledger.Entries {
+		if entry.Balance != wantBalances[i] {
+			t.Errorf("entry %d (%s) balance = %.2f, want %.2f", i, entry.Kind, entry.Balance, wantBalances[i])
+		}
+	}
+	if ledger.Entries[0].Kind != KindOpening || ledger.Entries[4].Amount != 85 {
+		t.Errorf("unexpected entries: %+v", ledger.Entries)
+	}
+	if ledger.Diverges || ledger.DivergentSteps != 0 {
+		t.Errorf("ledger should reconcile: %+v", ledger)
+	}
+
+	// Closing withdraws more than the tracked value and leaves nothing
+	closing, err := investment.Close(models.InvestmentCloseRequest{RealizedValue: 1700, CloseDate: day(6)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions = append(transactions, *closing)
+	closed := Build(InvestmentEvents(&investment, transactions, audits), investment.GetCurrentValue(), 0, 0)
+	if closed.Balance != 0 || closed.Diverges || closed.Entries[0].Balance != 1000 {
+		t.Errorf("closed ledger = %.2f diverges %v first %+v", closed.Balance, closed.Diverges, closed.Entries[0])
+	}
+}
+
+type fakeLoader struct {
+	goal *models.FinancialGoal
+}
+
+func (f *fakeLoader) LoadGoal(ctx context.Context, userID, goalID uuid.UUID) (*models.FinancialGoal, error) {
+	return f.goal, nil
+}
+
+func (f *fakeLoader) LoadGoalContributions(ctx context.Context, goalID uuid.UUID) ([]models.GoalContribution, error) {
+	return []models.GoalContribution{contribution(4200, 1, "")}, nil
+}
+
+func (f *fakeLoader) LoadInvestment(ctx context.Context, userID, investmentID uuid.UUID) (*models.Investment, error) {
+	return nil, nil
+}
+
+func (f *fakeLoader) LoadInvestmentTransactions(ctx context.Context, investmentID uuid.UUID) ([]models.InvestmentTransaction, error) {
+	return nil, nil
+}
+
+func (f *fakeLoader) LoadAuditLogs(ctx context.Context, entityType string, entityID uuid.UUID) ([]models.AuditLog, error) {
+	return nil, nil
+}
+
+func TestService(t *testing.T) {
+	goal := &models.FinancialGoal{ID: uuid.New(), CurrentAmount: 4200}
+	service := NewService(&fakeLoader{goal: goal})
+
+	ledger, err := service.GoalLedger(context.Background(), uuid.New(), goal.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("GoalLedger() error = %v", err)
+	}
+	if ledger.EntityType != "goal" || ledger.Balance != 4200 || ledger.Diverges {
+		t.Errorf("ledger = %+v", ledger)
+	}
+
+	if _, err := service.InvestmentLedger(context.Background(), uuid.New(), uuid.New(), 0, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("InvestmentLedger() error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,87 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// ErrNotFound is returned when the goal or investment does not belong to the user
+var ErrNotFound = errors.New("not found")
+
+// Loader loads the recorded mutations of a goal or investment. Loaders
+// return nil entities when they do not exist for the user.
+type Loader interface {
+	LoadGoal(ctx context.Context, userID, goalID uuid.UUID) (*models.FinancialGoal, error)
+	LoadGoalContributions(ctx context.Context, goalID uuid.UUID) ([]models.GoalContribution, error)
+	LoadInvestment(ctx context.Context, userID, investmentID uuid.UUID) (*models.Investment, error)
+	LoadInvestmentTransactions(ctx context.Context, investmentID uuid.UUID) ([]models.InvestmentTransaction, error)
+	LoadAuditLogs(ctx context.Context, entityType string, entityID uuid.UUID) ([]models.AuditLog, error)
+}
+
+// Service reconstructs goal and investment balances from their history
+type Service struct {
+	loader Loader
+}
+
+// NewService creates a ledger service
+func NewService(loader Loader) *Service {
+	return &Service{loader: loader}
+}
+
+// GoalLedger returns a page of the goal's history with a running balance
+// recomputed from contributions, compared to the stored current amount
+func (s *Service) GoalLedger(ctx context.Context, userID, goalID uuid.UUID, offset, limit int) (*Ledger, error) {
+	goal, err := s.loader.LoadGoal(ctx, userID, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load goal: %w", err)
+	}
+	if goal == nil {
+		return nil, ErrNotFound
+	}
+
+	contributions, err := s.loader.LoadGoalContributions(ctx, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contributions: %w", err)
+	}
+	audits, err := s.loader.LoadAuditLogs(ctx, "goal", goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load audit logs: %w", err)
+	}
+
+	ledger := Build(GoalEvents(contributions, audits), goal.CurrentAmount, offset, limit)
+	ledger.EntityType = "goal"
+	ledger.EntityID = goalID
+	return &ledger, nil
+}
+
+// InvestmentLedger returns a page of the investment's history with a running
+// value recomputed from transactions and value updates
+func (s *Service) InvestmentLedger(ctx context.Context, userID, investmentID uuid.UUID, offset, limit int) (*Ledger, error) {
+	investment, err := s.loader.LoadInvestment(ctx, userID, investmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load investment: %w", err)
+	}
+	if investment == nil {
+		return nil, ErrNotFound
+	}
+
+	transactions, err := s.loader.LoadInvestmentTransactions(ctx, investmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+	audits, err := s.loader.LoadAuditLogs(ctx, "investment", investmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load audit logs: %w", err)
+	}
+
+	events := InvestmentEvents(investment, transactions, audits)
+	ledger := Build(events, investment.GetCurrentValue(), offset, limit)
+	ledger.EntityType = "investment"
+	ledger.EntityID = investmentID
+	return &ledger, nil
+}
@@ -0,0 +1,242 @@
+package limits
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
+)
+
+// Rule is a spending limit prepared for evaluation
+type Rule struct {
+	Limit   models.SpendingLimit
+	pattern *regexp.Regexp
+}
+
+// Compile prepares active limits for evaluation. Merchant patterns are
+// case-insensitive; "*" matches any run of characters and a pattern without
+// wildcards matches anywhere in the merchant text.
+func Compile(limits []models.SpendingLimit) ([]Rule, error) {
+	rules := make([]Rule, 0, len(limits))
+	for _, limit := range limits {
+		if !limit.Active {
+			continue
+		}
+
+		rule := Rule{Limit: limit}
+		if limit.Scope == models.SpendingLimitScopeMerchant {
+			if limit.MerchantPattern == nil {
+				return nil, fmt.Errorf("spending limit %s has no merchant pattern", limit.ID)
+			}
+			pattern, err := compilePattern(*limit.MerchantPattern)
+			if err != nil {
+				return nil, fmt.Errorf("spending limit %s: %w", limit.ID, err)
+			}
+			rule.pattern = pattern
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Matches returns true if the expense falls within the limit's scope
+func (r *Rule) Matches(expense *models.Expense) bool {
+	switch r.Limit.Scope {
+	case models.SpendingLimitScopeCategory:
+		return r.Limit.CategoryID != nil && *r.Limit.CategoryID == expense.CategoryID
+	case models.SpendingLimitScopeMerchant:
+		if r.pattern.MatchString(expense.Description) {
+			return true
+		}
+		return expense.Location != nil && r.pattern.MatchString(*expense.Location)
+	default:
+		return false
+	}
+}
+
+// Result holds the limits an expense would exceed
+type Result struct {
+	Warnings []models.SpendingLimitViolation `json:"warnings,omitempty"`
+	Blocks   []models.SpendingLimitViolation `json:"blocks,omitempty"`
+}
+
+// Blocked returns true if a blocking limit rejects the expense and the user
+// has not confirmed the override
+func (r *Result) Blocked(confirmed bool) bool {
+	return len(r.Blocks) > 0 && !confirmed
+}
+
+// Notices returns every violation to report on an accepted expense. Overridden
+// blocks are reported alongside warnings.
+func (r *Result) Notices() []models.SpendingLimitViolation {
+	if len(r.Blocks) == 0 {
+		return r.Warnings
+	}
+	return append(append([]models.SpendingLimitViolation{}, r.Warnings...), r.Blocks...)
+}
+
+// LimitExceededError is returned when a blocking limit rejects an expense
+type LimitExceededError struct {
+	Violations []models.SpendingLimitViolation
+}
+
+// Error returns the error message
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("expense exceeds %d spending limit(s)", len(e.Violations))
+}
+
+// Code returns the API error code clients use to offer an override
+func (e *LimitExceededError) Code() string {
+	return models.ErrCodeSpendingLimitExceeded
+}
+
+// Evaluate checks an expense against the rules in a single pass. sameDay
+// holds the user's other expenses on the expense's day and is only consulted
+// for rules with a daily maximum. Amounts are the stored owner's shares, so
+// split expenses count correctly whether or not their splits are loaded.
+func Evaluate(rules []Rule, expense *models.Expense, sameDay []models.Expense) Result {
+	var result Result
+	amount := expense.MyShare
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(expense) {
+			continue
+		}
+		limit := rule.Limit
+
+		if limit.PerTransactionMax != nil && amount > *limit.PerTransactionMax {
+			result.add(limit, models.LimitKindPerTransaction, *limit.PerTransactionMax, amount,
+				fmt.Sprintf("%.2f exceeds the %.2f per-transaction limit %q", amount, *limit.PerTransactionMax, limit.Name))
+		}
+
+		if limit.DailyMax != nil {
+			total := amount
+			for j := range sameDay {
+				other := &sameDay[j]
+				if other.ID != expense.ID && !other.IsPendingMirror() && rule.Matches(other) {
+					total += other.MyShare
+				}
+			}
+			total = finance.RoundCents(total)
+			if total > *limit.DailyMax {
+				result.add(limit, models.LimitKindDaily, *limit.DailyMax, total,
+					fmt.Sprintf("%.2f spent today exceeds the %.2f daily limit %q", total, *limit.DailyMax, limit.Name))
+			}
+		}
+	}
+
+	return result
+}
+
+func (r *Result) add(limit models.SpendingLimit, kind string, max, attempted float64, message string) {
+	violation := models.SpendingLimitViolation{
+		LimitID:   limit.ID,
+		LimitName: limit.Name,
+		Action:    limit.Action,
+		Kind:      kind,
+		Limit:     max,
+		Attempted: attempted,
+		Message:   message,
+	}
+	if limit.Action == models.SpendingLimitActionBlock {
+		r.Blocks = append(r.Blocks, violation)
+	} else {
+		r.Warnings = append(r.Warnings, violation)
+	}
+}
+
+// HasDailyRule returns true if any rule needs the day's other expenses
+func HasDailyRule(rules []Rule) bool {
+	for _, rule := range rules {
+		if rule.Limit.DailyMax != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateCreateRequest validates a spending limit create request
+func ValidateCreateRequest(req *models.SpendingLimitCreateRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if err := utils.ValidateRequired(req.Name, "name"); err != nil {
+		errs.Add("name", "name is required")
+	}
+
+	errs = append(errs, validateShape(req.Scope, req.CategoryID, req.MerchantPattern, req.PerTransactionMax, req.DailyMax, req.Action)...)
+	return errs
+}
+
+// ValidateLimit validates a limit after an update request has been applied to it
+func ValidateLimit(limit *models.SpendingLimit) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if err := utils.ValidateRequired(limit.Name, "name"); err != nil {
+		errs.Add("name", "name is required")
+	}
+
+	errs = append(errs, validateShape(limit.Scope, limit.CategoryID, limit.MerchantPattern, limit.PerTransactionMax, limit.DailyMax, limit.Action)...)
+	return errs
+}
+
+// validateShape checks the scope, amount and action fields shared by create and update
+func validateShape(scope string, categoryID *uuid.UUID, merchantPattern *string, perTransactionMax, dailyMax *float64, action string) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	switch scope {
+	case models.SpendingLimitScopeCategory:
+		if categoryID == nil || *categoryID == uuid.Nil {
+			errs.Add("category_id", "category_id is required for category limits")
+		}
+		if merchantPattern != nil {
+			errs.Add("merchant_pattern", "merchant_pattern is only allowed for merchant limits")
+		}
+	case models.SpendingLimitScopeMerchant:
+		if merchantPattern == nil || strings.Trim(*merchantPattern, "* ") == "" {
+			errs.Add("merchant_pattern", "merchant_pattern is required for merchant limits")
+		} else if _, err := compilePattern(*merchantPattern); err != nil {
+			errs.Add("merchant_pattern", "merchant_pattern is invalid")
+		}
+		if categoryID != nil {
+			errs.Add("category_id", "category_id is only allowed for category limits")
+		}
+	default:
+		errs.Add("scope", models.SpendingLimitScopes.Message("scope"))
+	}
+
+	if perTransactionMax == nil && dailyMax == nil {
+		errs.Add("per_transaction_max", "one of per_transaction_max or daily_max is required")
+	}
+	if perTransactionMax != nil && *perTransactionMax <= 0 {
+		errs.Add("per_transaction_max", "per_transaction_max must be greater than 0")
+	}
+	if dailyMax != nil && *dailyMax <= 0 {
+		errs.Add("daily_max", "daily_max must be greater than 0")
+	}
+
+	if !models.SpendingLimitActions.Valid(action) {
+		errs.Add("action", models.SpendingLimitActions.Message("action"))
+	}
+
+	return errs
+}
+
+// compilePattern converts a merchant wildcard pattern to a regular expression
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := strings.Join(parts, ".*")
+	if strings.Contains(pattern, "*") {
+		expr = "^" + expr + "$"
+	}
+	return regexp.Compile("(?i)" + expr)
+}
@@ -0,0 +1,178 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func stringPtr(v string) *string { return &v }
+
+type fakeLoader struct {
+	limits      []models.SpendingLimit
+	expenses    []models.Expense
+	limitLoads  int
+	expenseDays []time.Time
+}
+
+func (l *fakeLoader) LoadLimits(ctx context.Context, userID uuid.UUID) ([]models.SpendingLimit, error) {
+	l.limitLoads++
+	return l.limits, nil
+}
+
+func (l *fakeLoader) LoadExpenses(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]models.Expense, error) {
+	l.expenseDays = append(l.expenseDays, start)
+	return l.expenses, nil
+}
+
+func TestEvaluate(t *testing.T) {
+	restaurants := uuid.New()
+	groceries := uuid.New()
+
+	rules, err := Compile([]models.SpendingLimit{
+		{ID: uuid.New(), Name: "Restaurants", Scope: models.SpendingLimitScopeCategory, CategoryID: &restaurants,
+			PerTransactionMax: floatPtr(200), Action: models.SpendingLimitActionWarn, Active: true},
+		{ID: uuid.New(), Name: "Coffee", Scope: models.SpendingLimitScopeMerchant, MerchantPattern: stringPtr("starbucks"),
+			DailyMax: floatPtr(10), Action: models.SpendingLimitActionBlock, Active: true},
+		{ID: uuid.New(), Name: "Inactive", Scope: models.SpendingLimitScopeCategory, CategoryID: &groceries,
+			PerTransactionMax: floatPtr(1), Action: models.SpendingLimitActionBlock},
+	})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		expense      models.Expense
+		sameDay      []models.Expense
+		wantWarnings int
+		wantBlocks   int
+	}{
+		{
+			name:         "under limit",
+			expense:      models.Expense{CategoryID: restaurants, Amount: 150, MyShare: 150, Description: "Dinner"},
+			wantWarnings: 0,
+		},
+		{
+			name:         "per-transaction warning",
+			expense:      models.Expense{CategoryID: restaurants, Amount: 250, MyShare: 250, Description: "Dinner"},
+			wantWarnings: 1,
+		},
+		{
+			name: "only my share counts",
+			expense: models.Expense{CategoryID: restaurants, Amount: 300, MyShare: 100, Description: "Dinner", Splits: []models.ExpenseSplit{
+				{IsOwner: true, ShareAmount: 100},
+				{ParticipantName: stringPtr("Alice"), ShareAmount: 200},
+			}},
+			wantWarnings: 0,
+		},
+		{
+			name:       "daily merchant block",
+			expense:    models.Expense{ID: uuid.New(), CategoryID: groceries, Amount: 6, MyShare: 6, Description: "STARBUCKS #1234"},
+			sameDay:    []models.Expense{{ID: uuid.New(), Amount: 5, MyShare: 5, Description: "Starbucks"}},
+			wantBlocks: 1,
+		},
+		{
+			name:    "same-day split expense counts its stored share",
+			expense: models.Expense{ID: uuid.New(), CategoryID: groceries, Amount: 4, MyShare: 4, Description: "Starbucks"},
+			sameDay: []models.Expense{{ID: uuid.New(), Amount: 15, MyShare: 5, Description: "Starbucks"}},
+		},
+		{
+			name:       "merchant matched by location",
+			expense:    models.Expense{CategoryID: groceries, Amount: 6, MyShare: 6, Description: "Latte", Location: stringPtr("Starbucks Main St")},
+			sameDay:    []models.Expense{{ID: uuid.New(), Amount: 5, MyShare: 5, Description: "Starbucks"}},
+			wantBlocks: 1,
+		},
+		{
+			name:    "inactive limits are ignored",
+			expense: models.Expense{CategoryID: groceries, Amount: 50, MyShare: 50, Description: "Groceries"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(rules, &tt.expense, tt.sameDay)
+			if len(result.Warnings) != tt.wantWarnings || len(result.Blocks) != tt.wantBlocks {
+				t.Errorf("Expected %d warnings and %d blocks, got %+v", tt.wantWarnings, tt.wantBlocks, result)
+			}
+		})
+	}
+}
+
+func TestWildcardPattern(t *testing.T) {
+	pattern, err := compilePattern("uber*eats")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	if !pattern.MatchString("Uber Eats") || pattern.MatchString("Uber trip") {
+		t.Error("Wildcard pattern matched unexpectedly")
+	}
+}
+
+func TestServiceCheck(t *testing.T) {
+	restaurants := uuid.New()
+	loader := &fakeLoader{limits: []models.SpendingLimit{
+		{ID: uuid.New(), Name: "Restaurants", Scope: models.SpendingLimitScopeCategory, CategoryID: &restaurants,
+			PerTransactionMax: floatPtr(200), Action: models.SpendingLimitActionBlock, Active: true},
+	}}
+	service := NewService(loader)
+	userID := uuid.New()
+	expense := &models.Expense{CategoryID: restaurants, Amount: 250, MyShare: 250, Description: "Dinner", ExpenseDate: time.Now()}
+
+	_, err := service.Check(context.Background(), userID, expense, false)
+	var exceeded *LimitExceededError
+	if !errors.As(err, &exceeded) || exceeded.Code() != models.ErrCodeSpendingLimitExceeded {
+		t.Fatalf("Expected a spending limit error, got %v", err)
+	}
+
+	warnings, err := service.Check(context.Background(), userID, expense, true)
+	if err != nil || len(warnings) != 1 {
+		t.Errorf("Confirmed override should succeed with a notice, got %v, %v", warnings, err)
+	}
+
+	if loader.limitLoads != 1 {
+		t.Errorf("Expected limits to be cached, loaded %d times", loader.limitLoads)
+	}
+	if len(loader.expenseDays) != 0 {
+		t.Error("Same-day expenses should only load for daily limits")
+	}
+
+	service.Invalidate(userID)
+	service.Check(context.Background(), userID, expense, true)
+	if loader.limitLoads != 2 {
+		t.Error("Invalidate should reload the user's limits")
+	}
+}
+
+func TestValidateCreateRequest(t *testing.T) {
+	category := uuid.New()
+
+	tests := []struct {
+		name    string
+		req     models.SpendingLimitCreateRequest
+		wantErr bool
+	}{
+		{"valid category limit", models.SpendingLimitCreateRequest{Name: "Food", Scope: "category", CategoryID: &category, PerTransactionMax: floatPtr(200), Action: "warn"}, false},
+		{"valid merchant limit", models.SpendingLimitCreateRequest{Name: "Coffee", Scope: "merchant", MerchantPattern: stringPtr("starbucks*"), DailyMax: floatPtr(10), Action: "block"}, false},
+		{"missing amounts", models.SpendingLimitCreateRequest{Name: "Food", Scope: "category", CategoryID: &category, Action: "warn"}, true},
+		{"category without id", models.SpendingLimitCreateRequest{Name: "Food", Scope: "category", PerTransactionMax: floatPtr(200), Action: "warn"}, true},
+		{"wildcard-only pattern", models.SpendingLimitCreateRequest{Name: "All", Scope: "merchant", MerchantPattern: stringPtr("*"), DailyMax: floatPtr(10), Action: "warn"}, true},
+		{"unknown action", models.SpendingLimitCreateRequest{Name: "Food", Scope: "category", CategoryID: &category, PerTransactionMax: floatPtr(200), Action: "deny"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateCreateRequest(&tt.req)
+			if errs.HasErrors() != tt.wantErr {
+				t.Errorf("ValidateCreateRequest() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// Loader loads spending limits and same-day expenses
+type Loader interface {
+	LoadLimits(ctx context.Context, userID uuid.UUID) ([]models.SpendingLimit, error)
+	// LoadExpenses returns the user's expenses dated within [start, end)
+	LoadExpenses(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]models.Expense, error)
+}
+
+// Service evaluates expenses against a user's spending limits in the expense
+// create path. Compiled limits are cached per user until Invalidate is called
+// after a limit is created, updated or deleted.
+type Service struct {
+	loader Loader
+
+	mu    sync.RWMutex
+	rules map[uuid.UUID][]Rule
+}
+
+// NewService creates a new spending limit service
+func NewService(loader Loader) *Service {
+	return &Service{
+		loader: loader,
+		rules:  make(map[uuid.UUID][]Rule),
+	}
+}
+
+// Check evaluates a new expense. It returns a LimitExceededError when a
+// blocking limit applies and confirmed is false; otherwise the returned
+// violations are the warnings to attach to the created expense. Limits
+// apply to the expense's MyShare, which must already reflect its splits.
+func (s *Service) Check(ctx context.Context, userID uuid.UUID, expense *models.Expense, confirmed bool) ([]models.SpendingLimitViolation, error) {
+	rules, err := s.userRules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	var sameDay []models.Expense
+	if HasDailyRule(rules) {
+		year, month, day := expense.ExpenseDate.Date()
+		start := time.Date(year, month, day, 0, 0, 0, 0, expense.ExpenseDate.Location())
+		sameDay, err = s.loader.LoadExpenses(ctx, userID, start, start.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load expenses for daily limits: %w", err)
+		}
+	}
+
+	result := Evaluate(rules, expense, sameDay)
+	if result.Blocked(confirmed) {
+		return nil, &LimitExceededError{Violations: result.Blocks}
+	}
+	return result.Notices(), nil
+}
+
+// Invalidate drops the cached limits for a user
+func (s *Service) Invalidate(userID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rules, userID)
+}
+
+// userRules returns the cached rules for a user, loading them on first use
+func (s *Service) userRules(ctx context.Context, userID uuid.UUID) ([]Rule, error) {
+	s.mu.RLock()
+	rules, ok := s.rules[userID]
+	s.mu.RUnlock()
+	if ok {
+		return rules, nil
+	}
+
+	limits, err := s.loader.LoadLimits(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spending limits: %w", err)
+	}
+	rules, err = Compile(limits)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.rules[userID] = rules
+	s.mu.Unlock()
+	return rules, nil
+}
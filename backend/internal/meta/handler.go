@@ -0,0 +1,53 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tgfinance/internal/models"
+)
+
+// enumsMaxAge is how long clients may cache the enum definitions, in seconds
+const enumsMaxAge = 86400
+
+// EnumsResponse lists every enumeration accepted by the API
+type EnumsResponse struct {
+	Enums []models.EnumSet `json:"enums"`
+}
+
+// Handler serves API metadata
+type Handler struct{}
+
+// NewHandler creates a new metadata handler
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// GetEnums handles GET /api/v1/meta/enums
+func (h *Handler) GetEnums(w http.ResponseWriter, r *http.Request) {
+	etag := models.EnumsETag()
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", enumsMaxAge))
+
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(EnumsResponse{Enums: models.Enums()})
+}
+
+// matchesETag returns true if an If-None-Match header lists etag
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package meta
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetEnums(t *testing.T) {
+	h := NewHandler()
+
+	rec := httptest.NewRecorder()
+	h.GetEnums(rec, httptest.NewRequest("GET", "/api/v1/meta/enums", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" || rec.Header().Get("Cache-Control") != "public, max-age=86400" {
+		t.Errorf("Expected caching headers, got %v", rec.Header())
+	}
+
+	var response EnumsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, set := range response.Enums {
+		names[set.Name] = true
+	}
+	for _, name := range []string{"goal_type", "goal_priority", "goal_status", "risk_level", "transaction_type"} {
+		if !names[name] {
+			t.Errorf("Missing enum %s", name)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/meta/enums", nil)
+	req.Header.Set("If-None-Match", `"stale", `+etag)
+	rec = httptest.NewRecorder()
+	h.GetEnums(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected 304 with an empty body for a matching ETag, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,324 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+)
+
+// Namespace prefixes every metric exported by tgfinance
+const Namespace = "tgfinance"
+
+// Collector names reported in the collector health metrics
+const (
+	CollectorOutbox       = "outbox"
+	CollectorScheduler    = "scheduler"
+	CollectorWebhooks     = "webhooks"
+	CollectorPriceRefresh = "price_refresh"
+	CollectorDeadLetters  = "dead_letters"
+)
+
+// DefaultWebhookWindow is how far back webhook deliveries are counted
+// for the failure rate
+const DefaultWebhookWindow = time.Hour
+
+// defaultCollectTimeout bounds each collector's query
+const defaultCollectTimeout = 5 * time.Second
+
+// OutboxSource reports the unprocessed outbox backlog
+type OutboxSource interface {
+	// OutboxBacklog returns the number of unprocessed events and the creation
+	// time of the oldest, which is nil when the backlog is empty
+	OutboxBacklog(ctx context.Context) (int, *time.Time, error)
+}
+
+// JobSource reports scheduler job runs
+type JobSource interface {
+	// JobLastSuccess returns the last successful run of each job by name
+	JobLastSuccess(ctx context.Context) (map[string]time.Time, error)
+}
+
+// WebhookSource reports webhook delivery outcomes
+type WebhookSource interface {
+	// WebhookDeliveries returns the number of deliveries attempted since
+	// and how many of them failed
+	WebhookDeliveries(ctx context.Context, since time.Time) (int, int, error)
+}
+
+// PriceSource reports investment price refresh state
+type PriceSource interface {
+	// OldestPriceSnapshot returns the time of the stalest latest snapshot
+	// across investments, or nil when there are none
+	OldestPriceSnapshot(ctx context.Context) (*time.Time, error)
+}
+
+// DeadLetterSource reports events whose delivery failed for good
+type DeadLetterSource interface {
+	// DeadLetterCounts returns the number of dead letters per event type
+	DeadLetterCounts(ctx context.Context) (map[string]int, error)
+}
+
+// BusinessSources are the queries behind the business metrics; nil sources
+// are not collected
+type BusinessSources struct {
+	Outbox      OutboxSource
+	Jobs        JobSource
+	Webhooks    WebhookSource
+	Prices      PriceSource
+	DeadLetters DeadLetterSource
+}
+
+// BusinessMetrics exports operational gauges for alerting. The gauges are
+// refreshed on a timer rather than on scrape, and each collector runs in
+// isolation: a failing or panicking query marks its collector down and
+// keeps the previous values, without affecting the scrape or the others.
+// Metric names and labels are relied on by alerting rules; renaming them is
+// a breaking change.
+type BusinessMetrics struct {
+	sources       BusinessSources
+	logger        *logger.Logger
+	clock         clock.Clock
+	timeout       time.Duration
+	webhookWindow time.Duration
+
+	outboxBacklog    prometheus.Gauge
+	outboxOldestAge  prometheus.Gauge
+	jobLastSuccess   *prometheus.GaugeVec
+	webhookFailure   prometheus.Gauge
+	priceStaleness   prometheus.Gauge
+	deadLetters      *prometheus.GaugeVec
+	collectorUp      *prometheus.GaugeVec
+	collectorErrors  *prometheus.CounterVec
+	collectorLastRun *prometheus.GaugeVec
+
+	mu sync.Mutex
+}
+
+// NewBusinessMetrics creates the business metrics over sources
+func NewBusinessMetrics(sources BusinessSources, log *logger.Logger) *BusinessMetrics {
+	return &BusinessMetrics{
+		sources:       sources,
+		logger:        log,
+		clock:         clock.Real(),
+		timeout:       defaultCollectTimeout,
+		webhookWindow: DefaultWebhookWindow,
+
+		outboxBacklog: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "outbox",
+			Name:      "backlog_size",
+			Help:      "Number of unprocessed outbox events.",
+		}),
+		outboxOldestAge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "outbox",
+			Name:      "oldest_unprocessed_age_seconds",
+			Help:      "Age of the oldest unprocessed outbox event, 0 when the backlog is empty.",
+		}),
+		jobLastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "scheduler",
+			Name:      "job_last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful run of each scheduler job.",
+		}, []string{"job"}),
+		webhookFailure: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "webhook",
+			Name:      "delivery_failure_ratio",
+			Help:      "Share of webhook deliveries that failed over the recent window.",
+		}),
+		priceStaleness: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "price_refresh",
+			Name:      "oldest_snapshot_age_seconds",
+			Help:      "Age of the oldest latest investment price snapshot.",
+		}),
+		deadLetters: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "outbox",
+			Name:      "dead_letters",
+			Help:      "Number of dead-lettered events awaiting action, by event type.",
+		}, []string{"event_type"}),
+		collectorUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "business_collector",
+			Name:      "up",
+			Help:      "Whether the last run of a business metrics collector succeeded.",
+		}, []string{"collector"}),
+		collectorErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "business_collector",
+			Name:      "errors_total",
+			Help:      "Number of failed business metrics collector runs.",
+		}, []string{"collector"}),
+		collectorLastRun: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "business_collector",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful run of a business metrics collector.",
+		}, []string{"collector"}),
+	}
+}
+
+// WithClock sets the clock used for ages and the refresh timer
+func (m *BusinessMetrics) WithClock(c clock.Clock) *BusinessMetrics {
+	m.clock = c
+	return m
+}
+
+// Register registers the business metrics, alongside the HTTP metrics
+func (m *BusinessMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.outboxBacklog, m.outboxOldestAge, m.jobLastSuccess, m.webhookFailure,
+		m.priceStaleness, m.deadLetters, m.collectorUp, m.collectorErrors, m.collectorLastRun,
+	} {
+		if err := reg.Register(c); err != nil {
+			return fmt.Errorf("failed to register business metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Run refreshes the metrics every interval until ctx is cancelled
+func (m *BusinessMetrics) Run(ctx context.Context, interval time.Duration) {
+	m.Refresh(ctx)
+
+	ticker := m.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			m.Refresh(ctx)
+		}
+	}
+}
+
+// Refresh runs every configured collector once
+func (m *BusinessMetrics) Refresh(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sources.Outbox != nil {
+		m.collect(ctx, CollectorOutbox, m.collectOutbox)
+	}
+	if m.sources.Jobs != nil {
+		m.collect(ctx, CollectorScheduler, m.collectJobs)
+	}
+	if m.sources.Webhooks != nil {
+		m.collect(ctx, CollectorWebhooks, m.collectWebhooks)
+	}
+	if m.sources.Prices != nil {
+		m.collect(ctx, CollectorPriceRefresh, m.collectPrices)
+	}
+	if m.sources.DeadLetters != nil {
+		m.collect(ctx, CollectorDeadLetters, m.collectDeadLetters)
+	}
+}
+
+// collect runs one collector with a timeout, recovering from panics
+func (m *BusinessMetrics) collect(ctx context.Context, name string, fn func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("collector panicked: %v", r)
+			}
+		}()
+		return fn(ctx)
+	}()
+
+	if err != nil {
+		m.collectorUp.WithLabelValues(name).Set(0)
+		m.collectorErrors.WithLabelValues(name).Inc()
+		if m.logger != nil {
+			m.logger.WithError(err).Warnf("Business metrics collector %s failed", name)
+		}
+		return
+	}
+	m.collectorUp.WithLabelValues(name).Set(1)
+	m.collectorErrors.WithLabelValues(name).Add(0)
+	m.collectorLastRun.WithLabelValues(name).Set(float64(m.clock.Now().Unix()))
+}
+
+// collectOutbox updates the outbox backlog gauges
+func (m *BusinessMetrics) collectOutbox(ctx context.Context) error {
+	size, oldest, err := m.sources.Outbox.OutboxBacklog(ctx)
+	if err != nil {
+		return err
+	}
+	m.outboxBacklog.Set(float64(size))
+	m.outboxOldestAge.Set(m.ageSeconds(oldest))
+	return nil
+}
+
+// collectJobs updates the scheduler job gauges
+func (m *BusinessMetrics) collectJobs(ctx context.Context) error {
+	runs, err := m.sources.Jobs.JobLastSuccess(ctx)
+	if err != nil {
+		return err
+	}
+	m.jobLastSuccess.Reset()
+	for job, at := range runs {
+		m.jobLastSuccess.WithLabelValues(job).Set(float64(at.Unix()))
+	}
+	return nil
+}
+
+// collectWebhooks updates the webhook failure rate
+func (m *BusinessMetrics) collectWebhooks(ctx context.Context) error {
+	total, failed, err := m.sources.Webhooks.WebhookDeliveries(ctx, m.clock.Now().Add(-m.webhookWindow))
+	if err != nil {
+		return err
+	}
+	ratio := 0.0
+	if total > 0 {
+		ratio = float64(failed) / float64(total)
+	}
+	m.webhookFailure.Set(ratio)
+	return nil
+}
+
+// collectPrices updates the price refresh staleness
+func (m *BusinessMetrics) collectPrices(ctx context.Context) error {
+	oldest, err := m.sources.Prices.OldestPriceSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	m.priceStaleness.Set(m.ageSeconds(oldest))
+	return nil
+}
+
+// collectDeadLetters updates the dead letter counts
+func (m *BusinessMetrics) collectDeadLetters(ctx context.Context) error {
+	counts, err := m.sources.DeadLetters.DeadLetterCounts(ctx)
+	if err != nil {
+		return err
+	}
+	m.deadLetters.Reset()
+	for eventType, count := range counts {
+		m.deadLetters.WithLabelValues(eventType).Set(float64(count))
+	}
+	return nil
+}
+
+// ageSeconds returns the age of t in seconds, 0 for nil or future times
+func (m *BusinessMetrics) ageSeconds(t *time.Time) float64 {
+	if t == nil {
+		return 0
+	}
+	age := m.clock.Now().Sub(*t).Seconds()
+	if age < 0 {
+		return 0
+	}
+	return age
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"tgfinance/pkg/clock"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeOutbox struct {
+	size   int
+	oldest *time.Time
+	err    error
+}
+
+func (f *fakeOutbox) OutboxBacklog(ctx context.Context) (int, *time.Time, error) {
+	return f.size, f.oldest, f.err
+}
+
+type fakeJobs map[string]time.Time
+
+func (f fakeJobs) JobLastSuccess(ctx context.Context) (map[string]time.Time, error) {
+	return f, nil
+}
+
+type fakeWebhooks struct{ total, failed int }
+
+func (f *fakeWebhooks) WebhookDeliveries(ctx context.Context, since time.Time) (int, int, error) {
+	return f.total, f.failed, nil
+}
+
+type panickingPrices struct{}
+
+func (panickingPrices) OldestPriceSnapshot(ctx context.Context) (*time.Time, error) {
+	panic("boom")
+}
+
+func gather(t *testing.T, reg *prometheus.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather: %v", err)
+	}
+	byName := make(map[string]*dto.MetricFamily)
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	return byName
+}
+
+func labelValues(f *dto.MetricFamily, name string) []string {
+	var values []string
+	for _, m := range f.GetMetric() {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == name {
+				values = append(values, l.GetValue())
+			}
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func newTestMetrics(t *testing.T, sources BusinessSources) (*BusinessMetrics, *prometheus.Registry) {
+	t.Helper()
+	m := NewBusinessMetrics(sources, nil).WithClock(clock.NewFake(testNow))
+	reg := prometheus.NewRegistry()
+	if err := m.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	return m, reg
+}
+
+func TestBusinessMetricNamesAndLabels(t *testing.T) {
+	oldest := testNow.Add(-90 * time.Second)
+	m, reg := newTestMetrics(t, BusinessSources{
+		Outbox:   &fakeOutbox{size: 7, oldest: &oldest},
+		Jobs:     fakeJobs{"recurring_expenses": testNow.Add(-time.Hour), "price_refresh": testNow},
+		Webhooks: &fakeWebhooks{total: 20, failed: 5},
+	})
+	m.Refresh(context.Background())
+
+	families := gather(t, reg)
+	values := map[string]float64{
+		"tgfinance_outbox_backlog_size":                   7,
+		"tgfinance_outbox_oldest_unprocessed_age_seconds": 90,
+		"tgfinance_webhook_delivery_failure_ratio":        0.25,
+	}
+	for name, want := range values {
+		f, ok := families[name]
+		if !ok {
+			t.Errorf("Missing metric %s", name)
+			continue
+		}
+		if got := f.GetMetric()[0].GetGauge().GetValue(); got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+
+	jobs, ok := families["tgfinance_scheduler_job_last_success_timestamp_seconds"]
+	if !ok {
+		t.Fatal("Missing scheduler job metric")
+	}
+	if got := labelValues(jobs, "job"); len(got) != 2 || got[0] != "price_refresh" || got[1] != "recurring_expenses" {
+		t.Errorf("Unexpected job labels %v", got)
+	}
+
+	up := families["tgfinance_business_collector_up"]
+	if got := labelValues(up, "collector"); len(got) != 3 {
+		t.Errorf("Expected health for the three configured collectors, got %v", got)
+	}
+}
+
+func TestBusinessCollectorFailureIsolation(t *testing.T) {
+	outbox := &fakeOutbox{size: 3}
+	m, reg := newTestMetrics(t, BusinessSources{
+		Outbox: outbox,
+		Prices: panickingPrices{},
+	})
+	m.Refresh(context.Background())
+
+	outbox.err = errors.New("database unavailable")
+	outbox.size = 100
+	m.Refresh(context.Background())
+
+	families := gather(t, reg)
+	if got := families["tgfinance_outbox_backlog_size"].GetMetric()[0].GetGauge().GetValue(); got != 3 {
+		t.Errorf("Expected the last good value to be kept, got %v", got)
+	}
+
+	for _, metric := range families["tgfinance_business_collector_up"].GetMetric() {
+		if metric.GetGauge().GetValue() != 0 {
+			t.Errorf("Expected failing collectors to be down, got %v", metric)
+		}
+	}
+	errorsByCollector := make(map[string]float64)
+	for _, metric := range families["tgfinance_business_collector_errors_total"].GetMetric() {
+		errorsByCollector[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+	}
+	if errorsByCollector[CollectorOutbox] != 1 || errorsByCollector[CollectorPriceRefresh] != 2 {
+		t.Errorf("Unexpected collector errors %v", errorsByCollector)
+	}
+}
+
+type fakeDeadLetters map[string]int
+
+func (f fakeDeadLetters) DeadLetterCounts(ctx context.Context) (map[string]int, error) {
+	return f, nil
+}
+
+func TestBusinessDeadLetterCounts(t *testing.T) {
+	m, reg := newTestMetrics(t, BusinessSources{
+		DeadLetters: fakeDeadLetters{"webhook.expense_created": 4, "notification.budget_alert": 1},
+	})
+	m.Refresh(context.Background())
+
+	f, ok := gather(t, reg)["tgfinance_outbox_dead_letters"]
+	if !ok {
+		t.Fatal("Missing dead letter metric")
+	}
+	counts := make(map[string]float64)
+	for _, metric := range f.GetMetric() {
+		counts[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+	}
+	if counts["webhook.expense_created"] != 4 || counts["notification.budget_alert"] != 1 || len(counts) != 2 {
+		t.Errorf("Unexpected dead letter counts %v", counts)
+	}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// IDPlaceholder replaces resource IDs in normalized routes
+const IDPlaceholder = ":id"
+
+// HTTPMetrics exports request counts, durations and in-flight requests.
+// Requests are labeled by method, normalized route and status class so the
+// number of series stays bounded.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+// NewHTTPMetrics creates the HTTP metrics
+func NewHTTPMetrics() *HTTPMetrics {
+	labels := []string{"method", "route", "status"}
+	return &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests served.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests being served.",
+		}),
+	}
+}
+
+// Register registers the HTTP metrics
+func (m *HTTPMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.inFlight} {
+		if err := reg.Register(c); err != nil {
+			return fmt.Errorf("failed to register HTTP metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Start counts a request as in flight and returns the function recording
+// its outcome
+func (m *HTTPMetrics) Start(method, path string) func(status int) {
+	start := time.Now()
+	m.inFlight.Inc()
+	return func(status int) {
+		m.inFlight.Dec()
+		labels := prometheus.Labels{"method": method, "route": NormalizeRoute(path), "status": StatusClass(status)}
+		m.requests.With(labels).Inc()
+		m.duration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
+
+// NormalizeRoute replaces UUID path segments with IDPlaceholder
+func NormalizeRoute(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if len(segment) == 36 {
+			if _, err := uuid.Parse(segment); err == nil {
+				segments[i] = IDPlaceholder
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// StatusClass returns the class of an HTTP status code, such as "2xx"
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// Handler serves the metrics of the gatherer for scraping at /metrics
+func Handler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
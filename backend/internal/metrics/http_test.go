@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNormalizeRoute(t *testing.T) {
+	tests := map[string]string{
+		"/api/v1/goals": "/api/v1/goals",
+		"/api/v1/goals/4f1c1f3e-9a5b-4a8e-8a53-6c1d2e3f4a5b":         "/api/v1/goals/:id",
+		"/api/v1/users/4F1C1F3E-9A5B-4A8E-8A53-6C1D2E3F4A5B/goals/x": "/api/v1/users/:id/goals/x",
+		"/api/v1/goals/not-a-uuid":                                   "/api/v1/goals/not-a-uuid",
+	}
+	for path, want := range tests {
+		if got := NormalizeRoute(path); got != want {
+			t.Errorf("NormalizeRoute(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	if got := StatusClass(http.StatusNotFound); got != "4xx" {
+		t.Errorf("StatusClass(404) = %q", got)
+	}
+	if got := StatusClass(0); got != "unknown" {
+		t.Errorf("StatusClass(0) = %q", got)
+	}
+}
+
+type fakePool sql.DBStats
+
+func (f *fakePool) Stats() sql.DBStats { return sql.DBStats(*f) }
+
+func TestDBStatsCollectorRefreshesOnScrape(t *testing.T) {
+	pool := &fakePool{MaxOpenConnections: 25, OpenConnections: 3, InUse: 2, Idle: 1, WaitDuration: 2 * time.Second}
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(NewDBStatsCollector(pool)); err != nil {
+		t.Fatal(err)
+	}
+
+	families := gather(t, reg)
+	if got := families["tgfinance_db_in_use_connections"].GetMetric()[0].GetGauge().GetValue(); got != 2 {
+		t.Errorf("in use = %v, want 2", got)
+	}
+	if got := families["tgfinance_db_wait_duration_seconds_total"].GetMetric()[0].GetCounter().GetValue(); got != 2 {
+		t.Errorf("wait duration = %v, want 2", got)
+	}
+
+	pool.InUse = 5
+	families = gather(t, reg)
+	if got := families["tgfinance_db_in_use_connections"].GetMetric()[0].GetGauge().GetValue(); got != 5 {
+		t.Errorf("in use after change = %v, want 5", got)
+	}
+}
+
+func TestHTTPMetricsHandler(t *testing.T) {
+	m := NewHTTPMetrics()
+	reg := prometheus.NewRegistry()
+	if err := m.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	m.Start(http.MethodGet, "/api/v1/goals")(http.StatusOK)
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), `tgfinance_http_requests_total{method="GET",route="/api/v1/goals",status="2xx"} 1`) {
+		t.Errorf("scrape output missing request counter:\n%s", body)
+	}
+}
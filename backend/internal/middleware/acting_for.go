@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActingForHeader carries the client user ID an advisor is acting for
+const ActingForHeader = "X-Acting-For"
+
+// ConsentChecker reports whether a client has granted an advisor access
+type ConsentChecker interface {
+	HasActiveConsent(ctx context.Context, advisorID, clientID uuid.UUID) (bool, error)
+}
+
+// CrossUserAction is an audit record of a request made on behalf of another user
+type CrossUserAction struct {
+	ActorUserID   uuid.UUID `json:"actor_user_id"`
+	SubjectUserID uuid.UUID `json:"subject_user_id"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	StatusCode    int       `json:"status_code"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// CrossUserAuditor records every cross-user action
+type CrossUserAuditor interface {
+	RecordCrossUserAction(ctx context.Context, action CrossUserAction) error
+}
+
+// EnableActingFor allows advisors to act for clients via the X-Acting-For
+// header. Without it the header is rejected.
+func (m *AuthMiddleware) EnableActingFor(consents ConsentChecker, auditor CrossUserAuditor) {
+	m.consents = consents
+	m.auditor = auditor
+}
+
+// actingForRestrictedPaths lists endpoints that must be called by the account owner
+var actingForRestrictedPaths = map[string][]string{
+	"/api/v1/auth/change-password": {"POST", "PUT"},
+	"/api/v1/users/me":             {"DELETE"},
+	"/api/v1/users/me/export":      {"GET", "POST"},
+	"/api/v1/export":               {"GET", "POST"},
+}
+
+// isActingForRestricted determines if the endpoint refuses acting-for requests
+func isActingForRestricted(path, method string) bool {
+	if methods, exists := actingForRestrictedPaths[path]; exists {
+		for _, m := range methods {
+			if m == method {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveActingFor validates the acting-for header, swaps the effective user in
+// context while keeping the actor, and audits the request
+func (m *AuthMiddleware) serveActingFor(w http.ResponseWriter, r *http.Request, next http.Handler, actorID uuid.UUID, header string) {
+	if m.consents == nil {
+		m.sendErrorResponse(w, http.StatusForbidden, "Acting for another user is not enabled")
+		return
+	}
+
+	clientID, err := uuid.Parse(header)
+	if err != nil {
+		m.sendErrorResponse(w, http.StatusBadRequest, "Invalid X-Acting-For header")
+		return
+	}
+	if clientID == actorID {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if isActingForRestricted(r.URL.Path, r.Method) {
+		m.sendErrorResponse(w, http.StatusForbidden, "This action cannot be performed on behalf of another user")
+		return
+	}
+
+	allowed, err := m.consents.HasActiveConsent(r.Context(), actorID, clientID)
+	if err != nil {
+		m.log(r.Context()).WithError(err).Error("Failed to check consent")
+		m.sendErrorResponse(w, http.StatusInternalServerError, "Failed to verify consent")
+		return
+	}
+	if !allowed {
+		m.log(r.Context()).WithField("actor_user_id", actorID.String()).Warn("Acting-for request without active consent")
+		m.sendErrorResponse(w, http.StatusForbidden, "No active consent from this user")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "user_id", clientID.String())
+	ctx = context.WithValue(ctx, "actor_user_id", actorID.String())
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(recorder, r.WithContext(ctx))
+
+	action := CrossUserAction{
+		ActorUserID:   actorID,
+		SubjectUserID: clientID,
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		StatusCode:    recorder.status,
+		OccurredAt:    time.Now(),
+	}
+	if m.auditor != nil {
+		if err := m.auditor.RecordCrossUserAction(context.WithoutCancel(r.Context()), action); err != nil {
+			m.log(r.Context()).WithError(err).Error("Failed to record cross-user action")
+		}
+	}
+}
+
+// RejectActingFor middleware refuses requests made on behalf of another user
+func (m *AuthMiddleware) RejectActingFor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsActingFor(r.Context()) {
+			m.sendErrorResponse(w, http.StatusForbidden, "This action cannot be performed on behalf of another user")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsActingFor returns true if the request is made on behalf of another user
+func IsActingFor(ctx context.Context) bool {
+	return ctx.Value("actor_user_id") != nil
+}
+
+// GetActorUserIDFromContext returns the authenticated user behind the request,
+// which differs from the effective user when acting for a client
+func GetActorUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	if actor := ctx.Value("actor_user_id"); actor != nil {
+		actorID, err := uuid.Parse(actor.(string))
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid actor user ID format: %w", err)
+		}
+		return actorID, nil
+	}
+	return GetUserIDFromContext(ctx)
+}
+
+// statusRecorder captures the response status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type fakeConsents struct {
+	allowed map[[2]uuid.UUID]bool
+}
+
+func (f *fakeConsents) HasActiveConsent(ctx context.Context, advisorID, clientID uuid.UUID) (bool, error) {
+	return f.allowed[[2]uuid.UUID{advisorID, clientID}], nil
+}
+
+type fakeAuditor struct {
+	actions []CrossUserAction
+}
+
+func (f *fakeAuditor) RecordCrossUserAction(ctx context.Context, action CrossUserAction) error {
+	f.actions = append(f.actions, action)
+	return nil
+}
+
+func TestActingFor(t *testing.T) {
+	advisor := uuid.New()
+	client := uuid.New()
+	m := newTestAuthMiddleware()
+	auditor := &fakeAuditor{}
+
+	var effective, actor uuid.UUID
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		effective, _ = GetUserIDFromContext(r.Context())
+		actor, _ = GetActorUserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Requests as Authenticate hands them over after validating the advisor's token
+	request := func(path, method, actingFor string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", advisor.String()))
+		rec := httptest.NewRecorder()
+		if actingFor == "" {
+			next.ServeHTTP(rec, req)
+		} else {
+			m.serveActingFor(rec, req, next, advisor, actingFor)
+		}
+		return rec.Code
+	}
+
+	if code := request("/api/v1/expenses", "GET", client.String()); code != http.StatusForbidden {
+		t.Errorf("Acting-for without EnableActingFor should be forbidden, got %d", code)
+	}
+
+	consents := &fakeConsents{allowed: make(map[[2]uuid.UUID]bool)}
+	m.EnableActingFor(consents, auditor)
+
+	if code := request("/api/v1/expenses", "GET", client.String()); code != http.StatusForbidden {
+		t.Errorf("Acting-for without consent should be forbidden, got %d", code)
+	}
+
+	consents.allowed[[2]uuid.UUID{advisor, client}] = true
+	if code := request("/api/v1/expenses", "GET", client.String()); code != http.StatusOK {
+		t.Fatalf("Expected consented request to succeed, got %d", code)
+	}
+	if effective != client || actor != advisor {
+		t.Errorf("Expected effective user %s acted on by %s, got %s by %s", client, advisor, effective, actor)
+	}
+	if len(auditor.actions) != 1 || auditor.actions[0].ActorUserID != advisor || auditor.actions[0].SubjectUserID != client {
+		t.Errorf("Expected one audited cross-user action, got %+v", auditor.actions)
+	}
+
+	if code := request("/api/v1/auth/change-password", "POST", client.String()); code != http.StatusForbidden {
+		t.Errorf("Password change should refuse acting-for, got %d", code)
+	}
+	if code := request("/api/v1/expenses", "GET", "not-a-uuid"); code != http.StatusBadRequest {
+		t.Errorf("Invalid header should be rejected, got %d", code)
+	}
+
+	if code := request("/api/v1/expenses", "GET", ""); code != http.StatusOK || effective != advisor {
+		t.Errorf("Requests without the header act as the advisor, got %d for %s", code, effective)
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"tgfinance/pkg/httputil"
+)
+
+// ReasonIPNotAllowed is the error code for requests from outside an allowlist
+const ReasonIPNotAllowed = "ip_not_allowed"
+
+// IPAllowlist creates middleware refusing requests from clients outside the
+// allowed IPs and CIDR ranges with 403. The client is the direct peer unless
+// the peer is a trusted proxy, in which case X-Forwarded-For is followed, so
+// clients cannot spoof their address. Without allowed networks every request
+// is refused. It sits in front of RequireAdmin, so admin routes need both the
+// right network and the admin role.
+func IPAllowlist(allowed, trustedProxies []string) (func(http.Handler) http.Handler, error) {
+	networks, err := parseNetworks(allowed, "allowed network")
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := ParseTrustedProxies(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !inNetworks(TrustedClientIP(r, proxies), networks) {
+				httputil.WriteError(w, http.StatusForbidden, ReasonIPNotAllowed, "Access is not allowed from this network")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
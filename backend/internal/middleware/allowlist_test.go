@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/auth"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	allowlist, err := IPAllowlist([]string{"10.8.0.0/16", "2001:db8:a::/48", "198.51.100.7"}, []string{"10.0.0.1", "fd00::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := allowlist(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded string
+		want      int
+	}{
+		{"vpn peer", "10.8.3.4:5000", "", http.StatusOK},
+		{"single address", "198.51.100.7:5000", "", http.StatusOK},
+		{"outside peer", "203.0.113.5:5000", "", http.StatusForbidden},
+		{"ipv6 vpn peer", "[2001:db8:a:1::5]:5000", "", http.StatusOK},
+		{"ipv6 outside peer", "[2001:db8:beef::5]:5000", "", http.StatusForbidden},
+		{"vpn client via trusted proxy", "10.0.0.1:5000", "10.8.3.4", http.StatusOK},
+		{"ipv6 client via trusted ipv6 proxy", "[fd00::1]:5000", "2001:db8:a::9", http.StatusOK},
+		{"outside client via trusted proxy", "10.0.0.1:5000", "203.0.113.5", http.StatusForbidden},
+		{"spoofed hop before the real client", "10.0.0.1:5000", "10.8.3.4, 203.0.113.5", http.StatusForbidden},
+		{"spoofed header from untrusted peer", "203.0.113.5:5000", "10.8.3.4", http.StatusForbidden},
+		{"spoofed header from untrusted ipv6 peer", "[2001:db8:beef::5]:5000", "2001:db8:a::9", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+			req.RemoteAddr = tt.peer
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusForbidden && errorCode(t, rec) != ReasonIPNotAllowed {
+				t.Error("wrong error code")
+			}
+		})
+	}
+
+	if _, err := IPAllowlist([]string{"10.8.0.0/33"}, nil); err == nil {
+		t.Error("invalid range should fail")
+	}
+	if _, err := IPAllowlist(nil, []string{"proxy"}); err == nil {
+		t.Error("invalid proxy should fail")
+	}
+}
+
+func TestIPAllowlistWithRequireAdmin(t *testing.T) {
+	m := newTestAuthMiddleware()
+	m.logger.SetOutput(&bytes.Buffer{})
+	allowlist, err := IPAllowlist([]string{"10.8.0.0/16"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewChain(allowlist, m.RequireAdmin).ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	status := func(peer, role string) int {
+		ctx := context.WithValue(context.Background(), "user_id", uuid.NewString())
+		ctx = context.WithValue(ctx, "user_role", role)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil).WithContext(ctx)
+		req.RemoteAddr = peer
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if got := status("10.8.0.9:1", auth.RoleAdmin); got != http.StatusOK {
+		t.Errorf("admin on vpn = %d", got)
+	}
+	if got := status("10.8.0.9:1", auth.RoleUser); got != http.StatusForbidden {
+		t.Errorf("user on vpn = %d", got)
+	}
+	if got := status("203.0.113.5:1", auth.RoleAdmin); got != http.StatusForbidden {
+		t.Errorf("admin off vpn = %d", got)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -11,21 +12,44 @@ import (
 
 	"tgfinance/internal/config"
 	"tgfinance/pkg/auth"
+	"tgfinance/pkg/httputil"
 	"tgfinance/pkg/logger"
 )
 
 // AuthMiddleware provides JWT authentication middleware
 type AuthMiddleware struct {
-	jwtManager *auth.JWTManager
+	jwtManager TokenManager
 	logger     *logger.Logger
+	consents   ConsentChecker
+	auditor    CrossUserAuditor
+
+	integrations *integrationTokens
+	blacklist    *auth.TokenBlacklist
+	skipRules    []SkipRule
+	cookies      cookieSettings
+	authEvents   AuthEventRecorder
 }
 
-// NewAuthMiddleware creates a new authentication middleware
-func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
-	return &AuthMiddleware{
-		jwtManager: auth.NewJWTManager(),
-		logger:     logger.New(cfg.Log.Level, cfg.Log.Format, cfg.Log.Output, cfg.Log.TimeFormat),
+// NewAuthMiddleware creates a new authentication middleware over deps. A
+// blacklist in deps enables token revocation.
+func NewAuthMiddleware(cfg *config.Config, deps Deps) *AuthMiddleware {
+	m := &AuthMiddleware{
+		jwtManager: deps.Tokens,
+		logger:     deps.Logger,
+		blacklist:  deps.Blacklist,
+		skipRules:  append([]SkipRule(nil), DefaultSkipRules...),
+		cookies:    newCookieSettings(cfg.Auth),
 	}
+
+	for _, path := range cfg.Auth.PublicPaths {
+		rule, err := ParseSkipRule(path)
+		if err != nil {
+			m.logger.WithError(err).Warn("Ignoring invalid public path")
+			continue
+		}
+		m.skipRules = append(m.skipRules, rule)
+	}
+	return m
 }
 
 // Authenticate middleware validates JWT tokens and extracts user information
@@ -37,29 +61,56 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// Extract token from Authorization header
+		// Extract token from Authorization header or auth cookie
 		token, err := m.extractToken(r)
 		if err != nil {
-			m.logger.WithError(err).Error("Failed to extract token")
+			m.log(r.Context()).WithError(err).Error("Failed to extract token")
+			m.recordAuthEvent(r, AuthEventFailed, uuid.Nil, false, "missing_token")
 			m.sendErrorResponse(w, http.StatusUnauthorized, "Invalid or missing authorization token")
 			return
 		}
 
+		// Integration tokens are resolved by their own authorizer
+		if m.isIntegrationToken(token) {
+			m.serveIntegrationToken(w, r, next, token)
+			return
+		}
+
 		// Validate token
-		claims, err := m.jwtManager.ValidateToken(token)
+		claims, err := m.jwtManager.ValidateAccessToken(token)
 		if err != nil {
-			m.logger.WithError(err).Error("Failed to validate token")
+			m.log(r.Context()).WithError(err).Error("Failed to validate token")
+			m.recordAuthEvent(r, AuthEventFailed, uuid.Nil, false, "invalid_token")
 			m.sendErrorResponse(w, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
+		// Refuse tokens revoked by logout
+		if m.isRevoked(r.Context(), claims) {
+			m.recordAuthEvent(r, AuthEventFailed, claims.UserID, false, "revoked_token")
+			m.sendErrorResponse(w, http.StatusUnauthorized, "Token has been revoked")
+			return
+		}
+
 		// Add user information to request context
-		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+		ctx := context.WithValue(withToken(r.Context(), claims), "user_id", claims.UserID.String())
 		ctx = context.WithValue(ctx, "user_email", claims.Email)
-		ctx = context.WithValue(ctx, "user_role", "user") // Default role
+		ctx = context.WithValue(ctx, "user_role", claims.EffectiveRole())
+		ctx = context.WithValue(ctx, "user_permissions", claims.Permissions)
+		ctx = context.WithValue(ctx, "mfa", claims.MFA)
 
 		// Log successful authentication
-		m.logger.WithUser(claims.UserID.String(), claims.Email).Info("User authenticated successfully")
+		m.log(r.Context()).WithFields(logrus.Fields{
+			"user_id": claims.UserID.String(),
+			"email":   logger.MaskEmail(claims.Email),
+		}).Info("User authenticated successfully")
+		setAccessLogUser(r.Context(), claims.UserID.String())
+
+		// Advisors acting for a client need an active consent
+		if actingFor := r.Header.Get(ActingForHeader); actingFor != "" {
+			m.serveActingFor(w, r.WithContext(ctx), next, claims.UserID, actingFor)
+			return
+		}
 
 		// Call next handler with updated context
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -77,7 +128,7 @@ func (m *AuthMiddleware) RequireRole(requiredRole string) func(http.Handler) htt
 			}
 
 			if userRole.(string) != requiredRole {
-				m.logger.WithFields(logrus.Fields{
+				m.log(r.Context()).WithFields(logrus.Fields{
 					"user_role":     userRole.(string),
 					"required_role": requiredRole,
 				}).Warn("User does not have required role")
@@ -92,14 +143,45 @@ func (m *AuthMiddleware) RequireRole(requiredRole string) func(http.Handler) htt
 
 // RequireAdmin middleware checks if the authenticated user is an admin
 func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
-	return m.RequireRole("admin")(next)
+	return m.RequireRole(auth.RoleAdmin)(next)
+}
+
+// ReasonMFARequired is the error reason when a route needs a second factor
+const ReasonMFARequired = "mfa_required"
+
+// RequireMFA middleware checks that the token was issued after a second factor
+func (m *AuthMiddleware) RequireMFA(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mfa, _ := r.Context().Value("mfa").(bool); !mfa {
+			m.sendErrorReason(w, http.StatusForbidden, ReasonMFARequired, "Two-factor authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission middleware checks if the authenticated token grants the permission
+func (m *AuthMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range GetUserPermissionsFromContext(r.Context()) {
+				if p == permission {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			m.log(r.Context()).WithField("required_permission", permission).Warn("User does not have required permission")
+			m.sendErrorResponse(w, http.StatusForbidden, "Insufficient permissions")
+		})
+	}
 }
 
 // RequireUser middleware ensures the user is accessing their own resources
 func (m *AuthMiddleware) RequireUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userID := r.Context().Value("user_id")
-		if userID == nil {
+		userID, err := GetUserIDFromContext(r.Context())
+		if err != nil {
 			m.sendErrorResponse(w, http.StatusUnauthorized, "User ID not found in context")
 			return
 		}
@@ -108,11 +190,11 @@ func (m *AuthMiddleware) RequireUser(next http.Handler) http.Handler {
 		pathParts := strings.Split(r.URL.Path, "/")
 		for i, part := range pathParts {
 			if part == "users" && i+1 < len(pathParts) {
-				pathUserID := pathParts[i+1]
-				if pathUserID != userID.(string) {
-					m.logger.WithFields(logrus.Fields{
-						"authenticated_user_id": userID.(string),
-						"requested_user_id":     pathUserID,
+				pathUserID, err := uuid.Parse(pathParts[i+1])
+				if err != nil || pathUserID != userID {
+					m.log(r.Context()).WithFields(logrus.Fields{
+						"authenticated_user_id": userID.String(),
+						"requested_user_id":     pathParts[i+1],
 					}).Warn("User trying to access another user's resource")
 					m.sendErrorResponse(w, http.StatusForbidden, "Cannot access another user's resources")
 					return
@@ -125,10 +207,69 @@ func (m *AuthMiddleware) RequireUser(next http.Handler) http.Handler {
 	})
 }
 
-// extractToken extracts the JWT token from the Authorization header
+// ErrResourceNotFound is returned by ownership loaders for missing resources
+var ErrResourceNotFound = errors.New("resource not found")
+
+// OwnerLoader returns the owner of a resource, or ErrResourceNotFound
+type OwnerLoader func(ctx context.Context, resourceID uuid.UUID) (uuid.UUID, error)
+
+// RequireOwnership middleware checks that the authenticated user owns the
+// resource whose ID is the path segment at position (zero-based, ignoring
+// the leading slash, so 3 for /api/v1/expenses/{id}). Resources of other
+// users answer 404 like missing ones so IDs cannot be probed.
+func (m *AuthMiddleware) RequireOwnership(position int, loader OwnerLoader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, err := GetUserIDFromContext(r.Context())
+			if err != nil {
+				m.sendErrorResponse(w, http.StatusUnauthorized, "User ID not found in context")
+				return
+			}
+
+			segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+			if position < 0 || position >= len(segments) {
+				m.sendErrorResponse(w, http.StatusNotFound, "Resource not found")
+				return
+			}
+			resourceID, err := uuid.Parse(segments[position])
+			if err != nil {
+				m.sendErrorResponse(w, http.StatusNotFound, "Resource not found")
+				return
+			}
+
+			ownerID, err := loader(r.Context(), resourceID)
+			if errors.Is(err, ErrResourceNotFound) {
+				m.sendErrorResponse(w, http.StatusNotFound, "Resource not found")
+				return
+			}
+			if err != nil {
+				m.log(r.Context()).WithError(err).Error("Failed to load resource owner")
+				m.sendErrorResponse(w, http.StatusInternalServerError, "Failed to check resource ownership")
+				return
+			}
+			if ownerID != userID {
+				m.log(r.Context()).WithFields(logrus.Fields{
+					"authenticated_user_id": userID.String(),
+					"resource_id":           resourceID.String(),
+				}).Warn("User trying to access another user's resource")
+				m.sendErrorResponse(w, http.StatusNotFound, "Resource not found")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// extractToken extracts the JWT token from the Authorization header, falling
+// back to the auth cookie
 func (m *AuthMiddleware) extractToken(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
+		// Browser clients send the token in the httpOnly auth cookie
+		if cookie, err := r.Cookie(m.cookies.name); err == nil && cookie.Value != "" {
+			return cookie.Value, nil
+		}
 		return "", fmt.Errorf("authorization header is required")
 	}
 
@@ -146,56 +287,33 @@ func (m *AuthMiddleware) extractToken(r *http.Request) (string, error) {
 	return token, nil
 }
 
-// shouldSkipAuth determines if authentication should be skipped for the given path and method
-func (m *AuthMiddleware) shouldSkipAuth(path, method string) bool {
-	// Skip authentication for these endpoints
-	skipPaths := map[string][]string{
-		"/health":               {"GET"},
-		"/metrics":              {"GET"},
-		"/api/v1/auth/login":    {"POST"},
-		"/api/v1/auth/register": {"POST"},
-		"/api/v1/auth/refresh":  {"POST"},
-	}
-
-	if methods, exists := skipPaths[path]; exists {
-		for _, m := range methods {
-			if m == method {
-				return true
-			}
-		}
-	}
-
-	// Skip authentication for OPTIONS requests (CORS preflight)
-	if method == "OPTIONS" {
-		return true
-	}
-
-	return false
+// log returns a log entry carrying the request ID
+func (m *AuthMiddleware) log(ctx context.Context) *logrus.Entry {
+	return m.logger.WithRequestID(ctx)
 }
 
-// sendErrorResponse sends a JSON error response
+// sendErrorResponse sends a JSON error response coded after the status
 func (m *AuthMiddleware) sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	// Note: In a real implementation, you'd use json.Marshal and w.Write
-	// For now, we'll just write a simple response
-	w.Write([]byte(fmt.Sprintf(`{"error":{"code":%d,"message":"%s"}}`, statusCode, message)))
+	httputil.WriteError(w, statusCode, httputil.CodeForStatus(statusCode), message)
 }
 
-// GetUserIDFromContext extracts user ID from request context
+// GetUserIDFromContext extracts user ID from request context, stored either
+// as a string or a uuid.UUID
 func GetUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
-	userIDStr := ctx.Value("user_id")
-	if userIDStr == nil {
+	switch value := ctx.Value("user_id").(type) {
+	case nil:
 		return uuid.Nil, fmt.Errorf("user ID not found in context")
+	case uuid.UUID:
+		return value, nil
+	case string:
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid user ID format: %w", err)
+		}
+		return userID, nil
+	default:
+		return uuid.Nil, fmt.Errorf("invalid user ID type %T", value)
 	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID format: %w", err)
-	}
-
-	return userID, nil
 }
 
 // GetUserEmailFromContext extracts user email from request context
@@ -217,3 +335,9 @@ func GetUserRoleFromContext(ctx context.Context) (string, error) {
 
 	return userRole.(string), nil
 }
+
+// GetUserPermissionsFromContext extracts the token permissions from request context
+func GetUserPermissionsFromContext(ctx context.Context) []string {
+	permissions, _ := ctx.Value("user_permissions").([]string)
+	return permissions
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Authentication event types reported to an AuthEventRecorder
+const (
+	AuthEventFailed = "auth_failed"
+	AuthEventLogout = "logout"
+)
+
+// AuthEvent describes an authentication outcome observed by the middleware
+type AuthEvent struct {
+	Type    string
+	UserID  uuid.UUID
+	Method  string
+	Path    string
+	IP      string
+	Success bool
+	Reason  string
+}
+
+// AuthEventRecorder records authentication events for the audit trail.
+// Implementations must not block the request.
+type AuthEventRecorder interface {
+	RecordAuthEvent(ctx context.Context, event AuthEvent)
+}
+
+// EnableAuthEvents reports failed authentication and logouts to recorder
+func (m *AuthMiddleware) EnableAuthEvents(recorder AuthEventRecorder) {
+	m.authEvents = recorder
+}
+
+// recordAuthEvent reports an event for the request when a recorder is enabled
+func (m *AuthMiddleware) recordAuthEvent(r *http.Request, eventType string, userID uuid.UUID, success bool, reason string) {
+	if m.authEvents == nil {
+		return
+	}
+	m.authEvents.RecordAuthEvent(r.Context(), AuthEvent{
+		Type:    eventType,
+		UserID:  userID,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		IP:      ClientIP(r),
+		Success: success,
+		Reason:  reason,
+	})
+}
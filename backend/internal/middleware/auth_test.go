@@ -0,0 +1,351 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/auth"
+)
+
+func newTestAuthMiddleware() *AuthMiddleware {
+	cfg := config.Load()
+	return NewAuthMiddleware(cfg, NewDeps(cfg))
+}
+
+func TestAuthenticateRejectsRefreshTokens(t *testing.T) {
+	cfg := config.Load()
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	jwtManager := auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions())
+	userID := uuid.New()
+
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/expenses", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	refresh, _ := jwtManager.GenerateRefreshToken(userID)
+	if code := request(refresh); code != http.StatusUnauthorized {
+		t.Errorf("Refresh token = %d, want 401", code)
+	}
+	if _, err := jwtManager.ValidateAccessToken(refresh); err == nil {
+		t.Error("Expected the refresh token to be rejected as an access token")
+	}
+}
+
+func TestAuthenticateRejectsRevokedTokens(t *testing.T) {
+	server := miniredis.RunT(t)
+	blacklist := auth.NewTokenBlacklistWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	cfg := config.Load()
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	m.EnableTokenRevocation(blacklist)
+	jwtManager := auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions())
+
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/expenses", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	token, _ := jwtManager.GenerateToken(uuid.New(), "test@example.com", auth.RoleUser)
+	claims, _ := jwtManager.ValidateAccessToken(token)
+	if err := blacklist.Revoke(context.Background(), claims.ID, time.Hour); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if code := request(token); code != http.StatusUnauthorized {
+		t.Errorf("Revoked token = %d, want 401", code)
+	}
+
+	everywhere, _ := jwtManager.GenerateToken(claims.UserID, "test@example.com", auth.RoleUser)
+	if err := blacklist.RevokeAllForUser(context.Background(), claims.UserID, time.Hour); err != nil {
+		t.Fatalf("Failed to revoke user tokens: %v", err)
+	}
+	if code := request(everywhere); code != http.StatusUnauthorized {
+		t.Errorf("Token revoked everywhere = %d, want 401", code)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	server := miniredis.RunT(t)
+	blacklist := auth.NewTokenBlacklistWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	m := newTestAuthMiddleware()
+
+	tokenID := uuid.NewString()
+	ctx := context.WithValue(context.Background(), "token_id", tokenID)
+	ctx = context.WithValue(ctx, "token_expires_at", time.Now().Add(time.Hour))
+	ctx = context.WithValue(ctx, "user_id", uuid.NewString())
+	logout := func(handler http.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil).WithContext(ctx))
+		return rec.Code
+	}
+
+	if code := logout(m.Logout); code != http.StatusNotImplemented {
+		t.Errorf("Logout without revocation = %d, want 501", code)
+	}
+
+	m.EnableTokenRevocation(blacklist)
+	if code := logout(m.Logout); code != http.StatusNoContent {
+		t.Fatalf("Logout = %d, want 204", code)
+	}
+	if revoked, _ := blacklist.IsRevoked(context.Background(), tokenID); !revoked {
+		t.Error("Expected logout to revoke the token")
+	}
+	if code := logout(m.LogoutEverywhere); code != http.StatusNoContent {
+		t.Errorf("LogoutEverywhere = %d, want 204", code)
+	}
+}
+
+// authEventLog is an AuthEventRecorder keeping events in memory
+type authEventLog []AuthEvent
+
+func (l *authEventLog) RecordAuthEvent(ctx context.Context, event AuthEvent) {
+	*l = append(*l, event)
+}
+
+func TestAuthEvents(t *testing.T) {
+	server := miniredis.RunT(t)
+	m := newTestAuthMiddleware()
+	m.logger.SetOutput(&strings.Builder{})
+	m.EnableTokenRevocation(auth.NewTokenBlacklistWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()})))
+	var events authEventLog
+	m.EnableAuthEvents(&events)
+
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, header := range []string{"", "Bearer invalid"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	userID := uuid.New()
+	ctx := context.WithValue(context.Background(), "token_id", uuid.NewString())
+	ctx = context.WithValue(ctx, "token_expires_at", time.Now().Add(time.Hour))
+	ctx = context.WithValue(ctx, "user_id", userID.String())
+	m.Logout(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil).WithContext(ctx))
+
+	if len(events) != 3 {
+		t.Fatalf("events = %+v", events)
+	}
+	if events[0].Type != AuthEventFailed || events[0].Success || events[0].Reason != "missing_token" || events[0].IP != "192.0.2.1" {
+		t.Errorf("missing token event = %+v", events[0])
+	}
+	if events[1].Reason != "invalid_token" {
+		t.Errorf("invalid token event = %+v", events[1])
+	}
+	if events[2].Type != AuthEventLogout || !events[2].Success || events[2].UserID != userID {
+		t.Errorf("logout event = %+v", events[2])
+	}
+}
+
+func TestRoleClaimsEnforced(t *testing.T) {
+	cfg := config.Load()
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	jwtManager := auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(wrapped http.Handler, role string, permissions ...string) int {
+		token, err := jwtManager.GenerateToken(uuid.New(), "test@example.com", role, permissions...)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		m.Authenticate(wrapped).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name        string
+		handler     http.Handler
+		role        string
+		permissions []string
+		want        int
+	}{
+		{"admin passes RequireAdmin", m.RequireAdmin(ok), auth.RoleAdmin, nil, http.StatusOK},
+		{"user fails RequireAdmin", m.RequireAdmin(ok), auth.RoleUser, nil, http.StatusForbidden},
+		{"empty role is a user", m.RequireRole(auth.RoleUser)(ok), "", nil, http.StatusOK},
+		{"custom role", m.RequireRole("support")(ok), "support", nil, http.StatusOK},
+		{"wrong custom role", m.RequireRole("support")(ok), auth.RoleAdmin, nil, http.StatusForbidden},
+		{"granted permission", m.RequirePermission("reports:read")(ok), auth.RoleUser, []string{"reports:read"}, http.StatusOK},
+		{"missing permission", m.RequirePermission("reports:write")(ok), auth.RoleUser, []string{"reports:read"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if code := request(tt.handler, tt.role, tt.permissions...); code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
+
+func TestRequireMFA(t *testing.T) {
+	cfg := config.Load()
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	jwtManager := auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions())
+	handler := m.Authenticate(m.RequireMFA(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	userID := uuid.New()
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/export", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	single, _ := jwtManager.GenerateToken(userID, "test@example.com", auth.RoleUser)
+	rec := request(single)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), ReasonMFARequired) {
+		t.Errorf("Single factor token = %d %s, want 403 %s", rec.Code, rec.Body.String(), ReasonMFARequired)
+	}
+
+	mfa, _ := jwtManager.GenerateMFAToken(userID, "test@example.com", auth.RoleUser)
+	if rec := request(mfa); rec.Code != http.StatusOK {
+		t.Errorf("MFA token = %d, want 200", rec.Code)
+	}
+}
+
+func TestRequireUser(t *testing.T) {
+	m := newTestAuthMiddleware()
+	handler := m.RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	userID := uuid.New()
+
+	request := func(path string, value interface{}) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if value != nil {
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", value))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		value interface{}
+		want  int
+	}{
+		{"own resource", "/api/v1/users/" + userID.String() + "/profile", userID.String(), http.StatusOK},
+		{"uuid in context", "/api/v1/users/" + userID.String(), userID, http.StatusOK},
+		{"upper case path", "/api/v1/users/" + strings.ToUpper(userID.String()), userID.String(), http.StatusOK},
+		{"another user", "/api/v1/users/" + uuid.NewString(), userID, http.StatusForbidden},
+		{"invalid path id", "/api/v1/users/me", userID, http.StatusForbidden},
+		{"no users segment", "/api/v1/expenses", userID, http.StatusOK},
+		{"missing user", "/api/v1/users/" + userID.String(), nil, http.StatusUnauthorized},
+		{"unexpected type", "/api/v1/users/" + userID.String(), 42, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code := request(tt.path, tt.value); code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
+
+func TestRequireOwnership(t *testing.T) {
+	m := newTestAuthMiddleware()
+	userID, otherID := uuid.New(), uuid.New()
+	owned, foreign, broken := uuid.New(), uuid.New(), uuid.New()
+	owners := map[uuid.UUID]uuid.UUID{owned: userID, foreign: otherID}
+
+	var loaded []uuid.UUID
+	loader := func(ctx context.Context, resourceID uuid.UUID) (uuid.UUID, error) {
+		loaded = append(loaded, resourceID)
+		if resourceID == broken {
+			return uuid.Nil, errors.New("database unavailable")
+		}
+		owner, ok := owners[resourceID]
+		if !ok {
+			return uuid.Nil, ErrResourceNotFound
+		}
+		return owner, nil
+	}
+	handler := m.RequireOwnership(3, loader)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", userID.String()))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"owned", "/api/v1/expenses/" + owned.String(), http.StatusOK},
+		{"nested route", "/api/v1/expenses/" + owned.String() + "/receipt", http.StatusOK},
+		{"another user's resource", "/api/v1/expenses/" + foreign.String(), http.StatusNotFound},
+		{"missing", "/api/v1/expenses/" + uuid.NewString(), http.StatusNotFound},
+		{"invalid id", "/api/v1/expenses/latest", http.StatusNotFound},
+		{"short path", "/api/v1/expenses", http.StatusNotFound},
+		{"loader error", "/api/v1/expenses/" + broken.String(), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if code := request(tt.path); code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, code, tt.want)
+		}
+	}
+	if len(loaded) == 0 || loaded[0] != owned {
+		t.Errorf("Expected the loader to receive the path resource ID, got %v", loaded)
+	}
+}
+
+func TestErrorResponsesAreJSON(t *testing.T) {
+	m := newTestAuthMiddleware()
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/expenses", nil))
+
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusUnauthorized || body.Error.Code != "unauthorized" || body.Error.Message == "" {
+		t.Errorf("Unexpected error response %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/logger"
+)
+
+// TokenManager validates access tokens; *auth.JWTManager implements it
+type TokenManager interface {
+	ValidateAccessToken(tokenString string) (*auth.Claims, error)
+	// RefreshExpiry bounds how long revoking all of a user's tokens must last
+	RefreshExpiry() time.Duration
+}
+
+// Deps are the dependencies shared by the middleware, so every middleware
+// logs through the same logger and tests can substitute doubles
+type Deps struct {
+	Logger *logger.Logger
+	Tokens TokenManager
+	// Blacklist enables token revocation when set
+	Blacklist *auth.TokenBlacklist
+}
+
+// NewDeps builds the default dependencies from config, without revocation
+func NewDeps(cfg *config.Config) Deps {
+	return Deps{
+		Logger: logger.NewWithFile(cfg.Log.Level, cfg.Log.Format, cfg.Log.Output, cfg.Log.TimeFormat, cfg.Log.FileOptions()),
+		Tokens: auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions()),
+	}
+}
+
+// Chain composes middleware, the first added being the outermost
+type Chain struct {
+	middleware []func(http.Handler) http.Handler
+}
+
+// NewChain creates a chain of the middleware
+func NewChain(middleware ...func(http.Handler) http.Handler) Chain {
+	return Chain{middleware: append([]func(http.Handler) http.Handler(nil), middleware...)}
+}
+
+// Use returns a new chain with the middleware appended; c is left unchanged
+// so route groups can extend a shared base chain
+func (c Chain) Use(middleware ...func(http.Handler) http.Handler) Chain {
+	combined := make([]func(http.Handler) http.Handler, 0, len(c.middleware)+len(middleware))
+	combined = append(combined, c.middleware...)
+	return Chain{middleware: append(combined, middleware...)}
+}
+
+// Then wraps h in the chain
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		h = c.middleware[i](h)
+	}
+	return h
+}
+
+// ThenFunc wraps f in the chain
+func (c Chain) ThenFunc(f http.HandlerFunc) http.Handler {
+	return c.Then(f)
+}
+
+// NewDefaultChain assembles the standard middleware: request ID first so
+// every log line carries it, then panic recovery, access logging, CORS, the
+// body size limit and authentication. The auth middleware is returned for
+// route-specific checks such as RequireRole.
+func NewDefaultChain(cfg *config.Config, deps Deps) (Chain, *AuthMiddleware) {
+	authMiddleware := NewAuthMiddleware(cfg, deps)
+	return NewChain(
+		RequestID,
+		Recovery(deps.Logger),
+		Logging(deps.Logger, cfg.Log.AccessLogSkipPaths),
+		CORS(cfg.Server.CORSAllowedOrigins),
+		BodyLimit(cfg.Server.MaxBodyBytes),
+		authMiddleware.Authenticate,
+	), authMiddleware
+}
@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/logger"
+)
+
+// fakeTokens is a TokenManager double returning canned results
+type fakeTokens struct {
+	claims *auth.Claims
+	err    error
+}
+
+func (f *fakeTokens) ValidateAccessToken(tokenString string) (*auth.Claims, error) {
+	return f.claims, f.err
+}
+
+func (f *fakeTokens) RefreshExpiry() time.Duration { return time.Hour }
+
+func testDeps(tokens TokenManager) (Deps, *bytes.Buffer) {
+	var logs bytes.Buffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	return Deps{Logger: log, Tokens: tokens}, &logs
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	base := NewChain(tag("a"), tag("b"))
+	extended := base.Use(tag("c"))
+	other := base.Use(tag("d"))
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { order = append(order, "handler") })
+
+	for chain, want := range map[*Chain]string{&base: "a,b,handler", &extended: "a,b,c,handler", &other: "a,b,d,handler"} {
+		order = nil
+		chain.ThenFunc(final).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := strings.Join(order, ","); got != want {
+			t.Errorf("order = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestAuthenticateWithTokenManagerDouble(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		name   string
+		tokens *fakeTokens
+		want   int
+	}{
+		{"valid", &fakeTokens{claims: &auth.Claims{UserID: userID, Role: auth.RoleUser}}, http.StatusOK},
+		{"expired", &fakeTokens{err: fmt.Errorf("token is invalid: %w", jwt.ErrTokenExpired)}, http.StatusUnauthorized},
+		{"bad signature", &fakeTokens{err: jwt.ErrTokenSignatureInvalid}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, logs := testDeps(tt.tokens)
+			m := NewAuthMiddleware(config.Load(), deps)
+			var seen uuid.UUID
+			handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen, _ = GetUserIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+			req.Header.Set("Authorization", "Bearer anything")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && seen != userID {
+				t.Errorf("handler saw user %s", seen)
+			}
+			if tt.tokens.err != nil && !strings.Contains(logs.String(), "Failed to validate token") {
+				t.Errorf("failure not logged through the injected logger: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestAuthenticateMasksEmail(t *testing.T) {
+	deps, logs := testDeps(&fakeTokens{claims: &auth.Claims{UserID: uuid.New(), Email: "tushar@example.com", Role: auth.RoleUser}})
+	m := NewAuthMiddleware(config.Load(), deps)
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+	req.Header.Set("Authorization", "Bearer anything")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if strings.Contains(raw, "User authenticated successfully") {
+			if err := json.Unmarshal([]byte(raw), &line); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if line == nil {
+		t.Fatalf("authentication not logged: %s", logs.String())
+	}
+	if line["email"] != "t***@example.com" {
+		t.Errorf("email = %v, want it masked", line["email"])
+	}
+	if strings.Contains(logs.String(), "tushar@example.com") {
+		t.Errorf("raw email logged: %s", logs.String())
+	}
+}
+
+func TestDefaultChain(t *testing.T) {
+	cfg := config.Load()
+	cfg.Server.CORSAllowedOrigins = []string{"https://app.example.com"}
+	cfg.Server.MaxBodyBytes = 8
+	deps, logs := testDeps(&fakeTokens{claims: &auth.Claims{UserID: uuid.New(), Role: auth.RoleUser}})
+	chain, _ := NewDefaultChain(cfg, deps)
+	handler := chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/panic" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Preflight is answered by CORS before authentication
+	preflight := httptest.NewRequest(http.MethodOptions, "/api/v1/goals", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	rec := serve(preflight)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		rec.Header().Get(RequestIDHeader) == "" {
+		t.Errorf("preflight = %d %v", rec.Code, rec.Header())
+	}
+
+	// Unauthenticated requests are rejected, oversized bodies before that
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d", rec.Code)
+	}
+	big := httptest.NewRequest(http.MethodPost, "/api/v1/goals", strings.NewReader("0123456789"))
+	if rec := serve(big); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body status = %d", rec.Code)
+	}
+
+	// Panics become a logged 500 carrying the request ID
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/panic", nil)
+	req.Header.Set("Authorization", "Bearer anything")
+	req.Header.Set(RequestIDHeader, "panic-1")
+	if rec := serve(req); rec.Code != http.StatusInternalServerError {
+		t.Errorf("panic status = %d", rec.Code)
+	}
+	if !strings.Contains(logs.String(), `"request_id":"panic-1"`) || !strings.Contains(logs.String(), "Recovered from panic: boom") {
+		t.Errorf("panic not logged with request ID: %s", logs.String())
+	}
+}
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	request := func(origins []string, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		CORS(origins)(ok).ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request([]string{"https://app.example.com"}, "https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("unknown origin should not be allowed")
+	}
+	if rec := request([]string{"https://app.example.com"}, "https://app.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("listed origin should get credentials: %v", rec.Header())
+	}
+	if rec := request([]string{"*"}, "https://any.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "*" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("wildcard must not allow credentials: %v", rec.Header())
+	}
+	if rec := request([]string{"*"}, ""); rec.Header().Get("Vary") != "" || rec.Code != http.StatusOK {
+		t.Error("same-origin requests pass through untouched")
+	}
+}
+
+func TestRecoveryReraisesAbort(t *testing.T) {
+	deps, _ := testDeps(nil)
+	handler := Recovery(deps.Logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,312 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/httputil"
+)
+
+// DefaultTier is used for users without a role
+const DefaultTier = "user"
+
+// LeaseStore tracks expiring leases per key so concurrent holders can be
+// counted across instances
+type LeaseStore interface {
+	// Acquire adds a lease if fewer than limit unexpired leases are held
+	Acquire(ctx context.Context, key, leaseID string, limit int, ttl time.Duration) (bool, error)
+	// Renew extends a held lease
+	Renew(ctx context.Context, key, leaseID string, ttl time.Duration) error
+	// Release removes a lease
+	Release(ctx context.Context, key, leaseID string) error
+}
+
+// ConcurrencyStats reports heavy operation usage for a user tier
+type ConcurrencyStats struct {
+	Holders    int64 `json:"holders"`
+	Rejections int64 `json:"rejections"`
+}
+
+// ConcurrencyLimiter caps how many heavy operations (reports, exports,
+// projections) a user may run at once. Each operation holds a lease that is
+// renewed while it runs and expires on its own if the process dies.
+type ConcurrencyLimiter struct {
+	store        LeaseStore
+	defaultLimit int
+	ttl          time.Duration
+	retryAfter   time.Duration
+	clock        clock.Clock
+
+	mu         sync.Mutex
+	tierLimits map[string]int
+	stats      map[string]*ConcurrencyStats
+}
+
+// NewConcurrencyLimiter creates a limiter allowing limit concurrent heavy
+// operations per user. ttl bounds how long a crashed request holds its lease.
+func NewConcurrencyLimiter(store LeaseStore, limit int, ttl time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		store:        store,
+		defaultLimit: limit,
+		ttl:          ttl,
+		retryAfter:   5 * time.Second,
+		clock:        clock.Real(),
+		tierLimits:   make(map[string]int),
+		stats:        make(map[string]*ConcurrencyStats),
+	}
+}
+
+// SetTierLimit overrides the concurrency limit for a user tier
+func (l *ConcurrencyLimiter) SetTierLimit(tier string, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tierLimits[tier] = limit
+}
+
+// SetClock sets the clock driving lease renewal
+func (l *ConcurrencyLimiter) SetClock(c clock.Clock) {
+	l.clock = c
+}
+
+// SetRetryAfter sets the delay suggested to rejected clients
+func (l *ConcurrencyLimiter) SetRetryAfter(d time.Duration) {
+	l.retryAfter = d
+}
+
+// Stats returns a snapshot of holders and rejections per tier
+func (l *ConcurrencyLimiter) Stats() map[string]ConcurrencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := make(map[string]ConcurrencyStats, len(l.stats))
+	for tier, stats := range l.stats {
+		snapshot[tier] = *stats
+	}
+	return snapshot
+}
+
+// Acquire takes a lease for a heavy operation. The returned release function
+// must be called when the operation finishes; the lease is also released if
+// ctx is cancelled. ok is false when the user's budget is exhausted.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, userID uuid.UUID, tier string) (release func(), ok bool, err error) {
+	if tier == "" {
+		tier = DefaultTier
+	}
+	key := "concurrency:" + userID.String()
+	leaseID := uuid.NewString()
+
+	ok, err = l.store.Acquire(ctx, key, leaseID, l.limitFor(tier), l.ttl)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	if !ok {
+		l.record(tier, func(s *ConcurrencyStats) { s.Rejections++ })
+		return nil, false, nil
+	}
+	l.record(tier, func(s *ConcurrencyStats) { s.Holders++ })
+
+	done := make(chan struct{})
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			close(done)
+			// Release with a fresh context so a cancelled request still frees its lease
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			l.store.Release(releaseCtx, key, leaseID)
+			l.record(tier, func(s *ConcurrencyStats) { s.Holders-- })
+		})
+	}
+
+	go l.watch(ctx, key, leaseID, done, release)
+	return release, true, nil
+}
+
+// watch renews the lease while the operation runs and releases it when the
+// request context ends
+func (l *ConcurrencyLimiter) watch(ctx context.Context, key, leaseID string, done <-chan struct{}, release func()) {
+	ticker := l.clock.NewTicker(l.ttl / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			release()
+			return
+		case <-ticker.C():
+			l.store.Renew(context.Background(), key, leaseID, l.ttl)
+		}
+	}
+}
+
+// Limit middleware rejects heavy requests with 429 when the authenticated
+// user already runs the maximum number of heavy operations. Requests are let
+// through if the lease store is unavailable.
+func (l *ConcurrencyLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := GetUserIDFromContext(r.Context())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tier, _ := GetUserRoleFromContext(r.Context())
+
+		release, ok, err := l.Acquire(r.Context(), userID, tier)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(l.retryAfter.Seconds()))))
+			httputil.WriteError(w, http.StatusTooManyRequests, httputil.CodeForStatus(http.StatusTooManyRequests), "Too many concurrent heavy requests")
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitFor returns the concurrency limit of a tier
+func (l *ConcurrencyLimiter) limitFor(tier string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit, ok := l.tierLimits[tier]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// record updates the stats of a tier
+func (l *ConcurrencyLimiter) record(tier string, update func(*ConcurrencyStats)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats, ok := l.stats[tier]
+	if !ok {
+		stats = &ConcurrencyStats{}
+		l.stats[tier] = stats
+	}
+	update(stats)
+}
+
+// MemoryLeaseStore keeps leases in process memory, for single instances and tests
+type MemoryLeaseStore struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	leases map[string]map[string]time.Time
+}
+
+// NewMemoryLeaseStore creates an in-memory lease store
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{clock: clock.Real(), leases: make(map[string]map[string]time.Time)}
+}
+
+// SetClock sets the clock used to expire leases
+func (s *MemoryLeaseStore) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Acquire adds a lease if fewer than limit unexpired leases are held
+func (s *MemoryLeaseStore) Acquire(ctx context.Context, key, leaseID string, limit int, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	held := s.leases[key]
+	for id, expires := range held {
+		if !expires.After(now) {
+			delete(held, id)
+		}
+	}
+	if len(held) >= limit {
+		return false, nil
+	}
+	if held == nil {
+		held = make(map[string]time.Time)
+		s.leases[key] = held
+	}
+	held[leaseID] = now.Add(ttl)
+	return true, nil
+}
+
+// Renew extends a held lease
+func (s *MemoryLeaseStore) Renew(ctx context.Context, key, leaseID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leases[key][leaseID]; ok {
+		s.leases[key][leaseID] = s.clock.Now().Add(ttl)
+	}
+	return nil
+}
+
+// Release removes a lease
+func (s *MemoryLeaseStore) Release(ctx context.Context, key, leaseID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.leases[key], leaseID)
+	if len(s.leases[key]) == 0 {
+		delete(s.leases, key)
+	}
+	return nil
+}
+
+// acquireLeaseScript drops expired leases and adds one if under the limit.
+// Leases are members of a sorted set scored by their expiry in milliseconds.
+var acquireLeaseScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return 1
+`)
+
+// RedisLeaseStore keeps leases in Redis so limits hold across instances
+type RedisLeaseStore struct {
+	client redis.UniversalClient
+	now    func() time.Time
+}
+
+// NewRedisLeaseStore creates a Redis-backed lease store
+func NewRedisLeaseStore(client redis.UniversalClient) *RedisLeaseStore {
+	return &RedisLeaseStore{client: client, now: time.Now}
+}
+
+// Acquire adds a lease if fewer than limit unexpired leases are held
+func (s *RedisLeaseStore) Acquire(ctx context.Context, key, leaseID string, limit int, ttl time.Duration) (bool, error) {
+	now := s.now()
+	acquired, err := acquireLeaseScript.Run(ctx, s.client, []string{key},
+		now.UnixMilli(), limit, now.Add(ttl).UnixMilli(), leaseID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+// Renew extends a held lease
+func (s *RedisLeaseStore) Renew(ctx context.Context, key, leaseID string, ttl time.Duration) error {
+	expires := s.now().Add(ttl)
+	pipe := s.client.TxPipeline()
+	pipe.ZAddXX(ctx, key, redis.Z{Score: float64(expires.UnixMilli()), Member: leaseID})
+	pipe.PExpire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Release removes a lease
+func (s *RedisLeaseStore) Release(ctx context.Context, key, leaseID string) error {
+	return s.client.ZRem(ctx, key, leaseID).Err()
+}
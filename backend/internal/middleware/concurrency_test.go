@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/pkg/clock"
+)
+
+func requestAs(userID uuid.UUID, role string) *http.Request {
+	ctx := context.WithValue(context.Background(), "user_id", userID.String())
+	ctx = context.WithValue(ctx, "user_role", role)
+	return httptest.NewRequest(http.MethodGet, "/api/v1/reports/monthly", nil).WithContext(ctx)
+}
+
+func TestConcurrencyLimiterMiddleware(t *testing.T) {
+	limiter := NewConcurrencyLimiter(NewMemoryLeaseStore(), 1, time.Minute)
+	userID := uuid.New()
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	handler := limiter.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			close(started)
+			<-finish
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		req := requestAs(userID, "user")
+		req.URL.RawQuery = "slow=1"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestAs(userID, "user"))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("second request = %d retry-after %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Other users have their own budget
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestAs(uuid.New(), "user"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("other user = %d, want 200", rec.Code)
+	}
+
+	close(finish)
+	wg.Wait()
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestAs(userID, "user"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("request after release = %d, want 200", rec.Code)
+	}
+
+	stats := limiter.Stats()["user"]
+	if stats.Rejections != 1 || stats.Holders != 0 {
+		t.Errorf("stats = %+v, want 1 rejection and no holders", stats)
+	}
+}
+
+func TestConcurrencyLimiterTierLimitAndCancellation(t *testing.T) {
+	limiter := NewConcurrencyLimiter(NewMemoryLeaseStore(), 1, time.Minute)
+	limiter.SetTierLimit("premium", 2)
+	userID := uuid.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, ok, _ := limiter.Acquire(ctx, userID, "premium"); !ok {
+		t.Fatal("first premium lease should be granted")
+	}
+	release, ok, _ := limiter.Acquire(context.Background(), userID, "premium")
+	if !ok {
+		t.Fatal("second premium lease should be granted")
+	}
+	if _, ok, _ := limiter.Acquire(context.Background(), userID, "premium"); ok {
+		t.Fatal("third premium lease should be rejected")
+	}
+
+	// Cancelling the request context frees its lease without an explicit release
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if r, ok, _ := limiter.Acquire(context.Background(), userID, "premium"); ok {
+			r()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lease was not released on cancellation")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	release()
+	release()
+	if holders := limiter.Stats()["premium"].Holders; holders != 0 {
+		t.Errorf("holders = %d, want 0 after double release", holders)
+	}
+}
+
+func TestMemoryLeaseStoreExpiry(t *testing.T) {
+	store := NewMemoryLeaseStore()
+	fake := clock.NewFake(time.Now())
+	store.SetClock(fake)
+	ctx := context.Background()
+
+	store.Acquire(ctx, "k", "crashed", 1, time.Minute)
+	if ok, _ := store.Acquire(ctx, "k", "next", 1, time.Minute); ok {
+		t.Fatal("lease should still be held")
+	}
+
+	fake.Advance(2 * time.Minute)
+	if ok, _ := store.Acquire(ctx, "k", "next", 1, time.Minute); !ok {
+		t.Error("expired lease of a crashed request should not count")
+	}
+}
+
+func TestConcurrencyLimiterRenewsLeases(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	store := NewMemoryLeaseStore()
+	store.SetClock(fake)
+	limiter := NewConcurrencyLimiter(store, 1, time.Minute)
+	limiter.SetClock(fake)
+	userID := uuid.New()
+
+	release, ok, _ := limiter.Acquire(context.Background(), userID, "user")
+	if !ok {
+		t.Fatal("lease should be granted")
+	}
+	defer release()
+
+	// The watchdog renews every ttl/2, so a long operation keeps its lease
+	key := "concurrency:" + userID.String()
+	fake.BlockUntil(1)
+	for i := 0; i < 4; i++ {
+		fake.Advance(30 * time.Second)
+		want := fake.Now().Add(time.Minute)
+		deadline := time.Now().Add(time.Second)
+		for !leaseExpiry(store, key).Equal(want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("lease was not renewed at %v", fake.Now())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if _, ok, _ := limiter.Acquire(context.Background(), userID, "user"); ok {
+		t.Error("renewed lease should still be held after 2 minutes")
+	}
+}
+
+// leaseExpiry returns the expiry of the only lease held under key
+func leaseExpiry(store *MemoryLeaseStore, key string) time.Time {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, expires := range store.leases[key] {
+		return expires
+	}
+	return time.Time{}
+}
+
+func TestRedisLeaseStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	store := NewRedisLeaseStore(client)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, lease := range []string{"a", "b"} {
+		if ok, err := store.Acquire(ctx, "concurrency:u", lease, 2, time.Minute); err != nil || !ok {
+			t.Fatalf("Acquire(%s) = %v, %v", lease, ok, err)
+		}
+	}
+	if ok, _ := store.Acquire(ctx, "concurrency:u", "c", 2, time.Minute); ok {
+		t.Fatal("third lease should be rejected")
+	}
+
+	if err := store.Release(ctx, "concurrency:u", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Acquire(ctx, "concurrency:u", "c", 2, time.Minute); !ok {
+		t.Fatal("lease should be granted after release")
+	}
+
+	// Renewed leases survive past their original expiry, others lapse
+	now = now.Add(45 * time.Second)
+	if err := store.Renew(ctx, "concurrency:u", "b", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(30 * time.Second)
+	if ok, _ := store.Acquire(ctx, "concurrency:u", "d", 2, time.Minute); !ok {
+		t.Fatal("expired lease c should not count")
+	}
+	if ok, _ := store.Acquire(ctx, "concurrency:u", "e", 2, time.Minute); ok {
+		t.Error("renewed lease b should still count")
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"tgfinance/internal/config"
+)
+
+// CSRFHeader carries the double-submit CSRF token
+const CSRFHeader = "X-CSRF-Token"
+
+// ReasonCSRFInvalid is the error reason when a cookie-authenticated request
+// lacks a matching CSRF token
+const ReasonCSRFInvalid = "csrf_token_invalid"
+
+// cookieSettings are the attributes of the auth and CSRF cookies
+type cookieSettings struct {
+	name     string
+	csrfName string
+	domain   string
+	secure   bool
+	sameSite http.SameSite
+}
+
+// newCookieSettings reads the cookie attributes from config
+func newCookieSettings(cfg config.AuthConfig) cookieSettings {
+	settings := cookieSettings{
+		name:     cfg.CookieName,
+		csrfName: cfg.CSRFCookieName,
+		domain:   cfg.CookieDomain,
+		secure:   cfg.CookieSecure,
+		sameSite: http.SameSiteLaxMode,
+	}
+	switch strings.ToLower(cfg.CookieSameSite) {
+	case "strict":
+		settings.sameSite = http.SameSiteStrictMode
+	case "none":
+		// Browsers drop SameSite=None cookies that are not Secure
+		settings.sameSite = http.SameSiteNoneMode
+		settings.secure = true
+	}
+	return settings
+}
+
+// cookie returns a cookie with the configured attributes
+func (s cookieSettings) cookie(name, value string, expires time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   s.domain,
+		Expires:  expires,
+		Secure:   s.secure,
+		HttpOnly: httpOnly,
+		SameSite: s.sameSite,
+	}
+}
+
+// SetAuthCookie stores the access token in an httpOnly cookie expiring with it
+func (m *AuthMiddleware) SetAuthCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	http.SetCookie(w, m.cookies.cookie(m.cookies.name, token, expiresAt, true))
+}
+
+// ClearAuthCookie removes the auth cookie, signing the browser out
+func (m *AuthMiddleware) ClearAuthCookie(w http.ResponseWriter) {
+	cookie := m.cookies.cookie(m.cookies.name, "", time.Unix(0, 0), true)
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// isCookieAuthenticated returns true if the request authenticates with the
+// auth cookie rather than the Authorization header
+func (m *AuthMiddleware) isCookieAuthenticated(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+	cookie, err := r.Cookie(m.cookies.name)
+	return err == nil && cookie.Value != ""
+}
+
+// CSRF middleware applies double-submit CSRF protection to cookie-
+// authenticated requests. Clients without a CSRF cookie are issued one, which
+// scripts read and echo in the X-CSRF-Token header of mutating requests.
+// Requests with an Authorization header are exempt, since browsers never
+// attach it on their own.
+func (m *AuthMiddleware) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(m.cookies.csrfName)
+		if err != nil || cookie.Value == "" {
+			token, err := newCSRFToken()
+			if err != nil {
+				m.log(r.Context()).WithError(err).Error("Failed to generate CSRF token")
+				m.sendErrorResponse(w, http.StatusInternalServerError, "Failed to generate CSRF token")
+				return
+			}
+			// Readable by scripts so they can submit it back
+			http.SetCookie(w, m.cookies.cookie(m.cookies.csrfName, token, time.Time{}, false))
+			cookie = &http.Cookie{}
+		}
+
+		if isMutating(r.Method) && m.isCookieAuthenticated(r) {
+			header := r.Header.Get(CSRFHeader)
+			if header == "" || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+				m.log(r.Context()).Warn("Rejected cookie-authenticated request without a valid CSRF token")
+				m.sendErrorReason(w, http.StatusForbidden, ReasonCSRFInvalid, "Missing or invalid CSRF token")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isMutating returns true for methods that may change state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return true
+}
+
+// newCSRFToken returns a random CSRF token
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
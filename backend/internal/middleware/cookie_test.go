@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/auth"
+)
+
+func newCookieTestMiddleware(t *testing.T) (*AuthMiddleware, string) {
+	t.Helper()
+	cfg := config.Load()
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	m.logger.SetOutput(&bytes.Buffer{})
+	token, err := auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions()).GenerateToken(uuid.New(), "test@example.com", auth.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, token
+}
+
+func TestAuthenticateTokenSources(t *testing.T) {
+	m, token := newCookieTestMiddleware(t)
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+		want   int
+	}{
+		{"header", "Bearer " + token, "", http.StatusOK},
+		{"cookie", "", token, http.StatusOK},
+		{"header wins over cookie", "Bearer invalid", token, http.StatusUnauthorized},
+		{"empty cookie", "", "", http.StatusUnauthorized},
+		{"invalid cookie", "", "invalid", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthCookieAttributes(t *testing.T) {
+	m, token := newCookieTestMiddleware(t)
+	expires := time.Now().Add(time.Hour)
+
+	rec := httptest.NewRecorder()
+	m.SetAuthCookie(rec, token, expires)
+	cookie := rec.Result().Cookies()[0]
+	if cookie.Name != "access_token" || cookie.Value != token || !cookie.HttpOnly || !cookie.Secure ||
+		cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/" {
+		t.Errorf("auth cookie = %+v", cookie)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ClearAuthCookie(rec)
+	cleared := rec.Result().Cookies()[0]
+	if cleared.Value != "" || cleared.MaxAge >= 0 {
+		t.Errorf("cleared cookie = %+v", cleared)
+	}
+
+	cfg := config.Load()
+	cfg.Auth.CookieSecure = false
+	cfg.Auth.CookieSameSite = "None"
+	if settings := newCookieSettings(cfg.Auth); settings.sameSite != http.SameSiteNoneMode || !settings.secure {
+		t.Errorf("SameSite=None must force Secure: %+v", settings)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	m, token := newCookieTestMiddleware(t)
+	handler := m.CSRF(m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	// A first request issues a script-readable CSRF cookie
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d", rec.Code)
+	}
+	var csrf *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "csrf_token" {
+			csrf = c
+		}
+	}
+	if csrf == nil || csrf.Value == "" || csrf.HttpOnly {
+		t.Fatalf("CSRF cookie = %+v", csrf)
+	}
+
+	tests := []struct {
+		name   string
+		bearer bool
+		cookie bool
+		header string
+		want   int
+		reason string
+	}{
+		{"cookie auth with token", false, true, csrf.Value, http.StatusOK, ""},
+		{"cookie auth without token", false, true, "", http.StatusForbidden, ReasonCSRFInvalid},
+		{"cookie auth with wrong token", false, true, "forged", http.StatusForbidden, ReasonCSRFInvalid},
+		{"cookie auth without CSRF cookie", false, false, csrf.Value, http.StatusForbidden, ReasonCSRFInvalid},
+		{"header auth is exempt", true, false, "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/goals", nil)
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer "+token)
+			} else {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			}
+			if tt.cookie {
+				req.AddCookie(csrf)
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.reason != "" && errorCode(t, rec) != tt.reason {
+				t.Errorf("error code should be %s", tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/logger"
+)
+
+// FeatureGate serves routes only while their feature flag is on; otherwise
+// they answer 404 as if they did not exist. The flags are swapped atomically,
+// so config reloads take effect without locking requests.
+type FeatureGate struct {
+	flags  atomic.Pointer[config.FeatureFlags]
+	logger *logger.Logger
+}
+
+// NewFeatureGate creates a gate over flags, warning about unknown names
+func NewFeatureGate(flags config.FeatureFlags, log *logger.Logger) *FeatureGate {
+	g := &FeatureGate{logger: log}
+	g.Set(flags)
+	return g
+}
+
+// Watch keeps the gate's flags in step with the watcher's reloads
+func (g *FeatureGate) Watch(w *config.Watcher) {
+	w.OnChange(config.SectionFeatures, func(cfg *config.Config) {
+		g.Set(cfg.Features)
+	})
+}
+
+// Set replaces the flags, warning about names the application does not check
+func (g *FeatureGate) Set(flags config.FeatureFlags) {
+	if unknown := flags.Unknown(); len(unknown) > 0 {
+		g.logger.WithFields(logrus.Fields{
+			"unknown": strings.Join(unknown, ", "),
+			"known":   strings.Join(config.KnownFeatures(), ", "),
+		}).Warn("Unknown feature flags configured")
+	}
+	g.flags.Store(&flags)
+}
+
+// Flags returns the current flags
+func (g *FeatureGate) Flags() config.FeatureFlags {
+	return *g.flags.Load()
+}
+
+// IsEnabled reports whether the feature is currently on
+func (g *FeatureGate) IsEnabled(feature config.Feature) bool {
+	return g.Flags().IsEnabled(feature)
+}
+
+// Require middleware answers 404 unless feature is on
+func (g *FeatureGate) Require(feature config.Feature) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !g.IsEnabled(feature) {
+				httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Resource not found")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireFunc is Require for a single handler function
+func (g *FeatureGate) RequireFunc(feature config.Feature, next http.HandlerFunc) http.HandlerFunc {
+	return g.Require(feature)(next).ServeHTTP
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/logger"
+)
+
+func newTestFeatureGate(flags config.FeatureFlags) (*FeatureGate, *bytes.Buffer) {
+	var logs bytes.Buffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	return NewFeatureGate(flags, log), &logs
+}
+
+func serveGated(handler http.Handler) int {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/budgets", nil))
+	return rec.Code
+}
+
+func TestFeatureGateRequire(t *testing.T) {
+	gate, _ := newTestFeatureGate(config.FeatureFlags{config.FeatureBudgets: true})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	budgets := gate.Require(config.FeatureBudgets)(ok)
+	multiCurrency := gate.RequireFunc(config.FeatureMultiCurrency, ok)
+	if code := serveGated(budgets); code != http.StatusOK {
+		t.Errorf("Expected an enabled feature to be served, got %d", code)
+	}
+	if code := serveGated(multiCurrency); code != http.StatusNotFound {
+		t.Errorf("Expected a disabled feature to be hidden, got %d", code)
+	}
+
+	gate.Set(config.FeatureFlags{config.FeatureMultiCurrency: true})
+	if code := serveGated(budgets); code != http.StatusNotFound {
+		t.Errorf("Expected budgets to be hidden after the flags changed, got %d", code)
+	}
+	if code := serveGated(multiCurrency); code != http.StatusOK {
+		t.Errorf("Expected multi-currency to be served after the flags changed, got %d", code)
+	}
+}
+
+func TestFeatureGateWarnsAboutUnknownFlags(t *testing.T) {
+	_, logs := newTestFeatureGate(config.FeatureFlags{config.FeatureBudgets: true, "bugdets": true})
+	out := logs.String()
+	if !strings.Contains(out, "Unknown feature flags") || !strings.Contains(out, `"unknown":"bugdets"`) ||
+		!strings.Contains(out, `"known":"multi_currency, budgets"`) {
+		t.Errorf("Expected a warning listing unknown and known flags, got %s", out)
+	}
+
+	_, logs = newTestFeatureGate(config.FeatureFlags{config.FeatureBudgets: true})
+	if logs.Len() != 0 {
+		t.Errorf("Expected no warning for known flags, got %s", logs.String())
+	}
+}
+
+func TestFeatureGateReload(t *testing.T) {
+	t.Setenv("FEATURES", "")
+	cfg, err := config.LoadAndValidate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate, _ := newTestFeatureGate(cfg.Features)
+	watcher := config.NewWatcher(cfg, logger.New("error", "json", "stdout", ""))
+	gate.Watch(watcher)
+	handler := gate.Require(config.FeatureBudgets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Requests keep reading the flags while reloads swap them
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					serveGated(handler)
+				}
+			}
+		}()
+	}
+
+	if code := serveGated(handler); code != http.StatusNotFound {
+		t.Errorf("Expected budgets to start hidden, got %d", code)
+	}
+	t.Setenv("FEATURES", "budgets")
+	if err := watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if code := serveGated(handler); code != http.StatusOK {
+		t.Errorf("Expected budgets to be served after the reload, got %d", code)
+	}
+	t.Setenv("FEATURES", "")
+	if err := watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if code := serveGated(handler); code != http.StatusNotFound {
+		t.Errorf("Expected budgets to be hidden after the reload, got %d", code)
+	}
+
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tgfinance/pkg/httputil"
+)
+
+type item struct {
+	ID int `json:"id"`
+}
+
+func streamItems(n int) httputil.Iterator[item] {
+	return func(yield func(*item) error) error {
+		for i := 0; i < n; i++ {
+			if err := yield(&item{ID: i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestGzipCompressesStreams(t *testing.T) {
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httputil.StreamList(w, http.StatusOK, "items", streamItems(1000), nil)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/expenses", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" || !rec.Flushed {
+		t.Fatalf("Expected a flushed gzip response, got headers %v", rec.Header())
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Invalid gzip stream: %v", err)
+	}
+	var body struct{ Items []item }
+	if err := json.Unmarshal(data, &body); err != nil || len(body.Items) != 1000 {
+		t.Errorf("Unexpected body: %d items, %v", len(body.Items), err)
+	}
+}
+
+func TestGzipSkipsClientsWithoutGzip(t *testing.T) {
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	for _, encoding := range []string{"", "gzip;q=0", "identity"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"ok":true}` {
+			t.Errorf("Accept-Encoding %q: unexpected compressed response", encoding)
+		}
+	}
+}
+
+func TestGzipLeavesNotModifiedAlone(t *testing.T) {
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Errorf("Unexpected 304 response: %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,456 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/httputil"
+)
+
+// IdempotencyKeyHeader carries the client-chosen key identifying a request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader marks responses replayed from an earlier request
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// Idempotency rejection reasons
+const (
+	ReasonIdempotencyKeyInvalid  = "idempotency_key_invalid"
+	ReasonIdempotencyKeyInFlight = "idempotency_key_in_flight"
+	ReasonIdempotencyKeyReused   = "idempotency_key_reused"
+	// The request was handled but its response is too large to replay
+	ReasonIdempotencyResponseTooLarge = "idempotency_response_too_large"
+)
+
+// validIdempotencyKey restricts keys to a safe charset and length
+var validIdempotencyKey = regexp.MustCompile(`^[A-Za-z0-9._:-]{8,255}$`)
+
+// idempotencyPollInterval is how often waiting retries check the first request
+const idempotencyPollInterval = 100 * time.Millisecond
+
+// replayedHeaders are the response headers stored for replay
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// StoredResponse is a response kept for replay
+type StoredResponse struct {
+	// Fingerprint identifies the request the response belongs to
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	// Truncated marks a response whose body was over MaxResponseBytes. Only
+	// the fingerprint is kept, so retries learn the request was handled but
+	// cannot see its response.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// IdempotencyStore claims idempotency keys and keeps their responses
+type IdempotencyStore interface {
+	// Begin claims key for owner until lockTTL passes. If a response is
+	// already stored it is returned instead; claimed is false when another
+	// request holds the key.
+	Begin(ctx context.Context, key, owner string, lockTTL time.Duration) (stored *StoredResponse, claimed bool, err error)
+	// Complete stores the response of owner's request for ttl
+	Complete(ctx context.Context, key, owner string, resp *StoredResponse, ttl time.Duration) error
+	// Release gives up owner's claim without storing a response
+	Release(ctx context.Context, key, owner string) error
+}
+
+// Idempotency replays the response of POST, PUT and PATCH requests retried
+// with the same Idempotency-Key, so flaky networks cannot create duplicates.
+// Keys are scoped per user; unauthenticated requests pass through.
+type Idempotency struct {
+	store IdempotencyStore
+	cfg   config.IdempotencyConfig
+	clock clock.Clock
+}
+
+// NewIdempotency creates the idempotency middleware
+func NewIdempotency(store IdempotencyStore, cfg config.IdempotencyConfig) *Idempotency {
+	return &Idempotency{store: store, cfg: cfg, clock: clock.Real()}
+}
+
+// SetClock sets the clock used while waiting for in-flight requests
+func (i *Idempotency) SetClock(c clock.Clock) {
+	i.clock = c
+}
+
+// Handle middleware stores responses of keyed requests and replays them on
+// retries. Retries of an in-flight request get 409, or wait for it when
+// configured. Requests are let through if the store is unavailable.
+func (i *Idempotency) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !isIdempotencyMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, err := GetUserIDFromContext(r.Context())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validIdempotencyKey.MatchString(key) {
+			httputil.WriteError(w, http.StatusBadRequest, ReasonIdempotencyKeyInvalid, "Idempotency-Key must be 8 to 255 letters, digits or ._:-")
+			return
+		}
+
+		fingerprint, err := requestFingerprint(r)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Failed to read request body")
+			return
+		}
+
+		storeKey := "idempotency:" + userID.String() + ":" + key
+		owner := uuid.NewString()
+		stored, claimed, err := i.store.Begin(r.Context(), storeKey, owner, i.cfg.LockTTL)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !claimed && stored == nil && i.cfg.Wait {
+			stored, claimed, err = i.wait(r.Context(), storeKey, owner)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		switch {
+		case stored != nil:
+			replay(w, stored, fingerprint)
+		case !claimed:
+			w.Header().Set("Retry-After", "1")
+			httputil.WriteError(w, http.StatusConflict, ReasonIdempotencyKeyInFlight, "A request with this Idempotency-Key is already in progress")
+		default:
+			i.serve(w, r, next, storeKey, owner, fingerprint)
+		}
+	})
+}
+
+// wait polls until the in-flight request stores its response or releases
+// the key, or the wait times out
+func (i *Idempotency) wait(ctx context.Context, key, owner string) (*StoredResponse, bool, error) {
+	deadline := i.clock.Now().Add(i.cfg.WaitTimeout)
+	for i.clock.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, false, nil
+		case <-i.clock.After(idempotencyPollInterval):
+		}
+		stored, claimed, err := i.store.Begin(ctx, key, owner, i.cfg.LockTTL)
+		if err != nil || stored != nil || claimed {
+			return stored, claimed, err
+		}
+	}
+	return nil, false, nil
+}
+
+// serve runs the request holding the key and stores its response. Server
+// errors and panics release the key so the request can be retried.
+func (i *Idempotency) serve(w http.ResponseWriter, r *http.Request, next http.Handler, key, owner, fingerprint string) {
+	// The claim outlives a cancelled request
+	ctx := context.WithoutCancel(r.Context())
+	completed := false
+	defer func() {
+		if !completed {
+			i.store.Release(ctx, key, owner)
+		}
+	}()
+
+	rw := &replayRecorder{ResponseWriter: w, limit: i.cfg.MaxResponseBytes}
+	next.ServeHTTP(rw, r)
+
+	status := rw.statusCode()
+	if status >= http.StatusInternalServerError {
+		return
+	}
+	resp := &StoredResponse{Fingerprint: fingerprint, Truncated: true}
+	if !rw.truncated {
+		resp = &StoredResponse{Fingerprint: fingerprint, Status: status, Header: http.Header{}, Body: rw.body.Bytes()}
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				resp.Header.Set(name, value)
+			}
+		}
+	}
+	completed = i.store.Complete(ctx, key, owner, resp, i.cfg.TTL) == nil
+}
+
+// replay writes a stored response. Reusing a key for a different request is
+// refused rather than answered with an unrelated response, and a response
+// too large to store gets 409 instead of an empty body.
+func replay(w http.ResponseWriter, stored *StoredResponse, fingerprint string) {
+	if stored.Fingerprint != fingerprint {
+		httputil.WriteError(w, http.StatusUnprocessableEntity, ReasonIdempotencyKeyReused, "Idempotency-Key was already used for a different request")
+		return
+	}
+	if stored.Truncated {
+		httputil.WriteError(w, http.StatusConflict, ReasonIdempotencyResponseTooLarge, "The request with this Idempotency-Key was handled but its response is too large to replay")
+		return
+	}
+	for name, values := range stored.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// requestFingerprint hashes the method, path and body of a request and
+// restores the body for the handler
+func requestFingerprint(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isIdempotencyMethod returns true for methods the middleware applies to
+func isIdempotencyMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// replayRecorder passes a response through while keeping a copy of up to
+// limit body bytes
+type replayRecorder struct {
+	http.ResponseWriter
+	status    int
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *replayRecorder) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *replayRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.truncated {
+		if w.body.Len()+len(b) > w.limit {
+			w.truncated = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *replayRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode returns the status sent, which is 200 when nothing was written
+func (w *replayRecorder) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// idempotencyRecord is the stored state of a key: a claim by owner, or a
+// completed response
+type idempotencyRecord struct {
+	Owner    string          `json:"owner,omitempty"`
+	Response *StoredResponse `json:"response,omitempty"`
+}
+
+// MemoryIdempotencyStore keeps keys in process memory, for single instances
+// and tests
+type MemoryIdempotencyStore struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	records map[string]memoryIdempotencyRecord
+	swept   time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	idempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an in-memory idempotency store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{clock: clock.Real(), records: make(map[string]memoryIdempotencyRecord)}
+}
+
+// SetClock sets the clock used to expire keys
+func (s *MemoryIdempotencyStore) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Begin claims key for owner unless it is held or has a stored response
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, key, owner string, lockTTL time.Duration) (*StoredResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.sweep(now)
+	if record, ok := s.records[key]; ok && record.expires.After(now) {
+		return record.Response, record.Owner == owner, nil
+	}
+	s.records[key] = memoryIdempotencyRecord{idempotencyRecord{Owner: owner}, now.Add(lockTTL)}
+	return nil, true, nil
+}
+
+// Complete stores the response if owner still holds the key
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key, owner string, resp *StoredResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	record := s.records[key]
+	if record.Owner != owner || record.Response != nil || !record.expires.After(now) {
+		return errIdempotencyClaimLost
+	}
+	s.records[key] = memoryIdempotencyRecord{idempotencyRecord{Response: resp}, now.Add(ttl)}
+	return nil
+}
+
+// Release removes owner's claim
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record := s.records[key]; record.Owner == owner && record.Response == nil {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// sweep drops expired keys
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < time.Minute {
+		return
+	}
+	s.swept = now
+
+	for key, record := range s.records {
+		if !record.expires.After(now) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// errIdempotencyClaimLost is returned when a claim expired before completion
+var errIdempotencyClaimLost = errors.New("idempotency key claim was lost")
+
+// beginIdempotencyScript returns the stored record, or claims the key when
+// there is none
+var beginIdempotencyScript = redis.NewScript(`
+local record = redis.call('GET', KEYS[1])
+if record then
+	return record
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return false
+`)
+
+// replaceClaimScript replaces or, without a value, deletes the key if it
+// still holds the owner's claim
+var replaceClaimScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] == '' then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return 1
+`)
+
+// RedisIdempotencyStore keeps keys in Redis so retries are recognized by
+// every instance
+type RedisIdempotencyStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisIdempotencyStore creates a Redis-backed idempotency store
+func NewRedisIdempotencyStore(client redis.UniversalClient) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client}
+}
+
+// Begin claims key for owner unless it is held or has a stored response
+func (s *RedisIdempotencyStore) Begin(ctx context.Context, key, owner string, lockTTL time.Duration) (*StoredResponse, bool, error) {
+	claim, err := claimValue(owner)
+	if err != nil {
+		return nil, false, err
+	}
+	value, err := beginIdempotencyScript.Run(ctx, s.client, []string{key}, claim, lockTTL.Milliseconds()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, false, err
+	}
+	return record.Response, record.Owner == owner, nil
+}
+
+// Complete stores the response if owner still holds the key
+func (s *RedisIdempotencyStore) Complete(ctx context.Context, key, owner string, resp *StoredResponse, ttl time.Duration) error {
+	claim, err := claimValue(owner)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(idempotencyRecord{Response: resp})
+	if err != nil {
+		return err
+	}
+	replaced, err := replaceClaimScript.Run(ctx, s.client, []string{key}, claim, value, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if replaced == 0 {
+		return errIdempotencyClaimLost
+	}
+	return nil
+}
+
+// Release removes owner's claim
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key, owner string) error {
+	claim, err := claimValue(owner)
+	if err != nil {
+		return err
+	}
+	return replaceClaimScript.Run(ctx, s.client, []string{key}, claim, "", 0).Err()
+}
+
+// claimValue returns the stored value of owner's claim
+func claimValue(owner string) (string, error) {
+	value, err := json.Marshal(idempotencyRecord{Owner: owner})
+	return string(value), err
+}
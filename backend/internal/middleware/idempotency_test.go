@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/clock"
+)
+
+// idempotencyStores returns each store implementation with a function
+// advancing its time
+func idempotencyStores(t *testing.T) map[string]func() (IdempotencyStore, func(time.Duration)) {
+	return map[string]func() (IdempotencyStore, func(time.Duration)){
+		"memory": func() (IdempotencyStore, func(time.Duration)) {
+			fake := clock.NewFake(time.Now())
+			store := NewMemoryIdempotencyStore()
+			store.SetClock(fake)
+			return store, fake.Advance
+		},
+		"redis": func() (IdempotencyStore, func(time.Duration)) {
+			server := miniredis.RunT(t)
+			return NewRedisIdempotencyStore(redis.NewClient(&redis.Options{Addr: server.Addr()})), server.FastForward
+		},
+	}
+}
+
+func testIdempotencyConfig() config.IdempotencyConfig {
+	return config.IdempotencyConfig{
+		TTL:              time.Hour,
+		LockTTL:          time.Minute,
+		WaitTimeout:      2 * time.Second,
+		MaxResponseBytes: 1024,
+	}
+}
+
+// keyedRequest returns a POST by userID carrying an Idempotency-Key
+func keyedRequest(userID uuid.UUID, key, body string) *http.Request {
+	ctx := context.WithValue(context.Background(), "user_id", userID.String())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/expenses", strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	return req
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	for name, newStore := range idempotencyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store, advance := newStore()
+			var created atomic.Int32
+			handler := NewIdempotency(store, testIdempotencyConfig()).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := created.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Location", fmt.Sprintf("/api/v1/expenses/%d", n))
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"id":%d}`, n)
+			}))
+			userID := uuid.New()
+			serve := func(req *http.Request) *httptest.ResponseRecorder {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			first := serve(keyedRequest(userID, "retry-key-1", `{"amount":5}`))
+			retry := serve(keyedRequest(userID, "retry-key-1", `{"amount":5}`))
+			if created.Load() != 1 {
+				t.Fatalf("handler ran %d times", created.Load())
+			}
+			if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() ||
+				retry.Header().Get("Location") != "/api/v1/expenses/1" || retry.Header().Get(IdempotentReplayedHeader) != "true" {
+				t.Errorf("replay = %d %q %v", retry.Code, retry.Body.String(), retry.Header())
+			}
+			if first.Header().Get(IdempotentReplayedHeader) != "" {
+				t.Error("first response marked as replayed")
+			}
+
+			// The same key with another body is refused
+			if rec := serve(keyedRequest(userID, "retry-key-1", `{"amount":6}`)); rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != ReasonIdempotencyKeyReused {
+				t.Errorf("reused key = %d", rec.Code)
+			}
+			// Keys are scoped per user
+			serve(keyedRequest(uuid.New(), "retry-key-1", `{"amount":5}`))
+			if created.Load() != 2 {
+				t.Errorf("another user's key was replayed")
+			}
+
+			// Stored responses expire after the TTL
+			advance(time.Hour + time.Second)
+			serve(keyedRequest(userID, "retry-key-1", `{"amount":5}`))
+			if created.Load() != 3 {
+				t.Errorf("expired key was replayed")
+			}
+		})
+	}
+}
+
+func TestIdempotencyResponseTooLarge(t *testing.T) {
+	for name, newStore := range idempotencyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store, _ := newStore()
+			var created atomic.Int32
+			body := strings.Repeat("x", testIdempotencyConfig().MaxResponseBytes+1)
+			handler := NewIdempotency(store, testIdempotencyConfig()).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				created.Add(1)
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, body[:len(body)/2])
+				io.WriteString(w, body[len(body)/2:])
+			}))
+			userID := uuid.New()
+
+			first := httptest.NewRecorder()
+			handler.ServeHTTP(first, keyedRequest(userID, "large-key-1", `{"amount":5}`))
+			if first.Code != http.StatusCreated || first.Body.String() != body {
+				t.Fatalf("first response = %d with %d bytes", first.Code, first.Body.Len())
+			}
+
+			// The retry is not run again, nor answered with an empty body
+			retry := httptest.NewRecorder()
+			handler.ServeHTTP(retry, keyedRequest(userID, "large-key-1", `{"amount":5}`))
+			if created.Load() != 1 {
+				t.Fatalf("handler ran %d times", created.Load())
+			}
+			if retry.Code != http.StatusConflict || errorCode(t, retry) != ReasonIdempotencyResponseTooLarge ||
+				retry.Header().Get(IdempotentReplayedHeader) != "" {
+				t.Errorf("retry = %d %q %v", retry.Code, retry.Body.String(), retry.Header())
+			}
+
+			// Another request under the key is still refused as a reuse
+			reused := httptest.NewRecorder()
+			handler.ServeHTTP(reused, keyedRequest(userID, "large-key-1", `{"amount":6}`))
+			if reused.Code != http.StatusUnprocessableEntity {
+				t.Errorf("reused key = %d", reused.Code)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	for name, newStore := range idempotencyStores(t) {
+		for _, wait := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s wait=%v", name, wait), func(t *testing.T) {
+				store, _ := newStore()
+				cfg := testIdempotencyConfig()
+				cfg.Wait = wait
+				started := make(chan struct{})
+				finish := make(chan struct{})
+				var calls atomic.Int32
+				handler := NewIdempotency(store, cfg).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if calls.Add(1) == 1 {
+						close(started)
+						<-finish
+					}
+					w.WriteHeader(http.StatusCreated)
+				}))
+				userID := uuid.New()
+
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					handler.ServeHTTP(httptest.NewRecorder(), keyedRequest(userID, "in-flight-key", ""))
+				}()
+				<-started
+
+				retried := make(chan *httptest.ResponseRecorder)
+				go func() {
+					rec := httptest.NewRecorder()
+					handler.ServeHTTP(rec, keyedRequest(userID, "in-flight-key", ""))
+					retried <- rec
+				}()
+
+				var rec *httptest.ResponseRecorder
+				if !wait {
+					rec = <-retried
+					close(finish)
+				} else {
+					time.Sleep(2 * idempotencyPollInterval)
+					close(finish)
+					rec = <-retried
+				}
+				<-done
+
+				if calls.Load() != 1 {
+					t.Fatalf("handler ran %d times", calls.Load())
+				}
+				if !wait && (rec.Code != http.StatusConflict || errorCode(t, rec) != ReasonIdempotencyKeyInFlight || rec.Header().Get("Retry-After") == "") {
+					t.Errorf("in-flight retry = %d %v", rec.Code, rec.Header())
+				}
+				if wait && (rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "true") {
+					t.Errorf("waiting retry = %d %v", rec.Code, rec.Header())
+				}
+			})
+		}
+	}
+}
+
+func TestIdempotencyReleasesOnFailure(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	var calls atomic.Int32
+	handler := NewIdempotency(store, testIdempotencyConfig()).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", 2048)))
+	}))
+	userID := uuid.New()
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, keyedRequest(userID, "failing-key", ""))
+		return rec
+	}
+
+	// Server errors are not stored, so the retry runs
+	serve()
+	if rec := serve(); rec.Code != http.StatusOK || rec.Body.Len() != 2048 {
+		t.Fatalf("retry after failure = %d", rec.Code)
+	}
+	// Oversized bodies are not stored but the request is not repeated
+	if rec := serve(); calls.Load() != 2 || rec.Code != http.StatusConflict {
+		t.Errorf("replay of oversized response = %d, %d bytes, %d calls", rec.Code, rec.Body.Len(), calls.Load())
+	}
+}
+
+func TestIdempotencySkipsAndValidates(t *testing.T) {
+	var calls atomic.Int32
+	handler := NewIdempotency(NewMemoryIdempotencyStore(), testIdempotencyConfig()).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	userID := uuid.New()
+
+	for _, key := range []string{"short", strings.Repeat("k", 256), "has spaces in it", "bad\nnewline-key"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, keyedRequest(userID, key, ""))
+		if rec.Code != http.StatusBadRequest || errorCode(t, rec) != ReasonIdempotencyKeyInvalid {
+			t.Errorf("key %q = %d", key, rec.Code)
+		}
+	}
+
+	// GETs and unauthenticated requests are never replayed
+	get := keyedRequest(userID, "valid-key-1", "")
+	get.Method = http.MethodGet
+	anonymous := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", nil)
+	anonymous.Header.Set(IdempotencyKeyHeader, "valid-key-1")
+	for _, req := range []*http.Request{get, get.Clone(get.Context()), anonymous, anonymous.Clone(context.Background())} {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("handler ran %d times, want 4", calls.Load())
+	}
+}
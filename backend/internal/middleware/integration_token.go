@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/httputil"
+)
+
+// IntegrationTokenPrefix marks bearer tokens that are integration tokens
+// rather than JWTs
+const IntegrationTokenPrefix = "tgfi_"
+
+// IntegrationRole is the user role of requests made with an integration token
+const IntegrationRole = "integration"
+
+// Error reasons returned to integration token clients alongside the status code
+const (
+	ReasonIntegrationReadOnly       = "integration_token_read_only"
+	ReasonIntegrationEndpointDenied = "integration_token_endpoint_not_allowed"
+)
+
+var (
+	// ErrIntegrationTokenInvalid is returned for unknown, expired, revoked or
+	// disabled integration tokens
+	ErrIntegrationTokenInvalid = errors.New("invalid or expired integration token")
+	// ErrIntegrationWriteDenied is returned when an integration token is used
+	// on a write route
+	ErrIntegrationWriteDenied = errors.New("integration tokens are read-only")
+	// ErrIntegrationEndpointDenied is returned when an integration token is used
+	// on a read route outside its allowlist
+	ErrIntegrationEndpointDenied = errors.New("endpoint is not allowed for this integration token")
+)
+
+// IntegrationPrincipal is the integration token a request was authenticated with
+type IntegrationPrincipal struct {
+	TokenID            uuid.UUID
+	UserID             uuid.UUID
+	RateLimitPerMinute int
+}
+
+// IntegrationTokenAuthorizer resolves integration tokens
+type IntegrationTokenAuthorizer interface {
+	// AuthorizeIntegrationToken returns the principal of token if it may call
+	// method and path, or one of the ErrIntegration* errors
+	AuthorizeIntegrationToken(ctx context.Context, token, method, path, clientIP string) (*IntegrationPrincipal, error)
+}
+
+// integrationTokens holds the integration token authorizer and the store
+// counting each token's requests per minute
+type integrationTokens struct {
+	authorizer IntegrationTokenAuthorizer
+	limits     RateLimitStore
+}
+
+// EnableIntegrationTokens accepts integration tokens as bearer tokens. Without
+// it they are rejected like any other invalid JWT. Their per-minute limits are
+// counted in limits, which should be shared by every instance.
+func (m *AuthMiddleware) EnableIntegrationTokens(authorizer IntegrationTokenAuthorizer, limits RateLimitStore) {
+	m.integrations = &integrationTokens{authorizer: authorizer, limits: limits}
+}
+
+// isIntegrationToken determines if token should be handled as an integration token
+func (m *AuthMiddleware) isIntegrationToken(token string) bool {
+	return m.integrations != nil && strings.HasPrefix(token, IntegrationTokenPrefix)
+}
+
+// serveIntegrationToken authorizes an integration token request, enforcing
+// the token's endpoint allowlist and rate limit
+func (m *AuthMiddleware) serveIntegrationToken(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	if r.Header.Get(ActingForHeader) != "" {
+		m.sendErrorResponse(w, http.StatusForbidden, "Integration tokens cannot act for another user")
+		return
+	}
+
+	principal, err := m.integrations.authorizer.AuthorizeIntegrationToken(r.Context(), token, r.Method, r.URL.Path, ClientIP(r))
+	switch {
+	case errors.Is(err, ErrIntegrationWriteDenied):
+		m.sendErrorReason(w, http.StatusForbidden, ReasonIntegrationReadOnly, "Integration tokens are read-only")
+		return
+	case errors.Is(err, ErrIntegrationEndpointDenied):
+		m.sendErrorReason(w, http.StatusForbidden, ReasonIntegrationEndpointDenied, "Endpoint is not allowed for this integration token")
+		return
+	case err != nil:
+		if !errors.Is(err, ErrIntegrationTokenInvalid) {
+			m.log(r.Context()).WithError(err).Error("Failed to authorize integration token")
+		}
+		m.sendErrorResponse(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	if retryAfter, limited := m.integrations.limited(r.Context(), principal); limited {
+		writeTooManyRequests(w, retryAfter)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "user_id", principal.UserID.String())
+	ctx = context.WithValue(ctx, "user_role", IntegrationRole)
+	ctx = context.WithValue(ctx, "integration_token_id", principal.TokenID.String())
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// limited counts a request against the token's per-minute limit and reports
+// whether it is over, and until when. Requests are let through if the store
+// is unavailable, as RateLimit does.
+func (t *integrationTokens) limited(ctx context.Context, principal *IntegrationPrincipal) (time.Duration, bool) {
+	if principal.RateLimitPerMinute <= 0 {
+		return 0, false
+	}
+	count, reset, err := t.limits.Increment(ctx, "ratelimit:integration:"+principal.TokenID.String(), time.Minute)
+	if err != nil || count <= int64(principal.RateLimitPerMinute) {
+		return 0, false
+	}
+	return reset, true
+}
+
+// sendErrorReason sends a JSON error response with the machine-readable
+// reason as its code
+func (m *AuthMiddleware) sendErrorReason(w http.ResponseWriter, statusCode int, reason, message string) {
+	httputil.WriteError(w, statusCode, reason, message)
+}
+
+// GetIntegrationTokenIDFromContext returns the ID of the integration token
+// the request was made with, if any
+func GetIntegrationTokenIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	value, ok := ctx.Value("integration_token_id").(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(value)
+	return id, err == nil
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"tgfinance/pkg/httputil"
+)
+
+// BodyLimit middleware caps request bodies at limit bytes. Requests declaring
+// a larger body are rejected up front; for the rest, an error response the
+// handler writes after reading past the limit is replaced with a 413.
+func BodyLimit(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				writeBodyTooLarge(w)
+				return
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit)}
+			r.Body = body
+			lw := &bodyLimitWriter{ResponseWriter: w, body: body}
+			next.ServeHTTP(lw, r)
+
+			if !lw.wroteHeader && body.exceeded {
+				writeBodyTooLarge(w)
+			}
+		})
+	}
+}
+
+func writeBodyTooLarge(w http.ResponseWriter) {
+	w.Header().Del("Content-Length")
+	httputil.WriteError(w, http.StatusRequestEntityTooLarge, httputil.CodeForStatus(http.StatusRequestEntityTooLarge), "Request body too large")
+}
+
+// limitedBody notes when a read hits the body limit
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+// Read reads from the limited body
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter swaps error responses caused by an oversized body for a 413
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	body        *limitedBody
+	wroteHeader bool
+	discard     bool
+}
+
+// WriteHeader writes the status, or a 413 if the body was too large
+func (w *bodyLimitWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.body.exceeded && statusCode >= http.StatusBadRequest {
+		w.discard = true
+		writeBodyTooLarge(w.ResponseWriter)
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes b unless the response was replaced
+func (w *bodyLimitWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Timeout middleware gives handlers timeout to respond, after which the
+// request context is cancelled and the client gets a 504. The response is
+// buffered until the handler returns so a late handler cannot write into the
+// 504; its writes fail with http.ErrHandlerTimeout. Streaming routes should
+// not be wrapped.
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				for key, values := range tw.header {
+					w.Header()[key] = values
+				}
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.body.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					httputil.WriteError(w, http.StatusGatewayTimeout, httputil.CodeForStatus(http.StatusGatewayTimeout), "Request timed out")
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler finishes in time
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+// Header returns the buffered response headers
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records the status code
+func (w *timeoutWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.status != 0 {
+		return
+	}
+	w.status = statusCode
+}
+
+// Write buffers b, failing once the request has timed out
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("error body is not JSON: %v", err)
+	}
+	return body.Error.Code
+}
+
+func TestBodyLimit(t *testing.T) {
+	var called bool
+	decode := BodyLimit(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		status  int
+		called  bool
+	}{
+		{"within limit", `{"amount":1}`, false, http.StatusCreated, true},
+		{"declared too large", `{"note":"` + strings.Repeat("x", 64) + `"}`, false, http.StatusRequestEntityTooLarge, false},
+		{"chunked too large", `{"note":"` + strings.Repeat("x", 64) + `"}`, true, http.StatusRequestEntityTooLarge, true},
+		{"chunked invalid within limit", `{"amount":`, true, http.StatusBadRequest, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/expenses", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			decode.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status || called != tt.called {
+				t.Fatalf("status = %d, called = %v; want %d, %v", rec.Code, called, tt.status, tt.called)
+			}
+			if tt.status == http.StatusRequestEntityTooLarge && errorCode(t, rec) != "request_entity_too_large" {
+				t.Error("413 should carry the JSON error body")
+			}
+		})
+	}
+
+	// Handlers that give up silently still answer 413
+	silent := BodyLimit(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too long"))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	silent.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("silent handler: status = %d", rec.Code)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	slow := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("X-Late", "1")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("late"))
+		lateWrite <- err
+	}))
+
+	rec := httptest.NewRecorder()
+	slow.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/reports", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", rec.Code)
+	}
+	if err := <-lateWrite; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("late write error = %v", err)
+	}
+	if errorCode(t, rec) != "gateway_timeout" || rec.Header().Get("X-Late") != "" {
+		t.Errorf("late handler leaked into the response: %v", rec.Header())
+	}
+
+	fast := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("done"))
+	}))
+	rec = httptest.NewRecorder()
+	fast.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "done" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("fast handler response = %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestTimeoutRepanics(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the handler panic", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/pkg/logger"
+)
+
+// accessLogKey is the context key of the access log entry being built
+type accessLogKey struct{}
+
+// accessLogRequest collects details known only to inner handlers
+type accessLogRequest struct {
+	userID string
+}
+
+// Logging middleware writes one access log entry per request, at error level
+// for 5xx responses, warn for 4xx and info otherwise. Requests to skipPaths
+// are not logged.
+func Logging(log *logger.Logger, skipPaths []string) func(http.Handler) http.Handler {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			details := &accessLogRequest{}
+			cw := &capturingWriter{ResponseWriter: w}
+			next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, details)))
+
+			status := cw.status
+			if status == 0 {
+				// net/http sends 200 for handlers that never write
+				status = http.StatusOK
+			}
+			fields := logrus.Fields{
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"status":     status,
+				"bytes":      cw.bytes,
+				"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+				"remote_ip":  ClientIP(r),
+				"user_agent": r.UserAgent(),
+			}
+			if details.userID != "" {
+				fields["user_id"] = details.userID
+			}
+
+			entry := log.WithRequestID(r.Context()).WithFields(fields)
+			switch {
+			case status >= http.StatusInternalServerError:
+				entry.Error("HTTP request")
+			case status >= http.StatusBadRequest:
+				entry.Warn("HTTP request")
+			default:
+				entry.Info("HTTP request")
+			}
+		})
+	}
+}
+
+// setAccessLogUser records the authenticated user for the access log
+func setAccessLogUser(ctx context.Context, userID string) {
+	if details, ok := ctx.Value(accessLogKey{}).(*accessLogRequest); ok {
+		details.userID = userID
+	}
+}
+
+// capturingWriter captures the status code and body size of a response for
+// the access log and metrics
+type capturingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the first status code written
+func (w *capturingWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write counts the bytes written to the client
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush flushes the underlying writer if it supports flushing
+func (w *capturingWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack takes over the connection if the underlying writer supports it
+func (w *capturingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *capturingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
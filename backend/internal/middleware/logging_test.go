@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/logger"
+)
+
+// accessLog runs handler behind the Logging middleware and returns the
+// decoded log entries
+func accessLog(t *testing.T, handler http.Handler, req *http.Request) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&out)
+
+	Logging(log, []string{"/health"})(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	var entries []map[string]any
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var entry map[string]any
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggingStatusAndLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  float64
+		bytes   float64
+		level   string
+	}{
+		{"no write at all", func(w http.ResponseWriter, r *http.Request) {}, 200, 0, "info"},
+		{"write without WriteHeader", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}, 200, 5, "info"},
+		{"client error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusNotFound)
+		}, 404, 5, "warning"},
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.WriteHeader(http.StatusOK)
+		}, 502, 0, "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+			req.Header.Set("User-Agent", "test-agent")
+			entries := accessLog(t, tt.handler, req)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			if entry["status"] != tt.status || entry["bytes"] != tt.bytes || entry["level"] != tt.level {
+				t.Errorf("entry = %v", entry)
+			}
+			if entry["method"] != "GET" || entry["path"] != "/api/v1/goals" || entry["user_agent"] != "test-agent" || entry["remote_ip"] != "192.0.2.1" {
+				t.Errorf("request fields = %v", entry)
+			}
+			if _, ok := entry["latency_ms"]; !ok {
+				t.Error("latency missing")
+			}
+		})
+	}
+}
+
+func TestLoggingSkipsPathsAndRecordsUser(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if entries := accessLog(t, ok, httptest.NewRequest(http.MethodGet, "/health", nil)); len(entries) != 0 {
+		t.Errorf("skipped path logged: %v", entries)
+	}
+
+	cfg := config.Load()
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	m.logger.SetOutput(&bytes.Buffer{})
+	jwtManager := auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions())
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "test@example.com", auth.RoleUser)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	entries := accessLog(t, m.Authenticate(ok), req)
+	if len(entries) != 1 || entries[0]["user_id"] != userID.String() {
+		t.Errorf("entries = %v", entries)
+	}
+}
+
+// hijackRecorder is a recorder supporting connection hijacking
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func TestLoggingPreservesFlusherAndHijacker(t *testing.T) {
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&bytes.Buffer{})
+
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler := Logging(log, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+		if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+			t.Errorf("Hijack: %v", err)
+		}
+	}))
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !rec.Flushed || !rec.hijacked {
+		t.Errorf("flushed = %v, hijacked = %v", rec.Flushed, rec.hijacked)
+	}
+
+	// Writers without hijacking report it as unsupported
+	handler = Logging(log, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Error("Hijack should fail on a writer without support")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/logger"
+)
+
+// MaintenanceBypassHeader lets ops traffic through maintenance mode when it
+// carries the configured bypass token
+const MaintenanceBypassHeader = "X-Maintenance-Bypass"
+
+// ReasonMaintenance is the error code of requests refused during maintenance
+const ReasonMaintenance = "maintenance"
+
+// maintenanceExemptRules are served during maintenance so health checks and
+// scraping keep working
+var maintenanceExemptRules = []SkipRule{
+	{Path: "/health", Prefix: true},
+	{Path: "/metrics"},
+}
+
+// Maintenance refuses traffic with 503 while maintenance mode is on, such as
+// during schema migrations. The mode is an atomic flag toggled at runtime;
+// changes are serialized so the log records them in order.
+type Maintenance struct {
+	mu         sync.Mutex
+	enabled    atomic.Bool
+	bypass     string
+	retryAfter time.Duration
+	logger     *logger.Logger
+}
+
+// NewMaintenance creates the maintenance mode middleware, starting enabled
+// when configured
+func NewMaintenance(cfg config.ServerConfig, log *logger.Logger) *Maintenance {
+	m := &Maintenance{bypass: cfg.MaintenanceBypassToken, retryAfter: cfg.MaintenanceRetryAfter, logger: log}
+	m.enabled.Store(cfg.MaintenanceMode)
+	return m
+}
+
+// Enabled reports whether maintenance mode is on
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Set switches maintenance mode on or off and logs the change with its
+// source. It returns false if the mode already was in that state.
+func (m *Maintenance) Set(enabled bool, source string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.enabled.Load() == enabled {
+		return false
+	}
+	m.enabled.Store(enabled)
+	m.logChange(enabled, source)
+	return true
+}
+
+// Toggle flips maintenance mode and returns the new state
+func (m *Maintenance) Toggle(source string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	enabled := !m.enabled.Load()
+	m.enabled.Store(enabled)
+	m.logChange(enabled, source)
+	return enabled
+}
+
+// logChange records a state change
+func (m *Maintenance) logChange(enabled bool, source string) {
+	entry := m.logger.WithFields(logrus.Fields{"maintenance": enabled, "source": source})
+	if enabled {
+		entry.Warn("Maintenance mode enabled")
+		return
+	}
+	entry.Info("Maintenance mode disabled")
+}
+
+// Handle middleware answers 503 with Retry-After during maintenance, except
+// for health checks, metrics and requests carrying the bypass token
+func (m *Maintenance) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.enabled.Load() || m.isExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(m.retryAfter.Seconds())))))
+		httputil.WriteError(w, http.StatusServiceUnavailable, ReasonMaintenance, "The service is undergoing maintenance, please try again later")
+	})
+}
+
+// isExempt returns true for requests served during maintenance
+func (m *Maintenance) isExempt(r *http.Request) bool {
+	for _, rule := range maintenanceExemptRules {
+		if rule.Matches(r.Method, r.URL.Path) {
+			return true
+		}
+	}
+	token := r.Header.Get(MaintenanceBypassHeader)
+	return m.bypass != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.bypass)) == 1
+}
+
+// maintenanceState is the body of the maintenance admin endpoint
+type maintenanceState struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ServeAdmin handles GET and PUT /api/v1/admin/maintenance, reporting and
+// switching maintenance mode. It belongs behind RequireAdmin; while
+// maintenance is on it is only reachable with the bypass token.
+func (m *Maintenance) ServeAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req maintenanceState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Request body must set enabled")
+			return
+		}
+		source := "admin"
+		if userID, err := GetUserIDFromContext(r.Context()); err == nil {
+			source = "admin:" + userID.String()
+		}
+		m.Set(*req.Enabled, source)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		httputil.WriteError(w, http.StatusMethodNotAllowed, httputil.CodeForStatus(http.StatusMethodNotAllowed), "Method not allowed")
+		return
+	}
+	enabled := m.Enabled()
+	httputil.WriteJSON(w, http.StatusOK, maintenanceState{Enabled: &enabled})
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package middleware
+
+import "context"
+
+// WatchSignal waits for ctx to be cancelled; SIGUSR2 only exists on Unix, so
+// maintenance mode is switched through the admin endpoint instead
+func (m *Maintenance) WatchSignal(ctx context.Context) {
+	<-ctx.Done()
+}
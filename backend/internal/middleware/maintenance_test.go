@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/logger"
+)
+
+func newTestMaintenance() (*Maintenance, *bytes.Buffer) {
+	var logs bytes.Buffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	cfg := config.Load().Server
+	cfg.MaintenanceBypassToken = "ops-secret"
+	cfg.MaintenanceRetryAfter = 90 * time.Second
+	return NewMaintenance(cfg, log), &logs
+}
+
+func TestMaintenanceMidStream(t *testing.T) {
+	m, logs := newTestMaintenance()
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(target string, bypass string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if bypass != "" {
+			req.Header.Set(MaintenanceBypassHeader, bypass)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var statuses []int
+	for i := 0; i < 6; i++ {
+		switch i {
+		case 2:
+			m.Set(true, "test")
+		case 4:
+			m.Set(false, "test")
+		}
+		statuses = append(statuses, serve("/api/v1/goals", "").Code)
+	}
+	want := []int{200, 200, 503, 503, 200, 200}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", statuses, want)
+		}
+	}
+	if !strings.Contains(logs.String(), "Maintenance mode enabled") || !strings.Contains(logs.String(), "Maintenance mode disabled") {
+		t.Errorf("changes not logged: %s", logs.String())
+	}
+
+	m.Set(true, "test")
+	rec := serve("/api/v1/goals", "")
+	if rec.Header().Get("Retry-After") != "90" || errorCode(t, rec) != ReasonMaintenance {
+		t.Errorf("refusal = %v", rec.Header())
+	}
+	for _, target := range []string{"/health", "/health/db", "/metrics"} {
+		if rec := serve(target, ""); rec.Code != http.StatusOK {
+			t.Errorf("%s = %d during maintenance", target, rec.Code)
+		}
+	}
+	if rec := serve("/api/v1/goals", "ops-secret"); rec.Code != http.StatusOK {
+		t.Errorf("bypass = %d", rec.Code)
+	}
+	if rec := serve("/api/v1/goals", "wrong"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("wrong bypass token = %d", rec.Code)
+	}
+}
+
+func TestMaintenanceConcurrentToggling(t *testing.T) {
+	m, logs := newTestMaintenance()
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				m.Toggle("test")
+				m.Set(j%2 == 0, "test")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/goals", nil))
+				if rec.Code != http.StatusOK && rec.Code != http.StatusServiceUnavailable {
+					t.Errorf("status = %d", rec.Code)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Only real changes are logged, and they alternate
+	m.Set(false, "test")
+	var previous string
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry struct{ Msg string }
+		json.Unmarshal([]byte(line), &entry)
+		if entry.Msg == previous {
+			t.Fatalf("%q logged twice in a row", entry.Msg)
+		}
+		previous = entry.Msg
+	}
+	if m.Set(false, "test") {
+		t.Error("setting the current state should report no change")
+	}
+}
+
+func TestMaintenanceAdminEndpoint(t *testing.T) {
+	m, logs := newTestMaintenance()
+	userID := uuid.New()
+	call := func(method, body string) (int, bool) {
+		ctx := context.WithValue(context.Background(), "user_id", userID.String())
+		req := httptest.NewRequest(method, "/api/v1/admin/maintenance", strings.NewReader(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		m.ServeAdmin(rec, req)
+		var state struct{ Enabled bool }
+		json.NewDecoder(rec.Body).Decode(&state)
+		return rec.Code, state.Enabled
+	}
+
+	if code, enabled := call(http.MethodPut, `{"enabled":true}`); code != http.StatusOK || !enabled || !m.Enabled() {
+		t.Fatalf("enable = %d %v", code, enabled)
+	}
+	if !strings.Contains(logs.String(), "admin:"+userID.String()) {
+		t.Errorf("admin not logged as the source: %s", logs.String())
+	}
+	if code, enabled := call(http.MethodGet, ""); code != http.StatusOK || !enabled {
+		t.Errorf("get = %d %v", code, enabled)
+	}
+	if code, _ := call(http.MethodPut, `{}`); code != http.StatusBadRequest {
+		t.Errorf("missing enabled = %d", code)
+	}
+	if code, _ := call(http.MethodDelete, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("delete = %d", code)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package middleware
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSignal toggles maintenance mode on every SIGUSR2 until ctx is cancelled
+func (m *Maintenance) WatchSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			m.Toggle("SIGUSR2")
+		}
+	}
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package middleware
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceSignal(t *testing.T) {
+	m, _ := newTestMaintenance()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.WatchSignal(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Signals sent before Notify is registered would kill the process
+	time.Sleep(50 * time.Millisecond)
+	for _, want := range []bool{true, false} {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for m.Enabled() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("maintenance = %v, want %v", m.Enabled(), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"tgfinance/internal/metrics"
+)
+
+// Metrics middleware records request counts, durations and in-flight
+// requests in m
+func Metrics(m *metrics.HTTPMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			done := m.Start(r.Method, r.URL.Path)
+			cw := &capturingWriter{ResponseWriter: w}
+			defer func() {
+				status := cw.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				done(status)
+			}()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
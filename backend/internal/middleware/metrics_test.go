@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tgfinance/internal/metrics"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	m := metrics.NewHTTPMetrics()
+	reg := prometheus.NewRegistry()
+	if err := m.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	var inFlight float64
+	handler := Metrics(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight = gaugeValue(t, reg, "tgfinance_http_requests_in_flight")
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	for _, method := range []string{http.MethodGet, http.MethodGet, http.MethodDelete} {
+		path := "/api/v1/goals/" + uuid.NewString()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+
+	if inFlight != 1 {
+		t.Errorf("in flight during request = %v, want 1", inFlight)
+	}
+	if got := gaugeValue(t, reg, "tgfinance_http_requests_in_flight"); got != 0 {
+		t.Errorf("in flight after requests = %v, want 0", got)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	found := map[string]bool{}
+	for _, f := range families {
+		found[f.GetName()] = true
+		if f.GetName() != "tgfinance_http_requests_total" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			counts[labels["method"]+" "+labels["route"]+" "+labels["status"]] = metric.GetCounter().GetValue()
+		}
+	}
+	if !found["tgfinance_http_request_duration_seconds"] {
+		t.Error("Missing duration histogram")
+	}
+	want := map[string]float64{
+		"GET /api/v1/goals/:id 2xx":    2,
+		"DELETE /api/v1/goals/:id 4xx": 1,
+	}
+	if len(counts) != len(want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	for series, n := range want {
+		if counts[series] != n {
+			t.Errorf("%s = %v, want %v", series, counts[series], n)
+		}
+	}
+}
+
+func gaugeValue(t *testing.T, reg *prometheus.Registry, name string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == name {
+			return f.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("Missing metric %s", name)
+	return 0
+}
@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/httputil"
+)
+
+// ClientIP returns the address of the direct peer of a request. Use
+// TrustedClientIP to see through proxies.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// TrustedClientIP returns the client address of a request, following
+// X-Forwarded-For only through the trusted proxies. The rightmost untrusted
+// hop is the client, so clients cannot spoof addresses by prepending entries.
+func TrustedClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	ip := ClientIP(r)
+	if !inNetworks(ip, trustedProxies) {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !inNetworks(hop, trustedProxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// ParseTrustedProxies parses IP addresses and CIDR ranges
+func ParseTrustedProxies(values []string) ([]*net.IPNet, error) {
+	return parseNetworks(values, "trusted proxy")
+}
+
+// parseNetworks parses IP addresses and CIDR ranges; single addresses become
+// host networks. kind names the values in errors.
+func parseNetworks(values []string, kind string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid %s %q", kind, value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", kind, value, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// inNetworks returns true if addr is an IP in one of networks
+func inNetworks(addr string, networks []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitStore counts requests per key in fixed windows so limits can be
+// shared across instances
+type RateLimitStore interface {
+	// Increment counts a request against key and returns the count in the
+	// current window and the time until the window resets
+	Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+}
+
+// RateLimit limits a route group to rule.Requests per client in fixed
+// windows of rule.Window. Clients are the authenticated user when there is
+// one and the client IP otherwise, so the same middleware serves public
+// routes such as /status and authenticated ones. Requests are let through
+// if the store is unavailable.
+type RateLimit struct {
+	store          RateLimitStore
+	group          string
+	rule           config.RateLimitRule
+	trustedProxies []*net.IPNet
+}
+
+// NewRateLimit creates a rate limit for a route group
+func NewRateLimit(store RateLimitStore, group string, rule config.RateLimitRule, trustedProxies []*net.IPNet) *RateLimit {
+	return &RateLimit{store: store, group: group, rule: rule, trustedProxies: trustedProxies}
+}
+
+// Limit middleware rejects requests over the limit with 429
+func (l *RateLimit) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.rule.Requests <= 0 || l.rule.Window <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		count, reset, err := l.store.Increment(r.Context(), l.key(r), l.rule.Window)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if count > int64(l.rule.Requests) {
+			writeTooManyRequests(w, reset)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeTooManyRequests sends 429 with Retry-After rounded up to whole
+// seconds, at least one
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+	httputil.WriteError(w, http.StatusTooManyRequests, httputil.CodeForStatus(http.StatusTooManyRequests), "Too many requests")
+}
+
+// key returns the store key of the client making the request
+func (l *RateLimit) key(r *http.Request) string {
+	if userID, err := GetUserIDFromContext(r.Context()); err == nil {
+		return "ratelimit:" + l.group + ":user:" + userID.String()
+	}
+	return "ratelimit:" + l.group + ":ip:" + TrustedClientIP(r, l.trustedProxies)
+}
+
+// rateLimitShards splits the memory store to reduce lock contention
+const rateLimitShards = 32
+
+// MemoryRateLimitStore counts requests in process memory, for single
+// instances and tests
+type MemoryRateLimitStore struct {
+	clock  clock.Clock
+	shards [rateLimitShards]rateLimitShard
+}
+
+type rateLimitShard struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	swept   time.Time
+}
+
+type rateWindow struct {
+	count  int64
+	resets time.Time
+}
+
+// NewMemoryRateLimitStore creates an in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{clock: clock.Real()}
+	for i := range s.shards {
+		s.shards[i].windows = make(map[string]*rateWindow)
+	}
+	return s
+}
+
+// SetClock sets the clock used to expire windows
+func (s *MemoryRateLimitStore) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Increment counts a request against key in the current window
+func (s *MemoryRateLimitStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%rateLimitShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := s.clock.Now()
+	shard.sweep(now)
+
+	w, ok := shard.windows[key]
+	if !ok || !now.Before(w.resets) {
+		w = &rateWindow{resets: now.Add(window)}
+		shard.windows[key] = w
+	}
+	w.count++
+	return w.count, w.resets.Sub(now), nil
+}
+
+// sweep drops expired windows
+func (s *rateLimitShard) sweep(now time.Time) {
+	if now.Sub(s.swept) < time.Minute {
+		return
+	}
+	s.swept = now
+
+	for key, w := range s.windows {
+		if !now.Before(w.resets) {
+			delete(s.windows, key)
+		}
+	}
+}
+
+// incrementScript counts a request and starts the window on the first one.
+// Keys left without an expiry are repaired so they cannot block forever.
+// Returns the count and the remaining window in milliseconds.
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RedisRateLimitStore counts requests in Redis so limits hold across instances
+type RedisRateLimitStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisRateLimitStore creates a Redis-backed rate limit store
+func NewRedisRateLimitStore(client redis.UniversalClient) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+// Increment counts a request against key in the current window
+func (s *RedisRateLimitStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	result, err := incrementScript.Run(ctx, s.client, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count request: %w", err)
+	}
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/clock"
+)
+
+func TestTrustedClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("invalid proxy should be rejected")
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"direct client", "203.0.113.7:1234", "", "203.0.113.7"},
+		{"untrusted peer cannot spoof", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:443", "198.51.100.1", "198.51.100.1"},
+		{"spoofed prefix ignored", "10.1.2.3:443", "1.1.1.1, 198.51.100.1", "198.51.100.1"},
+		{"proxy chain", "192.168.1.1:443", "198.51.100.1, 10.9.9.9", "198.51.100.1"},
+		{"no header", "10.1.2.3:443", "", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := TrustedClientIP(req, proxies); got != tt.want {
+				t.Errorf("TrustedClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limit := NewRateLimit(NewMemoryRateLimitStore(), "auth", config.RateLimitRule{Requests: 2, Window: time.Minute}, nil)
+	handler := limit.Limit(ok)
+	request := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	fromIP := func(ip string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		return req
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request(fromIP("203.0.113.7")); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d", i, rec.Code)
+		}
+	}
+	rec := request(fromIP("203.0.113.7"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Retry-After = %q", rec.Header().Get("Retry-After"))
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code == "" {
+		t.Errorf("error body = %+v, %v", body, err)
+	}
+
+	// Other IPs and authenticated users have their own buckets
+	if rec := request(fromIP("203.0.113.8")); rec.Code != http.StatusOK {
+		t.Errorf("other IP: status = %d", rec.Code)
+	}
+	user := requestAs(uuid.New(), "user")
+	user.RemoteAddr = "203.0.113.7:1234"
+	if rec := request(user); rec.Code != http.StatusOK {
+		t.Errorf("authenticated user: status = %d", rec.Code)
+	}
+
+	// A zero rule disables the limit
+	open := NewRateLimit(NewMemoryRateLimitStore(), "api", config.RateLimitRule{}, nil).Limit(ok)
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		open.ServeHTTP(rec, fromIP("203.0.113.9"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("disabled limit: status = %d", rec.Code)
+		}
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit := NewRateLimit(NewMemoryRateLimitStore(), "status", config.RateLimitRule{Requests: 1, Window: time.Minute}, proxies)
+	handler := limit.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// The proxy appends the real client; clients rotate the entries before it
+	for i, spoofed := range []string{"1.1.1.1", "2.2.2.2"} {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.RemoteAddr = "10.0.0.1:443"
+		req.Header.Set("X-Forwarded-For", spoofed+", 198.51.100.1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		want := http.StatusOK
+		if i > 0 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+	}
+}
+
+func TestRateLimitStoresConcurrent(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	stores := map[string]RateLimitStore{
+		"memory": NewMemoryRateLimitStore(),
+		"redis":  NewRedisRateLimitStore(client),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			limit := NewRateLimit(store, "api", config.RateLimitRule{Requests: 20, Window: time.Minute}, nil)
+			var allowed atomic.Int64
+			handler := limit.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				allowed.Add(1)
+			}))
+
+			userID := uuid.New()
+			var wg sync.WaitGroup
+			for i := 0; i < 100; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					handler.ServeHTTP(httptest.NewRecorder(), requestAs(userID, "user"))
+				}()
+			}
+			wg.Wait()
+
+			if got := allowed.Load(); got != 20 {
+				t.Errorf("allowed %d requests, want 20", got)
+			}
+		})
+	}
+}
+
+func TestRateLimitStoresMatch(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	redisStore := NewRedisRateLimitStore(client)
+	memoryStore := NewMemoryRateLimitStore()
+	fake := clock.NewFake(time.Now())
+	memoryStore.SetClock(fake)
+	advance := func(d time.Duration) {
+		fake.Advance(d)
+		server.FastForward(d)
+	}
+
+	ctx := context.Background()
+	steps := []struct {
+		key     string
+		advance time.Duration
+	}{
+		{"a", 0}, {"a", 10 * time.Second}, {"b", 0}, {"a", 20 * time.Second},
+		{"a", 30 * time.Second}, {"b", 0}, {"a", 5 * time.Second}, {"b", 45 * time.Second},
+	}
+	for i, step := range steps {
+		advance(step.advance)
+		memCount, memReset, memErr := memoryStore.Increment(ctx, step.key, time.Minute)
+		redisCount, redisReset, redisErr := redisStore.Increment(ctx, step.key, time.Minute)
+		if memErr != nil || redisErr != nil {
+			t.Fatalf("step %d: errors %v, %v", i, memErr, redisErr)
+		}
+		if memCount != redisCount || memReset != redisReset {
+			t.Errorf("step %d: memory (%d, %v), redis (%d, %v)", i, memCount, memReset, redisCount, redisReset)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/logger"
+)
+
+// Recovery middleware turns handler panics into a logged 500 instead of a
+// dropped connection. http.ErrAbortHandler is re-raised so deliberate aborts
+// keep working.
+func Recovery(log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &capturingWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				log.WithRequestID(r.Context()).WithField("stack", string(debug.Stack())).
+					Errorf("Recovered from panic: %v", p)
+				// Once the status is sent the client can only see a truncated body
+				if cw.status == 0 {
+					httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Internal server error")
+				}
+			}()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
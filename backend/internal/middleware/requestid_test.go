@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/config"
+	"tgfinance/pkg/auth"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{"missing", "", false},
+		{"valid", "req-42.abc:01", true},
+		{"invalid characters", "bad id\n", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(RequestIDHeader)
+			if echoed != seen {
+				t.Fatalf("response header %q, context %q", echoed, seen)
+			}
+			if tt.kept {
+				if echoed != tt.incoming {
+					t.Fatalf("request ID = %q, want %q", echoed, tt.incoming)
+				}
+				return
+			}
+			if _, err := uuid.Parse(echoed); err != nil {
+				t.Fatalf("request ID %q is not a generated UUID", echoed)
+			}
+		})
+	}
+}
+
+func TestRequestIDPropagatesThroughChain(t *testing.T) {
+	cfg := config.Load()
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	var logs bytes.Buffer
+	m.logger.SetOutput(&logs)
+	jwtManager := auth.NewJWTManagerWithOptions(cfg.Auth.JWTOptions())
+	token, _ := jwtManager.GenerateToken(uuid.New(), "test@example.com", auth.RoleUser)
+
+	var seen string
+	handler := RequestID(m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestIDFromContext(r.Context())
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set(RequestIDHeader, "chain-test-1")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if seen != "chain-test-1" {
+		t.Fatalf("handler saw request ID %q", seen)
+	}
+	if got := rec.Header().Get(RequestIDHeader); got != "chain-test-1" {
+		t.Fatalf("response header = %q", got)
+	}
+	if !strings.Contains(logs.String(), `"request_id":"chain-test-1"`) {
+		t.Fatalf("auth log lines lack the request ID: %s", logs.String())
+	}
+
+	// Rejections are logged with the ID as well
+	logs.Reset()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set(RequestIDHeader, "chain-test-2")
+	req.Header.Set("Authorization", "Bearer invalid")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	if rec.Header().Get(RequestIDHeader) != "chain-test-2" {
+		t.Fatalf("response header missing on rejection")
+	}
+	if !strings.Contains(logs.String(), `"request_id":"chain-test-2"`) {
+		t.Fatalf("rejection log lacks the request ID: %s", logs.String())
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"tgfinance/pkg/auth"
+)
+
+// EnableTokenRevocation makes Authenticate refuse tokens revoked in the
+// blacklist. Without it logged out tokens stay valid until they expire.
+func (m *AuthMiddleware) EnableTokenRevocation(blacklist *auth.TokenBlacklist) {
+	m.blacklist = blacklist
+}
+
+// isRevoked checks the blacklist after signature validation. Redis being
+// unavailable must not lock every user out, so errors fail open.
+func (m *AuthMiddleware) isRevoked(ctx context.Context, claims *auth.Claims) bool {
+	if m.blacklist == nil {
+		return false
+	}
+	revoked, err := m.blacklist.IsTokenRevoked(ctx, claims)
+	if err != nil {
+		m.log(ctx).WithError(err).Error("Failed to check token revocation")
+		return false
+	}
+	return revoked
+}
+
+// Logout revokes the token used for the request for the rest of its lifetime
+func (m *AuthMiddleware) Logout(w http.ResponseWriter, r *http.Request) {
+	if m.blacklist == nil {
+		m.sendErrorResponse(w, http.StatusNotImplemented, "Token revocation is not enabled")
+		return
+	}
+	tokenID, expiresAt := getTokenFromContext(r.Context())
+	if tokenID == "" {
+		m.sendErrorResponse(w, http.StatusUnauthorized, "Token cannot be revoked")
+		return
+	}
+
+	if err := m.blacklist.RevokeUntil(r.Context(), tokenID, expiresAt); err != nil {
+		m.log(r.Context()).WithError(err).Error("Failed to revoke token")
+		m.sendErrorResponse(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+	m.ClearAuthCookie(w)
+	if userID, err := GetUserIDFromContext(r.Context()); err == nil {
+		m.recordAuthEvent(r, AuthEventLogout, userID, true, "")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutEverywhere revokes every token issued to the user so far, including
+// refresh tokens held by other devices
+func (m *AuthMiddleware) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	if m.blacklist == nil {
+		m.sendErrorResponse(w, http.StatusNotImplemented, "Token revocation is not enabled")
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		m.sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := m.blacklist.RevokeAllForUser(r.Context(), userID, m.jwtManager.RefreshExpiry()); err != nil {
+		m.log(r.Context()).WithError(err).Error("Failed to revoke user tokens")
+		m.sendErrorResponse(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+	m.recordAuthEvent(r, AuthEventLogout, userID, true, "everywhere")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withToken stores the token ID and expiry in the context for Logout
+func withToken(ctx context.Context, claims *auth.Claims) context.Context {
+	ctx = context.WithValue(ctx, "token_id", claims.ID)
+	if claims.ExpiresAt != nil {
+		ctx = context.WithValue(ctx, "token_expires_at", claims.ExpiresAt.Time)
+	}
+	return ctx
+}
+
+// getTokenFromContext returns the ID and expiry of the token used for the request
+func getTokenFromContext(ctx context.Context) (string, time.Time) {
+	tokenID, _ := ctx.Value("token_id").(string)
+	expiresAt, _ := ctx.Value("token_expires_at").(time.Time)
+	return tokenID, expiresAt
+}
+
+// GetTokenIDFromContext extracts the jti of the authenticating token from request context
+func GetTokenIDFromContext(ctx context.Context) (string, error) {
+	tokenID, _ := getTokenFromContext(ctx)
+	if tokenID == "" {
+		return "", fmt.Errorf("token ID not found in context")
+	}
+	return tokenID, nil
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SkipRule exempts matching requests from authentication.
+//
+// Paths are matched case-sensitively, ignoring a trailing slash. A plain
+// Path matches exactly, or with Prefix set also every path below it. A "*"
+// segment matches any one segment, and a trailing "*" one or more, so
+// "/api/v1/public/*" covers everything under /api/v1/public. Methods match
+// case-insensitively; no methods or "*" match any method.
+type SkipRule struct {
+	Path    string
+	Prefix  bool
+	Methods []string
+}
+
+// DefaultSkipRules are the public routes: health checks, metrics, sign-in
+// and CORS preflight requests
+var DefaultSkipRules = []SkipRule{
+	{Path: "/health", Methods: []string{http.MethodGet}},
+	{Path: "/status", Methods: []string{http.MethodGet}},
+	{Path: "/metrics", Methods: []string{http.MethodGet}},
+	{Path: "/api/v1/auth/login", Methods: []string{http.MethodPost}},
+	{Path: "/api/v1/auth/register", Methods: []string{http.MethodPost}},
+	{Path: "/api/v1/auth/refresh", Methods: []string{http.MethodPost}},
+	{Path: "/", Prefix: true, Methods: []string{http.MethodOptions}},
+}
+
+// ParseSkipRule parses "[METHOD[|METHOD...]] /path", as used for configured
+// public paths
+func ParseSkipRule(value string) (SkipRule, error) {
+	fields := strings.Fields(value)
+	var rule SkipRule
+	switch len(fields) {
+	case 1:
+		rule.Path = fields[0]
+	case 2:
+		rule.Methods = strings.Split(fields[0], "|")
+		rule.Path = fields[1]
+	default:
+		return SkipRule{}, fmt.Errorf("invalid public path %q", value)
+	}
+	if !strings.HasPrefix(rule.Path, "/") {
+		return SkipRule{}, fmt.Errorf("invalid public path %q: path must start with /", value)
+	}
+	return rule, nil
+}
+
+// Matches returns true if the rule exempts the request
+func (r SkipRule) Matches(method, path string) bool {
+	if !r.matchesMethod(method) {
+		return false
+	}
+
+	path = trimTrailingSlash(path)
+	pattern := trimTrailingSlash(r.Path)
+	switch {
+	case r.Prefix:
+		return pattern == "/" || path == pattern || strings.HasPrefix(path, pattern+"/")
+	case strings.Contains(pattern, "*"):
+		return matchTemplate(strings.Split(pattern, "/"), strings.Split(path, "/"))
+	default:
+		return path == pattern
+	}
+}
+
+// matchesMethod returns true if the rule applies to the method
+func (r SkipRule) matchesMethod(method string) bool {
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if m == "*" || strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTemplate matches path segments against template segments
+func matchTemplate(template, segments []string) bool {
+	for i, want := range template {
+		if i >= len(segments) {
+			return false
+		}
+		if want == "*" {
+			if segments[i] == "" {
+				return false
+			}
+			if i == len(template)-1 {
+				return true
+			}
+			continue
+		}
+		if want != segments[i] {
+			return false
+		}
+	}
+	return len(segments) == len(template)
+}
+
+func trimTrailingSlash(path string) string {
+	if trimmed := strings.TrimRight(path, "/"); trimmed != "" {
+		return trimmed
+	}
+	return path
+}
+
+// SetSkipRules replaces the rules exempting requests from authentication
+func (m *AuthMiddleware) SetSkipRules(rules ...SkipRule) {
+	m.skipRules = rules
+}
+
+// AddSkipRules exempts more requests from authentication
+func (m *AuthMiddleware) AddSkipRules(rules ...SkipRule) {
+	m.skipRules = append(m.skipRules, rules...)
+}
+
+// shouldSkipAuth determines if authentication should be skipped for the given path and method
+func (m *AuthMiddleware) shouldSkipAuth(path, method string) bool {
+	for _, rule := range m.skipRules {
+		if rule.Matches(method, path) {
+			return true
+		}
+	}
+	return false
+}
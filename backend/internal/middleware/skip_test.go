@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tgfinance/internal/config"
+)
+
+func TestSkipRuleMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   SkipRule
+		method string
+		path   string
+		want   bool
+	}{
+		{"exact", SkipRule{Path: "/api/v1/auth/login", Methods: []string{"POST"}}, "POST", "/api/v1/auth/login", true},
+		{"exact trailing slash", SkipRule{Path: "/api/v1/auth/login", Methods: []string{"POST"}}, "POST", "/api/v1/auth/login/", true},
+		{"rule with trailing slash", SkipRule{Path: "/docs/"}, "GET", "/docs", true},
+		{"exact other method", SkipRule{Path: "/api/v1/auth/login", Methods: []string{"POST"}}, "GET", "/api/v1/auth/login", false},
+		{"exact does not match below", SkipRule{Path: "/health"}, "GET", "/health/db", false},
+		{"method case-insensitive", SkipRule{Path: "/health", Methods: []string{"get"}}, "GET", "/health", true},
+		{"path case-sensitive", SkipRule{Path: "/health"}, "GET", "/Health", false},
+		{"method wildcard", SkipRule{Path: "/health", Methods: []string{"*"}}, "DELETE", "/health", true},
+		{"no methods match any", SkipRule{Path: "/health"}, "PATCH", "/health", true},
+		{"prefix", SkipRule{Path: "/api/v1/public", Prefix: true}, "GET", "/api/v1/public/rates/usd", true},
+		{"prefix itself", SkipRule{Path: "/api/v1/public", Prefix: true}, "GET", "/api/v1/public", true},
+		{"prefix segment boundary", SkipRule{Path: "/api/v1/public", Prefix: true}, "GET", "/api/v1/publicity", false},
+		{"trailing wildcard", SkipRule{Path: "/api/v1/public/*"}, "GET", "/api/v1/public/rates/usd", true},
+		{"trailing wildcard needs a segment", SkipRule{Path: "/api/v1/public/*"}, "GET", "/api/v1/public/", false},
+		{"inner wildcard", SkipRule{Path: "/api/*/auth/login"}, "POST", "/api/v2/auth/login", true},
+		{"inner wildcard one segment", SkipRule{Path: "/api/*/auth/login"}, "POST", "/api/v2/beta/auth/login", false},
+		{"inner wildcard not empty", SkipRule{Path: "/api/*/auth/login"}, "POST", "/api//auth/login", false},
+		{"template length", SkipRule{Path: "/shares/*/preview"}, "GET", "/shares/abc/preview/full", false},
+		{"options everywhere", SkipRule{Path: "/", Prefix: true, Methods: []string{"OPTIONS"}}, "options", "/api/v1/goals", true},
+		{"options only", SkipRule{Path: "/", Prefix: true, Methods: []string{"OPTIONS"}}, "GET", "/api/v1/goals", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.method, tt.path); got != tt.want {
+				t.Errorf("Matches(%q, %q) = %v, want %v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSkipRule(t *testing.T) {
+	rule, err := ParseSkipRule("GET|HEAD /api/v1/public/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Path != "/api/v1/public/*" || len(rule.Methods) != 2 || !rule.Matches("HEAD", "/api/v1/public/x") {
+		t.Errorf("rule = %+v", rule)
+	}
+	if rule, err := ParseSkipRule("/docs"); err != nil || len(rule.Methods) != 0 {
+		t.Errorf("path only: %+v, %v", rule, err)
+	}
+	for _, invalid := range []string{"", "GET docs", "GET /a /b"} {
+		if _, err := ParseSkipRule(invalid); err == nil {
+			t.Errorf("ParseSkipRule(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestAuthenticateSkipRules(t *testing.T) {
+	cfg := config.Load()
+	cfg.Auth.PublicPaths = []string{"GET /api/v1/public/*", "not a rule at all"}
+	m := NewAuthMiddleware(cfg, NewDeps(cfg))
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	status := func(method, target string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec.Code
+	}
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{http.MethodPost, "/api/v1/auth/login", http.StatusOK},
+		{http.MethodPost, "/api/v1/auth/login/", http.StatusOK},
+		{http.MethodPost, "/api/v1/auth/login?next=/goals", http.StatusOK},
+		{http.MethodGet, "/api/v1/auth/login", http.StatusUnauthorized},
+		{http.MethodGet, "/health?verbose=1", http.StatusOK},
+		{http.MethodOptions, "/api/v1/goals", http.StatusOK},
+		{http.MethodGet, "/api/v1/goals", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/public/rates", http.StatusOK},
+		{http.MethodPost, "/api/v1/public/rates", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := status(tt.method, tt.target); got != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, got, tt.want)
+		}
+	}
+
+	// Replacing the rules drops the defaults
+	m.SetSkipRules(SkipRule{Path: "/health", Methods: []string{"GET"}})
+	if got := status(http.MethodOptions, "/api/v1/goals"); got != http.StatusUnauthorized {
+		t.Errorf("OPTIONS after SetSkipRules = %d, want 401", got)
+	}
+	m.AddSkipRules(SkipRule{Path: "/", Prefix: true, Methods: []string{"OPTIONS"}})
+	if got := status(http.MethodOptions, "/api/v1/goals"); got != http.StatusOK {
+		t.Errorf("OPTIONS after AddSkipRules = %d, want 200", got)
+	}
+}
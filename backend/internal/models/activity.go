@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Activity feed item types
+const (
+	ActivityExpenseAdded      = "expense_added"
+	ActivityImportCompleted   = "import_completed"
+	ActivityGoalMilestone     = "goal_milestone"
+	ActivityInvestmentMatured = "investment_matured"
+	ActivityNewDeviceLogin    = "new_device_login"
+	ActivityPasswordChanged   = "password_changed"
+)
+
+// ActivityTypes lists the activity feed item types
+var ActivityTypes = EnumSet{Name: "activity_type", Values: []EnumValue{
+	{Value: ActivityExpenseAdded, Label: "Expense added"},
+	{Value: ActivityImportCompleted, Label: "Import completed"},
+	{Value: ActivityGoalMilestone, Label: "Goal milestone", Description: "A goal reached 25, 50, 75 or 100 percent"},
+	{Value: ActivityInvestmentMatured, Label: "Investment matured"},
+	{Value: ActivityNewDeviceLogin, Label: "New device login"},
+	{Value: ActivityPasswordChanged, Label: "Password changed"},
+}}
+
+// ActivityEvent is a raw event read from one of the tables behind the
+// activity feed
+type ActivityEvent struct {
+	// Key identifies the event across tables, e.g. "expense:<id>", and
+	// breaks ties between events at the same time
+	Key        string     `json:"key" db:"key"`
+	Type       string     `json:"type" db:"type"`
+	EntityType string     `json:"entity_type" db:"entity_type"`
+	EntityID   *uuid.UUID `json:"entity_id,omitempty" db:"entity_id"`
+	Label      *string    `json:"label,omitempty" db:"label"`
+	Amount     *float64   `json:"amount,omitempty" db:"amount"`
+	OccurredAt time.Time  `json:"occurred_at" db:"occurred_at"`
+	// GroupID collapses consecutive events of the same burst, such as the
+	// expenses of one import, into a single feed item
+	GroupID *uuid.UUID `json:"group_id,omitempty" db:"group_id"`
+}
+
+// ActivityItem is an entry of the activity feed
+type ActivityItem struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Title      string     `json:"title"`
+	Icon       string     `json:"icon"`
+	EntityType string     `json:"entity_type,omitempty"`
+	EntityID   *uuid.UUID `json:"entity_id,omitempty"`
+	Count      int        `json:"count,omitempty"`
+	OccurredAt time.Time  `json:"occurred_at"`
+}
+
+// ActivityFeed is a page of the activity feed
+type ActivityFeed struct {
+	Items      []ActivityItem `json:"items"`
+	NextCursor *string        `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Allocation rule triggers
+const (
+	AllocationTriggerIncomeCreated = "income_created"
+	AllocationTriggerSchedule      = "schedule"
+)
+
+// AllocationRule represents a rule that automatically contributes part of
+// the user's income to a financial goal
+type AllocationRule struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	GoalID      uuid.UUID `json:"goal_id" db:"goal_id"`
+	Name        string    `json:"name" db:"name"`
+	Trigger     string    `json:"trigger" db:"trigger"`
+	Schedule    *string   `json:"schedule,omitempty" db:"schedule"`
+	Percentage  *float64  `json:"percentage,omitempty" db:"percentage"`
+	FixedAmount *float64  `json:"fixed_amount,omitempty" db:"fixed_amount"`
+	Priority    int       `json:"priority" db:"priority"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// Relations
+	Goal *FinancialGoal `json:"goal,omitempty"`
+}
+
+// AllocationRuleCreateRequest represents the request to create an allocation rule
+type AllocationRuleCreateRequest struct {
+	GoalID      uuid.UUID `json:"goal_id" validate:"required"`
+	Name        string    `json:"name" validate:"required"`
+	Trigger     string    `json:"trigger" validate:"required,oneof=income_created schedule"`
+	Schedule    *string   `json:"schedule,omitempty"`
+	Percentage  *float64  `json:"percentage,omitempty" validate:"omitempty,gt=0,lte=100"`
+	FixedAmount *float64  `json:"fixed_amount,omitempty" validate:"omitempty,gt=0"`
+	Priority    int       `json:"priority" validate:"gte=0"`
+	Active      *bool     `json:"active,omitempty"`
+}
+
+// AllocationRuleUpdateRequest represents the request to update an allocation rule
+type AllocationRuleUpdateRequest struct {
+	GoalID      *uuid.UUID `json:"goal_id,omitempty"`
+	Name        *string    `json:"name,omitempty"`
+	Trigger     *string    `json:"trigger,omitempty" validate:"omitempty,oneof=income_created schedule"`
+	Schedule    *string    `json:"schedule,omitempty"`
+	Percentage  *float64   `json:"percentage,omitempty" validate:"omitempty,gt=0,lte=100"`
+	FixedAmount *float64   `json:"fixed_amount,omitempty" validate:"omitempty,gt=0"`
+	Priority    *int       `json:"priority,omitempty" validate:"omitempty,gte=0"`
+	Active      *bool      `json:"active,omitempty"`
+}
+
+// AllocationSimulationRequest represents the request to simulate allocating a hypothetical income
+type AllocationSimulationRequest struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+}
+
+// AllocationResult describes how an income amount was (or would be) allocated to goals
+type AllocationResult struct {
+	IncomeAmount    float64             `json:"income_amount"`
+	AllocatedAmount float64             `json:"allocated_amount"`
+	Remaining       float64             `json:"remaining"`
+	Allocations     []PlannedAllocation `json:"allocations"`
+	Skipped         []SkippedAllocation `json:"skipped,omitempty"`
+	Contributions   []GoalContribution  `json:"-"`
+}
+
+// PlannedAllocation represents a contribution produced by an allocation rule
+type PlannedAllocation struct {
+	RuleID   uuid.UUID `json:"rule_id"`
+	RuleName string    `json:"rule_name"`
+	GoalID   uuid.UUID `json:"goal_id"`
+	GoalName string    `json:"goal_name"`
+	Amount   float64   `json:"amount"`
+	Capped   bool      `json:"capped"`
+}
+
+// SkippedAllocation represents a rule that did not produce a contribution
+type SkippedAllocation struct {
+	RuleID   uuid.UUID `json:"rule_id"`
+	RuleName string    `json:"rule_name"`
+	GoalID   uuid.UUID `json:"goal_id"`
+	Reason   string    `json:"reason"`
+}
+
+// AllocationSuggestionRequest represents the request to confirm a suggested
+// split of a spare amount across goals
+type AllocationSuggestionRequest struct {
+	Amount  float64 `json:"amount" validate:"required,gt=0"`
+	Confirm bool    `json:"confirm,omitempty"`
+}
+
+// AllocationSuggestion describes how a spare amount could be split across
+// the user's active goals
+type AllocationSuggestion struct {
+	Amount          float64               `json:"amount"`
+	AllocatedAmount float64               `json:"allocated_amount"`
+	Remaining       float64               `json:"remaining"`
+	Suggestions     []SuggestedAllocation `json:"suggestions"`
+	Contributions   []GoalContribution    `json:"-"`
+}
+
+// SuggestedAllocation represents the suggested contribution to one goal
+type SuggestedAllocation struct {
+	GoalID   uuid.UUID         `json:"goal_id"`
+	GoalName string            `json:"goal_name"`
+	Amount   float64           `json:"amount"`
+	Capped   bool              `json:"capped"`
+	Factors  SuggestionFactors `json:"factors"`
+}
+
+// SuggestionFactors explains the weight a goal received in a suggestion
+type SuggestionFactors struct {
+	Priority        string   `json:"priority"`
+	PriorityWeight  float64  `json:"priority_weight"`
+	MonthsRemaining *float64 `json:"months_remaining,omitempty"`
+	UrgencyWeight   float64  `json:"urgency_weight"`
+	RemainingAmount float64  `json:"remaining_amount"`
+	Weight          float64  `json:"weight"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Announcement severities
+const (
+	AnnouncementSeverityInfo     = "info"
+	AnnouncementSeverityWarning  = "warning"
+	AnnouncementSeverityCritical = "critical"
+)
+
+// Announcement audiences
+const (
+	AnnouncementAudienceAll  = "all"
+	AnnouncementAudienceRole = "role"
+)
+
+// Announcement is an admin message shown to users between StartsAt and
+// EndsAt. Times are stored and compared in UTC.
+type Announcement struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	Title        string     `json:"title" db:"title"`
+	Body         string     `json:"body" db:"body"`
+	Severity     string     `json:"severity" db:"severity"`
+	StartsAt     time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	Audience     string     `json:"audience" db:"audience"`
+	AudienceRole *string    `json:"audience_role,omitempty" db:"audience_role"`
+	CreatedBy    *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Dismissed is set per caller when listing active announcements
+	Dismissed bool `json:"dismissed"`
+}
+
+// AnnouncementCreateRequest represents the request to create an announcement
+type AnnouncementCreateRequest struct {
+	Title        string     `json:"title" validate:"required"`
+	Body         string     `json:"body" validate:"required"`
+	Severity     string     `json:"severity" validate:"required,oneof=info warning critical"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	Audience     string     `json:"audience" validate:"required,oneof=all role"`
+	AudienceRole *string    `json:"audience_role,omitempty"`
+}
+
+// AnnouncementUpdateRequest represents the request to update an announcement
+type AnnouncementUpdateRequest struct {
+	Title        *string    `json:"title,omitempty"`
+	Body         *string    `json:"body,omitempty"`
+	Severity     *string    `json:"severity,omitempty" validate:"omitempty,oneof=info warning critical"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	Audience     *string    `json:"audience,omitempty" validate:"omitempty,oneof=all role"`
+	AudienceRole *string    `json:"audience_role,omitempty"`
+}
+
+// AnnouncementDismissal records that a user dismissed an announcement
+type AnnouncementDismissal struct {
+	AnnouncementID uuid.UUID `json:"announcement_id" db:"announcement_id"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	DismissedAt    time.Time `json:"dismissed_at" db:"dismissed_at"`
+}
+
+// IsActive returns true if the announcement is scheduled to show at now.
+// Expired announcements are inactive without being deleted.
+func (a *Announcement) IsActive(now time.Time) bool {
+	if now.Before(a.StartsAt) {
+		return false
+	}
+	return a.EndsAt == nil || now.Before(*a.EndsAt)
+}
+
+// IsVisibleTo returns true if the announcement targets users with role
+func (a *Announcement) IsVisibleTo(role string) bool {
+	if a.Audience != AnnouncementAudienceRole {
+		return true
+	}
+	return a.AudienceRole != nil && *a.AudienceRole == role
+}
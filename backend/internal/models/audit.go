@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit log actions
+const (
+	AuditActionIntegrityRepair = "integrity_repair"
+	AuditActionValueUpdate     = "value_update"
+	AuditActionActingFor       = "acting_for"
+	AuditActionPasswordChange  = "password_change"
+	AuditActionSharingChange   = "sharing_change"
+)
+
+// AuditLog represents a recorded mutation
+type AuditLog struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	UserID      *uuid.UUID      `json:"user_id,omitempty" db:"user_id"`
+	ActorUserID *uuid.UUID      `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	Action      string          `json:"action" db:"action"`
+	EntityType  string          `json:"entity_type" db:"entity_type"`
+	EntityID    *uuid.UUID      `json:"entity_id,omitempty" db:"entity_id"`
+	OldValue    json.RawMessage `json:"old_value,omitempty" db:"old_value"`
+	NewValue    json.RawMessage `json:"new_value,omitempty" db:"new_value"`
+	Reason      *string         `json:"reason,omitempty" db:"reason"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// OldAmount returns the old value as an amount, or nil if it is not numeric
+func (a *AuditLog) OldAmount() *float64 {
+	return rawAmount(a.OldValue)
+}
+
+// NewAmount returns the new value as an amount, or nil if it is not numeric
+func (a *AuditLog) NewAmount() *float64 {
+	return rawAmount(a.NewValue)
+}
+
+// rawAmount decodes a JSON number
+func rawAmount(raw json.RawMessage) *float64 {
+	if len(raw) == 0 {
+		return nil
+	}
+	var amount float64
+	if err := json.Unmarshal(raw, &amount); err != nil {
+		return nil
+	}
+	return &amount
+}
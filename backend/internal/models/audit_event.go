@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit event types. Handlers may record domain events such as
+// "goal_deleted" alongside these.
+const (
+	AuditEventLogin          = "login"
+	AuditEventLogout         = "logout"
+	AuditEventAuthFailed     = "auth_failed"
+	AuditEventPasswordChange = "password_change"
+	AuditEventAPIMutation    = "api_mutation"
+)
+
+// Audit event outcomes
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is an immutable record of a security-relevant action
+type AuditEvent struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Type       string     `json:"event_type" db:"event_type"`
+	UserID     *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Method     string     `json:"method,omitempty" db:"method"`
+	Route      string     `json:"route,omitempty" db:"route"`
+	ResourceID *uuid.UUID `json:"resource_id,omitempty" db:"resource_id"`
+	IPAddress  string     `json:"ip_address,omitempty" db:"ip_address"`
+	Outcome    string     `json:"outcome" db:"outcome"`
+	StatusCode int        `json:"status_code,omitempty" db:"status_code"`
+	Detail     string     `json:"detail,omitempty" db:"detail"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+)
+
+// Budget periods
+const (
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodYearly  = "yearly"
+)
+
+// Budget carryover modes
+const (
+	CarryoverNone       = "none"
+	CarryoverUnderspend = "underspend"
+	CarryoverOverspend  = "overspend"
+	CarryoverBoth       = "both"
+)
+
+// Budget represents a spending limit for a category over a recurring period
+type Budget struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
+	CategoryID    uuid.UUID  `json:"category_id" db:"category_id"`
+	Amount        float64    `json:"amount" db:"amount"`
+	Period        string     `json:"period" db:"period"`
+	StartDate     time.Time  `json:"start_date" db:"start_date"`
+	EndDate       *time.Time `json:"end_date,omitempty" db:"end_date"`
+	ProRate       bool       `json:"pro_rate" db:"pro_rate"`
+	CarryoverMode string     `json:"carryover_mode" db:"carryover_mode"`
+	CarryoverCap  *float64   `json:"carryover_cap,omitempty" db:"carryover_cap"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Relations
+	Category *ExpenseCategory `json:"category,omitempty"`
+}
+
+// BudgetCreateRequest represents the request to create a new budget
+type BudgetCreateRequest struct {
+	CategoryID    uuid.UUID  `json:"category_id" validate:"required"`
+	Amount        float64    `json:"amount" validate:"required,gt=0"`
+	Period        string     `json:"period" validate:"required,oneof=weekly monthly yearly"`
+	StartDate     time.Time  `json:"start_date" validate:"required"`
+	EndDate       *time.Time `json:"end_date,omitempty"`
+	ProRate       *bool      `json:"pro_rate,omitempty"`
+	CarryoverMode *string    `json:"carryover_mode,omitempty" validate:"omitempty,oneof=none underspend overspend both"`
+	CarryoverCap  *float64   `json:"carryover_cap,omitempty" validate:"omitempty,gte=0"`
+}
+
+// BudgetUpdateRequest represents the request to update a budget
+type BudgetUpdateRequest struct {
+	Amount        *float64   `json:"amount,omitempty" validate:"omitempty,gt=0"`
+	EndDate       *time.Time `json:"end_date,omitempty"`
+	ProRate       *bool      `json:"pro_rate,omitempty"`
+	CarryoverMode *string    `json:"carryover_mode,omitempty" validate:"omitempty,oneof=none underspend overspend both"`
+	CarryoverCap  *float64   `json:"carryover_cap,omitempty" validate:"omitempty,gte=0"`
+}
+
+// BudgetStatus represents a budget's position within one period. The nominal
+// amount is the configured budget; the effective amount is what spending is
+// compared against after pro-rating and carryover.
+type BudgetStatus struct {
+	BudgetID        uuid.UUID `json:"budget_id"`
+	PeriodStart     time.Time `json:"period_start"`
+	PeriodEnd       time.Time `json:"period_end"`
+	NominalAmount   float64   `json:"nominal_amount"`
+	ProRated        bool      `json:"pro_rated"`
+	ProRatedAmount  float64   `json:"pro_rated_amount"`
+	ActiveDays      int       `json:"active_days"`
+	PeriodDays      int       `json:"period_days"`
+	Carryover       float64   `json:"carryover"`
+	EffectiveAmount float64   `json:"effective_amount"`
+	Spent           float64   `json:"spent"`
+	Remaining       float64   `json:"remaining"`
+	PercentUsed     float64   `json:"percent_used"`
+}
+
+// PeriodBounds returns the half-open [start, end) period containing at,
+// evaluated in loc. Weeks start on Monday.
+func (b *Budget) PeriodBounds(at time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	at = at.In(loc)
+	year, month, day := at.Date()
+
+	switch b.Period {
+	case BudgetPeriodWeekly:
+		offset := (int(at.Weekday()) + 6) % 7
+		start := time.Date(year, month, day-offset, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7), nil
+	case BudgetPeriodMonthly:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0), nil
+	case BudgetPeriodYearly:
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0), nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown budget period %q", b.Period)
+	}
+}
+
+// localStartDate returns the budget's start date as midnight in loc. Start
+// dates are calendar dates and carry no time zone of their own.
+func (b *Budget) localStartDate(loc *time.Location) time.Time {
+	year, month, day := b.StartDate.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// periodAmount returns the budget available within [start, end), scaled by
+// the days the budget is active when pro-rating is enabled
+func (b *Budget) periodAmount(start, end time.Time, loc *time.Location) (amount float64, activeDays, periodDays int) {
+	periodDays = calendarDays(start, end)
+	activeDays = periodDays
+
+	budgetStart := b.localStartDate(loc)
+	if !budgetStart.After(start) {
+		return b.Amount, activeDays, periodDays
+	}
+	if !budgetStart.Before(end) {
+		return 0, 0, periodDays
+	}
+
+	activeDays = calendarDays(budgetStart, end)
+	if !b.ProRate {
+		return b.Amount, activeDays, periodDays
+	}
+	return finance.RoundCents(b.Amount * float64(activeDays) / float64(periodDays)), activeDays, periodDays
+}
+
+// carries returns true if the budget carries the given difference forward
+func (b *Budget) carries(diff float64) bool {
+	switch b.CarryoverMode {
+	case CarryoverUnderspend:
+		return diff > 0
+	case CarryoverOverspend:
+		return diff < 0
+	case CarryoverBoth:
+		return diff != 0
+	default:
+		return false
+	}
+}
+
+// ComputeStatus returns the budget status for the period containing at.
+// spent is the spending within that period and previousSpent the spending in
+// the period before it, used only when carryover is enabled. Carryover looks
+// back a single period and is capped at CarryoverCap, or at the nominal amount
+// when no cap is set.
+func (b *Budget) ComputeStatus(at time.Time, loc *time.Location, spent, previousSpent float64) (*BudgetStatus, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	start, end, err := b.PeriodBounds(at, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BudgetStatus{
+		BudgetID:      b.ID,
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		NominalAmount: b.Amount,
+		Spent:         finance.RoundCents(spent),
+	}
+
+	status.ProRatedAmount, status.ActiveDays, status.PeriodDays = b.periodAmount(start, end, loc)
+	status.ProRated = status.ProRatedAmount != b.Amount
+
+	if b.CarryoverMode != "" && b.CarryoverMode != CarryoverNone {
+		prevStart, prevEnd, _ := b.PeriodBounds(start.Add(-time.Nanosecond), loc)
+		if b.localStartDate(loc).Before(prevEnd) {
+			prevAmount, _, _ := b.periodAmount(prevStart, prevEnd, loc)
+			diff := finance.RoundCents(prevAmount - previousSpent)
+			if b.carries(diff) {
+				limit := b.Amount
+				if b.CarryoverCap != nil {
+					limit = *b.CarryoverCap
+				}
+				status.Carryover = math.Max(-limit, math.Min(limit, diff))
+			}
+		}
+	}
+
+	status.EffectiveAmount = finance.RoundCents(math.Max(0, status.ProRatedAmount+status.Carryover))
+	status.Remaining = finance.RoundCents(status.EffectiveAmount - status.Spent)
+	status.PercentUsed = percentageOf(status.Spent, status.EffectiveAmount)
+
+	return status, nil
+}
+
+// SpentInPeriod sums the owner's share of the category's expenses within
+// [start, end), excluding mirrored expenses that have not been accepted
+func (b *Budget) SpentInPeriod(expenses []Expense, start, end time.Time) float64 {
+	total := 0.0
+	for i := range expenses {
+		expense := &expenses[i]
+		if expense.CategoryID != b.CategoryID || expense.IsPendingMirror() {
+			continue
+		}
+		if expense.ExpenseDate.Before(start) || !expense.ExpenseDate.Before(end) {
+			continue
+		}
+		total += expense.MyShare
+	}
+	return finance.RoundCents(total)
+}
+
+// calendarDays counts the calendar days in [start, end), independent of
+// daylight saving transitions
+func calendarDays(start, end time.Time) int {
+	sy, sm, sd := start.Date()
+	ey, em, ed := end.Date()
+	from := time.Date(sy, sm, sd, 0, 0, 0, 0, time.UTC)
+	to := time.Date(ey, em, ed, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
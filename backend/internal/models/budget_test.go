@@ -0,0 +1,176 @@
+package models
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Failed to load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestBudgetPeriodBoundsAcrossTimezones(t *testing.T) {
+	// 2024-03-31 23:30 UTC is already April in Tokyo and still March in New York
+	at := time.Date(2024, time.March, 31, 23, 30, 0, 0, time.UTC)
+	budget := Budget{Period: BudgetPeriodMonthly}
+
+	tests := []struct {
+		location  string
+		wantMonth time.Month
+		wantDays  int
+	}{
+		{"UTC", time.March, 31},
+		{"America/New_York", time.March, 31},
+		{"Asia/Tokyo", time.April, 30},
+		{"Pacific/Auckland", time.April, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			loc := mustLoadLocation(t, tt.location)
+			start, end, err := budget.PeriodBounds(at, loc)
+			if err != nil {
+				t.Fatalf("PeriodBounds() error = %v", err)
+			}
+			if start.Month() != tt.wantMonth || start.Day() != 1 || start.Hour() != 0 {
+				t.Errorf("Unexpected period start %v", start)
+			}
+			if days := calendarDays(start, end); days != tt.wantDays {
+				t.Errorf("Expected %d days, got %d", tt.wantDays, days)
+			}
+			if at.Before(start) || !at.Before(end) {
+				t.Errorf("%v is not within [%v, %v)", at, start, end)
+			}
+		})
+	}
+
+	weekly := Budget{Period: BudgetPeriodWeekly}
+	start, end, _ := weekly.PeriodBounds(time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC), time.UTC)
+	if start.Weekday() != time.Monday || start.Day() != 4 || end.Day() != 11 {
+		t.Errorf("Unexpected week bounds [%v, %v)", start, end)
+	}
+}
+
+func TestBudgetProRating(t *testing.T) {
+	// The US spring-forward transition on March 10 must not shorten the month
+	loc := mustLoadLocation(t, "America/New_York")
+	budget := Budget{
+		ID:        uuid.New(),
+		Amount:    310,
+		Period:    BudgetPeriodMonthly,
+		StartDate: time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC),
+		ProRate:   true,
+	}
+	at := time.Date(2024, time.March, 20, 12, 0, 0, 0, loc)
+
+	status, err := budget.ComputeStatus(at, loc, 50, 0)
+	if err != nil {
+		t.Fatalf("ComputeStatus() error = %v", err)
+	}
+	if status.PeriodDays != 31 || status.ActiveDays != 21 {
+		t.Errorf("Expected 21 of 31 days, got %d of %d", status.ActiveDays, status.PeriodDays)
+	}
+	if status.NominalAmount != 310 || status.EffectiveAmount != 210 || !status.ProRated {
+		t.Errorf("Expected nominal 310 pro-rated to 210, got %+v", status)
+	}
+	if status.Remaining != 160 {
+		t.Errorf("Expected remaining 160, got %v", status.Remaining)
+	}
+
+	budget.ProRate = false
+	status, _ = budget.ComputeStatus(at, loc, 50, 0)
+	if status.EffectiveAmount != 310 || status.ProRated {
+		t.Errorf("Pro-rating disabled should use the full amount, got %+v", status)
+	}
+
+	// The following month is a full period
+	budget.ProRate = true
+	status, _ = budget.ComputeStatus(time.Date(2024, time.April, 5, 0, 0, 0, 0, loc), loc, 0, 0)
+	if status.EffectiveAmount != 310 || status.ProRated {
+		t.Errorf("Expected full amount in the next period, got %+v", status)
+	}
+}
+
+func TestBudgetCarryover(t *testing.T) {
+	loc := mustLoadLocation(t, "Europe/Berlin")
+	at := time.Date(2024, time.May, 15, 0, 0, 0, 0, loc)
+	carryoverCap := 50.0
+
+	tests := []struct {
+		name          string
+		mode          string
+		cap           *float64
+		previousSpent float64
+		wantCarryover float64
+	}{
+		{"none", CarryoverNone, nil, 100, 0},
+		{"underspend carried", CarryoverUnderspend, nil, 150, 50},
+		{"overspend ignored in underspend mode", CarryoverUnderspend, nil, 250, 0},
+		{"overspend carried", CarryoverOverspend, nil, 250, -50},
+		{"underspend capped", CarryoverBoth, &carryoverCap, 100, 50},
+		{"overspend capped", CarryoverBoth, &carryoverCap, 400, -50},
+		{"default cap is nominal amount", CarryoverBoth, nil, 600, -200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := Budget{
+				Amount:        200,
+				Period:        BudgetPeriodMonthly,
+				StartDate:     time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				CarryoverMode: tt.mode,
+				CarryoverCap:  tt.cap,
+			}
+			status, err := budget.ComputeStatus(at, loc, 0, tt.previousSpent)
+			if err != nil {
+				t.Fatalf("ComputeStatus() error = %v", err)
+			}
+			if status.Carryover != tt.wantCarryover {
+				t.Errorf("Expected carryover %v, got %v", tt.wantCarryover, status.Carryover)
+			}
+			if status.NominalAmount != 200 || status.EffectiveAmount != finance.RoundCents(200+tt.wantCarryover) {
+				t.Errorf("Unexpected amounts: %+v", status)
+			}
+		})
+	}
+
+	// A budget starting this period has nothing to carry over
+	budget := Budget{Amount: 200, Period: BudgetPeriodMonthly, StartDate: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), CarryoverMode: CarryoverBoth}
+	status, _ := budget.ComputeStatus(at, loc, 0, 0)
+	if status.Carryover != 0 {
+		t.Errorf("Expected no carryover for a new budget, got %v", status.Carryover)
+	}
+}
+
+func TestBudgetSpentInPeriod(t *testing.T) {
+	food := uuid.New()
+	budget := Budget{CategoryID: food, Period: BudgetPeriodMonthly}
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	pending := MirrorStatusPending
+	source := uuid.New()
+
+	expenses := []Expense{
+		{CategoryID: food, Amount: 120, MyShare: 40, ExpenseDate: start, Splits: []ExpenseSplit{
+			{IsOwner: true, ShareAmount: 40},
+			{ParticipantName: stringPtr("Alice"), ShareAmount: 80},
+		}},
+		{CategoryID: food, Amount: 30, MyShare: 30, ExpenseDate: end},
+		{CategoryID: uuid.New(), Amount: 30, MyShare: 30, ExpenseDate: start},
+		{CategoryID: food, Amount: 25, MyShare: 25, ExpenseDate: start, SourceSplitID: &source, MirrorStatus: &pending},
+	}
+
+	if spent := budget.SpentInPeriod(expenses, start, end); spent != 40 {
+		t.Errorf("Expected spent 40, got %v", spent)
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit log actions of categorization rules
+const (
+	AuditActionRuleRecategorize = "rule_recategorize"
+	AuditActionRuleRevert       = "rule_revert"
+)
+
+// Rule job kinds and statuses
+const (
+	RuleJobApply  = "apply"
+	RuleJobRevert = "revert"
+
+	RuleJobRunning   = "running"
+	RuleJobSucceeded = "succeeded"
+	RuleJobFailed    = "failed"
+)
+
+// CategorizationRule assigns a category to expenses matching every one of
+// its criteria. DescriptionPattern is a case-insensitive glob where * matches
+// any run of characters; Merchant matches the normalized description.
+// Higher priorities win when several rules match.
+type CategorizationRule struct {
+	ID                 uuid.UUID `json:"id" db:"id"`
+	UserID             uuid.UUID `json:"user_id" db:"user_id"`
+	Name               string    `json:"name" db:"name"`
+	DescriptionPattern *string   `json:"description_pattern,omitempty" db:"description_pattern"`
+	Merchant           *string   `json:"merchant,omitempty" db:"merchant"`
+	MinAmount          *float64  `json:"min_amount,omitempty" db:"min_amount"`
+	MaxAmount          *float64  `json:"max_amount,omitempty" db:"max_amount"`
+	PaymentMethod      *string   `json:"payment_method,omitempty" db:"payment_method"`
+	CategoryID         uuid.UUID `json:"category_id" db:"category_id"`
+	Priority           int       `json:"priority" db:"priority"`
+	IsActive           bool      `json:"is_active" db:"is_active"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CategorizationRuleRequest represents the request to create or replace a
+// categorization rule
+type CategorizationRuleRequest struct {
+	Name               string    `json:"name"`
+	DescriptionPattern *string   `json:"description_pattern,omitempty"`
+	Merchant           *string   `json:"merchant,omitempty"`
+	MinAmount          *float64  `json:"min_amount,omitempty"`
+	MaxAmount          *float64  `json:"max_amount,omitempty"`
+	PaymentMethod      *string   `json:"payment_method,omitempty"`
+	CategoryID         uuid.UUID `json:"category_id"`
+	Priority           int       `json:"priority"`
+	IsActive           *bool     `json:"is_active,omitempty"`
+}
+
+// CategorizationRuleApplyRequest selects the rules to apply; all active
+// rules are applied when RuleIDs is empty
+type CategorizationRuleApplyRequest struct {
+	RuleIDs []uuid.UUID `json:"rule_ids,omitempty"`
+}
+
+// RuleChange is an expense re-categorized by a rule
+type RuleChange struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	ApplyID       uuid.UUID  `json:"apply_id" db:"apply_id"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
+	RuleID        uuid.UUID  `json:"rule_id" db:"rule_id"`
+	ExpenseID     uuid.UUID  `json:"expense_id" db:"expense_id"`
+	OldCategoryID uuid.UUID  `json:"old_category_id" db:"old_category_id"`
+	NewCategoryID uuid.UUID  `json:"new_category_id" db:"new_category_id"`
+	RevertedAt    *time.Time `json:"reverted_at,omitempty" db:"reverted_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RulePreview reports how many existing expenses a rule would affect
+type RulePreview struct {
+	RuleID  uuid.UUID `json:"rule_id"`
+	Matches int       `json:"matches"`
+	// Changes counts matches that are not in the rule's category already
+	// and that no higher-priority rule claims
+	Changes int `json:"changes"`
+}
+
+// RuleJob reports the progress of applying or reverting rules
+type RuleJob struct {
+	ID         uuid.UUID  `json:"id"`
+	Kind       string     `json:"kind"`
+	ApplyID    uuid.UUID  `json:"apply_id"`
+	Status     string     `json:"status"`
+	Processed  int        `json:"processed"`
+	Total      int        `json:"total"`
+	Changed    int        `json:"changed"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
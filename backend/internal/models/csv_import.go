@@ -0,0 +1,98 @@
+package models
+
+import "github.com/google/uuid"
+
+// CSV decimal separators
+const (
+	DecimalPoint = "."
+	DecimalComma = ","
+)
+
+// CSV date orders
+const (
+	DateOrderDMY = "DMY"
+	DateOrderMDY = "MDY"
+	DateOrderYMD = "YMD"
+)
+
+// CSVDecimalSeparators lists the decimal separators of CSV amounts
+var CSVDecimalSeparators = EnumSet{Name: "csv_decimal", Values: []EnumValue{
+	{Value: DecimalPoint, Label: "Point", Description: "1,234.56"},
+	{Value: DecimalComma, Label: "Comma", Description: "1.234,56"},
+}}
+
+// CSVDateOrders lists the date orders of CSV dates
+var CSVDateOrders = EnumSet{Name: "csv_date_order", Values: []EnumValue{
+	{Value: DateOrderDMY, Label: "Day/Month/Year", Description: "31/01/2024"},
+	{Value: DateOrderMDY, Label: "Month/Day/Year", Description: "01/31/2024"},
+	{Value: DateOrderYMD, Label: "Year-Month-Day", Description: "2024-01-31"},
+}}
+
+// CSVColumnMapping names the header of each column imported from a CSV file
+type CSVColumnMapping struct {
+	Date        string  `json:"date" validate:"required"`
+	Amount      string  `json:"amount" validate:"required"`
+	Description string  `json:"description" validate:"required"`
+	Category    *string `json:"category,omitempty"`
+}
+
+// CSVImportSettings are the number and date conventions a CSV file is parsed with
+type CSVImportSettings struct {
+	Delimiter string `json:"delimiter" validate:"required"`
+	Decimal   string `json:"decimal" validate:"required"`
+	DateOrder string `json:"date_order" validate:"required"`
+}
+
+// CSVImportRequest represents the options of a CSV expense import. Settings
+// are detected when omitted on a dry run and must be given to commit, so a
+// re-run parses the file exactly as the confirmed dry run did.
+type CSVImportRequest struct {
+	Mapping    CSVColumnMapping   `json:"mapping"`
+	Settings   *CSVImportSettings `json:"settings,omitempty"`
+	CategoryID uuid.UUID          `json:"category_id" validate:"required"`
+}
+
+// CSVDetection reports the conventions inferred from a sample of a CSV file
+type CSVDetection struct {
+	SampledRows       int     `json:"sampled_rows"`
+	Delimiter         string  `json:"delimiter"`
+	Decimal           string  `json:"decimal"`
+	DecimalConfidence float64 `json:"decimal_confidence"`
+	DateOrder         string  `json:"date_order"`
+	DateConfidence    float64 `json:"date_confidence"`
+	// Ambiguous is set when the sample cannot tell the conventions apart,
+	// such as dates whose day never exceeds 12, and the UI must ask the user
+	Ambiguous bool     `json:"ambiguous"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// Settings returns the detected conventions as import settings
+func (d *CSVDetection) Settings() CSVImportSettings {
+	return CSVImportSettings{Delimiter: d.Delimiter, Decimal: d.Decimal, DateOrder: d.DateOrder}
+}
+
+// CSVRowError describes a CSV row that could not be imported
+type CSVRowError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// CSVImportPreview is the dry-run response of a CSV import
+type CSVImportPreview struct {
+	Detection *CSVDetection          `json:"detection,omitempty"`
+	Settings  CSVImportSettings      `json:"settings"`
+	TotalRows int                    `json:"total_rows"`
+	ValidRows int                    `json:"valid_rows"`
+	Rows      []ExpenseCreateRequest `json:"rows"`
+	Errors    []CSVRowError          `json:"errors,omitempty"`
+}
+
+// CSVImportResult reports the outcome of a committed CSV import
+type CSVImportResult struct {
+	ImportID  *uuid.UUID        `json:"import_id,omitempty"`
+	Settings  CSVImportSettings `json:"settings"`
+	TotalRows int               `json:"total_rows"`
+	Imported  int               `json:"imported"`
+	Errors    []CSVRowError     `json:"errors,omitempty"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dead letter statuses
+const (
+	DeadLetterStatusDead      = "dead"
+	DeadLetterStatusRequeued  = "requeued"
+	DeadLetterStatusDiscarded = "discarded"
+)
+
+// Delivery error classes
+const (
+	ErrorClassTimeout     = "timeout"
+	ErrorClassConnection  = "connection"
+	ErrorClassRateLimited = "rate_limited"
+	ErrorClassClient      = "client_error"
+	ErrorClassServer      = "server_error"
+	ErrorClassPayload     = "invalid_payload"
+	ErrorClassUnknown     = "unknown"
+)
+
+// AuditActionDeadLetterDiscard is recorded when an operator discards a dead letter
+const AuditActionDeadLetterDiscard = "dead_letter_discard"
+
+// DeadLetter is an outbox event whose delivery failed after all retries
+type DeadLetter struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	EventID     uuid.UUID       `json:"event_id" db:"event_id"`
+	EventType   string          `json:"event_type" db:"event_type"`
+	UserID      *uuid.UUID      `json:"user_id,omitempty" db:"user_id"`
+	OrderingKey string          `json:"ordering_key" db:"ordering_key"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	ErrorClass  string          `json:"error_class" db:"error_class"`
+	LastError   string          `json:"last_error" db:"last_error"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	Status      string          `json:"status" db:"status"`
+	DeadAt      time.Time       `json:"dead_at" db:"dead_at"`
+	RequeuedAt  *time.Time      `json:"requeued_at,omitempty" db:"requeued_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+
+	// Discarding requires a reason, which is also written to the audit log
+	DiscardedAt   *time.Time `json:"discarded_at,omitempty" db:"discarded_at"`
+	DiscardedBy   *uuid.UUID `json:"discarded_by,omitempty" db:"discarded_by"`
+	DiscardReason *string    `json:"discard_reason,omitempty" db:"discard_reason"`
+}
+
+// DeadLetterFilter represents filters for dead letter queries
+type DeadLetterFilter struct {
+	EventType  *string    `json:"event_type,omitempty"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	ErrorClass *string    `json:"error_class,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	Limit      int        `json:"limit,omitempty"`
+	Offset     int        `json:"offset,omitempty"`
+}
+
+// DeadLetterBulkRetryRequest represents the request to retry every dead letter of an error class
+type DeadLetterBulkRetryRequest struct {
+	ErrorClass string `json:"error_class" validate:"required"`
+}
+
+// DeadLetterDiscardRequest represents the request to discard a dead letter
+type DeadLetterDiscardRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// DeadLetterRetryResult reports the outcome of a bulk retry
+type DeadLetterRetryResult struct {
+	Requeued int                 `json:"requeued"`
+	Failed   []DeadLetterFailure `json:"failed,omitempty"`
+}
+
+// DeadLetterFailure describes a dead letter that could not be requeued
+type DeadLetterFailure struct {
+	ID    uuid.UUID `json:"id"`
+	Error string    `json:"error"`
+}
+
+// ErrorClasses lists the delivery error classes
+var ErrorClasses = EnumSet{Name: "error_class", Values: []EnumValue{
+	{Value: ErrorClassTimeout, Label: "Timeout"},
+	{Value: ErrorClassConnection, Label: "Connection", Description: "The endpoint could not be reached"},
+	{Value: ErrorClassRateLimited, Label: "Rate limited"},
+	{Value: ErrorClassClient, Label: "Client error", Description: "The endpoint rejected the request"},
+	{Value: ErrorClassServer, Label: "Server error", Description: "The endpoint failed to handle the request"},
+	{Value: ErrorClassPayload, Label: "Invalid payload", Description: "The event could not be encoded or was rejected as malformed"},
+	{Value: ErrorClassUnknown, Label: "Unknown"},
+}}
+
+// IsRetryable returns true if the dead letter can be requeued
+func (d *DeadLetter) IsRetryable() bool {
+	return d.Status == DeadLetterStatusDead
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// EnumValue describes one value accepted by the API
+type EnumValue struct {
+	Value       string `json:"value"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+}
+
+// EnumSet is an ordered set of accepted values. The sets below are the
+// source of truth for both the validators and GET /api/v1/meta/enums.
+type EnumSet struct {
+	Name   string      `json:"name"`
+	Values []EnumValue `json:"values"`
+}
+
+// Valid returns true if value is a member of the set. Deprecated values
+// are still accepted.
+func (s EnumSet) Valid(value string) bool {
+	for _, v := range s.Values {
+		if v.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings returns the values of the set in order
+func (s EnumSet) Strings() []string {
+	values := make([]string, len(s.Values))
+	for i, v := range s.Values {
+		values[i] = v.Value
+	}
+	return values
+}
+
+// Message returns the validation message for an invalid value of field
+func (s EnumSet) Message(field string) string {
+	return field + " must be one of " + strings.Join(s.Strings(), ", ")
+}
+
+// Enumerations accepted by the API
+var (
+	GoalTypes = EnumSet{Name: "goal_type", Values: []EnumValue{
+		{Value: GoalTypeSavings, Label: "Savings", Description: "Set money aside for a general purpose"},
+		{Value: GoalTypeInvestment, Label: "Investment", Description: "Build up an investment position"},
+		{Value: GoalTypeDebtPayoff, Label: "Debt payoff", Description: "Pay off a loan or credit balance"},
+		{Value: GoalTypePurchase, Label: "Purchase", Description: "Save for a specific purchase"},
+		{Value: GoalTypeEmergencyFund, Label: "Emergency fund", Description: "Keep a reserve for unexpected expenses"},
+	}}
+	GoalPriorities = EnumSet{Name: "goal_priority", Values: []EnumValue{
+		{Value: GoalPriorityLow, Label: "Low"},
+		{Value: GoalPriorityMedium, Label: "Medium"},
+		{Value: GoalPriorityHigh, Label: "High"},
+	}}
+	GoalStatuses = EnumSet{Name: "goal_status", Values: []EnumValue{
+		{Value: GoalStatusActive, Label: "Active", Description: "Accepting contributions"},
+		{Value: GoalStatusCompleted, Label: "Completed", Description: "Target amount reached"},
+		{Value: GoalStatusCancelled, Label: "Cancelled", Description: "No longer pursued"},
+	}}
+	InvestmentStatuses = EnumSet{Name: "investment_status", Values: []EnumValue{
+		{Value: InvestmentStatusActive, Label: "Active"},
+		{Value: InvestmentStatusMatured, Label: "Matured", Description: "Reached its maturity date"},
+		{Value: InvestmentStatusCancelled, Label: "Cancelled"},
+		{Value: InvestmentStatusClosed, Label: "Closed", Description: "Fully withdrawn"},
+	}}
+	RiskLevels = EnumSet{Name: "risk_level", Values: []EnumValue{
+		{Value: RiskLevelLow, Label: "Low"},
+		{Value: RiskLevelMedium, Label: "Medium"},
+		{Value: RiskLevelHigh, Label: "High"},
+	}}
+	TransactionTypes = EnumSet{Name: "transaction_type", Values: []EnumValue{
+		{Value: TransactionTypeDeposit, Label: "Deposit", Description: "Money added to the investment"},
+		{Value: TransactionTypeWithdrawal, Label: "Withdrawal", Description: "Money taken out of the investment"},
+		{Value: TransactionTypeInterest, Label: "Interest", Description: "Interest credited to the investment"},
+		{Value: TransactionTypeDividend, Label: "Dividend", Description: "Dividend credited to the investment"},
+		{Value: TransactionTypeFee, Label: "Fee", Description: "Fee charged against the investment"},
+	}}
+	IncomeSources = EnumSet{Name: "income_source", Values: []EnumValue{
+		{Value: IncomeSourceSalary, Label: "Salary"},
+		{Value: IncomeSourceReimbursement, Label: "Reimbursement", Description: "Repayment of a shared expense"},
+		{Value: IncomeSourceOther, Label: "Other"},
+	}}
+	BudgetPeriods = EnumSet{Name: "budget_period", Values: []EnumValue{
+		{Value: BudgetPeriodWeekly, Label: "Weekly"},
+		{Value: BudgetPeriodMonthly, Label: "Monthly"},
+		{Value: BudgetPeriodYearly, Label: "Yearly"},
+	}}
+	CarryoverModes = EnumSet{Name: "carryover_mode", Values: []EnumValue{
+		{Value: CarryoverNone, Label: "None", Description: "Each period starts fresh"},
+		{Value: CarryoverUnderspend, Label: "Underspend", Description: "Unspent budget rolls into the next period"},
+		{Value: CarryoverOverspend, Label: "Overspend", Description: "Overspending reduces the next period"},
+		{Value: CarryoverBoth, Label: "Both", Description: "Both underspend and overspend carry over"},
+	}}
+	AllocationTriggers = EnumSet{Name: "allocation_trigger", Values: []EnumValue{
+		{Value: AllocationTriggerIncomeCreated, Label: "Income received", Description: "Runs when an income is recorded"},
+		{Value: AllocationTriggerSchedule, Label: "Schedule", Description: "Runs on a schedule"},
+	}}
+	SpendingLimitScopes = EnumSet{Name: "spending_limit_scope", Values: []EnumValue{
+		{Value: SpendingLimitScopeCategory, Label: "Category"},
+		{Value: SpendingLimitScopeMerchant, Label: "Merchant", Description: "Matches expense descriptions by pattern"},
+	}}
+	SpendingLimitActions = EnumSet{Name: "spending_limit_action", Values: []EnumValue{
+		{Value: SpendingLimitActionWarn, Label: "Warn", Description: "Allow the expense with a warning"},
+		{Value: SpendingLimitActionBlock, Label: "Block", Description: "Reject the expense unless confirmed"},
+	}}
+	MembershipRoles = EnumSet{Name: "membership_role", Values: []EnumValue{
+		{Value: MembershipRoleAdvisor, Label: "Advisor"},
+		{Value: MembershipRoleClient, Label: "Client"},
+	}}
+	AnnouncementSeverities = EnumSet{Name: "announcement_severity", Values: []EnumValue{
+		{Value: AnnouncementSeverityInfo, Label: "Info"},
+		{Value: AnnouncementSeverityWarning, Label: "Warning"},
+		{Value: AnnouncementSeverityCritical, Label: "Critical"},
+	}}
+	AnnouncementAudiences = EnumSet{Name: "announcement_audience", Values: []EnumValue{
+		{Value: AnnouncementAudienceAll, Label: "Everyone"},
+		{Value: AnnouncementAudienceRole, Label: "Role", Description: "Only users with audience_role"},
+	}}
+)
+
+// Enums returns every enumeration accepted by the API
+func Enums() []EnumSet {
+	return []EnumSet{
+		GoalTypes, GoalPriorities, GoalStatuses,
+		InvestmentStatuses, RiskLevels, TransactionTypes,
+		IncomeSources, BudgetPeriods, CarryoverModes, AllocationTriggers,
+		SpendingLimitScopes, SpendingLimitActions,
+		MembershipRoles, AnnouncementSeverities, AnnouncementAudiences,
+		ErrorClasses, CSVDecimalSeparators, CSVDateOrders, ActivityTypes,
+		SharingLevels, SharedEntityTypes,
+	}
+}
+
+var (
+	enumsETagOnce sync.Once
+	enumsETag     string
+)
+
+// EnumsETag returns a strong ETag derived from a hash of the enum definitions
+func EnumsETag() string {
+	enumsETagOnce.Do(func() {
+		data, _ := json.Marshal(Enums())
+		sum := sha256.Sum256(data)
+		enumsETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	})
+	return enumsETag
+}
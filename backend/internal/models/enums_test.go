@@ -0,0 +1,73 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestValidateTagsMatchEnums guards against validate tags drifting from the
+// enum sets served by the meta endpoint
+func TestValidateTagsMatchEnums(t *testing.T) {
+	cases := []struct {
+		request interface{}
+		field   string
+		set     EnumSet
+	}{
+		{GoalCreateRequest{}, "GoalType", GoalTypes},
+		{GoalCreateRequest{}, "Priority", GoalPriorities},
+		{InvestmentTransactionCreateRequest{}, "TransactionType", TransactionTypes},
+		{IncomeCreateRequest{}, "Source", IncomeSources},
+		{BudgetCreateRequest{}, "Period", BudgetPeriods},
+		{BudgetCreateRequest{}, "CarryoverMode", CarryoverModes},
+		{AllocationRuleCreateRequest{}, "Trigger", AllocationTriggers},
+		{AllocationRuleUpdateRequest{}, "Trigger", AllocationTriggers},
+		{SpendingLimitCreateRequest{}, "Scope", SpendingLimitScopes},
+		{SpendingLimitCreateRequest{}, "Action", SpendingLimitActions},
+		{AnnouncementCreateRequest{}, "Severity", AnnouncementSeverities},
+		{AnnouncementCreateRequest{}, "Audience", AnnouncementAudiences},
+	}
+
+	for _, tc := range cases {
+		typ := reflect.TypeOf(tc.request)
+		field, ok := typ.FieldByName(tc.field)
+		if !ok {
+			t.Errorf("%s has no field %s", typ.Name(), tc.field)
+			continue
+		}
+		var oneOf []string
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if strings.HasPrefix(rule, "oneof=") {
+				oneOf = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+			}
+		}
+		if !reflect.DeepEqual(oneOf, tc.set.Strings()) {
+			t.Errorf("%s.%s accepts %v but enum %s has %v", typ.Name(), tc.field, oneOf, tc.set.Name, tc.set.Strings())
+		}
+	}
+}
+
+func TestEnumSet(t *testing.T) {
+	if !GoalPriorities.Valid("high") || GoalPriorities.Valid("urgent") {
+		t.Error("Unexpected GoalPriorities membership")
+	}
+	if got := GoalPriorities.Message("priority"); got != "priority must be one of low, medium, high" {
+		t.Errorf("Unexpected message %q", got)
+	}
+	if EnumsETag() == "" || EnumsETag() != EnumsETag() {
+		t.Error("Expected a stable ETag")
+	}
+
+	seen := make(map[string]bool)
+	for _, set := range Enums() {
+		if seen[set.Name] {
+			t.Errorf("Duplicate enum %s", set.Name)
+		}
+		seen[set.Name] = true
+		for _, v := range set.Values {
+			if v.Value == "" || v.Label == "" {
+				t.Errorf("Enum %s has a value without a value or label: %+v", set.Name, v)
+			}
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
 )
 
 // ExpenseCategory represents an expense category
@@ -29,38 +32,108 @@ type Expense struct {
 	Location      *string   `json:"location,omitempty" db:"location"`
 	ReceiptURL    *string   `json:"receipt_url,omitempty" db:"receipt_url"`
 	Tags          []string  `json:"tags,omitempty" db:"tags"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	// MyShare is the owner's share, kept in step with Splits by
+	// CalculateMyShare whenever the expense is written. Totals read it so
+	// they do not depend on the splits being loaded.
+	MyShare   float64   `json:"my_share" db:"my_share"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Version   int64     `json:"version" db:"version"`
+
+	// Mirroring of a linked-user split from another user's expense
+	SourceSplitID *uuid.UUID `json:"source_split_id,omitempty" db:"source_split_id"`
+	MirrorStatus  *string    `json:"mirror_status,omitempty" db:"mirror_status"`
+
+	// CSV import that created the expense
+	ImportID *uuid.UUID `json:"import_id,omitempty" db:"import_id"`
 
 	// Relations
 	Category *ExpenseCategory `json:"category,omitempty"`
 	User     *User            `json:"user,omitempty"`
+	Splits   []ExpenseSplit   `json:"splits,omitempty"`
 }
 
 // ExpenseCreateRequest represents the request to create a new expense
 type ExpenseCreateRequest struct {
-	CategoryID    uuid.UUID `json:"category_id" validate:"required"`
-	Amount        float64   `json:"amount" validate:"required,gt=0"`
-	Description   string    `json:"description" validate:"required"`
-	ExpenseDate   time.Time `json:"expense_date" validate:"required"`
-	PaymentMethod *string   `json:"payment_method,omitempty"`
-	Location      *string   `json:"location,omitempty"`
-	ReceiptURL    *string   `json:"receipt_url,omitempty"`
-	Tags          []string  `json:"tags,omitempty"`
+	CategoryID    uuid.UUID             `json:"category_id" validate:"required"`
+	Amount        float64               `json:"amount" validate:"required,gt=0"`
+	Description   string                `json:"description" validate:"required"`
+	ExpenseDate   time.Time             `json:"expense_date" validate:"required"`
+	PaymentMethod *string               `json:"payment_method,omitempty"`
+	Location      *string               `json:"location,omitempty"`
+	ReceiptURL    *string               `json:"receipt_url,omitempty"`
+	Tags          []string              `json:"tags,omitempty"`
+	Splits        []ExpenseSplitRequest `json:"splits,omitempty"`
+
+	// ConfirmOverLimit overrides blocking spending limits
+	ConfirmOverLimit bool `json:"confirm_over_limit,omitempty"`
 }
 
 // ExpenseUpdateRequest represents the request to update an expense
 type ExpenseUpdateRequest struct {
-	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
-	Amount        *float64   `json:"amount,omitempty" validate:"omitempty,gt=0"`
-	Description   *string    `json:"description,omitempty"`
-	ExpenseDate   *time.Time `json:"expense_date,omitempty"`
-	PaymentMethod *string    `json:"payment_method,omitempty"`
-	Location      *string    `json:"location,omitempty"`
-	ReceiptURL    *string    `json:"receipt_url,omitempty"`
-	Tags          []string   `json:"tags,omitempty"`
+	CategoryID    *uuid.UUID             `json:"category_id,omitempty"`
+	Amount        *float64               `json:"amount,omitempty" validate:"omitempty,gt=0"`
+	Description   *string                `json:"description,omitempty"`
+	ExpenseDate   *time.Time             `json:"expense_date,omitempty"`
+	PaymentMethod utils.Optional[string] `json:"payment_method"`
+	Location      utils.Optional[string] `json:"location"`
+	ReceiptURL    utils.Optional[string] `json:"receipt_url"`
+	Tags          []string               `json:"tags,omitempty"`
+	Splits        []ExpenseSplitRequest  `json:"splits,omitempty"`
+	// Version is the row version the update is based on, as an alternative to If-Match
+	Version *int64 `json:"version,omitempty"`
+}
+
+// Validate checks the request's validate tags, amount, description, payment
+// method, receipt URL and expense tags. The receipt URL is trimmed and the
+// expense tags normalized in place.
+func (r *ExpenseCreateRequest) Validate(receiptURLs utils.URLOptions) utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	addFieldError(&errs, utils.ValidateAmount(r.Amount, "amount"))
+	addFieldError(&errs, utils.ValidateRequired(r.Description, "description"))
+	if r.PaymentMethod != nil {
+		addFieldError(&errs, utils.ValidateLength(*r.PaymentMethod, "payment_method", 0, maxPaymentMethodLength))
+	}
+	errs.AddError(normalizeExpenseTags(&r.Tags))
+	if r.ReceiptURL != nil {
+		if receiptURL, err := utils.ValidateURL(*r.ReceiptURL, "receipt_url", receiptURLs); err != nil {
+			errs.AddError(err)
+		} else {
+			r.ReceiptURL = &receiptURL
+		}
+	}
+	return errs
 }
 
+// Validate checks that the request changes something and validates the
+// fields it sets, leaving out a receipt URL being cleared. The receipt URL
+// is trimmed and the expense tags normalized in place.
+func (r *ExpenseUpdateRequest) Validate(receiptURLs utils.URLOptions) utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	checkNotEmptyUpdate(&errs, len(r.Changes()) > 0 || r.Tags != nil || r.Splits != nil)
+	if r.Amount != nil {
+		addFieldError(&errs, utils.ValidateAmount(*r.Amount, "amount"))
+	}
+	if r.Description != nil {
+		addFieldError(&errs, utils.ValidateRequired(*r.Description, "description"))
+	}
+	if paymentMethod, ok := r.PaymentMethod.Get(); ok {
+		addFieldError(&errs, utils.ValidateLength(paymentMethod, "payment_method", 0, maxPaymentMethodLength))
+	}
+	errs.AddError(normalizeExpenseTags(&r.Tags))
+	if r.ReceiptURL.Set && !r.ReceiptURL.Null {
+		if receiptURL, err := utils.ValidateURL(r.ReceiptURL.Value, "receipt_url", receiptURLs); err != nil {
+			errs.AddError(err)
+		} else {
+			r.ReceiptURL.Value = receiptURL
+		}
+	}
+	return errs
+}
+
+// ExpenseSortFields are the columns expense lists may be sorted by
+var ExpenseSortFields = []string{"expense_date", "amount", "description", "payment_method", "created_at", "updated_at"}
+
 // ExpenseFilter represents filters for expense queries
 type ExpenseFilter struct {
 	UserID        uuid.UUID  `json:"user_id"`
@@ -75,14 +148,43 @@ type ExpenseFilter struct {
 	Offset        int        `json:"offset,omitempty"`
 }
 
-// ExpenseSummary represents expense summary statistics
+// Validate normalizes the filter's tags so they match stored tags and checks
+// them and the date range before a query is built
+func (f *ExpenseFilter) Validate() utils.ValidationErrors {
+	var errs utils.ValidationErrors
+	errs.AddError(normalizeExpenseTags(&f.Tags))
+	if f.StartDate != nil && f.EndDate != nil {
+		errs.AddError(utils.ValidateDateRange(*f.StartDate, *f.EndDate, "start_date", "end_date"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// normalizeExpenseTags normalizes tags in place when they are valid
+func normalizeExpenseTags(tags *[]string) error {
+	if len(*tags) == 0 {
+		return nil
+	}
+	normalized, err := utils.ValidateTags(*tags, "tags", utils.DefaultMaxTags, utils.DefaultMaxTagLength)
+	if err != nil {
+		return err
+	}
+	*tags = normalized
+	return nil
+}
+
+// ExpenseSummary represents expense summary statistics. TotalAmountMoney is
+// the exact total, kept alongside TotalAmount while clients move off floats.
 type ExpenseSummary struct {
-	TotalAmount     float64                  `json:"total_amount"`
-	TotalCount      int                      `json:"total_count"`
-	AverageAmount   float64                  `json:"average_amount"`
-	ByCategory      []CategoryExpenseSummary `json:"by_category,omitempty"`
-	ByMonth         []MonthlyExpenseSummary  `json:"by_month,omitempty"`
-	ByPaymentMethod []PaymentMethodSummary   `json:"by_payment_method,omitempty"`
+	TotalAmount      float64                  `json:"total_amount"`
+	TotalAmountMoney Money                    `json:"total_amount_money"`
+	TotalCount       int                      `json:"total_count"`
+	AverageAmount    float64                  `json:"average_amount"`
+	ByCategory       []CategoryExpenseSummary `json:"by_category,omitempty"`
+	ByMonth          []MonthlyExpenseSummary  `json:"by_month,omitempty"`
+	ByPaymentMethod  []PaymentMethodSummary   `json:"by_payment_method,omitempty"`
 }
 
 // CategoryExpenseSummary represents expense summary by category
@@ -109,3 +211,106 @@ type PaymentMethodSummary struct {
 	Count         int     `json:"count"`
 	Percentage    float64 `json:"percentage"`
 }
+
+// IsPendingMirror returns true if the expense mirrors another user's split
+// and has not been accepted yet
+func (e *Expense) IsPendingMirror() bool {
+	return e.SourceSplitID != nil && (e.MirrorStatus == nil || *e.MirrorStatus != MirrorStatusAccepted)
+}
+
+// SummarizeExpenses builds summary statistics from a set of expenses using
+// each expense's share rather than its full amount, so split expenses are
+// only counted once across participants. Mirrored expenses that have not
+// been accepted are excluded. Expenses are grouped into the months of loc,
+// the user's time zone.
+func SummarizeExpenses(expenses []Expense, loc *time.Location) ExpenseSummary {
+	var summary ExpenseSummary
+
+	categoryIndex := make(map[uuid.UUID]int)
+	monthIndex := make(map[[2]int]int)
+	methodIndex := make(map[string]int)
+
+	for i := range expenses {
+		expense := &expenses[i]
+		if expense.IsPendingMirror() {
+			continue
+		}
+
+		share := expense.MyShare
+		summary.TotalAmount += share
+		summary.TotalAmountMoney = summary.TotalAmountMoney.Add(MoneyFromFloat(share))
+		summary.TotalCount++
+
+		idx, ok := categoryIndex[expense.CategoryID]
+		if !ok {
+			entry := CategoryExpenseSummary{CategoryID: expense.CategoryID}
+			if expense.Category != nil {
+				entry.CategoryName = expense.Category.Name
+			}
+			summary.ByCategory = append(summary.ByCategory, entry)
+			idx = len(summary.ByCategory) - 1
+			categoryIndex[expense.CategoryID] = idx
+		}
+		summary.ByCategory[idx].Amount += share
+		summary.ByCategory[idx].Count++
+
+		expenseDate := expense.ExpenseDate.In(loc)
+		monthKey := [2]int{expenseDate.Year(), int(expenseDate.Month())}
+		idx, ok = monthIndex[monthKey]
+		if !ok {
+			summary.ByMonth = append(summary.ByMonth, MonthlyExpenseSummary{Year: monthKey[0], Month: monthKey[1]})
+			idx = len(summary.ByMonth) - 1
+			monthIndex[monthKey] = idx
+		}
+		summary.ByMonth[idx].Amount += share
+		summary.ByMonth[idx].Count++
+
+		method := "unspecified"
+		if expense.PaymentMethod != nil && *expense.PaymentMethod != "" {
+			method = *expense.PaymentMethod
+		}
+		idx, ok = methodIndex[method]
+		if !ok {
+			summary.ByPaymentMethod = append(summary.ByPaymentMethod, PaymentMethodSummary{PaymentMethod: method})
+			idx = len(summary.ByPaymentMethod) - 1
+			methodIndex[method] = idx
+		}
+		summary.ByPaymentMethod[idx].Amount += share
+		summary.ByPaymentMethod[idx].Count++
+	}
+
+	summary.TotalAmount = finance.RoundCents(summary.TotalAmount)
+	if summary.TotalCount > 0 {
+		summary.AverageAmount = finance.RoundCents(summary.TotalAmount / float64(summary.TotalCount))
+	}
+
+	for i := range summary.ByCategory {
+		summary.ByCategory[i].Amount = finance.RoundCents(summary.ByCategory[i].Amount)
+		summary.ByCategory[i].Percentage = percentageOf(summary.ByCategory[i].Amount, summary.TotalAmount)
+	}
+	for i := range summary.ByMonth {
+		summary.ByMonth[i].Amount = finance.RoundCents(summary.ByMonth[i].Amount)
+	}
+	for i := range summary.ByPaymentMethod {
+		summary.ByPaymentMethod[i].Amount = finance.RoundCents(summary.ByPaymentMethod[i].Amount)
+		summary.ByPaymentMethod[i].Percentage = percentageOf(summary.ByPaymentMethod[i].Amount, summary.TotalAmount)
+	}
+
+	return summary
+}
+
+// percentageOf returns part as a percentage of total
+func percentageOf(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return (part / total) * 100
+}
+
+// CategorySuggestion represents a suggested category for an expense description
+type CategorySuggestion struct {
+	CategoryID   uuid.UUID `json:"category_id"`
+	CategoryName string    `json:"category_name,omitempty"`
+	Confidence   float64   `json:"confidence"`
+	Source       string    `json:"source"`
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
+)
+
+// Mirror statuses for expenses created from a linked-user split
+const (
+	MirrorStatusPending  = "pending"
+	MirrorStatusAccepted = "accepted"
+	MirrorStatusRejected = "rejected"
+)
+
+// ExpenseSplit represents one participant's share of an expense
+type ExpenseSplit struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	ExpenseID         uuid.UUID  `json:"expense_id" db:"expense_id"`
+	ParticipantName   *string    `json:"participant_name,omitempty" db:"participant_name"`
+	ParticipantUserID *uuid.UUID `json:"participant_user_id,omitempty" db:"participant_user_id"`
+	ShareAmount       float64    `json:"share_amount" db:"share_amount"`
+	SharePercentage   *float64   `json:"share_percentage,omitempty" db:"share_percentage"`
+	IsOwner           bool       `json:"is_owner" db:"is_owner"`
+	Settled           bool       `json:"settled" db:"settled"`
+	SettledAt         *time.Time `json:"settled_at,omitempty" db:"settled_at"`
+	ReimbursementID   *uuid.UUID `json:"reimbursement_id,omitempty" db:"reimbursement_id"`
+	MirroredExpenseID *uuid.UUID `json:"mirrored_expense_id,omitempty" db:"mirrored_expense_id"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ExpenseSplitRequest represents a participant's share in an expense create or update request.
+// Exactly one of ShareAmount or SharePercentage must be set, and exactly one of
+// ParticipantName or ParticipantUserID unless IsOwner is set.
+type ExpenseSplitRequest struct {
+	ParticipantName   *string    `json:"participant_name,omitempty"`
+	ParticipantUserID *uuid.UUID `json:"participant_user_id,omitempty"`
+	ShareAmount       *float64   `json:"share_amount,omitempty" validate:"omitempty,gt=0"`
+	SharePercentage   *float64   `json:"share_percentage,omitempty" validate:"omitempty,gt=0,lte=100"`
+	IsOwner           bool       `json:"is_owner"`
+}
+
+// ExpenseSplitSettleRequest represents the request to mark a split as settled
+type ExpenseSplitSettleRequest struct {
+	SettledAt           *time.Time `json:"settled_at,omitempty"`
+	CreateReimbursement bool       `json:"create_reimbursement"`
+}
+
+// ExpenseMirrorResponseRequest represents a linked user's answer to a mirrored expense
+type ExpenseMirrorResponseRequest struct {
+	Accept bool `json:"accept"`
+}
+
+// IsLinked returns true if the split participant is a tgfinance user other than the payer
+func (s *ExpenseSplit) IsLinked() bool {
+	return s.ParticipantUserID != nil && !s.IsOwner
+}
+
+// Settle marks the split as settled at the given time
+func (s *ExpenseSplit) Settle(at time.Time) error {
+	if s.IsOwner {
+		return fmt.Errorf("the payer's own share cannot be settled")
+	}
+	if s.Settled {
+		return fmt.Errorf("split is already settled")
+	}
+	s.Settled = true
+	s.SettledAt = &at
+	return nil
+}
+
+// CalculateMyShare returns the portion of the expense borne by its owner.
+// Expenses without splits are borne entirely by the owner.
+func (e *Expense) CalculateMyShare() float64 {
+	if len(e.Splits) == 0 {
+		return e.Amount
+	}
+
+	others := 0.0
+	for _, split := range e.Splits {
+		if !split.IsOwner {
+			others += split.ShareAmount
+		}
+	}
+	return finance.RoundCents(e.Amount - others)
+}
+
+// ResolveSplits converts split requests into splits whose share amounts sum
+// exactly to total. Percentage shares are converted to amounts, with any
+// rounding remainder assigned to the last percentage-based split.
+func ResolveSplits(total float64, requests []ExpenseSplitRequest) ([]ExpenseSplit, error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	var errs utils.ValidationErrors
+	splits := make([]ExpenseSplit, len(requests))
+	owners := 0
+	lastPercentage := -1
+	sum := 0.0
+
+	for i, req := range requests {
+		field := fmt.Sprintf("splits[%d]", i)
+
+		if req.IsOwner {
+			owners++
+			if req.ParticipantName != nil || req.ParticipantUserID != nil {
+				errs.Add(field, "the owner's share must not name a participant")
+			}
+		} else if (req.ParticipantName == nil) == (req.ParticipantUserID == nil) {
+			errs.Add(field, "exactly one of participant_name or participant_user_id is required")
+		} else if req.ParticipantName != nil {
+			if err := utils.ValidateRequired(*req.ParticipantName, "participant_name"); err != nil {
+				errs.Add(field+".participant_name", "participant_name is required")
+			}
+		}
+
+		switch {
+		case req.ShareAmount != nil && req.SharePercentage != nil:
+			errs.Add(field, "only one of share_amount or share_percentage may be set")
+			continue
+		case req.ShareAmount != nil:
+			if *req.ShareAmount <= 0 {
+				errs.Add(field+".share_amount", "share_amount must be greater than 0")
+				continue
+			}
+			splits[i].ShareAmount = finance.RoundCents(*req.ShareAmount)
+		case req.SharePercentage != nil:
+			if *req.SharePercentage <= 0 || *req.SharePercentage > 100 {
+				errs.Add(field+".share_percentage", "share_percentage must be greater than 0 and at most 100")
+				continue
+			}
+			percentage := *req.SharePercentage
+			splits[i].SharePercentage = &percentage
+			splits[i].ShareAmount = finance.RoundCents(total * percentage / 100)
+			lastPercentage = i
+		default:
+			errs.Add(field, "one of share_amount or share_percentage is required")
+			continue
+		}
+
+		splits[i].ParticipantName = req.ParticipantName
+		splits[i].ParticipantUserID = req.ParticipantUserID
+		splits[i].IsOwner = req.IsOwner
+		sum += splits[i].ShareAmount
+	}
+
+	if owners > 1 {
+		errs.Add("splits", "only one split may be the owner's share")
+	}
+
+	if errs.HasErrors() {
+		return nil, errs
+	}
+
+	diff := finance.RoundCents(total - sum)
+	if diff != 0 && lastPercentage >= 0 && math.Abs(diff) <= 0.01*float64(len(requests)) {
+		splits[lastPercentage].ShareAmount = finance.RoundCents(splits[lastPercentage].ShareAmount + diff)
+		diff = 0
+	}
+
+	if diff != 0 {
+		return nil, utils.ValidationErrors{{
+			Field:   "splits",
+			Message: fmt.Sprintf("split shares must sum to the expense amount %.2f (got %.2f)", total, finance.RoundCents(sum)),
+		}}
+	}
+
+	return splits, nil
+}
+
+// NewMirroredExpense builds the pending expense created on a linked participant's
+// side for their share of another user's expense
+func NewMirroredExpense(source *Expense, split *ExpenseSplit) (*Expense, error) {
+	if !split.IsLinked() {
+		return nil, fmt.Errorf("split is not linked to another user")
+	}
+
+	status := MirrorStatusPending
+	splitID := split.ID
+	return &Expense{
+		ID:            uuid.New(),
+		UserID:        *split.ParticipantUserID,
+		CategoryID:    source.CategoryID,
+		Amount:        split.ShareAmount,
+		MyShare:       split.ShareAmount,
+		Description:   source.Description,
+		ExpenseDate:   source.ExpenseDate,
+		Location:      source.Location,
+		Tags:          source.Tags,
+		SourceSplitID: &splitID,
+		MirrorStatus:  &status,
+	}, nil
+}
@@ -0,0 +1,419 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/utils"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func stringPtr(v string) *string { return &v }
+
+func TestResolveSplits(t *testing.T) {
+	friend := uuid.New()
+
+	tests := []struct {
+		name     string
+		total    float64
+		requests []ExpenseSplitRequest
+		want     []float64
+		wantErr  bool
+	}{
+		{
+			name:  "equal amounts",
+			total: 120,
+			requests: []ExpenseSplitRequest{
+				{IsOwner: true, ShareAmount: floatPtr(40)},
+				{ParticipantName: stringPtr("Alice"), ShareAmount: floatPtr(40)},
+				{ParticipantUserID: &friend, ShareAmount: floatPtr(40)},
+			},
+			want: []float64{40, 40, 40},
+		},
+		{
+			name:  "percentages with rounding remainder",
+			total: 100,
+			requests: []ExpenseSplitRequest{
+				{IsOwner: true, SharePercentage: floatPtr(33.33)},
+				{ParticipantName: stringPtr("Alice"), SharePercentage: floatPtr(33.33)},
+				{ParticipantName: stringPtr("Bob"), SharePercentage: floatPtr(33.34)},
+			},
+			want: []float64{33.33, 33.33, 33.34},
+		},
+		{
+			name:  "thirds of ten absorb remainder in last percentage split",
+			total: 10,
+			requests: []ExpenseSplitRequest{
+				{IsOwner: true, SharePercentage: floatPtr(33.3333)},
+				{ParticipantName: stringPtr("Alice"), SharePercentage: floatPtr(33.3333)},
+				{ParticipantName: stringPtr("Bob"), SharePercentage: floatPtr(33.3334)},
+			},
+			want: []float64{3.33, 3.33, 3.34},
+		},
+		{
+			name:  "mixed amount and percentage",
+			total: 200,
+			requests: []ExpenseSplitRequest{
+				{IsOwner: true, ShareAmount: floatPtr(50)},
+				{ParticipantName: stringPtr("Alice"), SharePercentage: floatPtr(75)},
+			},
+			want: []float64{50, 150},
+		},
+		{
+			name:  "shares do not sum to total",
+			total: 120,
+			requests: []ExpenseSplitRequest{
+				{IsOwner: true, ShareAmount: floatPtr(40)},
+				{ParticipantName: stringPtr("Alice"), ShareAmount: floatPtr(40)},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "both amount and percentage",
+			total: 100,
+			requests: []ExpenseSplitRequest{
+				{ParticipantName: stringPtr("Alice"), ShareAmount: floatPtr(100), SharePercentage: floatPtr(100)},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "missing participant",
+			total: 100,
+			requests: []ExpenseSplitRequest{
+				{ShareAmount: floatPtr(100)},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "blank participant name",
+			total: 100,
+			requests: []ExpenseSplitRequest{
+				{ParticipantName: stringPtr("  "), ShareAmount: floatPtr(100)},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "two owners",
+			total: 100,
+			requests: []ExpenseSplitRequest{
+				{IsOwner: true, ShareAmount: floatPtr(50)},
+				{IsOwner: true, ShareAmount: floatPtr(50)},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splits, err := ResolveSplits(tt.total, tt.requests)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSplits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(splits) != len(tt.want) {
+				t.Fatalf("ResolveSplits() returned %d splits, want %d", len(splits), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if splits[i].ShareAmount != want {
+					t.Errorf("split %d share = %v, want %v", i, splits[i].ShareAmount, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateMyShare(t *testing.T) {
+	expense := Expense{Amount: 120}
+	if share := expense.CalculateMyShare(); share != 120 {
+		t.Errorf("Expected unsplit share 120, got %v", share)
+	}
+
+	expense.Splits = []ExpenseSplit{
+		{IsOwner: true, ShareAmount: 40},
+		{ParticipantName: stringPtr("Alice"), ShareAmount: 40},
+		{ParticipantName: stringPtr("Bob"), ShareAmount: 40},
+	}
+	if share := expense.CalculateMyShare(); share != 40 {
+		t.Errorf("Expected split share 40, got %v", share)
+	}
+
+	// Settling a split does not change who bore the cost
+	if err := expense.Splits[1].Settle(time.Now()); err != nil {
+		t.Fatalf("Failed to settle split: %v", err)
+	}
+	if share := expense.CalculateMyShare(); share != 40 {
+		t.Errorf("Expected share 40 after settlement, got %v", share)
+	}
+
+	if err := expense.Splits[1].Settle(time.Now()); err == nil {
+		t.Error("Settling an already settled split should fail")
+	}
+	if err := expense.Splits[0].Settle(time.Now()); err == nil {
+		t.Error("Settling the owner's share should fail")
+	}
+}
+
+func TestSummarizeExpensesWithSplits(t *testing.T) {
+	payer := uuid.New()
+	friend := uuid.New()
+	food := uuid.New()
+	travel := uuid.New()
+	date := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	dinner := Expense{
+		ID:          uuid.New(),
+		UserID:      payer,
+		CategoryID:  food,
+		Amount:      120,
+		MyShare:     40,
+		ExpenseDate: date,
+		Category:    &ExpenseCategory{Name: "Food & Dining"},
+		Splits: []ExpenseSplit{
+			{ID: uuid.New(), IsOwner: true, ShareAmount: 40},
+			{ID: uuid.New(), ParticipantUserID: &friend, ShareAmount: 40},
+			{ID: uuid.New(), ParticipantName: stringPtr("Bob"), ShareAmount: 40},
+		},
+	}
+	taxi := Expense{
+		ID:          uuid.New(),
+		UserID:      payer,
+		CategoryID:  travel,
+		Amount:      60,
+		MyShare:     60,
+		ExpenseDate: date.AddDate(0, 1, 0),
+		Category:    &ExpenseCategory{Name: "Travel"},
+	}
+
+	summary := SummarizeExpenses([]Expense{dinner, taxi}, time.UTC)
+	if summary.TotalAmount != 100 {
+		t.Errorf("Expected payer total 100, got %v", summary.TotalAmount)
+	}
+	if summary.TotalCount != 2 {
+		t.Errorf("Expected 2 expenses, got %d", summary.TotalCount)
+	}
+	if summary.AverageAmount != 50 {
+		t.Errorf("Expected average 50, got %v", summary.AverageAmount)
+	}
+	if len(summary.ByCategory) != 2 || summary.ByCategory[0].Amount != 40 || summary.ByCategory[0].Percentage != 40 {
+		t.Errorf("Unexpected category breakdown: %+v", summary.ByCategory)
+	}
+	if len(summary.ByMonth) != 2 || summary.ByMonth[0].Amount != 40 || summary.ByMonth[1].Amount != 60 {
+		t.Errorf("Unexpected monthly breakdown: %+v", summary.ByMonth)
+	}
+
+	// The friend's mirrored expense is excluded until accepted
+	mirror, err := NewMirroredExpense(&dinner, &dinner.Splits[1])
+	if err != nil {
+		t.Fatalf("Failed to create mirrored expense: %v", err)
+	}
+	if mirror.UserID != friend || mirror.Amount != 40 {
+		t.Errorf("Unexpected mirrored expense: %+v", mirror)
+	}
+	if _, err := NewMirroredExpense(&dinner, &dinner.Splits[2]); err == nil {
+		t.Error("Name-only splits should not be mirrored")
+	}
+
+	friendSummary := SummarizeExpenses([]Expense{*mirror}, time.UTC)
+	if friendSummary.TotalAmount != 0 || friendSummary.TotalCount != 0 {
+		t.Errorf("Pending mirror should be excluded, got %+v", friendSummary)
+	}
+
+	accepted := MirrorStatusAccepted
+	mirror.MirrorStatus = &accepted
+	friendSummary = SummarizeExpenses([]Expense{*mirror}, time.UTC)
+	if friendSummary.TotalAmount != 40 {
+		t.Errorf("Expected friend total 40, got %v", friendSummary.TotalAmount)
+	}
+
+	// Across both users the dinner is counted exactly once for the linked participant
+	combined := SummarizeExpenses([]Expense{dinner, *mirror}, time.UTC)
+	if combined.TotalAmount != 80 {
+		t.Errorf("Expected combined total 80 (payer 40 + friend 40), got %v", combined.TotalAmount)
+	}
+}
+
+func TestNewReimbursementIncome(t *testing.T) {
+	expense := Expense{UserID: uuid.New(), Amount: 120, Description: "Dinner"}
+	split := ExpenseSplit{ID: uuid.New(), ParticipantName: stringPtr("Alice"), ShareAmount: 40}
+	settledAt := time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC)
+	if err := split.Settle(settledAt); err != nil {
+		t.Fatalf("Failed to settle split: %v", err)
+	}
+
+	income := NewReimbursementIncome(&expense, &split)
+	if income.Amount != 40 || income.Source != IncomeSourceReimbursement {
+		t.Errorf("Unexpected reimbursement income: %+v", income)
+	}
+	if income.UserID != expense.UserID || !income.IncomeDate.Equal(settledAt) {
+		t.Errorf("Reimbursement should belong to the payer on the settlement date: %+v", income)
+	}
+	if income.SplitID == nil || *income.SplitID != split.ID {
+		t.Error("Reimbursement should reference the settled split")
+	}
+
+	// Reimbursements do not reduce the expense share a second time
+	expense.Splits = []ExpenseSplit{{IsOwner: true, ShareAmount: 80}, split}
+	if share := expense.CalculateMyShare(); share != 80 {
+		t.Errorf("Expected share 80, got %v", share)
+	}
+}
+
+func TestTotalsUseStoredShareWithoutSplits(t *testing.T) {
+	food := uuid.New()
+	date := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	// Listings load expenses without their splits; my_share still holds the
+	// owner's portion of the split dinner
+	expenses := []Expense{
+		{ID: uuid.New(), CategoryID: food, Amount: 120, MyShare: 40, ExpenseDate: date},
+		{ID: uuid.New(), CategoryID: food, Amount: 20, MyShare: 20, ExpenseDate: date},
+	}
+
+	summary := SummarizeExpenses(expenses, time.UTC)
+	if summary.TotalAmount != 60 || summary.ByCategory[0].Amount != 60 {
+		t.Errorf("Expected split expense counted at its share, got %+v", summary)
+	}
+
+	budget := Budget{CategoryID: food, Period: BudgetPeriodMonthly}
+	if spent := budget.SpentInPeriod(expenses, date, date.AddDate(0, 1, 0)); spent != 60 {
+		t.Errorf("Expected spent 60, got %v", spent)
+	}
+}
+
+func TestSummarizeExpensesByLocalMonth(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lateJan31 := Expense{ID: uuid.New(), Amount: 25, MyShare: 25, ExpenseDate: time.Date(2026, time.January, 31, 23, 30, 0, 0, time.UTC)}
+	midJan := Expense{ID: uuid.New(), Amount: 10, MyShare: 10, ExpenseDate: time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC)}
+
+	months := func(summary ExpenseSummary) []MonthlyExpenseSummary { return summary.ByMonth }
+	utc := months(SummarizeExpenses([]Expense{midJan, lateJan31}, time.UTC))
+	if len(utc) != 1 || utc[0].Month != 1 || utc[0].Amount != 35 {
+		t.Errorf("Expected both expenses in January UTC, got %+v", utc)
+	}
+	local := months(SummarizeExpenses([]Expense{midJan, lateJan31}, kolkata))
+	if len(local) != 2 || local[0].Month != 1 || local[0].Amount != 10 || local[1].Month != 2 || local[1].Amount != 25 {
+		t.Errorf("Expected the late expense in February for Asia/Kolkata, got %+v", local)
+	}
+}
+
+func TestSummarizeWithAggregates(t *testing.T) {
+	food := uuid.New()
+	travel := uuid.New()
+	recent := Expense{
+		ID:            uuid.New(),
+		CategoryID:    food,
+		Amount:        60,
+		MyShare:       60,
+		ExpenseDate:   time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC),
+		PaymentMethod: stringPtr("card"),
+	}
+	aggregates := []ExpenseAggregate{
+		{Month: time.Date(2018, time.May, 1, 0, 0, 0, 0, time.UTC), CategoryID: food, PaymentMethod: "card", Amount: 100, Count: 3},
+		{Month: time.Date(2018, time.May, 1, 0, 0, 0, 0, time.UTC), CategoryID: travel, Amount: 40, Count: 1},
+	}
+
+	summary := SummarizeWithAggregates([]Expense{recent}, aggregates, time.UTC)
+	if summary.TotalAmount != 200 || summary.TotalCount != 5 || summary.AverageAmount != 40 {
+		t.Errorf("Unexpected totals %+v", summary)
+	}
+	if len(summary.ByCategory) != 2 || summary.ByCategory[0].Amount != 160 || summary.ByCategory[0].Count != 4 || summary.ByCategory[0].Percentage != 80 {
+		t.Errorf("Unexpected category breakdown: %+v", summary.ByCategory)
+	}
+	if len(summary.ByMonth) != 2 || summary.ByMonth[1].Year != 2018 || summary.ByMonth[1].Amount != 140 {
+		t.Errorf("Unexpected monthly breakdown: %+v", summary.ByMonth)
+	}
+	if len(summary.ByPaymentMethod) != 2 || summary.ByPaymentMethod[1].PaymentMethod != "unspecified" || summary.ByPaymentMethod[1].Amount != 40 {
+		t.Errorf("Unexpected payment method breakdown: %+v", summary.ByPaymentMethod)
+	}
+}
+
+func TestExpenseRequestValidateReceiptURL(t *testing.T) {
+	storage := utils.URLOptions{AllowedHosts: []string{"receipts.tgfinance.app"}}
+	valid := func(receiptURL *string) *ExpenseCreateRequest {
+		return &ExpenseCreateRequest{
+			CategoryID:  uuid.New(),
+			Amount:      12.5,
+			Description: "Lunch",
+			ExpenseDate: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+			ReceiptURL:  receiptURL,
+		}
+	}
+
+	if errs := valid(nil).Validate(storage); errs != nil {
+		t.Errorf("Expected no receipt URL to be valid, got %v", errs)
+	}
+	create := valid(stringPtr(" https://receipts.tgfinance.app/1.png\n"))
+	if errs := create.Validate(storage); errs != nil || *create.ReceiptURL != "https://receipts.tgfinance.app/1.png" {
+		t.Errorf("Expected a trimmed receipt URL, got %q, %v", *create.ReceiptURL, errs)
+	}
+	for _, receiptURL := range []string{"javascript:alert(1)", "http://169.254.169.254/", "https://example.com/1.png", ""} {
+		create := valid(stringPtr(receiptURL))
+		errs := create.Validate(storage)
+		if len(errs) != 1 || errs[0].Field != "receipt_url" || *create.ReceiptURL != receiptURL {
+			t.Errorf("Validate(%q) = %v", receiptURL, errs)
+		}
+	}
+	// Tag and receipt errors are reported together
+	if errs := (&ExpenseCreateRequest{ReceiptURL: stringPtr("data:,x")}).Validate(storage); len(errs) != 5 {
+		t.Errorf("Expected tag and receipt errors, got %v", errs)
+	}
+
+	update := ExpenseUpdateRequest{ReceiptURL: utils.Some("https://receipts.tgfinance.app/2.png ")}
+	if errs := update.Validate(storage); errs != nil || update.ReceiptURL.Value != "https://receipts.tgfinance.app/2.png" {
+		t.Errorf("Expected a trimmed receipt URL, got %q, %v", update.ReceiptURL.Value, errs)
+	}
+	update = ExpenseUpdateRequest{ReceiptURL: utils.Some("javascript:alert(1)")}
+	if errs := update.Validate(storage); len(errs) != 1 || errs[0].Field != "receipt_url" {
+		t.Errorf("Expected a receipt_url error, got %v", errs)
+	}
+	// Clearing the receipt URL needs no check
+	update = ExpenseUpdateRequest{ReceiptURL: utils.Null[string]()}
+	if errs := update.Validate(storage); errs != nil {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestExpenseTagNormalization(t *testing.T) {
+	create := &ExpenseCreateRequest{
+		CategoryID:  uuid.New(),
+		Amount:      20,
+		Description: "Dinner",
+		ExpenseDate: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+		Tags:        []string{" Date Night", "FOOD", "date night", "", "Café"},
+	}
+	if errs := create.Validate(utils.URLOptions{}); errs != nil {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if got := strings.Join(create.Tags, "|"); got != "date night|food|café" {
+		t.Errorf("Create tags = %q", got)
+	}
+
+	create.Tags = []string{"rent,utilities"}
+	if errs := create.Validate(utils.URLOptions{}); len(errs) != 1 || errs[0].Field != "tags[0]" || create.Tags[0] != "rent,utilities" {
+		t.Errorf("Expected a tag error leaving the tags as sent, got %v %q", errs, create.Tags)
+	}
+
+	update := &ExpenseUpdateRequest{Tags: []string{"Work", "WORK "}}
+	if errs := update.Validate(utils.URLOptions{}); errs != nil || strings.Join(update.Tags, "|") != "work" {
+		t.Errorf("Update tags = %q, %v", update.Tags, errs)
+	}
+
+	start := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	filter := &ExpenseFilter{Tags: []string{"Travel", " travel"}}
+	if errs := filter.Validate(); errs != nil || strings.Join(filter.Tags, "|") != "travel" {
+		t.Errorf("Filter tags = %q, %v", filter.Tags, errs)
+	}
+	filter = &ExpenseFilter{Tags: []string{"ok", "bad\x00"}, StartDate: &start, EndDate: &end}
+	if errs := filter.Validate(); strings.Join(errs.Fields(), ",") != "tags[1],end_date" {
+		t.Errorf("Filter errors = %v", errs)
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"tgfinance/pkg/utils"
+)
+
+func TestFieldsNeverExposeHiddenFields(t *testing.T) {
+	if _, err := utils.ParseFields("amount,expense_date,description,category.name,splits.share_amount,user.email", Expense{}); err != nil {
+		t.Errorf("Expected expense fields to be accepted, got %v", err)
+	}
+	if _, err := utils.ParseFields("user.password_hash", Expense{}); err == nil {
+		t.Error("password_hash must not be selectable")
+	}
+	if _, err := utils.ParseFields("by_category.amount,total_amount", ExpenseSummary{}); err != nil {
+		t.Errorf("Expected summary paths to be accepted, got %v", err)
+	}
+}
+
+func TestSortFieldsAreColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  any
+		fields []string
+	}{
+		{"expense", Expense{}, ExpenseSortFields},
+		{"income", Income{}, IncomeSortFields},
+		{"investment", Investment{}, InvestmentSortFields},
+		{"goal", FinancialGoal{}, GoalSortFields},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns := map[string]bool{}
+			model := reflect.TypeOf(tt.model)
+			for i := 0; i < model.NumField(); i++ {
+				columns[model.Field(i).Tag.Get("db")] = true
+			}
+			for _, field := range tt.fields {
+				if !columns[field] {
+					t.Errorf("Sort field %q is not a %s column", field, tt.name)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,31 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"tgfinance/pkg/utils"
+)
+
+// Goal types
+const (
+	GoalTypeSavings       = "savings"
+	GoalTypeInvestment    = "investment"
+	GoalTypeDebtPayoff    = "debt_payoff"
+	GoalTypePurchase      = "purchase"
+	GoalTypeEmergencyFund = "emergency_fund"
+)
+
+// Goal priorities
+const (
+	GoalPriorityLow    = "low"
+	GoalPriorityMedium = "medium"
+	GoalPriorityHigh   = "high"
+)
+
+// Goal statuses
+const (
+	GoalStatusActive    = "active"
+	GoalStatusCompleted = "completed"
+	GoalStatusCancelled = "cancelled"
 )
 
 // FinancialGoal represents a financial goal
@@ -20,6 +45,12 @@ type FinancialGoal struct {
 	Status        string     `json:"status" db:"status"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	Version       int64      `json:"version" db:"version"`
+
+	// Funding from a linked investment
+	LinkedInvestmentID *uuid.UUID `json:"linked_investment_id,omitempty" db:"linked_investment_id"`
+	SyncPercentage     *float64   `json:"sync_percentage,omitempty" db:"sync_percentage"`
+	LastSyncedAt       *time.Time `json:"last_synced_at,omitempty" db:"last_synced_at"`
 
 	// Relations
 	User *User `json:"user,omitempty"`
@@ -27,13 +58,16 @@ type FinancialGoal struct {
 
 // GoalContribution represents a contribution to a financial goal
 type GoalContribution struct {
-	ID               uuid.UUID `json:"id" db:"id"`
-	GoalID           uuid.UUID `json:"goal_id" db:"goal_id"`
-	Amount           float64   `json:"amount" db:"amount"`
-	ContributionDate time.Time `json:"contribution_date" db:"contribution_date"`
-	Source           *string   `json:"source,omitempty" db:"source"`
-	Notes            *string   `json:"notes,omitempty" db:"notes"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	ID               uuid.UUID  `json:"id" db:"id"`
+	GoalID           uuid.UUID  `json:"goal_id" db:"goal_id"`
+	Amount           float64    `json:"amount" db:"amount"`
+	ContributionDate time.Time  `json:"contribution_date" db:"contribution_date"`
+	Source           *string    `json:"source,omitempty" db:"source"`
+	Notes            *string    `json:"notes,omitempty" db:"notes"`
+	RuleID           *uuid.UUID `json:"rule_id,omitempty" db:"rule_id"`
+	IncomeID         *uuid.UUID `json:"income_id,omitempty" db:"income_id"`
+	CreatedBy        *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 
 	// Relations
 	Goal *FinancialGoal `json:"goal,omitempty"`
@@ -51,13 +85,47 @@ type GoalCreateRequest struct {
 
 // GoalUpdateRequest represents the request to update a financial goal
 type GoalUpdateRequest struct {
-	Name         *string    `json:"name,omitempty"`
-	Description  *string    `json:"description,omitempty"`
-	TargetAmount *float64   `json:"target_amount,omitempty" validate:"omitempty,gt=0"`
-	TargetDate   *time.Time `json:"target_date,omitempty"`
-	GoalType     *string    `json:"goal_type,omitempty"`
-	Priority     *string    `json:"priority,omitempty"`
-	Status       *string    `json:"status,omitempty"`
+	Name         *string                   `json:"name,omitempty"`
+	Description  utils.Optional[string]    `json:"description"`
+	TargetAmount *float64                  `json:"target_amount,omitempty" validate:"omitempty,gt=0"`
+	TargetDate   utils.Optional[time.Time] `json:"target_date"`
+	GoalType     *string                   `json:"goal_type,omitempty"`
+	Priority     *string                   `json:"priority,omitempty"`
+	Status       *string                   `json:"status,omitempty"`
+	// Version is the row version the update is based on, as an alternative to If-Match
+	Version *int64 `json:"version,omitempty"`
+}
+
+// Validate checks the request's validate tags, name and target amount, and
+// that the target date, if any, is in the future
+func (r *GoalCreateRequest) Validate() utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	addFieldError(&errs, utils.ValidateLength(r.Name, "name", 1, maxEntityNameLength))
+	addFieldError(&errs, utils.ValidateAmount(r.TargetAmount, "target_amount"))
+	if r.TargetDate != nil {
+		addFieldError(&errs, utils.ValidateFuture(*r.TargetDate, "target_date"))
+	}
+	return errs
+}
+
+// Validate checks that the request changes something and validates the
+// fields it sets, leaving out a target date being cleared
+func (r *GoalUpdateRequest) Validate() utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	checkNotEmptyUpdate(&errs, len(r.Changes()) > 0)
+	if r.Name != nil {
+		addFieldError(&errs, utils.ValidateLength(*r.Name, "name", 1, maxEntityNameLength))
+	}
+	if r.TargetAmount != nil {
+		addFieldError(&errs, utils.ValidateAmount(*r.TargetAmount, "target_amount"))
+	}
+	if targetDate, ok := r.TargetDate.Get(); ok {
+		addFieldError(&errs, utils.ValidateFuture(targetDate, "target_date"))
+	}
+	checkEnum(&errs, "goal_type", r.GoalType, GoalTypes)
+	checkEnum(&errs, "priority", r.Priority, GoalPriorities)
+	checkEnum(&errs, "status", r.Status, GoalStatuses)
+	return errs
 }
 
 // GoalContributionCreateRequest represents the request to create a goal contribution
@@ -66,8 +134,24 @@ type GoalContributionCreateRequest struct {
 	ContributionDate time.Time `json:"contribution_date" validate:"required"`
 	Source           *string   `json:"source,omitempty"`
 	Notes            *string   `json:"notes,omitempty"`
+	// Additional must be set to contribute to a goal funded by a linked investment
+	Additional bool `json:"additional,omitempty"`
 }
 
+// Validate checks the request's validate tags and amount, and that the
+// contribution date is not in the future
+func (r *GoalContributionCreateRequest) Validate() utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	addFieldError(&errs, utils.ValidateAmount(r.Amount, "amount"))
+	if !r.ContributionDate.IsZero() {
+		addFieldError(&errs, utils.ValidateNotFuture(r.ContributionDate, "contribution_date"))
+	}
+	return errs
+}
+
+// GoalSortFields are the columns goal lists may be sorted by
+var GoalSortFields = []string{"name", "target_amount", "current_amount", "target_date", "status", "created_at", "updated_at"}
+
 // GoalFilter represents filters for goal queries
 type GoalFilter struct {
 	UserID   uuid.UUID `json:"user_id"`
@@ -133,8 +217,13 @@ func (g *FinancialGoal) IsCompleted() bool {
 
 // IsOverdue returns true if the goal is overdue
 func (g *FinancialGoal) IsOverdue() bool {
+	return g.IsOverdueAt(time.Now())
+}
+
+// IsOverdueAt returns true if the goal is overdue at now
+func (g *FinancialGoal) IsOverdueAt(now time.Time) bool {
 	if g.TargetDate == nil {
 		return false
 	}
-	return time.Now().After(*g.TargetDate) && !g.IsCompleted()
+	return now.After(*g.TargetDate) && !g.IsCompleted()
 }
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+)
+
+// Goal contribution sources
+const (
+	ContributionSourceManual     = "manual"
+	ContributionSourceSync       = "investment_sync"
+	ContributionSourceAdditional = "additional"
+)
+
+// DefaultSyncPercentage is the share of a linked investment counted toward a goal
+const DefaultSyncPercentage = 100.0
+
+// GoalMilestones are the progress percentages reported when crossed
+var GoalMilestones = []float64{25, 50, 75, 100}
+
+// GoalLinkRequest represents the request to fund a goal from an investment
+type GoalLinkRequest struct {
+	InvestmentID   uuid.UUID `json:"investment_id" validate:"required"`
+	SyncPercentage *float64  `json:"sync_percentage,omitempty" validate:"omitempty,gt=0,lte=100"`
+}
+
+// IsLinked returns true if the goal is funded by a linked investment
+func (g *FinancialGoal) IsLinked() bool {
+	return g.LinkedInvestmentID != nil
+}
+
+// GetSyncPercentage returns the share of the linked investment counted toward the goal
+func (g *FinancialGoal) GetSyncPercentage() float64 {
+	if g.SyncPercentage != nil {
+		return *g.SyncPercentage
+	}
+	return DefaultSyncPercentage
+}
+
+// Link funds the goal from an investment owned by the same user
+func (g *FinancialGoal) Link(investment *Investment, req GoalLinkRequest) error {
+	if investment.UserID != g.UserID {
+		return fmt.Errorf("investment not found")
+	}
+	if investment.IsClosed() {
+		return fmt.Errorf("cannot link a closed investment")
+	}
+	if req.SyncPercentage != nil && (*req.SyncPercentage <= 0 || *req.SyncPercentage > 100) {
+		return fmt.Errorf("sync_percentage must be greater than 0 and at most 100")
+	}
+
+	investmentID := investment.ID
+	g.LinkedInvestmentID = &investmentID
+	g.SyncPercentage = req.SyncPercentage
+	return nil
+}
+
+// Unlink stops syncing from the investment. CurrentAmount stays frozen at its
+// last synced value.
+func (g *FinancialGoal) Unlink() {
+	g.LinkedInvestmentID = nil
+	g.SyncPercentage = nil
+}
+
+// ApplyContribution adds amount (negative for a decrease) to the goal, marks
+// an active goal completed once it reaches its target and returns the
+// milestones crossed upward. Manual, allocated and synced contributions all
+// go through here.
+func (g *FinancialGoal) ApplyContribution(amount float64) []float64 {
+	before := g.GetProgress()
+	g.CurrentAmount = finance.RoundCents(g.CurrentAmount + amount)
+	after := g.GetProgress()
+
+	if g.Status == "active" && g.IsCompleted() {
+		g.Status = "completed"
+	}
+
+	var crossed []float64
+	for _, milestone := range GoalMilestones {
+		if before < milestone && after >= milestone {
+			crossed = append(crossed, milestone)
+		}
+	}
+	return crossed
+}
+
+// NewContribution builds a user contribution to the goal. Linked goals only
+// accept contributions explicitly marked as additional to the synced amount.
+func (g *FinancialGoal) NewContribution(req GoalContributionCreateRequest) (*GoalContribution, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be greater than 0")
+	}
+
+	source := ContributionSourceManual
+	if req.Source != nil && *req.Source != "" {
+		source = *req.Source
+	}
+	if g.IsLinked() {
+		if !req.Additional {
+			return nil, fmt.Errorf("goal is funded by a linked investment; mark the contribution as additional")
+		}
+		source = ContributionSourceAdditional
+	}
+
+	return &GoalContribution{
+		ID:               uuid.New(),
+		GoalID:           g.ID,
+		Amount:           req.Amount,
+		ContributionDate: req.ContributionDate,
+		Source:           &source,
+		Notes:            req.Notes,
+		CreatedAt:        time.Now(),
+	}, nil
+}
+
+// AdditionalContributionTotal sums contributions made on top of a linked investment
+func AdditionalContributionTotal(contributions []GoalContribution) float64 {
+	total := 0.0
+	for _, contribution := range contributions {
+		if contribution.Source != nil && *contribution.Source == ContributionSourceAdditional {
+			total += contribution.Amount
+		}
+	}
+	return finance.RoundCents(total)
+}
+
+// SyncFromInvestment sets the goal's current amount to its share of the
+// linked investment's value plus any additional contributions. The change is
+// returned as a synthetic sync contribution so the goal history still sums to
+// CurrentAmount; no contribution is returned when nothing changed.
+func (g *FinancialGoal) SyncFromInvestment(investment *Investment, additional float64, at time.Time) (*GoalContribution, []float64, error) {
+	if !g.IsLinked() || *g.LinkedInvestmentID != investment.ID {
+		return nil, nil, fmt.Errorf("goal is not linked to investment %s", investment.ID)
+	}
+
+	target := finance.RoundCents(investment.GetCurrentValue()*g.GetSyncPercentage()/100 + additional)
+	delta := finance.RoundCents(target - g.CurrentAmount)
+	syncedAt := at
+	g.LastSyncedAt = &syncedAt
+
+	if math.Abs(delta) < 0.005 {
+		return nil, nil, nil
+	}
+
+	source := ContributionSourceSync
+	notes := fmt.Sprintf("Synced from investment %q at %.2f%%", investment.Name, g.GetSyncPercentage())
+	contribution := &GoalContribution{
+		ID:               uuid.New(),
+		GoalID:           g.ID,
+		Amount:           delta,
+		ContributionDate: at,
+		Source:           &source,
+		Notes:            &notes,
+		CreatedAt:        at,
+	}
+
+	crossed := g.ApplyContribution(delta)
+	return contribution, crossed, nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGoalInvestmentSync(t *testing.T) {
+	userID := uuid.New()
+	value := 40000.0
+	investment := Investment{ID: uuid.New(), UserID: userID, Name: "House fund", Amount: 30000, CurrentValue: &value, Status: InvestmentStatusActive}
+	goal := FinancialGoal{ID: uuid.New(), UserID: userID, Name: "House", TargetAmount: 50000, Status: "active"}
+	syncedAt := time.Date(2024, time.March, 1, 2, 0, 0, 0, time.UTC)
+
+	if err := goal.Link(&Investment{ID: uuid.New(), UserID: uuid.New()}, GoalLinkRequest{}); err == nil {
+		t.Error("Linking another user's investment should fail")
+	}
+
+	percentage := 50.0
+	if err := goal.Link(&investment, GoalLinkRequest{InvestmentID: investment.ID, SyncPercentage: &percentage}); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+
+	contribution, crossed, err := goal.SyncFromInvestment(&investment, 0, syncedAt)
+	if err != nil {
+		t.Fatalf("SyncFromInvestment() error = %v", err)
+	}
+	if contribution == nil || contribution.Amount != 20000 || *contribution.Source != ContributionSourceSync {
+		t.Fatalf("Unexpected sync contribution: %+v", contribution)
+	}
+	if goal.CurrentAmount != 20000 || !goal.LastSyncedAt.Equal(syncedAt) {
+		t.Errorf("Unexpected goal after sync: %+v", goal)
+	}
+	if len(crossed) != 1 || crossed[0] != 25 {
+		t.Errorf("Expected the 25%% milestone, got %v", crossed)
+	}
+
+	// Manual contributions to a linked goal must be marked additional
+	if _, err := goal.NewContribution(GoalContributionCreateRequest{Amount: 500}); err == nil {
+		t.Error("Unmarked contribution to a linked goal should be rejected")
+	}
+	extra, err := goal.NewContribution(GoalContributionCreateRequest{Amount: 500, Additional: true})
+	if err != nil || *extra.Source != ContributionSourceAdditional {
+		t.Fatalf("Expected an additional contribution, got %+v, %v", extra, err)
+	}
+	goal.ApplyContribution(extra.Amount)
+
+	// A falling investment value produces a negative sync contribution and keeps additional money
+	value = 30000
+	additional := AdditionalContributionTotal([]GoalContribution{*contribution, *extra})
+	contribution, _, _ = goal.SyncFromInvestment(&investment, additional, syncedAt.AddDate(0, 0, 1))
+	if contribution == nil || contribution.Amount != -5000 || goal.CurrentAmount != 15500 {
+		t.Errorf("Expected a -5000 sync to 15500, got %+v with current %v", contribution, goal.CurrentAmount)
+	}
+
+	if contribution, _, _ := goal.SyncFromInvestment(&investment, additional, syncedAt.AddDate(0, 0, 2)); contribution != nil {
+		t.Error("Unchanged value should not create a sync contribution")
+	}
+
+	// Syncing to the target completes the goal the same way a manual contribution would
+	value = 100000
+	_, crossed, _ = goal.SyncFromInvestment(&investment, additional, syncedAt.AddDate(0, 0, 3))
+	if goal.Status != "completed" || len(crossed) != 3 {
+		t.Errorf("Expected completion crossing 50/75/100, got status %s and %v", goal.Status, crossed)
+	}
+
+	goal.Unlink()
+	frozen := goal.CurrentAmount
+	if goal.IsLinked() {
+		t.Error("Goal should no longer be linked")
+	}
+	if _, _, err := goal.SyncFromInvestment(&investment, 0, syncedAt); err == nil || goal.CurrentAmount != frozen {
+		t.Error("Unlinked goals must not sync and keep their last amount")
+	}
+}
+
+func TestGoalIsOverdueAt(t *testing.T) {
+	targetDate := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	goal := &FinancialGoal{TargetAmount: 1000, CurrentAmount: 500, TargetDate: &targetDate}
+
+	if goal.IsOverdueAt(targetDate) {
+		t.Error("Goal should not be overdue on its target date")
+	}
+	if !goal.IsOverdueAt(targetDate.Add(time.Second)) {
+		t.Error("Goal should be overdue after its target date")
+	}
+
+	goal.CurrentAmount = 1000
+	if goal.IsOverdueAt(targetDate.AddDate(1, 0, 0)) {
+		t.Error("Completed goals are never overdue")
+	}
+	if (&FinancialGoal{TargetAmount: 1}).IsOverdueAt(targetDate) {
+		t.Error("Goals without a target date are never overdue")
+	}
+}
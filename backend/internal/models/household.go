@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Household sharing levels, from most to least restrictive
+const (
+	SharingNone       = "none"
+	SharingTotalsOnly = "totals_only"
+	SharingFull       = "full"
+)
+
+// Entity types a household member can share
+const (
+	SharedExpenses    = "expenses"
+	SharedIncomes     = "incomes"
+	SharedInvestments = "investments"
+	SharedGoals       = "goals"
+)
+
+// SharingLevels lists the household sharing levels
+var SharingLevels = EnumSet{Name: "sharing_level", Values: []EnumValue{
+	{Value: SharingNone, Label: "Nothing", Description: "Other members see nothing"},
+	{Value: SharingTotalsOnly, Label: "Totals only", Description: "Other members see totals in summaries but no individual records"},
+	{Value: SharingFull, Label: "Everything", Description: "Other members see every record"},
+}}
+
+// SharedEntityTypes lists the entity types covered by household sharing
+var SharedEntityTypes = EnumSet{Name: "shared_entity_type", Values: []EnumValue{
+	{Value: SharedExpenses, Label: "Expenses"},
+	{Value: SharedIncomes, Label: "Incomes"},
+	{Value: SharedInvestments, Label: "Investments"},
+	{Value: SharedGoals, Label: "Goals"},
+}}
+
+// SharingRank orders sharing levels by how much they reveal; unknown levels
+// rank with none
+func SharingRank(level string) int {
+	switch level {
+	case SharingTotalsOnly:
+		return 1
+	case SharingFull:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Household groups users who share their finances with each other
+type Household struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedBy uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// HouseholdMembership links a user to a household
+type HouseholdMembership struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	HouseholdID uuid.UUID `json:"household_id" db:"household_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// HouseholdSharing is what a member shares of one entity type with the rest
+// of the household
+type HouseholdSharing struct {
+	HouseholdID uuid.UUID `json:"household_id" db:"household_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	EntityType  string    `json:"entity_type" db:"entity_type"`
+	Level       string    `json:"level" db:"level"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SharingUpdateRequest represents a member's request to change a sharing level
+type SharingUpdateRequest struct {
+	EntityType string `json:"entity_type"`
+	Level      string `json:"level"`
+}
+
+// SharingReviewEntry describes what a member shares of one entity type
+type SharingReviewEntry struct {
+	EntityType string      `json:"entity_type"`
+	Level      string      `json:"level"`
+	Summary    string      `json:"summary"`
+	SharedWith []uuid.UUID `json:"shared_with"`
+	// TightenTo lists the stricter levels the member can switch to
+	TightenTo []string `json:"tighten_to"`
+}
+
+// SharingReview shows a member everything they share with their household
+type SharingReview struct {
+	HouseholdID uuid.UUID            `json:"household_id"`
+	UserID      uuid.UUID            `json:"user_id"`
+	Entries     []SharingReviewEntry `json:"entries"`
+}
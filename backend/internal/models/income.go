@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Income sources
+const (
+	IncomeSourceSalary        = "salary"
+	IncomeSourceReimbursement = "reimbursement"
+	IncomeSourceOther         = "other"
+)
+
+// Income represents an income entry
+type Income struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Amount      float64    `json:"amount" db:"amount"`
+	Source      string     `json:"source" db:"source"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	IncomeDate  time.Time  `json:"income_date" db:"income_date"`
+	SplitID     *uuid.UUID `json:"split_id,omitempty" db:"split_id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IncomeCreateRequest represents the request to create a new income entry
+type IncomeCreateRequest struct {
+	Amount      float64   `json:"amount" validate:"required,gt=0"`
+	Source      string    `json:"source" validate:"required,oneof=salary reimbursement other"`
+	Description *string   `json:"description,omitempty"`
+	IncomeDate  time.Time `json:"income_date" validate:"required"`
+}
+
+// IncomeSortFields are the columns income lists may be sorted by
+var IncomeSortFields = []string{"income_date", "amount", "source", "created_at", "updated_at"}
+
+// NewReimbursementIncome builds the income entry recorded when a participant
+// settles their share of the user's expense
+func NewReimbursementIncome(expense *Expense, split *ExpenseSplit) *Income {
+	description := "Reimbursement: " + expense.Description
+	if split.ParticipantName != nil {
+		description += " (" + *split.ParticipantName + ")"
+	}
+
+	incomeDate := time.Now()
+	if split.SettledAt != nil {
+		incomeDate = *split.SettledAt
+	}
+
+	splitID := split.ID
+	return &Income{
+		ID:          uuid.New(),
+		UserID:      expense.UserID,
+		Amount:      split.ShareAmount,
+		Source:      IncomeSourceReimbursement,
+		Description: &description,
+		IncomeDate:  incomeDate,
+		SplitID:     &splitID,
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import "tgfinance/pkg/finance"
+
+// Projection defaults applied when the client omits a parameter
+const (
+	DefaultProjectionAnnualReturn  = 7.0
+	DefaultProjectionInflationRate = 3.0
+	DefaultProjectionHorizonYears  = 20
+)
+
+// ProjectionRequest represents the query parameters of the projection endpoint
+type ProjectionRequest struct {
+	CurrentValue        *float64 `json:"current_value,omitempty"`
+	MonthlyContribution float64  `json:"monthly_contribution" validate:"gte=0"`
+	AnnualReturn        *float64 `json:"annual_return,omitempty" validate:"omitempty,gte=-50,lte=50"`
+	InflationRate       *float64 `json:"inflation_rate,omitempty"`
+	HorizonYears        *int     `json:"horizon_years,omitempty" validate:"omitempty,gte=1,lte=60"`
+	MonteCarlo          bool     `json:"monte_carlo"`
+	Iterations          int      `json:"iterations,omitempty" validate:"omitempty,lte=10000"`
+	Volatility          float64  `json:"volatility,omitempty"`
+	Seed                int64    `json:"seed,omitempty"`
+}
+
+// ToParams converts the request into projection parameters, using
+// portfolioValue (the user's current total investment value) when no
+// current value was supplied
+func (r *ProjectionRequest) ToParams(portfolioValue float64) finance.ProjectionParams {
+	params := finance.ProjectionParams{
+		CurrentValue:        portfolioValue,
+		MonthlyContribution: r.MonthlyContribution,
+		AnnualReturn:        DefaultProjectionAnnualReturn,
+		InflationRate:       DefaultProjectionInflationRate,
+		HorizonYears:        DefaultProjectionHorizonYears,
+		MonteCarlo:          r.MonteCarlo,
+		Iterations:          r.Iterations,
+		Volatility:          r.Volatility,
+		Seed:                r.Seed,
+	}
+
+	if r.CurrentValue != nil {
+		params.CurrentValue = *r.CurrentValue
+	}
+	if r.AnnualReturn != nil {
+		params.AnnualReturn = *r.AnnualReturn
+	}
+	if r.InflationRate != nil {
+		params.InflationRate = *r.InflationRate
+	}
+	if r.HorizonYears != nil {
+		params.HorizonYears = *r.HorizonYears
+	}
+
+	return params
+}
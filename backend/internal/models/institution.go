@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Institution is a canonical bank or broker that free-text institution names
+// are mapped to
+type Institution struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Aliases   []string  `json:"aliases,omitempty" db:"aliases"`
+	LogoURL   *string   `json:"logo_url,omitempty" db:"logo_url"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// InstitutionMatch is a canonical institution suggested for free text
+type InstitutionMatch struct {
+	Institution Institution `json:"institution"`
+	Score       float64     `json:"score"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IntegrationToken is a read-only API token a user creates for a third-party
+// tool, limited to an allowlist of endpoints
+type IntegrationToken struct {
+	ID                 uuid.UUID  `json:"id" db:"id"`
+	UserID             uuid.UUID  `json:"user_id" db:"user_id"`
+	Name               string     `json:"name" db:"name"`
+	Prefix             string     `json:"prefix" db:"token_prefix"`
+	Endpoints          []string   `json:"endpoints" db:"endpoints"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute" db:"rate_limit_per_minute"`
+	ExpiresAt          time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt         *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP         *string    `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	WriteAttempts      int        `json:"write_attempts" db:"write_attempts"`
+	DisabledAt         *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+
+	TokenHash string `json:"-" db:"token_hash"`
+}
+
+// IntegrationTokenRequest represents the request to create an integration token
+type IntegrationTokenRequest struct {
+	Name               string   `json:"name"`
+	Endpoints          []string `json:"endpoints"`
+	RateLimitPerMinute int      `json:"rate_limit_per_minute,omitempty"`
+	ExpiresInDays      int      `json:"expires_in_days,omitempty"`
+}
+
+// IntegrationTokenRenewRequest represents the request to extend a token's expiry
+type IntegrationTokenRenewRequest struct {
+	ExpiresInDays int `json:"expires_in_days,omitempty"`
+}
+
+// IntegrationTokenCreated is returned once when a token is created; the
+// plaintext token cannot be retrieved again
+type IntegrationTokenCreated struct {
+	IntegrationToken
+	Token string `json:"token"`
+}
+
+// IntegrationEndpoint describes an endpoint integration tokens may be allowed to call
+type IntegrationEndpoint struct {
+	Name    string `json:"name"`
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Label   string `json:"label"`
+}
+
+// IsActive returns true if the token can be used at now
+func (t *IntegrationToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && t.DisabledAt == nil && now.Before(t.ExpiresAt)
+}
+
+// Allows returns true if the named endpoint is on the token's allowlist
+func (t *IntegrationToken) Allows(endpoint string) bool {
+	for _, e := range t.Endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
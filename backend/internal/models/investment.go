@@ -1,9 +1,37 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
+)
+
+// Investment transaction types
+const (
+	TransactionTypeDeposit    = "deposit"
+	TransactionTypeWithdrawal = "withdrawal"
+	TransactionTypeInterest   = "interest"
+	TransactionTypeDividend   = "dividend"
+	TransactionTypeFee        = "fee"
+)
+
+// Investment statuses
+const (
+	InvestmentStatusActive    = "active"
+	InvestmentStatusMatured   = "matured"
+	InvestmentStatusCancelled = "cancelled"
+	InvestmentStatusClosed    = "closed"
+)
+
+// Investment type risk levels
+const (
+	RiskLevelLow    = "low"
+	RiskLevelMedium = "medium"
+	RiskLevelHigh   = "high"
 )
 
 // InvestmentType represents an investment type
@@ -14,6 +42,9 @@ type InvestmentType struct {
 	RiskLevel      string    `json:"risk_level" db:"risk_level"`
 	ExpectedReturn float64   `json:"expected_return" db:"expected_return"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+
+	// ValidationProfile holds the field rules investments of the type must follow
+	ValidationProfile *utils.ValidationProfile `json:"validation_profile,omitempty" db:"validation_profile"`
 }
 
 // Investment represents an investment entry
@@ -22,32 +53,56 @@ type Investment struct {
 	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
 	TypeID        uuid.UUID  `json:"type_id" db:"type_id"`
 	Name          string     `json:"name" db:"name"`
+	Symbol        *string    `json:"symbol,omitempty" db:"symbol"`
 	Amount        float64    `json:"amount" db:"amount"`
 	CurrentValue  *float64   `json:"current_value,omitempty" db:"current_value"`
 	StartDate     time.Time  `json:"start_date" db:"start_date"`
 	EndDate       *time.Time `json:"end_date,omitempty" db:"end_date"`
 	InterestRate  *float64   `json:"interest_rate,omitempty" db:"interest_rate"`
+	ExpenseRatio  *float64   `json:"expense_ratio,omitempty" db:"expense_ratio"`
 	Institution   *string    `json:"institution,omitempty" db:"institution"`
-	AccountNumber *string    `json:"account_number,omitempty" db:"account_number"`
-	Notes         *string    `json:"notes,omitempty" db:"notes"`
-	Status        string     `json:"status" db:"status"`
-	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	InstitutionID *uuid.UUID `json:"institution_id,omitempty" db:"institution_id"`
+	// AccountNumber is never written to JSON; responses carry
+	// MaskedAccountNumber, set by MaskAccountNumber
+	AccountNumber       *string   `json:"-" db:"account_number"`
+	MaskedAccountNumber *string   `json:"masked_account_number,omitempty" db:"-"`
+	Notes               *string   `json:"notes,omitempty" db:"notes"`
+	Status              string    `json:"status" db:"status"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+	Version             int64     `json:"version" db:"version"`
+
+	// Closing of the position
+	RealizedGain         *float64   `json:"realized_gain,omitempty" db:"realized_gain"`
+	ClosedAt             *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+	ClosingTransactionID *uuid.UUID `json:"closing_transaction_id,omitempty" db:"closing_transaction_id"`
 
 	// Relations
-	Type *InvestmentType `json:"type,omitempty"`
-	User *User           `json:"user,omitempty"`
+	Type                 *InvestmentType `json:"type,omitempty"`
+	User                 *User           `json:"user,omitempty"`
+	CanonicalInstitution *Institution    `json:"canonical_institution,omitempty"`
+}
+
+// MaskAccountNumber sets MaskedAccountNumber from AccountNumber. Call it
+// after loading or changing an investment and before responding or logging.
+func (i *Investment) MaskAccountNumber() {
+	i.MaskedAccountNumber = nil
+	if i.AccountNumber != nil && *i.AccountNumber != "" {
+		masked := utils.MaskAccountNumber(*i.AccountNumber)
+		i.MaskedAccountNumber = &masked
+	}
 }
 
 // InvestmentTransaction represents an investment transaction
 type InvestmentTransaction struct {
-	ID              uuid.UUID `json:"id" db:"id"`
-	InvestmentID    uuid.UUID `json:"investment_id" db:"investment_id"`
-	TransactionType string    `json:"transaction_type" db:"transaction_type"`
-	Amount          float64   `json:"amount" db:"amount"`
-	TransactionDate time.Time `json:"transaction_date" db:"transaction_date"`
-	Description     *string   `json:"description,omitempty" db:"description"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	InvestmentID    uuid.UUID  `json:"investment_id" db:"investment_id"`
+	TransactionType string     `json:"transaction_type" db:"transaction_type"`
+	Amount          float64    `json:"amount" db:"amount"`
+	TransactionDate time.Time  `json:"transaction_date" db:"transaction_date"`
+	Description     *string    `json:"description,omitempty" db:"description"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
 
 	// Relations
 	Investment *Investment `json:"investment,omitempty"`
@@ -57,11 +112,13 @@ type InvestmentTransaction struct {
 type InvestmentCreateRequest struct {
 	TypeID        uuid.UUID  `json:"type_id" validate:"required"`
 	Name          string     `json:"name" validate:"required"`
+	Symbol        *string    `json:"symbol,omitempty"`
 	Amount        float64    `json:"amount" validate:"required,gt=0"`
 	CurrentValue  *float64   `json:"current_value,omitempty"`
 	StartDate     time.Time  `json:"start_date" validate:"required"`
 	EndDate       *time.Time `json:"end_date,omitempty"`
 	InterestRate  *float64   `json:"interest_rate,omitempty"`
+	ExpenseRatio  *float64   `json:"expense_ratio,omitempty" validate:"omitempty,gte=0,lte=100"`
 	Institution   *string    `json:"institution,omitempty"`
 	AccountNumber *string    `json:"account_number,omitempty"`
 	Notes         *string    `json:"notes,omitempty"`
@@ -69,25 +126,119 @@ type InvestmentCreateRequest struct {
 
 // InvestmentUpdateRequest represents the request to update an investment
 type InvestmentUpdateRequest struct {
-	Name          *string    `json:"name,omitempty"`
-	Amount        *float64   `json:"amount,omitempty" validate:"omitempty,gt=0"`
-	CurrentValue  *float64   `json:"current_value,omitempty"`
-	EndDate       *time.Time `json:"end_date,omitempty"`
-	InterestRate  *float64   `json:"interest_rate,omitempty"`
-	Institution   *string    `json:"institution,omitempty"`
-	AccountNumber *string    `json:"account_number,omitempty"`
-	Notes         *string    `json:"notes,omitempty"`
-	Status        *string    `json:"status,omitempty"`
+	Name          *string                   `json:"name,omitempty"`
+	Symbol        utils.Optional[string]    `json:"symbol"`
+	Amount        *float64                  `json:"amount,omitempty" validate:"omitempty,gt=0"`
+	CurrentValue  utils.Optional[float64]   `json:"current_value"`
+	EndDate       utils.Optional[time.Time] `json:"end_date"`
+	InterestRate  utils.Optional[float64]   `json:"interest_rate"`
+	ExpenseRatio  utils.Optional[float64]   `json:"expense_ratio" validate:"omitempty,gte=0,lte=100"`
+	Institution   utils.Optional[string]    `json:"institution"`
+	AccountNumber utils.Optional[string]    `json:"account_number"`
+	Notes         utils.Optional[string]    `json:"notes"`
+	Status        *string                   `json:"status,omitempty"`
+	// Version is the row version the update is based on, as an alternative to If-Match
+	Version *int64 `json:"version,omitempty"`
+}
+
+// Validate checks the request's validate tags, name, amounts, interest rate
+// and account number, and that the end date, if any, is after the start
+// date. The account number is normalized in place.
+func (r *InvestmentCreateRequest) Validate() utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	addFieldError(&errs, utils.ValidateLength(r.Name, "name", 1, maxEntityNameLength))
+	addFieldError(&errs, utils.ValidateAmount(r.Amount, "amount"))
+	if r.CurrentValue != nil {
+		addFieldError(&errs, validateNonNegativeAmount(*r.CurrentValue, "current_value"))
+	}
+	if r.InterestRate != nil {
+		addFieldError(&errs, utils.ValidateRate(*r.InterestRate, "interest_rate"))
+	}
+	if r.AccountNumber != nil {
+		if accountNumber, err := utils.ValidateAccountNumber(*r.AccountNumber, "account_number"); err != nil {
+			addFieldError(&errs, err)
+		} else {
+			r.AccountNumber = &accountNumber
+		}
+	}
+	if r.EndDate != nil && !r.StartDate.IsZero() {
+		addFieldError(&errs, validateEndAfterStart(r.StartDate, *r.EndDate))
+	}
+	return errs
+}
+
+// Validate checks that the request changes something and validates the
+// fields it sets, normalizing the account number in place. The end date is
+// checked against the stored start date when the update is applied.
+func (r *InvestmentUpdateRequest) Validate() utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	checkNotEmptyUpdate(&errs, len(r.Changes()) > 0)
+	if r.Name != nil {
+		addFieldError(&errs, utils.ValidateLength(*r.Name, "name", 1, maxEntityNameLength))
+	}
+	if r.Amount != nil {
+		addFieldError(&errs, utils.ValidateAmount(*r.Amount, "amount"))
+	}
+	if currentValue, ok := r.CurrentValue.Get(); ok {
+		addFieldError(&errs, validateNonNegativeAmount(currentValue, "current_value"))
+	}
+	if interestRate, ok := r.InterestRate.Get(); ok {
+		addFieldError(&errs, utils.ValidateRate(interestRate, "interest_rate"))
+	}
+	if r.AccountNumber.HasValue() {
+		if accountNumber, err := utils.ValidateAccountNumber(r.AccountNumber.Value, "account_number"); err != nil {
+			addFieldError(&errs, err)
+		} else {
+			r.AccountNumber.Value = accountNumber
+		}
+	}
+	checkEnum(&errs, "status", r.Status, InvestmentStatuses)
+	return errs
+}
+
+// validateNonNegativeAmount validates an amount that may be zero, such as
+// the current value of an investment that lost everything
+func validateNonNegativeAmount(amount float64, fieldName string) error {
+	if amount < 0 {
+		return &utils.ValidationError{Field: fieldName, Message: fieldName + " must not be negative"}
+	}
+	if amount == 0 {
+		return nil
+	}
+	return utils.ValidateAmount(amount, fieldName)
+}
+
+// validateEndAfterStart validates that an investment ends on a later
+// calendar day than it starts, in the start date's location
+func validateEndAfterStart(start, end time.Time) error {
+	if end.In(start.Location()).Format(time.DateOnly) <= start.Format(time.DateOnly) {
+		return &utils.ValidationError{Field: "end_date", Message: "end_date must be after start_date"}
+	}
+	return nil
 }
 
 // InvestmentTransactionCreateRequest represents the request to create a transaction
 type InvestmentTransactionCreateRequest struct {
-	TransactionType string    `json:"transaction_type" validate:"required,oneof=deposit withdrawal interest dividend"`
+	TransactionType string    `json:"transaction_type" validate:"required,oneof=deposit withdrawal interest dividend fee"`
 	Amount          float64   `json:"amount" validate:"required,gt=0"`
 	TransactionDate time.Time `json:"transaction_date" validate:"required"`
 	Description     *string   `json:"description,omitempty"`
 }
 
+// Validate checks the request's validate tags and amount, and that the
+// transaction date is not in the future
+func (r *InvestmentTransactionCreateRequest) Validate() utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	addFieldError(&errs, utils.ValidateAmount(r.Amount, "amount"))
+	if !r.TransactionDate.IsZero() {
+		addFieldError(&errs, utils.ValidateNotFuture(r.TransactionDate, "transaction_date"))
+	}
+	return errs
+}
+
+// InvestmentSortFields are the columns investment lists may be sorted by
+var InvestmentSortFields = []string{"name", "amount", "current_value", "start_date", "end_date", "status", "created_at", "updated_at"}
+
 // InvestmentFilter represents filters for investment queries
 type InvestmentFilter struct {
 	UserID      uuid.UUID  `json:"user_id"`
@@ -100,15 +251,23 @@ type InvestmentFilter struct {
 	Offset      int        `json:"offset,omitempty"`
 }
 
-// InvestmentSummary represents investment summary statistics
+// InvestmentSummary represents investment summary statistics. The Money
+// fields are the exact totals, kept alongside the float ones while clients
+// move off floats.
 type InvestmentSummary struct {
-	TotalInvested     float64                   `json:"total_invested"`
-	TotalCurrentValue float64                   `json:"total_current_value"`
-	TotalGain         float64                   `json:"total_gain"`
-	TotalGainPercent  float64                   `json:"total_gain_percent"`
-	ByType            []TypeInvestmentSummary   `json:"by_type,omitempty"`
-	ByStatus          []StatusInvestmentSummary `json:"by_status,omitempty"`
-	ByInstitution     []InstitutionSummary      `json:"by_institution,omitempty"`
+	TotalInvested          float64                   `json:"total_invested"`
+	TotalInvestedMoney     Money                     `json:"total_invested_money"`
+	TotalCurrentValue      float64                   `json:"total_current_value"`
+	TotalCurrentValueMoney Money                     `json:"total_current_value_money"`
+	TotalGain              float64                   `json:"total_gain"`
+	TotalGainMoney         Money                     `json:"total_gain_money"`
+	TotalGainPercent       float64                   `json:"total_gain_percent"`
+	TotalRealizedGain      float64                   `json:"total_realized_gain"`
+	TotalRealizedGainMoney Money                     `json:"total_realized_gain_money"`
+	ClosedCount            int                       `json:"closed_count"`
+	ByType                 []TypeInvestmentSummary   `json:"by_type,omitempty"`
+	ByStatus               []StatusInvestmentSummary `json:"by_status,omitempty"`
+	ByInstitution          []InstitutionSummary      `json:"by_institution,omitempty"`
 }
 
 // TypeInvestmentSummary represents investment summary by type
@@ -131,11 +290,135 @@ type StatusInvestmentSummary struct {
 	Count          int     `json:"count"`
 }
 
-// InstitutionSummary represents investment summary by institution
+// InstitutionSummary represents investment summary by institution. Entries
+// are grouped by canonical institution when one is mapped, otherwise by the
+// raw institution text.
 type InstitutionSummary struct {
-	Institution    string  `json:"institution"`
-	InvestedAmount float64 `json:"invested_amount"`
-	CurrentValue   float64 `json:"current_value"`
-	Gain           float64 `json:"gain"`
-	Count          int     `json:"count"`
+	InstitutionID  *uuid.UUID `json:"institution_id,omitempty"`
+	Institution    string     `json:"institution"`
+	InvestedAmount float64    `json:"invested_amount"`
+	CurrentValue   float64    `json:"current_value"`
+	Gain           float64    `json:"gain"`
+	Count          int        `json:"count"`
+}
+
+// InvestmentProfileFields are the investment fields a type's validation
+// profile may refer to, by kind
+var InvestmentProfileFields = map[string]string{
+	"symbol":         utils.FieldKindString,
+	"current_value":  utils.FieldKindNumber,
+	"end_date":       utils.FieldKindDate,
+	"interest_rate":  utils.FieldKindNumber,
+	"expense_ratio":  utils.FieldKindNumber,
+	"institution":    utils.FieldKindString,
+	"account_number": utils.FieldKindString,
+	"notes":          utils.FieldKindString,
+}
+
+// ProfileValues returns the fields of InvestmentProfileFields, with nil for
+// unset fields
+func (i *Investment) ProfileValues() map[string]any {
+	return map[string]any{
+		"symbol":         deref(i.Symbol),
+		"current_value":  deref(i.CurrentValue),
+		"end_date":       deref(i.EndDate),
+		"interest_rate":  deref(i.InterestRate),
+		"expense_ratio":  deref(i.ExpenseRatio),
+		"institution":    deref(i.Institution),
+		"account_number": deref(i.AccountNumber),
+		"notes":          deref(i.Notes),
+	}
+}
+
+// deref returns the value p points to, or nil
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// GetCurrentValue returns the current value, falling back to the invested amount
+func (i *Investment) GetCurrentValue() float64 {
+	if i.CurrentValue != nil {
+		return *i.CurrentValue
+	}
+	return i.Amount
+}
+
+// ApplyTransaction recalculates the invested amount and current value after a
+// transaction. Deposits and withdrawals move both the invested amount and the
+// value; interest and dividends accrue to the value; fees are deducted from
+// the value the same way withdrawals are, without returning principal.
+func (i *Investment) ApplyTransaction(tx *InvestmentTransaction) error {
+	if i.IsClosed() {
+		return fmt.Errorf("cannot record transactions on a closed investment")
+	}
+	value := i.GetCurrentValue()
+
+	switch tx.TransactionType {
+	case TransactionTypeDeposit:
+		i.Amount += tx.Amount
+		value += tx.Amount
+	case TransactionTypeInterest, TransactionTypeDividend:
+		value += tx.Amount
+	case TransactionTypeWithdrawal, TransactionTypeFee:
+		if tx.Amount > value {
+			return fmt.Errorf("%s of %.2f exceeds current value %.2f", tx.TransactionType, tx.Amount, value)
+		}
+		if tx.TransactionType == TransactionTypeWithdrawal {
+			i.Amount -= tx.Amount
+		}
+		value -= tx.Amount
+	default:
+		return fmt.Errorf("unknown transaction type: %s", tx.TransactionType)
+	}
+
+	value = finance.RoundCents(value)
+	i.Amount = finance.RoundCents(i.Amount)
+	i.CurrentValue = &value
+	return nil
+}
+
+// PerformanceCashflows returns the investor cashflows used for XIRR, ending
+// with the current value at asOf. Principal not explained by deposit and
+// withdrawal transactions is treated as an opening deposit on StartDate.
+//
+// Fees are part of the cashflows: in the net series a fee is money that
+// leaves the position and never reaches the investor, while the gross series
+// credits each fee back to the investor on the date it was charged. The
+// difference between the two rates is the return lost to fees.
+func (i *Investment) PerformanceCashflows(transactions []InvestmentTransaction, asOf time.Time) (net, gross []finance.Cashflow) {
+	opening := i.Amount
+	for _, tx := range transactions {
+		switch tx.TransactionType {
+		case TransactionTypeDeposit:
+			opening -= tx.Amount
+		case TransactionTypeWithdrawal:
+			opening += tx.Amount
+		}
+	}
+	if opening > 0 {
+		net = append(net, finance.Cashflow{Date: i.StartDate, Amount: -finance.RoundCents(opening)})
+		gross = append(gross, finance.Cashflow{Date: i.StartDate, Amount: -finance.RoundCents(opening)})
+	}
+
+	for _, tx := range transactions {
+		switch tx.TransactionType {
+		case TransactionTypeDeposit:
+			net = append(net, finance.Cashflow{Date: tx.TransactionDate, Amount: -tx.Amount})
+			gross = append(gross, finance.Cashflow{Date: tx.TransactionDate, Amount: -tx.Amount})
+		case TransactionTypeWithdrawal:
+			net = append(net, finance.Cashflow{Date: tx.TransactionDate, Amount: tx.Amount})
+			gross = append(gross, finance.Cashflow{Date: tx.TransactionDate, Amount: tx.Amount})
+		case TransactionTypeFee:
+			net = append(net, finance.Cashflow{Date: tx.TransactionDate, Amount: 0})
+			gross = append(gross, finance.Cashflow{Date: tx.TransactionDate, Amount: tx.Amount})
+		}
+	}
+
+	value := i.GetCurrentValue()
+	net = append(net, finance.Cashflow{Date: asOf, Amount: value})
+	gross = append(gross, finance.Cashflow{Date: asOf, Amount: value})
+	return net, gross
 }
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+)
+
+// InvestmentCloseRequest represents the request to close an investment position
+type InvestmentCloseRequest struct {
+	RealizedValue float64   `json:"realized_value" validate:"gte=0"`
+	CloseDate     time.Time `json:"close_date" validate:"required"`
+	Notes         *string   `json:"notes,omitempty"`
+}
+
+// IsClosed returns true if the position has been closed
+func (i *Investment) IsClosed() bool {
+	return i.Status == InvestmentStatusClosed
+}
+
+// Close closes the position at the realized value. It returns the closing
+// withdrawal for the full realized value, records the realized gain against
+// the net invested amount and zeroes the current value. The invested amount
+// is kept for historical reports.
+func (i *Investment) Close(req InvestmentCloseRequest) (*InvestmentTransaction, error) {
+	if i.IsClosed() {
+		return nil, fmt.Errorf("investment is already closed")
+	}
+	if req.RealizedValue < 0 {
+		return nil, fmt.Errorf("realized value must not be negative")
+	}
+	if req.CloseDate.IsZero() {
+		return nil, fmt.Errorf("close date is required")
+	}
+	if req.CloseDate.Before(i.StartDate) {
+		return nil, fmt.Errorf("close date cannot be before the start date")
+	}
+
+	description := "Position closed"
+	if req.Notes != nil && *req.Notes != "" {
+		description = *req.Notes
+	}
+	tx := &InvestmentTransaction{
+		ID:              uuid.New(),
+		InvestmentID:    i.ID,
+		TransactionType: TransactionTypeWithdrawal,
+		Amount:          finance.RoundCents(req.RealizedValue),
+		TransactionDate: req.CloseDate,
+		Description:     &description,
+	}
+
+	gain := finance.RoundCents(req.RealizedValue - i.Amount)
+	zero := 0.0
+	closedAt := req.CloseDate
+
+	i.RealizedGain = &gain
+	i.ClosedAt = &closedAt
+	i.ClosingTransactionID = &tx.ID
+	i.CurrentValue = &zero
+	i.Status = InvestmentStatusClosed
+
+	return tx, nil
+}
+
+// Reopen reverses a close. closing must be the transaction recorded by Close;
+// the caller deletes it in the same database transaction.
+func (i *Investment) Reopen(closing *InvestmentTransaction) error {
+	if !i.IsClosed() {
+		return fmt.Errorf("investment is not closed")
+	}
+	if i.ClosingTransactionID == nil || closing == nil || closing.ID != *i.ClosingTransactionID {
+		return fmt.Errorf("closing transaction does not match the investment")
+	}
+
+	value := closing.Amount
+	i.CurrentValue = &value
+	i.RealizedGain = nil
+	i.ClosedAt = nil
+	i.ClosingTransactionID = nil
+	i.Status = InvestmentStatusActive
+	return nil
+}
+
+// SummarizeInvestments builds summary statistics. Closed positions are
+// excluded from invested, current value and unrealized gain totals but are
+// counted in the realized gain total and the by-status breakdown.
+func SummarizeInvestments(investments []Investment) InvestmentSummary {
+	var summary InvestmentSummary
+
+	typeIndex := make(map[uuid.UUID]int)
+	statusIndex := make(map[string]int)
+	institutionIndex := make(map[string]int)
+
+	for idx := range investments {
+		investment := &investments[idx]
+
+		i, ok := statusIndex[investment.Status]
+		if !ok {
+			summary.ByStatus = append(summary.ByStatus, StatusInvestmentSummary{Status: investment.Status})
+			i = len(summary.ByStatus) - 1
+			statusIndex[investment.Status] = i
+		}
+		summary.ByStatus[i].InvestedAmount += investment.Amount
+		summary.ByStatus[i].Count++
+
+		if investment.IsClosed() {
+			summary.ClosedCount++
+			if investment.RealizedGain != nil {
+				summary.TotalRealizedGain += *investment.RealizedGain
+				summary.TotalRealizedGainMoney = summary.TotalRealizedGainMoney.Add(MoneyFromFloat(*investment.RealizedGain))
+				summary.ByStatus[i].Gain += *investment.RealizedGain
+			}
+			continue
+		}
+
+		value := investment.GetCurrentValue()
+		gain := value - investment.Amount
+		summary.ByStatus[i].CurrentValue += value
+		summary.ByStatus[i].Gain += gain
+
+		summary.TotalInvested += investment.Amount
+		summary.TotalCurrentValue += value
+		summary.TotalInvestedMoney = summary.TotalInvestedMoney.Add(MoneyFromFloat(investment.Amount))
+		summary.TotalCurrentValueMoney = summary.TotalCurrentValueMoney.Add(MoneyFromFloat(value))
+
+		i, ok = typeIndex[investment.TypeID]
+		if !ok {
+			entry := TypeInvestmentSummary{TypeID: investment.TypeID}
+			if investment.Type != nil {
+				entry.TypeName = investment.Type.Name
+			}
+			summary.ByType = append(summary.ByType, entry)
+			i = len(summary.ByType) - 1
+			typeIndex[investment.TypeID] = i
+		}
+		summary.ByType[i].InvestedAmount += investment.Amount
+		summary.ByType[i].CurrentValue += value
+		summary.ByType[i].Gain += gain
+		summary.ByType[i].Count++
+
+		institution := "unspecified"
+		if investment.Institution != nil && *investment.Institution != "" {
+			institution = *investment.Institution
+		}
+		institutionKey := "raw:" + institution
+		if investment.InstitutionID != nil {
+			institutionKey = investment.InstitutionID.String()
+		}
+		i, ok = institutionIndex[institutionKey]
+		if !ok {
+			entry := InstitutionSummary{Institution: institution, InstitutionID: investment.InstitutionID}
+			if investment.CanonicalInstitution != nil {
+				entry.Institution = investment.CanonicalInstitution.Name
+			}
+			summary.ByInstitution = append(summary.ByInstitution, entry)
+			i = len(summary.ByInstitution) - 1
+			institutionIndex[institutionKey] = i
+		}
+		summary.ByInstitution[i].InvestedAmount += investment.Amount
+		summary.ByInstitution[i].CurrentValue += value
+		summary.ByInstitution[i].Gain += gain
+		summary.ByInstitution[i].Count++
+	}
+
+	summary.TotalInvested = finance.RoundCents(summary.TotalInvested)
+	summary.TotalCurrentValue = finance.RoundCents(summary.TotalCurrentValue)
+	summary.TotalGain = finance.RoundCents(summary.TotalCurrentValue - summary.TotalInvested)
+	summary.TotalGainPercent = percentageOf(summary.TotalGain, summary.TotalInvested)
+	summary.TotalRealizedGain = finance.RoundCents(summary.TotalRealizedGain)
+	summary.TotalGainMoney = summary.TotalCurrentValueMoney.Sub(summary.TotalInvestedMoney)
+
+	for i := range summary.ByType {
+		entry := &summary.ByType[i]
+		entry.InvestedAmount = finance.RoundCents(entry.InvestedAmount)
+		entry.CurrentValue = finance.RoundCents(entry.CurrentValue)
+		entry.Gain = finance.RoundCents(entry.Gain)
+		entry.GainPercent = percentageOf(entry.Gain, entry.InvestedAmount)
+	}
+	for i := range summary.ByStatus {
+		entry := &summary.ByStatus[i]
+		entry.InvestedAmount = finance.RoundCents(entry.InvestedAmount)
+		entry.CurrentValue = finance.RoundCents(entry.CurrentValue)
+		entry.Gain = finance.RoundCents(entry.Gain)
+	}
+	for i := range summary.ByInstitution {
+		entry := &summary.ByInstitution[i]
+		entry.InvestedAmount = finance.RoundCents(entry.InvestedAmount)
+		entry.CurrentValue = finance.RoundCents(entry.CurrentValue)
+		entry.Gain = finance.RoundCents(entry.Gain)
+	}
+
+	return summary
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+)
+
+// DefaultFeeProjectionGrowthRate is the assumed annual growth (percent) used
+// when projecting fee impact if the client does not supply one
+const DefaultFeeProjectionGrowthRate = 7.0
+
+// FeeProjectionYears are the horizons the fee report projects over
+var FeeProjectionYears = []int{10, 20}
+
+// FeeReport represents explicit fees and estimated expense-ratio drag across a portfolio
+type FeeReport struct {
+	GrowthRate          float64               `json:"growth_rate"`
+	TotalExplicitFees   float64               `json:"total_explicit_fees"`
+	TotalCurrentValue   float64               `json:"total_current_value"`
+	EstimatedAnnualDrag float64               `json:"estimated_annual_drag"`
+	WeightedRatio       float64               `json:"weighted_expense_ratio"`
+	Projections         []finance.FeeImpact   `json:"projections"`
+	Investments         []InvestmentFeeDetail `json:"investments"`
+}
+
+// InvestmentFeeDetail represents fee information for a single investment
+type InvestmentFeeDetail struct {
+	InvestmentID        uuid.UUID           `json:"investment_id"`
+	Name                string              `json:"name"`
+	CurrentValue        float64             `json:"current_value"`
+	ExpenseRatio        *float64            `json:"expense_ratio,omitempty"`
+	ExplicitFees        float64             `json:"explicit_fees"`
+	FeeCount            int                 `json:"fee_count"`
+	EstimatedAnnualDrag float64             `json:"estimated_annual_drag"`
+	Projections         []finance.FeeImpact `json:"projections,omitempty"`
+}
+
+// BuildFeeReport aggregates fee transactions and expense-ratio drag for the
+// given investments. transactions maps investment IDs to their transactions;
+// closed or cancelled investments still report historical explicit fees but
+// contribute no ongoing drag.
+func BuildFeeReport(investments []Investment, transactions map[uuid.UUID][]InvestmentTransaction, growthRate float64) FeeReport {
+	report := FeeReport{
+		GrowthRate:  growthRate,
+		Investments: make([]InvestmentFeeDetail, 0, len(investments)),
+	}
+
+	for _, investment := range investments {
+		detail := InvestmentFeeDetail{
+			InvestmentID: investment.ID,
+			Name:         investment.Name,
+			ExpenseRatio: investment.ExpenseRatio,
+		}
+
+		for _, tx := range transactions[investment.ID] {
+			if tx.TransactionType == TransactionTypeFee {
+				detail.ExplicitFees += tx.Amount
+				detail.FeeCount++
+			}
+		}
+		detail.ExplicitFees = finance.RoundCents(detail.ExplicitFees)
+
+		if investment.Status == InvestmentStatusActive {
+			detail.CurrentValue = investment.GetCurrentValue()
+			if investment.ExpenseRatio != nil {
+				detail.EstimatedAnnualDrag = finance.AnnualExpenseRatioDrag(detail.CurrentValue, *investment.ExpenseRatio)
+				for _, years := range FeeProjectionYears {
+					detail.Projections = append(detail.Projections,
+						finance.ProjectFeeImpact(detail.CurrentValue, *investment.ExpenseRatio, growthRate, years))
+				}
+			}
+		}
+
+		report.TotalExplicitFees += detail.ExplicitFees
+		report.TotalCurrentValue += detail.CurrentValue
+		report.EstimatedAnnualDrag += detail.EstimatedAnnualDrag
+		report.Investments = append(report.Investments, detail)
+	}
+
+	report.TotalExplicitFees = finance.RoundCents(report.TotalExplicitFees)
+	report.TotalCurrentValue = finance.RoundCents(report.TotalCurrentValue)
+	report.EstimatedAnnualDrag = finance.RoundCents(report.EstimatedAnnualDrag)
+
+	if report.TotalCurrentValue > 0 {
+		report.WeightedRatio = report.EstimatedAnnualDrag / report.TotalCurrentValue * 100
+	}
+
+	// Portfolio-wide projections sum the per-investment projections so each
+	// holding compounds at its own ratio
+	for i, years := range FeeProjectionYears {
+		total := finance.FeeImpact{Years: years}
+		for _, detail := range report.Investments {
+			if i < len(detail.Projections) {
+				total.ValueWithoutFees += detail.Projections[i].ValueWithoutFees
+				total.ValueWithFees += detail.Projections[i].ValueWithFees
+				total.FeeImpact += detail.Projections[i].FeeImpact
+			}
+		}
+		total.ValueWithoutFees = finance.RoundCents(total.ValueWithoutFees)
+		total.ValueWithFees = finance.RoundCents(total.ValueWithFees)
+		total.FeeImpact = finance.RoundCents(total.FeeImpact)
+		report.Projections = append(report.Projections, total)
+	}
+
+	return report
+}
@@ -0,0 +1,284 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+	"tgfinance/pkg/utils"
+)
+
+func TestInvestmentApplyTransaction(t *testing.T) {
+	investment := Investment{Amount: 1000}
+
+	steps := []struct {
+		tx        InvestmentTransaction
+		wantAmt   float64
+		wantValue float64
+		wantErr   bool
+	}{
+		{InvestmentTransaction{TransactionType: TransactionTypeDeposit, Amount: 500}, 1500, 1500, false},
+		{InvestmentTransaction{TransactionType: TransactionTypeInterest, Amount: 100}, 1500, 1600, false},
+		{InvestmentTransaction{TransactionType: TransactionTypeFee, Amount: 25}, 1500, 1575, false},
+		{InvestmentTransaction{TransactionType: TransactionTypeWithdrawal, Amount: 300}, 1200, 1275, false},
+		{InvestmentTransaction{TransactionType: TransactionTypeFee, Amount: 5000}, 1200, 1275, true},
+		{InvestmentTransaction{TransactionType: "bonus", Amount: 1}, 1200, 1275, true},
+	}
+
+	for i, step := range steps {
+		err := investment.ApplyTransaction(&step.tx)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("step %d: ApplyTransaction() error = %v, wantErr %v", i, err, step.wantErr)
+		}
+		if investment.Amount != step.wantAmt || investment.GetCurrentValue() != step.wantValue {
+			t.Errorf("step %d: amount %v value %v, want amount %v value %v",
+				i, investment.Amount, investment.GetCurrentValue(), step.wantAmt, step.wantValue)
+		}
+	}
+}
+
+func TestPerformanceCashflowsIncludeFees(t *testing.T) {
+	start := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	asOf := start.AddDate(1, 0, 0)
+	value := 1050.0
+	investment := Investment{Amount: 1000, CurrentValue: &value, StartDate: start}
+	transactions := []InvestmentTransaction{
+		{TransactionType: TransactionTypeFee, Amount: 50, TransactionDate: start.AddDate(0, 6, 0)},
+	}
+
+	net, gross := investment.PerformanceCashflows(transactions, asOf)
+	if len(net) != 3 || len(gross) != 3 {
+		t.Fatalf("Expected opening, fee and closing cashflows, got net %d gross %d", len(net), len(gross))
+	}
+	if net[0].Amount != -1000 || net[2].Amount != 1050 {
+		t.Errorf("Unexpected net cashflows: %+v", net)
+	}
+
+	netRate, err := finance.XIRR(net)
+	if err != nil {
+		t.Fatalf("Failed to compute net XIRR: %v", err)
+	}
+	grossRate, err := finance.XIRR(gross)
+	if err != nil {
+		t.Fatalf("Failed to compute gross XIRR: %v", err)
+	}
+	if grossRate <= netRate {
+		t.Errorf("Gross rate %v should exceed net rate %v when fees were charged", grossRate, netRate)
+	}
+}
+
+func TestBuildFeeReport(t *testing.T) {
+	ratio := 1.0
+	fundValue := 20000.0
+	fund := Investment{ID: uuid.New(), Name: "Index Fund", Amount: 18000, CurrentValue: &fundValue, ExpenseRatio: &ratio, Status: "active"}
+	deposit := Investment{ID: uuid.New(), Name: "Fixed Deposit", Amount: 5000, Status: "active"}
+	closed := Investment{ID: uuid.New(), Name: "Old Fund", Amount: 3000, ExpenseRatio: &ratio, Status: "matured"}
+
+	transactions := map[uuid.UUID][]InvestmentTransaction{
+		fund.ID: {
+			{TransactionType: TransactionTypeFee, Amount: 10},
+			{TransactionType: TransactionTypeDeposit, Amount: 1000},
+			{TransactionType: TransactionTypeFee, Amount: 15.5},
+		},
+		closed.ID: {
+			{TransactionType: TransactionTypeFee, Amount: 20},
+		},
+	}
+
+	report := BuildFeeReport([]Investment{fund, deposit, closed}, transactions, DefaultFeeProjectionGrowthRate)
+
+	if report.TotalExplicitFees != 45.5 {
+		t.Errorf("Expected explicit fees 45.5, got %v", report.TotalExplicitFees)
+	}
+	if report.EstimatedAnnualDrag != 200 {
+		t.Errorf("Expected annual drag 200, got %v", report.EstimatedAnnualDrag)
+	}
+	if report.TotalCurrentValue != 25000 {
+		t.Errorf("Expected active value 25000, got %v", report.TotalCurrentValue)
+	}
+	if report.WeightedRatio != 0.8 {
+		t.Errorf("Expected weighted ratio 0.8, got %v", report.WeightedRatio)
+	}
+	if report.Investments[0].FeeCount != 2 || report.Investments[2].ExplicitFees != 20 {
+		t.Errorf("Unexpected per-investment fees: %+v", report.Investments)
+	}
+	if report.Investments[2].EstimatedAnnualDrag != 0 {
+		t.Error("Closed investments should not contribute ongoing drag")
+	}
+
+	if len(report.Projections) != len(FeeProjectionYears) {
+		t.Fatalf("Expected %d projections, got %d", len(FeeProjectionYears), len(report.Projections))
+	}
+	want := finance.ProjectFeeImpact(20000, 1, DefaultFeeProjectionGrowthRate, 10)
+	if report.Projections[0] != want {
+		t.Errorf("Portfolio projection = %+v, want %+v", report.Projections[0], want)
+	}
+}
+
+func TestCloseAndReopenInvestment(t *testing.T) {
+	value := 1250.0
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	investment := Investment{ID: uuid.New(), Amount: 1000, CurrentValue: &value, StartDate: start, Status: InvestmentStatusActive}
+	closeDate := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := investment.Close(InvestmentCloseRequest{RealizedValue: 1300, CloseDate: start.AddDate(0, 0, -1)}); err == nil {
+		t.Error("Closing before the start date should fail")
+	}
+
+	tx, err := investment.Close(InvestmentCloseRequest{RealizedValue: 1300, CloseDate: closeDate})
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if tx.TransactionType != TransactionTypeWithdrawal || tx.Amount != 1300 || tx.InvestmentID != investment.ID {
+		t.Errorf("Unexpected closing transaction: %+v", tx)
+	}
+	if !investment.IsClosed() || *investment.RealizedGain != 300 || investment.GetCurrentValue() != 0 {
+		t.Errorf("Unexpected closed investment: %+v", investment)
+	}
+	if _, err := investment.Close(InvestmentCloseRequest{RealizedValue: 1300, CloseDate: closeDate}); err == nil {
+		t.Error("Closing twice should fail")
+	}
+	if err := investment.ApplyTransaction(&InvestmentTransaction{TransactionType: TransactionTypeDeposit, Amount: 10}); err == nil {
+		t.Error("Transactions on a closed investment should fail")
+	}
+
+	if err := investment.Reopen(&InvestmentTransaction{ID: uuid.New(), Amount: 1300}); err == nil {
+		t.Error("Reopening with a different transaction should fail")
+	}
+	if err := investment.Reopen(tx); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	if investment.IsClosed() || investment.GetCurrentValue() != 1300 || investment.RealizedGain != nil {
+		t.Errorf("Unexpected reopened investment: %+v", investment)
+	}
+}
+
+func TestSummarizeInvestmentsExcludesClosed(t *testing.T) {
+	activeValue := 1100.0
+	gain := 250.0
+	zero := 0.0
+	investments := []Investment{
+		{ID: uuid.New(), Amount: 1000, CurrentValue: &activeValue, Status: InvestmentStatusActive},
+		{ID: uuid.New(), Amount: 2000, CurrentValue: &zero, RealizedGain: &gain, Status: InvestmentStatusClosed},
+	}
+
+	summary := SummarizeInvestments(investments)
+	if summary.TotalInvested != 1000 || summary.TotalCurrentValue != 1100 || summary.TotalGain != 100 {
+		t.Errorf("Closed positions should be excluded from current totals: %+v", summary)
+	}
+	if summary.TotalRealizedGain != 250 || summary.ClosedCount != 1 {
+		t.Errorf("Expected realized gain 250 from one closed position, got %+v", summary)
+	}
+	if len(summary.ByStatus) != 2 || summary.ByStatus[1].Gain != 250 {
+		t.Errorf("Closed positions should appear in the status breakdown: %+v", summary.ByStatus)
+	}
+	if len(summary.ByType) != 1 {
+		t.Errorf("Closed positions should not appear in the type breakdown: %+v", summary.ByType)
+	}
+}
+
+func TestInvestmentUpdateRequestEndDate(t *testing.T) {
+	existing := time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantChange bool
+		wantEnd    *time.Time
+	}{
+		{"absent keeps end date", `{"name": "Renamed"}`, false, &existing},
+		{"null clears end date", `{"end_date": null}`, true, nil},
+		{"value replaces end date", `{"end_date": "2027-12-31T00:00:00Z"}`, true, ptrTime(time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req InvestmentUpdateRequest
+			if err := utils.DecodeJSON(strings.NewReader(tt.body), &req); err != nil {
+				t.Fatalf("DecodeJSON() error = %v", err)
+			}
+
+			value, changed := req.Changes()["end_date"]
+			if changed != tt.wantChange {
+				t.Fatalf("end_date changed = %v, want %v", changed, tt.wantChange)
+			}
+			if changed && (value == nil) != (tt.wantEnd == nil) {
+				t.Errorf("end_date change = %v, want %v", value, tt.wantEnd)
+			}
+
+			end := existing
+			investment := Investment{Name: "Bond", EndDate: &end}
+			req.Apply(&investment)
+			switch {
+			case tt.wantEnd == nil && investment.EndDate != nil:
+				t.Errorf("EndDate = %v, want nil", investment.EndDate)
+			case tt.wantEnd != nil && (investment.EndDate == nil || !investment.EndDate.Equal(*tt.wantEnd)):
+				t.Errorf("EndDate = %v, want %v", investment.EndDate, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func TestSummarizeInvestmentsGroupsByCanonicalInstitution(t *testing.T) {
+	hdfc := &Institution{ID: uuid.New(), Name: "HDFC Bank"}
+	short, long, other := "HDFC", "hdfc bank ltd", "Local Co-op"
+	investments := []Investment{
+		{ID: uuid.New(), Amount: 100, Institution: &short, InstitutionID: &hdfc.ID, CanonicalInstitution: hdfc, Status: InvestmentStatusActive},
+		{ID: uuid.New(), Amount: 200, Institution: &long, InstitutionID: &hdfc.ID, CanonicalInstitution: hdfc, Status: InvestmentStatusActive},
+		{ID: uuid.New(), Amount: 50, Institution: &other, Status: InvestmentStatusActive},
+	}
+
+	summary := SummarizeInvestments(investments)
+	if len(summary.ByInstitution) != 2 {
+		t.Fatalf("ByInstitution = %+v, want 2 groups", summary.ByInstitution)
+	}
+	mapped := summary.ByInstitution[0]
+	if mapped.Institution != "HDFC Bank" || mapped.InstitutionID == nil || mapped.Count != 2 || mapped.InvestedAmount != 300 {
+		t.Errorf("canonical group = %+v", mapped)
+	}
+	if raw := summary.ByInstitution[1]; raw.Institution != other || raw.InstitutionID != nil {
+		t.Errorf("unmatched group should fall back to the raw text: %+v", raw)
+	}
+}
+
+func TestInvestmentAccountNumberIsMasked(t *testing.T) {
+	accountNumber := "GB82WEST12345698765432"
+	investment := Investment{ID: uuid.New(), Name: "Savings", AccountNumber: &accountNumber}
+	investment.MaskAccountNumber()
+
+	data, err := json.Marshal(investment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), accountNumber) || strings.Contains(string(data), `"account_number"`) {
+		t.Errorf("Raw account number written to JSON: %s", data)
+	}
+	if !strings.Contains(string(data), `"masked_account_number":"****5432"`) {
+		t.Errorf("Expected the masked account number in JSON, got %s", data)
+	}
+
+	// Changing or clearing the account number updates the mask
+	(&InvestmentUpdateRequest{AccountNumber: utils.Some("12345678")}).Apply(&investment)
+	if investment.MaskedAccountNumber == nil || *investment.MaskedAccountNumber != "****5678" {
+		t.Errorf("Expected ****5678, got %v", investment.MaskedAccountNumber)
+	}
+	(&InvestmentUpdateRequest{AccountNumber: utils.Null[string]()}).Apply(&investment)
+	if investment.MaskedAccountNumber != nil {
+		t.Errorf("Expected no masked account number, got %q", *investment.MaskedAccountNumber)
+	}
+
+	spaced := "gb82 west 1234 5698 7654 32"
+	create := InvestmentCreateRequest{TypeID: uuid.New(), Name: "Savings", Amount: 100, StartDate: time.Now().AddDate(0, -1, 0),
+		AccountNumber: &spaced}
+	if errs := create.Validate(); errs != nil || *create.AccountNumber != accountNumber {
+		t.Errorf("Expected a normalized account number, got %q, %v", *create.AccountNumber, errs)
+	}
+}
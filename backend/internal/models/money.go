@@ -0,0 +1,226 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in cents. Sums of Money are exact, unlike sums of
+// float64 amounts, which drift as they accumulate. It is written to JSON as
+// a decimal string such as "30.00" and read from either a string or a number.
+type Money int64
+
+// moneyDecimals is the number of decimal places Money holds
+const moneyDecimals = 2
+
+// maxMoneyDigits bounds the integer digits of a parsed amount so the cents
+// fit in an int64
+const maxMoneyDigits = 16
+
+// MoneyFromFloat converts amount to Money, rounding half away from zero to
+// the cent. The shortest decimal form of amount is rounded, so 1.005 becomes
+// 1.01 even though its float64 value is slightly below it. NaN and
+// infinities become zero.
+func MoneyFromFloat(amount float64) Money {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0
+	}
+	m, err := parseMoney(strconv.FormatFloat(amount, 'f', -1, 64), true)
+	if err != nil {
+		return Money(math.Round(amount * 100))
+	}
+	return m
+}
+
+// MoneyFromCents returns the Money holding cents
+func MoneyFromCents(cents int64) Money {
+	return Money(cents)
+}
+
+// ParseMoney parses a decimal amount such as "12.50" or "-3". Amounts with
+// more than two decimal places are rejected rather than rounded.
+func ParseMoney(s string) (Money, error) {
+	return parseMoney(s, false)
+}
+
+// parseMoney parses a decimal amount, rounding extra decimal places half
+// away from zero when round is set. Exponents are accepted so JSON numbers
+// such as 1e3 parse.
+func parseMoney(s string, round bool) (Money, error) {
+	text := strings.TrimSpace(s)
+	if strings.ContainsAny(text, "eE") {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		text = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > maxMoneyDigits {
+		return 0, fmt.Errorf("amount %q is too large", s)
+	}
+
+	roundUp := false
+	if len(fraction) > moneyDecimals {
+		if !round {
+			return 0, fmt.Errorf("amount %q has more than %d decimal places", s, moneyDecimals)
+		}
+		roundUp = fraction[moneyDecimals] >= '5'
+		fraction = fraction[:moneyDecimals]
+	}
+	fraction += strings.Repeat("0", moneyDecimals-len(fraction))
+
+	cents, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if roundUp {
+		cents++
+	}
+	if negative {
+		cents = -cents
+	}
+	return Money(cents), nil
+}
+
+// isDigits returns true if s holds only ASCII digits
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Cents returns the amount in cents
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// Float returns the amount as a float64 for the float fields it is
+// replacing
+func (m Money) Float() float64 {
+	return float64(m) / 100
+}
+
+// Add returns m plus other
+func (m Money) Add(other Money) Money {
+	return m + other
+}
+
+// Sub returns m minus other
+func (m Money) Sub(other Money) Money {
+	return m - other
+}
+
+// MulRate returns m multiplied by rate, such as an exchange or interest
+// rate, rounded half away from zero to the cent
+func (m Money) MulRate(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// Round returns m rounded half away from zero to decimals places, such as
+// whole units for a zero decimals currency. Two or more decimals leave m
+// unchanged.
+func (m Money) Round(decimals int) Money {
+	if decimals >= moneyDecimals {
+		return m
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	unit := Money(math.Pow10(moneyDecimals - decimals))
+	remainder := m % unit
+	m -= remainder
+	switch {
+	case remainder*2 >= unit:
+		m += unit
+	case remainder*2 <= -unit:
+		m -= unit
+	}
+	return m
+}
+
+// String returns the amount with two decimal places, such as "-3.05"
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// MarshalJSON writes the amount as a decimal string
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON reads an amount written as a string or a number. Amounts
+// with more than two decimal places are rejected.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return fmt.Errorf("invalid amount %s", text)
+		}
+		text = unquoted
+	}
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as a decimal string for NUMERIC columns
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a NUMERIC, integer or float column. Values with more than two
+// decimal places, such as averages, are rounded to the cent.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanText(string(v))
+	case string:
+		return m.scanText(v)
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		*m = MoneyFromFloat(v)
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into Money", src)
+}
+
+func (m *Money) scanText(text string) error {
+	parsed, err := parseMoney(text, true)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
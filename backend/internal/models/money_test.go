@@ -0,0 +1,221 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMoneySumIsExact(t *testing.T) {
+	var money Money
+	var float float64
+	for i := 0; i < 300; i++ {
+		money = money.Add(MoneyFromFloat(0.1))
+		float += 0.1
+	}
+
+	if money != MoneyFromCents(3000) || money.String() != "30.00" {
+		t.Errorf("Money sum = %s, want 30.00", money)
+	}
+	if float == 30 {
+		t.Errorf("Expected the float sum to drift from 30, got exactly %v", float)
+	}
+}
+
+func TestSummaryMoneyTotals(t *testing.T) {
+	expenses := make([]Expense, 300)
+	for i := range expenses {
+		expenses[i].Amount = 0.1
+		expenses[i].MyShare = 0.1
+	}
+	summary := SummarizeExpenses(expenses, time.UTC)
+	if summary.TotalAmountMoney.String() != "30.00" || summary.TotalAmount != 30 {
+		t.Errorf("Expense totals = %s, %v", summary.TotalAmountMoney, summary.TotalAmount)
+	}
+
+	summary = SummarizeWithAggregates(expenses, []ExpenseAggregate{{Amount: 0.2}, {Amount: 0.1}}, time.UTC)
+	if summary.TotalAmountMoney.String() != "30.30" {
+		t.Errorf("Totals with aggregates = %s", summary.TotalAmountMoney)
+	}
+
+	realized := 0.3
+	investments := []Investment{
+		{Amount: 100.1, Status: InvestmentStatusActive},
+		{Amount: 200.2, Status: InvestmentStatusActive},
+		{Amount: 50, Status: InvestmentStatusClosed, RealizedGain: &realized},
+	}
+	investmentSummary := SummarizeInvestments(investments)
+	if investmentSummary.TotalInvestedMoney.String() != "300.30" || investmentSummary.TotalRealizedGainMoney.String() != "0.30" ||
+		investmentSummary.TotalGainMoney != investmentSummary.TotalCurrentValueMoney.Sub(investmentSummary.TotalInvestedMoney) {
+		t.Errorf("Investment totals = %+v", investmentSummary)
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   Money
+	}{
+		{0, 0},
+		{12.5, 1250},
+		{0.1, 10},
+		{1.005, 101},
+		{1.004999, 100},
+		{-1.005, -101},
+		{-0.004, 0},
+		{10.239999999, 1024},
+		{999999999.99, 99999999999},
+		{math.NaN(), 0},
+		{math.Inf(1), 0},
+	}
+	for _, tt := range tests {
+		if got := MoneyFromFloat(tt.amount); got != tt.want {
+			t.Errorf("MoneyFromFloat(%v) = %d, want %d", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Money
+		wantErr bool
+	}{
+		{"12.50", 1250, false},
+		{"12.5", 1250, false},
+		{"12", 1200, false},
+		{"-3.05", -305, false},
+		{"+3", 300, false},
+		{".5", 50, false},
+		{" 7.25 ", 725, false},
+		{"1e3", 100000, false},
+		{"007.10", 710, false},
+		{"10.239", 0, true},
+		{"", 0, true},
+		{".", 0, true},
+		{"-", 0, true},
+		{"1.2.3", 0, true},
+		{"12,50", 0, true},
+		{"abc", 0, true},
+		{"12345678901234567", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.text)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d, error %v", tt.text, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a, b := MoneyFromCents(1050), MoneyFromCents(325)
+	if got := a.Add(b); got != 1375 {
+		t.Errorf("Add = %s", got)
+	}
+	if got := b.Sub(a); got.String() != "-7.25" {
+		t.Errorf("Sub = %s", got)
+	}
+	if got := MoneyFromCents(1000).MulRate(0.0333); got != 33 {
+		t.Errorf("MulRate = %s, want 0.33", got)
+	}
+	if got := MoneyFromCents(1000).MulRate(1.0825); got.String() != "10.83" {
+		t.Errorf("MulRate = %s, want 10.83", got)
+	}
+	if got := MoneyFromCents(-1000).MulRate(1.0825); got.String() != "-10.83" {
+		t.Errorf("MulRate = %s, want -10.83", got)
+	}
+	if got := a.Float(); got != 10.5 {
+		t.Errorf("Float = %v", got)
+	}
+
+	rounding := []struct {
+		amount   Money
+		decimals int
+		want     Money
+	}{
+		{1250, 0, 1300},
+		{1249, 0, 1200},
+		{-1250, 0, -1300},
+		{-1249, 0, -1200},
+		{1245, 1, 1250},
+		{1244, 1, 1240},
+		{1244, 2, 1244},
+		{1250, -1, 1300},
+	}
+	for _, tt := range rounding {
+		if got := tt.amount.Round(tt.decimals); got != tt.want {
+			t.Errorf("%s.Round(%d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Total Money `json:"total"`
+		Debt  Money `json:"debt"`
+	}{MoneyFromCents(3000), MoneyFromCents(-5)})
+	if err != nil || string(data) != `{"total":"30.00","debt":"-0.05"}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+
+	tests := []struct {
+		json    string
+		want    Money
+		wantErr bool
+	}{
+		{`"30.00"`, 3000, false},
+		{`30`, 3000, false},
+		{`12.34`, 1234, false},
+		{`-0.5`, -50, false},
+		{`"-0.5"`, -50, false},
+		{`1.5e2`, 15000, false},
+		{`null`, 0, false},
+		{`12.345`, 0, true},
+		{`"12.345"`, 0, true},
+		{`"twelve"`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.json), &got)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d, error %v", tt.json, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneySQL(t *testing.T) {
+	value, err := MoneyFromCents(-1234).Value()
+	if err != nil || value != "-12.34" {
+		t.Errorf("Value = %v, %v", value, err)
+	}
+
+	tests := []struct {
+		src     any
+		want    Money
+		wantErr bool
+	}{
+		{[]byte("12.50"), 1250, false},
+		{"7.00", 700, false},
+		{[]byte("33.3333333333"), 3333, false},
+		{int64(42), 4200, false},
+		{19.99, 1999, false},
+		{nil, 0, false},
+		{[]byte("NaN"), 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		got := MoneyFromCents(1)
+		err := got.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%v) expected an error", tt.src)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Scan(%v) = %d, %v, want %d", tt.src, got, err, tt.want)
+		}
+	}
+}
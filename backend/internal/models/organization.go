@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization membership roles
+const (
+	MembershipRoleAdvisor = "advisor"
+	MembershipRoleClient  = "client"
+)
+
+// Organization represents an advisory firm whose advisors manage client accounts
+type Organization struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedBy uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// OrganizationMembership links a user to an organization as an advisor or client
+type OrganizationMembership struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	Role           string    `json:"role" db:"role"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// Consent is a client's grant allowing an advisor to act on their account
+type Consent struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	ClientUserID   uuid.UUID  `json:"client_user_id" db:"client_user_id"`
+	AdvisorUserID  uuid.UUID  `json:"advisor_user_id" db:"advisor_user_id"`
+	GrantedAt      time.Time  `json:"granted_at" db:"granted_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// OrganizationCreateRequest represents the request to create an organization
+type OrganizationCreateRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// MembershipCreateRequest represents the request to add a member to an organization
+type MembershipCreateRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Role   string    `json:"role" validate:"required,oneof=advisor client"`
+}
+
+// ConsentGrantRequest represents a client's request to grant an advisor access
+type ConsentGrantRequest struct {
+	OrganizationID uuid.UUID  `json:"organization_id" validate:"required"`
+	AdvisorUserID  uuid.UUID  `json:"advisor_user_id" validate:"required"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// IsActive returns true if the consent is neither revoked nor expired at the given time
+func (c *Consent) IsActive(at time.Time) bool {
+	if c.RevokedAt != nil {
+		return false
+	}
+	return c.ExpiresAt == nil || at.Before(*c.ExpiresAt)
+}
@@ -0,0 +1,87 @@
+package models
+
+import "tgfinance/pkg/utils"
+
+// setPointer records a change for a pointer field that was provided
+func setPointer[T any](changes map[string]any, column string, value *T) {
+	if value != nil {
+		changes[column] = *value
+	}
+}
+
+// setOptional records a change for an optional field that was provided, with
+// explicit nulls recorded as nil so the column is cleared
+func setOptional[T any](changes map[string]any, column string, value utils.Optional[T]) {
+	if value.IsSet() {
+		changes[column] = value.Interface()
+	}
+}
+
+// Changes returns the columns to update for the request, keyed by column name.
+// A nil value means the column should be set to NULL.
+func (r *ExpenseUpdateRequest) Changes() map[string]any {
+	changes := make(map[string]any)
+	setPointer(changes, "category_id", r.CategoryID)
+	setPointer(changes, "amount", r.Amount)
+	setPointer(changes, "description", r.Description)
+	setPointer(changes, "expense_date", r.ExpenseDate)
+	setOptional(changes, "payment_method", r.PaymentMethod)
+	setOptional(changes, "location", r.Location)
+	setOptional(changes, "receipt_url", r.ReceiptURL)
+	return changes
+}
+
+// Changes returns the columns to update for the request, keyed by column name.
+// A nil value means the column should be set to NULL.
+func (r *InvestmentUpdateRequest) Changes() map[string]any {
+	changes := make(map[string]any)
+	setPointer(changes, "name", r.Name)
+	setOptional(changes, "symbol", r.Symbol)
+	setPointer(changes, "amount", r.Amount)
+	setOptional(changes, "current_value", r.CurrentValue)
+	setOptional(changes, "end_date", r.EndDate)
+	setOptional(changes, "interest_rate", r.InterestRate)
+	setOptional(changes, "expense_ratio", r.ExpenseRatio)
+	setOptional(changes, "institution", r.Institution)
+	setOptional(changes, "account_number", r.AccountNumber)
+	setOptional(changes, "notes", r.Notes)
+	setPointer(changes, "status", r.Status)
+	return changes
+}
+
+// Apply applies the request to an investment: absent fields are left
+// unchanged and explicit nulls clear the field
+func (r *InvestmentUpdateRequest) Apply(investment *Investment) {
+	if r.Name != nil {
+		investment.Name = *r.Name
+	}
+	r.Symbol.Apply(&investment.Symbol)
+	if r.Amount != nil {
+		investment.Amount = *r.Amount
+	}
+	r.CurrentValue.Apply(&investment.CurrentValue)
+	r.EndDate.Apply(&investment.EndDate)
+	r.InterestRate.Apply(&investment.InterestRate)
+	r.ExpenseRatio.Apply(&investment.ExpenseRatio)
+	r.Institution.Apply(&investment.Institution)
+	r.AccountNumber.Apply(&investment.AccountNumber)
+	investment.MaskAccountNumber()
+	r.Notes.Apply(&investment.Notes)
+	if r.Status != nil {
+		investment.Status = *r.Status
+	}
+}
+
+// Changes returns the columns to update for the request, keyed by column name.
+// A nil value means the column should be set to NULL.
+func (r *GoalUpdateRequest) Changes() map[string]any {
+	changes := make(map[string]any)
+	setPointer(changes, "name", r.Name)
+	setOptional(changes, "description", r.Description)
+	setPointer(changes, "target_amount", r.TargetAmount)
+	setOptional(changes, "target_date", r.TargetDate)
+	setPointer(changes, "goal_type", r.GoalType)
+	setPointer(changes, "priority", r.Priority)
+	setPointer(changes, "status", r.Status)
+	return changes
+}
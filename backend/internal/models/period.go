@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Period close statuses
+const (
+	PeriodStatusLocked   = "locked"
+	PeriodStatusReopened = "reopened"
+)
+
+// AuditActionPeriodReopen is recorded when a user reopens a closed period
+const AuditActionPeriodReopen = "period_reopen"
+
+// PeriodClose records a user's close of a month. Period is formatted YYYY-MM.
+type PeriodClose struct {
+	ID           uuid.UUID       `json:"id" db:"id"`
+	UserID       uuid.UUID       `json:"user_id" db:"user_id"`
+	Period       string          `json:"period" db:"period"`
+	Status       string          `json:"status" db:"status"`
+	Snapshot     *ExpenseSummary `json:"snapshot,omitempty" db:"snapshot"`
+	ClosedAt     time.Time       `json:"closed_at" db:"closed_at"`
+	ReopenedAt   *time.Time      `json:"reopened_at,omitempty" db:"reopened_at"`
+	ReopenedBy   *uuid.UUID      `json:"reopened_by,omitempty" db:"reopened_by"`
+	ReopenReason *string         `json:"reopen_reason,omitempty" db:"reopen_reason"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// IsLocked returns true if writes dated inside the period are rejected
+func (p *PeriodClose) IsLocked() bool {
+	return p.Status == PeriodStatusLocked
+}
+
+// PeriodAdjustment is a change made to a closed period with the adjustment
+// flag, kept so the period's report can show what moved since the close
+type PeriodAdjustment struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	UserID    uuid.UUID       `json:"user_id" db:"user_id"`
+	Period    string          `json:"period" db:"period"`
+	ExpenseID uuid.UUID       `json:"expense_id" db:"expense_id"`
+	Action    string          `json:"action" db:"action"`
+	OldValue  json.RawMessage `json:"old_value,omitempty" db:"old_value"`
+	NewValue  json.RawMessage `json:"new_value,omitempty" db:"new_value"`
+	Reason    string          `json:"reason" db:"reason"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// PeriodReopenRequest represents the request to reopen a closed period.
+// Confirm must repeat the period being reopened.
+type PeriodReopenRequest struct {
+	Confirm string `json:"confirm"`
+	Reason  string `json:"reason"`
+}
+
+// PeriodSummary is a month's expense summary, read from the close snapshot
+// when the period is locked
+type PeriodSummary struct {
+	Period      string         `json:"period"`
+	Closed      bool           `json:"closed"`
+	ClosedAt    *time.Time     `json:"closed_at,omitempty"`
+	Summary     ExpenseSummary `json:"summary"`
+	Adjustments int            `json:"adjustments"`
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/ocr"
+)
+
+// OCR statuses for receipt attachments
+const (
+	OCRStatusPending    = "pending"
+	OCRStatusProcessing = "processing"
+	OCRStatusCompleted  = "completed"
+	OCRStatusFailed     = "ocr_failed"
+	OCRStatusSkipped    = "skipped"
+)
+
+// ReceiptAttachment represents an uploaded receipt file and its OCR state
+type ReceiptAttachment struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	UserID      uuid.UUID       `json:"user_id" db:"user_id"`
+	ExpenseID   *uuid.UUID      `json:"expense_id,omitempty" db:"expense_id"`
+	StorageKey  string          `json:"-" db:"storage_key"`
+	FileName    string          `json:"file_name" db:"file_name"`
+	ContentType string          `json:"content_type" db:"content_type"`
+	SizeBytes   int64           `json:"size_bytes" db:"size_bytes"`
+	OCRStatus   string          `json:"ocr_status" db:"ocr_status"`
+	OCRResult   json.RawMessage `json:"ocr_result,omitempty" db:"ocr_result"`
+	OCRError    *string         `json:"ocr_error,omitempty" db:"ocr_error"`
+	OCRAttempts int             `json:"ocr_attempts" db:"ocr_attempts"`
+	ProcessedAt *time.Time      `json:"processed_at,omitempty" db:"processed_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// ExpenseDraft represents an expense being prepared from a receipt before the
+// user confirms it. Nil fields have not been entered by the user.
+type ExpenseDraft struct {
+	AttachmentID *uuid.UUID            `json:"attachment_id,omitempty"`
+	Amount       *float64              `json:"amount,omitempty"`
+	Description  *string               `json:"description,omitempty"`
+	ExpenseDate  *time.Time            `json:"expense_date,omitempty"`
+	Currency     *string               `json:"currency,omitempty"`
+	OCRStatus    string                `json:"ocr_status,omitempty"`
+	Extracted    *ocr.ExtractedReceipt `json:"extracted,omitempty"`
+	Prefilled    []string              `json:"prefilled,omitempty"`
+}
+
+// NewReceiptAttachment creates an attachment awaiting OCR. Files OCR cannot read
+// are marked skipped so the upload still succeeds.
+func NewReceiptAttachment(userID uuid.UUID, storageKey, fileName, contentType string, size int64) *ReceiptAttachment {
+	status := OCRStatusPending
+	if !ocr.IsSupportedContentType(contentType) {
+		status = OCRStatusSkipped
+	}
+
+	return &ReceiptAttachment{
+		ID:          uuid.New(),
+		UserID:      userID,
+		StorageKey:  storageKey,
+		FileName:    fileName,
+		ContentType: contentType,
+		SizeBytes:   size,
+		OCRStatus:   status,
+	}
+}
+
+// MarkProcessing records the start of an OCR attempt
+func (a *ReceiptAttachment) MarkProcessing() error {
+	if a.OCRStatus != OCRStatusPending {
+		return fmt.Errorf("attachment is %s, not pending", a.OCRStatus)
+	}
+	a.OCRStatus = OCRStatusProcessing
+	a.OCRAttempts++
+	return nil
+}
+
+// RecordExtraction stores the outcome of an OCR attempt
+func (a *ReceiptAttachment) RecordExtraction(receipt ocr.ExtractedReceipt, extractErr error, at time.Time) error {
+	a.ProcessedAt = &at
+
+	if extractErr != nil {
+		message := extractErr.Error()
+		a.OCRStatus = OCRStatusFailed
+		a.OCRError = &message
+		a.OCRResult = nil
+		return nil
+	}
+
+	result, err := json.Marshal(receipt)
+	if err != nil {
+		return fmt.Errorf("failed to encode OCR result: %w", err)
+	}
+
+	a.OCRStatus = OCRStatusCompleted
+	a.OCRResult = result
+	a.OCRError = nil
+	return nil
+}
+
+// Requeue resets a failed attachment so OCR runs again
+func (a *ReceiptAttachment) Requeue() error {
+	if a.OCRStatus != OCRStatusFailed {
+		return fmt.Errorf("only attachments with status %s can be requeued", OCRStatusFailed)
+	}
+	a.OCRStatus = OCRStatusPending
+	a.OCRError = nil
+	return nil
+}
+
+// Extraction returns the stored OCR result, if any
+func (a *ReceiptAttachment) Extraction() (*ocr.ExtractedReceipt, error) {
+	if a.OCRStatus != OCRStatusCompleted || len(a.OCRResult) == 0 {
+		return nil, nil
+	}
+
+	var receipt ocr.ExtractedReceipt
+	if err := json.Unmarshal(a.OCRResult, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to decode OCR result: %w", err)
+	}
+	return &receipt, nil
+}
+
+// ApplyReceipt pre-fills the draft from an OCR extraction. Fields the user has
+// not entered are always filled; user-entered values are only replaced when
+// the extraction confidence reaches minConfidence. Prefilled lists the fields
+// that came from OCR so the client can ask the user to confirm them.
+func (d *ExpenseDraft) ApplyReceipt(receipt ocr.ExtractedReceipt, minConfidence float64) {
+	d.Extracted = &receipt
+	overwrite := receipt.Confidence >= minConfidence
+
+	if receipt.Total != nil && *receipt.Total > 0 && (d.Amount == nil || overwrite) {
+		total := *receipt.Total
+		d.Amount = &total
+		d.Prefilled = append(d.Prefilled, "amount")
+	}
+	if receipt.Date != nil && (d.ExpenseDate == nil || overwrite) {
+		date := *receipt.Date
+		d.ExpenseDate = &date
+		d.Prefilled = append(d.Prefilled, "expense_date")
+	}
+	if receipt.Merchant != "" && (d.Description == nil || overwrite) {
+		merchant := receipt.Merchant
+		d.Description = &merchant
+		d.Prefilled = append(d.Prefilled, "description")
+	}
+	if receipt.Currency != "" && (d.Currency == nil || overwrite) {
+		currency := receipt.Currency
+		d.Currency = &currency
+		d.Prefilled = append(d.Prefilled, "currency")
+	}
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/ocr"
+)
+
+func TestExpenseDraftApplyReceipt(t *testing.T) {
+	total := 42.5
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	receipt := ocr.ExtractedReceipt{Merchant: "Shell", Total: &total, Date: &date, Currency: "USD"}
+
+	t.Run("low confidence fills only empty fields", func(t *testing.T) {
+		userAmount := 40.0
+		draft := ExpenseDraft{Amount: &userAmount}
+		low := receipt
+		low.Confidence = 0.4
+
+		draft.ApplyReceipt(low, 0.8)
+
+		if *draft.Amount != 40 {
+			t.Errorf("User-entered amount was overwritten: %v", *draft.Amount)
+		}
+		if draft.ExpenseDate == nil || !draft.ExpenseDate.Equal(date) {
+			t.Errorf("Empty date should be prefilled, got %v", draft.ExpenseDate)
+		}
+		if draft.Description == nil || *draft.Description != "Shell" {
+			t.Errorf("Empty description should be prefilled, got %v", draft.Description)
+		}
+		for _, field := range draft.Prefilled {
+			if field == "amount" {
+				t.Error("Amount should not be reported as prefilled")
+			}
+		}
+	})
+
+	t.Run("high confidence overwrites", func(t *testing.T) {
+		userAmount := 40.0
+		draft := ExpenseDraft{Amount: &userAmount}
+		high := receipt
+		high.Confidence = 0.95
+
+		draft.ApplyReceipt(high, 0.8)
+
+		if *draft.Amount != 42.5 {
+			t.Errorf("Expected amount 42.5, got %v", *draft.Amount)
+		}
+		if len(draft.Prefilled) != 4 {
+			t.Errorf("Expected 4 prefilled fields, got %v", draft.Prefilled)
+		}
+	})
+}
+
+func TestReceiptAttachmentOCRLifecycle(t *testing.T) {
+	attachment := NewReceiptAttachment(uuid.New(), "receipts/a.jpg", "a.jpg", "image/jpeg", 1024)
+	if attachment.OCRStatus != OCRStatusPending {
+		t.Fatalf("Expected pending status, got %s", attachment.OCRStatus)
+	}
+
+	if err := attachment.MarkProcessing(); err != nil {
+		t.Fatalf("MarkProcessing() error = %v", err)
+	}
+	if err := attachment.RecordExtraction(ocr.ExtractedReceipt{}, errors.New("vision unavailable"), time.Now()); err != nil {
+		t.Fatalf("RecordExtraction() error = %v", err)
+	}
+	if attachment.OCRStatus != OCRStatusFailed || attachment.OCRError == nil {
+		t.Fatalf("Expected ocr_failed with error, got %+v", attachment)
+	}
+	if err := attachment.MarkProcessing(); err == nil {
+		t.Error("Failed attachments should not be processed without a requeue")
+	}
+
+	if err := attachment.Requeue(); err != nil {
+		t.Fatalf("Requeue() error = %v", err)
+	}
+	if err := attachment.MarkProcessing(); err != nil {
+		t.Fatalf("MarkProcessing() after requeue error = %v", err)
+	}
+
+	total := 12.0
+	if err := attachment.RecordExtraction(ocr.ExtractedReceipt{Merchant: "Cafe", Total: &total, Confidence: 0.9}, nil, time.Now()); err != nil {
+		t.Fatalf("RecordExtraction() error = %v", err)
+	}
+	if attachment.OCRStatus != OCRStatusCompleted || attachment.OCRAttempts != 2 {
+		t.Errorf("Expected completed after 2 attempts, got %s after %d", attachment.OCRStatus, attachment.OCRAttempts)
+	}
+
+	extracted, err := attachment.Extraction()
+	if err != nil || extracted == nil || extracted.Merchant != "Cafe" {
+		t.Errorf("Unexpected stored extraction %+v, err %v", extracted, err)
+	}
+	if err := attachment.Requeue(); err == nil {
+		t.Error("Completed attachments should not be requeued")
+	}
+
+	unsupported := NewReceiptAttachment(uuid.New(), "receipts/a.txt", "a.txt", "text/plain", 10)
+	if unsupported.OCRStatus != OCRStatusSkipped {
+		t.Errorf("Unsupported files should skip OCR, got %s", unsupported.OCRStatus)
+	}
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/finance"
+)
+
+// Entities with a user-configurable retention window
+const (
+	RetentionExpenses      = "expenses"
+	RetentionLoginHistory  = "login_history"
+	RetentionNotifications = "notifications"
+)
+
+// AuditActionRetentionPurge records a summary of data removed by retention
+const AuditActionRetentionPurge = "retention_purge"
+
+// RetentionSettings holds a user's retention windows; a nil window keeps the
+// data forever
+type RetentionSettings struct {
+	UserID             uuid.UUID `json:"user_id" db:"user_id"`
+	ExpenseYears       *int      `json:"expense_years" db:"expense_years"`
+	LoginHistoryMonths *int      `json:"login_history_months" db:"login_history_months"`
+	NotificationDays   *int      `json:"notification_days" db:"notification_days"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RetentionSettingsRequest replaces a user's retention windows; omitted or
+// null windows keep the data forever
+type RetentionSettingsRequest struct {
+	ExpenseYears       *int `json:"expense_years"`
+	LoginHistoryMonths *int `json:"login_history_months"`
+	NotificationDays   *int `json:"notification_days"`
+}
+
+// RetentionPreviewEntry reports what a retention window would remove
+type RetentionPreviewEntry struct {
+	Entity string     `json:"entity"`
+	Cutoff *time.Time `json:"cutoff,omitempty"`
+	Count  int        `json:"count"`
+	Oldest *time.Time `json:"oldest,omitempty"`
+}
+
+// RetentionPurgeResult reports what a retention run removed for one entity
+type RetentionPurgeResult struct {
+	Entity  string    `json:"entity"`
+	Cutoff  time.Time `json:"cutoff"`
+	Purged  int       `json:"purged"`
+	Batches int       `json:"batches"`
+}
+
+// ExpenseAggregate is the permanent monthly total of purged expenses in a
+// category and payment method, counted by the user's share
+type ExpenseAggregate struct {
+	UserID        uuid.UUID `json:"user_id" db:"user_id"`
+	Month         time.Time `json:"month" db:"month"`
+	CategoryID    uuid.UUID `json:"category_id" db:"category_id"`
+	PaymentMethod string    `json:"payment_method" db:"payment_method"`
+	Amount        float64   `json:"amount" db:"amount"`
+	Count         int       `json:"count" db:"expense_count"`
+}
+
+// IsEmpty returns true if no retention window is set
+func (s *RetentionSettings) IsEmpty() bool {
+	return s.ExpenseYears == nil && s.LoginHistoryMonths == nil && s.NotificationDays == nil
+}
+
+// SummarizeWithAggregates builds summary statistics like SummarizeExpenses,
+// also counting the monthly aggregates of expenses removed by retention so
+// long-term reports keep their totals. Aggregates are already grouped by
+// month, so only the expenses are grouped in loc.
+func SummarizeWithAggregates(expenses []Expense, aggregates []ExpenseAggregate, loc *time.Location) ExpenseSummary {
+	summary := SummarizeExpenses(expenses, loc)
+	if len(aggregates) == 0 {
+		return summary
+	}
+
+	categoryIndex := make(map[uuid.UUID]int)
+	for i, c := range summary.ByCategory {
+		categoryIndex[c.CategoryID] = i
+	}
+	monthIndex := make(map[[2]int]int)
+	for i, m := range summary.ByMonth {
+		monthIndex[[2]int{m.Year, m.Month}] = i
+	}
+	methodIndex := make(map[string]int)
+	for i, p := range summary.ByPaymentMethod {
+		methodIndex[p.PaymentMethod] = i
+	}
+
+	for _, agg := range aggregates {
+		summary.TotalAmount += agg.Amount
+		summary.TotalAmountMoney = summary.TotalAmountMoney.Add(MoneyFromFloat(agg.Amount))
+		summary.TotalCount += agg.Count
+
+		idx, ok := categoryIndex[agg.CategoryID]
+		if !ok {
+			summary.ByCategory = append(summary.ByCategory, CategoryExpenseSummary{CategoryID: agg.CategoryID})
+			idx = len(summary.ByCategory) - 1
+			categoryIndex[agg.CategoryID] = idx
+		}
+		summary.ByCategory[idx].Amount += agg.Amount
+		summary.ByCategory[idx].Count += agg.Count
+
+		monthKey := [2]int{agg.Month.Year(), int(agg.Month.Month())}
+		idx, ok = monthIndex[monthKey]
+		if !ok {
+			summary.ByMonth = append(summary.ByMonth, MonthlyExpenseSummary{Year: monthKey[0], Month: monthKey[1]})
+			idx = len(summary.ByMonth) - 1
+			monthIndex[monthKey] = idx
+		}
+		summary.ByMonth[idx].Amount += agg.Amount
+		summary.ByMonth[idx].Count += agg.Count
+
+		method := "unspecified"
+		if agg.PaymentMethod != "" {
+			method = agg.PaymentMethod
+		}
+		idx, ok = methodIndex[method]
+		if !ok {
+			summary.ByPaymentMethod = append(summary.ByPaymentMethod, PaymentMethodSummary{PaymentMethod: method})
+			idx = len(summary.ByPaymentMethod) - 1
+			methodIndex[method] = idx
+		}
+		summary.ByPaymentMethod[idx].Amount += agg.Amount
+		summary.ByPaymentMethod[idx].Count += agg.Count
+	}
+
+	summary.TotalAmount = finance.RoundCents(summary.TotalAmount)
+	summary.AverageAmount = 0
+	if summary.TotalCount > 0 {
+		summary.AverageAmount = finance.RoundCents(summary.TotalAmount / float64(summary.TotalCount))
+	}
+	for i := range summary.ByCategory {
+		summary.ByCategory[i].Amount = finance.RoundCents(summary.ByCategory[i].Amount)
+		summary.ByCategory[i].Percentage = percentageOf(summary.ByCategory[i].Amount, summary.TotalAmount)
+	}
+	for i := range summary.ByMonth {
+		summary.ByMonth[i].Amount = finance.RoundCents(summary.ByMonth[i].Amount)
+	}
+	for i := range summary.ByPaymentMethod {
+		summary.ByPaymentMethod[i].Amount = finance.RoundCents(summary.ByPaymentMethod[i].Amount)
+		summary.ByPaymentMethod[i].Percentage = percentageOf(summary.ByPaymentMethod[i].Amount, summary.TotalAmount)
+	}
+	return summary
+}
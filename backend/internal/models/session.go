@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrustedDeviceCookie is the cookie carrying a trusted-device token
+const TrustedDeviceCookie = "tgf_trusted_device"
+
+// Session represents a login session on a device
+type Session struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	DeviceName *string    `json:"device_name,omitempty" db:"device_name"`
+	UserAgent  *string    `json:"user_agent,omitempty" db:"user_agent"`
+	IPAddress  *string    `json:"ip_address,omitempty" db:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Trusted devices skip the TOTP step until TrustedUntil
+	TrustedUntil    *time.Time `json:"trusted_until,omitempty" db:"trusted_until"`
+	DeviceTokenHash *string    `json:"-" db:"device_token_hash"`
+}
+
+// SessionUpdateRequest represents the request to update a session
+type SessionUpdateRequest struct {
+	DeviceName *string `json:"device_name,omitempty" validate:"omitempty,max=100"`
+}
+
+// TwoFactorVerifyRequest represents the request to verify a TOTP code,
+// optionally trusting the current device for a number of days
+type TwoFactorVerifyRequest struct {
+	Code            string `json:"code" validate:"required"`
+	TrustDeviceDays int    `json:"trust_device_days,omitempty" validate:"gte=0"`
+}
+
+// IsRevoked returns true if the session has been revoked
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsTrusted returns true if the session is a trusted device at now
+func (s *Session) IsTrusted(now time.Time) bool {
+	return !s.IsRevoked() && s.DeviceTokenHash != nil && s.TrustedUntil != nil && now.Before(*s.TrustedUntil)
+}
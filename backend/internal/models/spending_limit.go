@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Spending limit scopes
+const (
+	SpendingLimitScopeCategory = "category"
+	SpendingLimitScopeMerchant = "merchant"
+)
+
+// Spending limit actions
+const (
+	SpendingLimitActionWarn  = "warn"
+	SpendingLimitActionBlock = "block"
+)
+
+// Spending limit violation kinds
+const (
+	LimitKindPerTransaction = "per_transaction"
+	LimitKindDaily          = "daily"
+)
+
+// ErrCodeSpendingLimitExceeded is returned when a blocking limit rejects an expense
+const ErrCodeSpendingLimitExceeded = "SPENDING_LIMIT_EXCEEDED"
+
+// SpendingLimit caps individual or daily spending for a category or for
+// merchants matching a pattern
+type SpendingLimit struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	UserID            uuid.UUID  `json:"user_id" db:"user_id"`
+	Name              string     `json:"name" db:"name"`
+	Scope             string     `json:"scope" db:"scope"`
+	CategoryID        *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	MerchantPattern   *string    `json:"merchant_pattern,omitempty" db:"merchant_pattern"`
+	PerTransactionMax *float64   `json:"per_transaction_max,omitempty" db:"per_transaction_max"`
+	DailyMax          *float64   `json:"daily_max,omitempty" db:"daily_max"`
+	Action            string     `json:"action" db:"action"`
+	Active            bool       `json:"active" db:"active"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SpendingLimitCreateRequest represents the request to create a spending limit
+type SpendingLimitCreateRequest struct {
+	Name              string     `json:"name" validate:"required"`
+	Scope             string     `json:"scope" validate:"required,oneof=category merchant"`
+	CategoryID        *uuid.UUID `json:"category_id,omitempty"`
+	MerchantPattern   *string    `json:"merchant_pattern,omitempty"`
+	PerTransactionMax *float64   `json:"per_transaction_max,omitempty" validate:"omitempty,gt=0"`
+	DailyMax          *float64   `json:"daily_max,omitempty" validate:"omitempty,gt=0"`
+	Action            string     `json:"action" validate:"required,oneof=warn block"`
+	Active            *bool      `json:"active,omitempty"`
+}
+
+// SpendingLimitUpdateRequest represents the request to update a spending limit
+type SpendingLimitUpdateRequest struct {
+	Name              *string    `json:"name,omitempty"`
+	Scope             *string    `json:"scope,omitempty" validate:"omitempty,oneof=category merchant"`
+	CategoryID        *uuid.UUID `json:"category_id,omitempty"`
+	MerchantPattern   *string    `json:"merchant_pattern,omitempty"`
+	PerTransactionMax *float64   `json:"per_transaction_max,omitempty" validate:"omitempty,gt=0"`
+	DailyMax          *float64   `json:"daily_max,omitempty" validate:"omitempty,gt=0"`
+	Action            *string    `json:"action,omitempty" validate:"omitempty,oneof=warn block"`
+	Active            *bool      `json:"active,omitempty"`
+}
+
+// SpendingLimitViolation describes an expense exceeding a spending limit
+type SpendingLimitViolation struct {
+	LimitID   uuid.UUID `json:"limit_id"`
+	LimitName string    `json:"limit_name"`
+	Action    string    `json:"action"`
+	Kind      string    `json:"kind"`
+	Limit     float64   `json:"limit"`
+	Attempted float64   `json:"attempted"`
+	Message   string    `json:"message"`
+}
+
+// ExpenseCreateResponse represents a created expense with any spending limit warnings
+type ExpenseCreateResponse struct {
+	*Expense
+	Warnings []SpendingLimitViolation `json:"warnings,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// UsageStat is the daily usage of one route
+type UsageStat struct {
+	Date          time.Time `json:"date" db:"date"`
+	Route         string    `json:"route" db:"route"`
+	UniqueUsers   int64     `json:"unique_users" db:"unique_users"`
+	TotalRequests int64     `json:"total_requests" db:"total_requests"`
+}
+
+// UsageFilter represents filters for usage stat queries
+type UsageFilter struct {
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Route     *string   `json:"route,omitempty"`
+}
@@ -1,9 +1,14 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/money"
+	"tgfinance/pkg/utils"
 )
 
 // User represents a user in the system
@@ -19,6 +24,12 @@ type User struct {
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 	IsActive     bool       `json:"is_active" db:"is_active"`
 	LastLogin    *time.Time `json:"last_login,omitempty" db:"last_login"`
+
+	// Preferences for server-side formatting of amounts
+	Locale   string `json:"locale" db:"locale"`
+	Currency string `json:"currency" db:"currency"`
+	// Timezone is the IANA zone that days and months are reported in
+	Timezone string `json:"timezone" db:"timezone"`
 }
 
 // UserCreateRequest represents the request to create a new user
@@ -29,6 +40,35 @@ type UserCreateRequest struct {
 	LastName    string     `json:"last_name" validate:"required"`
 	Phone       *string    `json:"phone,omitempty"`
 	DateOfBirth *time.Time `json:"date_of_birth,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+}
+
+// MinUserAge is the youngest a user may be, checked against date_of_birth
+const MinUserAge = 13
+
+// Validate checks the request's validate tags, email, password strength,
+// names, date of birth, time zone and phone number, which is normalized to
+// E.164 in place. Numbers without a country code are read as numbers of
+// defaultRegion; a blank phone is dropped. A missing time zone defaults to
+// utils.DefaultTimeZone.
+func (r *UserCreateRequest) Validate(defaultRegion string) utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	addFieldError(&errs, utils.ValidateEmail(r.Email))
+	addFieldError(&errs, utils.ValidatePassword(r.Password))
+	addFieldError(&errs, utils.ValidateName(r.FirstName, "first_name"))
+	addFieldError(&errs, utils.ValidateName(r.LastName, "last_name"))
+	if r.DateOfBirth != nil {
+		addFieldError(&errs, utils.ValidateDateOfBirth(*r.DateOfBirth, MinUserAge))
+	}
+	if r.Timezone == "" {
+		r.Timezone = utils.DefaultTimeZone
+	}
+	addFieldError(&errs, utils.ValidateTimeZone(r.Timezone, "timezone"))
+	if r.Phone != nil && strings.TrimSpace(*r.Phone) == "" {
+		r.Phone = nil
+	}
+	addFieldError(&errs, normalizeUserPhone(r.Phone, defaultRegion))
+	return errs
 }
 
 // UserUpdateRequest represents the request to update a user
@@ -37,6 +77,62 @@ type UserUpdateRequest struct {
 	LastName    *string    `json:"last_name,omitempty"`
 	Phone       *string    `json:"phone,omitempty"`
 	DateOfBirth *time.Time `json:"date_of_birth,omitempty"`
+	Locale      *string    `json:"locale,omitempty"`
+	Currency    *string    `json:"currency,omitempty" validate:"omitempty,len=3"`
+	Timezone    *string    `json:"timezone,omitempty"`
+}
+
+// Validate checks that the request changes something and validates the
+// fields it sets. The currency is upper cased and the phone number
+// normalized to E.164 in place; numbers without a country code are read as
+// numbers of defaultRegion and a blank phone is emptied to clear it.
+func (r *UserUpdateRequest) Validate(defaultRegion string) utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	checkNotEmptyUpdate(&errs, r.FirstName != nil || r.LastName != nil || r.Phone != nil ||
+		r.DateOfBirth != nil || r.Locale != nil || r.Currency != nil || r.Timezone != nil)
+	if r.FirstName != nil {
+		addFieldError(&errs, utils.ValidateName(*r.FirstName, "first_name"))
+	}
+	if r.LastName != nil {
+		addFieldError(&errs, utils.ValidateName(*r.LastName, "last_name"))
+	}
+	if r.DateOfBirth != nil {
+		addFieldError(&errs, utils.ValidateDateOfBirth(*r.DateOfBirth, MinUserAge))
+	}
+	if r.Locale != nil {
+		if _, ok := money.LookupLocale(*r.Locale); !ok {
+			addFieldError(&errs, &utils.ValidationError{Field: "locale", Message: "locale is not supported"})
+		}
+	}
+	if r.Timezone != nil {
+		addFieldError(&errs, utils.ValidateTimeZone(*r.Timezone, "timezone"))
+	}
+	if r.Currency != nil {
+		if currency, err := utils.ValidateCurrency(*r.Currency, "currency"); err != nil {
+			addFieldError(&errs, err)
+		} else {
+			*r.Currency = currency
+		}
+	}
+	if r.Phone != nil && strings.TrimSpace(*r.Phone) == "" {
+		*r.Phone = ""
+	} else {
+		addFieldError(&errs, normalizeUserPhone(r.Phone, defaultRegion))
+	}
+	return errs
+}
+
+// normalizeUserPhone replaces a non-nil phone with its E.164 form
+func normalizeUserPhone(phone *string, defaultRegion string) error {
+	if phone == nil {
+		return nil
+	}
+	normalized, err := utils.NormalizePhone(*phone, defaultRegion)
+	if err != nil {
+		return err
+	}
+	*phone = normalized
+	return nil
 }
 
 // UserLoginRequest represents the login request
@@ -45,10 +141,26 @@ type UserLoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// Validate checks that the email is well formed and a password was given.
+// Password strength is not checked so users with older passwords can still
+// log in.
+func (r *UserLoginRequest) Validate() utils.ValidationErrors {
+	errs := utils.ValidateStruct(r)
+	addFieldError(&errs, utils.ValidateEmail(r.Email))
+	return errs
+}
+
 // UserLoginResponse represents the login response
 type UserLoginResponse struct {
-	User  User   `json:"user"`
-	Token string `json:"token"`
+	User   User            `json:"user"`
+	Token  string          `json:"token"`
+	Tokens *auth.TokenPair `json:"tokens,omitempty"`
+}
+
+// NewUserLoginResponse builds a login response from an issued token pair,
+// keeping Token set to the access token for older clients
+func NewUserLoginResponse(user User, tokens *auth.TokenPair) *UserLoginResponse {
+	return &UserLoginResponse{User: user, Token: tokens.AccessToken, Tokens: tokens}
 }
 
 // UserProfile represents the user profile for display
@@ -63,6 +175,14 @@ type UserProfile struct {
 	LastLogin   *time.Time `json:"last_login,omitempty"`
 }
 
+// Location returns the user's time zone, or UTC if it is unset or unknown
+func (u *User) Location() *time.Location {
+	if loc, err := utils.LoadTimeZone(u.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
 // GetFullName returns the full name of the user
 func (u *User) GetFullName() string {
 	return u.FirstName + " " + u.LastName
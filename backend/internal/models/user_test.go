@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserRequestValidatePhone(t *testing.T) {
+	valid := func(phone *string) *UserCreateRequest {
+		return &UserCreateRequest{
+			Email:     "ada@example.com",
+			Password:  "Str0ng!pass",
+			FirstName: "Ada",
+			LastName:  "Lovelace",
+			Phone:     phone,
+		}
+	}
+
+	if errs := valid(nil).Validate("US"); errs != nil {
+		t.Errorf("Expected no phone to be valid, got %v", errs)
+	}
+	for phone, want := range map[string]string{
+		"(415) 555-2671":   "+14155552671",
+		"+44 20 7183 8750": "+442071838750",
+	} {
+		create := valid(stringPtr(phone))
+		if errs := create.Validate("US"); errs != nil || *create.Phone != want {
+			t.Errorf("Validate(%q) = %q, %v, want %q", phone, *create.Phone, errs, want)
+		}
+	}
+	create := valid(stringPtr("020 7183 8750"))
+	if errs := create.Validate("GB"); errs != nil || *create.Phone != "+442071838750" {
+		t.Errorf("Expected the default region to be used, got %q, %v", *create.Phone, errs)
+	}
+	create = valid(stringPtr(" "))
+	if errs := create.Validate("US"); errs != nil || create.Phone != nil {
+		t.Errorf("Expected a blank phone to be dropped, got %v, %v", create.Phone, errs)
+	}
+	for _, phone := range []string{"415 555 2671 x123", "+999 1234 5678", "555 555 5555 5"} {
+		create := valid(stringPtr(phone))
+		errs := create.Validate("US")
+		if len(errs) != 1 || errs[0].Field != "phone" || *create.Phone != phone {
+			t.Errorf("Validate(%q) = %v", phone, errs)
+		}
+	}
+
+	update := UserUpdateRequest{Phone: stringPtr("098765 43210")}
+	if errs := update.Validate("IN"); errs != nil || *update.Phone != "+919876543210" {
+		t.Errorf("Expected a normalized phone, got %q, %v", *update.Phone, errs)
+	}
+	update = UserUpdateRequest{Phone: stringPtr("  ")}
+	if errs := update.Validate("US"); errs != nil || *update.Phone != "" {
+		t.Errorf("Expected a blank phone to clear it, got %q, %v", *update.Phone, errs)
+	}
+	update = UserUpdateRequest{Phone: stringPtr("1-800-FLOWERS")}
+	if errs := update.Validate("US"); len(errs) != 1 || errs[0].Field != "phone" {
+		t.Errorf("Expected a phone error, got %v", errs)
+	}
+}
+
+func TestUserTimezone(t *testing.T) {
+	create := &UserCreateRequest{Email: "ana@example.com", Password: "Secret123!", FirstName: "Ana", LastName: "Lima"}
+	if errs := create.Validate("US"); errs != nil || create.Timezone != "UTC" {
+		t.Errorf("Expected the time zone to default to UTC, got %q, %v", create.Timezone, errs)
+	}
+
+	user := User{Timezone: "Asia/Kolkata"}
+	if got := user.Location().String(); got != "Asia/Kolkata" {
+		t.Errorf("Location() = %s, want Asia/Kolkata", got)
+	}
+	for _, tz := range []string{"", "Nowhere/Special"} {
+		user := User{Timezone: tz}
+		if got := user.Location(); got != time.UTC {
+			t.Errorf("Location() for %q = %s, want UTC", tz, got)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import "tgfinance/pkg/utils"
+
+// Lengths of the VARCHAR columns request fields are stored in
+const (
+	maxPersonNameLength    = 100
+	maxPaymentMethodLength = 50
+	maxEntityNameLength    = 255
+)
+
+// addFieldError adds err unless its field already failed, so a field that
+// fails both its validate tag and a utils validator is reported once
+func addFieldError(errs *utils.ValidationErrors, err error) {
+	var found utils.ValidationErrors
+	found.AddError(err)
+	if len(found) == 0 {
+		return
+	}
+	failed := make(map[string]bool)
+	for _, field := range errs.Fields() {
+		failed[field] = true
+	}
+	for _, e := range found {
+		if !failed[e.Field] {
+			*errs = append(*errs, e)
+		}
+	}
+}
+
+// checkEnum adds an error if value is provided and not a member of set
+func checkEnum(errs *utils.ValidationErrors, field string, value *string, set EnumSet) {
+	if value != nil && !set.Valid(*value) {
+		addFieldError(errs, &utils.ValidationError{Field: field, Message: set.Message(field)})
+	}
+}
+
+// checkNotEmptyUpdate adds an error if an update request changes nothing
+func checkNotEmptyUpdate(errs *utils.ValidationErrors, changed bool) {
+	if !changed {
+		errs.Add("body", "request must update at least one field")
+	}
+}
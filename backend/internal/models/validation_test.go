@@ -0,0 +1,268 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+// requestModels lists every request model so their validate tags are checked
+var requestModels = []interface{}{
+	AllocationRuleCreateRequest{}, AllocationRuleUpdateRequest{}, AllocationSimulationRequest{},
+	AllocationSuggestionRequest{}, AnnouncementCreateRequest{}, AnnouncementUpdateRequest{},
+	BudgetCreateRequest{}, BudgetUpdateRequest{}, CategorizationRuleRequest{},
+	CategorizationRuleApplyRequest{}, CSVImportRequest{}, DeadLetterBulkRetryRequest{},
+	DeadLetterDiscardRequest{}, ExpenseCreateRequest{}, ExpenseUpdateRequest{}, ExpenseSplitRequest{},
+	ExpenseSplitSettleRequest{}, ExpenseMirrorResponseRequest{}, GoalCreateRequest{}, GoalUpdateRequest{},
+	GoalContributionCreateRequest{}, GoalLinkRequest{}, SharingUpdateRequest{}, IncomeCreateRequest{},
+	ProjectionRequest{}, IntegrationTokenRequest{}, IntegrationTokenRenewRequest{},
+	InvestmentCreateRequest{}, InvestmentUpdateRequest{}, InvestmentTransactionCreateRequest{},
+	InvestmentCloseRequest{}, OrganizationCreateRequest{}, MembershipCreateRequest{},
+	ConsentGrantRequest{}, PeriodReopenRequest{}, RetentionSettingsRequest{}, SessionUpdateRequest{},
+	TwoFactorVerifyRequest{}, SpendingLimitCreateRequest{}, SpendingLimitUpdateRequest{},
+	UserCreateRequest{}, UserUpdateRequest{}, UserLoginRequest{}, WebhookSubscriptionRequest{},
+}
+
+func TestRequestModelValidateStructTags(t *testing.T) {
+	for _, model := range requestModels {
+		if err := utils.ValidateStructTags(model); err != nil {
+			t.Errorf("%T: %v", model, err)
+		}
+	}
+}
+
+func TestRequestModelValidation(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+	integer := func(i int) *int { return &i }
+	day := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	tests := []struct {
+		name   string
+		model  interface{}
+		fields []string
+	}{
+		{"allocation rule create", AllocationRuleCreateRequest{GoalID: id, Name: "Save", Trigger: "schedule", Percentage: num(10)}, nil},
+		{"allocation rule create invalid", AllocationRuleCreateRequest{Trigger: "monthly", Percentage: num(101), FixedAmount: num(0), Priority: -1},
+			[]string{"goal_id", "name", "trigger", "percentage", "fixed_amount", "priority"}},
+		{"allocation rule update", AllocationRuleUpdateRequest{Trigger: str("income_created"), Priority: integer(0)}, nil},
+		{"allocation rule update invalid", AllocationRuleUpdateRequest{Trigger: str("never"), Percentage: num(0), Priority: integer(-1)},
+			[]string{"trigger", "percentage", "priority"}},
+		{"allocation simulation", AllocationSimulationRequest{Amount: 100}, nil},
+		{"allocation simulation invalid", AllocationSimulationRequest{Amount: -5}, []string{"amount"}},
+		{"allocation suggestion invalid", AllocationSuggestionRequest{}, []string{"amount"}},
+		{"announcement create", AnnouncementCreateRequest{Title: "Hi", Body: "News", Severity: "info", Audience: "all"}, nil},
+		{"announcement create invalid", AnnouncementCreateRequest{Severity: "urgent", Audience: "everyone"},
+			[]string{"title", "body", "severity", "audience"}},
+		{"announcement update invalid", AnnouncementUpdateRequest{Severity: str("urgent"), Audience: str("")}, []string{"severity", "audience"}},
+		{"budget create", BudgetCreateRequest{CategoryID: id, Amount: 500, Period: "monthly", StartDate: day, CarryoverMode: str("both")}, nil},
+		{"budget create invalid", BudgetCreateRequest{Amount: 0, Period: "daily", CarryoverMode: str("all"), CarryoverCap: num(-1)},
+			[]string{"category_id", "amount", "period", "start_date", "carryover_mode", "carryover_cap"}},
+		{"budget update", BudgetUpdateRequest{CarryoverCap: num(0)}, nil},
+		{"budget update invalid", BudgetUpdateRequest{Amount: num(0)}, []string{"amount"}},
+		{"categorization rule has no tags", CategorizationRuleRequest{}, nil},
+		{"csv import", CSVImportRequest{Mapping: CSVColumnMapping{Date: "Date", Amount: "Amount", Description: "Memo"}, CategoryID: id}, nil},
+		{"csv import invalid", CSVImportRequest{Mapping: CSVColumnMapping{Date: "Date"}, Settings: &CSVImportSettings{Delimiter: ","}},
+			[]string{"mapping.amount", "mapping.description", "settings.decimal", "settings.date_order", "category_id"}},
+		{"dead letter retry invalid", DeadLetterBulkRetryRequest{}, []string{"error_class"}},
+		{"dead letter discard invalid", DeadLetterDiscardRequest{Reason: " "}, []string{"reason"}},
+		{"expense create", ExpenseCreateRequest{CategoryID: id, Amount: 12.5, Description: "Lunch", ExpenseDate: day,
+			Splits: []ExpenseSplitRequest{{ShareAmount: num(6.25)}}}, nil},
+		{"expense create invalid", ExpenseCreateRequest{Amount: -1, Splits: []ExpenseSplitRequest{{SharePercentage: num(150)}}},
+			[]string{"category_id", "amount", "description", "expense_date", "splits[0].share_percentage"}},
+		{"expense update", ExpenseUpdateRequest{PaymentMethod: utils.Null[string]()}, nil},
+		{"expense update invalid", ExpenseUpdateRequest{Amount: num(0), Splits: []ExpenseSplitRequest{{ShareAmount: num(-2)}}},
+			[]string{"amount", "splits[0].share_amount"}},
+		{"goal create", GoalCreateRequest{Name: "House", TargetAmount: 1000, GoalType: "purchase", Priority: "high"}, nil},
+		{"goal create invalid", GoalCreateRequest{GoalType: "vacation", Priority: "urgent"},
+			[]string{"name", "target_amount", "goal_type", "priority"}},
+		{"goal update invalid", GoalUpdateRequest{TargetAmount: num(-10)}, []string{"target_amount"}},
+		{"goal contribution", GoalContributionCreateRequest{Amount: 50, ContributionDate: day}, nil},
+		{"goal contribution invalid", GoalContributionCreateRequest{}, []string{"amount", "contribution_date"}},
+		{"goal link", GoalLinkRequest{InvestmentID: id, SyncPercentage: num(100)}, nil},
+		{"goal link invalid", GoalLinkRequest{SyncPercentage: num(0)}, []string{"investment_id", "sync_percentage"}},
+		{"income create", IncomeCreateRequest{Amount: 3000, Source: "salary", IncomeDate: day}, nil},
+		{"income create invalid", IncomeCreateRequest{Amount: 3000, Source: "gift"}, []string{"source", "income_date"}},
+		{"projection", ProjectionRequest{MonthlyContribution: 0, AnnualReturn: num(-50), HorizonYears: integer(60)}, nil},
+		{"projection invalid", ProjectionRequest{MonthlyContribution: -1, AnnualReturn: num(51), HorizonYears: integer(0), Iterations: 10001},
+			[]string{"monthly_contribution", "annual_return", "horizon_years", "iterations"}},
+		{"investment create", InvestmentCreateRequest{TypeID: id, Name: "Index fund", Amount: 1000, StartDate: day, ExpenseRatio: num(0.2)}, nil},
+		{"investment create invalid", InvestmentCreateRequest{Amount: 1000, ExpenseRatio: num(101)},
+			[]string{"type_id", "name", "start_date", "expense_ratio"}},
+		{"investment update", InvestmentUpdateRequest{ExpenseRatio: utils.Null[float64]()}, nil},
+		{"investment update invalid", InvestmentUpdateRequest{Amount: num(0), ExpenseRatio: utils.Some(-0.1)}, []string{"amount", "expense_ratio"}},
+		{"investment transaction", InvestmentTransactionCreateRequest{TransactionType: "dividend", Amount: 5, TransactionDate: day}, nil},
+		{"investment transaction invalid", InvestmentTransactionCreateRequest{TransactionType: "refund", Amount: 5},
+			[]string{"transaction_type", "transaction_date"}},
+		{"investment close", InvestmentCloseRequest{RealizedValue: 0, CloseDate: day}, nil},
+		{"investment close invalid", InvestmentCloseRequest{RealizedValue: -1}, []string{"realized_value", "close_date"}},
+		{"organization create invalid", OrganizationCreateRequest{}, []string{"name"}},
+		{"membership", MembershipCreateRequest{UserID: id, Role: "advisor"}, nil},
+		{"membership invalid", MembershipCreateRequest{Role: "owner"}, []string{"user_id", "role"}},
+		{"consent grant invalid", ConsentGrantRequest{OrganizationID: id}, []string{"advisor_user_id"}},
+		{"session update", SessionUpdateRequest{DeviceName: str("Laptop")}, nil},
+		{"session update invalid", SessionUpdateRequest{DeviceName: str(strings.Repeat("x", 101))}, []string{"device_name"}},
+		{"two factor", TwoFactorVerifyRequest{Code: "123456"}, nil},
+		{"two factor invalid", TwoFactorVerifyRequest{TrustDeviceDays: -1}, []string{"code", "trust_device_days"}},
+		{"spending limit create", SpendingLimitCreateRequest{Name: "Coffee", Scope: "merchant", Action: "warn", DailyMax: num(10)}, nil},
+		{"spending limit create invalid", SpendingLimitCreateRequest{Scope: "user", Action: "deny", PerTransactionMax: num(0)},
+			[]string{"name", "scope", "per_transaction_max", "action"}},
+		{"spending limit update invalid", SpendingLimitUpdateRequest{Scope: str("user"), DailyMax: num(-1), Action: str("deny")},
+			[]string{"scope", "daily_max", "action"}},
+		{"user create", UserCreateRequest{Email: "ana@example.com", Password: "Secret123!", FirstName: "Ana", LastName: "Lima"}, nil},
+		{"user create invalid", UserCreateRequest{Email: "ana@", Password: "short"},
+			[]string{"email", "password", "first_name", "last_name"}},
+		{"user update", UserUpdateRequest{Currency: str("EUR")}, nil},
+		{"user update invalid", UserUpdateRequest{Currency: str("EURO")}, []string{"currency"}},
+		{"user login invalid", UserLoginRequest{Email: "ana"}, []string{"email", "password"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, err := range utils.ValidateStruct(tt.model) {
+				fields = append(fields, err.Field)
+			}
+			if got, want := strings.Join(fields, ","), strings.Join(tt.fields, ","); got != want {
+				t.Errorf("failed fields = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRequestValidate(t *testing.T) {
+	utils.SetClock(clock.NewFake(time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)))
+	t.Cleanup(func() { utils.SetClock(clock.Real()) })
+
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	datePtr := func(year int, month time.Month, day int) *time.Time {
+		d := date(year, month, day)
+		return &d
+	}
+	past := date(2026, time.March, 1)
+	id := uuid.New()
+	noURLs := utils.URLOptions{}
+
+	tests := []struct {
+		name     string
+		validate func() utils.ValidationErrors
+		fields   []string
+	}{
+		{"user create", (&UserCreateRequest{Email: "ana@example.com", Password: "Secret123!", FirstName: "Ana", LastName: "Lima",
+			DateOfBirth: datePtr(1990, time.May, 4)}).validator("US"), nil},
+		{"user create reports each field once", (&UserCreateRequest{Email: "ana@", Password: "short"}).validator("US"),
+			[]string{"email", "password", "first_name", "last_name"}},
+		{"user create weak password", (&UserCreateRequest{Email: "ana@example.com", Password: "password1", FirstName: "Ana", LastName: "Lima"}).validator("US"),
+			[]string{"password"}},
+		{"user create bad names", (&UserCreateRequest{Email: "ana@example.com", Password: "Secret123!", FirstName: "4na", LastName: "L"}).validator("US"),
+			[]string{"first_name", "last_name"}},
+		{"user create too young", (&UserCreateRequest{Email: "ana@example.com", Password: "Secret123!", FirstName: "Ana", LastName: "Lima",
+			DateOfBirth: datePtr(2020, time.January, 1)}).validator("US"), []string{"date_of_birth"}},
+		{"user create time zone", (&UserCreateRequest{Email: "ana@example.com", Password: "Secret123!", FirstName: "Ana", LastName: "Lima",
+			Timezone: "Asia/Kolkata"}).validator("US"), nil},
+		{"user create unknown time zone", (&UserCreateRequest{Email: "ana@example.com", Password: "Secret123!", FirstName: "Ana", LastName: "Lima",
+			Timezone: "Asia/Bangalore"}).validator("US"), []string{"timezone"}},
+		{"user update", (&UserUpdateRequest{FirstName: str("Ana"), Locale: str("pt-BR"), Currency: str("brl")}).validator("US"), nil},
+		{"user update time zone", (&UserUpdateRequest{Timezone: str("America/Sao_Paulo")}).validator("US"), nil},
+		{"user update invalid time zone", (&UserUpdateRequest{Timezone: str("")}).validator("US"), []string{"timezone"}},
+		{"user update empty", (&UserUpdateRequest{}).validator("US"), []string{"body"}},
+		{"user update invalid", (&UserUpdateRequest{LastName: str("!"), Locale: str("xx-YY"), Currency: str("ABC"),
+			DateOfBirth: datePtr(2027, time.January, 1)}).validator("US"), []string{"last_name", "date_of_birth", "locale", "currency"}},
+		{"user login", (&UserLoginRequest{Email: "ana@example.com", Password: "weak"}).Validate, nil},
+		{"user login invalid", (&UserLoginRequest{Email: "ana"}).Validate, []string{"email", "password"}},
+
+		{"expense create", (&ExpenseCreateRequest{CategoryID: id, Amount: 12.5, Description: "Lunch", ExpenseDate: past,
+			PaymentMethod: str("card"), Tags: []string{"Food"}}).validator(noURLs), nil},
+		{"expense create invalid", (&ExpenseCreateRequest{CategoryID: id, Amount: 12.345, Description: "  ", ExpenseDate: past,
+			PaymentMethod: str(strings.Repeat("c", 51)), ReceiptURL: str("ftp://example.com/r.png")}).validator(noURLs),
+			[]string{"description", "amount", "payment_method", "receipt_url"}},
+		{"expense update", (&ExpenseUpdateRequest{Tags: []string{"food"}}).validator(noURLs), nil},
+		{"expense update empty", (&ExpenseUpdateRequest{}).validator(noURLs), []string{"body"}},
+		{"expense update invalid", (&ExpenseUpdateRequest{Amount: num(1e10), Description: str(""), PaymentMethod: utils.Some(strings.Repeat("c", 51))}).validator(noURLs),
+			[]string{"amount", "description", "payment_method"}},
+
+		{"investment create", (&InvestmentCreateRequest{TypeID: id, Name: "Bond", Amount: 1000, StartDate: past,
+			EndDate: datePtr(2027, time.March, 1), CurrentValue: num(0)}).Validate, nil},
+		{"investment create end before start", (&InvestmentCreateRequest{TypeID: id, Name: "Bond", Amount: 1000, StartDate: past,
+			EndDate: datePtr(2026, time.February, 1)}).Validate, []string{"end_date"}},
+		{"investment create ends the day it starts", (&InvestmentCreateRequest{TypeID: id, Name: "Bond", Amount: 1000, StartDate: past,
+			EndDate: &past}).Validate, []string{"end_date"}},
+		{"investment create invalid", (&InvestmentCreateRequest{TypeID: id, Name: strings.Repeat("n", 256), Amount: 10.001, StartDate: past,
+			CurrentValue: num(-1)}).Validate, []string{"name", "amount", "current_value"}},
+		{"investment create interest rate", (&InvestmentCreateRequest{TypeID: id, Name: "Bond", Amount: 1000, StartDate: past,
+			InterestRate: num(7.125)}).Validate, nil},
+		{"investment create interest rate in basis points", (&InvestmentCreateRequest{TypeID: id, Name: "Bond", Amount: 1000, StartDate: past,
+			InterestRate: num(7000)}).Validate, []string{"interest_rate"}},
+		{"investment create negative interest rate", (&InvestmentCreateRequest{TypeID: id, Name: "Bond", Amount: 1000, StartDate: past,
+			InterestRate: num(-0.5)}).Validate, []string{"interest_rate"}},
+		{"investment update interest rate", (&InvestmentUpdateRequest{InterestRate: utils.Some(100.0)}).Validate, nil},
+		{"investment update interest rate precision", (&InvestmentUpdateRequest{InterestRate: utils.Some(4.12345)}).Validate, []string{"interest_rate"}},
+		{"investment update clears interest rate", (&InvestmentUpdateRequest{InterestRate: utils.Null[float64]()}).Validate, nil},
+		{"investment create account number", (&InvestmentCreateRequest{TypeID: id, Name: "Savings", Amount: 1000, StartDate: past,
+			AccountNumber: str("GB82 WEST 1234 5698 7654 32")}).Validate, nil},
+		{"investment create bad IBAN", (&InvestmentCreateRequest{TypeID: id, Name: "Savings", Amount: 1000, StartDate: past,
+			AccountNumber: str("GB82 WEST 1234 5698 7654 33")}).Validate, []string{"account_number"}},
+		{"investment update short account number", (&InvestmentUpdateRequest{AccountNumber: utils.Some("1234")}).Validate, []string{"account_number"}},
+		{"investment update clears account number", (&InvestmentUpdateRequest{AccountNumber: utils.Null[string]()}).Validate, nil},
+		{"investment update", (&InvestmentUpdateRequest{Status: str("matured"), CurrentValue: utils.Null[float64]()}).Validate, nil},
+		{"investment update empty", (&InvestmentUpdateRequest{Version: new(int64)}).Validate, []string{"body"}},
+		{"investment update invalid", (&InvestmentUpdateRequest{Name: str(" "), CurrentValue: utils.Some(-5.0), Status: str("frozen")}).Validate,
+			[]string{"name", "current_value", "status"}},
+		{"investment transaction", (&InvestmentTransactionCreateRequest{TransactionType: "fee", Amount: 2.5, TransactionDate: past}).Validate, nil},
+		{"investment transaction in the future", (&InvestmentTransactionCreateRequest{TransactionType: "fee", Amount: 2.5,
+			TransactionDate: date(2026, time.April, 1)}).Validate, []string{"transaction_date"}},
+		{"investment transaction invalid", (&InvestmentTransactionCreateRequest{TransactionType: "fee", Amount: 0.001}).Validate,
+			[]string{"transaction_date", "amount"}},
+
+		{"goal create", (&GoalCreateRequest{Name: "House", TargetAmount: 1000, GoalType: "purchase", Priority: "high",
+			TargetDate: datePtr(2027, time.January, 1)}).Validate, nil},
+		{"goal create target date in the past", (&GoalCreateRequest{Name: "House", TargetAmount: 1000, GoalType: "purchase", Priority: "high",
+			TargetDate: &past}).Validate, []string{"target_date"}},
+		{"goal create target date today", (&GoalCreateRequest{Name: "House", TargetAmount: 1000, GoalType: "purchase", Priority: "high",
+			TargetDate: datePtr(2026, time.March, 10)}).Validate, []string{"target_date"}},
+		{"goal create invalid", (&GoalCreateRequest{GoalType: "vacation", Priority: "urgent", TargetAmount: 1.005}).Validate,
+			[]string{"name", "goal_type", "priority", "target_amount"}},
+		{"goal update", (&GoalUpdateRequest{TargetDate: utils.Null[time.Time](), Status: str("completed")}).Validate, nil},
+		{"goal update empty", (&GoalUpdateRequest{}).Validate, []string{"body"}},
+		{"goal update invalid", (&GoalUpdateRequest{Name: str(""), TargetDate: utils.Some(past), GoalType: str("vacation"),
+			Priority: str("urgent"), Status: str("paused")}).Validate, []string{"name", "target_date", "goal_type", "priority", "status"}},
+		{"goal contribution", (&GoalContributionCreateRequest{Amount: 50, ContributionDate: date(2026, time.March, 10)}).Validate, nil},
+		{"goal contribution in the future", (&GoalContributionCreateRequest{Amount: 50, ContributionDate: date(2026, time.March, 12)}).Validate,
+			[]string{"contribution_date"}},
+		{"goal contribution invalid", (&GoalContributionCreateRequest{Amount: 50.555}).Validate, []string{"contribution_date", "amount"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := strings.Join(tt.validate().Fields(), ","), strings.Join(tt.fields, ","); got != want {
+				t.Errorf("failed fields = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// validator binds the default phone region for TestRequestValidate
+func (r *UserCreateRequest) validator(region string) func() utils.ValidationErrors {
+	return func() utils.ValidationErrors { return r.Validate(region) }
+}
+
+func (r *UserUpdateRequest) validator(region string) func() utils.ValidationErrors {
+	return func() utils.ValidationErrors { return r.Validate(region) }
+}
+
+// validator binds the receipt URL options for TestRequestValidate
+func (r *ExpenseCreateRequest) validator(opts utils.URLOptions) func() utils.ValidationErrors {
+	return func() utils.ValidationErrors { return r.Validate(opts) }
+}
+
+func (r *ExpenseUpdateRequest) validator(opts utils.URLOptions) func() utils.ValidationErrors {
+	return func() utils.ValidationErrors { return r.Validate(opts) }
+}
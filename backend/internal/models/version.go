@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrCodeVersionConflict is returned when an update was based on a stale version
+const ErrCodeVersionConflict = "VERSION_CONFLICT"
+
+// InitialVersion is the version of a newly created row
+const InitialVersion int64 = 1
+
+// VersionConflictError is returned when a row changed since the client read
+// it. Current holds the server's copy so clients can offer a merge.
+type VersionConflictError struct {
+	Resource        string    `json:"resource"`
+	ID              uuid.UUID `json:"id"`
+	ExpectedVersion int64     `json:"expected_version"`
+	CurrentVersion  int64     `json:"current_version"`
+	Current         any       `json:"current,omitempty"`
+}
+
+// Error returns the error message
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s %s was modified: expected version %d, current version %d",
+		e.Resource, e.ID, e.ExpectedVersion, e.CurrentVersion)
+}
+
+// Code returns the API error code
+func (e *VersionConflictError) Code() string {
+	return ErrCodeVersionConflict
+}
+
+// StatusCode returns the HTTP status for the conflict
+func (e *VersionConflictError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// ETag formats a row version as a strong entity tag
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseETag parses an entity tag produced by ETag, accepting weak tags
+func ParseETag(tag string) (int64, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, fmt.Errorf("invalid entity tag %q", tag)
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < InitialVersion {
+		return 0, fmt.Errorf("invalid entity tag %q", tag)
+	}
+	return version, nil
+}
+
+// ResolveVersion returns the version an update is based on, taken from the
+// If-Match header or the version field of the body. One of them is required
+// and they must agree when both are given.
+func ResolveVersion(ifMatch string, bodyVersion *int64) (int64, error) {
+	if ifMatch == "" {
+		if bodyVersion == nil {
+			return 0, fmt.Errorf("If-Match header or version field is required")
+		}
+		if *bodyVersion < InitialVersion {
+			return 0, fmt.Errorf("version must be at least %d", InitialVersion)
+		}
+		return *bodyVersion, nil
+	}
+
+	version, err := ParseETag(ifMatch)
+	if err != nil {
+		return 0, err
+	}
+	if bodyVersion != nil && *bodyVersion != version {
+		return 0, fmt.Errorf("If-Match version %d does not match body version %d", version, *bodyVersion)
+	}
+	return version, nil
+}
+
+// CheckVersion returns a conflict error if expected is not the current version
+func CheckVersion(resource string, id uuid.UUID, expected, current int64, currentState any) error {
+	if expected == current {
+		return nil
+	}
+	return &VersionConflictError{
+		Resource:        resource,
+		ID:              id,
+		ExpectedVersion: expected,
+		CurrentVersion:  current,
+		Current:         currentState,
+	}
+}
+
+// BulkUpdateItem represents one item of a bulk update request
+type BulkUpdateItem[T any] struct {
+	ID      uuid.UUID `json:"id"`
+	Version int64     `json:"version"`
+	Changes T         `json:"changes"`
+}
+
+// BulkUpdateItemResult reports the outcome of one bulk update item
+type BulkUpdateItemResult struct {
+	ID       uuid.UUID             `json:"id"`
+	Updated  bool                  `json:"updated"`
+	Version  int64                 `json:"version,omitempty"`
+	Conflict *VersionConflictError `json:"conflict,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// BulkUpdateResult reports per-item outcomes of a bulk update
+type BulkUpdateResult struct {
+	Updated   int                    `json:"updated"`
+	Conflicts int                    `json:"conflicts"`
+	Failed    int                    `json:"failed"`
+	Items     []BulkUpdateItemResult `json:"items"`
+}
+
+// Add records the outcome of one item, classifying version conflicts
+func (r *BulkUpdateResult) Add(id uuid.UUID, newVersion int64, err error) {
+	item := BulkUpdateItemResult{ID: id}
+	switch conflict, ok := err.(*VersionConflictError); {
+	case err == nil:
+		item.Updated = true
+		item.Version = newVersion
+		r.Updated++
+	case ok:
+		item.Conflict = conflict
+		item.Error = conflict.Error()
+		r.Conflicts++
+	default:
+		item.Error = err.Error()
+		r.Failed++
+	}
+	r.Items = append(r.Items, item)
+}
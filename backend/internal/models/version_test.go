@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// versionedGoalStore mimics UPDATE ... WHERE id = $1 AND version = $2
+type versionedGoalStore struct {
+	goals map[uuid.UUID]FinancialGoal
+}
+
+func (s *versionedGoalStore) update(id uuid.UUID, version int64, req GoalUpdateRequest) (int64, error) {
+	goal := s.goals[id]
+	if err := CheckVersion("goal", id, version, goal.Version, goal); err != nil {
+		return 0, err
+	}
+	if req.Name != nil {
+		goal.Name = *req.Name
+	}
+	if req.TargetAmount != nil {
+		goal.TargetAmount = *req.TargetAmount
+	}
+	goal.Version++
+	s.goals[id] = goal
+	return goal.Version, nil
+}
+
+func TestLostUpdateIsRejected(t *testing.T) {
+	id := uuid.New()
+	store := &versionedGoalStore{goals: map[uuid.UUID]FinancialGoal{
+		id: {ID: id, Name: "House", TargetAmount: 50000, Version: InitialVersion},
+	}}
+
+	// Both devices read the goal at the same version
+	etag := ETag(store.goals[id].Version)
+
+	firstVersion, err := ResolveVersion(etag, nil)
+	if err != nil {
+		t.Fatalf("ResolveVersion() error = %v", err)
+	}
+	name := "Beach house"
+	if _, err := store.update(id, firstVersion, GoalUpdateRequest{Name: &name}); err != nil {
+		t.Fatalf("first writer error = %v", err)
+	}
+
+	secondVersion, _ := ResolveVersion(etag, nil)
+	target := 60000.0
+	_, err = store.update(id, secondVersion, GoalUpdateRequest{TargetAmount: &target})
+
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("second writer error = %v, want VersionConflictError", err)
+	}
+	if conflict.StatusCode() != http.StatusConflict || conflict.Code() != ErrCodeVersionConflict {
+		t.Errorf("conflict status = %d code = %s", conflict.StatusCode(), conflict.Code())
+	}
+	if conflict.CurrentVersion != 2 || conflict.ExpectedVersion != 1 {
+		t.Errorf("conflict versions = %d/%d, want 1/2", conflict.ExpectedVersion, conflict.CurrentVersion)
+	}
+	if current, ok := conflict.Current.(FinancialGoal); !ok || current.Name != "Beach house" {
+		t.Errorf("conflict should embed the current server state, got %+v", conflict.Current)
+	}
+	if store.goals[id].TargetAmount != 50000 {
+		t.Errorf("second write should not be applied")
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	two := int64(2)
+	zero := int64(0)
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		body    *int64
+		want    int64
+		wantErr bool
+	}{
+		{"if-match", `"3"`, nil, 3, false},
+		{"weak if-match", `W/"3"`, nil, 3, false},
+		{"body version", "", &two, 2, false},
+		{"both agree", `"2"`, &two, 2, false},
+		{"both disagree", `"3"`, &two, 0, true},
+		{"missing", "", nil, 0, true},
+		{"invalid tag", `3`, nil, 0, true},
+		{"wildcard", `*`, nil, 0, true},
+		{"zero body version", "", &zero, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveVersion(tt.ifMatch, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateResult(t *testing.T) {
+	var result BulkUpdateResult
+	ok, stale, broken := uuid.New(), uuid.New(), uuid.New()
+
+	result.Add(ok, 4, nil)
+	result.Add(stale, 0, CheckVersion("expense", stale, 1, 2, nil))
+	result.Add(broken, 0, errors.New("amount must be greater than 0"))
+
+	if result.Updated != 1 || result.Conflicts != 1 || result.Failed != 1 {
+		t.Fatalf("counts = %d/%d/%d, want 1/1/1", result.Updated, result.Conflicts, result.Failed)
+	}
+	if result.Items[0].Version != 4 || result.Items[1].Conflict == nil || result.Items[2].Conflict != nil {
+		t.Errorf("unexpected items: %+v", result.Items)
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription delivers a user's events of the listed types to a URL,
+// using the payload schema version the consumer was built against
+type WebhookSubscription struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	URL            string    `json:"url" db:"url"`
+	EventTypes     []string  `json:"event_types" db:"event_types"`
+	PayloadVersion int       `json:"payload_version" db:"payload_version"`
+	IsActive       bool      `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribes returns true if the subscription receives events of eventType
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscriptionRequest represents the request to create a webhook
+// subscription. PayloadVersion defaults to the latest schema version.
+type WebhookSubscriptionRequest struct {
+	URL            string   `json:"url"`
+	EventTypes     []string `json:"event_types"`
+	PayloadVersion *int     `json:"payload_version,omitempty"`
+}
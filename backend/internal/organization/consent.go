@@ -0,0 +1,157 @@
+package organization
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// DefaultConsentCacheTTL bounds how long a consent lookup is reused
+const DefaultConsentCacheTTL = time.Minute
+
+// Store persists organizations, memberships and consents
+type Store interface {
+	GetMembership(ctx context.Context, organizationID, userID uuid.UUID) (*models.OrganizationMembership, error)
+	// FindActiveConsent returns the active consent from client to advisor, or nil
+	FindActiveConsent(ctx context.Context, advisorID, clientID uuid.UUID, at time.Time) (*models.Consent, error)
+	GetConsent(ctx context.Context, consentID uuid.UUID) (*models.Consent, error)
+	CreateConsent(ctx context.Context, consent *models.Consent) error
+	RevokeConsent(ctx context.Context, consentID uuid.UUID, at time.Time) error
+}
+
+type consentKey struct {
+	advisor uuid.UUID
+	client  uuid.UUID
+}
+
+type cachedConsent struct {
+	active   bool
+	cachedAt time.Time
+}
+
+// Service manages client consents and answers whether an advisor may act for
+// a client. Lookups are cached briefly; grants and revocations invalidate the
+// cache entry so revocation takes effect on the next request.
+type Service struct {
+	store Store
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu    sync.RWMutex
+	cache map[consentKey]cachedConsent
+}
+
+// NewService creates a new organization consent service
+func NewService(store Store, ttl time.Duration) *Service {
+	if ttl <= 0 {
+		ttl = DefaultConsentCacheTTL
+	}
+	return &Service{
+		store: store,
+		ttl:   ttl,
+		now:   time.Now,
+		cache: make(map[consentKey]cachedConsent),
+	}
+}
+
+// HasActiveConsent returns true if the client has granted the advisor access
+func (s *Service) HasActiveConsent(ctx context.Context, advisorID, clientID uuid.UUID) (bool, error) {
+	key := consentKey{advisor: advisorID, client: clientID}
+	now := s.now()
+
+	s.mu.RLock()
+	entry, ok := s.cache[key]
+	s.mu.RUnlock()
+	if ok && now.Sub(entry.cachedAt) < s.ttl {
+		return entry.active, nil
+	}
+
+	consent, err := s.store.FindActiveConsent(ctx, advisorID, clientID, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up consent: %w", err)
+	}
+	active := consent != nil && consent.IsActive(now)
+
+	s.mu.Lock()
+	s.cache[key] = cachedConsent{active: active, cachedAt: now}
+	s.mu.Unlock()
+	return active, nil
+}
+
+// Grant records a client's consent for an advisor of the same organization
+func (s *Service) Grant(ctx context.Context, clientID uuid.UUID, req models.ConsentGrantRequest) (*models.Consent, error) {
+	if req.AdvisorUserID == clientID {
+		return nil, fmt.Errorf("cannot grant consent to yourself")
+	}
+	now := s.now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	if err := s.requireRole(ctx, req.OrganizationID, clientID, models.MembershipRoleClient); err != nil {
+		return nil, err
+	}
+	if err := s.requireRole(ctx, req.OrganizationID, req.AdvisorUserID, models.MembershipRoleAdvisor); err != nil {
+		return nil, err
+	}
+
+	consent := &models.Consent{
+		ID:             uuid.New(),
+		OrganizationID: req.OrganizationID,
+		ClientUserID:   clientID,
+		AdvisorUserID:  req.AdvisorUserID,
+		GrantedAt:      now,
+		ExpiresAt:      req.ExpiresAt,
+	}
+	if err := s.store.CreateConsent(ctx, consent); err != nil {
+		return nil, fmt.Errorf("failed to create consent: %w", err)
+	}
+
+	s.Invalidate(req.AdvisorUserID, clientID)
+	return consent, nil
+}
+
+// Revoke revokes one of the client's consents immediately
+func (s *Service) Revoke(ctx context.Context, clientID, consentID uuid.UUID) error {
+	consent, err := s.store.GetConsent(ctx, consentID)
+	if err != nil {
+		return fmt.Errorf("failed to get consent: %w", err)
+	}
+	if consent == nil || consent.ClientUserID != clientID {
+		return fmt.Errorf("consent not found")
+	}
+	if consent.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.store.RevokeConsent(ctx, consentID, s.now()); err != nil {
+		return fmt.Errorf("failed to revoke consent: %w", err)
+	}
+
+	s.Invalidate(consent.AdvisorUserID, clientID)
+	return nil
+}
+
+// Invalidate drops the cached consent decision for an advisor and client
+func (s *Service) Invalidate(advisorID, clientID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, consentKey{advisor: advisorID, client: clientID})
+}
+
+// requireRole checks that the user belongs to the organization with the role
+func (s *Service) requireRole(ctx context.Context, organizationID, userID uuid.UUID, role string) error {
+	membership, err := s.store.GetMembership(ctx, organizationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get membership: %w", err)
+	}
+	if membership == nil || membership.Role != role {
+		return fmt.Errorf("user %s is not a %s of the organization", userID, role)
+	}
+	return nil
+}
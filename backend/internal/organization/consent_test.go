@@ -0,0 +1,96 @@
+package organization
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+type fakeStore struct {
+	memberships map[uuid.UUID]string
+	consents    map[uuid.UUID]*models.Consent
+	lookups     int
+}
+
+func (s *fakeStore) GetMembership(ctx context.Context, organizationID, userID uuid.UUID) (*models.OrganizationMembership, error) {
+	role, ok := s.memberships[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &models.OrganizationMembership{OrganizationID: organizationID, UserID: userID, Role: role}, nil
+}
+
+func (s *fakeStore) FindActiveConsent(ctx context.Context, advisorID, clientID uuid.UUID, at time.Time) (*models.Consent, error) {
+	s.lookups++
+	for _, consent := range s.consents {
+		if consent.AdvisorUserID == advisorID && consent.ClientUserID == clientID && consent.IsActive(at) {
+			return consent, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) GetConsent(ctx context.Context, consentID uuid.UUID) (*models.Consent, error) {
+	return s.consents[consentID], nil
+}
+
+func (s *fakeStore) CreateConsent(ctx context.Context, consent *models.Consent) error {
+	s.consents[consent.ID] = consent
+	return nil
+}
+
+func (s *fakeStore) RevokeConsent(ctx context.Context, consentID uuid.UUID, at time.Time) error {
+	s.consents[consentID].RevokedAt = &at
+	return nil
+}
+
+func TestConsentLifecycle(t *testing.T) {
+	advisor := uuid.New()
+	client := uuid.New()
+	outsider := uuid.New()
+	store := &fakeStore{
+		memberships: map[uuid.UUID]string{advisor: models.MembershipRoleAdvisor, client: models.MembershipRoleClient},
+		consents:    make(map[uuid.UUID]*models.Consent),
+	}
+	service := NewService(store, time.Hour)
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	if ok, _ := service.HasActiveConsent(ctx, advisor, client); ok {
+		t.Fatal("No consent should exist yet")
+	}
+
+	if _, err := service.Grant(ctx, client, models.ConsentGrantRequest{OrganizationID: orgID, AdvisorUserID: outsider}); err == nil {
+		t.Error("Granting consent to a non-advisor should fail")
+	}
+	if _, err := service.Grant(ctx, advisor, models.ConsentGrantRequest{OrganizationID: orgID, AdvisorUserID: client}); err == nil {
+		t.Error("Only clients may grant consent")
+	}
+
+	consent, err := service.Grant(ctx, client, models.ConsentGrantRequest{OrganizationID: orgID, AdvisorUserID: advisor})
+	if err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if ok, _ := service.HasActiveConsent(ctx, advisor, client); !ok {
+		t.Error("Granted consent should be visible immediately")
+	}
+	lookups := store.lookups
+	service.HasActiveConsent(ctx, advisor, client)
+	if store.lookups != lookups {
+		t.Error("Consent lookups should be cached")
+	}
+
+	if err := service.Revoke(ctx, advisor, consent.ID); err == nil {
+		t.Error("Only the client may revoke their consent")
+	}
+	if err := service.Revoke(ctx, client, consent.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if ok, _ := service.HasActiveConsent(ctx, advisor, client); ok {
+		t.Error("Revocation should take effect immediately")
+	}
+}
@@ -0,0 +1,131 @@
+package period
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the period close endpoints
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new period close handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Close handles POST /api/v1/periods/{period}/close
+func (h *Handler) Close(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+
+	closed, err := h.service.Close(r.Context(), userID, r.PathValue("period"))
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to close period")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, closed)
+	}
+}
+
+// Reopen handles POST /api/v1/periods/{period}/reopen
+func (h *Handler) Reopen(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	var req models.PeriodReopenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	closed, err := h.service.Reopen(r.Context(), userID, r.PathValue("period"), &req)
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case errors.Is(err, ErrNotClosed):
+		httputil.WriteErrorFrom(w, http.StatusConflict, httputil.CodeForStatus(http.StatusConflict), err)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to reopen period")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, closed)
+	}
+}
+
+// Summary handles GET /api/v1/periods/{period}/summary
+func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+
+	summary, err := h.service.Summary(r.Context(), userID, r.PathValue("period"))
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to get period summary")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, summary)
+	}
+}
+
+// Adjustments handles GET /api/v1/periods/{period}/adjustments
+func (h *Handler) Adjustments(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+
+	adjustments, err := h.service.Adjustments(r.Context(), userID, r.PathValue("period"))
+	var errs utils.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	case err != nil:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to list period adjustments")
+	default:
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"adjustments": adjustments})
+	}
+}
+
+// WriteLockedError writes the response for a write rejected by a period
+// lock, for the handlers of expense writes. It returns false if err is not
+// a period lock error.
+func WriteLockedError(w http.ResponseWriter, err error) bool {
+	var locked *LockedError
+	if !errors.As(MapError(err), &locked) {
+		return false
+	}
+	httputil.WriteJSON(w, http.StatusConflict, map[string]interface{}{"error": lockedResponse{
+		ErrorResponse: httputil.ErrorResponse{Code: ErrCodePeriodLocked, Message: locked.Error()},
+		Period:        locked.Period,
+	}})
+	return true
+}
+
+// lockedResponse is the error of a write rejected by a period lock, naming
+// the closed period
+type lockedResponse struct {
+	httputil.ErrorResponse
+	Period string `json:"period"`
+}
@@ -0,0 +1,67 @@
+package period
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Layout formats a period as YYYY-MM
+const Layout = "2006-01"
+
+// lockedCode is the SQLSTATE raised by the expenses trigger for writes
+// dated inside a locked period
+const lockedCode = "TG001"
+
+// ErrCodePeriodLocked is the API error code of writes dated inside a closed
+// period
+const ErrCodePeriodLocked = "period_locked"
+
+// ErrPeriodLocked is matched by every LockedError
+var ErrPeriodLocked = errors.New("period is closed")
+
+// LockedError reports a write rejected because it is dated inside a closed
+// period
+type LockedError struct {
+	Period string
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("period %s is closed; reopen it or record the change as an adjustment", e.Period)
+}
+
+// Is makes errors.Is(err, ErrPeriodLocked) match
+func (e *LockedError) Is(target error) bool {
+	return target == ErrPeriodLocked
+}
+
+// Parse parses a YYYY-MM period and returns the first day of the month in UTC
+func Parse(period string) (time.Time, error) {
+	start, err := time.Parse(Layout, period)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("period must be formatted YYYY-MM")
+	}
+	return start, nil
+}
+
+// Of returns the period containing t
+func Of(t time.Time) string {
+	return t.Format(Layout)
+}
+
+// Bounds returns the first day of the period and of the following one
+func Bounds(start time.Time) (time.Time, time.Time) {
+	return start, start.AddDate(0, 1, 0)
+}
+
+// MapError converts the database error raised for a write inside a locked
+// period into a LockedError, and returns other errors unchanged
+func MapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == lockedCode {
+		return &LockedError{Period: pqErr.Detail}
+	}
+	return err
+}
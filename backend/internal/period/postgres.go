@@ -0,0 +1,130 @@
+package period
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// closeColumns selects a period close with its period formatted YYYY-MM
+const closeColumns = `id, user_id, to_char(period, 'YYYY-MM'), status, snapshot, closed_at,
+	reopened_at, reopened_by, reopen_reason, created_at, updated_at`
+
+// PostgresStore persists period closes in PostgreSQL
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL period store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// GetClose returns the user's close of a period, or nil
+func (s *PostgresStore) GetClose(ctx context.Context, userID uuid.UUID, period string) (*models.PeriodClose, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+closeColumns+` FROM period_closes
+		WHERE user_id = $1 AND period = to_date($2, 'YYYY-MM')`, userID, period)
+	closed, err := scanClose(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return closed, err
+}
+
+// Lock marks the period locked, creating its close if needed. A reopened
+// period is locked again and loses its old snapshot; a locked one is left
+// untouched.
+func (s *PostgresStore) Lock(ctx context.Context, userID uuid.UUID, period string, at time.Time) (*models.PeriodClose, error) {
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO period_closes (user_id, period, status, closed_at)
+		VALUES ($1, to_date($2, 'YYYY-MM'), 'locked', $3)
+		ON CONFLICT (user_id, period) DO UPDATE
+			SET status = 'locked', snapshot = NULL, closed_at = EXCLUDED.closed_at
+			WHERE period_closes.status <> 'locked'
+		RETURNING `+closeColumns, userID, period, at)
+	closed, err := scanClose(row)
+	if err == sql.ErrNoRows {
+		return s.GetClose(ctx, userID, period)
+	}
+	return closed, err
+}
+
+// SaveSnapshot stores the summary taken when the period was locked
+func (s *PostgresStore) SaveSnapshot(ctx context.Context, closeID uuid.UUID, snapshot *models.ExpenseSummary) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE period_closes SET snapshot = $2 WHERE id = $1 AND status = 'locked'`, closeID, data)
+	return err
+}
+
+// Reopen unlocks a period close
+func (s *PostgresStore) Reopen(ctx context.Context, closeID, actorID uuid.UUID, reason string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE period_closes
+		SET status = 'reopened', reopened_at = $2, reopened_by = $3, reopen_reason = $4
+		WHERE id = $1 AND status = 'locked'`, closeID, at, actorID, reason)
+	return err
+}
+
+// ListAdjustments returns the adjustments of a period, oldest first
+func (s *PostgresStore) ListAdjustments(ctx context.Context, userID uuid.UUID, period string) ([]models.PeriodAdjustment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, to_char(period, 'YYYY-MM'), expense_id, action, old_value, new_value, reason, created_at
+		FROM period_adjustments
+		WHERE user_id = $1 AND period = to_date($2, 'YYYY-MM')
+		ORDER BY created_at, id`, userID, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := []models.PeriodAdjustment{}
+	for rows.Next() {
+		var a models.PeriodAdjustment
+		var oldValue, newValue []byte
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Period, &a.ExpenseID, &a.Action, &oldValue, &newValue, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.OldValue = oldValue
+		a.NewValue = newValue
+		adjustments = append(adjustments, a)
+	}
+	return adjustments, rows.Err()
+}
+
+// AllowAdjustment lets the rest of tx write expenses dated inside locked
+// periods, recording each write as an adjustment with reason. It only lasts
+// until tx ends.
+func AllowAdjustment(ctx context.Context, tx *sql.Tx, reason string) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('tgfinance.period_adjustment', $1, true)`, reason)
+	return err
+}
+
+// scanClose scans a row selected with closeColumns
+func scanClose(row *sql.Row) (*models.PeriodClose, error) {
+	var c models.PeriodClose
+	var snapshot []byte
+	err := row.Scan(&c.ID, &c.UserID, &c.Period, &c.Status, &snapshot, &c.ClosedAt,
+		&c.ReopenedAt, &c.ReopenedBy, &c.ReopenReason, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		c.Snapshot = &models.ExpenseSummary{}
+		if err := json.Unmarshal(snapshot, c.Snapshot); err != nil {
+			return nil, err
+		}
+		// Snapshots taken before the Money total existed only hold the float
+		if c.Snapshot.TotalAmountMoney == 0 {
+			c.Snapshot.TotalAmountMoney = models.MoneyFromFloat(c.Snapshot.TotalAmount)
+		}
+	}
+	return &c, nil
+}
@@ -0,0 +1,205 @@
+package period
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+// ErrNotClosed is returned when reopening a period that is not locked
+var ErrNotClosed = errors.New("period is not closed")
+
+// Store persists period closes and lists their adjustments
+type Store interface {
+	GetClose(ctx context.Context, userID uuid.UUID, period string) (*models.PeriodClose, error)
+	// Lock marks the period locked, creating its close if needed, and returns
+	// the close in effect. A period that is already locked is left untouched.
+	Lock(ctx context.Context, userID uuid.UUID, period string, at time.Time) (*models.PeriodClose, error)
+	SaveSnapshot(ctx context.Context, closeID uuid.UUID, snapshot *models.ExpenseSummary) error
+	Reopen(ctx context.Context, closeID, actorID uuid.UUID, reason string, at time.Time) error
+	ListAdjustments(ctx context.Context, userID uuid.UUID, period string) ([]models.PeriodAdjustment, error)
+}
+
+// SummarySource computes live expense summaries
+type SummarySource interface {
+	// ExpenseSummary summarizes the user's expenses dated in [from, to)
+	ExpenseSummary(ctx context.Context, userID uuid.UUID, from, to time.Time) (*models.ExpenseSummary, error)
+}
+
+// Auditor records period reopens
+type Auditor interface {
+	RecordAudit(ctx context.Context, entry models.AuditLog) error
+}
+
+// Service closes and reopens monthly periods. Closing is idempotent: the
+// period is locked before its summary is snapshotted, so the snapshot cannot
+// miss a concurrent write, and re-running a close that stopped in between
+// only takes the missing snapshot.
+type Service struct {
+	store     Store
+	summaries SummarySource
+	auditor   Auditor
+	clock     clock.Clock
+}
+
+// NewService creates a new period close service
+func NewService(store Store, summaries SummarySource, auditor Auditor) *Service {
+	return &Service{store: store, summaries: summaries, auditor: auditor, clock: clock.Real()}
+}
+
+// Close locks a period that has ended and snapshots its summary. Closing a
+// locked period returns its existing close.
+func (s *Service) Close(ctx context.Context, userID uuid.UUID, period string) (*models.PeriodClose, error) {
+	start, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	from, to := Bounds(start)
+	now := s.clock.Now().UTC()
+	if now.Before(to) {
+		return nil, utils.ValidationErrors{{Field: "period", Message: "period has not ended yet"}}
+	}
+
+	closed, err := s.store.Lock(ctx, userID, period, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock period: %w", err)
+	}
+	if closed.Snapshot != nil {
+		return closed, nil
+	}
+
+	snapshot, err := s.summaries.ExpenseSummary(ctx, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize period: %w", err)
+	}
+	if err := s.store.SaveSnapshot(ctx, closed.ID, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to save period snapshot: %w", err)
+	}
+	closed.Snapshot = snapshot
+	return closed, nil
+}
+
+// Reopen unlocks a closed period. The request must confirm the period and
+// give a reason; the reopen is audit-logged.
+func (s *Service) Reopen(ctx context.Context, userID uuid.UUID, period string, req *models.PeriodReopenRequest) (*models.PeriodClose, error) {
+	if _, err := parsePeriod(period); err != nil {
+		return nil, err
+	}
+	if errs := ValidateReopenRequest(period, req); errs.HasErrors() {
+		return nil, errs
+	}
+
+	closed, err := s.store.GetClose(ctx, userID, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get period close: %w", err)
+	}
+	if closed == nil || !closed.IsLocked() {
+		return nil, ErrNotClosed
+	}
+
+	now := s.clock.Now().UTC()
+	reason := strings.TrimSpace(req.Reason)
+	if err := s.store.Reopen(ctx, closed.ID, userID, reason, now); err != nil {
+		return nil, fmt.Errorf("failed to reopen period: %w", err)
+	}
+
+	oldValue, _ := json.Marshal(models.PeriodStatusLocked)
+	newValue, _ := json.Marshal(models.PeriodStatusReopened)
+	entry := models.AuditLog{
+		ID:          uuid.New(),
+		UserID:      &userID,
+		ActorUserID: &userID,
+		Action:      models.AuditActionPeriodReopen,
+		EntityType:  "period_close",
+		EntityID:    &closed.ID,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		Reason:      &reason,
+		CreatedAt:   now,
+	}
+	if err := s.auditor.RecordAudit(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record period reopen: %w", err)
+	}
+
+	closed.Status = models.PeriodStatusReopened
+	closed.ReopenedAt = &now
+	closed.ReopenedBy = &userID
+	closed.ReopenReason = &reason
+	return closed, nil
+}
+
+// Summary returns the period's expense summary, read from the close snapshot
+// while the period is locked and computed live otherwise
+func (s *Service) Summary(ctx context.Context, userID uuid.UUID, period string) (*models.PeriodSummary, error) {
+	start, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	closed, err := s.store.GetClose(ctx, userID, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get period close: %w", err)
+	}
+
+	result := &models.PeriodSummary{Period: period}
+	if closed != nil && closed.IsLocked() && closed.Snapshot != nil {
+		adjustments, err := s.store.ListAdjustments(ctx, userID, period)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list period adjustments: %w", err)
+		}
+		result.Closed = true
+		result.ClosedAt = &closed.ClosedAt
+		result.Summary = *closed.Snapshot
+		result.Adjustments = len(adjustments)
+		return result, nil
+	}
+
+	from, to := Bounds(start)
+	summary, err := s.summaries.ExpenseSummary(ctx, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize period: %w", err)
+	}
+	result.Summary = *summary
+	return result, nil
+}
+
+// Adjustments lists the changes recorded against a closed period
+func (s *Service) Adjustments(ctx context.Context, userID uuid.UUID, period string) ([]models.PeriodAdjustment, error) {
+	if _, err := parsePeriod(period); err != nil {
+		return nil, err
+	}
+	adjustments, err := s.store.ListAdjustments(ctx, userID, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list period adjustments: %w", err)
+	}
+	return adjustments, nil
+}
+
+// ValidateReopenRequest validates a request to reopen period
+func ValidateReopenRequest(period string, req *models.PeriodReopenRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+	if req.Confirm != period {
+		errs.Add("confirm", "must repeat the period "+period+" to confirm the reopen")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		errs.Add("reason", "reason is required")
+	}
+	return errs
+}
+
+// parsePeriod parses a period, reporting a malformed one as a validation error
+func parsePeriod(period string) (time.Time, error) {
+	start, err := Parse(period)
+	if err != nil {
+		return time.Time{}, utils.ValidationErrors{{Field: "period", Message: err.Error()}}
+	}
+	return start, nil
+}
@@ -0,0 +1,250 @@
+package period
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+type memoryStore struct {
+	closes      map[string]*models.PeriodClose
+	adjustments []models.PeriodAdjustment
+	snapshotErr error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{closes: make(map[string]*models.PeriodClose)}
+}
+
+func (s *memoryStore) GetClose(ctx context.Context, userID uuid.UUID, period string) (*models.PeriodClose, error) {
+	if c, ok := s.closes[period]; ok && c.UserID == userID {
+		copied := *c
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) Lock(ctx context.Context, userID uuid.UUID, period string, at time.Time) (*models.PeriodClose, error) {
+	c, ok := s.closes[period]
+	if !ok {
+		c = &models.PeriodClose{ID: uuid.New(), UserID: userID, Period: period}
+		s.closes[period] = c
+	}
+	if !c.IsLocked() {
+		c.Status = models.PeriodStatusLocked
+		c.Snapshot = nil
+		c.ClosedAt = at
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (s *memoryStore) SaveSnapshot(ctx context.Context, closeID uuid.UUID, snapshot *models.ExpenseSummary) error {
+	if s.snapshotErr != nil {
+		return s.snapshotErr
+	}
+	for _, c := range s.closes {
+		if c.ID == closeID {
+			c.Snapshot = snapshot
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Reopen(ctx context.Context, closeID, actorID uuid.UUID, reason string, at time.Time) error {
+	for _, c := range s.closes {
+		if c.ID == closeID {
+			c.Status = models.PeriodStatusReopened
+			c.ReopenedAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListAdjustments(ctx context.Context, userID uuid.UUID, period string) ([]models.PeriodAdjustment, error) {
+	var adjustments []models.PeriodAdjustment
+	for _, a := range s.adjustments {
+		if a.UserID == userID && a.Period == period {
+			adjustments = append(adjustments, a)
+		}
+	}
+	return adjustments, nil
+}
+
+// liveSummaries totals a running amount so each call can see new expenses
+type liveSummaries struct {
+	total float64
+	calls int
+}
+
+func (l *liveSummaries) ExpenseSummary(ctx context.Context, userID uuid.UUID, from, to time.Time) (*models.ExpenseSummary, error) {
+	l.calls++
+	return &models.ExpenseSummary{TotalAmount: l.total}, nil
+}
+
+type recordingAuditor struct{ entries []models.AuditLog }
+
+func (a *recordingAuditor) RecordAudit(ctx context.Context, entry models.AuditLog) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func newTestService(store Store, summaries SummarySource, auditor Auditor) *Service {
+	service := NewService(store, summaries, auditor)
+	service.clock = clock.NewFake(time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC))
+	return service
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	store := newMemoryStore()
+	summaries := &liveSummaries{total: 250}
+	service := newTestService(store, summaries, &recordingAuditor{})
+	userID := uuid.New()
+	ctx := context.Background()
+
+	first, err := service.Close(ctx, userID, "2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.IsLocked() || first.Snapshot == nil || first.Snapshot.TotalAmount != 250 {
+		t.Fatalf("Unexpected close %+v", first)
+	}
+
+	summaries.total = 300
+	second, err := service.Close(ctx, userID, "2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID || second.Snapshot.TotalAmount != 250 || summaries.calls != 1 {
+		t.Errorf("Closing again should keep the first snapshot, got %+v after %d summaries", second.Snapshot, summaries.calls)
+	}
+
+	summary, err := service.Summary(ctx, userID, "2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Closed || summary.Summary.TotalAmount != 250 {
+		t.Errorf("Closed period should report the snapshot, got %+v", summary)
+	}
+}
+
+func TestCloseResumesMissingSnapshot(t *testing.T) {
+	store := newMemoryStore()
+	store.snapshotErr = errors.New("connection reset")
+	summaries := &liveSummaries{total: 80}
+	service := newTestService(store, summaries, &recordingAuditor{})
+	userID := uuid.New()
+	ctx := context.Background()
+
+	if _, err := service.Close(ctx, userID, "2024-05"); err == nil {
+		t.Fatal("Expected the failed snapshot to be reported")
+	}
+	if !store.closes["2024-05"].IsLocked() {
+		t.Fatal("The period should be locked before the snapshot is taken")
+	}
+
+	store.snapshotErr = nil
+	closed, err := service.Close(ctx, userID, "2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Snapshot == nil || closed.Snapshot.TotalAmount != 80 {
+		t.Errorf("Re-running the close should take the missing snapshot, got %+v", closed.Snapshot)
+	}
+}
+
+func TestCloseRejectsOpenAndMalformedPeriods(t *testing.T) {
+	service := newTestService(newMemoryStore(), &liveSummaries{}, &recordingAuditor{})
+	for _, period := range []string{"2024-06", "2024-07", "2024-5", "May 2024"} {
+		_, err := service.Close(context.Background(), uuid.New(), period)
+		var errs utils.ValidationErrors
+		if !errors.As(err, &errs) {
+			t.Errorf("Close(%q) = %v, want validation error", period, err)
+		}
+	}
+}
+
+func TestReopenRequiresConfirmationAndIsAudited(t *testing.T) {
+	store := newMemoryStore()
+	summaries := &liveSummaries{total: 100}
+	auditor := &recordingAuditor{}
+	service := newTestService(store, summaries, auditor)
+	userID := uuid.New()
+	ctx := context.Background()
+
+	if _, err := service.Reopen(ctx, userID, "2024-05", &models.PeriodReopenRequest{Confirm: "2024-05", Reason: "Late invoice"}); !errors.Is(err, ErrNotClosed) {
+		t.Errorf("Expected ErrNotClosed, got %v", err)
+	}
+	if _, err := service.Close(ctx, userID, "2024-05"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := service.Reopen(ctx, userID, "2024-05", &models.PeriodReopenRequest{Confirm: "2024-04"})
+	var errs utils.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected confirm and reason errors, got %v", err)
+	}
+
+	reopened, err := service.Reopen(ctx, userID, "2024-05", &models.PeriodReopenRequest{Confirm: "2024-05", Reason: " Late invoice "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.IsLocked() || *reopened.ReopenReason != "Late invoice" {
+		t.Errorf("Unexpected reopened close %+v", reopened)
+	}
+	if len(auditor.entries) != 1 || auditor.entries[0].Action != models.AuditActionPeriodReopen || *auditor.entries[0].EntityID != reopened.ID {
+		t.Errorf("Unexpected audit entries %+v", auditor.entries)
+	}
+
+	summaries.total = 140
+	summary, err := service.Summary(ctx, userID, "2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Closed || summary.Summary.TotalAmount != 140 {
+		t.Errorf("Reopened period should be summarized live, got %+v", summary)
+	}
+
+	closed, err := service.Close(ctx, userID, "2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Snapshot.TotalAmount != 140 {
+		t.Errorf("Closing again should take a fresh snapshot, got %v", closed.Snapshot.TotalAmount)
+	}
+}
+
+func TestLockedErrorMapping(t *testing.T) {
+	err := MapError(&pq.Error{Code: "TG001", Message: "period 2024-05 is closed", Detail: "2024-05"})
+	var locked *LockedError
+	if !errors.As(err, &locked) || locked.Period != "2024-05" || !errors.Is(err, ErrPeriodLocked) {
+		t.Fatalf("Expected a locked error for 2024-05, got %v", err)
+	}
+
+	other := &pq.Error{Code: "23505"}
+	if MapError(other) != error(other) {
+		t.Error("Other database errors should be returned unchanged")
+	}
+
+	rec := httptest.NewRecorder()
+	if !WriteLockedError(rec, &pq.Error{Code: "TG001", Detail: "2024-05"}) {
+		t.Fatal("Expected the lock error to be written")
+	}
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"period":"2024-05"`) {
+		t.Errorf("Unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if WriteLockedError(httptest.NewRecorder(), errors.New("boom")) {
+		t.Error("Other errors should not be written")
+	}
+}
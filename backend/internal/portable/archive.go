@@ -0,0 +1,242 @@
+package portable
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"tgfinance/internal/models"
+)
+
+// SchemaVersion is the archive format version written by this build
+const SchemaVersion = 1
+
+// Entity names, in dependency order. Each is stored as <name>.jsonl.
+const (
+	EntityCategories             = "categories"
+	EntityExpenses               = "expenses"
+	EntityIncomes                = "incomes"
+	EntityGoals                  = "goals"
+	EntityGoalContributions      = "goal_contributions"
+	EntityInvestments            = "investments"
+	EntityInvestmentTransactions = "investment_transactions"
+)
+
+// Entities lists every entity type in the order it must be imported
+var Entities = []string{
+	EntityCategories,
+	EntityExpenses,
+	EntityIncomes,
+	EntityInvestments,
+	EntityInvestmentTransactions,
+	EntityGoals,
+	EntityGoalContributions,
+}
+
+const manifestName = "manifest.json"
+
+// maxEntryBytes bounds a single decompressed archive member
+const maxEntryBytes = 512 << 20
+
+// Manifest describes the contents of a portable archive
+type Manifest struct {
+	SchemaVersion int            `json:"schema_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Counts        map[string]int `json:"counts"`
+}
+
+// Dataset holds every entity of an account
+type Dataset struct {
+	Categories             []models.ExpenseCategory       `json:"categories"`
+	Expenses               []models.Expense               `json:"expenses"`
+	Incomes                []models.Income                `json:"incomes"`
+	Goals                  []models.FinancialGoal         `json:"goals"`
+	GoalContributions      []models.GoalContribution      `json:"goal_contributions"`
+	Investments            []models.Investment            `json:"investments"`
+	InvestmentTransactions []models.InvestmentTransaction `json:"investment_transactions"`
+}
+
+// Counts returns the number of records per entity type
+func (d *Dataset) Counts() map[string]int {
+	return map[string]int{
+		EntityCategories:             len(d.Categories),
+		EntityExpenses:               len(d.Expenses),
+		EntityIncomes:                len(d.Incomes),
+		EntityGoals:                  len(d.Goals),
+		EntityGoalContributions:      len(d.GoalContributions),
+		EntityInvestments:            len(d.Investments),
+		EntityInvestmentTransactions: len(d.InvestmentTransactions),
+	}
+}
+
+// Write writes the dataset as a ZIP archive of JSON-lines files plus a manifest
+func Write(w io.Writer, data *Dataset, exportedAt time.Time) error {
+	archive := zip.NewWriter(w)
+
+	entries := []struct {
+		name    string
+		records interface{}
+	}{
+		{EntityCategories, data.Categories},
+		{EntityExpenses, data.Expenses},
+		{EntityIncomes, data.Incomes},
+		{EntityGoals, data.Goals},
+		{EntityGoalContributions, data.GoalContributions},
+		{EntityInvestments, data.Investments},
+		{EntityInvestmentTransactions, data.InvestmentTransactions},
+	}
+
+	for _, entry := range entries {
+		file, err := archive.Create(entry.name + ".jsonl")
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", entry.name, err)
+		}
+		if err := writeLines(file, entry.records); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.name, err)
+		}
+	}
+
+	manifest := Manifest{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    exportedAt.UTC(),
+		Counts:        data.Counts(),
+	}
+	file, err := archive.Create(manifestName)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return archive.Close()
+}
+
+// Read parses a portable archive, rejecting unsupported schema versions and
+// archives whose contents do not match the manifest counts
+func Read(r io.ReaderAt, size int64) (*Dataset, *Manifest, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid archive: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	manifestFile, ok := files[manifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("archive has no %s", manifestName)
+	}
+	var manifest Manifest
+	if err := readJSON(manifestFile, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.SchemaVersion < 1 || manifest.SchemaVersion > SchemaVersion {
+		return nil, nil, fmt.Errorf("unsupported archive schema version %d (supported: 1-%d)", manifest.SchemaVersion, SchemaVersion)
+	}
+
+	data := &Dataset{}
+	targets := map[string]interface{}{
+		EntityCategories:             &data.Categories,
+		EntityExpenses:               &data.Expenses,
+		EntityIncomes:                &data.Incomes,
+		EntityGoals:                  &data.Goals,
+		EntityGoalContributions:      &data.GoalContributions,
+		EntityInvestments:            &data.Investments,
+		EntityInvestmentTransactions: &data.InvestmentTransactions,
+	}
+	for _, entity := range Entities {
+		file, ok := files[entity+".jsonl"]
+		if !ok {
+			if manifest.Counts[entity] > 0 {
+				return nil, nil, fmt.Errorf("archive is missing %s.jsonl", entity)
+			}
+			continue
+		}
+		if err := readLines(file, targets[entity]); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", entity, err)
+		}
+	}
+
+	counts := data.Counts()
+	for _, entity := range Entities {
+		if counts[entity] != manifest.Counts[entity] {
+			return nil, nil, fmt.Errorf("%s count mismatch: manifest says %d, archive has %d", entity, manifest.Counts[entity], counts[entity])
+		}
+	}
+
+	return data, &manifest, nil
+}
+
+// writeLines writes each element of a slice as one JSON document per line
+func writeLines(w io.Writer, records interface{}) error {
+	raw, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+
+	buffered := bufio.NewWriter(w)
+	for _, item := range items {
+		if _, err := buffered.Write(item); err != nil {
+			return err
+		}
+		if err := buffered.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// readLines decodes a JSON-lines archive member into a pointer to a slice
+func readLines(file *zip.File, target interface{}) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	scanner := bufio.NewScanner(io.LimitReader(rc, maxEntryBytes))
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	first := true
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		buf.Write(line)
+		first = false
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+
+	return json.Unmarshal(buf.Bytes(), target)
+}
+
+// readJSON decodes a single JSON archive member
+func readJSON(file *zip.File, target interface{}) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(io.LimitReader(rc, 1<<20)).Decode(target)
+}
@@ -0,0 +1,389 @@
+package portable
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+// Import modes for accounts that already contain data
+const (
+	ModeMerge   = "merge"
+	ModeReplace = "replace"
+)
+
+// Entity import statuses
+const (
+	StatusImported   = "imported"
+	StatusRolledBack = "rolled_back"
+)
+
+// Sink persists imported records. Every entity type is written inside its own
+// transaction so a failure rolls back that entity type only.
+type Sink interface {
+	Begin(ctx context.Context, entity string) (EntityTx, error)
+}
+
+// EntityTx is a transaction scoped to a single entity type
+type EntityTx interface {
+	// DeleteExisting removes the user's existing records of the entity type (replace mode)
+	DeleteExisting(ctx context.Context, userID uuid.UUID) error
+	Insert(ctx context.Context, record interface{}) error
+	Commit() error
+	Rollback() error
+}
+
+// CategoryResolver maps an exported category onto a category of the target
+// deployment, typically by name, creating it if needed
+type CategoryResolver func(ctx context.Context, category models.ExpenseCategory) (uuid.UUID, error)
+
+// EntityResult reports the import outcome of one entity type
+type EntityResult struct {
+	Entity   string   `json:"entity"`
+	Total    int      `json:"total"`
+	Imported int      `json:"imported"`
+	Status   string   `json:"status"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// ImportResult reports the outcome of a portable import
+type ImportResult struct {
+	Mode     string         `json:"mode"`
+	Entities []EntityResult `json:"entities"`
+}
+
+// HasFailures returns true if any entity type was rolled back
+func (r *ImportResult) HasFailures() bool {
+	for _, entity := range r.Entities {
+		if entity.Status != StatusImported {
+			return true
+		}
+	}
+	return false
+}
+
+// maxReportedErrors bounds the error messages kept per entity type
+const maxReportedErrors = 20
+
+// Importer imports a portable dataset into a user's account
+type Importer struct {
+	sink            Sink
+	resolveCategory CategoryResolver
+}
+
+// NewImporter creates a new importer
+func NewImporter(sink Sink, resolveCategory CategoryResolver) *Importer {
+	return &Importer{sink: sink, resolveCategory: resolveCategory}
+}
+
+// Import assigns fresh IDs to every record, rewrites references through the
+// old-to-new ID maps, validates each record and writes each entity type in
+// its own transaction. Records referencing a parent that failed to import
+// fail as well, so relationships are never left dangling.
+func (im *Importer) Import(ctx context.Context, userID uuid.UUID, data *Dataset, mode string) (*ImportResult, error) {
+	if mode != ModeMerge && mode != ModeReplace {
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	result := &ImportResult{Mode: mode}
+	ids := make(map[string]map[uuid.UUID]uuid.UUID, len(Entities))
+	for _, entity := range Entities {
+		ids[entity] = make(map[uuid.UUID]uuid.UUID)
+	}
+
+	// Categories are shared across users and resolved rather than inserted
+	categoryResult := EntityResult{Entity: EntityCategories, Total: len(data.Categories), Status: StatusImported}
+	for _, category := range data.Categories {
+		newID, err := im.resolveCategory(ctx, category)
+		if err != nil {
+			categoryResult.addError(fmt.Sprintf("category %q: %v", category.Name, err))
+			continue
+		}
+		ids[EntityCategories][category.ID] = newID
+		categoryResult.Imported++
+	}
+	if len(categoryResult.Errors) > 0 {
+		categoryResult.Status = StatusRolledBack
+	}
+	result.Entities = append(result.Entities, categoryResult)
+
+	steps := []struct {
+		entity  string
+		records []interface{}
+		prepare func(record interface{}) (interface{}, uuid.UUID, uuid.UUID, error)
+	}{
+		{EntityExpenses, toRecords(data.Expenses), func(r interface{}) (interface{}, uuid.UUID, uuid.UUID, error) {
+			return prepareExpense(r.(models.Expense), userID, ids)
+		}},
+		{EntityIncomes, toRecords(data.Incomes), func(r interface{}) (interface{}, uuid.UUID, uuid.UUID, error) {
+			return prepareIncome(r.(models.Income), userID)
+		}},
+		{EntityInvestments, toRecords(data.Investments), func(r interface{}) (interface{}, uuid.UUID, uuid.UUID, error) {
+			return prepareInvestment(r.(models.Investment), userID)
+		}},
+		{EntityInvestmentTransactions, toRecords(data.InvestmentTransactions), func(r interface{}) (interface{}, uuid.UUID, uuid.UUID, error) {
+			return prepareTransaction(r.(models.InvestmentTransaction), ids)
+		}},
+		{EntityGoals, toRecords(data.Goals), func(r interface{}) (interface{}, uuid.UUID, uuid.UUID, error) {
+			return prepareGoal(r.(models.FinancialGoal), userID, ids)
+		}},
+		{EntityGoalContributions, toRecords(data.GoalContributions), func(r interface{}) (interface{}, uuid.UUID, uuid.UUID, error) {
+			return prepareContribution(r.(models.GoalContribution), ids)
+		}},
+	}
+
+	for _, step := range steps {
+		entityResult, mapping, err := im.importEntity(ctx, userID, step.entity, step.records, mode, step.prepare)
+		if err != nil {
+			return nil, err
+		}
+		if entityResult.Status == StatusImported {
+			ids[step.entity] = mapping
+		}
+		result.Entities = append(result.Entities, entityResult)
+	}
+
+	return result, nil
+}
+
+// importEntity prepares and writes every record of one entity type, rolling
+// back the whole entity type on the first validation or write failure
+func (im *Importer) importEntity(
+	ctx context.Context,
+	userID uuid.UUID,
+	entity string,
+	records []interface{},
+	mode string,
+	prepare func(record interface{}) (interface{}, uuid.UUID, uuid.UUID, error),
+) (EntityResult, map[uuid.UUID]uuid.UUID, error) {
+	result := EntityResult{Entity: entity, Total: len(records), Status: StatusImported}
+	mapping := make(map[uuid.UUID]uuid.UUID, len(records))
+
+	prepared := make([]interface{}, 0, len(records))
+	for i, record := range records {
+		newRecord, oldID, newID, err := prepare(record)
+		if err != nil {
+			result.addError(fmt.Sprintf("record %d: %v", i+1, err))
+			continue
+		}
+		mapping[oldID] = newID
+		prepared = append(prepared, newRecord)
+	}
+	if len(result.Errors) > 0 {
+		result.Status = StatusRolledBack
+		return result, nil, nil
+	}
+
+	tx, err := im.sink.Begin(ctx, entity)
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to begin %s import: %w", entity, err)
+	}
+
+	fail := func(message string) (EntityResult, map[uuid.UUID]uuid.UUID, error) {
+		tx.Rollback()
+		result.addError(message)
+		result.Status = StatusRolledBack
+		result.Imported = 0
+		return result, nil, nil
+	}
+
+	if mode == ModeReplace {
+		if err := tx.DeleteExisting(ctx, userID); err != nil {
+			return fail(fmt.Sprintf("failed to remove existing %s: %v", entity, err))
+		}
+	}
+
+	for i, record := range prepared {
+		if err := tx.Insert(ctx, record); err != nil {
+			return fail(fmt.Sprintf("record %d: %v", i+1, err))
+		}
+		result.Imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fail(fmt.Sprintf("failed to commit %s: %v", entity, err))
+	}
+
+	return result, mapping, nil
+}
+
+func (r *EntityResult) addError(message string) {
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, message)
+	}
+}
+
+func prepareExpense(expense models.Expense, userID uuid.UUID, ids map[string]map[uuid.UUID]uuid.UUID) (interface{}, uuid.UUID, uuid.UUID, error) {
+	oldID := expense.ID
+	categoryID, ok := ids[EntityCategories][expense.CategoryID]
+	if !ok {
+		return nil, oldID, uuid.Nil, fmt.Errorf("unknown category %s", expense.CategoryID)
+	}
+	if err := firstError(
+		utils.ValidateAmount(expense.Amount, "amount"),
+		utils.ValidateRequired(expense.Description, "description"),
+	); err != nil {
+		return nil, oldID, uuid.Nil, err
+	}
+
+	expense.ID = uuid.New()
+	expense.UserID = userID
+	expense.CategoryID = categoryID
+	expense.SourceSplitID = nil
+	expense.MirrorStatus = nil
+	expense.Category = nil
+	expense.User = nil
+
+	// Linked participants belong to the source deployment's users
+	for i := range expense.Splits {
+		split := &expense.Splits[i]
+		split.ID = uuid.New()
+		split.ExpenseID = expense.ID
+		if split.ParticipantUserID != nil && split.ParticipantName == nil && !split.IsOwner {
+			name := "Linked participant"
+			split.ParticipantName = &name
+		}
+		split.ParticipantUserID = nil
+		split.MirroredExpenseID = nil
+		split.ReimbursementID = nil
+	}
+	expense.MyShare = expense.CalculateMyShare()
+
+	return expense, oldID, expense.ID, nil
+}
+
+func prepareIncome(income models.Income, userID uuid.UUID) (interface{}, uuid.UUID, uuid.UUID, error) {
+	oldID := income.ID
+	if err := utils.ValidateAmount(income.Amount, "amount"); err != nil {
+		return nil, oldID, uuid.Nil, err
+	}
+
+	income.ID = uuid.New()
+	income.UserID = userID
+	income.SplitID = nil
+	return income, oldID, income.ID, nil
+}
+
+func prepareGoal(goal models.FinancialGoal, userID uuid.UUID, ids map[string]map[uuid.UUID]uuid.UUID) (interface{}, uuid.UUID, uuid.UUID, error) {
+	oldID := goal.ID
+	if err := firstError(
+		utils.ValidateRequired(goal.Name, "name"),
+		utils.ValidateAmount(goal.TargetAmount, "target_amount"),
+	); err != nil {
+		return nil, oldID, uuid.Nil, err
+	}
+
+	goal.ID = uuid.New()
+	goal.UserID = userID
+	goal.User = nil
+
+	// A goal whose linked investment did not import keeps its amount, unlinked
+	if goal.LinkedInvestmentID != nil {
+		if investmentID, ok := ids[EntityInvestments][*goal.LinkedInvestmentID]; ok {
+			goal.LinkedInvestmentID = &investmentID
+		} else {
+			goal.Unlink()
+		}
+	}
+	return goal, oldID, goal.ID, nil
+}
+
+func prepareContribution(contribution models.GoalContribution, ids map[string]map[uuid.UUID]uuid.UUID) (interface{}, uuid.UUID, uuid.UUID, error) {
+	oldID := contribution.ID
+	goalID, ok := ids[EntityGoals][contribution.GoalID]
+	if !ok {
+		return nil, oldID, uuid.Nil, fmt.Errorf("unknown goal %s", contribution.GoalID)
+	}
+	if err := utils.ValidateAmount(contribution.Amount, "amount"); err != nil {
+		return nil, oldID, uuid.Nil, err
+	}
+
+	contribution.ID = uuid.New()
+	contribution.GoalID = goalID
+	contribution.RuleID = nil
+	if contribution.IncomeID != nil {
+		if incomeID, ok := ids[EntityIncomes][*contribution.IncomeID]; ok {
+			contribution.IncomeID = &incomeID
+		} else {
+			contribution.IncomeID = nil
+		}
+	}
+	contribution.Goal = nil
+	return contribution, oldID, contribution.ID, nil
+}
+
+func prepareInvestment(investment models.Investment, userID uuid.UUID) (interface{}, uuid.UUID, uuid.UUID, error) {
+	oldID := investment.ID
+	if err := firstError(
+		utils.ValidateRequired(investment.Name, "name"),
+		utils.ValidateAmount(investment.Amount, "amount"),
+	); err != nil {
+		return nil, oldID, uuid.Nil, err
+	}
+
+	investment.ID = uuid.New()
+	investment.UserID = userID
+	// The closing transaction is imported afterwards under a new ID
+	investment.ClosingTransactionID = nil
+	// Institution IDs differ between deployments; the backfill re-maps the raw text
+	investment.InstitutionID = nil
+	investment.CanonicalInstitution = nil
+	investment.Type = nil
+	investment.User = nil
+	return investment, oldID, investment.ID, nil
+}
+
+func prepareTransaction(transaction models.InvestmentTransaction, ids map[string]map[uuid.UUID]uuid.UUID) (interface{}, uuid.UUID, uuid.UUID, error) {
+	oldID := transaction.ID
+	investmentID, ok := ids[EntityInvestments][transaction.InvestmentID]
+	if !ok {
+		return nil, oldID, uuid.Nil, fmt.Errorf("unknown investment %s", transaction.InvestmentID)
+	}
+	switch transaction.TransactionType {
+	case models.TransactionTypeDeposit, models.TransactionTypeWithdrawal, models.TransactionTypeInterest,
+		models.TransactionTypeDividend, models.TransactionTypeFee:
+	default:
+		return nil, oldID, uuid.Nil, fmt.Errorf("invalid transaction type %q", transaction.TransactionType)
+	}
+	if err := utils.ValidateAmount(transaction.Amount, "amount"); err != nil {
+		return nil, oldID, uuid.Nil, err
+	}
+
+	transaction.ID = uuid.New()
+	transaction.InvestmentID = investmentID
+	transaction.Investment = nil
+	return transaction, oldID, transaction.ID, nil
+}
+
+// firstError returns the first non-nil error
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toRecords converts a typed slice to a slice of interface values
+func toRecords[T any](items []T) []interface{} {
+	records := make([]interface{}, len(items))
+	for i, item := range items {
+		records[i] = item
+	}
+	return records
+}
+
+// NormalizeMode returns the import mode for a request value, defaulting to merge
+func NormalizeMode(mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		return ModeMerge
+	}
+	return mode
+}
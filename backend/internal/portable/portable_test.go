@@ -0,0 +1,203 @@
+package portable
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+func sampleDataset() *Dataset {
+	userID := uuid.New()
+	category := models.ExpenseCategory{ID: uuid.New(), Name: "Food & Dining"}
+	goal := models.FinancialGoal{ID: uuid.New(), UserID: userID, Name: "Holiday", TargetAmount: 2000}
+	investment := models.Investment{ID: uuid.New(), UserID: userID, Name: "Index fund", Amount: 1000}
+	income := models.Income{ID: uuid.New(), UserID: userID, Amount: 3000}
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	return &Dataset{
+		Categories: []models.ExpenseCategory{category},
+		Expenses: []models.Expense{
+			{ID: uuid.New(), UserID: userID, CategoryID: category.ID, Amount: 42.5, Description: "Groceries", ExpenseDate: date},
+		},
+		Incomes: []models.Income{income},
+		Goals:   []models.FinancialGoal{goal},
+		GoalContributions: []models.GoalContribution{
+			{ID: uuid.New(), GoalID: goal.ID, Amount: 100, ContributionDate: date, IncomeID: &income.ID},
+		},
+		Investments: []models.Investment{investment},
+		InvestmentTransactions: []models.InvestmentTransaction{
+			{ID: uuid.New(), InvestmentID: investment.ID, TransactionType: models.TransactionTypeDeposit, Amount: 1000, TransactionDate: date},
+		},
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	data := sampleDataset()
+	var buf bytes.Buffer
+	if err := Write(&buf, data, time.Now()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, manifest, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if manifest.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", SchemaVersion, manifest.SchemaVersion)
+	}
+	for entity, count := range data.Counts() {
+		if got.Counts()[entity] != count {
+			t.Errorf("%s: expected %d records, got %d", entity, count, got.Counts()[entity])
+		}
+	}
+	if got.Expenses[0].ID != data.Expenses[0].ID || got.Expenses[0].Amount != 42.5 {
+		t.Errorf("Unexpected expense after round trip: %+v", got.Expenses[0])
+	}
+}
+
+func TestReadRejectsUnsupportedVersion(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, _ := archive.Create(manifestName)
+	json.NewEncoder(w).Encode(Manifest{SchemaVersion: SchemaVersion + 1})
+	archive.Close()
+
+	if _, _, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil {
+		t.Error("Expected an error for an unsupported schema version")
+	}
+}
+
+type fakeSink struct {
+	failEntity string
+	committed  map[string][]interface{}
+	deleted    []string
+}
+
+type fakeTx struct {
+	sink    *fakeSink
+	entity  string
+	pending []interface{}
+}
+
+func (s *fakeSink) Begin(ctx context.Context, entity string) (EntityTx, error) {
+	return &fakeTx{sink: s, entity: entity}, nil
+}
+
+func (tx *fakeTx) DeleteExisting(ctx context.Context, userID uuid.UUID) error {
+	tx.sink.deleted = append(tx.sink.deleted, tx.entity)
+	return nil
+}
+
+func (tx *fakeTx) Insert(ctx context.Context, record interface{}) error {
+	if tx.entity == tx.sink.failEntity {
+		return errors.New("insert failed")
+	}
+	tx.pending = append(tx.pending, record)
+	return nil
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.sink.committed[tx.entity] = tx.pending
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error { return nil }
+
+func newCategoryResolver() (CategoryResolver, map[string]uuid.UUID) {
+	existing := map[string]uuid.UUID{"Food & Dining": uuid.New()}
+	return func(ctx context.Context, category models.ExpenseCategory) (uuid.UUID, error) {
+		if id, ok := existing[category.Name]; ok {
+			return id, nil
+		}
+		return uuid.Nil, errors.New("unknown category")
+	}, existing
+}
+
+func TestImportRemapsIDs(t *testing.T) {
+	data := sampleDataset()
+	sink := &fakeSink{committed: make(map[string][]interface{})}
+	resolver, existing := newCategoryResolver()
+	userID := uuid.New()
+
+	result, err := NewImporter(sink, resolver).Import(context.Background(), userID, data, ModeMerge)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result.HasFailures() {
+		t.Fatalf("Unexpected failures: %+v", result.Entities)
+	}
+	if len(sink.deleted) != 0 {
+		t.Error("Merge mode should not delete existing records")
+	}
+
+	expense := sink.committed[EntityExpenses][0].(models.Expense)
+	if expense.ID == data.Expenses[0].ID || expense.UserID != userID {
+		t.Errorf("Expense should get a new ID and the importing user: %+v", expense)
+	}
+	if expense.CategoryID != existing["Food & Dining"] {
+		t.Error("Expense category should be resolved by name")
+	}
+
+	goal := sink.committed[EntityGoals][0].(models.FinancialGoal)
+	income := sink.committed[EntityIncomes][0].(models.Income)
+	contribution := sink.committed[EntityGoalContributions][0].(models.GoalContribution)
+	if contribution.GoalID != goal.ID {
+		t.Error("Contribution should reference the new goal ID")
+	}
+	if contribution.IncomeID == nil || *contribution.IncomeID != income.ID {
+		t.Error("Contribution should reference the new income ID")
+	}
+
+	investment := sink.committed[EntityInvestments][0].(models.Investment)
+	transaction := sink.committed[EntityInvestmentTransactions][0].(models.InvestmentTransaction)
+	if transaction.InvestmentID != investment.ID {
+		t.Error("Transaction should reference the new investment ID")
+	}
+}
+
+func TestImportRollsBackPerEntity(t *testing.T) {
+	data := sampleDataset()
+	resolver, _ := newCategoryResolver()
+
+	sink := &fakeSink{failEntity: EntityInvestments, committed: make(map[string][]interface{})}
+	result, err := NewImporter(sink, resolver).Import(context.Background(), uuid.New(), data, ModeReplace)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, entity := range result.Entities {
+		statuses[entity.Entity] = entity.Status
+	}
+	if statuses[EntityGoals] != StatusImported || statuses[EntityExpenses] != StatusImported {
+		t.Errorf("Unrelated entity types should import: %+v", statuses)
+	}
+	if statuses[EntityInvestments] != StatusRolledBack {
+		t.Error("Failed entity type should be rolled back")
+	}
+	if statuses[EntityInvestmentTransactions] != StatusRolledBack {
+		t.Error("Transactions of a rolled back investment import should fail")
+	}
+	if _, ok := sink.committed[EntityInvestmentTransactions]; ok {
+		t.Error("No transactions should be committed")
+	}
+
+	// Invalid records fail validation before anything is written
+	data.Goals[0].Name = " "
+	sink = &fakeSink{committed: make(map[string][]interface{})}
+	result, _ = NewImporter(sink, resolver).Import(context.Background(), uuid.New(), data, ModeMerge)
+	if !result.HasFailures() {
+		t.Error("Expected invalid goal to fail the import")
+	}
+	if _, ok := sink.committed[EntityGoals]; ok {
+		t.Error("Invalid goals should not be committed")
+	}
+}
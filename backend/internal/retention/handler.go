@@ -0,0 +1,91 @@
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"tgfinance/internal/middleware"
+	"tgfinance/internal/models"
+	"tgfinance/pkg/httputil"
+	"tgfinance/pkg/utils"
+)
+
+// Handler serves the retention settings endpoints
+type Handler struct {
+	retention *Service
+}
+
+// NewHandler creates a new retention handler
+func NewHandler(retention *Service) *Handler {
+	return &Handler{retention: retention}
+}
+
+// Get handles GET /api/v1/users/me/retention
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	settings, err := h.retention.Settings(r.Context(), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to get retention settings")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, settings)
+}
+
+// Update handles PUT /api/v1/users/me/retention
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	var req models.RetentionSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	settings, err := h.retention.UpdateSettings(r.Context(), userID, &req)
+	if !writeRetentionError(w, err) {
+		httputil.WriteJSON(w, http.StatusOK, settings)
+	}
+}
+
+// Preview handles POST /api/v1/users/me/retention/preview, reporting what
+// the submitted settings would remove before they are saved
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnauthorized, httputil.CodeForStatus(http.StatusUnauthorized), "Unauthorized")
+		return
+	}
+	var req models.RetentionSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+
+	entries, err := h.retention.Preview(r.Context(), userID, &req)
+	if !writeRetentionError(w, err) {
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+	}
+}
+
+// writeRetentionError writes the response for a retention service error and
+// returns false if there was none
+func writeRetentionError(w http.ResponseWriter, err error) bool {
+	var errs utils.ValidationErrors
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &errs):
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), errs)
+	default:
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to process retention settings")
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"tgfinance/internal/models"
+)
+
+// PostgresSettingsStore persists retention settings in PostgreSQL
+type PostgresSettingsStore struct {
+	db *sql.DB
+}
+
+// NewPostgresSettingsStore creates a new PostgreSQL retention settings store
+func NewPostgresSettingsStore(db *sql.DB) *PostgresSettingsStore {
+	return &PostgresSettingsStore{db: db}
+}
+
+// GetSettings returns the user's settings, or nil when none are saved
+func (s *PostgresSettingsStore) GetSettings(ctx context.Context, userID uuid.UUID) (*models.RetentionSettings, error) {
+	var settings models.RetentionSettings
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, expense_years, login_history_months, notification_days, updated_at
+		FROM user_retention_settings WHERE user_id = $1`, userID).
+		Scan(&settings.UserID, &settings.ExpenseYears, &settings.LoginHistoryMonths, &settings.NotificationDays, &settings.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveSettings stores the user's settings
+func (s *PostgresSettingsStore) SaveSettings(ctx context.Context, settings *models.RetentionSettings) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_retention_settings (user_id, expense_years, login_history_months, notification_days)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET expense_years = EXCLUDED.expense_years,
+			login_history_months = EXCLUDED.login_history_months, notification_days = EXCLUDED.notification_days`,
+		settings.UserID, settings.ExpenseYears, settings.LoginHistoryMonths, settings.NotificationDays)
+	return err
+}
+
+// ListConfigured returns the settings of users with at least one window set
+func (s *PostgresSettingsStore) ListConfigured(ctx context.Context) ([]models.RetentionSettings, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, expense_years, login_history_months, notification_days, updated_at
+		FROM user_retention_settings
+		WHERE expense_years IS NOT NULL OR login_history_months IS NOT NULL OR notification_days IS NOT NULL
+		ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configured []models.RetentionSettings
+	for rows.Next() {
+		var settings models.RetentionSettings
+		if err := rows.Scan(&settings.UserID, &settings.ExpenseYears, &settings.LoginHistoryMonths,
+			&settings.NotificationDays, &settings.UpdatedAt); err != nil {
+			return nil, err
+		}
+		configured = append(configured, settings)
+	}
+	return configured, rows.Err()
+}
+
+// purgeableExpenses selects the user's expenses dated before the cutoff,
+// leaving out expenses whose splits are mirrored into another user's
+// expenses, since deleting them would cascade into that user's data
+const purgeableExpenses = `e.user_id = $1 AND e.expense_date < $2
+	AND NOT EXISTS (
+		SELECT 1 FROM expense_splits s JOIN expenses m ON m.source_split_id = s.id
+		WHERE s.expense_id = e.id)`
+
+// ExpensePurger rolls expenses into monthly category aggregates and deletes
+// them
+type ExpensePurger struct {
+	db *sql.DB
+}
+
+// NewExpensePurger creates a new expense purger
+func NewExpensePurger(db *sql.DB) *ExpensePurger {
+	return &ExpensePurger{db: db}
+}
+
+// Entity returns the retention entity the purger handles
+func (p *ExpensePurger) Entity() string {
+	return models.RetentionExpenses
+}
+
+// Count returns how many expenses are older than cutoff and the oldest date
+func (p *ExpensePurger) Count(ctx context.Context, userID uuid.UUID, cutoff time.Time) (int, *time.Time, error) {
+	var count int
+	var oldest *time.Time
+	err := p.db.QueryRowContext(ctx, `SELECT COUNT(*), MIN(e.expense_date) FROM expenses e WHERE `+purgeableExpenses,
+		userID, cutoff).Scan(&count, &oldest)
+	return count, oldest, err
+}
+
+// PurgeBatch aggregates and deletes up to limit expenses older than cutoff
+// in one transaction. Aggregates count the user's share and skip mirrored
+// expenses that were never accepted, like expense summaries do. Locked
+// periods are bypassed: their totals live on in the aggregates.
+func (p *ExpensePurger) PurgeBatch(ctx context.Context, userID uuid.UUID, cutoff time.Time, limit int) (int, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('tgfinance.skip_period_lock', 'on', true)`); err != nil {
+		return 0, err
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT e.id FROM expenses e WHERE `+purgeableExpenses+`
+		ORDER BY e.expense_date, e.id LIMIT $3 FOR UPDATE`, userID, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO expense_monthly_aggregates (user_id, month, category_id, payment_method, amount, expense_count)
+		SELECT e.user_id, date_trunc('month', e.expense_date)::date, e.category_id, COALESCE(e.payment_method, ''),
+			SUM(e.my_share),
+			COUNT(*)
+		FROM expenses e
+		WHERE e.id = ANY($1) AND (e.source_split_id IS NULL OR e.mirror_status = 'accepted')
+		GROUP BY 1, 2, 3, 4
+		ON CONFLICT (user_id, month, category_id, payment_method) DO UPDATE SET
+			amount = expense_monthly_aggregates.amount + EXCLUDED.amount,
+			expense_count = expense_monthly_aggregates.expense_count + EXCLUDED.expense_count`, pq.Array(ids)); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM expenses WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), tx.Commit()
+}
+
+// ListAggregates returns the user's expense aggregates for months in
+// [from, to), for reports spanning purged data
+func (p *ExpensePurger) ListAggregates(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]models.ExpenseAggregate, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT user_id, month, category_id, payment_method, amount::float8, expense_count
+		FROM expense_monthly_aggregates
+		WHERE user_id = $1 AND month >= $2 AND month < $3
+		ORDER BY month, category_id, payment_method`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aggregates := []models.ExpenseAggregate{}
+	for rows.Next() {
+		var a models.ExpenseAggregate
+		if err := rows.Scan(&a.UserID, &a.Month, &a.CategoryID, &a.PaymentMethod, &a.Amount, &a.Count); err != nil {
+			return nil, err
+		}
+		aggregates = append(aggregates, a)
+	}
+	return aggregates, rows.Err()
+}
+
+// staleSessions selects the user's sessions that started before the cutoff
+// and have not been used since, so active logins are never removed
+const staleSessions = `user_id = $1 AND created_at < $2 AND (revoked_at IS NOT NULL OR last_seen_at < $2)`
+
+// LoginHistoryPurger deletes old login sessions
+type LoginHistoryPurger struct {
+	db *sql.DB
+}
+
+// NewLoginHistoryPurger creates a new login history purger
+func NewLoginHistoryPurger(db *sql.DB) *LoginHistoryPurger {
+	return &LoginHistoryPurger{db: db}
+}
+
+// Entity returns the retention entity the purger handles
+func (p *LoginHistoryPurger) Entity() string {
+	return models.RetentionLoginHistory
+}
+
+// Count returns how many sessions are older than cutoff and the oldest start
+func (p *LoginHistoryPurger) Count(ctx context.Context, userID uuid.UUID, cutoff time.Time) (int, *time.Time, error) {
+	var count int
+	var oldest *time.Time
+	err := p.db.QueryRowContext(ctx, `SELECT COUNT(*), MIN(created_at) FROM user_sessions WHERE `+staleSessions,
+		userID, cutoff).Scan(&count, &oldest)
+	return count, oldest, err
+}
+
+// PurgeBatch deletes up to limit sessions older than cutoff
+func (p *LoginHistoryPurger) PurgeBatch(ctx context.Context, userID uuid.UUID, cutoff time.Time, limit int) (int, error) {
+	result, err := p.db.ExecContext(ctx, `
+		DELETE FROM user_sessions WHERE id IN (
+			SELECT id FROM user_sessions WHERE `+staleSessions+` ORDER BY created_at LIMIT $3)`,
+		userID, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
@@ -0,0 +1,299 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+	"tgfinance/pkg/utils"
+)
+
+// Minimum retention windows, so a typo cannot wipe recent data
+const (
+	MinExpenseYears       = 2
+	MinLoginHistoryMonths = 3
+	MinNotificationDays   = 7
+)
+
+// BatchSize is how many records a purge removes per transaction
+const BatchSize = 500
+
+// Purger removes one entity's records older than a cutoff
+type Purger interface {
+	// Entity returns the retention entity the purger handles
+	Entity() string
+	// Count returns how many of the user's records are older than cutoff and
+	// the date of the oldest
+	Count(ctx context.Context, userID uuid.UUID, cutoff time.Time) (int, *time.Time, error)
+	// PurgeBatch removes up to limit of the user's records older than cutoff
+	// in one transaction and returns how many were removed
+	PurgeBatch(ctx context.Context, userID uuid.UUID, cutoff time.Time, limit int) (int, error)
+}
+
+// SettingsStore persists retention settings
+type SettingsStore interface {
+	// GetSettings returns the user's settings, or nil when none are saved
+	GetSettings(ctx context.Context, userID uuid.UUID) (*models.RetentionSettings, error)
+	SaveSettings(ctx context.Context, settings *models.RetentionSettings) error
+	// ListConfigured returns the settings of users with at least one window set
+	ListConfigured(ctx context.Context) ([]models.RetentionSettings, error)
+}
+
+// Auditor records audit log entries
+type Auditor interface {
+	RecordAudit(ctx context.Context, entry models.AuditLog) error
+}
+
+// Service manages retention settings and purges data older than each
+// user's windows. Expenses are rolled into monthly aggregates by their
+// purger before deletion; other entities are simply removed.
+type Service struct {
+	settings SettingsStore
+	auditor  Auditor
+	purgers  []Purger
+	logger   *logger.Logger
+	clock    clock.Clock
+}
+
+// NewService creates a new retention service over the entity purgers
+func NewService(settings SettingsStore, auditor Auditor, log *logger.Logger, purgers ...Purger) *Service {
+	return &Service{settings: settings, auditor: auditor, purgers: purgers, logger: log, clock: clock.Real()}
+}
+
+// Settings returns the user's retention settings, which keep everything
+// when none are saved
+func (s *Service) Settings(ctx context.Context, userID uuid.UUID) (*models.RetentionSettings, error) {
+	settings, err := s.settings.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention settings: %w", err)
+	}
+	if settings == nil {
+		settings = &models.RetentionSettings{UserID: userID}
+	}
+	return settings, nil
+}
+
+// UpdateSettings replaces the user's retention windows
+func (s *Service) UpdateSettings(ctx context.Context, userID uuid.UUID, req *models.RetentionSettingsRequest) (*models.RetentionSettings, error) {
+	if errs := ValidateSettingsRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+	settings := settingsFrom(userID, req)
+	settings.UpdatedAt = s.clock.Now().UTC()
+	if err := s.settings.SaveSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to save retention settings: %w", err)
+	}
+	return settings, nil
+}
+
+// Preview reports what the proposed windows would remove now, without
+// saving them
+func (s *Service) Preview(ctx context.Context, userID uuid.UUID, req *models.RetentionSettingsRequest) ([]models.RetentionPreviewEntry, error) {
+	if errs := ValidateSettingsRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+	settings := settingsFrom(userID, req)
+	now := s.clock.Now().UTC()
+
+	entries := make([]models.RetentionPreviewEntry, 0, len(s.purgers))
+	for _, p := range s.purgers {
+		entry := models.RetentionPreviewEntry{Entity: p.Entity()}
+		if cutoff, ok := Cutoff(settings, p.Entity(), now); ok {
+			count, oldest, err := p.Count(ctx, userID, cutoff)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count %s: %w", p.Entity(), err)
+			}
+			entry.Cutoff, entry.Count, entry.Oldest = &cutoff, count, oldest
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Purge removes the user's data older than their saved windows, batch by
+// batch, and records one summary audit entry per entity
+func (s *Service) Purge(ctx context.Context, userID uuid.UUID) ([]models.RetentionPurgeResult, error) {
+	settings, err := s.Settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+
+	var results []models.RetentionPurgeResult
+	for _, p := range s.purgers {
+		cutoff, ok := Cutoff(settings, p.Entity(), now)
+		if !ok {
+			continue
+		}
+		result, err := s.purge(ctx, userID, p, cutoff)
+		if result.Purged > 0 {
+			if auditErr := s.audit(ctx, userID, result); auditErr != nil && err == nil {
+				err = auditErr
+			}
+			results = append(results, result)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// RunScheduled purges the data of every user with retention windows each
+// interval until ctx is cancelled. A failing user does not stop the others.
+func (s *Service) RunScheduled(ctx context.Context, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			s.PurgeAll(ctx)
+		}
+	}
+}
+
+// PurgeAll purges the data of every user with retention windows
+func (s *Service) PurgeAll(ctx context.Context) {
+	configured, err := s.settings.ListConfigured(ctx)
+	if err != nil {
+		s.logError(err, "Failed to list retention settings")
+		return
+	}
+	for _, settings := range configured {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.Purge(ctx, settings.UserID); err != nil {
+			s.logError(err, fmt.Sprintf("Retention purge failed for user %s", settings.UserID))
+		}
+	}
+}
+
+// purge removes one entity's records older than cutoff in batches
+func (s *Service) purge(ctx context.Context, userID uuid.UUID, p Purger, cutoff time.Time) (models.RetentionPurgeResult, error) {
+	result := models.RetentionPurgeResult{Entity: p.Entity(), Cutoff: cutoff}
+	for {
+		purged, err := p.PurgeBatch(ctx, userID, cutoff, BatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to purge %s: %w", p.Entity(), err)
+		}
+		if purged == 0 {
+			return result, nil
+		}
+		result.Purged += purged
+		result.Batches++
+		if s.logger != nil {
+			s.logger.Infof("Retention purged %d %s for user %s (batch %d, %d so far)",
+				purged, p.Entity(), userID, result.Batches, result.Purged)
+		}
+		if purged < BatchSize {
+			return result, nil
+		}
+	}
+}
+
+// audit records a summary of one entity's purge
+func (s *Service) audit(ctx context.Context, userID uuid.UUID, result models.RetentionPurgeResult) error {
+	if s.auditor == nil {
+		return nil
+	}
+	summary := Summary(result)
+	value, _ := json.Marshal(result)
+	return s.auditor.RecordAudit(ctx, models.AuditLog{
+		ID:         uuid.New(),
+		UserID:     &userID,
+		Action:     models.AuditActionRetentionPurge,
+		EntityType: result.Entity,
+		NewValue:   value,
+		Reason:     &summary,
+		CreatedAt:  s.clock.Now().UTC(),
+	})
+}
+
+func (s *Service) logError(err error, message string) {
+	if s.logger != nil {
+		s.logger.WithError(err).Error(message)
+	}
+}
+
+// Cutoff returns the time before which an entity's records fall outside the
+// user's window, and false when the entity is kept forever. Expense cutoffs
+// fall on a month boundary so each monthly aggregate covers a whole month.
+func Cutoff(settings *models.RetentionSettings, entity string, now time.Time) (time.Time, bool) {
+	switch entity {
+	case models.RetentionExpenses:
+		if settings.ExpenseYears == nil {
+			return time.Time{}, false
+		}
+		cutoff := now.AddDate(-*settings.ExpenseYears, 0, 0)
+		return time.Date(cutoff.Year(), cutoff.Month(), 1, 0, 0, 0, 0, time.UTC), true
+	case models.RetentionLoginHistory:
+		if settings.LoginHistoryMonths == nil {
+			return time.Time{}, false
+		}
+		return now.AddDate(0, -*settings.LoginHistoryMonths, 0), true
+	case models.RetentionNotifications:
+		if settings.NotificationDays == nil {
+			return time.Time{}, false
+		}
+		return now.AddDate(0, 0, -*settings.NotificationDays), true
+	}
+	return time.Time{}, false
+}
+
+// Summary describes a purge for the audit log, e.g.
+// "purged 1,204 expenses older than 2019-01"
+func Summary(result models.RetentionPurgeResult) string {
+	cutoff := result.Cutoff.Format("2006-01-02")
+	if result.Entity == models.RetentionExpenses {
+		cutoff = result.Cutoff.Format("2006-01")
+	}
+	entity := result.Entity
+	if entity == models.RetentionLoginHistory {
+		entity = "login history entries"
+	}
+	return fmt.Sprintf("purged %s %s older than %s", groupThousands(result.Purged), entity, cutoff)
+}
+
+// groupThousands formats n with comma thousands separators
+func groupThousands(n int) string {
+	digits := strconv.Itoa(n)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
+
+// ValidateSettingsRequest validates retention windows against their minimums
+func ValidateSettingsRequest(req *models.RetentionSettingsRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+	if req.ExpenseYears != nil && *req.ExpenseYears < MinExpenseYears {
+		errs.Add("expense_years", fmt.Sprintf("expense_years must be at least %d", MinExpenseYears))
+	}
+	if req.LoginHistoryMonths != nil && *req.LoginHistoryMonths < MinLoginHistoryMonths {
+		errs.Add("login_history_months", fmt.Sprintf("login_history_months must be at least %d", MinLoginHistoryMonths))
+	}
+	if req.NotificationDays != nil && *req.NotificationDays < MinNotificationDays {
+		errs.Add("notification_days", fmt.Sprintf("notification_days must be at least %d", MinNotificationDays))
+	}
+	return errs
+}
+
+// settingsFrom builds settings from a request
+func settingsFrom(userID uuid.UUID, req *models.RetentionSettingsRequest) *models.RetentionSettings {
+	return &models.RetentionSettings{
+		UserID:             userID,
+		ExpenseYears:       req.ExpenseYears,
+		LoginHistoryMonths: req.LoginHistoryMonths,
+		NotificationDays:   req.NotificationDays,
+	}
+}
@@ -0,0 +1,240 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/utils"
+)
+
+var testNow = time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func intPtr(v int) *int { return &v }
+
+type memorySettings map[uuid.UUID]models.RetentionSettings
+
+func (m memorySettings) GetSettings(ctx context.Context, userID uuid.UUID) (*models.RetentionSettings, error) {
+	if s, ok := m[userID]; ok {
+		return &s, nil
+	}
+	return nil, nil
+}
+
+func (m memorySettings) SaveSettings(ctx context.Context, settings *models.RetentionSettings) error {
+	m[settings.UserID] = *settings
+	return nil
+}
+
+func (m memorySettings) ListConfigured(ctx context.Context) ([]models.RetentionSettings, error) {
+	var configured []models.RetentionSettings
+	for _, s := range m {
+		if !s.IsEmpty() {
+			configured = append(configured, s)
+		}
+	}
+	sort.Slice(configured, func(i, j int) bool { return configured[i].UserID.String() < configured[j].UserID.String() })
+	return configured, nil
+}
+
+// memoryPurger holds record dates per user
+type memoryPurger struct {
+	entity  string
+	records map[uuid.UUID][]time.Time
+	batches []int
+	fail    bool
+}
+
+func (p *memoryPurger) Entity() string { return p.entity }
+
+func (p *memoryPurger) Count(ctx context.Context, userID uuid.UUID, cutoff time.Time) (int, *time.Time, error) {
+	count := 0
+	var oldest *time.Time
+	for _, at := range p.records[userID] {
+		if at.Before(cutoff) {
+			count++
+			if oldest == nil || at.Before(*oldest) {
+				at := at
+				oldest = &at
+			}
+		}
+	}
+	return count, oldest, nil
+}
+
+func (p *memoryPurger) PurgeBatch(ctx context.Context, userID uuid.UUID, cutoff time.Time, limit int) (int, error) {
+	if p.fail {
+		return 0, errors.New("database unavailable")
+	}
+	var kept []time.Time
+	purged := 0
+	for _, at := range p.records[userID] {
+		if at.Before(cutoff) && purged < limit {
+			purged++
+			continue
+		}
+		kept = append(kept, at)
+	}
+	p.records[userID] = kept
+	if purged > 0 {
+		p.batches = append(p.batches, purged)
+	}
+	return purged, nil
+}
+
+type recordingAuditor struct {
+	entries []models.AuditLog
+}
+
+func (a *recordingAuditor) RecordAudit(ctx context.Context, entry models.AuditLog) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+// dates returns n dates a day apart, ending at last
+func dates(last time.Time, n int) []time.Time {
+	out := make([]time.Time, n)
+	for i := range out {
+		out[i] = last.AddDate(0, 0, -i)
+	}
+	return out
+}
+
+func newTestService(purgers ...Purger) (*Service, memorySettings, *recordingAuditor) {
+	settings := memorySettings{}
+	auditor := &recordingAuditor{}
+	service := NewService(settings, auditor, nil, purgers...)
+	service.clock = clock.NewFake(testNow)
+	return service, settings, auditor
+}
+
+func TestValidateSettingsRequest(t *testing.T) {
+	errs := ValidateSettingsRequest(&models.RetentionSettingsRequest{
+		ExpenseYears: intPtr(1), LoginHistoryMonths: intPtr(0), NotificationDays: intPtr(6),
+	})
+	if len(errs) != 3 {
+		t.Errorf("Expected all windows below their minimum to fail, got %v", errs)
+	}
+	if errs := ValidateSettingsRequest(&models.RetentionSettingsRequest{ExpenseYears: intPtr(MinExpenseYears)}); errs.HasErrors() {
+		t.Errorf("Minimum windows should be accepted, got %v", errs)
+	}
+}
+
+func TestCutoff(t *testing.T) {
+	settings := &models.RetentionSettings{ExpenseYears: intPtr(5), LoginHistoryMonths: intPtr(6), NotificationDays: intPtr(30)}
+	tests := []struct {
+		entity string
+		want   time.Time
+	}{
+		{models.RetentionExpenses, time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{models.RetentionLoginHistory, time.Date(2023, 12, 15, 12, 0, 0, 0, time.UTC)},
+		{models.RetentionNotifications, time.Date(2024, 5, 16, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got, ok := Cutoff(settings, tt.entity, testNow); !ok || !got.Equal(tt.want) {
+			t.Errorf("Cutoff(%s) = %v, %v, want %v", tt.entity, got, ok, tt.want)
+		}
+	}
+	if _, ok := Cutoff(&models.RetentionSettings{}, models.RetentionExpenses, testNow); ok {
+		t.Error("Entities without a window should be kept forever")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	got := Summary(models.RetentionPurgeResult{Entity: models.RetentionExpenses, Cutoff: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), Purged: 1204})
+	if got != "purged 1,204 expenses older than 2019-01" {
+		t.Errorf("Summary = %q", got)
+	}
+	got = Summary(models.RetentionPurgeResult{Entity: models.RetentionLoginHistory, Cutoff: testNow, Purged: 1234567})
+	if got != "purged 1,234,567 login history entries older than 2024-06-15" {
+		t.Errorf("Summary = %q", got)
+	}
+}
+
+func TestPreviewDoesNotSave(t *testing.T) {
+	userID := uuid.New()
+	expenses := &memoryPurger{entity: models.RetentionExpenses, records: map[uuid.UUID][]time.Time{
+		userID: dates(time.Date(2019, 6, 30, 0, 0, 0, 0, time.UTC), 40),
+	}}
+	logins := &memoryPurger{entity: models.RetentionLoginHistory, records: map[uuid.UUID][]time.Time{userID: dates(testNow, 10)}}
+	service, settings, _ := newTestService(expenses, logins)
+
+	entries, err := service.Preview(context.Background(), userID, &models.RetentionSettingsRequest{ExpenseYears: intPtr(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2019-05-22 .. 2019-05-31 fall before the June 2019 cutoff
+	if len(entries) != 2 || entries[0].Count != 10 || entries[0].Oldest == nil || entries[0].Oldest.Day() != 22 {
+		t.Errorf("Unexpected expense preview %+v", entries)
+	}
+	if entries[1].Cutoff != nil || entries[1].Count != 0 {
+		t.Errorf("Login history without a window should not be counted, got %+v", entries[1])
+	}
+	if len(settings) != 0 || len(expenses.records[userID]) != 40 {
+		t.Error("Preview should not save settings or remove data")
+	}
+
+	var errs utils.ValidationErrors
+	if _, err := service.Preview(context.Background(), userID, &models.RetentionSettingsRequest{NotificationDays: intPtr(1)}); !errors.As(err, &errs) {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+}
+
+func TestPurgeInBatchesWithSummaryAudit(t *testing.T) {
+	userID, other := uuid.New(), uuid.New()
+	old := time.Date(2017, 12, 31, 0, 0, 0, 0, time.UTC)
+	expenses := &memoryPurger{entity: models.RetentionExpenses, records: map[uuid.UUID][]time.Time{
+		userID: append(dates(old, BatchSize+204), testNow),
+		other:  dates(old, 3),
+	}}
+	logins := &memoryPurger{entity: models.RetentionLoginHistory, records: map[uuid.UUID][]time.Time{userID: dates(testNow, 5)}}
+	notifications := &memoryPurger{entity: models.RetentionNotifications, records: map[uuid.UUID][]time.Time{}}
+	service, _, auditor := newTestService(expenses, logins, notifications)
+	ctx := context.Background()
+
+	if _, err := service.UpdateSettings(ctx, userID, &models.RetentionSettingsRequest{
+		ExpenseYears: intPtr(2), LoginHistoryMonths: intPtr(3), NotificationDays: intPtr(30),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	service.PurgeAll(ctx)
+
+	if len(expenses.records[userID]) != 1 || len(expenses.records[other]) != 3 {
+		t.Errorf("Expected only the configured user's old expenses to be purged, got %d and %d",
+			len(expenses.records[userID]), len(expenses.records[other]))
+	}
+	if len(expenses.batches) != 2 || expenses.batches[0] != BatchSize {
+		t.Errorf("Expected two batches, got %v", expenses.batches)
+	}
+	if len(logins.records[userID]) != 5 {
+		t.Error("Recent logins should be kept")
+	}
+	if len(auditor.entries) != 1 {
+		t.Fatalf("Expected one summary audit entry, got %+v", auditor.entries)
+	}
+	entry := auditor.entries[0]
+	if entry.Action != models.AuditActionRetentionPurge || entry.EntityType != models.RetentionExpenses ||
+		entry.Reason == nil || *entry.Reason != "purged 704 expenses older than 2022-06" {
+		t.Errorf("Unexpected audit entry %+v", entry)
+	}
+}
+
+func TestPurgeStopsOnFailure(t *testing.T) {
+	userID := uuid.New()
+	expenses := &memoryPurger{entity: models.RetentionExpenses, fail: true, records: map[uuid.UUID][]time.Time{}}
+	service, settings, auditor := newTestService(expenses)
+	settings[userID] = models.RetentionSettings{UserID: userID, ExpenseYears: intPtr(2)}
+
+	if _, err := service.Purge(context.Background(), userID); err == nil {
+		t.Error("Expected the purge error to be returned")
+	}
+	if len(auditor.entries) != 0 {
+		t.Error("Nothing should be audited when nothing was purged")
+	}
+}
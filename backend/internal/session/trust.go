@@ -0,0 +1,171 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/auth"
+	"tgfinance/pkg/utils"
+)
+
+// MaxTrustDays is the longest a device may be trusted for
+const MaxTrustDays = 30
+
+// maxDeviceNameLength matches the device_name column
+const maxDeviceNameLength = 100
+
+// ErrNotFound is returned when a session does not exist or belongs to another user
+var ErrNotFound = errors.New("session not found")
+
+// Store persists sessions
+type Store interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	// FindByDeviceToken returns the user's session holding the device token hash, or nil
+	FindByDeviceToken(ctx context.Context, userID uuid.UUID, tokenHash string) (*models.Session, error)
+	SetDeviceName(ctx context.Context, id uuid.UUID, name *string) error
+	// SetTrust sets or, with nil arguments, clears a session's trusted-device marker
+	SetTrust(ctx context.Context, id uuid.UUID, tokenHash *string, until *time.Time) error
+	// Revoke revokes a session and clears its trusted-device marker
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	// ClearUserTrust clears the trusted-device markers on all of a user's sessions
+	ClearUserTrust(ctx context.Context, userID uuid.UUID) error
+}
+
+// TrustService manages device names and trusted devices for 2FA
+type TrustService struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewTrustService creates a new trusted-device service
+func NewTrustService(store Store) *TrustService {
+	return &TrustService{store: store, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// List returns a user's sessions, including their names and trust status
+func (s *TrustService) List(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	sessions, err := s.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// Trust marks a session as trusted for days after a successful TOTP
+// verification. It returns the device token to set in the
+// TrustedDeviceCookie; only its hash is stored, so trust is bound to the
+// device holding the cookie rather than to the session ID.
+func (s *TrustService) Trust(ctx context.Context, userID, sessionID uuid.UUID, days int) (string, time.Time, error) {
+	if days <= 0 || days > MaxTrustDays {
+		return "", time.Time{}, fmt.Errorf("trust_device_days must be between 1 and %d", MaxTrustDays)
+	}
+	session, err := s.owned(ctx, userID, sessionID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if session.IsRevoked() {
+		return "", time.Time{}, ErrNotFound
+	}
+
+	token, err := auth.GenerateDeviceToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	hash := auth.HashDeviceToken(token)
+	until := s.now().AddDate(0, 0, days)
+	if err := s.store.SetTrust(ctx, sessionID, &hash, &until); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to trust session: %w", err)
+	}
+	return token, until, nil
+}
+
+// SkipTOTP returns true if the device token presented in the
+// TrustedDeviceCookie belongs to a trusted, unrevoked session of the user
+func (s *TrustService) SkipTOTP(ctx context.Context, userID uuid.UUID, deviceToken string) (bool, error) {
+	if deviceToken == "" {
+		return false, nil
+	}
+	hash := auth.HashDeviceToken(deviceToken)
+	session, err := s.store.FindByDeviceToken(ctx, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up trusted device: %w", err)
+	}
+	if session == nil || session.UserID != userID || session.DeviceTokenHash == nil {
+		return false, nil
+	}
+	return auth.VerifyDeviceToken(*session.DeviceTokenHash, deviceToken) && session.IsTrusted(s.now()), nil
+}
+
+// Rename sets the user-assigned device name of a session; an empty name clears it
+func (s *TrustService) Rename(ctx context.Context, userID, sessionID uuid.UUID, req *models.SessionUpdateRequest) (*models.Session, error) {
+	if errs := ValidateUpdateRequest(req); errs.HasErrors() {
+		return nil, errs
+	}
+	session, err := s.owned(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if req.DeviceName == nil {
+		return session, nil
+	}
+
+	var name *string
+	if trimmed := strings.TrimSpace(*req.DeviceName); trimmed != "" {
+		name = &trimmed
+	}
+	if err := s.store.SetDeviceName(ctx, sessionID, name); err != nil {
+		return nil, fmt.Errorf("failed to rename session: %w", err)
+	}
+	session.DeviceName = name
+	return session, nil
+}
+
+// Revoke revokes a session, which also clears its trust
+func (s *TrustService) Revoke(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if _, err := s.owned(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	if err := s.store.Revoke(ctx, sessionID, s.now()); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// OnPasswordChanged drops every trusted-device marker of the user, so all
+// devices need a TOTP code at their next login
+func (s *TrustService) OnPasswordChanged(ctx context.Context, userID uuid.UUID) error {
+	if err := s.store.ClearUserTrust(ctx, userID); err != nil {
+		return fmt.Errorf("failed to clear trusted devices: %w", err)
+	}
+	return nil
+}
+
+// owned returns a session if it belongs to userID
+func (s *TrustService) owned(ctx context.Context, userID, sessionID uuid.UUID) (*models.Session, error) {
+	session, err := s.store.Get(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil || session.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return session, nil
+}
+
+// ValidateUpdateRequest validates a session update request
+func ValidateUpdateRequest(req *models.SessionUpdateRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if req.DeviceName != nil && len(strings.TrimSpace(*req.DeviceName)) > maxDeviceNameLength {
+		errs.Add("device_name", fmt.Sprintf("device_name must be at most %d characters", maxDeviceNameLength))
+	}
+
+	return errs
+}
@@ -0,0 +1,189 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+type memoryStore struct {
+	sessions map[uuid.UUID]*models.Session
+}
+
+func newMemoryStore(sessions ...*models.Session) *memoryStore {
+	s := &memoryStore{sessions: make(map[uuid.UUID]*models.Session)}
+	for _, session := range sessions {
+		s.sessions[session.ID] = session
+	}
+	return s
+}
+
+func (m *memoryStore) Get(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *memoryStore) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *memoryStore) FindByDeviceToken(ctx context.Context, userID uuid.UUID, tokenHash string) (*models.Session, error) {
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.DeviceTokenHash != nil && *session.DeviceTokenHash == tokenHash {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) SetDeviceName(ctx context.Context, id uuid.UUID, name *string) error {
+	m.sessions[id].DeviceName = name
+	return nil
+}
+
+func (m *memoryStore) SetTrust(ctx context.Context, id uuid.UUID, tokenHash *string, until *time.Time) error {
+	m.sessions[id].DeviceTokenHash = tokenHash
+	m.sessions[id].TrustedUntil = until
+	return nil
+}
+
+func (m *memoryStore) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	m.sessions[id].RevokedAt = &at
+	return m.SetTrust(ctx, id, nil, nil)
+}
+
+func (m *memoryStore) ClearUserTrust(ctx context.Context, userID uuid.UUID) error {
+	for id, session := range m.sessions {
+		if session.UserID == userID {
+			m.SetTrust(ctx, id, nil, nil)
+		}
+	}
+	return nil
+}
+
+func newSession(userID uuid.UUID) *models.Session {
+	return &models.Session{ID: uuid.New(), UserID: userID}
+}
+
+func TestTrustedDeviceSkipsTOTP(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	laptop, phone := newSession(userID), newSession(userID)
+	store := newMemoryStore(laptop, phone)
+	service := NewTrustService(store)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	token, until, err := service.Trust(ctx, userID, laptop.ID, 30)
+	if err != nil {
+		t.Fatalf("Failed to trust session: %v", err)
+	}
+	if !until.Equal(now.AddDate(0, 0, 30)) {
+		t.Errorf("Expected trust for 30 days, got %v", until)
+	}
+	if *store.sessions[laptop.ID].DeviceTokenHash == token {
+		t.Error("The raw device token must not be stored")
+	}
+
+	if skip, _ := service.SkipTOTP(ctx, userID, token); !skip {
+		t.Error("Expected the trusted device to skip TOTP")
+	}
+	if skip, _ := service.SkipTOTP(ctx, uuid.New(), token); skip {
+		t.Error("A device token must not work for another user")
+	}
+	if skip, _ := service.SkipTOTP(ctx, userID, "forged"); skip {
+		t.Error("Expected an unknown token to require TOTP")
+	}
+
+	now = now.AddDate(0, 0, 31)
+	if skip, _ := service.SkipTOTP(ctx, userID, token); skip {
+		t.Error("Expected expired trust to require TOTP")
+	}
+}
+
+func TestRevokeAndPasswordChangeClearTrust(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	laptop, desktop := newSession(userID), newSession(userID)
+	service := NewTrustService(newMemoryStore(laptop, desktop))
+
+	laptopToken, _, _ := service.Trust(ctx, userID, laptop.ID, 7)
+	desktopToken, _, _ := service.Trust(ctx, userID, desktop.ID, 7)
+
+	if err := service.Revoke(ctx, userID, laptop.ID); err != nil {
+		t.Fatalf("Failed to revoke: %v", err)
+	}
+	if skip, _ := service.SkipTOTP(ctx, userID, laptopToken); skip {
+		t.Error("Revoking a session should clear its trust")
+	}
+	if skip, _ := service.SkipTOTP(ctx, userID, desktopToken); !skip {
+		t.Error("Other sessions should stay trusted")
+	}
+
+	if err := service.OnPasswordChanged(ctx, userID); err != nil {
+		t.Fatalf("Failed to clear trust: %v", err)
+	}
+	if skip, _ := service.SkipTOTP(ctx, userID, desktopToken); skip {
+		t.Error("A password change should drop all trusted devices")
+	}
+	if _, _, err := service.Trust(ctx, userID, laptop.ID, 7); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected revoked session to be untrustable, got %v", err)
+	}
+}
+
+func TestTrustValidation(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	session := newSession(userID)
+	service := NewTrustService(newMemoryStore(session))
+
+	for _, days := range []int{0, MaxTrustDays + 1} {
+		if _, _, err := service.Trust(ctx, userID, session.ID, days); err == nil {
+			t.Errorf("Expected %d days to be rejected", days)
+		}
+	}
+	if _, _, err := service.Trust(ctx, uuid.New(), session.ID, 7); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another user's session to be hidden, got %v", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	session := newSession(userID)
+	service := NewTrustService(newMemoryStore(session))
+
+	name := "  Work laptop "
+	renamed, err := service.Rename(ctx, userID, session.ID, &models.SessionUpdateRequest{DeviceName: &name})
+	if err != nil || renamed.DeviceName == nil || *renamed.DeviceName != "Work laptop" {
+		t.Fatalf("Expected trimmed device name, got %+v, %v", renamed, err)
+	}
+
+	empty := ""
+	renamed, _ = service.Rename(ctx, userID, session.ID, &models.SessionUpdateRequest{DeviceName: &empty})
+	if renamed.DeviceName != nil {
+		t.Errorf("Expected an empty name to clear it, got %q", *renamed.DeviceName)
+	}
+
+	long := strings.Repeat("x", maxDeviceNameLength+1)
+	if _, err := service.Rename(ctx, userID, session.ID, &models.SessionUpdateRequest{DeviceName: &long}); err == nil {
+		t.Error("Expected an overlong device name to be rejected")
+	}
+}
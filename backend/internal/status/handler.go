@@ -0,0 +1,59 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tgfinance/pkg/httputil"
+)
+
+// Handler serves the public status endpoint and the admin incident endpoints
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new status handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetStatus handles GET /status
+func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	response := h.service.Status(r.Context())
+
+	statusCode := http.StatusOK
+	if response.Status == StateDown {
+		statusCode = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "public, max-age=10")
+	httputil.WriteJSON(w, statusCode, response)
+}
+
+// SetIncident handles PUT /api/v1/admin/status/incident
+func (h *Handler) SetIncident(w http.ResponseWriter, r *http.Request) {
+	var req IncidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		httputil.WriteErrorFrom(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), err)
+		return
+	}
+
+	incident, err := h.service.SetIncident(r.Context(), req)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to set incident")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, incident)
+}
+
+// ClearIncident handles DELETE /api/v1/admin/status/incident
+func (h *Handler) ClearIncident(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.ClearIncident(r.Context()); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to clear incident")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
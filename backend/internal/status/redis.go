@@ -0,0 +1,58 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incidentKey holds the current incident as JSON
+const incidentKey = "status:incident"
+
+// RedisIncidentStore keeps the current incident in Redis so every API
+// instance shows the same message. Expiring incidents expire with their key.
+type RedisIncidentStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisIncidentStore creates a Redis-backed incident store
+func NewRedisIncidentStore(client redis.UniversalClient) *RedisIncidentStore {
+	return &RedisIncidentStore{client: client}
+}
+
+// Get returns the current incident, or nil if none is set or it expired
+func (s *RedisIncidentStore) Get(ctx context.Context) (*Incident, error) {
+	data, err := s.client.Get(ctx, incidentKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var incident Incident
+	if err := json.Unmarshal(data, &incident); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// Set stores the incident, expiring it after ttl when ttl is positive
+func (s *RedisIncidentStore) Set(ctx context.Context, incident Incident, ttl time.Duration) error {
+	data, err := json.Marshal(incident)
+	if err != nil {
+		return err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client.Set(ctx, incidentKey, data, ttl).Err()
+}
+
+// Clear removes the current incident
+func (s *RedisIncidentStore) Clear(ctx context.Context) error {
+	return s.client.Del(ctx, incidentKey).Err()
+}
@@ -0,0 +1,286 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Component and overall states
+const (
+	StateOperational = "operational"
+	StateDegraded    = "degraded"
+	StateDown        = "down"
+)
+
+// Incident severities
+const (
+	SeverityInfo     = "info"
+	SeverityMinor    = "minor"
+	SeverityMajor    = "major"
+	SeverityCritical = "critical"
+)
+
+// Component names
+const (
+	ComponentAPI      = "api"
+	ComponentDatabase = "database"
+	ComponentJobs     = "background_jobs"
+)
+
+// DefaultCacheTTL is how long a computed status is served before probing again
+const DefaultCacheTTL = 10 * time.Second
+
+// probeTimeout bounds each readiness probe
+const probeTimeout = 2 * time.Second
+
+// Incident is an operator-provided message shown on the status page
+type Incident struct {
+	Message   string     `json:"message"`
+	Severity  string     `json:"severity"`
+	SetAt     time.Time  `json:"set_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IncidentRequest represents the admin request to set an incident
+type IncidentRequest struct {
+	Message   string `json:"message" validate:"required"`
+	Severity  string `json:"severity" validate:"required,oneof=info minor major critical"`
+	ExpiresIn *int   `json:"expires_in_seconds,omitempty" validate:"omitempty,gt=0"`
+}
+
+// Validate checks an incident request
+func (r *IncidentRequest) Validate() error {
+	if r.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+	switch r.Severity {
+	case SeverityInfo, SeverityMinor, SeverityMajor, SeverityCritical:
+	default:
+		return fmt.Errorf("severity must be one of info, minor, major, critical")
+	}
+	if r.ExpiresIn != nil && *r.ExpiresIn <= 0 {
+		return fmt.Errorf("expires_in_seconds must be greater than 0")
+	}
+	return nil
+}
+
+// IncidentStore stores the current incident. RedisIncidentStore is used in
+// production so every API instance shows the same message.
+type IncidentStore interface {
+	Get(ctx context.Context) (*Incident, error)
+	Set(ctx context.Context, incident Incident, ttl time.Duration) error
+	Clear(ctx context.Context) error
+}
+
+// Probe is a named readiness check. Errors are never exposed to clients.
+type Probe struct {
+	Component string
+	Check     func(ctx context.Context) error
+	// Critical marks components whose failure takes the whole service down
+	Critical bool
+}
+
+// ComponentStatus is the public state of one component
+type ComponentStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// Response is the public status payload
+type Response struct {
+	Status     string            `json:"status"`
+	Components []ComponentStatus `json:"components"`
+	Incident   *Incident         `json:"incident,omitempty"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// Heartbeat records the last successful tick of the background job runner
+type Heartbeat struct {
+	mu   sync.RWMutex
+	last time.Time
+}
+
+// Tick records a successful run
+func (h *Heartbeat) Tick(at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = at
+}
+
+// Last returns the time of the last successful run
+func (h *Heartbeat) Last() time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.last
+}
+
+// Probe returns a probe that fails when no tick happened within maxAge
+func (h *Heartbeat) Probe(maxAge time.Duration, now func() time.Time) Probe {
+	return Probe{
+		Component: ComponentJobs,
+		Check: func(ctx context.Context) error {
+			last := h.Last()
+			if last.IsZero() || now().Sub(last) > maxAge {
+				return fmt.Errorf("no successful job tick since %s", last)
+			}
+			return nil
+		},
+	}
+}
+
+// Service computes and caches the public service status
+type Service struct {
+	probes   []Probe
+	store    IncidentStore
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	cached   *Response
+	cachedAt time.Time
+}
+
+// NewService creates a new status service
+func NewService(store IncidentStore, cacheTTL time.Duration, probes ...Probe) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{probes: probes, store: store, cacheTTL: cacheTTL, now: time.Now}
+}
+
+// Status returns the current status, serving a cached copy when it is fresh
+func (s *Service) Status(ctx context.Context) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.cached != nil && now.Sub(s.cachedAt) < s.cacheTTL {
+		return *s.cached
+	}
+
+	response := s.compute(ctx, now)
+	s.cached = &response
+	s.cachedAt = now
+	return response
+}
+
+// Invalidate drops the cached status, e.g. after an incident change
+func (s *Service) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = nil
+}
+
+// SetIncident stores an incident with an optional expiry
+func (s *Service) SetIncident(ctx context.Context, req IncidentRequest) (*Incident, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	incident := Incident{Message: req.Message, Severity: req.Severity, SetAt: s.now()}
+	var ttl time.Duration
+	if req.ExpiresIn != nil {
+		ttl = time.Duration(*req.ExpiresIn) * time.Second
+		expiresAt := incident.SetAt.Add(ttl)
+		incident.ExpiresAt = &expiresAt
+	}
+
+	if err := s.store.Set(ctx, incident, ttl); err != nil {
+		return nil, fmt.Errorf("failed to store incident: %w", err)
+	}
+	s.Invalidate()
+	return &incident, nil
+}
+
+// ClearIncident removes the current incident
+func (s *Service) ClearIncident(ctx context.Context) error {
+	if err := s.store.Clear(ctx); err != nil {
+		return fmt.Errorf("failed to clear incident: %w", err)
+	}
+	s.Invalidate()
+	return nil
+}
+
+// compute runs every probe and derives the overall state
+func (s *Service) compute(ctx context.Context, now time.Time) Response {
+	response := Response{
+		Status:     StateOperational,
+		Components: []ComponentStatus{{Name: ComponentAPI, State: StateOperational}},
+		CheckedAt:  now,
+	}
+
+	for _, probe := range s.probes {
+		probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		err := probe.Check(probeCtx)
+		cancel()
+
+		state := StateOperational
+		if err != nil {
+			state = StateDown
+			if probe.Critical {
+				response.Status = StateDown
+			} else if response.Status == StateOperational {
+				response.Status = StateDegraded
+			}
+		}
+		response.Components = append(response.Components, ComponentStatus{Name: probe.Component, State: state})
+	}
+
+	if s.store != nil {
+		if incident, err := s.store.Get(ctx); err == nil && incident != nil {
+			if incident.ExpiresAt == nil || now.Before(*incident.ExpiresAt) {
+				response.Incident = incident
+				if response.Status == StateOperational && (incident.Severity == SeverityMajor || incident.Severity == SeverityCritical) {
+					response.Status = StateDegraded
+				}
+			}
+		}
+	}
+
+	return response
+}
+
+// MemoryIncidentStore is an in-process IncidentStore
+type MemoryIncidentStore struct {
+	mu       sync.Mutex
+	incident *Incident
+	now      func() time.Time
+}
+
+// NewMemoryIncidentStore creates a new in-memory incident store
+func NewMemoryIncidentStore() *MemoryIncidentStore {
+	return &MemoryIncidentStore{now: time.Now}
+}
+
+// Get returns the current incident, or nil if none is set or it expired
+func (m *MemoryIncidentStore) Get(ctx context.Context) (*Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.incident == nil {
+		return nil, nil
+	}
+	if m.incident.ExpiresAt != nil && !m.now().Before(*m.incident.ExpiresAt) {
+		m.incident = nil
+		return nil, nil
+	}
+	incident := *m.incident
+	return &incident, nil
+}
+
+// Set stores the incident; ttl is reflected in the incident's ExpiresAt
+func (m *MemoryIncidentStore) Set(ctx context.Context, incident Incident, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.incident = &incident
+	return nil
+}
+
+// Clear removes the current incident
+func (m *MemoryIncidentStore) Clear(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.incident = nil
+	return nil
+}
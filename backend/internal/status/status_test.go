@@ -0,0 +1,141 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStatusCachesProbes(t *testing.T) {
+	calls := 0
+	dbErr := errors.New("dial tcp db.internal:5432: connection refused")
+	db := Probe{Component: ComponentDatabase, Critical: true, Check: func(ctx context.Context) error {
+		calls++
+		return dbErr
+	}}
+
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(NewMemoryIncidentStore(), DefaultCacheTTL, db)
+	service.now = func() time.Time { return now }
+
+	response := service.Status(context.Background())
+	if response.Status != StateDown {
+		t.Errorf("Expected status down, got %s", response.Status)
+	}
+	for _, component := range response.Components {
+		if strings.Contains(component.State, "db.internal") {
+			t.Error("Component state must not expose internal errors")
+		}
+	}
+
+	now = now.Add(5 * time.Second)
+	service.Status(context.Background())
+	if calls != 1 {
+		t.Errorf("Expected cached status within TTL, probes ran %d times", calls)
+	}
+
+	now = now.Add(10 * time.Second)
+	service.Status(context.Background())
+	if calls != 2 {
+		t.Errorf("Expected probes to rerun after TTL, ran %d times", calls)
+	}
+}
+
+func TestHeartbeatProbe(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	heartbeat := &Heartbeat{}
+	probe := heartbeat.Probe(5*time.Minute, func() time.Time { return now })
+
+	if err := probe.Check(context.Background()); err == nil {
+		t.Error("Expected failure before the first tick")
+	}
+	heartbeat.Tick(now.Add(-time.Minute))
+	if err := probe.Check(context.Background()); err != nil {
+		t.Errorf("Expected recent tick to pass, got %v", err)
+	}
+
+	service := NewService(nil, DefaultCacheTTL, probe)
+	service.now = func() time.Time { return now.Add(10 * time.Minute) }
+	heartbeat.Tick(now.Add(-time.Hour))
+	if response := service.Status(context.Background()); response.Status != StateDegraded {
+		t.Errorf("Stale job runner should degrade the service, got %s", response.Status)
+	}
+}
+
+func TestIncidents(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryIncidentStore()
+	store.now = func() time.Time { return now }
+	service := NewService(store, DefaultCacheTTL)
+	service.now = func() time.Time { return now }
+
+	if _, err := service.SetIncident(context.Background(), IncidentRequest{Message: "Bank sync delayed", Severity: "urgent"}); err == nil {
+		t.Error("Expected invalid severity to be rejected")
+	}
+
+	expiresIn := 60
+	if _, err := service.SetIncident(context.Background(), IncidentRequest{Message: "Bank sync delayed", Severity: SeverityMajor, ExpiresIn: &expiresIn}); err != nil {
+		t.Fatalf("SetIncident() error = %v", err)
+	}
+
+	response := service.Status(context.Background())
+	if response.Incident == nil || response.Status != StateDegraded {
+		t.Errorf("Expected a degraded status with the incident, got %+v", response)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if response := service.Status(context.Background()); response.Incident != nil {
+		t.Error("Expired incident should not be shown")
+	}
+
+	service.SetIncident(context.Background(), IncidentRequest{Message: "Maintenance tonight", Severity: SeverityInfo})
+	if err := service.ClearIncident(context.Background()); err != nil {
+		t.Fatalf("ClearIncident() error = %v", err)
+	}
+	if response := service.Status(context.Background()); response.Incident != nil || response.Status != StateOperational {
+		t.Errorf("Expected operational status after clearing, got %+v", response)
+	}
+}
+
+func TestRedisIncidentStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := NewRedisIncidentStore(client)
+	ctx := context.Background()
+
+	if incident, err := store.Get(ctx); err != nil || incident != nil {
+		t.Fatalf("Expected no incident, got %+v, %v", incident, err)
+	}
+
+	setAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.Set(ctx, Incident{Message: "Bank sync delayed", Severity: SeverityMajor, SetAt: setAt}, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	incident, err := store.Get(ctx)
+	if err != nil || incident == nil || incident.Message != "Bank sync delayed" || !incident.SetAt.Equal(setAt) {
+		t.Fatalf("Unexpected incident %+v, %v", incident, err)
+	}
+
+	server.FastForward(2 * time.Minute)
+	if incident, _ := store.Get(ctx); incident != nil {
+		t.Error("Expected the incident to expire with its ttl")
+	}
+
+	store.Set(ctx, Incident{Message: "Maintenance tonight", Severity: SeverityInfo}, 0)
+	server.FastForward(time.Hour)
+	if incident, _ := store.Get(ctx); incident == nil {
+		t.Error("Incidents without a ttl should not expire")
+	}
+	if err := store.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if incident, _ := store.Get(ctx); incident != nil {
+		t.Error("Expected no incident after clearing")
+	}
+}
@@ -0,0 +1,143 @@
+package usage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/middleware"
+)
+
+// DateFormat keys daily counters by UTC date
+const DateFormat = "2006-01-02"
+
+// DefaultBufferSize bounds queued usage events before new ones are dropped
+const DefaultBufferSize = 1024
+
+// Counter stores daily per-route counters. Visitors are salted hashes, never
+// raw user IDs.
+type Counter interface {
+	// Salt returns the salt for a day, creating it on first use. Salts
+	// expire shortly after the day ends so hashes cannot be linked later.
+	Salt(ctx context.Context, date string) (string, error)
+	Record(ctx context.Context, date, route, visitor string) error
+}
+
+type event struct {
+	at     time.Time
+	route  string
+	userID uuid.UUID
+}
+
+// Recorder counts unique users and requests per route. Recording never
+// blocks a request: events are queued and dropped when the queue is full.
+type Recorder struct {
+	counter Counter
+	enabled bool
+	events  chan event
+	dropped atomic.Int64
+	now     func() time.Time
+}
+
+// NewRecorder creates a usage recorder. When enabled is false nothing is recorded.
+func NewRecorder(counter Counter, enabled bool, bufferSize int) *Recorder {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Recorder{
+		counter: counter,
+		enabled: enabled,
+		events:  make(chan event, bufferSize),
+		now:     time.Now,
+	}
+}
+
+// Record queues a request by userID to route
+func (r *Recorder) Record(route string, userID uuid.UUID) {
+	if !r.enabled || userID == uuid.Nil {
+		return
+	}
+	select {
+	case r.events <- event{at: r.now().UTC(), route: route, userID: userID}:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events were dropped because the queue was full
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Run writes queued events to the counter until ctx is cancelled. Counter
+// errors are ignored; usage analytics are best effort.
+func (r *Recorder) Run(ctx context.Context) {
+	salts := make(map[string]string)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-r.events:
+			date := e.at.Format(DateFormat)
+			salt, ok := salts[date]
+			if !ok {
+				var err error
+				if salt, err = r.counter.Salt(ctx, date); err != nil {
+					continue
+				}
+				// Only the current day's salt is kept in memory
+				salts = map[string]string{date: salt}
+			}
+			r.counter.Record(ctx, date, e.route, HashVisitor(salt, e.userID))
+		}
+	}
+}
+
+// Middleware records authenticated requests after they are served
+func (r *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req)
+		if !r.enabled {
+			return
+		}
+		userID, err := middleware.GetUserIDFromContext(req.Context())
+		if err != nil {
+			return
+		}
+		r.Record(RouteName(req), userID)
+	})
+}
+
+// HashVisitor returns a salted hash of a user ID
+func HashVisitor(salt string, userID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write(userID[:])
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// idSegment matches path segments that identify a record
+var idSegment = regexp.MustCompile(`^([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9]+)$`)
+
+// RouteName returns the route of a request: the matched mux pattern when
+// there is one, otherwise the method and path with IDs replaced by {id}
+func RouteName(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+
+	segments := strings.Split(r.URL.Path, "/")
+	for i, segment := range segments {
+		if idSegment.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return r.Method + " " + strings.Join(segments, "/")
+}
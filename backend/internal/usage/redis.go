@@ -0,0 +1,93 @@
+package usage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/internal/models"
+)
+
+const (
+	// counterTTL keeps daily counters long enough for a late rollup
+	counterTTL = 8 * 24 * time.Hour
+	// saltTTL discards a day's salt once the day is over
+	saltTTL = 48 * time.Hour
+)
+
+// RedisStore keeps daily unique-user HyperLogLogs and request counts in Redis
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a Redis-backed usage store
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func saltKey(date string) string {
+	return "usage:salt:" + date
+}
+
+func visitorsKey(date, route string) string {
+	return "usage:visitors:" + date + ":" + route
+}
+
+func requestsKey(date string) string {
+	return "usage:requests:" + date
+}
+
+// Salt returns the random salt of a day, creating it on first use
+func (s *RedisStore) Salt(ctx context.Context, date string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	if err := s.client.SetNX(ctx, saltKey(date), hex.EncodeToString(buf), saltTTL).Err(); err != nil {
+		return "", err
+	}
+	return s.client.Get(ctx, saltKey(date)).Result()
+}
+
+// Record adds a visitor to the route's HyperLogLog and counts the request
+func (s *RedisStore) Record(ctx context.Context, date, route, visitor string) error {
+	pipe := s.client.Pipeline()
+	pipe.PFAdd(ctx, visitorsKey(date, route), visitor)
+	pipe.Expire(ctx, visitorsKey(date, route), counterTTL)
+	pipe.HIncrBy(ctx, requestsKey(date), route, 1)
+	pipe.Expire(ctx, requestsKey(date), counterTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DailyStats returns the counters of a day
+func (s *RedisStore) DailyStats(ctx context.Context, day time.Time) ([]models.UsageStat, error) {
+	date := day.UTC().Format(DateFormat)
+	requests, err := s.client.HGetAll(ctx, requestsKey(date)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.UsageStat, 0, len(requests))
+	for route, count := range requests {
+		var total int64
+		if _, err := fmt.Sscan(count, &total); err != nil {
+			continue
+		}
+		unique, err := s.client.PFCount(ctx, visitorsKey(date, route)).Result()
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, models.UsageStat{
+			Date:          startOfDay(day),
+			Route:         route,
+			UniqueUsers:   unique,
+			TotalRequests: total,
+		})
+	}
+	return stats, nil
+}
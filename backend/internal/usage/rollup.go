@@ -0,0 +1,65 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"tgfinance/internal/models"
+)
+
+// MaxQueryDays bounds the date range of a usage query
+const MaxQueryDays = 366
+
+// Source reads a day's counters
+type Source interface {
+	DailyStats(ctx context.Context, day time.Time) ([]models.UsageStat, error)
+}
+
+// Sink stores rolled up stats. Saving a day twice must replace its rows.
+type Sink interface {
+	SaveUsageStats(ctx context.Context, stats []models.UsageStat) error
+}
+
+// Rollup copies a day's counters into the usage_stats table
+func Rollup(ctx context.Context, source Source, sink Sink, day time.Time) (int, error) {
+	stats, err := source.DailyStats(ctx, day)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read usage counters: %w", err)
+	}
+	if len(stats) == 0 {
+		return 0, nil
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	if err := sink.SaveUsageStats(ctx, stats); err != nil {
+		return 0, fmt.Errorf("failed to save usage stats: %w", err)
+	}
+	return len(stats), nil
+}
+
+// RollupPreviousDay is the nightly job: it rolls up the UTC day before now
+func RollupPreviousDay(ctx context.Context, source Source, sink Sink, now time.Time) (int, error) {
+	return Rollup(ctx, source, sink, startOfDay(now).AddDate(0, 0, -1))
+}
+
+// ValidateFilter checks the date range of a usage query
+func ValidateFilter(filter *models.UsageFilter) error {
+	if filter.StartDate.IsZero() || filter.EndDate.IsZero() {
+		return fmt.Errorf("start_date and end_date are required")
+	}
+	if filter.EndDate.Before(filter.StartDate) {
+		return fmt.Errorf("end_date must not be before start_date")
+	}
+	if filter.EndDate.Sub(filter.StartDate) > MaxQueryDays*24*time.Hour {
+		return fmt.Errorf("date range must not exceed %d days", MaxQueryDays)
+	}
+	return nil
+}
+
+// startOfDay returns midnight UTC of t's UTC date
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
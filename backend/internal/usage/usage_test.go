@@ -0,0 +1,197 @@
+package usage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/internal/models"
+)
+
+type fakeCounter struct {
+	mu      sync.Mutex
+	records []string
+	done    chan struct{}
+}
+
+func (f *fakeCounter) Salt(ctx context.Context, date string) (string, error) {
+	return "salt-" + date, nil
+}
+
+func (f *fakeCounter) Record(ctx context.Context, date, route, visitor string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records = append(f.records, date+"|"+route+"|"+visitor)
+	if f.done != nil {
+		f.done <- struct{}{}
+	}
+	return nil
+}
+
+func authenticated(method, path string, userID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	return req.WithContext(context.WithValue(req.Context(), "user_id", userID.String()))
+}
+
+func TestRecorderMiddleware(t *testing.T) {
+	counter := &fakeCounter{done: make(chan struct{}, 1)}
+	recorder := NewRecorder(counter, true, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go recorder.Run(ctx)
+
+	userID := uuid.New()
+	handler := recorder.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), authenticated(http.MethodGet, "/api/v1/goals/"+uuid.NewString()+"/ledger", userID))
+
+	select {
+	case <-counter.done:
+	case <-time.After(time.Second):
+		t.Fatal("event was not recorded")
+	}
+
+	record := counter.records[0]
+	if !strings.Contains(record, "|GET /api/v1/goals/{id}/ledger|") {
+		t.Errorf("record = %q, want normalized route", record)
+	}
+	if strings.Contains(record, userID.String()) {
+		t.Error("raw user ID must not be stored")
+	}
+
+	// Unauthenticated requests are not counted
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	select {
+	case <-counter.done:
+		t.Error("unauthenticated request was recorded")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestRecorderNeverBlocks(t *testing.T) {
+	recorder := NewRecorder(&fakeCounter{}, true, 2)
+
+	finished := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			recorder.Record("GET /api/v1/expenses", uuid.New())
+		}
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked without a running worker")
+	}
+	if recorder.Dropped() != 3 {
+		t.Errorf("dropped = %d, want 3", recorder.Dropped())
+	}
+}
+
+func TestRecorderOptOut(t *testing.T) {
+	recorder := NewRecorder(&fakeCounter{}, false, 1)
+	recorder.Record("GET /api/v1/expenses", uuid.New())
+	recorder.Record("GET /api/v1/expenses", uuid.New())
+	if len(recorder.events) != 0 || recorder.Dropped() != 0 {
+		t.Error("disabled recorder should ignore events")
+	}
+}
+
+func TestHashVisitorRotatesWithSalt(t *testing.T) {
+	userID := uuid.New()
+	if HashVisitor("a", userID) != HashVisitor("a", userID) {
+		t.Error("hash should be stable within a day")
+	}
+	if HashVisitor("a", userID) == HashVisitor("b", userID) {
+		t.Error("hash should change with the salt")
+	}
+}
+
+type fakeSink struct {
+	saved []models.UsageStat
+}
+
+func (f *fakeSink) SaveUsageStats(ctx context.Context, stats []models.UsageStat) error {
+	f.saved = append(f.saved, stats...)
+	return nil
+}
+
+func TestRedisStoreRollup(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	date := "2026-04-01"
+	salt, err := store.Salt(ctx, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := store.Salt(ctx, date); again != salt {
+		t.Error("salt should be stable for a day")
+	}
+
+	alice, bob := uuid.New(), uuid.New()
+	for _, visit := range []struct {
+		route string
+		user  uuid.UUID
+	}{
+		{"GET /api/v1/expenses", alice},
+		{"GET /api/v1/expenses", alice},
+		{"GET /api/v1/expenses", bob},
+		{"POST /api/v1/goals", bob},
+	} {
+		if err := store.Record(ctx, date, visit.route, HashVisitor(salt, visit.user)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink := &fakeSink{}
+	now := time.Date(2026, 4, 2, 1, 0, 0, 0, time.UTC)
+	count, err := RollupPreviousDay(ctx, store, sink, now)
+	if err != nil || count != 2 {
+		t.Fatalf("RollupPreviousDay() = %d, %v", count, err)
+	}
+
+	expenses := sink.saved[0]
+	if expenses.Route != "GET /api/v1/expenses" || expenses.UniqueUsers != 2 || expenses.TotalRequests != 3 {
+		t.Errorf("expenses stat = %+v", expenses)
+	}
+	if !expenses.Date.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("stat date = %v", expenses.Date)
+	}
+	if goals := sink.saved[1]; goals.UniqueUsers != 1 || goals.TotalRequests != 1 {
+		t.Errorf("goals stat = %+v", goals)
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		filter  models.UsageFilter
+		wantErr bool
+	}{
+		{"valid", models.UsageFilter{StartDate: start, EndDate: start.AddDate(0, 1, 0)}, false},
+		{"missing", models.UsageFilter{StartDate: start}, true},
+		{"reversed", models.UsageFilter{StartDate: start, EndDate: start.AddDate(0, 0, -1)}, true},
+		{"too long", models.UsageFilter{StartDate: start, EndDate: start.AddDate(2, 0, 0)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateFilter(&tt.filter); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
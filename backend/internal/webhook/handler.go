@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+
+	"tgfinance/pkg/httputil"
+)
+
+// Handler serves the webhook developer endpoints
+type Handler struct {
+	registry *Registry
+}
+
+// NewHandler creates a new webhook handler
+func NewHandler(registry *Registry) *Handler {
+	return &Handler{registry: registry}
+}
+
+// EventSamples handles GET /api/v1/webhooks/event-samples, returning an
+// example delivery per event type and schema version. The type and version
+// query parameters narrow the samples.
+func (h *Handler) EventSamples(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	eventType := query.Get("type")
+	if eventType != "" && len(h.registry.Versions(eventType)) == 0 {
+		httputil.WriteError(w, http.StatusNotFound, httputil.CodeForStatus(http.StatusNotFound), "Unknown event type")
+		return
+	}
+	version := 0
+	if raw := query.Get("version"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			httputil.WriteError(w, http.StatusBadRequest, httputil.CodeForStatus(http.StatusBadRequest), "Invalid version")
+			return
+		}
+		version = v
+	}
+
+	samples, err := h.registry.Samples(eventType, version)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, httputil.CodeForStatus(http.StatusInternalServerError), "Failed to build event samples")
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"samples": samples})
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types delivered to webhooks
+const (
+	EventExpenseCreated    = "expense.created"
+	EventExpenseUpdated    = "expense.updated"
+	EventExpenseDeleted    = "expense.deleted"
+	EventGoalCompleted     = "goal.completed"
+	EventInvestmentMatured = "investment.matured"
+)
+
+// LatestSchemaVersion is the payload version new subscriptions receive
+const LatestSchemaVersion = 1
+
+// ErrNoBuilder is returned for events without a payload builder for the
+// requested schema version
+var ErrNoBuilder = errors.New("no payload builder for event")
+
+// Event is an outbox event awaiting delivery. Data holds the internal model
+// the event was recorded with.
+type Event struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       string          `json:"type"`
+	UserID     uuid.UUID       `json:"user_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Delivery is the envelope posted to webhook endpoints
+type Delivery struct {
+	ID            uuid.UUID   `json:"id"`
+	Type          string      `json:"type"`
+	SchemaVersion int         `json:"schema_version"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+	Data          interface{} `json:"data"`
+}
+
+// Sample is an example delivery of an event type and schema version
+type Sample struct {
+	Type          string    `json:"type"`
+	SchemaVersion int       `json:"schema_version"`
+	Payload       *Delivery `json:"payload"`
+}
+
+// Builder projects the internal model of an event onto the stable payload
+// of one schema version
+type Builder func(data json.RawMessage) (interface{}, error)
+
+type schema struct {
+	build  Builder
+	sample interface{}
+}
+
+// Registry holds the payload builders of every event type and schema
+// version. Payloads are only ever produced by a builder, so a model gaining
+// a field never changes what consumers receive.
+type Registry struct {
+	schemas map[string]map[int]schema
+}
+
+// NewRegistry creates an empty payload registry
+func NewRegistry() *Registry {
+	return &Registry{schemas: make(map[string]map[int]schema)}
+}
+
+// DefaultRegistry returns the registry of every supported payload
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	registerV1(r)
+	return r
+}
+
+// Register adds the builder of an event type and schema version. sample is
+// an internal model used to produce the example payload.
+func (r *Registry) Register(eventType string, version int, build Builder, sample interface{}) {
+	if r.schemas[eventType] == nil {
+		r.schemas[eventType] = make(map[int]schema)
+	}
+	r.schemas[eventType][version] = schema{build: build, sample: sample}
+}
+
+// Supports returns true if the event type has a builder for version
+func (r *Registry) Supports(eventType string, version int) bool {
+	_, ok := r.schemas[eventType][version]
+	return ok
+}
+
+// EventTypes returns the registered event types in order
+func (r *Registry) EventTypes() []string {
+	types := make([]string, 0, len(r.schemas))
+	for t := range r.schemas {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Versions returns the schema versions of an event type in order
+func (r *Registry) Versions(eventType string) []int {
+	versions := make([]int, 0, len(r.schemas[eventType]))
+	for v := range r.schemas[eventType] {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Build produces the delivery of an event in a schema version
+func (r *Registry) Build(event Event, version int) (*Delivery, error) {
+	s, ok := r.schemas[event.Type][version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s v%d", ErrNoBuilder, event.Type, version)
+	}
+	data, err := s.build(event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s v%d payload: %w", event.Type, version, err)
+	}
+	return &Delivery{
+		ID:            event.ID,
+		Type:          event.Type,
+		SchemaVersion: version,
+		OccurredAt:    event.OccurredAt,
+		Data:          data,
+	}, nil
+}
+
+// Samples returns example deliveries, optionally restricted to one event
+// type and one version (0 for all)
+func (r *Registry) Samples(eventType string, version int) ([]Sample, error) {
+	samples := []Sample{}
+	for _, t := range r.EventTypes() {
+		if eventType != "" && t != eventType {
+			continue
+		}
+		for _, v := range r.Versions(t) {
+			if version != 0 && v != version {
+				continue
+			}
+			delivery, err := r.Build(sampleEvent(t, r.schemas[t][v].sample), v)
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, Sample{Type: t, SchemaVersion: v, Payload: delivery})
+		}
+	}
+	return samples, nil
+}
+
+// sampleEvent wraps a sample model in an event with fixed identifiers, so
+// samples are stable across calls
+func sampleEvent(eventType string, model interface{}) Event {
+	data, _ := json.Marshal(model)
+	return Event{
+		ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte("tgfinance:sample:"+eventType)),
+		Type:       eventType,
+		UserID:     sampleUserID,
+		OccurredAt: sampleTime,
+		Data:       data,
+	}
+}
@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/utils"
+)
+
+// ValidateSubscriptionRequest validates a webhook subscription and defaults
+// its payload version to the latest. Every subscribed event type must have a
+// builder for the chosen version.
+func (r *Registry) ValidateSubscriptionRequest(req *models.WebhookSubscriptionRequest) utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		errs.Add("url", "url must be an absolute http or https URL")
+	}
+
+	if req.PayloadVersion == nil {
+		version := LatestSchemaVersion
+		req.PayloadVersion = &version
+	}
+
+	if len(req.EventTypes) == 0 {
+		errs.Add("event_types", "at least one event type is required")
+	}
+	for _, eventType := range req.EventTypes {
+		if len(r.Versions(eventType)) == 0 {
+			errs.Add("event_types", fmt.Sprintf("unknown event type %q", eventType))
+		} else if !r.Supports(eventType, *req.PayloadVersion) {
+			errs.Add("payload_version", fmt.Sprintf("%s has no payload version %d", eventType, *req.PayloadVersion))
+		}
+	}
+	return errs
+}
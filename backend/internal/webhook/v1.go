@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+)
+
+// dateLayout formats calendar dates in payloads
+const dateLayout = "2006-01-02"
+
+// ExpenseV1 is the v1 payload of expense.created and expense.updated
+type ExpenseV1 struct {
+	ID            uuid.UUID `json:"id"`
+	CategoryID    uuid.UUID `json:"category_id"`
+	Amount        float64   `json:"amount"`
+	Description   string    `json:"description"`
+	ExpenseDate   string    `json:"expense_date"`
+	PaymentMethod *string   `json:"payment_method"`
+	Location      *string   `json:"location"`
+	Tags          []string  `json:"tags"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ExpenseDeletedV1 is the v1 payload of expense.deleted
+type ExpenseDeletedV1 struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// GoalCompletedV1 is the v1 payload of goal.completed
+type GoalCompletedV1 struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	GoalType      string    `json:"goal_type"`
+	TargetAmount  float64   `json:"target_amount"`
+	CurrentAmount float64   `json:"current_amount"`
+	CompletedAt   time.Time `json:"completed_at"`
+}
+
+// InvestmentMaturedV1 is the v1 payload of investment.matured
+type InvestmentMaturedV1 struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Amount       float64   `json:"amount"`
+	CurrentValue *float64  `json:"current_value"`
+	InterestRate *float64  `json:"interest_rate"`
+	MaturityDate *string   `json:"maturity_date"`
+	Institution  *string   `json:"institution"`
+}
+
+// registerV1 registers the v1 builders of every event type
+func registerV1(r *Registry) {
+	r.Register(EventExpenseCreated, 1, builder(expenseV1), sampleExpense)
+	r.Register(EventExpenseUpdated, 1, builder(expenseV1), sampleExpense)
+	r.Register(EventExpenseDeleted, 1, builder(func(e *models.Expense) interface{} {
+		return ExpenseDeletedV1{ID: e.ID}
+	}), sampleExpense)
+	r.Register(EventGoalCompleted, 1, builder(func(g *models.FinancialGoal) interface{} {
+		return GoalCompletedV1{
+			ID:            g.ID,
+			Name:          g.Name,
+			GoalType:      g.GoalType,
+			TargetAmount:  g.TargetAmount,
+			CurrentAmount: g.CurrentAmount,
+			CompletedAt:   g.UpdatedAt,
+		}
+	}), sampleGoal)
+	r.Register(EventInvestmentMatured, 1, builder(func(i *models.Investment) interface{} {
+		payload := InvestmentMaturedV1{
+			ID:           i.ID,
+			Name:         i.Name,
+			Amount:       i.Amount,
+			CurrentValue: i.CurrentValue,
+			InterestRate: i.InterestRate,
+			Institution:  i.Institution,
+		}
+		if i.EndDate != nil {
+			date := i.EndDate.Format(dateLayout)
+			payload.MaturityDate = &date
+		}
+		return payload
+	}), sampleInvestment)
+}
+
+// expenseV1 projects an expense onto its v1 payload
+func expenseV1(e *models.Expense) interface{} {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return ExpenseV1{
+		ID:            e.ID,
+		CategoryID:    e.CategoryID,
+		Amount:        e.Amount,
+		Description:   e.Description,
+		ExpenseDate:   e.ExpenseDate.Format(dateLayout),
+		PaymentMethod: e.PaymentMethod,
+		Location:      e.Location,
+		Tags:          tags,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+}
+
+// builder decodes the internal model M of an event and projects it
+func builder[M any](project func(*M) interface{}) Builder {
+	return func(data json.RawMessage) (interface{}, error) {
+		var model M
+		if err := json.Unmarshal(data, &model); err != nil {
+			return nil, fmt.Errorf("failed to decode event data: %w", err)
+		}
+		return project(&model), nil
+	}
+}
+
+// Fixed models behind the example payloads
+var (
+	sampleTime   = time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+	sampleUserID = uuid.MustParse("7a4c5b1e-0f2d-4c3b-9a8e-1d2c3b4a5f60")
+
+	sampleExpense = models.Expense{
+		ID:            uuid.MustParse("0b9e7c6d-5a4f-4e3d-8c2b-1a0f9e8d7c6b"),
+		UserID:        sampleUserID,
+		CategoryID:    uuid.MustParse("3c2b1a0f-9e8d-4c7b-a6f5-e4d3c2b1a0f9"),
+		Amount:        1249.5,
+		Description:   "Groceries",
+		ExpenseDate:   time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC),
+		PaymentMethod: stringPtr("upi"),
+		Tags:          []string{"household"},
+		MyShare:       1249.5,
+		CreatedAt:     sampleTime,
+		UpdatedAt:     sampleTime,
+		Version:       1,
+	}
+
+	sampleGoal = models.FinancialGoal{
+		ID:            uuid.MustParse("5e4d3c2b-1a0f-4e9d-8c7b-6a5f4e3d2c1b"),
+		UserID:        sampleUserID,
+		Name:          "Emergency fund",
+		TargetAmount:  300000,
+		CurrentAmount: 300000,
+		GoalType:      models.GoalTypeEmergencyFund,
+		Priority:      models.GoalPriorityHigh,
+		Status:        models.GoalStatusCompleted,
+		CreatedAt:     sampleTime.AddDate(-1, 0, 0),
+		UpdatedAt:     sampleTime,
+		Version:       12,
+	}
+
+	sampleInvestment = models.Investment{
+		ID:           uuid.MustParse("9d8c7b6a-5f4e-4d3c-b2a1-0f9e8d7c6b5a"),
+		UserID:       sampleUserID,
+		TypeID:       uuid.MustParse("1f0e9d8c-7b6a-4f5e-8d3c-2b1a0f9e8d7c"),
+		Name:         "SBI fixed deposit",
+		Amount:       100000,
+		CurrentValue: floatPtr(107100),
+		StartDate:    time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:      timePtr(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)),
+		InterestRate: floatPtr(7.1),
+		Institution:  stringPtr("State Bank of India"),
+		Status:       models.InvestmentStatusMatured,
+		CreatedAt:    time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:    sampleTime,
+		Version:      3,
+	}
+)
+
+func stringPtr(s string) *string     { return &s }
+func floatPtr(f float64) *float64    { return &f }
+func timePtr(t time.Time) *time.Time { return &t }
@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/logger"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden payload files")
+
+// TestPayloadGolden locks the shape of every payload. A failure means a
+// payload changed for existing consumers: add a new schema version instead
+// of editing a released one.
+func TestPayloadGolden(t *testing.T) {
+	registry := DefaultRegistry()
+	samples, err := registry.Samples("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != len(registry.EventTypes()) {
+		t.Fatalf("Expected one sample per event type, got %d", len(samples))
+	}
+
+	for _, sample := range samples {
+		name := fmt.Sprintf("%s.v%d.json", sample.Type, sample.SchemaVersion)
+		t.Run(name, func(t *testing.T) {
+			got, err := json.MarshalIndent(sample.Payload, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", name)
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Missing golden file, run go test -update: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Payload changed:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPayloadIgnoresNewModelFields(t *testing.T) {
+	registry := DefaultRegistry()
+	data, _ := json.Marshal(map[string]interface{}{
+		"id": uuid.New(), "amount": 10, "expense_date": "2024-05-31T00:00:00Z",
+		"import_id": uuid.New(), "some_future_field": true,
+	})
+	delivery, err := registry.Build(Event{ID: uuid.New(), Type: EventExpenseCreated, Data: data}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(delivery.Data)
+	if strings.Contains(string(body), "import_id") || strings.Contains(string(body), "some_future_field") {
+		t.Errorf("Payload leaked model fields: %s", body)
+	}
+	if delivery.SchemaVersion != 1 {
+		t.Errorf("schema_version = %d, want 1", delivery.SchemaVersion)
+	}
+}
+
+func TestWorkerDeliversSubscribedVersion(t *testing.T) {
+	var received map[string]interface{}
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	worker := NewWorker(DefaultRegistry(), server.Client(), nil)
+	sub := &models.WebhookSubscription{ID: uuid.New(), URL: server.URL, PayloadVersion: 1}
+	event := sampleEvent(EventGoalCompleted, sampleGoal)
+
+	status, err := worker.Deliver(context.Background(), sub, event)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Deliver = %d, %v", status, err)
+	}
+	if received["schema_version"] != float64(1) || received["type"] != EventGoalCompleted {
+		t.Errorf("Unexpected delivery %v", received)
+	}
+	if headers.Get(HeaderSchemaVersion) != "1" || headers.Get(HeaderEvent) != EventGoalCompleted || headers.Get(HeaderDelivery) != event.ID.String() {
+		t.Errorf("Unexpected headers %v", headers)
+	}
+}
+
+func TestWorkerRefusesEventsWithoutBuilder(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	worker := NewWorker(DefaultRegistry(), server.Client(), log)
+
+	for _, tc := range []struct {
+		eventType string
+		version   int
+	}{
+		{eventType: "budget.exceeded", version: 1},
+		{eventType: EventExpenseCreated, version: 2},
+	} {
+		sub := &models.WebhookSubscription{ID: uuid.New(), URL: server.URL, PayloadVersion: tc.version}
+		event := Event{ID: uuid.New(), Type: tc.eventType, Data: json.RawMessage(`{"id":"x"}`)}
+		if _, err := worker.Deliver(context.Background(), sub, event); !errors.Is(err, ErrNoBuilder) {
+			t.Errorf("%s v%d: expected ErrNoBuilder, got %v", tc.eventType, tc.version, err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("Expected no requests, got %d", calls)
+	}
+
+	var entry map[string]interface{}
+	line, _ := logs.ReadBytes('\n')
+	if err := json.Unmarshal(line, &entry); err != nil || entry["level"] != "error" || !strings.Contains(fmt.Sprint(entry["msg"]), "budget.exceeded") {
+		t.Errorf("Expected an error log naming the event type, got %s", line)
+	}
+}
+
+func TestWorkerReportsFailedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	worker := NewWorker(DefaultRegistry(), server.Client(), nil)
+	sub := &models.WebhookSubscription{URL: server.URL, PayloadVersion: 1}
+	status, err := worker.Deliver(context.Background(), sub, sampleEvent(EventExpenseDeleted, sampleExpense))
+	if err == nil || status != http.StatusServiceUnavailable {
+		t.Errorf("Deliver = %d, %v, want 503 error", status, err)
+	}
+}
+
+func TestValidateSubscriptionRequest(t *testing.T) {
+	registry := DefaultRegistry()
+
+	req := &models.WebhookSubscriptionRequest{URL: "https://example.com/hook", EventTypes: []string{EventExpenseCreated}}
+	if errs := registry.ValidateSubscriptionRequest(req); errs.HasErrors() {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if *req.PayloadVersion != LatestSchemaVersion {
+		t.Errorf("payload_version = %d, want latest", *req.PayloadVersion)
+	}
+
+	version := 2
+	req = &models.WebhookSubscriptionRequest{URL: "ftp://example.com", EventTypes: []string{EventExpenseCreated, "budget.exceeded"}, PayloadVersion: &version}
+	errs := registry.ValidateSubscriptionRequest(req)
+	fields := make(map[string]bool)
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	if len(errs) != 3 || !fields["url"] || !fields["event_types"] || !fields["payload_version"] {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+}
+
+func TestEventSamplesHandler(t *testing.T) {
+	handler := NewHandler(DefaultRegistry())
+
+	rec := httptest.NewRecorder()
+	handler.EventSamples(rec, httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/event-samples?type=expense.created&version=1", nil))
+	var body struct {
+		Samples []Sample `json:"samples"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %v", rec.Code, err)
+	}
+	if len(body.Samples) != 1 || body.Samples[0].Type != EventExpenseCreated || body.Samples[0].Payload.SchemaVersion != 1 {
+		t.Errorf("Unexpected samples %+v", body.Samples)
+	}
+
+	for query, want := range map[string]int{"?type=budget.exceeded": http.StatusNotFound, "?version=abc": http.StatusBadRequest, "": http.StatusOK} {
+		rec := httptest.NewRecorder()
+		handler.EventSamples(rec, httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/event-samples"+query, nil))
+		if rec.Code != want {
+			t.Errorf("%q = %d, want %d", query, rec.Code, want)
+		}
+	}
+}
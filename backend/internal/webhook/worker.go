@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"tgfinance/internal/models"
+	"tgfinance/pkg/logger"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent         = "X-TGFinance-Event"
+	HeaderDelivery      = "X-TGFinance-Delivery"
+	HeaderSchemaVersion = "X-TGFinance-Schema-Version"
+)
+
+// Worker posts events to webhook subscriptions
+type Worker struct {
+	registry *Registry
+	client   *http.Client
+	logger   *logger.Logger
+}
+
+// NewWorker creates a new webhook delivery worker
+func NewWorker(registry *Registry, client *http.Client, log *logger.Logger) *Worker {
+	return &Worker{registry: registry, client: client, logger: log}
+}
+
+// Deliver posts an event to a subscription in the payload version the
+// subscription asked for, and returns the status the endpoint answered with,
+// or 0 if there was no response. Events without a builder for that version
+// are refused and logged as errors rather than sent as raw models.
+func (w *Worker) Deliver(ctx context.Context, sub *models.WebhookSubscription, event Event) (int, error) {
+	delivery, err := w.registry.Build(event, sub.PayloadVersion)
+	if err != nil {
+		if errors.Is(err, ErrNoBuilder) && w.logger != nil {
+			w.logger.WithError(err).Errorf("Refusing to deliver event %s of type %s to webhook %s: no payload builder for schema version %d",
+				event.ID, event.Type, sub.ID, sub.PayloadVersion)
+		}
+		return 0, err
+	}
+
+	body, err := json.Marshal(delivery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode delivery: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create delivery request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, event.ID.String())
+	req.Header.Set(HeaderSchemaVersion, strconv.Itoa(delivery.SchemaVersion))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deliver event: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,53 @@
+-- Expense splitting across participants
+-- Adds per-participant shares, mirrored expenses for linked users and income entries for reimbursements
+
+-- The owner's share of each expense; equals amount for expenses without splits.
+-- Summaries and budgets aggregate my_share instead of amount.
+ALTER TABLE expenses ADD COLUMN my_share DECIMAL(10,2);
+UPDATE expenses SET my_share = amount;
+ALTER TABLE expenses ALTER COLUMN my_share SET NOT NULL;
+
+-- Income entries (salary, reimbursements of split expenses, ...)
+CREATE TABLE incomes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL,
+    source VARCHAR(50) NOT NULL CHECK (source IN ('salary', 'reimbursement', 'other')),
+    description TEXT,
+    income_date DATE NOT NULL,
+    split_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Expense splits table
+CREATE TABLE expense_splits (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    participant_name VARCHAR(100),
+    participant_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    share_amount DECIMAL(10,2) NOT NULL CHECK (share_amount > 0),
+    share_percentage DECIMAL(5,2),
+    is_owner BOOLEAN NOT NULL DEFAULT FALSE,
+    settled BOOLEAN NOT NULL DEFAULT FALSE,
+    settled_at TIMESTAMP WITH TIME ZONE,
+    reimbursement_id UUID REFERENCES incomes(id) ON DELETE SET NULL,
+    mirrored_expense_id UUID REFERENCES expenses(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (is_owner OR participant_name IS NOT NULL OR participant_user_id IS NOT NULL)
+);
+
+ALTER TABLE incomes ADD CONSTRAINT fk_incomes_split_id FOREIGN KEY (split_id) REFERENCES expense_splits(id) ON DELETE SET NULL;
+
+-- Mirrored expenses created on a linked participant's side await acceptance
+ALTER TABLE expenses ADD COLUMN source_split_id UUID REFERENCES expense_splits(id) ON DELETE CASCADE;
+ALTER TABLE expenses ADD COLUMN mirror_status VARCHAR(20) CHECK (mirror_status IN ('pending', 'accepted', 'rejected'));
+
+CREATE INDEX idx_expense_splits_expense_id ON expense_splits(expense_id);
+CREATE INDEX idx_expense_splits_participant_user_id ON expense_splits(participant_user_id);
+CREATE INDEX idx_incomes_user_id ON incomes(user_id);
+CREATE INDEX idx_incomes_date ON incomes(income_date);
+
+CREATE TRIGGER update_expense_splits_updated_at BEFORE UPDATE ON expense_splits FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_incomes_updated_at BEFORE UPDATE ON incomes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
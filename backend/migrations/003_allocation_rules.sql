@@ -0,0 +1,29 @@
+-- Savings auto-allocation rules
+-- Rules contribute a share of recorded income (or a fixed amount on a schedule) to a goal
+
+CREATE TABLE allocation_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    goal_id UUID NOT NULL REFERENCES financial_goals(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    trigger VARCHAR(20) NOT NULL CHECK (trigger IN ('income_created', 'schedule')),
+    schedule VARCHAR(100),
+    percentage DECIMAL(5,2) CHECK (percentage > 0 AND percentage <= 100),
+    fixed_amount DECIMAL(12,2) CHECK (fixed_amount > 0),
+    priority INTEGER NOT NULL DEFAULT 0 CHECK (priority >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((percentage IS NULL) <> (fixed_amount IS NULL)),
+    CHECK (trigger <> 'schedule' OR schedule IS NOT NULL)
+);
+
+-- Record which rule and income produced each automatic contribution
+ALTER TABLE goal_contributions ADD COLUMN rule_id UUID REFERENCES allocation_rules(id) ON DELETE SET NULL;
+ALTER TABLE goal_contributions ADD COLUMN income_id UUID REFERENCES incomes(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_allocation_rules_user_id ON allocation_rules(user_id);
+CREATE INDEX idx_allocation_rules_goal_id ON allocation_rules(goal_id);
+CREATE INDEX idx_goal_contributions_rule_id ON goal_contributions(rule_id);
+
+CREATE TRIGGER update_allocation_rules_updated_at BEFORE UPDATE ON allocation_rules FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,11 @@
+-- Investment fees and expense ratio tracking
+
+-- Annual expense ratio as a percentage (e.g. 0.75 for 0.75%)
+ALTER TABLE investments ADD COLUMN expense_ratio DECIMAL(7,4) CHECK (expense_ratio >= 0 AND expense_ratio <= 100);
+
+-- Allow fee transactions
+ALTER TABLE investment_transactions DROP CONSTRAINT investment_transactions_transaction_type_check;
+ALTER TABLE investment_transactions ADD CONSTRAINT investment_transactions_transaction_type_check
+    CHECK (transaction_type IN ('deposit', 'withdrawal', 'interest', 'dividend', 'fee'));
+
+CREATE INDEX idx_investment_transactions_investment_id ON investment_transactions(investment_id);
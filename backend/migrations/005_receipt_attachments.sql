@@ -0,0 +1,24 @@
+-- Receipt attachments with asynchronous OCR extraction
+
+CREATE TABLE receipt_attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expense_id UUID REFERENCES expenses(id) ON DELETE SET NULL,
+    storage_key TEXT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    ocr_status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (ocr_status IN ('pending', 'processing', 'completed', 'ocr_failed', 'skipped')),
+    ocr_result JSONB,
+    ocr_error TEXT,
+    ocr_attempts INTEGER NOT NULL DEFAULT 0,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_receipt_attachments_user_id ON receipt_attachments(user_id);
+CREATE INDEX idx_receipt_attachments_expense_id ON receipt_attachments(expense_id);
+CREATE INDEX idx_receipt_attachments_ocr_status ON receipt_attachments(ocr_status);
+
+CREATE TRIGGER update_receipt_attachments_updated_at BEFORE UPDATE ON receipt_attachments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,50 @@
+-- Data integrity reports and audit log for repairs
+
+CREATE TABLE integrity_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('user', 'global')),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    checks TEXT[] NOT NULL,
+    repair BOOLEAN NOT NULL DEFAULT false,
+    users_checked INTEGER NOT NULL DEFAULT 0,
+    discrepancy_count INTEGER NOT NULL DEFAULT 0,
+    repaired_count INTEGER NOT NULL DEFAULT 0,
+    by_severity JSONB NOT NULL DEFAULT '{}',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE integrity_discrepancies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    report_id UUID NOT NULL REFERENCES integrity_reports(id) ON DELETE CASCADE,
+    check_name VARCHAR(100) NOT NULL,
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('info', 'warning', 'critical')),
+    user_id UUID NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID,
+    expected DECIMAL(15,2),
+    actual DECIMAL(15,2),
+    message TEXT NOT NULL,
+    repairable BOOLEAN NOT NULL DEFAULT false,
+    repaired BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE audit_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(100) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID,
+    old_value JSONB,
+    new_value JSONB,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_integrity_reports_started_at ON integrity_reports(started_at);
+CREATE INDEX idx_integrity_discrepancies_report_id ON integrity_discrepancies(report_id);
+CREATE INDEX idx_integrity_discrepancies_check_name ON integrity_discrepancies(check_name);
+CREATE INDEX idx_audit_logs_entity ON audit_logs(entity_type, entity_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
@@ -0,0 +1,7 @@
+-- Budget pro-rating for mid-period starts and carryover between periods
+
+ALTER TABLE budgets
+    ADD COLUMN pro_rate BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN carryover_mode VARCHAR(20) NOT NULL DEFAULT 'none' CHECK (carryover_mode IN ('none', 'underspend', 'overspend', 'both')),
+    ADD COLUMN carryover_cap DECIMAL(10,2) CHECK (carryover_cap >= 0);
+
@@ -0,0 +1,12 @@
+-- Closing investment positions with realized gains
+
+ALTER TABLE investments DROP CONSTRAINT investments_status_check;
+ALTER TABLE investments ADD CONSTRAINT investments_status_check
+    CHECK (status IN ('active', 'matured', 'cancelled', 'closed'));
+
+ALTER TABLE investments
+    ADD COLUMN realized_gain DECIMAL(12,2),
+    ADD COLUMN closed_at DATE,
+    ADD COLUMN closing_transaction_id UUID REFERENCES investment_transactions(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_investments_status ON investments(status);
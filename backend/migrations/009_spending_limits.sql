@@ -0,0 +1,23 @@
+-- Per-category and per-merchant spending limits
+
+CREATE TABLE spending_limits (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('category', 'merchant')),
+    category_id UUID REFERENCES expense_categories(id) ON DELETE CASCADE,
+    merchant_pattern VARCHAR(255),
+    per_transaction_max DECIMAL(10,2) CHECK (per_transaction_max > 0),
+    daily_max DECIMAL(10,2) CHECK (daily_max > 0),
+    action VARCHAR(10) NOT NULL CHECK (action IN ('warn', 'block')),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (per_transaction_max IS NOT NULL OR daily_max IS NOT NULL),
+    CHECK ((scope = 'category') = (category_id IS NOT NULL)),
+    CHECK ((scope = 'merchant') = (merchant_pattern IS NOT NULL))
+);
+
+CREATE INDEX idx_spending_limits_user_id ON spending_limits(user_id);
+
+CREATE TRIGGER update_spending_limits_updated_at BEFORE UPDATE ON spending_limits FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
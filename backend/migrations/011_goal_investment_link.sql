@@ -0,0 +1,8 @@
+-- Goals funded by a linked investment
+
+ALTER TABLE financial_goals
+    ADD COLUMN linked_investment_id UUID REFERENCES investments(id) ON DELETE SET NULL,
+    ADD COLUMN sync_percentage DECIMAL(5,2) CHECK (sync_percentage > 0 AND sync_percentage <= 100),
+    ADD COLUMN last_synced_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_financial_goals_linked_investment_id ON financial_goals(linked_investment_id) WHERE linked_investment_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Row versions for optimistic locking of concurrent updates
+
+ALTER TABLE expenses ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE investments ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE financial_goals ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- Locale and base currency preferences for server-side formatting
+
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(20) NOT NULL DEFAULT 'en',
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
@@ -0,0 +1,8 @@
+-- Record who made each balance-changing mutation so ledgers can attribute it
+
+ALTER TABLE goal_contributions ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE investment_transactions ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE audit_logs ADD COLUMN actor_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_goal_contributions_goal_date ON goal_contributions(goal_id, contribution_date, created_at);
+CREATE INDEX idx_investment_transactions_investment_date ON investment_transactions(investment_id, transaction_date, created_at);
@@ -0,0 +1,12 @@
+-- Daily per-route feature usage rolled up from Redis counters
+
+CREATE TABLE usage_stats (
+    date DATE NOT NULL,
+    route VARCHAR(200) NOT NULL,
+    unique_users BIGINT NOT NULL DEFAULT 0,
+    total_requests BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (date, route)
+);
+
+CREATE INDEX idx_usage_stats_route ON usage_stats(route, date);
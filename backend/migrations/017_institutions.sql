@@ -0,0 +1,37 @@
+-- Canonical institutions for normalizing free-text investment institutions
+
+CREATE TABLE institutions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(200) NOT NULL UNIQUE,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    logo_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_institutions_updated_at BEFORE UPDATE ON institutions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- The raw institution text is kept alongside the mapped institution
+ALTER TABLE investments ADD COLUMN institution_id UUID REFERENCES institutions(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_investments_institution_id ON investments(institution_id);
+
+INSERT INTO institutions (name, aliases) VALUES
+    ('HDFC Bank', ARRAY['HDFC', 'HDFC Bank Ltd', 'HDFC Securities']),
+    ('ICICI Bank', ARRAY['ICICI', 'ICICI Direct', 'ICICI Securities']),
+    ('State Bank of India', ARRAY['SBI', 'SBI Bank']),
+    ('Axis Bank', ARRAY['Axis', 'Axis Direct']),
+    ('Kotak Mahindra Bank', ARRAY['Kotak', 'Kotak Securities']),
+    ('Zerodha', ARRAY['Kite', 'Zerodha Broking']),
+    ('Groww', ARRAY['Groww Invest']),
+    ('Upstox', ARRAY['RKSV']),
+    ('Vanguard', ARRAY['The Vanguard Group']),
+    ('Fidelity Investments', ARRAY['Fidelity']),
+    ('Charles Schwab', ARRAY['Schwab', 'TD Ameritrade']),
+    ('Interactive Brokers', ARRAY['IBKR', 'IB']),
+    ('Robinhood', ARRAY['Robinhood Markets']),
+    ('JPMorgan Chase', ARRAY['Chase', 'JP Morgan', 'J.P. Morgan']),
+    ('Bank of America', ARRAY['BofA', 'Merrill', 'Merrill Edge']),
+    ('Wells Fargo', ARRAY['Wells']),
+    ('Citibank', ARRAY['Citi', 'Citigroup']),
+    ('E*TRADE', ARRAY['ETrade', 'E Trade', 'Morgan Stanley E*TRADE']);
@@ -0,0 +1,28 @@
+-- Dead-lettered outbox deliveries that operators can inspect, retry or discard
+
+CREATE TABLE dead_letters (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ordering_key VARCHAR(200) NOT NULL,
+    payload JSONB NOT NULL,
+    error_class VARCHAR(30) NOT NULL,
+    last_error TEXT NOT NULL,
+    attempts INTEGER NOT NULL CHECK (attempts > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'dead' CHECK (status IN ('dead', 'requeued', 'discarded')),
+    dead_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    requeued_at TIMESTAMP WITH TIME ZONE,
+    discarded_at TIMESTAMP WITH TIME ZONE,
+    discarded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    discard_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (status <> 'discarded' OR discard_reason IS NOT NULL)
+);
+
+CREATE INDEX idx_dead_letters_status ON dead_letters(status, dead_at);
+CREATE INDEX idx_dead_letters_event_type ON dead_letters(event_type) WHERE status = 'dead';
+CREATE INDEX idx_dead_letters_error_class ON dead_letters(error_class) WHERE status = 'dead';
+
+CREATE TRIGGER update_dead_letters_updated_at BEFORE UPDATE ON dead_letters FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,8 @@
+-- Activity feed: expenses remember the CSV import that created them so an
+-- import shows as a single feed item, and per-user indexes for feed queries
+
+ALTER TABLE expenses ADD COLUMN import_id UUID;
+
+CREATE INDEX idx_expenses_user_created ON expenses(user_id, created_at DESC, id DESC);
+CREATE INDEX idx_user_sessions_user_created ON user_sessions(user_id, created_at DESC);
+CREATE INDEX idx_audit_logs_user_created ON audit_logs(user_id, created_at DESC) WHERE user_id IS NOT NULL;
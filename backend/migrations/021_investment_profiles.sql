@@ -0,0 +1,17 @@
+-- Per-type validation profiles for investments, and ticker symbols for
+-- listed holdings
+
+ALTER TABLE investment_types ADD COLUMN validation_profile JSONB;
+ALTER TABLE investments ADD COLUMN symbol VARCHAR(20);
+
+UPDATE investment_types SET validation_profile =
+    '{"required": ["interest_rate", "end_date"], "forbidden": ["symbol"], "constraints": {"interest_rate": {"min": 0, "max": 25}}}'
+WHERE name IN ('Bank Fixed Deposit', 'Recurring Deposit', 'Post Office RD');
+
+UPDATE investment_types SET validation_profile =
+    '{"required": ["symbol"], "constraints": {"symbol": {"max_length": 20, "pattern": "^[A-Z0-9.\\-]+$"}}}'
+WHERE name IN ('Stocks', 'US Stocks');
+
+UPDATE investment_types SET validation_profile =
+    '{"forbidden": ["symbol", "interest_rate"]}'
+WHERE name = 'Real Estate';
@@ -0,0 +1,34 @@
+-- Households and per-member sharing levels. Each member chooses, per entity
+-- type, whether other members see nothing, only totals, or every record.
+-- A missing sharing row means nothing is shared.
+
+CREATE TABLE households (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE household_memberships (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (household_id, user_id)
+);
+
+CREATE TABLE household_sharing (
+    household_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    entity_type VARCHAR(30) NOT NULL CHECK (entity_type IN ('expenses', 'incomes', 'investments', 'goals')),
+    level VARCHAR(20) NOT NULL CHECK (level IN ('none', 'totals_only', 'full')),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (household_id, user_id, entity_type),
+    FOREIGN KEY (household_id, user_id) REFERENCES household_memberships(household_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_household_memberships_user_id ON household_memberships(user_id);
+
+CREATE TRIGGER update_households_updated_at BEFORE UPDATE ON households FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_household_sharing_updated_at BEFORE UPDATE ON household_sharing FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
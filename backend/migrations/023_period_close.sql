@@ -0,0 +1,86 @@
+-- Monthly period close. A locked period freezes its expense summary in a
+-- snapshot, and the expenses trigger rejects any write dated inside it, so
+-- imports and bulk operations cannot bypass the lock. A write made while
+-- tgfinance.period_adjustment holds a reason is let through and recorded as
+-- an adjustment of the period instead.
+
+CREATE TABLE period_closes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period DATE NOT NULL CHECK (period = date_trunc('month', period)::date),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('locked', 'reopened')),
+    snapshot JSONB,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reopened_at TIMESTAMP WITH TIME ZONE,
+    reopened_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reopen_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, period)
+);
+
+CREATE TABLE period_adjustments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period DATE NOT NULL,
+    expense_id UUID NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('insert', 'update', 'delete')),
+    old_value JSONB,
+    new_value JSONB,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_period_adjustments_user_period ON period_adjustments(user_id, period, created_at);
+
+CREATE TRIGGER update_period_closes_updated_at BEFORE UPDATE ON period_closes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE OR REPLACE FUNCTION enforce_period_lock()
+RETURNS TRIGGER AS $$
+DECLARE
+    reason TEXT := NULLIF(current_setting('tgfinance.period_adjustment', true), '');
+    row_user_id UUID;
+    row_id UUID;
+    old_period DATE;
+    new_period DATE;
+    old_value JSONB;
+    new_value JSONB;
+    locked_period DATE;
+BEGIN
+    IF current_setting('tgfinance.skip_period_lock', true) = 'on' THEN
+        RETURN CASE WHEN TG_OP = 'DELETE' THEN OLD ELSE NEW END;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        row_user_id := OLD.user_id;
+        row_id := OLD.id;
+        old_period := date_trunc('month', OLD.expense_date)::date;
+        old_value := to_jsonb(OLD);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        row_user_id := NEW.user_id;
+        row_id := NEW.id;
+        new_period := date_trunc('month', NEW.expense_date)::date;
+        new_value := to_jsonb(NEW);
+    END IF;
+
+    FOR locked_period IN
+        SELECT pc.period FROM period_closes pc
+        WHERE pc.user_id = row_user_id AND pc.status = 'locked'
+            AND pc.period IN (old_period, new_period)
+        ORDER BY pc.period
+    LOOP
+        IF reason IS NULL THEN
+            RAISE EXCEPTION 'period % is closed', to_char(locked_period, 'YYYY-MM')
+                USING ERRCODE = 'TG001', DETAIL = to_char(locked_period, 'YYYY-MM'),
+                    HINT = 'Reopen the period or record the change as an adjustment';
+        END IF;
+        INSERT INTO period_adjustments (user_id, period, expense_id, action, old_value, new_value, reason)
+        VALUES (row_user_id, locked_period, row_id, lower(TG_OP), old_value, new_value, reason);
+    END LOOP;
+
+    RETURN CASE WHEN TG_OP = 'DELETE' THEN OLD ELSE NEW END;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER enforce_expenses_period_lock BEFORE INSERT OR UPDATE OR DELETE ON expenses FOR EACH ROW EXECUTE FUNCTION enforce_period_lock();
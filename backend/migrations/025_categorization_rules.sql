@@ -0,0 +1,38 @@
+-- User categorization rules and the expense changes made when applying them.
+-- Changes keep the previous category so an apply can be reverted.
+
+CREATE TABLE categorization_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description_pattern VARCHAR(255),
+    merchant VARCHAR(255),
+    min_amount DECIMAL(10,2),
+    max_amount DECIMAL(10,2),
+    payment_method VARCHAR(50),
+    category_id UUID NOT NULL REFERENCES expense_categories(id),
+    priority INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (description_pattern IS NOT NULL OR merchant IS NOT NULL OR min_amount IS NOT NULL
+        OR max_amount IS NOT NULL OR payment_method IS NOT NULL),
+    CHECK (min_amount IS NULL OR max_amount IS NULL OR min_amount <= max_amount)
+);
+
+CREATE TABLE categorization_rule_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    apply_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rule_id UUID REFERENCES categorization_rules(id) ON DELETE SET NULL,
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    old_category_id UUID NOT NULL REFERENCES expense_categories(id),
+    new_category_id UUID NOT NULL REFERENCES expense_categories(id),
+    reverted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_categorization_rules_user_id ON categorization_rules(user_id);
+CREATE INDEX idx_categorization_rule_changes_apply_id ON categorization_rule_changes(apply_id);
+
+CREATE TRIGGER update_categorization_rules_updated_at BEFORE UPDATE ON categorization_rules FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
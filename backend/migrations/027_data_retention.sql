@@ -0,0 +1,28 @@
+-- Per-user data retention windows, and the permanent monthly category
+-- aggregates that purged expenses are rolled into
+
+CREATE TABLE user_retention_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    expense_years INTEGER CHECK (expense_years > 0),
+    login_history_months INTEGER CHECK (login_history_months > 0),
+    notification_days INTEGER CHECK (notification_days > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE expense_monthly_aggregates (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    month DATE NOT NULL CHECK (month = date_trunc('month', month)::date),
+    category_id UUID NOT NULL REFERENCES expense_categories(id),
+    payment_method VARCHAR(50) NOT NULL DEFAULT '',
+    amount DECIMAL(15,2) NOT NULL,
+    expense_count INTEGER NOT NULL CHECK (expense_count > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, month, category_id, payment_method)
+);
+
+CREATE INDEX idx_expenses_user_date ON expenses(user_id, expense_date, id);
+
+CREATE TRIGGER update_user_retention_settings_updated_at BEFORE UPDATE ON user_retention_settings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_expense_monthly_aggregates_updated_at BEFORE UPDATE ON expense_monthly_aggregates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
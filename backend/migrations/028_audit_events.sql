@@ -0,0 +1,29 @@
+-- Append-only security audit trail: sign-ins, failed authentication and
+-- every mutating API call. user_id has no foreign key so events outlive the
+-- accounts they describe.
+
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_type VARCHAR(100) NOT NULL,
+    user_id UUID,
+    method VARCHAR(10),
+    route VARCHAR(255),
+    resource_id UUID,
+    ip_address VARCHAR(45),
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('success', 'failure')),
+    status_code INTEGER,
+    detail TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_events_user_created ON audit_events(user_id, created_at DESC) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+
+CREATE OR REPLACE FUNCTION prevent_audit_event_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events FOR EACH ROW EXECUTE FUNCTION prevent_audit_event_changes();
@@ -0,0 +1,4 @@
+-- Time zone used to group a user's expenses into days and months
+
+ALTER TABLE users
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -1,9 +1,18 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"tgfinance/pkg/auth/passwordcheck"
+	"tgfinance/pkg/clock"
 )
 
 func TestJWTManager(t *testing.T) {
@@ -12,7 +21,7 @@ func TestJWTManager(t *testing.T) {
 	email := "test@example.com"
 
 	// Test token generation
-	token, err := jwtManager.GenerateToken(userID, email)
+	token, err := jwtManager.GenerateToken(userID, email, RoleUser)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -95,6 +104,24 @@ func TestPasswordManager(t *testing.T) {
 	}
 }
 
+func TestPasswordPolicy(t *testing.T) {
+	passwordManager := NewPasswordManager().WithPolicy(passwordcheck.NewPolicy(nil, nil))
+
+	if err := passwordManager.IsPasswordValid("Password123!"); !errors.Is(err, passwordcheck.ErrCommonPassword) {
+		t.Errorf("Expected common password to be rejected, got %v", err)
+	}
+	if _, err := passwordManager.HashPassword("P@ssw0rd1"); !errors.Is(err, passwordcheck.ErrCommonPassword) {
+		t.Errorf("Expected common password not to be hashed, got %v", err)
+	}
+	if err := passwordManager.IsPasswordValid("Gl4cier-Tandem-Oboe7"); err != nil {
+		t.Errorf("Expected strong password to pass, got %v", err)
+	}
+	// Without a policy only the strength rules apply
+	if err := NewPasswordManager().IsPasswordValid("Password123!"); err != nil {
+		t.Errorf("Expected strength rules alone to accept the password, got %v", err)
+	}
+}
+
 func TestPasswordStrength(t *testing.T) {
 	passwordManager := NewPasswordManager()
 
@@ -116,3 +143,511 @@ func TestPasswordStrength(t *testing.T) {
 		}
 	}
 }
+
+func TestArgon2idPasswords(t *testing.T) {
+	// Small parameters keep the test fast
+	opts := PasswordOptions{Algorithm: AlgorithmArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}
+	argonManager := NewPasswordManagerWithOptions(opts)
+	bcryptManager := NewPasswordManager()
+	password := "SecurePass123!"
+
+	hash, err := argonManager.HashPassword(password)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Unexpected argon2id hash format %q", hash)
+	}
+	if err := argonManager.VerifyPassword(hash, password); err != nil {
+		t.Errorf("Failed to verify argon2id password: %v", err)
+	}
+	if err := argonManager.VerifyPassword(hash, "WrongPass123!"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected mismatch for wrong password, got %v", err)
+	}
+
+	// Both formats verify with either manager
+	legacy, _ := bcryptManager.HashPassword(password)
+	if err := argonManager.VerifyPassword(legacy, password); err != nil {
+		t.Errorf("Failed to verify bcrypt hash with argon2id manager: %v", err)
+	}
+	if err := bcryptManager.VerifyPassword(hash, password); err != nil {
+		t.Errorf("Failed to verify argon2id hash with bcrypt manager: %v", err)
+	}
+
+	for _, bad := range []string{"$argon2id$v=19$m=1024$salt$key", "$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5"} {
+		if err := argonManager.VerifyPassword(bad, password); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Expected invalid hash error for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	opts := PasswordOptions{Algorithm: AlgorithmArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}
+	argonManager := NewPasswordManagerWithOptions(opts)
+	bcryptManager := NewPasswordManager()
+	password := "SecurePass123!"
+
+	argonHash, _ := argonManager.HashPassword(password)
+	bcryptHash, _ := bcryptManager.HashPassword(password)
+	cheapBcrypt, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	opts.Argon2Iterations = 2
+	strongerArgon := NewPasswordManagerWithOptions(opts)
+
+	tests := []struct {
+		name    string
+		manager *PasswordManager
+		hash    string
+		want    bool
+	}{
+		{"bcrypt hash under argon2id", argonManager, bcryptHash, true},
+		{"argon2id hash under argon2id", argonManager, argonHash, false},
+		{"argon2id hash with stronger params", strongerArgon, argonHash, true},
+		{"bcrypt hash under bcrypt", bcryptManager, bcryptHash, false},
+		{"cheap bcrypt hash", bcryptManager, string(cheapBcrypt), true},
+		{"argon2id hash under bcrypt", bcryptManager, argonHash, true},
+		{"garbage", bcryptManager, "not-a-hash", true},
+	}
+	for _, tt := range tests {
+		if got := tt.manager.NeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBcryptCost(t *testing.T) {
+	for _, cost := range []int{bcrypt.MinCost - 1, bcrypt.MaxCost + 1} {
+		if _, err := NewPasswordManagerWithCost(cost); err == nil {
+			t.Errorf("Expected cost %d to be rejected", cost)
+		}
+	}
+
+	cheap, err := NewPasswordManagerWithCost(bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to create password manager: %v", err)
+	}
+	hash, _ := cheap.HashPassword("SecurePass123!")
+	if cost, _ := bcrypt.Cost([]byte(hash)); cost != bcrypt.MinCost {
+		t.Errorf("Expected cost %d, got %d", bcrypt.MinCost, cost)
+	}
+	if cheap.NeedsRehash(hash) {
+		t.Error("Expected hash at the configured cost not to need a rehash")
+	}
+
+	// Raising or lowering the cost asks for a rehash
+	raised := NewPasswordManagerWithOptions(PasswordOptions{BcryptCost: bcrypt.MinCost + 1})
+	if !raised.NeedsRehash(hash) {
+		t.Error("Expected hash below the configured cost to need a rehash")
+	}
+	lowered, _ := raised.HashPassword("SecurePass123!")
+	if !cheap.NeedsRehash(lowered) {
+		t.Error("Expected hash above the configured cost to need a rehash")
+	}
+	if err := cheap.VerifyPassword(lowered, "SecurePass123!"); err != nil {
+		t.Errorf("Expected hashes at other costs to verify, got %v", err)
+	}
+
+	if pm := NewPasswordManagerWithOptions(PasswordOptions{BcryptCost: 99}); pm.cost != bcrypt.DefaultCost {
+		t.Errorf("Expected out-of-range configured cost to fall back to %d, got %d", bcrypt.DefaultCost, pm.cost)
+	}
+}
+
+func TestPasswordPeppers(t *testing.T) {
+	password := "SecurePass123!"
+	plain, _ := NewPasswordManagerWithCost(bcrypt.MinCost)
+	first := NewPasswordManagerWithOptions(PasswordOptions{BcryptCost: bcrypt.MinCost, Peppers: []string{"pepper-1"}})
+	rotated := NewPasswordManagerWithOptions(PasswordOptions{BcryptCost: bcrypt.MinCost, Peppers: []string{"pepper-2", "pepper-1"}})
+
+	unpeppered, _ := plain.HashPassword(password)
+	peppered, _ := first.HashPassword(password)
+	current, _ := rotated.HashPassword(password)
+
+	// The database hash alone does not verify without the pepper
+	if err := plain.VerifyPassword(peppered, password); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected peppered hash to fail without the pepper, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		hash   string
+		rehash bool
+	}{
+		{"current pepper", current, false},
+		{"historical pepper", peppered, true},
+		{"no pepper", unpeppered, true},
+	}
+	for _, tt := range tests {
+		rehash, err := rotated.CheckPassword(tt.hash, password)
+		if err != nil {
+			t.Errorf("%s: failed to verify password: %v", tt.name, err)
+			continue
+		}
+		if rehash != tt.rehash {
+			t.Errorf("%s: rehash = %v, want %v", tt.name, rehash, tt.rehash)
+		}
+		if _, err := rotated.CheckPassword(tt.hash, "WrongPass123!"); !errors.Is(err, ErrPasswordMismatch) {
+			t.Errorf("%s: expected mismatch for wrong password, got %v", tt.name, err)
+		}
+	}
+
+	if rehash, err := first.CheckPassword(current, password); !errors.Is(err, ErrPasswordMismatch) || rehash {
+		t.Errorf("Expected hash with an unknown pepper to fail, got %v %v", rehash, err)
+	}
+}
+
+func BenchmarkBcryptCost(b *testing.B) {
+	for _, cost := range []int{10, 12} {
+		pm, _ := NewPasswordManagerWithCost(cost)
+		b.Run(fmt.Sprintf("cost=%d", cost), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := pm.HashPassword("SecurePass123!"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHashPassword(b *testing.B) {
+	managers := map[string]*PasswordManager{
+		AlgorithmBcrypt:   NewPasswordManager(),
+		AlgorithmArgon2id: NewPasswordManagerWithOptions(PasswordOptions{Algorithm: AlgorithmArgon2id}),
+	}
+	for name, manager := range managers {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := manager.HashPassword("SecurePass123!"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyPassword(b *testing.B) {
+	managers := map[string]*PasswordManager{
+		AlgorithmBcrypt:   NewPasswordManager(),
+		AlgorithmArgon2id: NewPasswordManagerWithOptions(PasswordOptions{Algorithm: AlgorithmArgon2id}),
+	}
+	for name, manager := range managers {
+		hash, _ := manager.HashPassword("SecurePass123!")
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := manager.VerifyPassword(hash, "SecurePass123!"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeviceToken(t *testing.T) {
+	token, err := GenerateDeviceToken()
+	if err != nil {
+		t.Fatalf("Failed to generate device token: %v", err)
+	}
+	other, _ := GenerateDeviceToken()
+	if token == other {
+		t.Error("Expected distinct device tokens")
+	}
+
+	hash := HashDeviceToken(token)
+	if hash == token {
+		t.Error("Hash should not equal the token")
+	}
+	if !VerifyDeviceToken(hash, token) {
+		t.Error("Expected token to verify against its hash")
+	}
+	if VerifyDeviceToken(hash, other) || VerifyDeviceToken("", "") {
+		t.Error("Expected mismatched or empty tokens to fail verification")
+	}
+}
+
+func TestJWTManagerExpiryWithClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	jwtManager := NewJWTManager().WithClock(fake)
+
+	token, err := jwtManager.GenerateToken(uuid.New(), "test@example.com", RoleUser)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	fake.Advance(23 * time.Hour)
+	if _, err := jwtManager.ValidateToken(token); err != nil {
+		t.Errorf("Expected token to be valid before expiry, got %v", err)
+	}
+
+	fake.Advance(2 * time.Hour)
+	if _, err := jwtManager.ValidateToken(token); err == nil {
+		t.Error("Expected token to be rejected after 24 hours")
+	}
+}
+
+func TestJWTManagerWithOptions(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	jwtManager := NewJWTManagerWithOptions(JWTOptions{
+		Secret:        "test-secret",
+		Issuer:        "tgfinance-test",
+		AccessExpiry:  time.Second,
+		RefreshExpiry: time.Minute,
+	}).WithClock(fake)
+	userID := uuid.New()
+
+	token, err := jwtManager.GenerateToken(userID, "test@example.com", RoleUser)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	refresh, err := jwtManager.GenerateRefreshToken(userID)
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+
+	claims, err := jwtManager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.Issuer != "tgfinance-test" || !claims.ExpiresAt.Time.Equal(fake.Now().Add(time.Second)) {
+		t.Errorf("Unexpected claims issuer %q expiry %v", claims.Issuer, claims.ExpiresAt)
+	}
+
+	fake.Advance(2 * time.Second)
+	if _, err := jwtManager.ValidateToken(token); err == nil {
+		t.Error("Expected access token to expire after 1 second")
+	}
+	if _, err := jwtManager.ValidateToken(refresh); err != nil {
+		t.Errorf("Expected refresh token to outlive the access token, got %v", err)
+	}
+
+	// Tokens signed with another secret are rejected
+	other := NewJWTManagerWithOptions(JWTOptions{Secret: "other-secret"}).WithClock(fake)
+	if _, err := other.ValidateToken(refresh); err == nil {
+		t.Error("Expected token signed with another secret to be rejected")
+	}
+}
+
+func TestJWTManagerDefaults(t *testing.T) {
+	jwtManager := NewJWTManagerWithOptions(JWTOptions{})
+	if jwtManager.issuer != DefaultIssuer || jwtManager.accessExpiry != DefaultAccessExpiry ||
+		jwtManager.refreshExpiry != DefaultRefreshExpiry || len(jwtManager.secretKey) == 0 {
+		t.Errorf("Unexpected defaults %+v", jwtManager)
+	}
+}
+
+func TestTokenTypes(t *testing.T) {
+	jwtManager := NewJWTManagerWithOptions(JWTOptions{Secret: "test-secret"})
+	userID := uuid.New()
+	access, _ := jwtManager.GenerateToken(userID, "test@example.com", RoleUser)
+	refresh, _ := jwtManager.GenerateRefreshToken(userID)
+
+	if claims, err := jwtManager.ValidateAccessToken(access); err != nil || claims.TokenType != TokenTypeAccess {
+		t.Errorf("Expected access token to validate as access, got %v", err)
+	}
+	if claims, err := jwtManager.ValidateRefreshToken(refresh); err != nil || claims.TokenType != TokenTypeRefresh {
+		t.Errorf("Expected refresh token to validate as refresh, got %v", err)
+	}
+	if _, err := jwtManager.ValidateAccessToken(refresh); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("Expected refresh token to be rejected as access token, got %v", err)
+	}
+	if _, err := jwtManager.ValidateRefreshToken(access); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("Expected access token to be rejected as refresh token, got %v", err)
+	}
+
+	// Tokens issued before the claim existed carry no type and are rejected
+	untyped := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: userID, Email: "test@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer: DefaultIssuer, Audience: jwt.ClaimStrings{DefaultAudience}}})
+	signed, _ := untyped.SignedString([]byte("test-secret"))
+	if _, err := jwtManager.ValidateAccessToken(signed); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("Expected untyped token to be rejected, got %v", err)
+	}
+}
+
+func TestGenerateTokenPair(t *testing.T) {
+	issuedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	jwtManager := NewJWTManagerWithOptions(JWTOptions{
+		AccessExpiry:  15 * time.Minute,
+		RefreshExpiry: 30 * 24 * time.Hour,
+	}).WithClock(clock.NewFake(issuedAt))
+	userID := uuid.New()
+
+	pair, err := jwtManager.GenerateTokenPair(userID, "test@example.com", RoleUser)
+	if err != nil {
+		t.Fatalf("Failed to generate token pair: %v", err)
+	}
+	if pair.TokenType != "Bearer" {
+		t.Errorf("Expected token type Bearer, got %s", pair.TokenType)
+	}
+	if want := issuedAt.Add(15 * time.Minute); !pair.AccessExpiresAt.Equal(want) {
+		t.Errorf("Expected access expiry %v, got %v", want, pair.AccessExpiresAt)
+	}
+	if want := issuedAt.Add(30 * 24 * time.Hour); !pair.RefreshExpiresAt.Equal(want) {
+		t.Errorf("Expected refresh expiry %v, got %v", want, pair.RefreshExpiresAt)
+	}
+
+	access, err := jwtManager.ValidateAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+	if !access.ExpiresAt.Time.Equal(pair.AccessExpiresAt) || access.Email != "test@example.com" {
+		t.Errorf("Access token claims do not match the pair: %+v", access)
+	}
+	refresh, err := jwtManager.ValidateRefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to validate refresh token: %v", err)
+	}
+	if !refresh.ExpiresAt.Time.Equal(pair.RefreshExpiresAt) || refresh.UserID != userID {
+		t.Errorf("Refresh token claims do not match the pair: %+v", refresh)
+	}
+}
+
+func TestRoleClaims(t *testing.T) {
+	jwtManager := NewJWTManager()
+
+	token, _ := jwtManager.GenerateToken(uuid.New(), "admin@example.com", RoleAdmin, "reports:read")
+	claims, err := jwtManager.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.EffectiveRole() != RoleAdmin {
+		t.Errorf("Expected role %s, got %s", RoleAdmin, claims.EffectiveRole())
+	}
+	if !claims.HasPermission("reports:read") || claims.HasPermission("reports:write") {
+		t.Errorf("Unexpected permissions %v", claims.Permissions)
+	}
+
+	token, _ = jwtManager.GenerateToken(uuid.New(), "user@example.com", "")
+	claims, _ = jwtManager.ValidateAccessToken(token)
+	if claims.EffectiveRole() != RoleUser {
+		t.Errorf("Expected empty role to default to %s, got %s", RoleUser, claims.EffectiveRole())
+	}
+}
+
+func TestClockSkewLeeway(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	validator := func(skew time.Duration) *JWTManager {
+		return NewJWTManagerWithOptions(JWTOptions{
+			Secret:       "test-secret",
+			AccessExpiry: time.Minute,
+			ClockSkew:    skew,
+		}).WithClock(clock.NewFake(now))
+	}
+
+	// A server running five seconds ahead issues tokens with nbf and iat in our future
+	ahead := validator(0).WithClock(clock.NewFake(now.Add(5 * time.Second)))
+	early, _ := ahead.GenerateToken(uuid.New(), "test@example.com", RoleUser)
+
+	// A token that expired one second ago
+	behind := validator(0).WithClock(clock.NewFake(now.Add(-time.Minute - time.Second)))
+	expired, _ := behind.GenerateToken(uuid.New(), "test@example.com", RoleUser)
+
+	tests := []struct {
+		name  string
+		token string
+		skew  time.Duration
+		valid bool
+	}{
+		{"future nbf without leeway", early, 0, false},
+		{"future nbf beyond leeway", early, 2 * time.Second, false},
+		{"future nbf within leeway", early, 10 * time.Second, true},
+		{"expired without leeway", expired, 0, false},
+		{"expired within leeway", expired, 2 * time.Second, true},
+	}
+	for _, tt := range tests {
+		_, err := validator(tt.skew).ValidateAccessToken(tt.token)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%s: valid = %v, want %v (err %v)", tt.name, valid, tt.valid, err)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys := map[string]string{"2024-01": "old-secret", "2024-06": "new-secret"}
+	before := NewJWTManagerWithOptions(JWTOptions{Keys: keys, KeyID: "2024-01"})
+	after := NewJWTManagerWithOptions(JWTOptions{Keys: keys, KeyID: "2024-06"})
+	userID := uuid.New()
+
+	oldToken, _ := before.GenerateToken(userID, "test@example.com", RoleUser)
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &Claims{})
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if parsed.Header["kid"] != "2024-01" {
+		t.Errorf("Expected kid 2024-01, got %v", parsed.Header["kid"])
+	}
+
+	// Tokens signed before the rotation still validate with their kid
+	if claims, err := after.ValidateAccessToken(oldToken); err != nil || claims.UserID != userID {
+		t.Errorf("Expected old-kid token to validate after rotation, got %v", err)
+	}
+	newToken, _ := after.GenerateToken(userID, "test@example.com", RoleUser)
+	if _, err := after.ValidateAccessToken(newToken); err != nil {
+		t.Errorf("Expected new token to validate, got %v", err)
+	}
+
+	// Once the old key is retired its tokens are rejected
+	retired := NewJWTManagerWithOptions(JWTOptions{Keys: map[string]string{"2024-06": "new-secret"}, KeyID: "2024-06"})
+	if _, err := retired.ValidateAccessToken(oldToken); err == nil {
+		t.Error("Expected token with a retired kid to be rejected")
+	}
+
+	// Tokens without a kid fall back to the current key
+	legacy, _ := NewJWTManagerWithOptions(JWTOptions{Secret: "new-secret"}).GenerateToken(userID, "test@example.com", RoleUser)
+	if _, err := after.ValidateAccessToken(legacy); err != nil {
+		t.Errorf("Expected token without kid to validate with the current key, got %v", err)
+	}
+	if _, err := before.ValidateAccessToken(legacy); err == nil {
+		t.Error("Expected token without kid to be rejected by another current key")
+	}
+
+	// A key ID missing from the keys does not fall back to the secret
+	typo := NewJWTManagerWithOptions(JWTOptions{Secret: "new-secret", Keys: keys, KeyID: "2024-6"})
+	if token, err := typo.GenerateToken(userID, "test@example.com", RoleUser); err == nil {
+		t.Errorf("Expected an unknown key ID to fail signing, got %q", token)
+	}
+	if _, err := typo.ValidateAccessToken(legacy); err == nil {
+		t.Error("Expected token without kid to be rejected without a current key")
+	}
+}
+
+func TestIssuerAndAudienceValidation(t *testing.T) {
+	api := NewJWTManagerWithOptions(JWTOptions{Secret: "shared-secret"})
+	userID := uuid.New()
+
+	token, _ := api.GenerateToken(userID, "test@example.com", RoleUser)
+	claims, err := api.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.Issuer != DefaultIssuer || len(claims.Audience) != 1 || claims.Audience[0] != DefaultAudience {
+		t.Errorf("Unexpected issuer %q audience %v", claims.Issuer, claims.Audience)
+	}
+
+	// Another internal service sharing the secret
+	otherIssuer, _ := NewJWTManagerWithOptions(JWTOptions{Secret: "shared-secret", Issuer: "billing"}).
+		GenerateToken(userID, "test@example.com", RoleUser)
+	otherAudience, _ := NewJWTManagerWithOptions(JWTOptions{Secret: "shared-secret", Audience: "mobile"}).
+		GenerateToken(userID, "test@example.com", RoleUser)
+
+	_, issuerErr := api.ValidateAccessToken(otherIssuer)
+	if !errors.Is(issuerErr, jwt.ErrTokenInvalidIssuer) {
+		t.Errorf("Expected invalid issuer error, got %v", issuerErr)
+	}
+	_, audienceErr := api.ValidateAccessToken(otherAudience)
+	if !errors.Is(audienceErr, jwt.ErrTokenInvalidAudience) {
+		t.Errorf("Expected invalid audience error, got %v", audienceErr)
+	}
+	if issuerErr != nil && audienceErr != nil && issuerErr.Error() == audienceErr.Error() {
+		t.Errorf("Expected distinct error messages, got %q for both", issuerErr)
+	}
+
+	// A service accepting tokens from several frontends and issuers
+	multi := NewJWTManagerWithOptions(JWTOptions{
+		Secret:           "shared-secret",
+		AllowedIssuers:   []string{DefaultIssuer, "billing"},
+		AllowedAudiences: []string{DefaultAudience, "mobile"},
+	})
+	for name, token := range map[string]string{"default": token, "issuer": otherIssuer, "audience": otherAudience} {
+		if _, err := multi.ValidateAccessToken(token); err != nil {
+			t.Errorf("Expected %s token to be accepted, got %v", name, err)
+		}
+	}
+}
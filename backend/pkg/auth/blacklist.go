@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/pkg/clock"
+)
+
+// blacklistPrefix namespaces the revocation keys in Redis
+const blacklistPrefix = "auth:revoked:"
+
+// TokenBlacklist records revoked tokens in Redis so they are refused before
+// they expire. Single tokens are revoked by their jti; "log out everywhere"
+// revokes every token of a user issued before that moment, to the
+// nanosecond, so logging in again straight away yields a valid token.
+type TokenBlacklist struct {
+	client redis.UniversalClient
+	clock  clock.Clock
+}
+
+// NewTokenBlacklistWithClient creates a blacklist over an existing Redis client
+func NewTokenBlacklistWithClient(client redis.UniversalClient) *TokenBlacklist {
+	return &TokenBlacklist{client: client, clock: clock.Real()}
+}
+
+// WithClock sets the clock used to stamp user-wide revocations
+func (b *TokenBlacklist) WithClock(c clock.Clock) *TokenBlacklist {
+	b.clock = c
+	return b
+}
+
+// Revoke revokes the token with the jti for ttl, which should cover the
+// token's remaining lifetime
+func (b *TokenBlacklist) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	if jti == "" {
+		return errors.New("token has no jti")
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return b.client.Set(ctx, blacklistPrefix+"jti:"+jti, 1, ttl).Err()
+}
+
+// RevokeUntil revokes the token with the jti until expiresAt, measured on
+// the blacklist's clock
+func (b *TokenBlacklist) RevokeUntil(ctx context.Context, jti string, expiresAt time.Time) error {
+	return b.Revoke(ctx, jti, expiresAt.Sub(b.clock.Now()))
+}
+
+// IsRevoked returns true if the token with the jti has been revoked
+func (b *TokenBlacklist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := b.client.Exists(ctx, blacklistPrefix+"jti:"+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeAllForUser revokes every token of the user issued before now. ttl
+// should cover the longest token lifetime, after which the marker is no
+// longer needed.
+func (b *TokenBlacklist) RevokeAllForUser(ctx context.Context, userID uuid.UUID, ttl time.Duration) error {
+	return b.client.Set(ctx, blacklistPrefix+"user:"+userID.String(), b.clock.Now().UnixNano(), ttl).Err()
+}
+
+// legacyCutoffLimit bounds the user markers written in Unix seconds before
+// cutoffs had nanosecond precision
+const legacyCutoffLimit = 1e12
+
+// IsTokenRevoked returns true if the token was revoked by its jti or by a
+// revocation of all the user's tokens issued before it
+func (b *TokenBlacklist) IsTokenRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	pipe := b.client.Pipeline()
+	jti := pipe.Exists(ctx, blacklistPrefix+"jti:"+claims.ID)
+	cutoff := pipe.Get(ctx, blacklistPrefix+"user:"+claims.UserID.String())
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	if jti.Val() > 0 {
+		return true, nil
+	}
+	if cutoff.Err() != nil {
+		return false, nil
+	}
+	revokedAt, err := strconv.ParseInt(cutoff.Val(), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid revocation marker: %w", err)
+	}
+	revokedBefore := time.Unix(0, revokedAt)
+	if revokedAt < legacyCutoffLimit {
+		revokedBefore = time.Unix(revokedAt+1, 0)
+	}
+	issuedAt := claims.IssuedTime()
+	return issuedAt.IsZero() || issuedAt.Before(revokedBefore), nil
+}
+
+// RevokeOnce revokes the jti for ttl and reports whether this call revoked
+// it, so single-use tokens can be consumed atomically
+func (b *TokenBlacklist) RevokeOnce(ctx context.Context, jti string, ttl time.Duration) (bool, error) {
+	if jti == "" {
+		return false, errors.New("token has no jti")
+	}
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+	return b.client.SetNX(ctx, blacklistPrefix+"jti:"+jti, 1, ttl).Result()
+}
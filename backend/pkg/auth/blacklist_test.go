@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/pkg/clock"
+)
+
+func TestTokenBlacklist(t *testing.T) {
+	server := miniredis.RunT(t)
+	blacklist := NewTokenBlacklistWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	ctx := context.Background()
+
+	jwtManager := NewJWTManager()
+	token, _ := jwtManager.GenerateToken(uuid.New(), "test@example.com", RoleUser)
+	claims, err := jwtManager.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.ID == "" {
+		t.Fatal("Expected generated token to carry a jti")
+	}
+
+	if revoked, _ := blacklist.IsTokenRevoked(ctx, claims); revoked {
+		t.Error("Expected fresh token not to be revoked")
+	}
+	if err := blacklist.Revoke(ctx, claims.ID, time.Minute); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if revoked, _ := blacklist.IsRevoked(ctx, claims.ID); !revoked {
+		t.Error("Expected jti to be revoked")
+	}
+	if revoked, _ := blacklist.IsTokenRevoked(ctx, claims); !revoked {
+		t.Error("Expected token to be revoked")
+	}
+
+	// The entry expires with the token
+	server.FastForward(time.Minute)
+	if revoked, _ := blacklist.IsRevoked(ctx, claims.ID); revoked {
+		t.Error("Expected revocation to expire")
+	}
+	if err := blacklist.Revoke(ctx, "", time.Minute); err == nil {
+		t.Error("Expected error revoking a token without jti")
+	}
+}
+
+func TestRevokeAllForUser(t *testing.T) {
+	server := miniredis.RunT(t)
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	blacklist := NewTokenBlacklistWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()})).WithClock(fake)
+	jwtManager := NewJWTManagerWithOptions(JWTOptions{}).WithClock(fake)
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+
+	parse := func(token string, validate func(string) (*Claims, error)) *Claims {
+		claims, err := validate(token)
+		if err != nil {
+			t.Fatalf("Failed to validate token: %v", err)
+		}
+		return claims
+	}
+	access, _ := jwtManager.GenerateToken(userID, "test@example.com", RoleUser)
+	refresh, _ := jwtManager.GenerateRefreshToken(userID)
+	other, _ := jwtManager.GenerateToken(otherID, "other@example.com", RoleUser)
+	accessClaims := parse(access, jwtManager.ValidateAccessToken)
+	refreshClaims := parse(refresh, jwtManager.ValidateRefreshToken)
+	otherClaims := parse(other, jwtManager.ValidateAccessToken)
+
+	fake.Advance(time.Minute)
+	if err := blacklist.RevokeAllForUser(ctx, userID, jwtManager.RefreshExpiry()); err != nil {
+		t.Fatalf("Failed to revoke user tokens: %v", err)
+	}
+
+	for name, claims := range map[string]*Claims{"access": accessClaims, "refresh": refreshClaims} {
+		if revoked, _ := blacklist.IsTokenRevoked(ctx, claims); !revoked {
+			t.Errorf("Expected %s token to be revoked", name)
+		}
+	}
+	if revoked, _ := blacklist.IsTokenRevoked(ctx, otherClaims); revoked {
+		t.Error("Expected other user's token to stay valid")
+	}
+
+	// Logging in again after the revocation issues a valid token
+	fake.Advance(time.Second)
+	fresh, _ := jwtManager.GenerateToken(userID, "test@example.com", RoleUser)
+	if revoked, _ := blacklist.IsTokenRevoked(ctx, parse(fresh, jwtManager.ValidateAccessToken)); revoked {
+		t.Error("Expected token issued after the revocation to be valid")
+	}
+}
+
+func TestRevokeAllForUserWithinTheSameSecond(t *testing.T) {
+	server := miniredis.RunT(t)
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 100*int(time.Millisecond), time.UTC))
+	blacklist := NewTokenBlacklistWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()})).WithClock(fake)
+	jwtManager := NewJWTManagerWithOptions(JWTOptions{}).WithClock(fake)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	validate := func(token string) *Claims {
+		claims, err := jwtManager.ValidateAccessToken(token)
+		if err != nil {
+			t.Fatalf("Failed to validate token: %v", err)
+		}
+		return claims
+	}
+
+	before, _ := jwtManager.GenerateToken(userID, "test@example.com", RoleUser)
+	fake.Advance(200 * time.Millisecond)
+	if err := blacklist.RevokeAllForUser(ctx, userID, time.Hour); err != nil {
+		t.Fatalf("Failed to revoke user tokens: %v", err)
+	}
+	fake.Advance(300 * time.Millisecond)
+	after, _ := jwtManager.GenerateToken(userID, "test@example.com", RoleUser)
+
+	if revoked, _ := blacklist.IsTokenRevoked(ctx, validate(before)); !revoked {
+		t.Error("Expected the token issued before the revocation to be revoked")
+	}
+	if revoked, _ := blacklist.IsTokenRevoked(ctx, validate(after)); revoked {
+		t.Error("Expected a login in the same second after the revocation to be valid")
+	}
+
+	// Markers written in whole seconds still cover their second
+	server.Set(blacklistPrefix+"user:"+userID.String(), "1709294400")
+	legacy := validate(after)
+	legacy.IssuedAtNano = 0
+	if revoked, _ := blacklist.IsTokenRevoked(ctx, legacy); !revoked {
+		t.Error("Expected a legacy marker to revoke tokens issued in its second")
+	}
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// deviceTokenBytes is the amount of randomness in a trusted-device token
+const deviceTokenBytes = 32
+
+// GenerateDeviceToken generates a random token identifying a trusted device
+func GenerateDeviceToken() (string, error) {
+	b := make([]byte, deviceTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate device token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashDeviceToken hashes a device token for storage; only the hash is persisted
+func HashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyDeviceToken returns true if token matches the stored hash
+func VerifyDeviceToken(hash, token string) bool {
+	if hash == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashDeviceToken(token))) == 1
+}
@@ -8,95 +8,349 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"tgfinance/pkg/clock"
+)
+
+// Defaults used for settings missing from the configuration
+const (
+	DefaultIssuer        = "tgfinance"
+	DefaultAudience      = "tgfinance-api"
+	DefaultAccessExpiry  = 24 * time.Hour
+	DefaultRefreshExpiry = 7 * 24 * time.Hour
+	defaultSecret        = "your-super-secret-jwt-key-change-in-production"
 )
 
+// Token types carried in the token_type claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Roles carried in the role claim
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ErrWrongTokenType is returned when a valid token of another type is presented
+var ErrWrongTokenType = errors.New("wrong token type")
+
 // Claims represents the JWT claims
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	TokenType   string    `json:"token_type"`
+	Role        string    `json:"role,omitempty"`
+	Permissions []string  `json:"permissions,omitempty"`
+	MFA         bool      `json:"mfa,omitempty"`
+	// IssuedAtNano is the issue time in Unix nanoseconds, since iat only
+	// has whole seconds
+	IssuedAtNano int64 `json:"iat_ns,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IssuedTime returns when the token was issued, falling back to the whole
+// second of iat for tokens without iat_ns. It is zero if neither is set.
+func (c *Claims) IssuedTime() time.Time {
+	if c.IssuedAtNano != 0 {
+		return time.Unix(0, c.IssuedAtNano)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// EffectiveRole returns the role of the token, treating tokens issued
+// before roles were added as regular users
+func (c *Claims) EffectiveRole() string {
+	if c.Role == "" {
+		return RoleUser
+	}
+	return c.Role
+}
+
+// HasPermission returns true if the token grants the permission
+func (c *Claims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTOptions configure a JWTManager. Empty or non-positive settings fall
+// back to the defaults.
+type JWTOptions struct {
+	Secret string
+	// Keys maps key IDs to secrets; KeyID selects the signing key
+	Keys  map[string]string
+	KeyID string
+
+	Issuer           string
+	Audience         string
+	AllowedIssuers   []string
+	AllowedAudiences []string
+
+	AccessExpiry  time.Duration
+	RefreshExpiry time.Duration
+	// ClockSkew is the leeway allowed on exp, nbf and iat
+	ClockSkew time.Duration
+}
+
 // JWTManager handles JWT token operations
 type JWTManager struct {
-	secretKey []byte
-	issuer    string
+	secretKey     []byte
+	keyID         string
+	keys          map[string][]byte
+	issuer        string
+	audience      string
+	issuers       []string
+	audiences     []string
+	accessExpiry  time.Duration
+	refreshExpiry time.Duration
+	leeway        time.Duration
+	clock         clock.Clock
 }
 
-// NewJWTManager creates a new JWT manager
+// NewJWTManager creates a JWT manager from the JWT_SECRET environment
+// variable with the default issuer and expiries. Prefer
+// NewJWTManagerWithOptions, which honors the loaded configuration.
 func NewJWTManager() *JWTManager {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "your-super-secret-jwt-key-change-in-production"
-	}
+	return NewJWTManagerWithOptions(JWTOptions{Secret: os.Getenv("JWT_SECRET")})
+}
 
-	return &JWTManager{
-		secretKey: []byte(secretKey),
-		issuer:    "tgfinance",
+// NewJWTManagerWithOptions creates a JWT manager. With Keys, tokens are
+// signed with the key KeyID names and carry its kid; the other keys still
+// verify tokens issued before a rotation. Secret is then unused, so a KeyID
+// missing from Keys makes token generation fail rather than silently
+// signing without a kid. Validation accepts the
+// manager's own issuer and audience unless AllowedIssuers or
+// AllowedAudiences list the accepted values.
+func NewJWTManagerWithOptions(opts JWTOptions) *JWTManager {
+	j := &JWTManager{
+		secretKey:     []byte(opts.Secret),
+		issuer:        opts.Issuer,
+		audience:      opts.Audience,
+		issuers:       opts.AllowedIssuers,
+		audiences:     opts.AllowedAudiences,
+		accessExpiry:  opts.AccessExpiry,
+		refreshExpiry: opts.RefreshExpiry,
+		leeway:        opts.ClockSkew,
+		clock:         clock.Real(),
+	}
+	if len(opts.Keys) > 0 {
+		j.keys = make(map[string][]byte, len(opts.Keys))
+		for kid, secret := range opts.Keys {
+			j.keys[kid] = []byte(secret)
+		}
+		j.keyID = opts.KeyID
+		j.secretKey = j.keys[opts.KeyID]
+	} else if len(j.secretKey) == 0 {
+		j.secretKey = []byte(defaultSecret)
+	}
+	if j.issuer == "" {
+		j.issuer = DefaultIssuer
+	}
+	if j.audience == "" {
+		j.audience = DefaultAudience
+	}
+	if len(j.issuers) == 0 {
+		j.issuers = []string{j.issuer}
+	}
+	if len(j.audiences) == 0 {
+		j.audiences = []string{j.audience}
 	}
+	if j.accessExpiry <= 0 {
+		j.accessExpiry = DefaultAccessExpiry
+	}
+	if j.refreshExpiry <= 0 {
+		j.refreshExpiry = DefaultRefreshExpiry
+	}
+	return j
 }
 
-// GenerateToken generates a new JWT token for a user
-func (j *JWTManager) GenerateToken(userID uuid.UUID, email string) (string, error) {
-	now := time.Now()
-	expiresAt := now.Add(24 * time.Hour) // 24 hours
+// WithClock sets the clock used to issue and validate tokens
+func (j *JWTManager) WithClock(c clock.Clock) *JWTManager {
+	j.clock = c
+	return j
+}
 
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    j.issuer,
-			Subject:   userID.String(),
-		},
-	}
+// TokenPair is an access and refresh token issued together, with their
+// expiry so clients can schedule refreshes
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	TokenType        string    `json:"token_type"`
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+// GenerateToken generates a new JWT token for a user with the role and
+// permissions. An empty role issues a regular user token.
+func (j *JWTManager) GenerateToken(userID uuid.UUID, email, role string, permissions ...string) (string, error) {
+	claims := &Claims{UserID: userID, Email: email, TokenType: TokenTypeAccess, Role: role, Permissions: permissions}
+	token, _, err := j.generate(claims, j.clock.Now(), j.accessExpiry)
+	return token, err
 }
 
-// GenerateRefreshToken generates a refresh token
+// GenerateMFAToken generates an access token for a user who has completed a
+// second factor, which RequireMFA routes demand
+func (j *JWTManager) GenerateMFAToken(userID uuid.UUID, email, role string, permissions ...string) (string, error) {
+	claims := &Claims{UserID: userID, Email: email, TokenType: TokenTypeAccess, Role: role, Permissions: permissions, MFA: true}
+	token, _, err := j.generate(claims, j.clock.Now(), j.accessExpiry)
+	return token, err
+}
+
+// GenerateRefreshToken generates a refresh token. It carries no role, which
+// is looked up again when the access token is refreshed.
 func (j *JWTManager) GenerateRefreshToken(userID uuid.UUID) (string, error) {
-	now := time.Now()
-	expiresAt := now.Add(7 * 24 * time.Hour) // 7 days
+	token, _, err := j.generate(&Claims{UserID: userID, TokenType: TokenTypeRefresh}, j.clock.Now(), j.refreshExpiry)
+	return token, err
+}
 
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    j.issuer,
-			Subject:   userID.String(),
-		},
+// GenerateTokenPair generates an access and a refresh token issued at the same instant
+func (j *JWTManager) GenerateTokenPair(userID uuid.UUID, email, role string, permissions ...string) (*TokenPair, error) {
+	now := j.clock.Now()
+	access, accessExpiresAt, err := j.generate(&Claims{
+		UserID: userID, Email: email, TokenType: TokenTypeAccess, Role: role, Permissions: permissions,
+	}, now, j.accessExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
+	refresh, refreshExpiresAt, err := j.generate(&Claims{UserID: userID, TokenType: TokenTypeRefresh}, now, j.refreshExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshExpiresAt: refreshExpiresAt,
+		TokenType:        "Bearer",
+	}, nil
+}
+
+// generate fills in the registered claims for a token issued at now, signs
+// it and returns its expiry
+func (j *JWTManager) generate(claims *Claims, now time.Time, expiry time.Duration) (string, time.Time, error) {
+	if len(j.secretKey) == 0 {
+		return "", time.Time{}, fmt.Errorf("signing key %q is not among the JWT keys", j.keyID)
+	}
+	expiresAt := now.Add(expiry)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	claims.IssuedAtNano = now.UnixNano()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    j.issuer,
+		Audience:  jwt.ClaimStrings{j.audience},
+		Subject:   claims.UserID.String(),
+		ID:        uuid.NewString(),
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if j.keyID != "" {
+		unsigned.Header["kid"] = j.keyID
+	}
+	token, err := unsigned.SignedString(j.secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token of any type and returns the claims.
+// Use ValidateAccessToken or ValidateRefreshToken to authorize requests.
+// The exp, nbf and iat checks tolerate the configured clock skew, and the
+// issuer and audience must be among the accepted ones.
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := j.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// parse validates a token like ValidateToken but also returns the claims of
+// correctly signed tokens that fail a time or audience check, so callers can
+// report why they were refused
+func (j *JWTManager) parse(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return j.secretKey, nil
-	})
+		return j.verificationKey(token)
+	}, jwt.WithTimeFunc(j.clock.Now), jwt.WithLeeway(j.leeway), jwt.WithIssuedAt(), jwt.WithAudience(j.audiences...))
 
 	if err != nil {
+		// Claims errors are only reported once the signature is verified
+		if errors.Is(err, jwt.ErrTokenInvalidClaims) {
+			if claims, ok := token.Claims.(*Claims); ok {
+				return claims, err
+			}
+		}
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if !contains(j.issuers, claims.Issuer) {
+			return claims, fmt.Errorf("%w: %q", jwt.ErrTokenInvalidIssuer, claims.Issuer)
+		}
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
+// verificationKey selects the key by the kid header. Tokens without a kid
+// are checked against the current signing key.
+func (j *JWTManager) verificationKey(token *jwt.Token) ([]byte, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return j.secretKey, nil
+	}
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// ValidateAccessToken validates an access token, rejecting refresh tokens
+// and tokens without a type
+func (j *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return j.validateType(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a refresh token, rejecting access tokens
+// and tokens without a type
+func (j *JWTManager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return j.validateType(tokenString, TokenTypeRefresh)
+}
+
+// validateType validates a token and checks its type
+func (j *JWTManager) validateType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType {
+		return nil, fmt.Errorf("%w: expected %s token", ErrWrongTokenType, tokenType)
+	}
+	return claims, nil
+}
+
+// RefreshExpiry returns the lifetime of refresh tokens, the longest any
+// token issued by the manager stays valid
+func (j *JWTManager) RefreshExpiry() time.Duration {
+	return j.refreshExpiry
+}
+
 // ExtractUserIDFromToken extracts user ID from token without full validation
 func (j *JWTManager) ExtractUserIDFromToken(tokenString string) (uuid.UUID, error) {
 	claims, err := j.ValidateToken(tokenString)
@@ -105,3 +359,12 @@ func (j *JWTManager) ExtractUserIDFromToken(tokenString string) (uuid.UUID, erro
 	}
 	return claims.UserID, nil
 }
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
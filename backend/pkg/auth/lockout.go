@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+)
+
+// LockoutPolicy locks an account for Duration after MaxFailures failed
+// logins within Window
+type LockoutPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	Duration    time.Duration
+}
+
+// DefaultLockoutPolicy locks for 15 minutes after 5 failures in 15 minutes
+var DefaultLockoutPolicy = LockoutPolicy{MaxFailures: 5, Window: 15 * time.Minute, Duration: 15 * time.Minute}
+
+// WithDefaults returns the policy with non-positive settings replaced by
+// those of DefaultLockoutPolicy
+func (p LockoutPolicy) WithDefaults() LockoutPolicy {
+	if p.MaxFailures <= 0 {
+		p.MaxFailures = DefaultLockoutPolicy.MaxFailures
+	}
+	if p.Window <= 0 {
+		p.Window = DefaultLockoutPolicy.Window
+	}
+	if p.Duration <= 0 {
+		p.Duration = DefaultLockoutPolicy.Duration
+	}
+	return p
+}
+
+// LoginAttemptTracker throttles brute-force logins per account. RecordFailure
+// and IsLocked return the remaining lockout, zero when the account is not
+// locked.
+type LoginAttemptTracker interface {
+	RecordFailure(ctx context.Context, email, ip string) (time.Duration, error)
+	RecordSuccess(ctx context.Context, email string) error
+	IsLocked(ctx context.Context, email string) (time.Duration, error)
+}
+
+// lockoutPrefix namespaces the login attempt keys in Redis
+const lockoutPrefix = "auth:login:"
+
+// recordFailureScript counts a failure and locks the account when the limit
+// is reached, atomically so concurrent attempts cannot slip past the limit.
+// Failures while locked are not counted. Returns the remaining lockout in
+// milliseconds and whether this failure triggered it.
+var recordFailureScript = redis.NewScript(`
+local locked = redis.call('PTTL', KEYS[2])
+if locked > 0 then
+	return {locked, 0}
+end
+local failures = redis.call('INCR', KEYS[1])
+if failures == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+if failures >= tonumber(ARGV[2]) then
+	redis.call('SET', KEYS[2], failures, 'PX', ARGV[3])
+	redis.call('DEL', KEYS[1])
+	return {tonumber(ARGV[3]), failures}
+end
+return {0, 0}
+`)
+
+// RedisLoginAttemptTracker tracks login attempts in Redis so lockouts hold
+// across instances
+type RedisLoginAttemptTracker struct {
+	client redis.UniversalClient
+	policy LockoutPolicy
+	logger *logger.Logger
+}
+
+// NewRedisLoginAttemptTrackerWithClient creates a tracker over an existing
+// Redis client. Unset policy settings take their defaults.
+func NewRedisLoginAttemptTrackerWithClient(client redis.UniversalClient, policy LockoutPolicy, log *logger.Logger) *RedisLoginAttemptTracker {
+	return &RedisLoginAttemptTracker{client: client, policy: policy.WithDefaults(), logger: log}
+}
+
+// RecordFailure counts a failed login and returns the lockout if the account is locked
+func (t *RedisLoginAttemptTracker) RecordFailure(ctx context.Context, email, ip string) (time.Duration, error) {
+	key := normalizeLoginEmail(email)
+	result, err := recordFailureScript.Run(ctx, t.client,
+		[]string{lockoutPrefix + "failures:" + key, lockoutPrefix + "locked:" + key},
+		t.policy.Window.Milliseconds(), t.policy.MaxFailures, t.policy.Duration.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	remaining := time.Duration(result[0]) * time.Millisecond
+	if result[1] > 0 {
+		logLockout(t.logger, key, ip, int(result[1]), remaining)
+	}
+	return remaining, nil
+}
+
+// RecordSuccess clears the failure count; an active lockout stays in place
+func (t *RedisLoginAttemptTracker) RecordSuccess(ctx context.Context, email string) error {
+	return t.client.Del(ctx, lockoutPrefix+"failures:"+normalizeLoginEmail(email)).Err()
+}
+
+// IsLocked returns the remaining lockout of the account
+func (t *RedisLoginAttemptTracker) IsLocked(ctx context.Context, email string) (time.Duration, error) {
+	ttl, err := t.client.PTTL(ctx, lockoutPrefix+"locked:"+normalizeLoginEmail(email)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check account lockout: %w", err)
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// MemoryLoginAttemptTracker tracks login attempts in process, for tests and
+// single-node deployments without Redis. Accounts are forgotten once their
+// window and lockout have both passed, so failures against many addresses
+// cannot grow memory without bound.
+type MemoryLoginAttemptTracker struct {
+	mu       sync.Mutex
+	policy   LockoutPolicy
+	logger   *logger.Logger
+	clock    clock.Clock
+	attempts map[string]*loginAttempts
+	swept    time.Time
+}
+
+type loginAttempts struct {
+	failures    int
+	windowEnd   time.Time
+	lockedUntil time.Time
+}
+
+// expired returns true once neither the window nor the lockout is running
+func (a *loginAttempts) expired(now time.Time) bool {
+	return !now.Before(a.windowEnd) && !now.Before(a.lockedUntil)
+}
+
+// NewMemoryLoginAttemptTracker creates an in-process tracker. Unset policy
+// settings take their defaults.
+func NewMemoryLoginAttemptTracker(policy LockoutPolicy, log *logger.Logger) *MemoryLoginAttemptTracker {
+	return &MemoryLoginAttemptTracker{policy: policy.WithDefaults(), logger: log, clock: clock.Real(), attempts: map[string]*loginAttempts{}}
+}
+
+// WithClock sets the clock used for windows and lockouts
+func (t *MemoryLoginAttemptTracker) WithClock(c clock.Clock) *MemoryLoginAttemptTracker {
+	t.clock = c
+	return t
+}
+
+// RecordFailure counts a failed login and returns the lockout if the account is locked
+func (t *MemoryLoginAttemptTracker) RecordFailure(ctx context.Context, email, ip string) (time.Duration, error) {
+	key := normalizeLoginEmail(email)
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	t.sweep(now)
+	a, ok := t.attempts[key]
+	if !ok {
+		a = &loginAttempts{}
+		t.attempts[key] = a
+	}
+	if now.Before(a.lockedUntil) {
+		t.mu.Unlock()
+		return a.lockedUntil.Sub(now), nil
+	}
+	if !now.Before(a.windowEnd) {
+		a.failures = 0
+		a.windowEnd = now.Add(t.policy.Window)
+	}
+	a.failures++
+	failures := a.failures
+	if failures < t.policy.MaxFailures {
+		t.mu.Unlock()
+		return 0, nil
+	}
+	a.failures = 0
+	a.windowEnd = time.Time{}
+	a.lockedUntil = now.Add(t.policy.Duration)
+	t.mu.Unlock()
+
+	logLockout(t.logger, key, ip, failures, t.policy.Duration)
+	return t.policy.Duration, nil
+}
+
+// RecordSuccess clears the failure count; an active lockout stays in place
+func (t *MemoryLoginAttemptTracker) RecordSuccess(ctx context.Context, email string) error {
+	key := normalizeLoginEmail(email)
+	now := t.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a, ok := t.attempts[key]
+	if !ok {
+		return nil
+	}
+	if !now.Before(a.lockedUntil) {
+		delete(t.attempts, key)
+		return nil
+	}
+	a.failures = 0
+	return nil
+}
+
+// IsLocked returns the remaining lockout of the account
+func (t *MemoryLoginAttemptTracker) IsLocked(ctx context.Context, email string) (time.Duration, error) {
+	now := t.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if a, ok := t.attempts[normalizeLoginEmail(email)]; ok && now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now), nil
+	}
+	return 0, nil
+}
+
+// sweep drops the accounts whose window and lockout have passed, at most
+// once a minute. The caller holds t.mu.
+func (t *MemoryLoginAttemptTracker) sweep(now time.Time) {
+	if now.Sub(t.swept) < time.Minute {
+		return
+	}
+	t.swept = now
+
+	for key, a := range t.attempts {
+		if a.expired(now) {
+			delete(t.attempts, key)
+		}
+	}
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func logLockout(log *logger.Logger, email, ip string, failures int, lockout time.Duration) {
+	if log == nil {
+		return
+	}
+	log.WithFields(logrus.Fields{
+		"email":    logger.MaskEmail(email),
+		"ip":       ip,
+		"failures": failures,
+		"lockout":  lockout.String(),
+	}).Warn("Account locked after repeated failed logins")
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/pkg/clock"
+	"tgfinance/pkg/logger"
+)
+
+// testLockout runs the lockout policy against a tracker; advance moves the
+// tracker's notion of time forward
+func testLockout(t *testing.T, tracker LoginAttemptTracker, advance func(time.Duration)) {
+	ctx := context.Background()
+	policy := LockoutPolicy{MaxFailures: 3, Window: time.Minute, Duration: 10 * time.Minute}
+
+	fail := func(email string) time.Duration {
+		t.Helper()
+		lockout, err := tracker.RecordFailure(ctx, email, "203.0.113.7")
+		if err != nil {
+			t.Fatalf("Failed to record failure: %v", err)
+		}
+		return lockout
+	}
+
+	// Failures spread beyond the window do not add up
+	fail("user@example.com")
+	fail("user@example.com")
+	advance(policy.Window + time.Second)
+	if lockout := fail("user@example.com"); lockout != 0 {
+		t.Errorf("Expected failures outside the window not to lock, got %v", lockout)
+	}
+
+	// A success resets the count
+	tracker.RecordSuccess(ctx, "user@example.com")
+	fail("user@example.com")
+	fail("user@example.com")
+	if lockout, _ := tracker.IsLocked(ctx, "user@example.com"); lockout != 0 {
+		t.Errorf("Expected account not to be locked after a success, got %v", lockout)
+	}
+
+	// The third failure in the window locks, matching emails case-insensitively
+	if lockout := fail("USER@example.com "); lockout != policy.Duration {
+		t.Errorf("Expected lockout of %v, got %v", policy.Duration, lockout)
+	}
+	advance(time.Minute)
+	if lockout, _ := tracker.IsLocked(ctx, "user@example.com"); lockout != policy.Duration-time.Minute {
+		t.Errorf("Expected remaining lockout of %v, got %v", policy.Duration-time.Minute, lockout)
+	}
+	if lockout := fail("user@example.com"); lockout != policy.Duration-time.Minute {
+		t.Errorf("Expected failures while locked not to extend the lockout, got %v", lockout)
+	}
+	tracker.RecordSuccess(ctx, "user@example.com")
+	if lockout, _ := tracker.IsLocked(ctx, "user@example.com"); lockout == 0 {
+		t.Error("Expected a success not to lift an active lockout")
+	}
+	if lockout, _ := tracker.IsLocked(ctx, "other@example.com"); lockout != 0 {
+		t.Errorf("Expected other accounts to be unaffected, got %v", lockout)
+	}
+
+	advance(policy.Duration)
+	if lockout, _ := tracker.IsLocked(ctx, "user@example.com"); lockout != 0 {
+		t.Errorf("Expected lockout to expire, got %v", lockout)
+	}
+	if lockout := fail("user@example.com"); lockout != 0 {
+		t.Errorf("Expected count to restart after the lockout, got %v", lockout)
+	}
+}
+
+func TestRedisLoginAttemptTracker(t *testing.T) {
+	server := miniredis.RunT(t)
+	policy := LockoutPolicy{MaxFailures: 3, Window: time.Minute, Duration: 10 * time.Minute}
+	tracker := NewRedisLoginAttemptTrackerWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}), policy,
+		logger.New("info", "json", "stdout", ""))
+
+	testLockout(t, tracker, server.FastForward)
+}
+
+func TestMemoryLoginAttemptTracker(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	policy := LockoutPolicy{MaxFailures: 3, Window: time.Minute, Duration: 10 * time.Minute}
+	tracker := NewMemoryLoginAttemptTracker(policy, logger.New("info", "json", "stdout", "")).WithClock(fake)
+
+	testLockout(t, tracker, fake.Advance)
+}
+
+func TestMemoryLoginAttemptTrackerForgetsAccounts(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	policy := LockoutPolicy{MaxFailures: 2, Window: time.Minute, Duration: 10 * time.Minute}
+	var logs strings.Builder
+	log := logger.New("info", "json", "stdout", "")
+	log.SetOutput(&logs)
+	tracker := NewMemoryLoginAttemptTracker(policy, log).WithClock(fake)
+
+	for i := 0; i < 100; i++ {
+		tracker.RecordFailure(ctx, fmt.Sprintf("user%d@example.com", i), "203.0.113.7")
+	}
+	tracker.RecordFailure(ctx, "locked@example.com", "203.0.113.7")
+	tracker.RecordFailure(ctx, "locked@example.com", "203.0.113.7")
+	if strings.Contains(logs.String(), "locked@example.com") {
+		t.Errorf("Expected the lockout log to mask the email, got %s", logs.String())
+	}
+
+	// A success forgets an account that is not locked
+	tracker.RecordSuccess(ctx, "user0@example.com")
+	if _, ok := tracker.attempts["user0@example.com"]; ok {
+		t.Error("Expected a success to drop the account")
+	}
+
+	// Once the windows pass only the locked account is kept
+	fake.Advance(policy.Window + time.Second)
+	tracker.RecordFailure(ctx, "late@example.com", "203.0.113.7")
+	if len(tracker.attempts) != 2 {
+		t.Errorf("Expected expired accounts to be swept, %d remain", len(tracker.attempts))
+	}
+	if lockout, _ := tracker.IsLocked(ctx, "locked@example.com"); lockout == 0 {
+		t.Error("Expected the sweep to keep an active lockout")
+	}
+
+	fake.Advance(policy.Duration)
+	tracker.RecordFailure(ctx, "late@example.com", "203.0.113.7")
+	if _, ok := tracker.attempts["locked@example.com"]; ok {
+		t.Error("Expected the account to be swept once its lockout passed")
+	}
+}
+
+func TestLoginAttemptTrackerConcurrentFailures(t *testing.T) {
+	server := miniredis.RunT(t)
+	policy := LockoutPolicy{MaxFailures: 5, Window: time.Minute, Duration: time.Minute}
+	trackers := map[string]LoginAttemptTracker{
+		"redis":  NewRedisLoginAttemptTrackerWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}), policy, nil),
+		"memory": NewMemoryLoginAttemptTracker(policy, nil),
+	}
+
+	for name, tracker := range trackers {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		allowed := 0
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lockout, err := tracker.RecordFailure(context.Background(), "user@example.com", "203.0.113.7")
+				if err != nil {
+					t.Errorf("%s: failed to record failure: %v", name, err)
+				}
+				if lockout == 0 {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Only the failures before the limit go unlocked
+		if allowed != policy.MaxFailures-1 {
+			t.Errorf("%s: %d failures went unlocked, want %d", name, allowed, policy.MaxFailures-1)
+		}
+	}
+}
+
+func TestLockoutPolicyWithDefaults(t *testing.T) {
+	if policy := (LockoutPolicy{}).WithDefaults(); policy != DefaultLockoutPolicy {
+		t.Errorf("Expected defaults, got %+v", policy)
+	}
+	policy := LockoutPolicy{MaxFailures: 10, Window: time.Hour, Duration: 2 * time.Hour}.WithDefaults()
+	if policy.MaxFailures != 10 || policy.Window != time.Hour || policy.Duration != 2*time.Hour {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+}
+
+func TestTrackersDefaultEmptyPolicy(t *testing.T) {
+	server := miniredis.RunT(t)
+	log := logger.New("error", "json", "stdout", "")
+	trackers := map[string]LoginAttemptTracker{
+		"redis":  NewRedisLoginAttemptTrackerWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}), LockoutPolicy{}, log),
+		"memory": NewMemoryLoginAttemptTracker(LockoutPolicy{}, log),
+	}
+	for name, tracker := range trackers {
+		// The first failure neither errors on a zero expiry nor locks
+		lockout, err := tracker.RecordFailure(context.Background(), "user@example.com", "203.0.113.7")
+		if err != nil || lockout != 0 {
+			t.Errorf("%s: first failure = %v, %v", name, lockout, err)
+		}
+		for i := 1; i < DefaultLockoutPolicy.MaxFailures; i++ {
+			lockout, err = tracker.RecordFailure(context.Background(), "user@example.com", "203.0.113.7")
+		}
+		if err != nil || lockout != DefaultLockoutPolicy.Duration {
+			t.Errorf("%s: lockout after %d failures = %v, %v", name, DefaultLockoutPolicy.MaxFailures, lockout, err)
+		}
+	}
+}
@@ -1,30 +1,148 @@
 package auth
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 	"unicode"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Password hashing algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Password verification errors. ErrPasswordMismatch is bcrypt's mismatch
+// error so existing checks keep working for both formats.
+var (
+	ErrPasswordMismatch = bcrypt.ErrMismatchedHashAndPassword
+	ErrInvalidHash      = errors.New("invalid password hash")
+)
+
+// Argon2Params are the Argon2id cost parameters; Memory is in KiB
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the OWASP recommendation for Argon2id
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
 // PasswordManager handles password hashing and verification
 type PasswordManager struct {
-	cost int
+	cost      int
+	algorithm string
+	argon2    Argon2Params
+	peppers   [][]byte
+	policy    PasswordPolicy
 }
 
-// NewPasswordManager creates a new password manager
+// PasswordPolicy rejects passwords that meet the strength rules but are
+// still unsafe, such as common or breached passwords
+type PasswordPolicy interface {
+	Validate(ctx context.Context, password string) error
+}
+
+// WithPolicy adds the policy to the strength checks of HashPassword and
+// IsPasswordValid
+func (pm *PasswordManager) WithPolicy(policy PasswordPolicy) *PasswordManager {
+	pm.policy = policy
+	return pm
+}
+
+// NewPasswordManager creates a new password manager hashing with bcrypt
 func NewPasswordManager() *PasswordManager {
 	return &PasswordManager{
-		cost: bcrypt.DefaultCost, // 10 rounds
+		cost:      bcrypt.DefaultCost, // 10 rounds
+		algorithm: AlgorithmBcrypt,
+		argon2:    DefaultArgon2Params,
+	}
+}
+
+// NewPasswordManagerWithCost creates a password manager hashing with bcrypt
+// at the cost, which must be between bcrypt.MinCost and bcrypt.MaxCost
+func NewPasswordManagerWithCost(cost int) (*PasswordManager, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	pm := NewPasswordManager()
+	pm.cost = cost
+	return pm, nil
+}
+
+// PasswordOptions configure a PasswordManager
+type PasswordOptions struct {
+	// Algorithm is AlgorithmBcrypt or AlgorithmArgon2id
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+	// Peppers are HMAC keys applied before hashing, current first
+	Peppers []string
+}
+
+// NewPasswordManagerWithOptions creates a password manager hashing with the
+// given algorithm and pepper. Unknown algorithms fall back to bcrypt, and
+// out-of-range bcrypt costs and non-positive Argon2 parameters to the
+// defaults.
+func NewPasswordManagerWithOptions(opts PasswordOptions) *PasswordManager {
+	pm := NewPasswordManager()
+	for _, pepper := range opts.Peppers {
+		pm.peppers = append(pm.peppers, []byte(pepper))
+	}
+	if opts.BcryptCost >= bcrypt.MinCost && opts.BcryptCost <= bcrypt.MaxCost {
+		pm.cost = opts.BcryptCost
+	}
+	if opts.Algorithm == AlgorithmArgon2id {
+		pm.algorithm = AlgorithmArgon2id
+	}
+	if opts.Argon2Memory > 0 {
+		pm.argon2.Memory = uint32(opts.Argon2Memory)
+	}
+	if opts.Argon2Iterations > 0 {
+		pm.argon2.Iterations = uint32(opts.Argon2Iterations)
 	}
+	if opts.Argon2Parallelism > 0 && opts.Argon2Parallelism <= 255 {
+		pm.argon2.Parallelism = uint8(opts.Argon2Parallelism)
+	}
+	return pm
 }
 
-// HashPassword hashes a password using bcrypt
+// HashPassword hashes a password using the configured algorithm, peppered
+// with the current pepper if one is configured
 func (pm *PasswordManager) HashPassword(password string) (string, error) {
 	// Validate password strength before hashing
 	if err := pm.validatePasswordStrength(password); err != nil {
 		return "", err
 	}
+	if len(pm.peppers) > 0 {
+		password = applyPepper(pm.peppers[0], password)
+	}
+
+	if pm.algorithm == AlgorithmArgon2id {
+		return hashArgon2id(password, pm.argon2)
+	}
 
 	// Hash the password
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), pm.cost)
@@ -35,9 +153,119 @@ func (pm *PasswordManager) HashPassword(password string) (string, error) {
 	return string(hashedBytes), nil
 }
 
-// VerifyPassword verifies a password against its hash
+// VerifyPassword verifies a password against its hash, detecting the
+// algorithm from the hash format
 func (pm *PasswordManager) VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	_, err := pm.CheckPassword(hashedPassword, password)
+	return err
+}
+
+// CheckPassword verifies a password like VerifyPassword and reports whether
+// the hash should be replaced: it was made with an old pepper or none, or
+// NeedsRehash is true. Peppers are tried current first, then the historical
+// ones, then none, so a wrong password costs one hash per pepper plus one.
+func (pm *PasswordManager) CheckPassword(hashedPassword, password string) (bool, error) {
+	for i, pepper := range pm.peppers {
+		err := compareHash(hashedPassword, applyPepper(pepper, password))
+		if err == nil {
+			return i > 0 || pm.NeedsRehash(hashedPassword), nil
+		}
+		if !errors.Is(err, ErrPasswordMismatch) {
+			return false, err
+		}
+	}
+
+	if err := compareHash(hashedPassword, password); err != nil {
+		return false, err
+	}
+	return len(pm.peppers) > 0 || pm.NeedsRehash(hashedPassword), nil
+}
+
+// applyPepper keys the password with the pepper. The base64 encoded
+// HMAC-SHA256 stays under bcrypt's 72 byte input limit.
+func applyPepper(pepper []byte, password string) string {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// compareHash compares an input against a hash, detecting the algorithm
+// from the hash format
+func compareHash(hashedPassword, input string) error {
+	if strings.HasPrefix(hashedPassword, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hashedPassword)
+		if err != nil {
+			return err
+		}
+		candidate := argon2.IDKey([]byte(input), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		if subtle.ConstantTimeCompare(key, candidate) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(input))
+}
+
+// NeedsRehash returns true if the hash was made with another algorithm or
+// other parameters than configured, including a different bcrypt cost, so it
+// should be replaced after the next successful login
+func (pm *PasswordManager) NeedsRehash(hashedPassword string) bool {
+	if strings.HasPrefix(hashedPassword, "$argon2id$") {
+		if pm.algorithm != AlgorithmArgon2id {
+			return true
+		}
+		params, _, _, err := decodeArgon2id(hashedPassword)
+		return err != nil || params.Memory != pm.argon2.Memory ||
+			params.Iterations != pm.argon2.Iterations || params.Parallelism != pm.argon2.Parallelism
+	}
+
+	if pm.algorithm != AlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != pm.cost
+}
+
+// hashArgon2id hashes a password in the $argon2id$v=19$m=,t=,p=$salt$hash format
+func hashArgon2id(password string, params Argon2Params) (string, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// decodeArgon2id parses an encoded Argon2id hash
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported version", ErrInvalidHash)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
 }
 
 // validatePasswordStrength validates password requirements
@@ -86,6 +314,10 @@ func (pm *PasswordManager) validatePasswordStrength(password string) error {
 		return errors.New("password must contain at least one special character")
 	}
 
+	if pm.policy != nil {
+		return pm.policy.Validate(context.Background(), password)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,126 @@
+package passwordcheck
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tgfinance/pkg/logger"
+)
+
+func TestIsCommon(t *testing.T) {
+	tests := []struct {
+		password string
+		common   bool
+	}{
+		{"Password123!", true},
+		{"P@ssw0rd", true},
+		{"QWERTY", true},
+		{"Dragon99!", true},
+		{"letmein", true},
+		{"M0nk3y", true},
+		{"Summer2024!", true},
+		{"Gl4cier-Tandem-Oboe7", false},
+		{"xK9#mQ2$vL", false},
+	}
+	for _, tt := range tests {
+		if got := IsCommon(tt.password); got != tt.common {
+			t.Errorf("IsCommon(%q) = %v, want %v", tt.password, got, tt.common)
+		}
+	}
+}
+
+type fakeBreaches struct {
+	breached bool
+	err      error
+}
+
+func (f fakeBreaches) IsBreached(ctx context.Context, password string) (bool, error) {
+	return f.breached, f.err
+}
+
+func TestPolicy(t *testing.T) {
+	log := logger.New("info", "json", "stdout", "")
+	ctx := context.Background()
+	strong := "Gl4cier-Tandem-Oboe7"
+
+	if err := NewPolicy(nil, log).Validate(ctx, "Password123!"); !errors.Is(err, ErrCommonPassword) {
+		t.Errorf("Expected common password error, got %v", err)
+	}
+	if err := NewPolicy(nil, log).Validate(ctx, strong); err != nil {
+		t.Errorf("Expected strong password without breach checker to pass, got %v", err)
+	}
+	if err := NewPolicy(fakeBreaches{breached: true}, log).Validate(ctx, strong); !errors.Is(err, ErrBreachedPassword) {
+		t.Errorf("Expected breached password error, got %v", err)
+	}
+	// Network failures never block the password
+	if err := NewPolicy(fakeBreaches{err: errors.New("timeout")}, log).Validate(ctx, strong); err != nil {
+		t.Errorf("Expected failing breach check to fail open, got %v", err)
+	}
+}
+
+func TestHIBPClient(t *testing.T) {
+	breached := "Gl4cier-Tandem-Oboe7"
+	sum := sha1.Sum([]byte(breached))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	padded := "Another-Strong-Pass9"
+	paddedSum := sha1.Sum([]byte(padded))
+	paddedHash := strings.ToUpper(hex.EncodeToString(paddedSum[:]))
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Error("Expected padding to be requested")
+		}
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:42\r\n%s:0\r\n", hash[5:], paddedHash[5:])
+	}))
+	defer server.Close()
+
+	client := NewHIBPClient(time.Second)
+	client.baseURL = server.URL + "/range/"
+	ctx := context.Background()
+
+	if ok, err := client.IsBreached(ctx, breached); err != nil || !ok {
+		t.Errorf("Expected breached password to be found, got %v %v", ok, err)
+	}
+	if paths[0] != "/range/"+hash[:5] {
+		t.Errorf("Expected only the hash prefix to be sent, got %s", paths[0])
+	}
+	if ok, err := client.IsBreached(ctx, padded); err != nil || ok {
+		t.Errorf("Expected padding entry not to count as breached, got %v %v", ok, err)
+	}
+	if ok, err := client.IsBreached(ctx, "Unlisted-Pass-123"); err != nil || ok {
+		t.Errorf("Expected unlisted password not to be breached, got %v %v", ok, err)
+	}
+}
+
+func TestHIBPClientFailures(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	for name, url := range map[string]string{"timeout": slow.URL, "status": failing.URL} {
+		client := NewHIBPClient(50 * time.Millisecond)
+		client.baseURL = url + "/range/"
+		if _, err := client.IsBreached(context.Background(), "Gl4cier-Tandem-Oboe7"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		policy := NewPolicy(client, logger.New("info", "json", "stdout", ""))
+		if err := policy.Validate(context.Background(), "Gl4cier-Tandem-Oboe7"); err != nil {
+			t.Errorf("%s: expected policy to fail open, got %v", name, err)
+		}
+	}
+}
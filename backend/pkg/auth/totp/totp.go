@@ -0,0 +1,168 @@
+// Package totp implements time-based one-time passwords (RFC 6238) and
+// recovery codes for two-factor authentication.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters understood by common authenticator apps
+const (
+	Issuer        = "TGFinance"
+	Digits        = 6
+	Period        = 30 * time.Second
+	DefaultWindow = 1
+	secretBytes   = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Key is a new TOTP secret and the URI to provision it in an authenticator app
+type Key struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// GenerateSecret generates a random secret for the account
+func GenerateSecret(accountName string) (*Key, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	secret := encoding.EncodeToString(b)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", Issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + Issuer + ":" + accountName,
+		RawQuery: params.Encode(),
+	}
+	return &Key{Secret: secret, URI: uri.String()}, nil
+}
+
+// GenerateCode returns the code for the secret at t
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, counter(t)), nil
+}
+
+// ValidateCode returns true if code is valid for the secret now, allowing
+// one time step of drift either way
+func ValidateCode(secret, code string) bool {
+	return ValidateCodeAt(secret, code, time.Now(), DefaultWindow)
+}
+
+// ValidateCodeAt returns true if code is valid for the secret at t or within
+// window time steps of it
+func ValidateCodeAt(secret, candidate string, t time.Time, window int) bool {
+	candidate = strings.TrimSpace(candidate)
+	if len(candidate) != Digits {
+		return false
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return false
+	}
+
+	current := counter(t)
+	for step := -window; step <= window; step++ {
+		if int64(current)+int64(step) < 0 {
+			continue
+		}
+		expected := code(key, uint64(int64(current)+int64(step)))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(candidate)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+func counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(Period.Seconds()))
+}
+
+// code computes the HOTP value (RFC 4226) for the counter
+func code(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1000000)
+}
+
+// recoveryAlphabet is Crockford's base32, which leaves out easily confused
+// letters. Its 32 symbols map random bytes without bias.
+const recoveryAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// GenerateRecoveryCodes generates n recovery codes formatted as xxxxx-xxxxx.
+// Show the codes to the user once and store only the hashes.
+func GenerateRecoveryCodes(n int) (codes []string, hashes []string, err error) {
+	for i := 0; i < n; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		for j := range b {
+			b[j] = recoveryAlphabet[int(b[j])%len(recoveryAlphabet)]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode hashes a recovery code for storage, ignoring case,
+// spaces and dashes
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRecoveryCode checks code against the stored hashes. On success it
+// returns the hashes without the used one, which must be persisted so the
+// code cannot be used again.
+func VerifyRecoveryCode(hashes []string, code string) ([]string, bool) {
+	hash := HashRecoveryCode(code)
+	for i, stored := range hashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			remaining := make([]string, 0, len(hashes)-1)
+			remaining = append(remaining, hashes[:i]...)
+			return append(remaining, hashes[i+1:]...), true
+		}
+	}
+	return hashes, false
+}
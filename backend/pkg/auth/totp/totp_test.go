@@ -0,0 +1,120 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed from the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateCodeMatchesRFC(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		got, err := GenerateCode(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("Failed to generate code: %v", err)
+		}
+		if got != want {
+			t.Errorf("GenerateCode at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateCodeWindow(t *testing.T) {
+	issued := time.Unix(1234567890, 0)
+	code, _ := GenerateCode(rfcSecret, issued)
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		window int
+		valid  bool
+	}{
+		{"same step", issued, 0, true},
+		{"next step without window", issued.Add(Period), 0, false},
+		{"next step", issued.Add(Period), 1, true},
+		{"previous step", issued.Add(-Period), 1, true},
+		{"two steps later", issued.Add(2 * Period), 1, false},
+		{"two steps with wider window", issued.Add(2 * Period), 2, true},
+	}
+	for _, tt := range tests {
+		if got := ValidateCodeAt(rfcSecret, code, tt.at, tt.window); got != tt.valid {
+			t.Errorf("%s: valid = %v, want %v", tt.name, got, tt.valid)
+		}
+	}
+
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if ValidateCodeAt(rfcSecret, bad, issued, 1) {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if ValidateCodeAt("not base32!", code, issued, 1) {
+		t.Error("Expected invalid secret to be rejected")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	key, err := GenerateSecret("user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+
+	uri, err := url.Parse(key.URI)
+	if err != nil {
+		t.Fatalf("Invalid provisioning URI %q: %v", key.URI, err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/TGFinance:user@example.com" {
+		t.Errorf("Unexpected provisioning URI %q", key.URI)
+	}
+	if uri.Query().Get("secret") != key.Secret || uri.Query().Get("issuer") != Issuer {
+		t.Errorf("Unexpected provisioning parameters %v", uri.Query())
+	}
+
+	code, _ := GenerateCode(key.Secret, time.Now())
+	if !ValidateCode(key.Secret, code) {
+		t.Error("Expected current code to validate")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("Failed to generate recovery codes: %v", err)
+	}
+	if len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("Expected 10 codes and hashes, got %d and %d", len(codes), len(hashes))
+	}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Unexpected code format %q", code)
+		}
+		if hashes[i] == code {
+			t.Error("Expected recovery codes to be stored hashed")
+		}
+	}
+
+	// Codes are accepted without the dash and in upper case, once
+	remaining, ok := VerifyRecoveryCode(hashes, strings.ToUpper(strings.Replace(codes[3], "-", "", 1)))
+	if !ok || len(remaining) != 9 {
+		t.Fatalf("Expected code to be consumed, got ok=%v remaining=%d", ok, len(remaining))
+	}
+	if _, ok := VerifyRecoveryCode(remaining, codes[3]); ok {
+		t.Error("Expected used recovery code to be rejected")
+	}
+	if _, ok := VerifyRecoveryCode(remaining, codes[4]); !ok {
+		t.Error("Expected unused recovery code to be accepted")
+	}
+	if after, ok := VerifyRecoveryCode(remaining, "aaaaa-aaaaa"); ok || len(after) != 9 {
+		t.Error("Expected unknown code to be rejected without consuming")
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenTypeEmailVerification marks tokens sent in email verification links
+const TokenTypeEmailVerification = "email_verification"
+
+// DefaultVerificationTTL is how long email verification links stay valid
+const DefaultVerificationTTL = 24 * time.Hour
+
+// Email verification errors
+var (
+	ErrVerificationTokenInvalid = errors.New("invalid verification token")
+	ErrVerificationTokenExpired = errors.New("verification token has expired")
+	ErrVerificationTokenUsed    = errors.New("verification token has already been used")
+	ErrVerificationEmailChanged = errors.New("email address has changed since the token was issued")
+)
+
+// SingleUseStore consumes token IDs so each can be used once
+type SingleUseStore interface {
+	RevokeOnce(ctx context.Context, jti string, ttl time.Duration) (bool, error)
+}
+
+// EmailLookup returns the current email address of a user
+type EmailLookup interface {
+	CurrentEmail(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// VerificationResult describes a checked verification token. It is returned
+// alongside the expired, used and email changed errors so handlers can offer
+// to resend the link.
+type VerificationResult struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Email   string    `json:"email"`
+	Expired bool      `json:"expired"`
+}
+
+// VerificationTokenManager issues and verifies single-use email verification
+// tokens bound to a user ID and email address
+type VerificationTokenManager struct {
+	jwtManager *JWTManager
+	used       SingleUseStore
+	emails     EmailLookup
+	ttl        time.Duration
+}
+
+// NewVerificationTokenManager creates a verification token manager signing
+// with the JWT manager's keys
+func NewVerificationTokenManager(jwtManager *JWTManager, used SingleUseStore, emails EmailLookup) *VerificationTokenManager {
+	return &VerificationTokenManager{
+		jwtManager: jwtManager,
+		used:       used,
+		emails:     emails,
+		ttl:        DefaultVerificationTTL,
+	}
+}
+
+// WithTTL sets how long verification tokens stay valid
+func (v *VerificationTokenManager) WithTTL(ttl time.Duration) *VerificationTokenManager {
+	v.ttl = ttl
+	return v
+}
+
+// GenerateEmailVerificationToken generates a verification token for the
+// user's email address
+func (v *VerificationTokenManager) GenerateEmailVerificationToken(userID uuid.UUID, email string) (string, error) {
+	claims := &Claims{UserID: userID, Email: email, TokenType: TokenTypeEmailVerification}
+	token, _, err := v.jwtManager.generate(claims, v.jwtManager.clock.Now(), v.ttl)
+	return token, err
+}
+
+// VerifyEmailToken checks the token, that the user's email has not changed
+// since it was issued and that it has not been used before, then consumes it
+func (v *VerificationTokenManager) VerifyEmailToken(ctx context.Context, token string) (*VerificationResult, error) {
+	claims, err := v.jwtManager.parse(token)
+	if claims == nil || claims.TokenType != TokenTypeEmailVerification {
+		return nil, ErrVerificationTokenInvalid
+	}
+	result := &VerificationResult{UserID: claims.UserID, Email: claims.Email}
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		result.Expired = true
+		return result, ErrVerificationTokenExpired
+	}
+	if err != nil {
+		return nil, ErrVerificationTokenInvalid
+	}
+
+	current, err := v.emails.CurrentEmail(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user email: %w", err)
+	}
+	if !strings.EqualFold(current, claims.Email) {
+		return result, ErrVerificationEmailChanged
+	}
+
+	first, err := v.used.RevokeOnce(ctx, claims.ID, claims.ExpiresAt.Sub(v.jwtManager.clock.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume verification token: %w", err)
+	}
+	if !first {
+		return result, ErrVerificationTokenUsed
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"tgfinance/pkg/clock"
+)
+
+type memoryEmails map[uuid.UUID]string
+
+func (m memoryEmails) CurrentEmail(ctx context.Context, userID uuid.UUID) (string, error) {
+	return m[userID], nil
+}
+
+func TestEmailVerificationTokens(t *testing.T) {
+	server := miniredis.RunT(t)
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	jwtManager := NewJWTManagerWithOptions(JWTOptions{Secret: "test-secret"}).WithClock(fake)
+	blacklist := NewTokenBlacklistWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	userID := uuid.New()
+	emails := memoryEmails{userID: "test@example.com"}
+	verifier := NewVerificationTokenManager(jwtManager, blacklist, emails)
+	ctx := context.Background()
+
+	token, err := verifier.GenerateEmailVerificationToken(userID, "test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate verification token: %v", err)
+	}
+	if _, err := jwtManager.ValidateAccessToken(token); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("Expected verification token to be refused as access token, got %v", err)
+	}
+
+	result, err := verifier.VerifyEmailToken(ctx, token)
+	if err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	if result.UserID != userID || result.Email != "test@example.com" || result.Expired {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if _, err := verifier.VerifyEmailToken(ctx, token); !errors.Is(err, ErrVerificationTokenUsed) {
+		t.Errorf("Expected second use to fail, got %v", err)
+	}
+
+	// Changing the email invalidates links sent to the old address
+	stale, _ := verifier.GenerateEmailVerificationToken(userID, "test@example.com")
+	emails[userID] = "new@example.com"
+	if result, err := verifier.VerifyEmailToken(ctx, stale); !errors.Is(err, ErrVerificationEmailChanged) || result == nil {
+		t.Errorf("Expected email changed error with result, got %+v %v", result, err)
+	}
+
+	// Expired links still report who they were for
+	expiring, _ := verifier.GenerateEmailVerificationToken(userID, "new@example.com")
+	fake.Advance(DefaultVerificationTTL + time.Minute)
+	result, err = verifier.VerifyEmailToken(ctx, expiring)
+	if !errors.Is(err, ErrVerificationTokenExpired) || result == nil || !result.Expired || result.Email != "new@example.com" {
+		t.Errorf("Expected expired result, got %+v %v", result, err)
+	}
+
+	access, _ := jwtManager.GenerateToken(userID, "new@example.com", RoleUser)
+	for name, token := range map[string]string{"access": access, "malformed": "not-a-token"} {
+		if _, err := verifier.VerifyEmailToken(ctx, token); !errors.Is(err, ErrVerificationTokenInvalid) {
+			t.Errorf("Expected %s token to be invalid, got %v", name, err)
+		}
+	}
+}
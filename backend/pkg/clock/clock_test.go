@@ -0,0 +1,84 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeAfter(t *testing.T) {
+	f := NewFake(start)
+	ch := f.After(time.Hour)
+
+	f.Advance(59 * time.Minute)
+	select {
+	case <-ch:
+		t.Fatal("Timer fired early")
+	default:
+	}
+
+	f.Advance(time.Minute)
+	select {
+	case at := <-ch:
+		if !at.Equal(start.Add(time.Hour)) {
+			t.Errorf("Expected the deadline to be sent, got %v", at)
+		}
+	default:
+		t.Fatal("Timer did not fire")
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("Expected the fired timer to be removed, got %d waiters", f.Waiters())
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(start)
+	ticker := f.NewTicker(10 * time.Minute)
+
+	f.Advance(10 * time.Minute)
+	if at := <-ticker.C(); !at.Equal(start.Add(10 * time.Minute)) {
+		t.Errorf("Unexpected tick %v", at)
+	}
+
+	// A receiver that falls behind gets one tick, like a real ticker
+	f.Advance(30 * time.Minute)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("Expected missed ticks to be dropped")
+	default:
+	}
+	if !f.Now().Equal(start.Add(40 * time.Minute)) {
+		t.Errorf("Expected the clock at +40m, got %v", f.Now())
+	}
+
+	ticker.Stop()
+	f.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Error("Stopped ticker fired")
+	default:
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(start)
+	done := make(chan time.Time)
+	go func() {
+		done <- <-f.After(time.Second)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	if at := <-done; !at.Equal(start.Add(time.Second)) {
+		t.Errorf("Unexpected time %v", at)
+	}
+}
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	if now := Real().Now(); now.Before(before) {
+		t.Errorf("Real clock went backwards: %v < %v", now, before)
+	}
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Querier is implemented by *DB, *sql.DB and *sql.Tx
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Iterate runs a query and hands each row to yield as it is read from the
+// cursor, so large results can be streamed without holding them in memory.
+// scan fills a row from the cursor. Iteration stops at the first error from
+// scan or yield, which is returned. Repositories build their Iterate
+// variants on it, and the result fits httputil.Iterator.
+func Iterate[T any](ctx context.Context, db Querier, scan func(rows *sql.Rows, row *T) error, yield func(row *T) error, query string, args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := scan(rows, &row); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := yield(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionConflict is returned when a versioned update matches no row
+// because the row was changed by another writer
+var ErrVersionConflict = errors.New("row version conflict")
+
+// placeholderPattern matches positional query parameters
+var placeholderPattern = regexp.MustCompile(`\$[0-9]+`)
+
+// QueryRower is implemented by *DB, *sql.DB and *sql.Tx
+type QueryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// BuildUpdate builds an UPDATE statement for the given column changes. A nil
+// change value generates SET column = NULL. Columns are sorted so the query
+// text is stable. ok is false when there is nothing to update.
+func BuildUpdate(table string, changes map[string]any, where string, whereArgs ...any) (query string, args []any, ok bool) {
+	if len(changes) == 0 {
+		return "", nil, false
+	}
+
+	assignments, args := buildAssignments(changes)
+	where, args = appendWhere(where, args, whereArgs)
+
+	query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(assignments, ", "), where)
+	return query, args, true
+}
+
+// BuildVersionedUpdate builds an UPDATE statement that only applies when the
+// row is still at version, increments the version and returns the new one
+func BuildVersionedUpdate(table string, changes map[string]any, version int64, where string, whereArgs ...any) (query string, args []any) {
+	assignments, args := buildAssignments(changes)
+	assignments = append(assignments, "version = version + 1")
+	where, args = appendWhere(where, args, whereArgs)
+
+	args = append(args, version)
+	query = fmt.Sprintf("UPDATE %s SET %s WHERE %s AND version = $%d RETURNING version",
+		table, strings.Join(assignments, ", "), where, len(args))
+	return query, args
+}
+
+// UpdateVersioned runs a versioned update and returns the new version, or
+// ErrVersionConflict when the row is missing or at a different version
+func UpdateVersioned(ctx context.Context, db QueryRower, table string, changes map[string]any, version int64, where string, whereArgs ...any) (int64, error) {
+	query, args := BuildVersionedUpdate(table, changes, version, where, whereArgs...)
+
+	var newVersion int64
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&newVersion); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrVersionConflict
+		}
+		return 0, fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	return newVersion, nil
+}
+
+// buildAssignments returns the SET assignments and their arguments
+func buildAssignments(changes map[string]any) ([]string, []any) {
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	args := make([]any, 0, len(changes))
+	assignments := make([]string, 0, len(columns))
+	for _, column := range columns {
+		value := changes[column]
+		if value == nil {
+			assignments = append(assignments, column+" = NULL")
+			continue
+		}
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	return assignments, args
+}
+
+// appendWhere renumbers the placeholders of the WHERE clause after the SET
+// arguments and appends its arguments
+func appendWhere(where string, args, whereArgs []any) (string, []any) {
+	offset := len(args)
+	where = placeholderPattern.ReplaceAllStringFunc(where, func(placeholder string) string {
+		n, _ := strconv.Atoi(placeholder[1:])
+		return fmt.Sprintf("$%d", n+offset)
+	})
+	return where, append(args, whereArgs...)
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildUpdate(t *testing.T) {
+	query, args, ok := BuildUpdate("investments", map[string]any{
+		"notes":    nil,
+		"name":     "Bond ladder",
+		"end_date": "2027-01-01",
+	}, "id = $1 AND user_id = $2", "inv-id", "user-id")
+
+	if !ok {
+		t.Fatal("BuildUpdate() ok = false")
+	}
+	want := "UPDATE investments SET end_date = $1, name = $2, notes = NULL WHERE id = $3 AND user_id = $4"
+	if query != want {
+		t.Errorf("query = %q\nwant   %q", query, want)
+	}
+	if !reflect.DeepEqual(args, []any{"2027-01-01", "Bond ladder", "inv-id", "user-id"}) {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestBuildUpdateManyPlaceholders(t *testing.T) {
+	changes := make(map[string]any)
+	for _, column := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		changes[column] = 1
+	}
+
+	query, _, _ := BuildUpdate("t", changes, "id = $1 AND x = $2", 1, 2)
+	if want := "WHERE id = $11 AND x = $12"; query[len(query)-len(want):] != want {
+		t.Errorf("query = %q", query)
+	}
+}
+
+func TestBuildUpdateNoChanges(t *testing.T) {
+	if _, _, ok := BuildUpdate("goals", nil, "id = $1", 1); ok {
+		t.Error("BuildUpdate() with no changes should not be ok")
+	}
+}
+
+func TestBuildVersionedUpdate(t *testing.T) {
+	query, args := BuildVersionedUpdate("financial_goals", map[string]any{"name": "House"}, 3, "id = $1 AND user_id = $2", "goal-id", "user-id")
+
+	want := "UPDATE financial_goals SET name = $1, version = version + 1 WHERE id = $2 AND user_id = $3 AND version = $4 RETURNING version"
+	if query != want {
+		t.Errorf("query = %q\nwant   %q", query, want)
+	}
+	if !reflect.DeepEqual(args, []any{"House", "goal-id", "user-id", int64(3)}) {
+		t.Errorf("args = %v", args)
+	}
+}
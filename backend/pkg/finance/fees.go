@@ -0,0 +1,43 @@
+package finance
+
+import "math"
+
+// FeeImpact represents the projected cost of an expense ratio over a horizon
+type FeeImpact struct {
+	Years            int     `json:"years"`
+	ValueWithoutFees float64 `json:"value_without_fees"`
+	ValueWithFees    float64 `json:"value_with_fees"`
+	FeeImpact        float64 `json:"fee_impact"`
+}
+
+// AnnualExpenseRatioDrag estimates the yearly cost of an expense ratio
+// (a percentage, e.g. 0.75 for 0.75%) on the given value
+func AnnualExpenseRatioDrag(value, expenseRatioPercent float64) float64 {
+	if value <= 0 || expenseRatioPercent <= 0 {
+		return 0
+	}
+	return RoundCents(value * expenseRatioPercent / 100)
+}
+
+// ProjectFeeImpact projects value over the given number of years at an annual
+// growth rate (percentage) with and without the expense ratio (percentage)
+// deducted from the growth each year
+func ProjectFeeImpact(value, expenseRatioPercent, growthRatePercent float64, years int) FeeImpact {
+	growth := growthRatePercent / 100
+	ratio := expenseRatioPercent / 100
+
+	without := value * math.Pow(1+growth, float64(years))
+	with := value * math.Pow((1+growth)*(1-ratio), float64(years))
+
+	return FeeImpact{
+		Years:            years,
+		ValueWithoutFees: RoundCents(without),
+		ValueWithFees:    RoundCents(with),
+		FeeImpact:        RoundCents(without - with),
+	}
+}
+
+// RoundCents rounds an amount to two decimal places
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
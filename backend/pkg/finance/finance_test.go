@@ -0,0 +1,110 @@
+package finance
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name      string
+		cashflows []Cashflow
+		want      float64
+		wantErr   bool
+	}{
+		{
+			name: "single year ten percent",
+			cashflows: []Cashflow{
+				{Date: date(2021, time.January, 1), Amount: -1000},
+				{Date: date(2022, time.January, 1), Amount: 1100},
+			},
+			want: 0.1,
+		},
+		{
+			name: "unordered input",
+			cashflows: []Cashflow{
+				{Date: date(2023, time.January, 1), Amount: 1210},
+				{Date: date(2021, time.January, 1), Amount: -1000},
+			},
+			want: math.Pow(1.21, 365.0/730.0) - 1,
+		},
+		{
+			name: "loss",
+			cashflows: []Cashflow{
+				{Date: date(2021, time.January, 1), Amount: -1000},
+				{Date: date(2022, time.January, 1), Amount: 500},
+			},
+			want: -0.5,
+		},
+		{
+			name: "multiple deposits",
+			cashflows: []Cashflow{
+				{Date: date(2021, time.January, 1), Amount: -1000},
+				{Date: date(2021, time.July, 1), Amount: -1000},
+				{Date: date(2022, time.January, 1), Amount: 2150},
+			},
+			want: 0.0999,
+		},
+		{
+			name:      "only outflows",
+			cashflows: []Cashflow{{Date: date(2021, time.January, 1), Amount: -1000}, {Date: date(2022, time.January, 1), Amount: -1}},
+			wantErr:   true,
+		},
+		{
+			name:      "single cashflow",
+			cashflows: []Cashflow{{Date: date(2021, time.January, 1), Amount: -1000}},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := XIRR(tt.cashflows)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("XIRR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && math.Abs(got-tt.want) > 1e-3 {
+				t.Errorf("XIRR() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnualExpenseRatioDrag(t *testing.T) {
+	if drag := AnnualExpenseRatioDrag(100000, 0.75); drag != 750 {
+		t.Errorf("Expected drag 750, got %v", drag)
+	}
+	if drag := AnnualExpenseRatioDrag(100000, 0); drag != 0 {
+		t.Errorf("Expected no drag without ratio, got %v", drag)
+	}
+	if drag := AnnualExpenseRatioDrag(-5, 1); drag != 0 {
+		t.Errorf("Expected no drag on negative value, got %v", drag)
+	}
+}
+
+func TestProjectFeeImpact(t *testing.T) {
+	impact := ProjectFeeImpact(10000, 1, 7, 10)
+
+	wantWithout := RoundCents(10000 * math.Pow(1.07, 10))
+	wantWith := RoundCents(10000 * math.Pow(1.07*0.99, 10))
+
+	if impact.ValueWithoutFees != wantWithout {
+		t.Errorf("ValueWithoutFees = %v, want %v", impact.ValueWithoutFees, wantWithout)
+	}
+	if impact.ValueWithFees != wantWith {
+		t.Errorf("ValueWithFees = %v, want %v", impact.ValueWithFees, wantWith)
+	}
+	if impact.FeeImpact <= 0 || impact.FeeImpact != RoundCents(impact.ValueWithoutFees-impact.ValueWithFees) {
+		t.Errorf("Unexpected fee impact %v", impact.FeeImpact)
+	}
+
+	longer := ProjectFeeImpact(10000, 1, 7, 20)
+	if longer.FeeImpact <= impact.FeeImpact {
+		t.Errorf("Fee impact should grow with the horizon: 10y %v, 20y %v", impact.FeeImpact, longer.FeeImpact)
+	}
+}
@@ -0,0 +1,201 @@
+package finance
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"tgfinance/pkg/utils"
+)
+
+// Projection parameter bounds
+const (
+	MinAnnualReturn         = -50.0
+	MaxAnnualReturn         = 50.0
+	MinInflationRate        = -10.0
+	MaxInflationRate        = 50.0
+	MaxHorizonYears         = 60
+	MaxVolatility           = 100.0
+	DefaultVolatility       = 15.0
+	DefaultIterations       = 1000
+	MaxMonteCarloIterations = 10000
+)
+
+// ProjectionParams holds the inputs of a compound growth projection. Rates are
+// annual percentages (7 means 7%).
+type ProjectionParams struct {
+	CurrentValue        float64 `json:"current_value"`
+	MonthlyContribution float64 `json:"monthly_contribution"`
+	AnnualReturn        float64 `json:"annual_return"`
+	InflationRate       float64 `json:"inflation_rate"`
+	HorizonYears        int     `json:"horizon_years"`
+
+	// Monte Carlo mode draws each year's return from a normal distribution
+	// around AnnualReturn with the given volatility
+	MonteCarlo bool    `json:"monte_carlo"`
+	Iterations int     `json:"iterations,omitempty"`
+	Volatility float64 `json:"volatility,omitempty"`
+	Seed       int64   `json:"seed,omitempty"`
+}
+
+// ProjectionYear represents the projected portfolio at the end of a year
+type ProjectionYear struct {
+	Year               int     `json:"year"`
+	TotalContributions float64 `json:"total_contributions"`
+	NominalValue       float64 `json:"nominal_value"`
+	InflationAdjusted  float64 `json:"inflation_adjusted_value"`
+	CumulativeGrowth   float64 `json:"cumulative_growth"`
+}
+
+// PercentileBand represents Monte Carlo outcome percentiles at the end of a year
+type PercentileBand struct {
+	Year int     `json:"year"`
+	P10  float64 `json:"p10"`
+	P25  float64 `json:"p25"`
+	P50  float64 `json:"p50"`
+	P75  float64 `json:"p75"`
+	P90  float64 `json:"p90"`
+}
+
+// Projection represents the result of a growth projection
+type Projection struct {
+	Params ProjectionParams `json:"params"`
+	Years  []ProjectionYear `json:"years"`
+	Bands  []PercentileBand `json:"bands,omitempty"`
+}
+
+// Validate checks the projection parameters against their bounds
+func (p *ProjectionParams) Validate() utils.ValidationErrors {
+	var errs utils.ValidationErrors
+
+	if p.CurrentValue < 0 {
+		errs.Add("current_value", "current_value must not be negative")
+	}
+	if p.MonthlyContribution < 0 {
+		errs.Add("monthly_contribution", "monthly_contribution must not be negative")
+	}
+	if p.AnnualReturn < MinAnnualReturn || p.AnnualReturn > MaxAnnualReturn {
+		errs.Add("annual_return", "annual_return must be between -50 and 50")
+	}
+	if p.InflationRate < MinInflationRate || p.InflationRate > MaxInflationRate {
+		errs.Add("inflation_rate", "inflation_rate must be between -10 and 50")
+	}
+	if p.HorizonYears < 1 || p.HorizonYears > MaxHorizonYears {
+		errs.Add("horizon_years", "horizon_years must be between 1 and 60")
+	}
+	if p.MonteCarlo {
+		if p.Iterations < 0 || p.Iterations > MaxMonteCarloIterations {
+			errs.Add("iterations", "iterations must be between 1 and 10000")
+		}
+		if p.Volatility < 0 || p.Volatility > MaxVolatility {
+			errs.Add("volatility", "volatility must be between 0 and 100")
+		}
+	}
+
+	return errs
+}
+
+// Project computes a year-by-year projection with monthly compounding and
+// contributions made at the end of each month. When MonteCarlo is set,
+// percentile bands are added from simulated return paths. Parameters must be
+// validated first; Project clamps the iteration count to the hard cap
+// regardless.
+func Project(params ProjectionParams) Projection {
+	projection := Projection{Params: params}
+
+	monthlyRate := params.AnnualReturn / 100 / 12
+	value := params.CurrentValue
+	contributions := params.CurrentValue
+
+	for year := 1; year <= params.HorizonYears; year++ {
+		for month := 0; month < 12; month++ {
+			value = value*(1+monthlyRate) + params.MonthlyContribution
+			contributions += params.MonthlyContribution
+		}
+		projection.Years = append(projection.Years, ProjectionYear{
+			Year:               year,
+			TotalContributions: RoundCents(contributions),
+			NominalValue:       RoundCents(value),
+			InflationAdjusted:  RoundCents(deflate(value, params.InflationRate, year)),
+			CumulativeGrowth:   RoundCents(value - contributions),
+		})
+	}
+
+	if params.MonteCarlo {
+		projection.Bands = monteCarlo(params)
+	}
+
+	return projection
+}
+
+// monteCarlo simulates return paths and returns nominal value percentiles per year
+func monteCarlo(params ProjectionParams) []PercentileBand {
+	iterations := params.Iterations
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	if iterations > MaxMonteCarloIterations {
+		iterations = MaxMonteCarloIterations
+	}
+	volatility := params.Volatility
+	if volatility == 0 {
+		volatility = DefaultVolatility
+	}
+
+	rng := rand.New(rand.NewSource(params.Seed))
+
+	// outcomes[year][iteration]
+	outcomes := make([][]float64, params.HorizonYears)
+	for year := range outcomes {
+		outcomes[year] = make([]float64, iterations)
+	}
+
+	for i := 0; i < iterations; i++ {
+		value := params.CurrentValue
+		for year := 0; year < params.HorizonYears; year++ {
+			annual := (params.AnnualReturn + rng.NormFloat64()*volatility) / 100
+			if annual < -0.99 {
+				annual = -0.99
+			}
+			monthlyRate := math.Pow(1+annual, 1.0/12) - 1
+			for month := 0; month < 12; month++ {
+				value = value*(1+monthlyRate) + params.MonthlyContribution
+			}
+			outcomes[year][i] = value
+		}
+	}
+
+	bands := make([]PercentileBand, params.HorizonYears)
+	for year, values := range outcomes {
+		sort.Float64s(values)
+		bands[year] = PercentileBand{
+			Year: year + 1,
+			P10:  RoundCents(percentile(values, 10)),
+			P25:  RoundCents(percentile(values, 25)),
+			P50:  RoundCents(percentile(values, 50)),
+			P75:  RoundCents(percentile(values, 75)),
+			P90:  RoundCents(percentile(values, 90)),
+		}
+	}
+
+	return bands
+}
+
+// percentile returns the p-th percentile of sorted values using linear interpolation
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// deflate converts a nominal value to today's money
+func deflate(value, inflationRate float64, years int) float64 {
+	return value / math.Pow(1+inflationRate/100, float64(years))
+}
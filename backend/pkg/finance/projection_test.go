@@ -0,0 +1,134 @@
+package finance
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProjectDeterministic(t *testing.T) {
+	t.Run("zero return accumulates contributions", func(t *testing.T) {
+		projection := Project(ProjectionParams{CurrentValue: 1000, MonthlyContribution: 100, HorizonYears: 2})
+		if len(projection.Years) != 2 {
+			t.Fatalf("Expected 2 years, got %d", len(projection.Years))
+		}
+		last := projection.Years[1]
+		if last.NominalValue != 3400 || last.TotalContributions != 3400 || last.CumulativeGrowth != 0 {
+			t.Errorf("Unexpected zero-return projection: %+v", last)
+		}
+	})
+
+	t.Run("monthly compounding", func(t *testing.T) {
+		projection := Project(ProjectionParams{CurrentValue: 10000, MonthlyContribution: 500, AnnualReturn: 12, HorizonYears: 10})
+		r := 0.01
+		n := 120.0
+		want := 10000*math.Pow(1+r, n) + 500*(math.Pow(1+r, n)-1)/r
+		got := projection.Years[9].NominalValue
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("NominalValue = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("inflation adjustment", func(t *testing.T) {
+		projection := Project(ProjectionParams{CurrentValue: 10000, AnnualReturn: 0, InflationRate: 10, HorizonYears: 1})
+		if projection.Years[0].InflationAdjusted != RoundCents(10000/1.1) {
+			t.Errorf("InflationAdjusted = %v, want %v", projection.Years[0].InflationAdjusted, RoundCents(10000/1.1))
+		}
+	})
+
+	t.Run("no bands without monte carlo", func(t *testing.T) {
+		projection := Project(ProjectionParams{CurrentValue: 1000, AnnualReturn: 5, HorizonYears: 5})
+		if projection.Bands != nil {
+			t.Error("Bands should only be computed in Monte Carlo mode")
+		}
+	})
+}
+
+func TestProjectMonteCarlo(t *testing.T) {
+	params := ProjectionParams{
+		CurrentValue:        50000,
+		MonthlyContribution: 1000,
+		AnnualReturn:        7,
+		HorizonYears:        20,
+		MonteCarlo:          true,
+		Iterations:          500,
+		Seed:                42,
+	}
+
+	first := Project(params)
+	second := Project(params)
+
+	if len(first.Bands) != 20 {
+		t.Fatalf("Expected 20 bands, got %d", len(first.Bands))
+	}
+	for i := range first.Bands {
+		if first.Bands[i] != second.Bands[i] {
+			t.Fatalf("Same seed should produce identical bands, year %d: %+v vs %+v", i+1, first.Bands[i], second.Bands[i])
+		}
+		band := first.Bands[i]
+		if !(band.P10 <= band.P25 && band.P25 <= band.P50 && band.P50 <= band.P75 && band.P75 <= band.P90) {
+			t.Errorf("Percentiles out of order in year %d: %+v", band.Year, band)
+		}
+	}
+
+	params.Seed = 7
+	other := Project(params)
+	if other.Bands[19] == first.Bands[19] {
+		t.Error("Different seeds should produce different bands")
+	}
+
+	// The median should land in the neighbourhood of the deterministic projection
+	deterministic := first.Years[19].NominalValue
+	median := first.Bands[19].P50
+	if median < deterministic*0.7 || median > deterministic*1.3 {
+		t.Errorf("Median %v too far from deterministic %v", median, deterministic)
+	}
+}
+
+func TestProjectMonteCarloIterationCap(t *testing.T) {
+	params := ProjectionParams{CurrentValue: 1000, AnnualReturn: 5, HorizonYears: 1, MonteCarlo: true, Iterations: MaxMonteCarloIterations * 100}
+	if errs := params.Validate(); !errs.HasErrors() {
+		t.Error("Iterations above the cap should fail validation")
+	}
+
+	// Even unvalidated input is clamped to the cap
+	capped := params
+	capped.Iterations = MaxMonteCarloIterations
+	if Project(params).Bands[0] != Project(capped).Bands[0] {
+		t.Error("Iterations above the cap should be clamped")
+	}
+}
+
+func TestProjectionParamsValidate(t *testing.T) {
+	valid := ProjectionParams{CurrentValue: 1000, MonthlyContribution: 100, AnnualReturn: 7, InflationRate: 3, HorizonYears: 30}
+
+	tests := []struct {
+		name    string
+		mutate  func(p *ProjectionParams)
+		wantErr bool
+	}{
+		{"valid", func(p *ProjectionParams) {}, false},
+		{"lowest return", func(p *ProjectionParams) { p.AnnualReturn = -50 }, false},
+		{"highest return", func(p *ProjectionParams) { p.AnnualReturn = 50 }, false},
+		{"return too low", func(p *ProjectionParams) { p.AnnualReturn = -50.1 }, true},
+		{"return too high", func(p *ProjectionParams) { p.AnnualReturn = 50.1 }, true},
+		{"max horizon", func(p *ProjectionParams) { p.HorizonYears = 60 }, false},
+		{"horizon too long", func(p *ProjectionParams) { p.HorizonYears = 61 }, true},
+		{"zero horizon", func(p *ProjectionParams) { p.HorizonYears = 0 }, true},
+		{"negative value", func(p *ProjectionParams) { p.CurrentValue = -1 }, true},
+		{"negative contribution", func(p *ProjectionParams) { p.MonthlyContribution = -1 }, true},
+		{"inflation too high", func(p *ProjectionParams) { p.InflationRate = 51 }, true},
+		{"monte carlo negative volatility", func(p *ProjectionParams) { p.MonteCarlo = true; p.Volatility = -1 }, true},
+		{"monte carlo at cap", func(p *ProjectionParams) { p.MonteCarlo = true; p.Iterations = MaxMonteCarloIterations }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := valid
+			tt.mutate(&params)
+			errs := params.Validate()
+			if errs.HasErrors() != tt.wantErr {
+				t.Errorf("Validate() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
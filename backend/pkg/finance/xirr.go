@@ -0,0 +1,107 @@
+package finance
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// Cashflow represents a dated cash movement from the investor's point of view:
+// negative for money paid in, positive for money received
+type Cashflow struct {
+	Date   time.Time `json:"date"`
+	Amount float64   `json:"amount"`
+}
+
+// ErrNoSolution is returned when XIRR cannot find a rate for the cashflows
+var ErrNoSolution = errors.New("xirr: cashflows have no solution")
+
+const (
+	xirrTolerance     = 1e-9
+	xirrMaxIterations = 100
+	daysPerYear       = 365.0
+)
+
+// XIRR returns the annualized internal rate of return (as a fraction, 0.1 = 10%)
+// for irregularly spaced cashflows. At least one negative and one positive
+// cashflow are required.
+func XIRR(cashflows []Cashflow) (float64, error) {
+	if len(cashflows) < 2 {
+		return 0, ErrNoSolution
+	}
+
+	flows := make([]Cashflow, len(cashflows))
+	copy(flows, cashflows)
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].Date.Before(flows[j].Date) })
+
+	hasNegative, hasPositive := false, false
+	for _, cf := range flows {
+		if cf.Amount < 0 {
+			hasNegative = true
+		}
+		if cf.Amount > 0 {
+			hasPositive = true
+		}
+	}
+	if !hasNegative || !hasPositive {
+		return 0, ErrNoSolution
+	}
+
+	start := flows[0].Date
+	years := make([]float64, len(flows))
+	for i, cf := range flows {
+		years[i] = cf.Date.Sub(start).Hours() / 24 / daysPerYear
+	}
+
+	npv := func(rate float64) (value, derivative float64) {
+		for i, cf := range flows {
+			discount := math.Pow(1+rate, years[i])
+			value += cf.Amount / discount
+			derivative -= years[i] * cf.Amount / (discount * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	// Newton-Raphson from a 10% guess
+	rate := 0.1
+	for i := 0; i < xirrMaxIterations; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < xirrTolerance {
+			return rate, nil
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < xirrTolerance {
+			return next, nil
+		}
+		rate = next
+	}
+
+	// Fall back to bisection over a wide bracket
+	low, high := -0.9999, 10.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	if lowValue*highValue > 0 {
+		return 0, ErrNoSolution
+	}
+	for i := 0; i < 1000; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < xirrTolerance || (high-low)/2 < xirrTolerance {
+			return mid, nil
+		}
+		if midValue*lowValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+
+	return 0, ErrNoSolution
+}
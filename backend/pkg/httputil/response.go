@@ -0,0 +1,78 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"tgfinance/pkg/utils"
+)
+
+// ErrorResponse is the error of a failed request, sent as {"error": {...}}.
+// Code is machine-readable, Message is for people and Details lists
+// per-field validation errors.
+type ErrorResponse struct {
+	Code    string                  `json:"code"`
+	Message string                  `json:"message"`
+	Details []utils.ValidationError `json:"details,omitempty"`
+}
+
+type errorEnvelope struct {
+	Error ErrorResponse `json:"error"`
+}
+
+// CodeForStatus returns the default error code for a status, such as
+// "not_found" for 404
+func CodeForStatus(statusCode int) string {
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// WriteJSON encodes v and writes it with the status. The body is encoded
+// before anything is sent, so an encoding failure still yields a valid 500.
+func WriteJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		statusCode = http.StatusInternalServerError
+		data, _ = json.Marshal(errorEnvelope{Error: ErrorResponse{
+			Code:    CodeForStatus(statusCode),
+			Message: "Failed to encode response",
+		}})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(append(data, '\n'))
+}
+
+// WriteError writes an error response with the code and message
+func WriteError(w http.ResponseWriter, statusCode int, code, message string) {
+	WriteJSON(w, statusCode, errorEnvelope{Error: ErrorResponse{Code: code, Message: message}})
+}
+
+// WriteErrorFrom writes an error response for err. Validation errors are
+// listed in Details under a generic message.
+func WriteErrorFrom(w http.ResponseWriter, statusCode int, code string, err error) {
+	response := ErrorResponse{Code: code, Message: err.Error()}
+
+	var many utils.ValidationErrors
+	var one *utils.ValidationError
+	var value utils.ValidationError
+	switch {
+	case errors.As(err, &many):
+		response.Message = "Validation failed"
+		response.Details = many
+	case errors.As(err, &one):
+		response.Message = "Validation failed"
+		response.Details = []utils.ValidationError{*one}
+	case errors.As(err, &value):
+		response.Message = "Validation failed"
+		response.Details = []utils.ValidationError{value}
+	}
+
+	WriteJSON(w, statusCode, errorEnvelope{Error: response})
+}
@@ -0,0 +1,121 @@
+package httputil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tgfinance/pkg/utils"
+)
+
+// orderRecorder records whether the status and content type were set before
+// the first body write
+type orderRecorder struct {
+	*httptest.ResponseRecorder
+	headerWritten bool
+	typeAtWrite   string
+	wroteEarly    bool
+}
+
+func (o *orderRecorder) WriteHeader(statusCode int) {
+	o.headerWritten = true
+	o.typeAtWrite = o.Header().Get("Content-Type")
+	o.ResponseRecorder.WriteHeader(statusCode)
+}
+
+func (o *orderRecorder) Write(b []byte) (int, error) {
+	if !o.headerWritten {
+		o.wroteEarly = true
+	}
+	return o.ResponseRecorder.Write(b)
+}
+
+func decodeError(t *testing.T, body []byte) ErrorResponse {
+	t.Helper()
+	var envelope struct {
+		Error ErrorResponse `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", body, err)
+	}
+	return envelope.Error
+}
+
+func TestWriteError(t *testing.T) {
+	rec := &orderRecorder{ResponseRecorder: httptest.NewRecorder()}
+	message := "Bad \"quoted\" value\nwith a newline and a \\ backslash"
+	WriteError(rec, http.StatusBadRequest, "bad_request", message)
+
+	if rec.wroteEarly || rec.typeAtWrite != "application/json" {
+		t.Errorf("Expected status and content type before the body, got early=%v type=%q", rec.wroteEarly, rec.typeAtWrite)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rec.Code)
+	}
+	got := decodeError(t, rec.Body.Bytes())
+	if got.Code != "bad_request" || got.Message != message || got.Details != nil {
+		t.Errorf("Unexpected error %+v", got)
+	}
+}
+
+func TestWriteErrorFrom(t *testing.T) {
+	var errs utils.ValidationErrors
+	errs.Add("amount", "amount must be positive")
+	errs.Add("date", `date "tomorrow" is invalid`)
+
+	tests := []struct {
+		name    string
+		err     error
+		message string
+		details int
+	}{
+		{"validation errors", errs, "Validation failed", 2},
+		{"wrapped validation errors", fmt.Errorf("create expense: %w", errs), "Validation failed", 2},
+		{"single validation error", &utils.ValidationError{Field: "email", Message: "email is required"}, "Validation failed", 1},
+		{"plain error", fmt.Errorf("expense %q not found", "groceries"), `expense "groceries" not found`, 0},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		WriteErrorFrom(rec, http.StatusBadRequest, "invalid_request", tt.err)
+		got := decodeError(t, rec.Body.Bytes())
+		if got.Code != "invalid_request" || got.Message != tt.message || len(got.Details) != tt.details {
+			t.Errorf("%s: unexpected error %+v", tt.name, got)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, http.StatusCreated, map[string]string{"note": "say \"hi\"\n"})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["note"] != "say \"hi\"\n" {
+		t.Errorf("Unexpected body %q: %v", rec.Body.String(), err)
+	}
+
+	// Values that cannot be encoded still produce a valid error
+	rec = httptest.NewRecorder()
+	WriteJSON(rec, http.StatusOK, map[string]interface{}{"ch": make(chan int)})
+	if rec.Code != http.StatusInternalServerError || decodeError(t, rec.Body.Bytes()).Code != "internal_server_error" {
+		t.Errorf("Expected a 500 error response, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]string{
+		http.StatusUnauthorized:    "unauthorized",
+		http.StatusNotFound:        "not_found",
+		http.StatusTooManyRequests: "too_many_requests",
+		http.StatusTeapot:          "im_a_teapot",
+		599:                        "error",
+	}
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package httputil
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+)
+
+// flushEvery is the number of items written between flushes to the client
+const flushEvery = 100
+
+// bufferSize is the write buffer used while streaming
+const bufferSize = 32 << 10
+
+// Iterator yields items one at a time, typically from a database rows cursor,
+// stopping at the first error returned by yield
+type Iterator[T any] func(yield func(item *T) error) error
+
+// StreamList writes a JSON object whose key field is an array of the items,
+// followed by the fields returned by trailer, which is given the number of
+// items written. Items are encoded as they are produced and flushed every
+// flushEvery items, so memory use does not grow with the list; when the
+// response is compressed the flush goes through the compressor.
+//
+// The status and headers are sent with the first item. If items fails before
+// that, nothing has been written and the error is returned so the caller can
+// send an error response. If it fails later the JSON cannot be completed, so
+// the connection is aborted and the client sees a truncated response rather
+// than a body that looks complete.
+func StreamList[T any](w http.ResponseWriter, statusCode int, key string, items Iterator[T], trailer func(count int) map[string]any) error {
+	out := bufio.NewWriterSize(w, bufferSize)
+	started := false
+	count := 0
+
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(statusCode)
+		name, _ := json.Marshal(key)
+		out.WriteString("{")
+		out.Write(name)
+		_, err := out.WriteString(":[")
+		return err
+	}
+
+	err := items(func(item *T) error {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if count > 0 {
+			out.WriteByte(',')
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+		count++
+		if count%flushEvery == 0 {
+			return flush(w, out)
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			return err
+		}
+		// Drop whatever is buffered; the response must not end as valid JSON
+		panic(http.ErrAbortHandler)
+	}
+
+	if !started {
+		if err := start(); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	}
+	out.WriteString("]")
+	if trailer != nil {
+		fields := trailer(count)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			nameJSON, _ := json.Marshal(name)
+			value, err := json.Marshal(fields[name])
+			if err != nil {
+				panic(http.ErrAbortHandler)
+			}
+			out.WriteByte(',')
+			out.Write(nameJSON)
+			out.WriteByte(':')
+			out.Write(value)
+		}
+	}
+	out.WriteString("}\n")
+	if err := flush(w, out); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+// flush writes buffered output and pushes it to the client
+func flush(w http.ResponseWriter, out *bufio.Writer) error {
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if err := http.NewResponseController(w).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+type row struct {
+	ID     int    `json:"id"`
+	Amount string `json:"amount"`
+}
+
+func rows(n int, failAt int) Iterator[row] {
+	return func(yield func(item *row) error) error {
+		for i := 0; i < n; i++ {
+			if i == failAt {
+				return errors.New("connection reset by database")
+			}
+			if err := yield(&row{ID: i, Amount: "12.50"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestStreamList(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := StreamList(rec, http.StatusOK, "expenses", rows(250, -1), func(count int) map[string]any {
+		return map[string]any{"total": count, "scope": "household"}
+	})
+	if err != nil {
+		t.Fatalf("StreamList failed: %v", err)
+	}
+
+	var body struct {
+		Expenses []row `json:"expenses"`
+		Total    int   `json:"total"`
+		Scope    string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(body.Expenses) != 250 || body.Total != 250 || body.Scope != "household" || body.Expenses[249].ID != 249 {
+		t.Errorf("Unexpected body: %d items, total %d, scope %q", len(body.Expenses), body.Total, body.Scope)
+	}
+	if !rec.Flushed {
+		t.Error("Expected the stream to be flushed")
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestStreamListEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := StreamList(rec, http.StatusOK, "expenses", rows(0, -1), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Body.String(); got != "{\"expenses\":[]}\n" {
+		t.Errorf("Unexpected body %q", got)
+	}
+}
+
+func TestStreamListErrorBeforeFirstItem(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := StreamList(rec, http.StatusOK, "expenses", rows(10, 0), nil); err == nil {
+		t.Fatal("Expected the error to be returned")
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+		t.Error("Nothing should be written when the first item fails")
+	}
+}
+
+func TestStreamListMidStreamErrorAbortsConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		StreamList(w, http.StatusOK, "expenses", rows(1000, 500), nil)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("Expected a truncated response, read %d bytes cleanly", len(body))
+	}
+	if json.Valid(body) {
+		t.Error("A failed stream must not produce valid JSON")
+	}
+}
+
+// countingWriter discards the body, as a client reading the stream would
+type countingWriter struct {
+	header http.Header
+	bytes  int
+}
+
+func (w *countingWriter) Header() http.Header         { return w.header }
+func (w *countingWriter) WriteHeader(statusCode int)  {}
+func (w *countingWriter) Write(b []byte) (int, error) { w.bytes += len(b); return len(b), nil }
+
+func TestStreamListMemoryIsFlat(t *testing.T) {
+	const total = 50000
+	var samples []uint64
+	items := func(yield func(item *row) error) error {
+		for i := 0; i < total; i++ {
+			if i%10000 == 0 {
+				var stats runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				samples = append(samples, stats.HeapAlloc)
+			}
+			if err := yield(&row{ID: i, Amount: "1234.56"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := &countingWriter{header: http.Header{}}
+	if err := StreamList(w, http.StatusOK, "expenses", items, nil); err != nil {
+		t.Fatal(err)
+	}
+	if w.bytes < total*20 {
+		t.Fatalf("Expected the full stream to be written, got %d bytes", w.bytes)
+	}
+
+	// A buffered response would grow by the ~1.5MB body between samples
+	const slack = 256 << 10
+	for _, sample := range samples[1:] {
+		if sample > samples[0]+slack {
+			t.Errorf("Heap grew while streaming: samples %v", samples)
+			break
+		}
+	}
+}
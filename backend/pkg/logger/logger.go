@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"time"
@@ -8,45 +10,46 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// Logger provides structured logging functionality
+// Logger provides structured logging functionality. Fields named like
+// secrets, such as "password" or "refresh_token", are logged as [REDACTED].
 type Logger struct {
 	*logrus.Logger
+	timeFormat string
+	redact     *redactHook
+	file       *RotatingWriter
 }
 
-// New creates a new logger instance
+// levels maps the level names accepted in configuration to logrus levels
+var levels = map[string]logrus.Level{
+	"debug": logrus.DebugLevel,
+	"info":  logrus.InfoLevel,
+	"warn":  logrus.WarnLevel,
+	"error": logrus.ErrorLevel,
+	"fatal": logrus.FatalLevel,
+	"panic": logrus.PanicLevel,
+}
+
+// New creates a new logger instance. Unknown levels log at info and unknown
+// formats as text. The "file" and "both" outputs use DefaultFileOptions.
 func New(level, format, output, timeFormat string) *Logger {
+	return NewWithFile(level, format, output, timeFormat, DefaultFileOptions)
+}
+
+// NewWithFile is New with the file and rotation settings for the "file"
+// output, and "both", which also writes to stdout. Unknown outputs write to
+// stdout.
+func NewWithFile(level, format, output, timeFormat string, file FileOptions) *Logger {
 	logger := logrus.New()
+	l := &Logger{Logger: logger, timeFormat: timeFormat, redact: newRedactHook(DefaultRedactFields)}
+	logger.AddHook(l.redact)
 
 	// Set log level
-	switch level {
-	case "debug":
-		logger.SetLevel(logrus.DebugLevel)
-	case "info":
-		logger.SetLevel(logrus.InfoLevel)
-	case "warn":
-		logger.SetLevel(logrus.WarnLevel)
-	case "error":
-		logger.SetLevel(logrus.ErrorLevel)
-	case "fatal":
-		logger.SetLevel(logrus.FatalLevel)
-	case "panic":
-		logger.SetLevel(logrus.PanicLevel)
-	default:
+	if err := l.SetLevelByName(level); err != nil {
 		logger.SetLevel(logrus.InfoLevel)
 	}
 
 	// Set log format
-	switch format {
-	case "json":
-		logger.SetFormatter(&logrus.JSONFormatter{
-			TimestampFormat: timeFormat,
-		})
-	case "text":
-		logger.SetFormatter(&logrus.TextFormatter{
-			TimestampFormat: timeFormat,
-			FullTimestamp:   true,
-		})
-	default:
+	if err := l.SetFormatByName(format); err != nil {
 		logger.SetFormatter(&logrus.TextFormatter{
 			TimestampFormat: time.RFC3339,
 			FullTimestamp:   true,
@@ -60,17 +63,62 @@ func New(level, format, output, timeFormat string) *Logger {
 	case "stderr":
 		logger.SetOutput(os.Stderr)
 	case "file":
-		file, err := os.OpenFile("logs/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-		if err == nil {
-			logger.SetOutput(file)
-		} else {
-			logger.SetOutput(os.Stderr)
-		}
+		l.file = NewRotatingWriter(file)
+		logger.SetOutput(l.file)
+	case "both":
+		l.file = NewRotatingWriter(file)
+		logger.SetOutput(io.MultiWriter(os.Stdout, l.file))
 	default:
 		logger.SetOutput(os.Stdout)
 	}
 
-	return &Logger{Logger: logger}
+	return l
+}
+
+// Close closes the log file, if any, waiting for rotated files to be
+// compressed
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// SetLevelByName sets the level from its name, such as "debug". It is safe
+// to call while other goroutines are logging.
+func (l *Logger) SetLevelByName(name string) error {
+	level, ok := levels[name]
+	if !ok {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	l.Logger.SetLevel(level)
+	return nil
+}
+
+// SetFormatByName switches to the "json" or "text" format. It is safe to
+// call while other goroutines are logging.
+func (l *Logger) SetFormatByName(format string) error {
+	switch format {
+	case "json":
+		l.Logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: l.timeFormat,
+		})
+	case "text":
+		l.Logger.SetFormatter(&logrus.TextFormatter{
+			TimestampFormat: l.timeFormat,
+			FullTimestamp:   true,
+		})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	return nil
+}
+
+// SetRedactFields replaces the words that mark fields as sensitive, which
+// default to DefaultRedactFields. It is safe to call while other goroutines
+// are logging.
+func (l *Logger) SetRedactFields(fields ...string) {
+	l.redact.set(fields)
 }
 
 // WithContext adds context information to the logger
@@ -83,11 +131,33 @@ func (l *Logger) WithRequest(r interface{}) *logrus.Entry {
 	return l.WithField("request", r)
 }
 
-// WithUser adds user information to the logger
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context carrying the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID of the context, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WithRequestID adds the request ID of the context to the logger
+func (l *Logger) WithRequestID(ctx context.Context) *logrus.Entry {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return l.WithField("request_id", requestID)
+	}
+	return logrus.NewEntry(l.Logger)
+}
+
+// WithUser adds user information to the logger, masking the email address
 func (l *Logger) WithUser(userID, email string) *logrus.Entry {
 	return l.WithFields(logrus.Fields{
 		"user_id": userID,
-		"email":   email,
+		"email":   MaskEmail(email),
 	})
 }
 
@@ -99,14 +169,26 @@ func (l *Logger) WithDatabase(operation, table string) *logrus.Entry {
 	})
 }
 
-// WithError adds error information to the logger
+// WithError adds error information to the logger. A nil err adds nothing,
+// so callers need not check before logging.
 func (l *Logger) WithError(err error) *logrus.Entry {
-	return l.WithField("error", err.Error())
+	return l.WithErr(err)
+}
+
+// WithErr adds err under logrus.ErrorKey as the error value rather than its
+// message, so hooks such as Sentry can unwrap its chain; formatters print
+// the message. A nil err adds nothing.
+func (l *Logger) WithErr(err error) *logrus.Entry {
+	if err == nil {
+		return logrus.NewEntry(l.Logger)
+	}
+	return l.Logger.WithError(err)
 }
 
-// WithFields adds multiple fields to the logger
+// WithFields adds multiple fields to the logger. It delegates to the
+// embedded logrus logger, whose method it shadows.
 func (l *Logger) WithFields(fields logrus.Fields) *logrus.Entry {
-	return l.WithFields(fields)
+	return l.Logger.WithFields(fields)
 }
 
 // SetOutput sets the logger output
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSetLevelByName(t *testing.T) {
+	l := New("info", "json", "stdout", "")
+	for name, want := range levels {
+		if err := l.SetLevelByName(name); err != nil {
+			t.Errorf("SetLevelByName(%q) error = %v", name, err)
+		}
+		if l.GetLevel() != want {
+			t.Errorf("SetLevelByName(%q) level = %v, want %v", name, l.GetLevel(), want)
+		}
+	}
+
+	l.SetLevel(logrus.WarnLevel)
+	for _, name := range []string{"", "verbose", "DEBUG", "warning"} {
+		if err := l.SetLevelByName(name); err == nil {
+			t.Errorf("SetLevelByName(%q) expected an error", name)
+		}
+	}
+	if l.GetLevel() != logrus.WarnLevel {
+		t.Errorf("Expected unknown levels to leave the level alone, got %v", l.GetLevel())
+	}
+}
+
+func TestSetFormatByName(t *testing.T) {
+	var out bytes.Buffer
+	l := New("info", "json", "stdout", "")
+	l.SetOutput(&out)
+
+	if err := l.SetFormatByName("text"); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("as text")
+	if !strings.Contains(out.String(), `msg="as text"`) {
+		t.Errorf("Expected a text line, got %s", out.String())
+	}
+
+	out.Reset()
+	if err := l.SetFormatByName("xml"); err == nil {
+		t.Error("Expected an unknown format to be an error")
+	}
+	if err := l.SetFormatByName("json"); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("as json")
+	if !strings.Contains(out.String(), `"msg":"as json"`) {
+		t.Errorf("Expected a JSON line, got %s", out.String())
+	}
+}
+
+func TestNewFallsBack(t *testing.T) {
+	l := New("verbose", "xml", "stdout", "")
+	if l.GetLevel() != logrus.InfoLevel {
+		t.Errorf("Expected unknown levels to log at info, got %v", l.GetLevel())
+	}
+	if _, ok := l.Formatter.(*logrus.TextFormatter); !ok {
+		t.Errorf("Expected unknown formats to log as text, got %T", l.Formatter)
+	}
+}
+
+// captureHook records the entries it fires for, as an error reporting hook
+// would see them
+type captureHook struct {
+	entries []*logrus.Entry
+}
+
+func (h *captureHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *captureHook) Fire(entry *logrus.Entry) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+func TestWithFields(t *testing.T) {
+	var out bytes.Buffer
+	l := New("info", "json", "stdout", "")
+	l.SetOutput(&out)
+
+	// Calling the method directly, as the shadowing bug was only reachable
+	// this way
+	l.WithFields(logrus.Fields{"user_id": "u1", "count": 2}).Info("fields")
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["user_id"] != "u1" || line["count"] != float64(2) || line["msg"] != "fields" {
+		t.Errorf("Unexpected line %v", line)
+	}
+
+	out.Reset()
+	l.WithFields(nil).Info("no fields")
+	if !strings.Contains(out.String(), `"msg":"no fields"`) {
+		t.Errorf("Expected nil fields to log, got %s", out.String())
+	}
+}
+
+func TestWithError(t *testing.T) {
+	var out bytes.Buffer
+	hook := &captureHook{}
+	l := New("info", "json", "stdout", "")
+	l.SetOutput(&out)
+	l.AddHook(hook)
+
+	err := fmt.Errorf("open receipt: %w", &fs.PathError{Op: "open", Path: "r.png", Err: fs.ErrNotExist})
+	for name, withErr := range map[string]func(error) *logrus.Entry{"WithError": l.WithError, "WithErr": l.WithErr} {
+		t.Run(name, func(t *testing.T) {
+			out.Reset()
+			hook.entries = nil
+			withErr(err).Error("failed")
+
+			if !strings.Contains(out.String(), `"error":"open receipt: open r.png: file does not exist"`) {
+				t.Errorf("Expected the error message in the line, got %s", out.String())
+			}
+			logged, ok := hook.entries[0].Data[logrus.ErrorKey].(error)
+			var pathErr *fs.PathError
+			if !ok || !errors.As(logged, &pathErr) || !errors.Is(logged, fs.ErrNotExist) {
+				t.Errorf("Expected hooks to get the wrapped error, got %#v", hook.entries[0].Data[logrus.ErrorKey])
+			}
+
+			out.Reset()
+			withErr(nil).WithField("step", "upload").Warn("no error")
+			var line map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := line["error"]; ok || line["step"] != "upload" {
+				t.Errorf("Expected a nil error to add no field, got %v", line)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces the values of sensitive fields
+const Redacted = "[REDACTED]"
+
+// DefaultRedactFields are the words that mark a field as sensitive. A field
+// whose name contains one, ignoring case, is redacted, so "refresh_token"
+// and "X-Authorization" are covered.
+var DefaultRedactFields = []string{"password", "token", "authorization", "account_number", "secret"}
+
+// emailFields mark a field as holding an email address, which is masked
+// with MaskEmail rather than redacted so support can still tell users apart
+var emailFields = []string{"email"}
+
+// redactHook replaces the values of sensitive fields, including those of
+// maps nested in fields, such as request headers. Maps are copied before
+// redacting, as they may belong to the caller; entries without sensitive
+// fields are left untouched and cost no allocations.
+type redactHook struct {
+	fields atomic.Pointer[[]string]
+}
+
+func newRedactHook(fields []string) *redactHook {
+	h := &redactHook{}
+	h.set(fields)
+	return h
+}
+
+// set replaces the denylist; it is safe to call while logging
+func (h *redactHook) set(fields []string) {
+	lower := make([]string, len(fields))
+	for i, field := range fields {
+		lower[i] = strings.ToLower(field)
+	}
+	h.fields.Store(&lower)
+}
+
+// Levels returns every level
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts the entry's fields and masks emails. logrus hands hooks a copy of the entry's
+// field map, so top-level fields can be replaced in place.
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	fields := *h.fields.Load()
+	for key, value := range entry.Data {
+		if sensitive(key, fields) {
+			entry.Data[key] = Redacted
+		} else if email, ok := value.(string); ok && sensitive(key, emailFields) {
+			entry.Data[key] = MaskEmail(email)
+		} else if redacted, ok := redactNested(value, fields); ok {
+			entry.Data[key] = redacted
+		}
+	}
+	return nil
+}
+
+// redactNested returns a redacted copy of value if it is a map holding
+// sensitive fields at any depth
+func redactNested(value interface{}, fields []string) (interface{}, bool) {
+	switch m := value.(type) {
+	case logrus.Fields:
+		if redacted, ok := redactMap(m, fields); ok {
+			return logrus.Fields(redacted), true
+		}
+	case map[string]interface{}:
+		return redactMap(m, fields)
+	case map[string]string:
+		var out map[string]string
+		for key := range m {
+			if !sensitive(key, fields) {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]string, len(m))
+				for k, v := range m {
+					out[k] = v
+				}
+			}
+			out[key] = Redacted
+		}
+		return out, out != nil
+	case http.Header:
+		if redacted, ok := redactNested(map[string][]string(m), fields); ok {
+			return http.Header(redacted.(map[string][]string)), true
+		}
+	case map[string][]string:
+		var out map[string][]string
+		for key := range m {
+			if !sensitive(key, fields) {
+				continue
+			}
+			if out == nil {
+				out = make(map[string][]string, len(m))
+				for k, v := range m {
+					out[k] = v
+				}
+			}
+			out[key] = []string{Redacted}
+		}
+		return out, out != nil
+	}
+	return nil, false
+}
+
+// redactMap returns a redacted copy of m if it holds sensitive fields
+func redactMap(m map[string]interface{}, fields []string) (map[string]interface{}, bool) {
+	var out map[string]interface{}
+	for key, value := range m {
+		var replacement interface{}
+		if sensitive(key, fields) {
+			replacement = Redacted
+		} else if email, ok := value.(string); ok && sensitive(key, emailFields) {
+			replacement = MaskEmail(email)
+		} else if redacted, ok := redactNested(value, fields); ok {
+			replacement = redacted
+		} else {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		out[key] = replacement
+	}
+	return out, out != nil
+}
+
+// sensitive reports whether key contains one of the lowercase fields,
+// ignoring case, without allocating
+func sensitive(key string, fields []string) bool {
+	for _, field := range fields {
+		for i := 0; i+len(field) <= len(key); i++ {
+			if strings.EqualFold(key[i:i+len(field)], field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MaskEmail keeps the first character and the domain of an email address,
+// so "tushar@example.com" becomes "t***@example.com". Values without an @
+// are masked entirely.
+func MaskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return "***"
+	}
+	first := ""
+	if at > 0 {
+		_, size := utf8.DecodeRuneInString(email)
+		first = email[:size]
+	}
+	return first + "***" + email[at:]
+}
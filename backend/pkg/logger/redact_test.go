@@ -0,0 +1,197 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logJSON logs one entry with fields and returns the decoded line
+func logJSON(t *testing.T, l *Logger, fields logrus.Fields) map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	l.SetOutput(&out)
+	l.WithFields(fields).Info("test")
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Failed to decode %q: %v", out.String(), err)
+	}
+	return line
+}
+
+func TestRedactFields(t *testing.T) {
+	l := New("info", "json", "stdout", "")
+	nested := map[string]interface{}{
+		"Refresh_Token": "rt-secret",
+		"amount":        "12.50",
+		"bank": logrus.Fields{
+			"Account_Number": "12345678",
+			"name":           "Main",
+		},
+	}
+	headers := http.Header{"Authorization": {"Bearer abc"}, "Accept": {"application/json"}}
+	labels := map[string]string{"client_secret": "cs", "client_id": "web"}
+	fields := logrus.Fields{
+		"user_id":     "u1",
+		"password":    "hunter2",
+		"NewPassword": "hunter3",
+		"request":     nested,
+		"headers":     headers,
+		"labels":      labels,
+		"count":       3,
+	}
+
+	line := logJSON(t, l, fields)
+	out, _ := json.Marshal(line)
+	for _, leak := range []string{"hunter", "rt-secret", "12345678", "Bearer", `"cs"`} {
+		if strings.Contains(string(out), leak) {
+			t.Errorf("Expected %s to be redacted, got %s", leak, out)
+		}
+	}
+
+	request := line["request"].(map[string]interface{})
+	bank := request["bank"].(map[string]interface{})
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"password", line["password"], Redacted},
+		{"mixed case key", line["NewPassword"], Redacted},
+		{"nested token", request["Refresh_Token"], Redacted},
+		{"nested plain value", request["amount"], "12.50"},
+		{"doubly nested account number", bank["Account_Number"], Redacted},
+		{"doubly nested plain value", bank["name"], "Main"},
+		{"header", line["headers"].(map[string]interface{})["Authorization"].([]interface{})[0], Redacted},
+		{"string map", line["labels"].(map[string]interface{})["client_secret"], Redacted},
+		{"string map plain value", line["labels"].(map[string]interface{})["client_id"], "web"},
+		{"plain value", line["user_id"], "u1"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// The caller's maps are left alone
+	if fields["password"] != "hunter2" || nested["Refresh_Token"] != "rt-secret" ||
+		nested["bank"].(logrus.Fields)["Account_Number"] != "12345678" ||
+		headers.Get("Authorization") != "Bearer abc" || labels["client_secret"] != "cs" {
+		t.Error("Expected redaction not to modify the caller's maps")
+	}
+}
+
+func TestSetRedactFields(t *testing.T) {
+	l := New("info", "json", "stdout", "")
+	l.SetRedactFields(append(DefaultRedactFields, "IBAN")...)
+	line := logJSON(t, l, logrus.Fields{"payee_iban": "DE89370400440532013000", "token": "t"})
+	if line["payee_iban"] != Redacted || line["token"] != Redacted {
+		t.Errorf("Expected added fields to be redacted, got %v", line)
+	}
+
+	l.SetRedactFields()
+	if line := logJSON(t, l, logrus.Fields{"token": "t"}); line["token"] != "t" {
+		t.Errorf("Expected an empty denylist to redact nothing, got %v", line)
+	}
+}
+
+func TestWithUserMasksEmail(t *testing.T) {
+	var out bytes.Buffer
+	l := New("info", "json", "stdout", "")
+	l.SetOutput(&out)
+	l.WithUser("u1", "tushar@example.com").WithField("token", "abc").Info("Signed in")
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["email"] != "t***@example.com" || line["user_id"] != "u1" || line["token"] != Redacted {
+		t.Errorf("Unexpected user fields %v", line)
+	}
+}
+
+func TestRedactMasksEmailFields(t *testing.T) {
+	l := New("info", "json", "stdout", "")
+	line := logJSON(t, l, logrus.Fields{
+		"email":      "tushar@example.com",
+		"User_Email": "priya@example.com",
+		"request":    map[string]interface{}{"email": "sam@example.com"},
+	})
+	if line["email"] != "t***@example.com" || line["User_Email"] != "p***@example.com" {
+		t.Errorf("Expected email fields to be masked, got %v", line)
+	}
+	if request := line["request"].(map[string]interface{}); request["email"] != "s***@example.com" {
+		t.Errorf("Expected nested email fields to be masked, got %v", request)
+	}
+
+	// Masking twice, as WithUser and the hook both do, is harmless
+	var out bytes.Buffer
+	l.SetOutput(&out)
+	l.WithUser("u1", "tushar@example.com").Info("Signed in")
+	if !strings.Contains(out.String(), `"email":"t***@example.com"`) {
+		t.Errorf("Expected WithUser's email to stay masked, got %s", out.String())
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	for email, want := range map[string]string{
+		"tushar@example.com": "t***@example.com",
+		"a@b.co":             "a***@b.co",
+		"élodie@example.fr":  "é***@example.fr",
+		"odd@name@host.com":  "o***@host.com",
+		"@example.com":       "***@example.com",
+		"not-an-email":       "***",
+		"":                   "",
+	} {
+		if got := MaskEmail(email); got != want {
+			t.Errorf("MaskEmail(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
+
+func TestRedactHookAllocations(t *testing.T) {
+	hook := newRedactHook(DefaultRedactFields)
+	entry := &logrus.Entry{Data: logrus.Fields{
+		"user_id": "u1", "method": "GET", "path": "/api/v1/expenses", "status": 200,
+		"request": map[string]interface{}{"amount": "12.50", "category": "food"},
+	}}
+	if allocs := testing.AllocsPerRun(100, func() { hook.Fire(entry) }); allocs != 0 {
+		t.Errorf("Expected entries without sensitive fields to cost no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkRedactHook(b *testing.B) {
+	plain := logrus.Fields{
+		"request_id": "4f1c2b", "user_id": "u1", "method": "GET", "path": "/api/v1/expenses",
+		"status": 200, "duration_ms": 12, "ip": "203.0.113.7",
+	}
+	sensitive := logrus.Fields{
+		"request_id": "4f1c2b", "user_id": "u1", "password": "hunter2",
+		"request": map[string]interface{}{"refresh_token": "rt", "amount": "12.50"},
+	}
+	for _, bm := range []struct {
+		name   string
+		fields logrus.Fields
+		redact bool
+	}{
+		{"plain/without hook", plain, false},
+		{"plain/with hook", plain, true},
+		{"sensitive/with hook", sensitive, true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			l := New("info", "json", "stdout", "")
+			l.SetOutput(io.Discard)
+			if !bm.redact {
+				l.ReplaceHooks(logrus.LevelHooks{})
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.WithFields(bm.fields).Info("request")
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tgfinance/pkg/clock"
+)
+
+// backupTimeFormat stamps rotated files, such as app-2024-03-01T09-30-00.000.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileOptions configures file output and its rotation. Zero limits are off:
+// no size rotation, every backup kept, backups kept regardless of age.
+type FileOptions struct {
+	Path string
+	// MaxSizeMB rotates the file before it would grow past this size
+	MaxSizeMB int
+	// MaxBackups and MaxAgeDays bound the rotated files kept
+	MaxBackups int
+	MaxAgeDays int
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// DefaultFileOptions are used by New for the "file" and "both" outputs
+var DefaultFileOptions = FileOptions{
+	Path:       "logs/app.log",
+	MaxSizeMB:  100,
+	MaxBackups: 7,
+	MaxAgeDays: 28,
+	Compress:   true,
+}
+
+// RotatingWriter appends to a log file, creating its directory, and rotates
+// it by size. Rotated files are renamed with a timestamp, then compressed and
+// pruned in the background. If the file cannot be opened, written or rotated,
+// lines go to the fallback, stderr by default, with one warning until the
+// file works again, so logging never stops and failures do not spam.
+type RotatingWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	fallback   io.Writer
+	clock      clock.Clock
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	failing bool
+	// cleanup tracks background compression and pruning; tidying
+	// serializes it
+	cleanup sync.WaitGroup
+	tidying sync.Mutex
+}
+
+// NewRotatingWriter creates a writer for opts. The file is opened on the
+// first write.
+func NewRotatingWriter(opts FileOptions) *RotatingWriter {
+	return &RotatingWriter{
+		path:       opts.Path,
+		maxSize:    int64(opts.MaxSizeMB) << 20,
+		maxBackups: opts.MaxBackups,
+		maxAge:     time.Duration(opts.MaxAgeDays) * 24 * time.Hour,
+		compress:   opts.Compress,
+		fallback:   os.Stderr,
+		clock:      clock.Real(),
+	}
+}
+
+// Write appends p to the file, rotating it first if p would take it past
+// the size limit. It only fails if the fallback does.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.prepare(int64(len(p))); err != nil {
+		return w.fail(err, p)
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		w.closeFile()
+		return w.fail(err, p)
+	}
+	w.failing = false
+	return n, nil
+}
+
+// Rotate moves the current file aside and starts a new one
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Close closes the file and waits for background compression and pruning
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	err := w.closeFile()
+	w.mu.Unlock()
+	w.cleanup.Wait()
+	return err
+}
+
+// prepare opens the file if needed and rotates it if n more bytes would
+// take it past the size limit
+func (w *RotatingWriter) prepare(n int64) error {
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	if w.maxSize > 0 && w.size > 0 && w.size+n > w.maxSize {
+		return w.rotate()
+	}
+	return nil
+}
+
+// open opens the file for appending, creating its directory
+func (w *RotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// rotate renames the file with a timestamp, opens a new one and starts
+// compressing and pruning the backups
+func (w *RotatingWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, w.backupName(w.clock.Now())); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate %s: %w", w.path, err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	now := w.clock.Now()
+	w.cleanup.Add(1)
+	go func() {
+		defer w.cleanup.Done()
+		w.tidy(now)
+	}()
+	return nil
+}
+
+func (w *RotatingWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file, w.size = nil, 0
+	return err
+}
+
+// fail writes p to the fallback, warning once per run of failures
+func (w *RotatingWriter) fail(err error, p []byte) (int, error) {
+	if !w.failing {
+		w.failing = true
+		fmt.Fprintf(w.fallback, "logger: cannot write to %s, logging to stderr until it recovers: %v\n", w.path, err)
+	}
+	return w.fallback.Write(p)
+}
+
+// backupName returns the name a file rotated at t is renamed to
+func (w *RotatingWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+// nameParts splits the path into its directory, the backup name prefix and
+// the extension
+func (w *RotatingWriter) nameParts() (dir, prefix, ext string) {
+	base := filepath.Base(w.path)
+	ext = filepath.Ext(base)
+	return filepath.Dir(w.path), strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// backup is a rotated file
+type backup struct {
+	path    string
+	rotated time.Time
+}
+
+// backups lists the rotated files, newest first
+func (w *RotatingWriter) backups() ([]backup, error) {
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		name, ok = strings.CutSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		if !ok {
+			continue
+		}
+		rotated, err := time.Parse(backupTimeFormat, name)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, entry.Name()), rotated: rotated})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].rotated.After(backups[j].rotated) })
+	return backups, nil
+}
+
+// tidy removes backups beyond the count and age limits and compresses the
+// rest. Failures are warned about on the fallback; they only cost disk.
+func (w *RotatingWriter) tidy(now time.Time) {
+	w.tidying.Lock()
+	defer w.tidying.Unlock()
+
+	backups, err := w.backups()
+	if err != nil {
+		w.warn(err)
+		return
+	}
+	for i, b := range backups {
+		expired := w.maxAge > 0 && now.Sub(b.rotated) > w.maxAge
+		if w.maxBackups > 0 && i >= w.maxBackups || expired {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				w.warn(err)
+			}
+			continue
+		}
+		if w.compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compressFile(b.path); err != nil {
+				w.warn(err)
+			}
+		}
+	}
+}
+
+func (w *RotatingWriter) warn(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.fallback, "logger: cleaning up rotated logs of %s: %v\n", w.path, err)
+}
+
+// compressFile gzips path to path.gz and removes it
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gz, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
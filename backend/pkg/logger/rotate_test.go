@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tgfinance/pkg/clock"
+)
+
+// testWriter returns a writer for dir/app.log rotating past maxSize bytes,
+// with a fake clock and its fallback captured
+func testWriter(t *testing.T, dir string, maxSize int64, opts FileOptions) (*RotatingWriter, *clock.Fake, *bytes.Buffer) {
+	t.Helper()
+	opts.Path = filepath.Join(dir, "app.log")
+	w := NewRotatingWriter(opts)
+	w.maxSize = maxSize
+	fake := clock.NewFake(time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC))
+	w.clock = fake
+	var fallback bytes.Buffer
+	w.fallback = &fallback
+	t.Cleanup(func() { w.Close() })
+	return w, fake, &fallback
+}
+
+func write(t *testing.T, w io.Writer, line string) {
+	t.Helper()
+	if _, err := w.Write([]byte(line)); err != nil {
+		t.Fatalf("Write(%q) error = %v", line, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%s is not gzipped: %v", path, err)
+		}
+		if b, err = io.ReadAll(gz); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return string(b)
+}
+
+// backupNames returns the rotated files in dir, oldest first
+func backupNames(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "app-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+// block creates a non-empty directory at path, so renaming a file to it
+// fails
+func block(t *testing.T, path string) {
+	t.Helper()
+	if err := os.Mkdir(path, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "keep"), nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotatingWriterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	w, fake, fallback := testWriter(t, dir, 20, FileOptions{})
+
+	write(t, w, "first line\n")
+	write(t, w, "second\n")
+	fake.Advance(time.Minute)
+	write(t, w, "third line\n")
+
+	backups := backupNames(t, dir)
+	if len(backups) != 1 {
+		t.Fatalf("Expected one rotated file, got %v", backups)
+	}
+	if want := filepath.Join(dir, "app-2024-03-01T09-31-00.000.log"); backups[0] != want {
+		t.Errorf("Rotated file = %s, want %s", backups[0], want)
+	}
+	if got := readFile(t, backups[0]); got != "first line\nsecond\n" {
+		t.Errorf("Rotated file contains %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "app.log")); got != "third line\n" {
+		t.Errorf("Current file contains %q", got)
+	}
+	if fallback.Len() != 0 {
+		t.Errorf("Expected nothing on the fallback, got %q", fallback.String())
+	}
+}
+
+func TestRotatingWriterCreatesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "var", "log", "tgfinance")
+	w, _, _ := testWriter(t, dir, 0, FileOptions{})
+
+	write(t, w, "hello\n")
+	if got := readFile(t, filepath.Join(dir, "app.log")); got != "hello\n" {
+		t.Errorf("File contains %q", got)
+	}
+}
+
+func TestRotatingWriterAppendsToExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("0123456789\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	w, _, _ := testWriter(t, dir, 20, FileOptions{})
+
+	write(t, w, "more than enough\n")
+	if backups := backupNames(t, dir); len(backups) != 1 {
+		t.Fatalf("Expected the existing size to count towards the limit, got backups %v", backups)
+	}
+}
+
+func TestRotatingWriterPrunesBackups(t *testing.T) {
+	dir := t.TempDir()
+	w, fake, _ := testWriter(t, dir, 0, FileOptions{MaxBackups: 2, MaxAgeDays: 1})
+
+	// An old backup from an earlier run is pruned by age
+	old := filepath.Join(dir, "app-2024-02-01T00-00-00.000.log")
+	if err := os.WriteFile(old, []byte("old\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		write(t, w, line)
+		fake.Advance(time.Minute)
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := backupNames(t, dir)
+	if len(backups) != 2 {
+		t.Fatalf("Expected the two newest backups, got %v", backups)
+	}
+	for i, want := range []string{"three\n", "four\n"} {
+		if got := readFile(t, backups[i]); got != want {
+			t.Errorf("Backup %s contains %q, want %q", backups[i], got, want)
+		}
+	}
+}
+
+func TestRotatingWriterCompresses(t *testing.T) {
+	dir := t.TempDir()
+	w, _, fallback := testWriter(t, dir, 10, FileOptions{Compress: true})
+
+	write(t, w, "to be compressed\n")
+	write(t, w, "current\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := backupNames(t, dir)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("Expected one gzipped backup, got %v", backups)
+	}
+	if got := readFile(t, backups[0]); got != "to be compressed\n" {
+		t.Errorf("Backup contains %q", got)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".tmp-*")); len(leftovers) > 0 {
+		t.Errorf("Expected no temporary files, got %v", leftovers)
+	}
+	if fallback.Len() != 0 {
+		t.Errorf("Expected nothing on the fallback, got %q", fallback.String())
+	}
+}
+
+func TestRotatingWriterFallsBackOnce(t *testing.T) {
+	dir := t.TempDir()
+	w, fake, fallback := testWriter(t, dir, 10, FileOptions{})
+
+	// A directory where the backup should go makes the rename fail
+	block(t, w.backupName(fake.Now()))
+	write(t, w, "in the file\n")
+	write(t, w, "lost one\n")
+	write(t, w, "lost two\n")
+
+	out := fallback.String()
+	if n := strings.Count(out, "logger: cannot write to"); n != 1 {
+		t.Errorf("Expected one warning, got %d in %q", n, out)
+	}
+	if !strings.Contains(out, "lost one\n") || !strings.Contains(out, "lost two\n") {
+		t.Errorf("Expected the lines on the fallback, got %q", out)
+	}
+
+	// Once the file works again, lines go back to it
+	fake.Advance(time.Second)
+	write(t, w, "recovered\n")
+	if got := readFile(t, filepath.Join(dir, "app.log")); got != "recovered\n" {
+		t.Errorf("Current file contains %q", got)
+	}
+	if strings.Contains(fallback.String(), "recovered") {
+		t.Error("Expected the recovered line in the file only")
+	}
+
+	// A later failure warns again
+	fake.Advance(time.Second)
+	block(t, w.backupName(fake.Now()))
+	write(t, w, "failing again\n")
+	if n := strings.Count(fallback.String(), "logger: cannot write to"); n != 2 {
+		t.Errorf("Expected a second warning, got %d", n)
+	}
+}
+
+func TestRotatingWriterUnwritableDirectory(t *testing.T) {
+	parent := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(parent, nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	w, _, fallback := testWriter(t, parent, 0, FileOptions{})
+
+	write(t, w, "one\n")
+	write(t, w, "two\n")
+	if want := "one\ntwo\n"; !strings.HasSuffix(fallback.String(), want) {
+		t.Errorf("Fallback = %q, want the lines after one warning", fallback.String())
+	}
+	if n := strings.Count(fallback.String(), "logger: cannot write to"); n != 1 {
+		t.Errorf("Expected one warning, got %d", n)
+	}
+}
+
+func TestNewWithFileBoth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	l := NewWithFile("info", "json", "both", "", FileOptions{Path: path})
+	l.Info("to both")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); !strings.Contains(got, `"msg":"to both"`) {
+		t.Errorf("File contains %q", got)
+	}
+}
@@ -0,0 +1,173 @@
+// Package mailer sends email such as password resets and goal reminders
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidMessage is returned for messages that cannot be sent as given
+var ErrInvalidMessage = errors.New("mailer: invalid message")
+
+// Message is an email to send. At least one of TextBody and HTMLBody is
+// required; with both, clients show the one they prefer.
+type Message struct {
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// Sender sends email
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// recipients validates the message and returns its parsed recipients
+func (m Message) recipients() ([]*mail.Address, error) {
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("%w: no recipients", ErrInvalidMessage)
+	}
+	addrs := make([]*mail.Address, len(m.To))
+	for i, to := range m.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad recipient %q", ErrInvalidMessage, to)
+		}
+		addrs[i] = addr
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, fmt.Errorf("%w: subject must be one line", ErrInvalidMessage)
+	}
+	if m.TextBody == "" && m.HTMLBody == "" {
+		return nil, fmt.Errorf("%w: no body", ErrInvalidMessage)
+	}
+	return addrs, nil
+}
+
+// build renders the message to the recipients as MIME, with
+// quoted-printable UTF-8 bodies
+func (m Message) build(from *mail.Address, recipients []*mail.Address, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	to := make([]string, len(recipients))
+	for i, addr := range recipients {
+		to[i] = addr.String()
+	}
+
+	header("From", from.String())
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+
+	if m.TextBody == "" || m.HTMLBody == "" {
+		contentType, body := "text/plain", m.TextBody
+		if m.TextBody == "" {
+			contentType, body = "text/html", m.HTMLBody
+		}
+		header("Content-Type", contentType+"; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", m.TextBody},
+		{"text/html", m.HTMLBody},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID returns a unique Message-ID in the sender's domain
+func messageID(from string) string {
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok && d != "" {
+		domain = d
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// NoopSender discards mail, for environments without SMTP
+type NoopSender struct{}
+
+// Send validates msg and discards it
+func (NoopSender) Send(ctx context.Context, msg Message) error {
+	_, err := msg.recipients()
+	return err
+}
+
+// MockSender records sent mail for tests. Err, if set, is returned instead
+// of recording.
+type MockSender struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error
+}
+
+// Send records msg
+func (m *MockSender) Send(ctx context.Context, msg Message) error {
+	if _, err := msg.recipients(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far
+func (m *MockSender) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"no recipients", Message{Subject: "Hi", TextBody: "Hello"}},
+		{"bad recipient", Message{To: []string{"not an address"}, Subject: "Hi", TextBody: "Hello"}},
+		{"header injection in recipient", Message{To: []string{"ana@example.com\r\nBcc: eve@example.com"}, Subject: "Hi", TextBody: "Hello"}},
+		{"header injection in subject", Message{To: []string{"ana@example.com"}, Subject: "Hi\r\nBcc: eve@example.com", TextBody: "Hello"}},
+		{"no body", Message{To: []string{"ana@example.com"}, Subject: "Hi"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (NoopSender{}).Send(context.Background(), tt.msg); !errors.Is(err, ErrInvalidMessage) {
+				t.Errorf("Expected ErrInvalidMessage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMessageBuild(t *testing.T) {
+	from := &mail.Address{Name: "TG Finance", Address: "no-reply@tgfinance.test"}
+	now := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	msg := Message{
+		To:       []string{"ana@example.com"},
+		Subject:  "Meta atingida: €500 economizados",
+		TextBody: "Parabéns!\nYou saved a long line of text that goes past the seventy-six character limit for quoted-printable.",
+		HTMLBody: "<p>Parabéns!</p>",
+	}
+	recipients, err := msg.recipients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := msg.build(from, recipients, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q, %v; want %q", subject, err, msg.Subject)
+	}
+	if got := parsed.Header.Get("Date"); got != "Fri, 01 Mar 2024 09:30:00 +0000" {
+		t.Errorf("Date = %q", got)
+	}
+	if got := parsed.Header.Get("Message-ID"); !strings.HasSuffix(got, "@tgfinance.test>") {
+		t.Errorf("Message-ID = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", parsed.Header.Get("Content-Type"), err)
+	}
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.TextBody},
+		{"text/html; charset=utf-8", msg.HTMLBody},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Header.Get("Content-Type") != want.contentType {
+			t.Errorf("Content-Type = %q, want %q", part.Header.Get("Content-Type"), want.contentType)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Quoted-printable text uses CRLF line breaks on the wire
+		if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != want.body {
+			t.Errorf("Body = %q, want %q", got, want.body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("Expected two parts, got %v", err)
+	}
+}
+
+func TestMessageBuildSinglePart(t *testing.T) {
+	msg := Message{To: []string{"ana@example.com"}, Subject: "Hi", HTMLBody: "<p>Hello</p>"}
+	recipients, _ := msg.recipients()
+	data, err := msg.build(&mail.Address{Address: "no-reply@tgfinance.test"}, recipients, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestMockSender(t *testing.T) {
+	mock := &MockSender{}
+	msg := Message{To: []string{"ana@example.com"}, Subject: "Hi", TextBody: "Hello"}
+	if err := mock.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.Send(context.Background(), Message{}); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected invalid messages to be rejected, got %v", err)
+	}
+	if sent := mock.Sent(); len(sent) != 1 || sent[0].Subject != "Hi" {
+		t.Errorf("Sent() = %+v", sent)
+	}
+
+	mock.Err = errors.New("relay down")
+	if err := mock.Send(context.Background(), msg); err != mock.Err {
+		t.Errorf("Expected the configured error, got %v", err)
+	}
+	if len(mock.Sent()) != 1 {
+		t.Error("Expected a failed send not to be recorded")
+	}
+}
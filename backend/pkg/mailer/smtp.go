@@ -0,0 +1,253 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"sync"
+	"time"
+
+	"tgfinance/pkg/clock"
+)
+
+// SMTP TLS modes
+const (
+	TLSNone     = "none"
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+)
+
+// SMTPOptions configures an SMTP sender
+type SMTPOptions struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// From is the sender address, such as "TG Finance <no-reply@example.com>"
+	From string
+	// TLSMode is TLSStartTLS, TLSImplicit or TLSNone
+	TLSMode string
+	Timeout time.Duration
+}
+
+// New returns an SMTP sender for opts, or a NoopSender if no SMTP host is
+// configured
+func New(opts SMTPOptions) (Sender, error) {
+	if opts.Host == "" {
+		return NoopSender{}, nil
+	}
+	return NewSMTPSender(opts)
+}
+
+// SMTPSender sends mail through an SMTP server. One connection is kept open
+// and reused for later messages, so sends are serialized; a broken
+// connection is replaced on the next send. Authentication uses PLAIN, which
+// net/smtp only allows over TLS or to localhost.
+type SMTPSender struct {
+	opts      SMTPOptions
+	from      *mail.Address
+	tlsConfig *tls.Config
+	clock     clock.Clock
+
+	mu     sync.Mutex
+	conn   net.Conn
+	client *smtp.Client
+}
+
+// NewSMTPSender creates a sender for opts. No connection is made until the
+// first message is sent.
+func NewSMTPSender(opts SMTPOptions) (*SMTPSender, error) {
+	from, err := mail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: bad from address %q: %w", opts.From, err)
+	}
+	switch opts.TLSMode {
+	case TLSNone, TLSStartTLS, TLSImplicit:
+	default:
+		return nil, fmt.Errorf("mailer: unknown TLS mode %q", opts.TLSMode)
+	}
+	return &SMTPSender{
+		opts:      opts,
+		from:      from,
+		tlsConfig: &tls.Config{ServerName: opts.Host, MinVersion: tls.VersionTLS12},
+		clock:     clock.Real(),
+	}, nil
+}
+
+// WithTLSConfig sets the TLS configuration, such as trusted roots for a
+// private mail relay
+func (s *SMTPSender) WithTLSConfig(c *tls.Config) *SMTPSender {
+	s.tlsConfig = c
+	return s
+}
+
+// WithClock sets the clock used for the Date header
+func (s *SMTPSender) WithClock(c clock.Clock) *SMTPSender {
+	s.clock = c
+	return s
+}
+
+// Send delivers msg. Cancelling ctx aborts the exchange with the server and
+// drops the connection.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	recipients, err := msg.recipients()
+	if err != nil {
+		return err
+	}
+	data, err := msg.build(s.from, recipients, s.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err = s.send(ctx, recipients, data)
+	if err != nil {
+		s.close()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("mailer: %w", err)
+	}
+	return nil
+}
+
+// send delivers data over the open connection, reconnecting if there is
+// none or the server no longer answers
+func (s *SMTPSender) send(ctx context.Context, recipients []*mail.Address, data []byte) error {
+	if s.client != nil {
+		s.setDeadline(ctx)
+		stop := s.abortOnCancel(ctx)
+		err := s.client.Reset()
+		stop()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.close()
+		}
+	}
+	if s.client == nil {
+		if err := s.dial(ctx); err != nil {
+			return err
+		}
+	}
+
+	s.setDeadline(ctx)
+	defer s.abortOnCancel(ctx)()
+
+	if err := s.client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	for _, addr := range recipients {
+		if err := s.client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := s.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// dial connects, upgrades to TLS as configured and authenticates
+func (s *SMTPSender) dial(ctx context.Context) error {
+	addr := net.JoinHostPort(s.opts.Host, s.opts.Port)
+	dialer := &net.Dialer{Timeout: s.opts.Timeout}
+
+	var conn net.Conn
+	var err error
+	if s.opts.TLSMode == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.setDeadline(ctx)
+	defer s.abortOnCancel(ctx)()
+
+	client, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		s.conn = nil
+		return err
+	}
+	s.client = client
+
+	if s.opts.TLSMode == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			s.close()
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(s.tlsConfig); err != nil {
+			s.close()
+			return err
+		}
+	}
+	if s.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)); err != nil {
+			s.close()
+			return err
+		}
+	}
+	return nil
+}
+
+// setDeadline bounds the next exchange by the timeout and ctx's deadline
+func (s *SMTPSender) setDeadline(ctx context.Context) {
+	var deadline time.Time
+	if s.opts.Timeout > 0 {
+		deadline = time.Now().Add(s.opts.Timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	s.conn.SetDeadline(deadline)
+}
+
+// abortOnCancel unblocks reads and writes on the connection when ctx is
+// cancelled, until the returned function is called
+func (s *SMTPSender) abortOnCancel(ctx context.Context) func() {
+	conn := s.conn
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	return func() { stop() }
+}
+
+// close drops the connection without waiting for the server
+func (s *SMTPSender) close() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn, s.client = nil, nil
+}
+
+// Close ends the session politely and closes the connection
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == nil {
+		return nil
+	}
+	s.conn.SetDeadline(time.Now().Add(time.Second))
+	err := s.client.Quit()
+	s.close()
+	return err
+}
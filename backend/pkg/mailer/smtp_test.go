@@ -0,0 +1,386 @@
+package mailer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// received is a message accepted by testSMTPServer
+type received struct {
+	from string
+	to   []string
+	data string
+	tls  bool
+}
+
+// testSMTPServer is an in-memory SMTP server on a loopback port. It speaks
+// enough of the protocol for net/smtp: EHLO, STARTTLS, AUTH PLAIN, MAIL,
+// RCPT, DATA, RSET, NOOP and QUIT.
+type testSMTPServer struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	// startTLS advertises STARTTLS on plain connections
+	startTLS bool
+	// dropAfterData closes each connection after its first message
+	dropAfterData bool
+	// stall, if set, delays the reply to DATA until it is closed
+	stall chan struct{}
+
+	mu       sync.Mutex
+	messages []received
+	auths    []string
+	conns    int
+	wg       sync.WaitGroup
+}
+
+// newTestSMTPServer starts a server in the TLS mode and returns it with a
+// client TLS configuration trusting its certificate
+func newTestSMTPServer(t *testing.T, mode string) (*testSMTPServer, *tls.Config) {
+	t.Helper()
+	serverTLS, clientTLS := testCertificates(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode == TLSImplicit {
+		ln = tls.NewListener(ln, serverTLS)
+	}
+	s := &testSMTPServer{ln: ln, tlsConfig: serverTLS, startTLS: mode == TLSStartTLS}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		ln.Close()
+		if s.stall != nil {
+			select {
+			case <-s.stall:
+			default:
+				close(s.stall)
+			}
+		}
+		s.wg.Wait()
+	})
+	return s, clientTLS
+}
+
+// options returns SMTP options for the server
+func (s *testSMTPServer) options(mode string) SMTPOptions {
+	_, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return SMTPOptions{
+		Host: "127.0.0.1", Port: port, Username: "mailer", Password: "s3cret",
+		From: "TG Finance <no-reply@tgfinance.test>", TLSMode: mode, Timeout: 2 * time.Second,
+	}
+}
+
+func (s *testSMTPServer) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.messages...)
+}
+
+func (s *testSMTPServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func (s *testSMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *testSMTPServer) session(conn net.Conn) {
+	_, isTLS := conn.(*tls.Conn)
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 test ESMTP")
+
+	var msg received
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"test", "AUTH PLAIN", "8BITMIME"}
+			if s.startTLS && !isTLS {
+				lines = append(lines, "STARTTLS")
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, isTLS = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			_, initial, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(initial)
+			s.mu.Lock()
+			s.auths = append(s.auths, string(decoded))
+			s.mu.Unlock()
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			msg = received{from: envelopeAddress(arg, "FROM:"), tls: isTLS}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, envelopeAddress(arg, "TO:"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			if s.stall != nil {
+				<-s.stall
+				return
+			}
+			msg.data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+			if s.dropAfterData {
+				return
+			}
+		case "RSET":
+			msg = received{}
+			tp.PrintfLine("250 ok")
+		case "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// envelopeAddress returns the address of a MAIL or RCPT argument without
+// its ESMTP parameters
+func envelopeAddress(arg, prefix string) string {
+	addr, _, _ := strings.Cut(strings.TrimPrefix(arg, prefix), " ")
+	return strings.Trim(addr, "<>")
+}
+
+// testCertificates returns a server TLS configuration with a self-signed
+// certificate for 127.0.0.1 and a client configuration trusting it
+func testCertificates(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		&tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+}
+
+func newTestSender(t *testing.T, server *testSMTPServer, clientTLS *tls.Config, mode string) *SMTPSender {
+	t.Helper()
+	sender, err := NewSMTPSender(server.options(mode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.WithTLSConfig(clientTLS)
+	t.Cleanup(func() { sender.Close() })
+	return sender
+}
+
+var testMessage = Message{
+	To:       []string{"Ana Lima <ana@example.com>", "bo@example.com"},
+	Subject:  "Reset your password",
+	TextBody: "Use the link to reset your password.",
+	HTMLBody: "<p>Use the link to reset your password.</p>",
+}
+
+func TestSMTPSenderModes(t *testing.T) {
+	for _, mode := range []string{TLSNone, TLSStartTLS, TLSImplicit} {
+		t.Run(mode, func(t *testing.T) {
+			server, clientTLS := newTestSMTPServer(t, mode)
+			sender := newTestSender(t, server, clientTLS, mode)
+
+			if err := sender.Send(context.Background(), testMessage); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			got := server.received()
+			if len(got) != 1 {
+				t.Fatalf("Expected 1 message, got %d", len(got))
+			}
+			if got[0].from != "no-reply@tgfinance.test" || strings.Join(got[0].to, ",") != "ana@example.com,bo@example.com" {
+				t.Errorf("Unexpected envelope from %s to %v", got[0].from, got[0].to)
+			}
+			if wantTLS := mode != TLSNone; got[0].tls != wantTLS {
+				t.Errorf("Expected TLS %v, got %v", wantTLS, got[0].tls)
+			}
+
+			parsed, err := mail.ReadMessage(strings.NewReader(got[0].data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header.Get("Subject") != "Reset your password" {
+				t.Errorf("Subject = %q", parsed.Header.Get("Subject"))
+			}
+			if !strings.Contains(got[0].data, "Use the link to reset your password.") {
+				t.Errorf("Expected the body in the message, got %s", got[0].data)
+			}
+			server.mu.Lock()
+			auths := server.auths
+			server.mu.Unlock()
+			if len(auths) != 1 || auths[0] != "\x00mailer\x00s3cret" {
+				t.Errorf("Expected PLAIN auth as mailer, got %q", auths)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderReusesConnection(t *testing.T) {
+	server, clientTLS := newTestSMTPServer(t, TLSStartTLS)
+	sender := newTestSender(t, server, clientTLS, TLSStartTLS)
+
+	for i := 0; i < 3; i++ {
+		if err := sender.Send(context.Background(), testMessage); err != nil {
+			t.Fatalf("Send() %d error = %v", i, err)
+		}
+	}
+	if n := len(server.received()); n != 3 {
+		t.Errorf("Expected 3 messages, got %d", n)
+	}
+	if n := server.connections(); n != 1 {
+		t.Errorf("Expected 1 connection to be reused, got %d", n)
+	}
+}
+
+func TestSMTPSenderReconnects(t *testing.T) {
+	server, clientTLS := newTestSMTPServer(t, TLSNone)
+	server.dropAfterData = true
+	sender := newTestSender(t, server, clientTLS, TLSNone)
+
+	for i := 0; i < 2; i++ {
+		if err := sender.Send(context.Background(), testMessage); err != nil {
+			t.Fatalf("Send() %d error = %v", i, err)
+		}
+	}
+	if n := server.connections(); n != 2 {
+		t.Errorf("Expected a new connection after the server hung up, got %d", n)
+	}
+}
+
+func TestSMTPSenderRequiresSTARTTLS(t *testing.T) {
+	server, clientTLS := newTestSMTPServer(t, TLSNone)
+	sender := newTestSender(t, server, clientTLS, TLSStartTLS)
+
+	err := sender.Send(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Expected a STARTTLS error, got %v", err)
+	}
+	if len(server.received()) != 0 {
+		t.Error("Expected nothing to be sent in the clear")
+	}
+}
+
+func TestSMTPSenderCancel(t *testing.T) {
+	server, clientTLS := newTestSMTPServer(t, TLSNone)
+	server.stall = make(chan struct{})
+	sender := newTestSender(t, server, clientTLS, TLSNone)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := sender.Send(ctx, testMessage)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected cancellation to abort the send, took %v", elapsed)
+	}
+
+	if err := sender.Send(ctx, testMessage); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled context to be refused, got %v", err)
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	sender, err := New(SMTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sender.(NoopSender); !ok {
+		t.Errorf("Expected a NoopSender without an SMTP host, got %T", sender)
+	}
+
+	cfg := SMTPOptions{Host: "smtp.example.com", Port: "587", From: "no-reply@example.com", TLSMode: TLSStartTLS}
+	if sender, err := New(cfg); err != nil {
+		t.Fatal(err)
+	} else if _, ok := sender.(*SMTPSender); !ok {
+		t.Errorf("Expected an SMTPSender, got %T", sender)
+	}
+
+	for _, bad := range []SMTPOptions{
+		{Host: "smtp.example.com", From: "not an address", TLSMode: TLSStartTLS},
+		{Host: "smtp.example.com", From: "no-reply@example.com", TLSMode: "ssl"},
+	} {
+		if _, err := New(bad); err == nil {
+			t.Errorf("Expected an error for %+v", bad)
+		}
+	}
+}
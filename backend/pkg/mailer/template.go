@@ -0,0 +1,84 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template renders messages from a subject, a text body and an HTML body.
+// The HTML body uses html/template, so values are escaped for the context
+// they appear in and user-supplied names cannot inject markup or links. The
+// subject and text body use text/template; a rendered subject must be one
+// line so values cannot inject headers.
+type Template struct {
+	name    string
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// NewTemplate parses a template. Either body may be empty, but not both.
+func NewTemplate(name, subject, text, html string) (*Template, error) {
+	if text == "" && html == "" {
+		return nil, fmt.Errorf("mailer: template %s has no body", name)
+	}
+	t := &Template{name: name}
+	var err error
+	if t.subject, err = texttemplate.New(name + ".subject").Option("missingkey=error").Parse(subject); err != nil {
+		return nil, fmt.Errorf("mailer: template %s subject: %w", name, err)
+	}
+	if text != "" {
+		if t.text, err = texttemplate.New(name + ".txt").Option("missingkey=error").Parse(text); err != nil {
+			return nil, fmt.Errorf("mailer: template %s text body: %w", name, err)
+		}
+	}
+	if html != "" {
+		if t.html, err = htmltemplate.New(name + ".html").Option("missingkey=error").Parse(html); err != nil {
+			return nil, fmt.Errorf("mailer: template %s HTML body: %w", name, err)
+		}
+	}
+	return t, nil
+}
+
+// MustTemplate is NewTemplate for templates fixed at compile time; it panics
+// if they do not parse
+func MustTemplate(name, subject, text, html string) *Template {
+	t, err := NewTemplate(name, subject, text, html)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Render executes the template with data into a message to the recipients
+func (t *Template) Render(to []string, data interface{}) (Message, error) {
+	msg := Message{To: to}
+
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, data); err != nil {
+		return Message{}, fmt.Errorf("mailer: render %s subject: %w", t.name, err)
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return Message{}, fmt.Errorf("%w: template %s rendered a subject of more than one line", ErrInvalidMessage, t.name)
+	}
+
+	if t.text != nil {
+		buf.Reset()
+		if err := t.text.Execute(&buf, data); err != nil {
+			return Message{}, fmt.Errorf("mailer: render %s text body: %w", t.name, err)
+		}
+		msg.TextBody = buf.String()
+	}
+	if t.html != nil {
+		buf.Reset()
+		if err := t.html.Execute(&buf, data); err != nil {
+			return Message{}, fmt.Errorf("mailer: render %s HTML body: %w", t.name, err)
+		}
+		msg.HTMLBody = buf.String()
+	}
+	return msg, nil
+}
@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var resetTemplate = MustTemplate("reset",
+	"Password reset for {{.Name}}",
+	"Hi {{.Name}},\nReset your password at {{.URL}}\n",
+	`<p>Hi {{.Name}},</p><p><a href="{{.URL}}">Reset your password</a></p>`,
+)
+
+func TestTemplateRender(t *testing.T) {
+	msg, err := resetTemplate.Render([]string{"ana@example.com"}, map[string]string{
+		"Name": "Ana & Bo",
+		"URL":  "https://tgfinance.test/reset?token=abc&user=1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Password reset for Ana & Bo" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if !strings.Contains(msg.TextBody, "Hi Ana & Bo,") || !strings.Contains(msg.TextBody, "token=abc&user=1") {
+		t.Errorf("Expected the text body unescaped, got %q", msg.TextBody)
+	}
+	if !strings.Contains(msg.HTMLBody, "Hi Ana &amp; Bo,") || !strings.Contains(msg.HTMLBody, `href="https://tgfinance.test/reset?token=abc&amp;user=1"`) {
+		t.Errorf("Expected the HTML body escaped, got %q", msg.HTMLBody)
+	}
+	if len(msg.To) != 1 || msg.To[0] != "ana@example.com" {
+		t.Errorf("To = %v", msg.To)
+	}
+}
+
+func TestTemplateEscapesInjection(t *testing.T) {
+	msg, err := resetTemplate.Render([]string{"ana@example.com"}, map[string]string{
+		"Name": `<script>alert("x")</script>`,
+		"URL":  `javascript:alert("x")`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.HTMLBody, "<script>") {
+		t.Errorf("Expected markup to be escaped, got %q", msg.HTMLBody)
+	}
+	if strings.Contains(msg.HTMLBody, "javascript:") {
+		t.Errorf("Expected an unsafe URL to be filtered, got %q", msg.HTMLBody)
+	}
+}
+
+func TestTemplateRejectsSubjectInjection(t *testing.T) {
+	_, err := resetTemplate.Render([]string{"ana@example.com"}, map[string]string{
+		"Name": "Ana\r\nBcc: eve@example.com",
+		"URL":  "https://tgfinance.test/reset",
+	})
+	if !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected ErrInvalidMessage, got %v", err)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	if _, err := NewTemplate("empty", "Subject", "", ""); err == nil {
+		t.Error("Expected an error for a template without a body")
+	}
+	if _, err := NewTemplate("broken", "Subject", "", "{{.Name"); err == nil {
+		t.Error("Expected a parse error")
+	}
+	if _, err := resetTemplate.Render([]string{"ana@example.com"}, map[string]string{"Name": "Ana"}); err == nil {
+		t.Error("Expected an error for a missing value")
+	}
+}
@@ -0,0 +1,35 @@
+package money
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IsSupportedCurrency returns true if code is a supported ISO 4217 currency
+func IsSupportedCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// ValidateCurrency validates a currency code, which must be an uppercase
+// ISO 4217 code that is supported
+func ValidateCurrency(code string) error {
+	if len(code) != 3 || strings.ToUpper(code) != code {
+		return fmt.Errorf("currency must be a 3-letter uppercase ISO 4217 code")
+	}
+	if !IsSupportedCurrency(code) {
+		return fmt.Errorf("currency %s is not supported", code)
+	}
+	return nil
+}
+
+// SupportedCurrencies returns the supported currency codes in order
+func SupportedCurrencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,224 @@
+package money
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultLocale is used when a user's locale is not supported
+const DefaultLocale = "en"
+
+//go:embed locales.json
+var localeData []byte
+
+// Locale describes how amounts are written in a locale
+type Locale struct {
+	Decimal string `json:"decimal"`
+	Group   string `json:"group"`
+	// Grouping lists digit group sizes from the right; the last size repeats
+	Grouping    []int `json:"grouping"`
+	SymbolAfter bool  `json:"symbol_after"`
+	Space       bool  `json:"space"`
+}
+
+// Currency describes a currency's symbol and minor unit digits
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+var (
+	locales    map[string]Locale
+	currencies map[string]Currency
+
+	// warned records unknown locales that were already logged
+	warned sync.Map
+
+	// Warnf logs unknown locale fallbacks
+	Warnf = log.Printf
+)
+
+func init() {
+	var data struct {
+		Locales    map[string]Locale   `json:"locales"`
+		Currencies map[string]Currency `json:"currencies"`
+	}
+	if err := json.Unmarshal(localeData, &data); err != nil {
+		panic("money: invalid locale data: " + err.Error())
+	}
+	locales = data.Locales
+	currencies = data.Currencies
+}
+
+// LookupLocale returns the formatting rules for a locale such as "en-IN" or
+// "de_DE", falling back to the language and then to DefaultLocale. ok is
+// false when the fallback to DefaultLocale was used.
+func LookupLocale(locale string) (Locale, bool) {
+	tag := strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	language, region, _ := strings.Cut(tag, "-")
+	language = strings.ToLower(language)
+
+	if region != "" {
+		if l, ok := locales[language+"-"+strings.ToUpper(region)]; ok {
+			return l, true
+		}
+	}
+	if l, ok := locales[language]; ok {
+		return l, true
+	}
+	return locales[DefaultLocale], false
+}
+
+// LookupCurrency returns the symbol and decimals for an ISO 4217 code.
+// Unknown currencies use the code as their symbol and two decimals.
+func LookupCurrency(code string) Currency {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if c, ok := currencies[code]; ok {
+		return c
+	}
+	return Currency{Symbol: code, Decimals: 2}
+}
+
+// resolveLocale looks up a locale and warns once per unsupported locale
+func resolveLocale(locale string) Locale {
+	l, ok := LookupLocale(locale)
+	if !ok {
+		if _, seen := warned.LoadOrStore(locale, true); !seen {
+			Warnf("money: unsupported locale %q, falling back to %s", locale, DefaultLocale)
+		}
+	}
+	return l
+}
+
+// Format formats an amount in a currency for a locale, e.g. $1,234.56,
+// 1.234,56 € or ₹1,23,456.00
+func Format(amount float64, currency, locale string) string {
+	l := resolveLocale(locale)
+	c := LookupCurrency(currency)
+	return l.withSymbol(l.number(amount, c.Decimals), c.Symbol, amount < 0 && !isZero(amount, c.Decimals))
+}
+
+// FormatNumber formats an amount without a currency symbol
+func FormatNumber(amount float64, decimals int, locale string) string {
+	l := resolveLocale(locale)
+	number := l.number(amount, decimals)
+	if amount < 0 && !isZero(amount, decimals) {
+		return "-" + number
+	}
+	return number
+}
+
+// FormatCompact formats large amounts with a magnitude suffix for dashboard
+// widgets, e.g. $1.2K or $3.4M. Amounts under 1000 use Format.
+func FormatCompact(amount float64, currency, locale string) string {
+	abs := math.Abs(amount)
+	if abs < 1000 {
+		return Format(amount, currency, locale)
+	}
+
+	suffixes := []struct {
+		divisor float64
+		suffix  string
+	}{
+		{1e12, "T"},
+		{1e9, "B"},
+		{1e6, "M"},
+		{1e3, "K"},
+	}
+
+	l := resolveLocale(locale)
+	c := LookupCurrency(currency)
+	for i, s := range suffixes {
+		if abs < s.divisor {
+			continue
+		}
+		scaled := math.Round(abs/s.divisor*10) / 10
+		// Rounding can carry into the next magnitude, e.g. 999.95K is 1M
+		if scaled >= 1000 && i > 0 {
+			s = suffixes[i-1]
+			scaled = math.Round(abs/s.divisor*10) / 10
+		}
+		number := strconv.FormatFloat(scaled, 'f', -1, 64)
+		number = strings.Replace(number, ".", l.Decimal, 1) + s.suffix
+		return l.withSymbol(number, c.Symbol, amount < 0)
+	}
+	return Format(amount, currency, locale)
+}
+
+// FuncMap returns template functions that format amounts for a locale. It can
+// be converted to an html/template FuncMap.
+func FuncMap(locale string) template.FuncMap {
+	return template.FuncMap{
+		"money": func(amount float64, currency string) string {
+			return Format(amount, currency, locale)
+		},
+		"moneyCompact": func(amount float64, currency string) string {
+			return FormatCompact(amount, currency, locale)
+		},
+		"number": func(amount float64, decimals int) string {
+			return FormatNumber(amount, decimals, locale)
+		},
+	}
+}
+
+// number formats the absolute amount with grouping and decimal separators
+func (l Locale) number(amount float64, decimals int) string {
+	digits := strconv.FormatFloat(math.Abs(amount), 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(digits, ".")
+
+	var groups []string
+	sizes := l.Grouping
+	for i := 0; len(integer) > 0; {
+		size := 3
+		if len(sizes) > 0 {
+			size = sizes[min(i, len(sizes)-1)]
+		}
+		if len(integer) <= size {
+			groups = append(groups, integer)
+			break
+		}
+		groups = append(groups, integer[len(integer)-size:])
+		integer = integer[:len(integer)-size]
+		i++
+	}
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+
+	result := strings.Join(groups, l.Group)
+	if fraction != "" {
+		result += l.Decimal + fraction
+	}
+	return result
+}
+
+// withSymbol places the currency symbol and sign around a formatted number
+func (l Locale) withSymbol(number, symbol string, negative bool) string {
+	// Locales that space the symbol use a no-break space, as CLDR does
+	separator := ""
+	if l.Space {
+		separator = "\u00a0"
+	}
+
+	var result string
+	if l.SymbolAfter {
+		result = number + separator + symbol
+	} else {
+		result = symbol + separator + number
+	}
+	if negative {
+		return "-" + result
+	}
+	return result
+}
+
+// isZero returns true if the amount rounds to zero at the given decimals
+func isZero(amount float64, decimals int) bool {
+	return math.Abs(amount)*math.Pow(10, float64(decimals)) < 0.5
+}
@@ -0,0 +1,104 @@
+package money
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		locale   string
+		want     string
+	}{
+		{"us dollars", 1234.56, "USD", "en", "$1,234.56"},
+		{"region falls back to language", 1234.56, "USD", "en-US", "$1,234.56"},
+		{"german euros", 1234.56, "EUR", "de-DE", "1.234,56\u00a0€"},
+		{"french grouping", 1234567.8, "EUR", "fr_FR", "1\u202f234\u202f567,80\u00a0€"},
+		{"indian grouping", 123456, "INR", "en-IN", "₹1,23,456.00"},
+		{"indian crore", 12345678.9, "INR", "hi-IN", "₹1,23,45,678.90"},
+		{"negative", -1234.5, "USD", "en", "-$1,234.50"},
+		{"negative german", -0.5, "EUR", "de", "-0,50\u00a0€"},
+		{"negative rounding to zero", -0.001, "USD", "en", "$0.00"},
+		{"zero", 0, "GBP", "en-GB", "£0.00"},
+		{"zero-decimal yen", 1234.56, "JPY", "ja", "¥1,235"},
+		{"zero-decimal won", -50000, "KRW", "en", "-₩50,000"},
+		{"unknown currency", 10, "xyz", "en", "XYZ10.00"},
+		{"small amount", 7, "USD", "en", "$7.00"},
+		{"unknown locale", 1234.56, "USD", "tlh", "$1,234.56"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.amount, tt.currency, tt.locale); got != tt.want {
+				t.Errorf("Format(%v, %s, %s) = %q, want %q", tt.amount, tt.currency, tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCompact(t *testing.T) {
+	tests := []struct {
+		amount float64
+		locale string
+		want   string
+	}{
+		{999, "en", "$999.00"},
+		{1200, "en", "$1.2K"},
+		{1000, "en", "$1K"},
+		{-3450000, "en", "-$3.5M"},
+		{999950, "en", "$1M"},
+		{2500000000, "en", "$2.5B"},
+		{1250, "de", "1,3K\u00a0$"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatCompact(tt.amount, "USD", tt.locale); got != tt.want {
+			t.Errorf("FormatCompact(%v, %s) = %q, want %q", tt.amount, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownLocaleWarnsOnce(t *testing.T) {
+	var warnings []string
+	original := Warnf
+	Warnf = func(format string, args ...any) {
+		warnings = append(warnings, format)
+	}
+	defer func() { Warnf = original }()
+
+	Format(1, "USD", "xx-warn")
+	Format(2, "USD", "xx-warn")
+
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %d, want 1", len(warnings))
+	}
+	if _, ok := LookupLocale("xx-warn"); ok {
+		t.Error("LookupLocale() should report the fallback")
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	tmpl := template.Must(template.New("email").Funcs(FuncMap("en-IN")).
+		Parse(`{{money .Amount "INR"}} / {{moneyCompact .Amount "INR"}} / {{number .Amount 1}}`))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]float64{"Amount": 123456}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "₹1,23,456.00 / ₹123.5K / 1,23,456.0") {
+		t.Errorf("template output = %q", got)
+	}
+}
+
+func TestValidateCurrency(t *testing.T) {
+	for code, valid := range map[string]bool{"USD": true, "EUR": true, "KWD": true, "usd": false, "US": false, "XYZ": false, "": false} {
+		if err := ValidateCurrency(code); (err == nil) != valid {
+			t.Errorf("ValidateCurrency(%q) error = %v, want valid %v", code, err, valid)
+		}
+	}
+}
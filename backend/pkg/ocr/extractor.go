@@ -0,0 +1,165 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ExtractedReceipt holds the fields read from a receipt image or PDF.
+// Confidence ranges from 0 to 1.
+type ExtractedReceipt struct {
+	Merchant   string     `json:"merchant,omitempty"`
+	Total      *float64   `json:"total,omitempty"`
+	Date       *time.Time `json:"date,omitempty"`
+	Currency   string     `json:"currency,omitempty"`
+	Confidence float64    `json:"confidence"`
+}
+
+// Extractor reads receipt fields from an uploaded file
+type Extractor interface {
+	Extract(ctx context.Context, file io.Reader, contentType string) (ExtractedReceipt, error)
+}
+
+// ErrUnsupportedContentType is returned for files the extractor cannot read
+var ErrUnsupportedContentType = errors.New("ocr: unsupported content type")
+
+// supportedContentTypes lists the receipt formats accepted for extraction
+var supportedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"image/heic":      true,
+	"application/pdf": true,
+}
+
+// IsSupportedContentType returns true if receipts of the content type can be extracted
+func IsSupportedContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return supportedContentTypes[strings.ToLower(mediaType)]
+}
+
+// HTTPExtractor calls a cloud vision receipt API. The file is POSTed as the
+// raw request body with its content type, and the service responds with a
+// JSON document matching ExtractedReceipt (date as YYYY-MM-DD).
+type HTTPExtractor struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPExtractor creates a new cloud vision extractor
+func NewHTTPExtractor(endpoint, apiKey string, timeout time.Duration) *HTTPExtractor {
+	return &HTTPExtractor{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// visionResponse is the wire format returned by the vision service
+type visionResponse struct {
+	Merchant   string   `json:"merchant"`
+	Total      *float64 `json:"total"`
+	Date       string   `json:"date"`
+	Currency   string   `json:"currency"`
+	Confidence float64  `json:"confidence"`
+}
+
+// Extract sends the file to the vision service and parses its response
+func (e *HTTPExtractor) Extract(ctx context.Context, file io.Reader, contentType string) (ExtractedReceipt, error) {
+	if !IsSupportedContentType(contentType) {
+		return ExtractedReceipt{}, ErrUnsupportedContentType
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, file)
+	if err != nil {
+		return ExtractedReceipt{}, fmt.Errorf("ocr: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return ExtractedReceipt{}, fmt.Errorf("ocr: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return ExtractedReceipt{}, fmt.Errorf("ocr: service returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	var payload visionResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&payload); err != nil {
+		return ExtractedReceipt{}, fmt.Errorf("ocr: failed to decode response: %w", err)
+	}
+
+	receipt := ExtractedReceipt{
+		Merchant:   strings.TrimSpace(payload.Merchant),
+		Total:      payload.Total,
+		Currency:   strings.ToUpper(strings.TrimSpace(payload.Currency)),
+		Confidence: clampConfidence(payload.Confidence),
+	}
+	if payload.Date != "" {
+		if date, err := time.Parse("2006-01-02", payload.Date); err == nil {
+			receipt.Date = &date
+		}
+	}
+
+	return receipt, nil
+}
+
+// StubExtractor returns a fixed result, for tests and deployments without OCR
+type StubExtractor struct {
+	Receipt ExtractedReceipt
+	Err     error
+	Calls   int
+}
+
+// Extract returns the configured receipt or error
+func (s *StubExtractor) Extract(ctx context.Context, file io.Reader, contentType string) (ExtractedReceipt, error) {
+	s.Calls++
+	if err := ctx.Err(); err != nil {
+		return ExtractedReceipt{}, err
+	}
+	if s.Err != nil {
+		return ExtractedReceipt{}, s.Err
+	}
+	return s.Receipt, nil
+}
+
+// clampConfidence keeps confidence within [0, 1]
+func clampConfidence(confidence float64) float64 {
+	if confidence < 0 {
+		return 0
+	}
+	if confidence > 1 {
+		return 1
+	}
+	return confidence
+}
+
+// NewExtractor creates the extractor for the configured provider
+func NewExtractor(provider, endpoint, apiKey string, timeout time.Duration) (Extractor, error) {
+	switch provider {
+	case "cloud_vision":
+		if endpoint == "" {
+			return nil, errors.New("ocr: endpoint is required for the cloud_vision provider")
+		}
+		return NewHTTPExtractor(endpoint, apiKey, timeout), nil
+	case "stub":
+		return &StubExtractor{}, nil
+	default:
+		return nil, fmt.Errorf("ocr: unknown provider %q", provider)
+	}
+}
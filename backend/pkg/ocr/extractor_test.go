@@ -0,0 +1,112 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPExtractor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Expected bearer auth, got %q", got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "image/jpeg" {
+			t.Errorf("Expected image/jpeg, got %q", got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "receipt-bytes" {
+			t.Errorf("Unexpected body %q", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"merchant":" Shell ","total":42.5,"date":"2024-03-05","currency":"usd","confidence":1.7}`))
+	}))
+	defer server.Close()
+
+	extractor := NewHTTPExtractor(server.URL, "test-key", time.Second)
+	receipt, err := extractor.Extract(context.Background(), strings.NewReader("receipt-bytes"), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	if receipt.Merchant != "Shell" || receipt.Currency != "USD" {
+		t.Errorf("Unexpected merchant/currency: %+v", receipt)
+	}
+	if receipt.Total == nil || *receipt.Total != 42.5 {
+		t.Errorf("Expected total 42.5, got %v", receipt.Total)
+	}
+	if receipt.Date == nil || !receipt.Date.Equal(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %v", receipt.Date)
+	}
+	if receipt.Confidence != 1 {
+		t.Errorf("Confidence should be clamped to 1, got %v", receipt.Confidence)
+	}
+}
+
+func TestHTTPExtractorErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	extractor := NewHTTPExtractor(server.URL, "", time.Second)
+
+	if _, err := extractor.Extract(context.Background(), strings.NewReader("x"), "image/png"); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("Expected service error with status, got %v", err)
+	}
+
+	if _, err := extractor.Extract(context.Background(), strings.NewReader("x"), "text/plain"); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Errorf("Expected ErrUnsupportedContentType, got %v", err)
+	}
+}
+
+func TestHTTPExtractorTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	extractor := NewHTTPExtractor(server.URL, "", 20*time.Millisecond)
+	if _, err := extractor.Extract(context.Background(), strings.NewReader("x"), "application/pdf"); err == nil {
+		t.Error("Expected timeout error")
+	}
+}
+
+func TestIsSupportedContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"image/jpeg", true},
+		{"IMAGE/PNG", true},
+		{"application/pdf; charset=binary", true},
+		{"text/html", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSupportedContentType(tt.contentType); got != tt.want {
+			t.Errorf("IsSupportedContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestNewExtractor(t *testing.T) {
+	if _, err := NewExtractor("cloud_vision", "", "", time.Second); err == nil {
+		t.Error("cloud_vision without endpoint should fail")
+	}
+	if _, err := NewExtractor("unknown", "", "", time.Second); err == nil {
+		t.Error("Unknown provider should fail")
+	}
+	if extractor, err := NewExtractor("stub", "", "", time.Second); err != nil || extractor == nil {
+		t.Errorf("Stub provider should be available, got %v", err)
+	}
+}
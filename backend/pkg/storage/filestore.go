@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tgfinance/pkg/clock"
+)
+
+// DefaultFileBaseURL is where the filesystem store is served when no base
+// URL is configured
+const DefaultFileBaseURL = "/files"
+
+// FileStore is an ObjectStore on the local filesystem for development. Its
+// presigned links carry an HMAC of the key and expiry, checked by ServeHTTP,
+// which must be mounted at the base URL's path.
+type FileStore struct {
+	local    *LocalStore
+	baseURL  string
+	basePath string
+	secret   []byte
+	clock    clock.Clock
+}
+
+// NewFileStore creates a filesystem store under opts.Dir. Links are signed
+// with opts.SecretKey, or a random key if it is empty, in which case they
+// stop working on restart.
+func NewFileStore(opts Options) (*FileStore, error) {
+	local, err := NewLocalStore(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultFileBaseURL
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage base URL: %w", err)
+	}
+
+	secret := []byte(opts.SecretKey)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &FileStore{local: local, baseURL: baseURL, basePath: base.Path, secret: secret, clock: clock.Real()}, nil
+}
+
+// WithClock sets the clock used for link expiry
+func (s *FileStore) WithClock(c clock.Clock) *FileStore {
+	s.clock = c
+	return s
+}
+
+// Put writes r to key after checking its content type
+func (s *FileStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if _, err := ContentType(contentType); err != nil {
+		return err
+	}
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	return s.local.Put(ctx, key, r)
+}
+
+// PresignGet returns a link to ServeHTTP valid for ttl
+func (s *FileStore) PresignGet(key string, ttl time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(s.clock.Now().Add(ttl).Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {s.sign(key, expires)}}
+	return s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Delete removes the object at key
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := s.local.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP serves objects for links from PresignGet, answering 403 for
+// links that are expired or not signed by this store. The content type
+// follows the key's extension; anything else is sent as an attachment.
+func (s *FileStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, s.basePath+"/")
+	expires := r.URL.Query().Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if !ok || err != nil || checkKey(key) != nil ||
+		!hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.sign(key, expires))) {
+		http.Error(w, "Invalid link", http.StatusForbidden)
+		return
+	}
+	if !s.clock.Now().Before(time.Unix(unix, 0)) {
+		http.Error(w, "Link expired", http.StatusForbidden)
+		return
+	}
+
+	f, err := s.local.Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Failed to open file", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if contentType := contentTypeForKey(key); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.FormatInt(max(0, unix-s.clock.Now().Unix()), 10))
+	if r.Method == http.MethodGet {
+		io.Copy(w, f)
+	}
+}
+
+// sign returns the hex HMAC of a link to key expiring at expires
+func (s *FileStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
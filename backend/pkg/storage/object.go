@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnsupportedContentType is returned for uploads that are not images or
+// PDFs
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// receiptContentTypes are the content types accepted for receipts, with
+// the file extension their keys get
+var receiptContentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"image/heic":      ".heic",
+	"application/pdf": ".pdf",
+}
+
+// receiptsPrefix namespaces receipt keys, which continue with the owner's ID
+const receiptsPrefix = "receipts/"
+
+// ObjectStore stores uploaded files such as receipts and hands out
+// time-limited download links, so files are never served from the API's
+// own origin with the user's credentials
+type ObjectStore interface {
+	// Put writes the object; contentType must be an image or PDF
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// PresignGet returns a URL that downloads the object until ttl passes
+	PresignGet(key string, ttl time.Duration) (string, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Storage providers
+const (
+	ProviderS3         = "s3"
+	ProviderMinIO      = "minio"
+	ProviderFilesystem = "filesystem"
+)
+
+// MaxPresignTTL is the longest an S3 presigned URL may stay valid
+const MaxPresignTTL = 7 * 24 * time.Hour
+
+// Options configures an object store
+type Options struct {
+	// Provider is ProviderS3, ProviderMinIO or ProviderFilesystem
+	Provider string
+	Bucket   string
+	Region   string
+	// Endpoint is the S3 API URL, required for MinIO; empty uses AWS
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	// BaseURL prefixes the links handed to clients
+	BaseURL string
+	// Dir is the root directory of the filesystem provider
+	Dir string
+}
+
+// NewObjectStore creates the object store for the configured provider
+func NewObjectStore(opts Options) (ObjectStore, error) {
+	switch opts.Provider {
+	case ProviderS3, ProviderMinIO:
+		return NewS3Store(opts)
+	case ProviderFilesystem:
+		return NewFileStore(opts)
+	default:
+		return nil, fmt.Errorf("unknown storage provider %q", opts.Provider)
+	}
+}
+
+// ContentType returns the media type of contentType without parameters,
+// or ErrUnsupportedContentType unless it is an image or PDF accepted for
+// receipts
+func ContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+	if _, ok := receiptContentTypes[mediaType]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedContentType, mediaType)
+	}
+	return mediaType, nil
+}
+
+// ReceiptKey returns a new key for a receipt of the content type in the
+// user's namespace, such as "receipts/{user ID}/{random ID}.pdf"
+func ReceiptKey(userID uuid.UUID, contentType string) (string, error) {
+	mediaType, err := ContentType(contentType)
+	if err != nil {
+		return "", err
+	}
+	return receiptsPrefix + userID.String() + "/" + uuid.NewString() + receiptContentTypes[mediaType], nil
+}
+
+// OwnsKey reports whether key is in the user's receipt namespace, to check
+// client-supplied keys before presigning or deleting them
+func OwnsKey(userID uuid.UUID, key string) bool {
+	rest, ok := strings.CutPrefix(key, receiptsPrefix+userID.String()+"/")
+	return ok && checkKey(rest) == nil
+}
+
+// contentTypeForKey returns the content type implied by the key's
+// extension, or "" if it is not one receipts use
+func contentTypeForKey(key string) string {
+	for contentType, ext := range receiptContentTypes {
+		if strings.HasSuffix(key, ext) {
+			return contentType
+		}
+	}
+	return ""
+}
+
+// checkKey rejects keys that are empty, absolute or have empty, "." or ".."
+// segments, so they mean the same to every backend
+func checkKey(key string) error {
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("invalid storage key: %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid storage key: %q", key)
+		}
+	}
+	return nil
+}
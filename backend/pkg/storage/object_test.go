@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tgfinance/pkg/clock"
+)
+
+// testObjectStore is the contract every ObjectStore must meet. Links are
+// fetched over HTTP; clk drives their expiry.
+func testObjectStore(t *testing.T, store ObjectStore, clk *clock.Fake) {
+	ctx := context.Background()
+	userID := uuid.New()
+	fetch := func(link string) (int, string, string) {
+		t.Helper()
+		resp, err := http.Get(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(body)
+	}
+
+	key, err := ReceiptKey(userID, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, key, strings.NewReader("first"), "image/png"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put(ctx, key, strings.NewReader("\x89PNG receipt"), "image/png"); err != nil {
+		t.Fatalf("Put() overwrite error = %v", err)
+	}
+
+	link, err := store.PresignGet(key, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignGet() error = %v", err)
+	}
+	if status, contentType, body := fetch(link); status != http.StatusOK || contentType != "image/png" || body != "\x89PNG receipt" {
+		t.Errorf("GET link = %d %s %q, want 200 image/png with the latest contents", status, contentType, body)
+	}
+
+	// A link only works for its own key
+	otherKey, _ := ReceiptKey(userID, "application/pdf")
+	if err := store.Put(ctx, otherKey, strings.NewReader("%PDF-1.7"), "application/pdf; charset=binary"); err != nil {
+		t.Fatalf("Put() PDF error = %v", err)
+	}
+	if status, _, _ := fetch(strings.Replace(link, key, otherKey, 1)); status != http.StatusForbidden {
+		t.Errorf("Expected a link edited to another key to be refused, got %d", status)
+	}
+
+	clk.Advance(time.Minute)
+	if status, _, _ := fetch(link); status != http.StatusForbidden {
+		t.Errorf("Expected an expired link to be refused, got %d", status)
+	}
+
+	for _, contentType := range []string{"text/html", "image/svg+xml", "application/octet-stream", ""} {
+		if err := store.Put(ctx, key, strings.NewReader("<script>"), contentType); !errors.Is(err, ErrUnsupportedContentType) {
+			t.Errorf("Put(%q) error = %v, want ErrUnsupportedContentType", contentType, err)
+		}
+	}
+	for _, bad := range []string{"", "../escape", "/etc/passwd", "receipts//a.png", "receipts/./a.png"} {
+		if err := store.Put(ctx, bad, strings.NewReader("x"), "image/png"); err == nil {
+			t.Errorf("Expected key %q to be rejected", bad)
+		}
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	link, _ = store.PresignGet(key, time.Minute)
+	if status, _, _ := fetch(link); status != http.StatusNotFound {
+		t.Errorf("Expected a deleted object to be gone, got %d", status)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Expected deleting a missing object to succeed, got %v", err)
+	}
+}
+
+func TestFileStoreContract(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	var store *FileStore
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	var err error
+	store, err = NewFileStore(Options{Dir: t.TempDir(), BaseURL: server.URL + "/files/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testObjectStore(t, store.WithClock(clk), clk)
+}
+
+func TestS3StoreContract(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	testObjectStore(t, newFakeS3Store(t, clk).WithClock(clk), clk)
+}
+
+func TestFileStoreServe(t *testing.T) {
+	store, err := NewFileStore(Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "exports/report", strings.NewReader("<html>"), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	link, err := store.PresignGet("exports/report", time.Minute)
+	if err != nil || !strings.HasPrefix(link, DefaultFileBaseURL+"/exports/report?") {
+		t.Fatalf("PresignGet() = %s, %v", link, err)
+	}
+
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Disposition") != "attachment" ||
+		rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Expected a key without a known extension to download as an attachment, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, link, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected DELETE to be refused, got %d", rec.Code)
+	}
+
+	// Links from another store, such as before a restart, are refused
+	other, _ := NewFileStore(Options{Dir: t.TempDir()})
+	rec = httptest.NewRecorder()
+	other.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a link signed by another store to be refused, got %d", rec.Code)
+	}
+}
+
+func TestReceiptKeys(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	key, err := ReceiptKey(userID, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "receipts/"+userID.String()+"/") || !strings.HasSuffix(key, ".jpg") {
+		t.Errorf("ReceiptKey() = %s", key)
+	}
+	if again, _ := ReceiptKey(userID, "image/jpeg"); again == key {
+		t.Error("Expected a new key for every receipt")
+	}
+	if _, err := ReceiptKey(userID, "text/html"); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Errorf("Expected ErrUnsupportedContentType, got %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key, true},
+		{"receipts/" + otherID.String() + "/a.jpg", false},
+		{"receipts/" + userID.String() + "/../" + otherID.String() + "/a.jpg", false},
+		{"receipts/" + userID.String() + "/", false},
+		{"receipts/" + userID.String(), false},
+		{"backups/" + userID.String() + "/a.jpg", false},
+	}
+	for _, tt := range tests {
+		if got := OwnsKey(userID, tt.key); got != tt.want {
+			t.Errorf("OwnsKey(%s) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	for input, want := range map[string]string{
+		"image/png":                 "image/png",
+		"IMAGE/JPEG":                "image/jpeg",
+		"application/pdf; name=a":   "application/pdf",
+		"image/heic":                "image/heic",
+		"image/webp":                "image/webp",
+		"text/html; charset=utf-8":  "",
+		"image/svg+xml":             "",
+		"application/x-msdownload":  "",
+		"not a content type at all": "",
+	} {
+		got, err := ContentType(input)
+		if got != want || (want == "") != errors.Is(err, ErrUnsupportedContentType) {
+			t.Errorf("ContentType(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestNewObjectStore(t *testing.T) {
+	store, err := NewObjectStore(Options{Provider: ProviderFilesystem, Dir: t.TempDir()})
+	if _, ok := store.(*FileStore); !ok || err != nil {
+		t.Errorf("Expected a FileStore, got %T, %v", store, err)
+	}
+	store, err = NewObjectStore(Options{Provider: ProviderMinIO, Bucket: "b", Endpoint: "http://minio:9000", AccessKey: "a", SecretKey: "s"})
+	if _, ok := store.(*S3Store); !ok || err != nil {
+		t.Errorf("Expected an S3Store, got %T, %v", store, err)
+	}
+	if _, err := NewObjectStore(Options{Provider: "gcs"}); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
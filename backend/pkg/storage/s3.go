@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tgfinance/pkg/clock"
+)
+
+// S3 request signing constants
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3DateFormat      = "20060102T150405Z"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Store is an ObjectStore on Amazon S3 or an S3-compatible service such
+// as MinIO. Requests are signed with AWS Signature Version 4. With an
+// endpoint, buckets are addressed by path as MinIO expects; without one,
+// the AWS virtual-hosted endpoint for the region is used.
+type S3Store struct {
+	// api is the URL requests go to and public the one links use; both
+	// include the bucket when it is addressed by path
+	api       string
+	public    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	clock     clock.Clock
+}
+
+// NewS3Store creates a store for opts' bucket
+func NewS3Store(opts Options) (*S3Store, error) {
+	if opts.Bucket == "" || opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, fmt.Errorf("s3 storage needs a bucket, access key and secret key")
+	}
+
+	api := "https://" + opts.Bucket + ".s3." + opts.Region + ".amazonaws.com"
+	if opts.Endpoint != "" {
+		endpoint, err := url.Parse(opts.Endpoint)
+		if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid storage endpoint %q", opts.Endpoint)
+		}
+		api = strings.TrimSuffix(opts.Endpoint, "/") + "/" + s3Escape(opts.Bucket, false)
+	}
+	public := api
+	if opts.BaseURL != "" {
+		public = strings.TrimSuffix(opts.BaseURL, "/")
+		if opts.Endpoint != "" {
+			public += "/" + s3Escape(opts.Bucket, false)
+		}
+	}
+
+	return &S3Store{
+		api:       api,
+		public:    public,
+		region:    opts.Region,
+		accessKey: opts.AccessKey,
+		secretKey: opts.SecretKey,
+		client:    &http.Client{},
+		clock:     clock.Real(),
+	}, nil
+}
+
+// WithClock sets the clock used to date signatures
+func (s *S3Store) WithClock(c clock.Clock) *S3Store {
+	s.clock = c
+	return s
+}
+
+// Put uploads r to key. The body is read into memory to sign its hash, so
+// callers bound its size, as SERVER_MAX_UPLOAD_BYTES does for uploads.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	contentType, err := ContentType(contentType)
+	if err != nil {
+		return err
+	}
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.api+"/"+s3Escape(key, false), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return s.do(req, key, sha256Hex(body))
+}
+
+// PresignGet returns a presigned GET URL for key valid for ttl, at most
+// seven days
+func (s *S3Store) PresignGet(key string, ttl time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > MaxPresignTTL {
+		return "", fmt.Errorf("presign TTL must be positive and at most %s, got %s", MaxPresignTTL, ttl)
+	}
+	u, err := url.Parse(s.public + "/" + s3Escape(key, false))
+	if err != nil {
+		return "", err
+	}
+
+	now := s.clock.Now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {s.accessKey + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format(s3DateFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(ttl.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	canonical := s3CanonicalRequest(http.MethodGet, u.EscapedPath(), query, u.Host, http.Header{}, s3UnsignedPayload)
+	return s.public + "/" + s3Escape(key, false) + "?" + s3CanonicalQuery(query) + "&X-Amz-Signature=" + s.signature(now, canonical), nil
+}
+
+// Delete removes the object at key
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.api+"/"+s3Escape(key, false), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, key, sha256Hex(nil))
+}
+
+// do signs and sends req, turning error responses into errors
+func (s *S3Store) do(req *http.Request, key, payloadHash string) error {
+	s.sign(req, payloadHash, s.clock.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 %s %s: %w", req.Method, key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if xml.Unmarshal(data, &s3Err) != nil || s3Err.Code == "" {
+		return fmt.Errorf("s3 %s %s: %s", req.Method, key, resp.Status)
+	}
+	return fmt.Errorf("s3 %s %s: %s: %s: %s", req.Method, key, resp.Status, s3Err.Code, s3Err.Message)
+}
+
+// sign adds the date, payload hash and Authorization headers to req,
+// signing every header already set on it along with the host
+func (s *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonical := s3CanonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.Query(), req.URL.Host, req.Header, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, s.scope(now), strings.Join(s3HeaderNames(req.Header), ";"), s.signature(now, canonical)))
+}
+
+// scope is the credential scope of signatures made at t
+func (s *S3Store) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request made at t
+func (s *S3Store) signature(t time.Time, canonicalRequest string) string {
+	stringToSign := s3Algorithm + "\n" + t.Format(s3DateFormat) + "\n" + s.scope(t) + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format("20060102"))
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// s3CanonicalRequest builds the canonical form of a request that
+// Signature Version 4 signs; headers are signed along with the host
+func s3CanonicalRequest(method, escapedPath string, query url.Values, host string, headers http.Header, payloadHash string) string {
+	names := s3HeaderNames(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := host
+		if name != "host" {
+			values := headers.Values(name)
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.Join(strings.Fields(v), " ")
+			}
+			value = strings.Join(trimmed, ",")
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+
+	if escapedPath == "" {
+		escapedPath = "/"
+	}
+	return strings.Join([]string{
+		method,
+		escapedPath,
+		s3CanonicalQuery(query),
+		canonicalHeaders.String(),
+		strings.Join(names, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// s3HeaderNames returns the signed header names: the host and every header
+// but Authorization, lowercase and sorted
+func s3HeaderNames(headers http.Header) []string {
+	names := []string{"host"}
+	for name := range headers {
+		if lower := strings.ToLower(name); lower != "authorization" {
+			names = append(names, lower)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// s3CanonicalQuery encodes query sorted by name with S3's escaping
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, s3Escape(name, true)+"="+s3Escape(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes every byte but the RFC 3986 unreserved
+// characters and, unless escapeSlash is set, slashes
+func s3Escape(value string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
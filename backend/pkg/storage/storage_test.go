@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, body := range map[string]string{"backups/b.tar.gz": "second", "backups/a.tar.gz": "first", "other/c": "x"} {
+		if err := store.Put(ctx, key, strings.NewReader(body)); err != nil {
+			t.Fatalf("Put(%s) failed: %v", key, err)
+		}
+	}
+
+	objects, err := store.List(ctx, "backups/")
+	if err != nil || len(objects) != 2 || objects[0].Key != "backups/a.tar.gz" || objects[0].Size != 5 {
+		t.Fatalf("Unexpected listing %+v, %v", objects, err)
+	}
+
+	r, err := store.Open(ctx, "backups/b.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "second" {
+		t.Errorf("Expected stored contents, got %q", data)
+	}
+
+	if err := store.Delete(ctx, "backups/a.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(ctx, "backups/a.tar.gz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "/etc/passwd"} {
+		if err := store.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
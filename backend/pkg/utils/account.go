@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Account number length bounds; 34 is the longest IBAN
+const (
+	MinAccountNumberLength = 6
+	MaxAccountNumberLength = 34
+)
+
+// accountNumberMask replaces the hidden characters of a masked account number
+const accountNumberMask = "****"
+
+// ibanLengths are the IBAN lengths of the countries using IBANs
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22,
+	"BH": 22, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24, "FI": 18, "FO": 18, "FR": 27,
+	"GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28,
+	"IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20,
+	"LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24,
+	"ME": 22, "MK": 19, "MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24,
+	"PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "SA": 24, "SC": 31,
+	"SE": 24, "SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28, "TL": 23, "TN": 24,
+	"TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+}
+
+// NormalizeAccountNumber removes spaces and dashes from an account number
+// and upper cases it, so "gb82 west 1234" becomes "GB82WEST1234"
+func NormalizeAccountNumber(value string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\t' {
+			return -1
+		}
+		return r
+	}, value))
+}
+
+// ValidateAccountNumber normalizes an account number with
+// NormalizeAccountNumber and validates that it has 6 to 34 letters and
+// digits. Values that look like an IBAN, with a country code and that
+// country's IBAN length, must also pass the IBAN mod-97 check.
+func ValidateAccountNumber(value, fieldName string) (string, error) {
+	normalized := NormalizeAccountNumber(value)
+	if normalized == "" {
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s is required", fieldName)}
+	}
+	for _, r := range normalized {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'Z') {
+			return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s can only contain letters and digits", fieldName)}
+		}
+	}
+	if len(normalized) < MinAccountNumberLength || len(normalized) > MaxAccountNumberLength {
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be between %d and %d characters long", fieldName, MinAccountNumberLength, MaxAccountNumberLength)}
+	}
+	if LooksLikeIBAN(normalized) && !ValidIBAN(normalized) {
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s is not a valid IBAN", fieldName)}
+	}
+	return normalized, nil
+}
+
+// LooksLikeIBAN returns true if value, normalized, starts with a country
+// code using IBANs and two check digits and has that country's IBAN length
+func LooksLikeIBAN(value string) bool {
+	if len(value) < 4 {
+		return false
+	}
+	length, ok := ibanLengths[value[:2]]
+	return ok && len(value) == length && isDigit(value[2]) && isDigit(value[3])
+}
+
+// ValidIBAN returns true if value, normalized, passes the ISO 13616 mod-97
+// check: moved to the end, its country code and check digits make the whole
+// number, with letters as 10 to 35, leave a remainder of 1 when divided by 97
+func ValidIBAN(value string) bool {
+	if len(value) < 5 {
+		return false
+	}
+	remainder := 0
+	for _, c := range value[4:] + value[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A'+10)) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// MaskAccountNumber hides all but the last characters of an account number
+// for responses and logs, such as "****1234". At most 4 characters and
+// never more than half of the number are shown, so short values are masked
+// entirely.
+func MaskAccountNumber(value string) string {
+	runes := []rune(NormalizeAccountNumber(value))
+	if len(runes) == 0 {
+		return ""
+	}
+	visible := 0
+	if len(runes) > 4 {
+		visible = min(4, len(runes)/2)
+	}
+	return accountNumberMask + string(runes[len(runes)-visible:])
+}
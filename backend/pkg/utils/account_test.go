@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAccountNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"digits", "12345678", "12345678", false},
+		{"letters and digits", "brk-0042-77x", "BRK004277X", false},
+		{"spaces removed", " 1234 5678 90 ", "1234567890", false},
+		{"at min length", "123456", "123456", false},
+		{"below min length", "12345", "", true},
+		{"at max length", "ACCT567890123456789012345678901234", "ACCT567890123456789012345678901234", false},
+		{"above max length", "ACCT5678901234567890123456789012345", "", true},
+		{"punctuation", "1234.5678", "", true},
+		{"non-ASCII", "12345678é", "", true},
+		{"empty", " - ", "", true},
+		{"valid UK IBAN", "GB82 WEST 1234 5698 7654 32", "GB82WEST12345698765432", false},
+		{"valid German IBAN", "de89370400440532013000", "DE89370400440532013000", false},
+		{"valid Norwegian IBAN", "NO93 8601 1117 947", "NO9386011117947", false},
+		{"IBAN with bad checksum", "GB82 WEST 1234 5698 7654 33", "", true},
+		{"IBAN with swapped digits", "DE89370400440532031000", "", true},
+		{"IBAN-like of another length is not checked", "GB82WEST123456", "GB82WEST123456", false},
+		{"unknown country is not checked", "ZZ82WEST12345698765432", "ZZ82WEST12345698765432", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateAccountNumber(tt.value, "account_number")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAccountNumber(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateAccountNumber(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "account_number") {
+				t.Errorf("Expected an account_number validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		iban string
+		want bool
+	}{
+		{"GB82WEST12345698765432", true},
+		{"FR1420041010050500013M02606", true},
+		{"GB82WEST12345698765433", false},
+		{"GB28WEST12345698765432", false},
+		{"GB82", false},
+		{"GB82WEST1234569876543!", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidIBAN(tt.iban); got != tt.want {
+			t.Errorf("ValidIBAN(%q) = %v, want %v", tt.iban, got, tt.want)
+		}
+	}
+}
+
+func TestMaskAccountNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"12345678", "****5678"},
+		{"GB82 WEST 1234 5698 7654 32", "****5432"},
+		{"123456", "****456"},
+		{"12345", "****45"},
+		{"1234", "****"},
+		{"12", "****"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := MaskAccountNumber(tt.value); got != tt.want {
+			t.Errorf("MaskAccountNumber(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
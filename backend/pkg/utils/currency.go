@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CurrencyOption changes which codes ValidateCurrency accepts
+type CurrencyOption int
+
+const (
+	// AllowHistorical accepts codes withdrawn from ISO 4217, such as DEM
+	AllowHistorical CurrencyOption = iota + 1
+)
+
+// NormalizeCurrency returns code trimmed and upper-cased
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidateCurrency validates an ISO 4217 currency code in either case and
+// returns it upper-cased. Withdrawn codes are rejected unless
+// AllowHistorical is passed.
+func ValidateCurrency(code, fieldName string, opts ...CurrencyOption) (string, error) {
+	if err := ValidateRequired(code, fieldName); err != nil {
+		return "", err
+	}
+
+	normalized := NormalizeCurrency(code)
+	if _, ok := activeCurrencies[normalized]; ok {
+		return normalized, nil
+	}
+	if _, ok := historicalCurrencies[normalized]; ok {
+		for _, opt := range opts {
+			if opt == AllowHistorical {
+				return normalized, nil
+			}
+		}
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s %s is no longer an active currency", fieldName, normalized)}
+	}
+	return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be a valid ISO 4217 currency code", fieldName)}
+}
+
+// CurrencyMinorUnits returns the number of decimal places of a currency,
+// such as 0 for JPY, 2 for USD and 3 for KWD. ok is false for unknown codes.
+func CurrencyMinorUnits(code string) (units int, ok bool) {
+	normalized := NormalizeCurrency(code)
+	if units, ok := activeCurrencies[normalized]; ok {
+		return units, true
+	}
+	units, ok = historicalCurrencies[normalized]
+	return units, ok
+}
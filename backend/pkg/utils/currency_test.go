@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		opts    []CurrencyOption
+		want    string
+		wantErr bool
+	}{
+		{"USD", "USD", nil, "USD", false},
+		{"lower case", "jpy", nil, "JPY", false},
+		{"mixed case with spaces", " KwD ", nil, "KWD", false},
+		{"euro", "eur", nil, "EUR", false},
+		{"retired code", "DEM", nil, "", true},
+		{"retired code lower case", "dem", nil, "", true},
+		{"retired code allowed", "dem", []CurrencyOption{AllowHistorical}, "DEM", false},
+		{"active code with historical allowed", "USD", []CurrencyOption{AllowHistorical}, "USD", false},
+		{"empty", "", nil, "", true},
+		{"whitespace", "   ", nil, "", true},
+		{"unknown code", "ABC", nil, "", true},
+		{"unknown code with historical allowed", "ZZZ", []CurrencyOption{AllowHistorical}, "", true},
+		{"too long", "USDX", nil, "", true},
+		{"too short", "US", nil, "", true},
+		{"digits", "840", nil, "", true},
+		{"symbol", "$", nil, "", true},
+		{"precious metal", "XAU", nil, "", true},
+		{"garbage", "u$d\x00", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateCurrency(tt.code, "currency", tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateCurrency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateCurrency() = %q, want %q", got, tt.want)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "currency") {
+				t.Errorf("Expected a currency validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateCurrencyMessages(t *testing.T) {
+	_, err := ValidateCurrency("DEM", "currency")
+	if err == nil || err.Error() != "currency: currency DEM is no longer an active currency" {
+		t.Errorf("Unexpected retired code error %v", err)
+	}
+	_, err = ValidateCurrency("ABC", "currency")
+	if err == nil || err.Error() != "currency: currency must be a valid ISO 4217 currency code" {
+		t.Errorf("Unexpected unknown code error %v", err)
+	}
+}
+
+func TestCurrencyMinorUnits(t *testing.T) {
+	tests := []struct {
+		code   string
+		units  int
+		wantOK bool
+	}{
+		{"JPY", 0, true},
+		{"jpy", 0, true},
+		{"USD", 2, true},
+		{"KWD", 3, true},
+		{"BHD", 3, true},
+		{"CLF", 4, true},
+		{"DEM", 2, true},
+		{"ITL", 0, true},
+		{"ABC", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			units, ok := CurrencyMinorUnits(tt.code)
+			if units != tt.units || ok != tt.wantOK {
+				t.Errorf("CurrencyMinorUnits(%q) = %d, %v, want %d, %v", tt.code, units, ok, tt.units, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCurrencyListsDisjoint(t *testing.T) {
+	for code := range historicalCurrencies {
+		if _, ok := activeCurrencies[code]; ok {
+			t.Errorf("%s is both active and historical", code)
+		}
+	}
+	for code, units := range activeCurrencies {
+		if len(code) != 3 || NormalizeCurrency(code) != code || units < 0 || units > 4 {
+			t.Errorf("Invalid active currency %q with %d minor units", code, units)
+		}
+	}
+}
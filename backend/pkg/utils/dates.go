@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"tgfinance/pkg/clock"
+)
+
+// maxAgeYears bounds dates of birth to catch typos such as 0199 for 1990
+const maxAgeYears = 150
+
+// Time zones span UTC-12 to UTC+14, so the current date somewhere on Earth
+// lies between today in these zones
+var (
+	earliestZone = time.FixedZone("UTC-12", -12*60*60)
+	latestZone   = time.FixedZone("UTC+14", 14*60*60)
+)
+
+// dateClock tells the date validators the current time
+var dateClock atomic.Value
+
+func init() {
+	dateClock.Store(clockHolder{clock.Real()})
+}
+
+// clockHolder lets clocks of different types share the atomic.Value
+type clockHolder struct {
+	clock.Clock
+}
+
+// SetClock sets the clock the date validators compare against, such as a
+// fake clock in tests or demo mode
+func SetClock(c clock.Clock) {
+	dateClock.Store(clockHolder{c})
+}
+
+// now returns the current time of the date validators' clock
+func now() time.Time {
+	return dateClock.Load().(clockHolder).Now()
+}
+
+// calendarDate returns the date of t in its own location as midnight UTC, so
+// dates compare by calendar day regardless of zone or DST
+func calendarDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// ValidateDateRange validates that end is not before start. Start and end on
+// the same calendar day of start's location are allowed in either order, and
+// a zero start or end leaves the range open.
+func ValidateDateRange(start, end time.Time, startField, endField string) error {
+	if start.IsZero() || end.IsZero() || !end.Before(start) {
+		return nil
+	}
+	if calendarDate(end.In(start.Location())).Equal(calendarDate(start)) {
+		return nil
+	}
+	return &ValidationError{Field: endField, Message: fmt.Sprintf("%s must not be before %s", endField, startField)}
+}
+
+// ValidateNotFuture validates that date, such as an expense date, is not
+// after today. A date is only in the future once it has not yet begun
+// anywhere, so users ahead of UTC can enter their own today.
+func ValidateNotFuture(date time.Time, fieldName string) error {
+	if calendarDate(date).After(calendarDate(now().In(latestZone))) {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must not be in the future", fieldName)}
+	}
+	return nil
+}
+
+// ValidateFuture validates that date, such as a goal target date, is after
+// today. Today is taken in the zone furthest behind UTC, so a date that has
+// already begun for the user but not everywhere is still accepted.
+func ValidateFuture(date time.Time, fieldName string) error {
+	if !calendarDate(date).After(calendarDate(now().In(earliestZone))) {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be in the future", fieldName)}
+	}
+	return nil
+}
+
+// ValidateDateOfBirth validates that dob is a plausible date of birth of
+// someone at least minAgeYears old. Birthdays on February 29 fall on March 1
+// in other years.
+func ValidateDateOfBirth(dob time.Time, minAgeYears int) error {
+	const fieldName = "date_of_birth"
+	if dob.IsZero() {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s is required", fieldName)}
+	}
+	if err := ValidateNotFuture(dob, fieldName); err != nil {
+		return err
+	}
+
+	age := AgeOn(dob, now().In(dob.Location()))
+	if age < minAgeYears {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("you must be at least %d years old", minAgeYears)}
+	}
+	if age > maxAgeYears {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be within the last %d years", fieldName, maxAgeYears)}
+	}
+	return nil
+}
+
+// AgeOn returns the age in whole years on the calendar date of at of
+// someone born on the calendar date of dob
+func AgeOn(dob, at time.Time) int {
+	birthYear, birthMonth, birthDay := dob.Date()
+	year, month, day := at.Date()
+
+	age := year - birthYear
+	if month < birthMonth || month == birthMonth && day < birthDay {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"tgfinance/pkg/clock"
+)
+
+// useClock sets the date validators' clock to a fake at now for the test
+func useClock(t *testing.T, now time.Time) *clock.Fake {
+	fake := clock.NewFake(now)
+	SetClock(fake)
+	t.Cleanup(func() { SetClock(clock.Real()) })
+	return fake
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", name, err)
+	}
+	return loc
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestValidateDateRange(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name    string
+		start   time.Time
+		end     time.Time
+		wantErr bool
+	}{
+		{"end after start", day(2026, time.January, 1), day(2026, time.December, 31), false},
+		{"same day", day(2026, time.March, 8), day(2026, time.March, 8), false},
+		{"same day end earlier in the day", time.Date(2026, time.March, 8, 18, 0, 0, 0, time.UTC), time.Date(2026, time.March, 8, 9, 0, 0, 0, time.UTC), false},
+		{"end before start", day(2026, time.March, 9), day(2026, time.March, 8), true},
+		{"open start", time.Time{}, day(2026, time.March, 8), false},
+		{"open end", day(2026, time.March, 8), time.Time{}, false},
+		// Clocks skip 02:00-03:00 on March 8, 2026 in New York, so the day is 23 hours long
+		{"across spring forward", time.Date(2026, time.March, 7, 23, 0, 0, 0, newYork), time.Date(2026, time.March, 8, 3, 30, 0, 0, newYork), false},
+		{"same day as spring forward", time.Date(2026, time.March, 8, 23, 30, 0, 0, newYork), time.Date(2026, time.March, 8, 0, 30, 0, 0, newYork), false},
+		{"day before spring forward", time.Date(2026, time.March, 8, 0, 0, 0, 0, newYork), time.Date(2026, time.March, 7, 23, 59, 0, 0, newYork), true},
+		// Clocks repeat 01:00-02:00 on November 1, 2026, so the day is 25 hours long
+		{"repeated hour in order", time.Date(2026, time.November, 1, 1, 30, 0, 0, newYork), time.Date(2026, time.November, 1, 1, 30, 0, 0, newYork).Add(time.Hour), false},
+		{"same day as fall back", time.Date(2026, time.November, 1, 23, 59, 0, 0, newYork), time.Date(2026, time.November, 1, 0, 0, 0, 0, newYork), false},
+		{"day before fall back", time.Date(2026, time.November, 1, 0, 0, 0, 0, newYork), time.Date(2026, time.October, 31, 23, 0, 0, 0, newYork), true},
+		{"end in another zone on the same day", time.Date(2026, time.March, 8, 22, 0, 0, 0, newYork), time.Date(2026, time.March, 9, 1, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDateRange(tt.start, tt.end, "start_date", "end_date")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDateRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "end_date" || validationErr.Message != "end_date must not be before start_date") {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateNotFuture(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	// 22:00 UTC on March 7 is already March 8 in UTC+14
+	useClock(t, time.Date(2026, time.March, 7, 22, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name    string
+		date    time.Time
+		wantErr bool
+	}{
+		{"past", day(2025, time.December, 31), false},
+		{"today", day(2026, time.March, 7), false},
+		{"today ahead of UTC", day(2026, time.March, 8), false},
+		{"tomorrow everywhere", day(2026, time.March, 9), true},
+		{"local date on DST change", time.Date(2026, time.March, 8, 3, 0, 0, 0, newYork), false},
+		{"next year", day(2027, time.March, 7), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNotFuture(tt.date, "expense_date")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNotFuture() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFuture(t *testing.T) {
+	// 08:00 UTC on March 8 is still March 7 in UTC-12
+	fake := useClock(t, time.Date(2026, time.March, 8, 8, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name    string
+		date    time.Time
+		wantErr bool
+	}{
+		{"next year", day(2027, time.January, 1), false},
+		{"tomorrow", day(2026, time.March, 9), false},
+		{"today in UTC but not everywhere", day(2026, time.March, 8), false},
+		{"yesterday in UTC", day(2026, time.March, 7), true},
+		{"past", day(2020, time.March, 8), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFuture(tt.date, "target_date")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFuture() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Once the day has begun everywhere it is no longer in the future
+	fake.Advance(6 * time.Hour)
+	if err := ValidateFuture(day(2026, time.March, 8), "target_date"); err == nil {
+		t.Error("Expected today to be rejected once it has begun everywhere")
+	}
+}
+
+func TestValidateDateOfBirth(t *testing.T) {
+	tests := []struct {
+		name    string
+		now     time.Time
+		dob     time.Time
+		wantErr bool
+	}{
+		{"adult", day(2026, time.October, 16), day(1990, time.May, 4), false},
+		{"eighteenth birthday", day(2026, time.October, 16), day(2008, time.October, 16), false},
+		{"day before eighteenth birthday", day(2026, time.October, 15), day(2008, time.October, 16), true},
+		{"leap day birthday on February 28 of a common year", day(2026, time.February, 28), day(2008, time.February, 29), true},
+		{"leap day birthday on March 1 of a common year", day(2026, time.March, 1), day(2008, time.February, 29), false},
+		{"future", day(2026, time.October, 16), day(2027, time.January, 1), true},
+		{"zero", day(2026, time.October, 16), time.Time{}, true},
+		{"implausibly old", day(2026, time.October, 16), day(1801, time.January, 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useClock(t, tt.now.Add(12*time.Hour))
+			err := ValidateDateOfBirth(tt.dob, 18)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDateOfBirth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "date_of_birth") {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestAgeOn(t *testing.T) {
+	tests := []struct {
+		dob  time.Time
+		at   time.Time
+		want int
+	}{
+		{day(2000, time.February, 29), day(2001, time.February, 28), 0},
+		{day(2000, time.February, 29), day(2001, time.March, 1), 1},
+		{day(2000, time.February, 29), day(2004, time.February, 28), 3},
+		{day(2000, time.February, 29), day(2004, time.February, 29), 4},
+		{day(2000, time.December, 31), day(2001, time.January, 1), 0},
+		{day(2000, time.January, 1), day(2000, time.January, 1), 0},
+	}
+	for _, tt := range tests {
+		if got := AgeOn(tt.dob, tt.at); got != tt.want {
+			t.Errorf("AgeOn(%s, %s) = %d, want %d", tt.dob.Format(time.DateOnly), tt.at.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
+
+func TestValidationErrorsAddError(t *testing.T) {
+	useClock(t, day(2026, time.March, 8))
+
+	var errs ValidationErrors
+	errs.AddError(ValidateDateRange(day(2026, time.March, 9), day(2026, time.March, 1), "start_date", "end_date"))
+	errs.AddError(ValidateNotFuture(day(2026, time.March, 1), "expense_date"))
+	errs.AddError(ValidateFuture(day(2026, time.March, 1), "target_date"))
+	errs.AddError(ValidationErrors{{Field: "amount", Message: "amount is required"}})
+	errs.AddError(ValidationError{Field: "name", Message: "name is required"})
+	errs.AddError(errors.New("lookup failed"))
+
+	want := []string{"end_date", "target_date", "amount", "name", ""}
+	if len(errs) != len(want) {
+		t.Fatalf("errors = %v", errs)
+	}
+	for i, field := range want {
+		if errs[i].Field != field {
+			t.Errorf("errs[%d].Field = %q, want %q", i, errs[i].Field, field)
+		}
+	}
+}
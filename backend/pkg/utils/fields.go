@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldsParam is the query parameter selecting response fields
+const FieldsParam = "fields"
+
+// maxFieldDepth bounds how deep dot paths may reach into nested relations
+const maxFieldDepth = 4
+
+// Projection selects a subset of a response's fields. Fields are named by
+// their JSON keys; nested objects, arrays of objects and relations are
+// addressed with dot paths such as category.name.
+type Projection struct {
+	fields map[string]*Projection
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	fieldTreeCache    sync.Map
+)
+
+// fieldTree is the set of JSON paths a type can serialize
+type fieldTree map[string]fieldTree
+
+// ParseFields parses a comma-separated fields parameter for responses of
+// model's type. An empty parameter returns a nil projection that keeps every
+// field. Unknown field names, including fields never serialized such as
+// json:"-", are reported as validation errors.
+func ParseFields(raw string, model interface{}) (*Projection, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	tree := fieldsOf(reflect.TypeOf(model))
+	projection := &Projection{fields: make(map[string]*Projection)}
+	var errs ValidationErrors
+
+	for _, path := range strings.Split(raw, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !projection.add(strings.Split(path, "."), tree) {
+			errs.Add(FieldsParam, fmt.Sprintf("unknown field: %s", path))
+		}
+	}
+	if errs.HasErrors() {
+		return nil, errs
+	}
+	return projection, nil
+}
+
+// add adds a path to the projection if the tree allows it
+func (p *Projection) add(parts []string, tree fieldTree) bool {
+	sub, ok := tree[parts[0]]
+	if !ok {
+		return false
+	}
+	child, exists := p.fields[parts[0]]
+	if len(parts) == 1 {
+		// Selecting a whole object supersedes selecting some of its fields
+		p.fields[parts[0]] = nil
+		return true
+	}
+	if len(sub) == 0 {
+		return false
+	}
+	if exists && child == nil {
+		return sub.allows(parts[1:])
+	}
+	if child == nil {
+		child = &Projection{fields: make(map[string]*Projection)}
+		p.fields[parts[0]] = child
+	}
+	return child.add(parts[1:], sub)
+}
+
+// allows returns true if the tree contains the path
+func (t fieldTree) allows(parts []string) bool {
+	sub, ok := t[parts[0]]
+	if !ok {
+		return false
+	}
+	if len(parts) == 1 {
+		return true
+	}
+	return sub.allows(parts[1:])
+}
+
+// Fields returns the selected top-level field names in order
+func (p *Projection) Fields() []string {
+	if p == nil {
+		return nil
+	}
+	names := make([]string, 0, len(p.fields))
+	for name := range p.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply returns v reduced to the selected fields. Slices are projected
+// element by element. A nil projection returns v unchanged.
+func (p *Projection) Apply(v interface{}) (interface{}, error) {
+	if p == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return p.project(decoded), nil
+}
+
+// project filters a decoded JSON value
+func (p *Projection) project(v interface{}) interface{} {
+	if p == nil {
+		return v
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(p.fields))
+		for name, child := range p.fields {
+			if field, ok := value[name]; ok {
+				out[name] = child.project(field)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = p.project(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// fieldsOf returns the JSON field tree of a type, cached per type
+func fieldsOf(t reflect.Type) fieldTree {
+	if t == nil {
+		return fieldTree{}
+	}
+	if cached, ok := fieldTreeCache.Load(t); ok {
+		return cached.(fieldTree)
+	}
+	tree := buildFieldTree(t, map[reflect.Type]bool{}, 0)
+	if tree == nil {
+		tree = fieldTree{}
+	}
+	fieldTreeCache.Store(t, tree)
+	return tree
+}
+
+// buildFieldTree walks the exported, serialized fields of a struct type
+func buildFieldTree(t reflect.Type, visiting map[reflect.Type]bool, depth int) fieldTree {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if isJSONLeaf(t) {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isJSONLeaf(t) || visiting[t] || depth > maxFieldDepth {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	tree := fieldTree{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range buildFieldTree(embedded, visiting, depth) {
+					if _, exists := tree[k]; !exists {
+						tree[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		tree[name] = buildFieldTree(field.Type, visiting, depth+1)
+	}
+	return tree
+}
+
+// isJSONLeaf returns true if a type serializes itself, like time.Time or uuid.UUID
+func isJSONLeaf(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type projectionCategory struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type projectionOwner struct {
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+}
+
+type projectionItem struct {
+	Amount   float64             `json:"amount"`
+	Date     time.Time           `json:"date"`
+	Tags     []string            `json:"tags,omitempty"`
+	Note     Optional[string]    `json:"note"`
+	Category *projectionCategory `json:"category,omitempty"`
+	Owner    *projectionOwner    `json:"owner,omitempty"`
+	secret   string
+}
+
+func projected(t *testing.T, p *Projection, v interface{}) string {
+	t.Helper()
+	out, err := p.Apply(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(out)
+	return string(data)
+}
+
+func TestParseFieldsAndApply(t *testing.T) {
+	item := projectionItem{
+		Amount:   12.5,
+		Date:     time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Tags:     []string{"lunch"},
+		Category: &projectionCategory{Name: "Food", Color: "#fff"},
+		Owner:    &projectionOwner{Email: "a@example.com", PasswordHash: "hash"},
+	}
+
+	p, err := ParseFields("amount, category.name", projectionItem{})
+	if err != nil {
+		t.Fatalf("ParseFields failed: %v", err)
+	}
+	if got, want := projected(t, p, item), `{"amount":12.5,"category":{"name":"Food"}}`; got != want {
+		t.Errorf("Apply() = %s, want %s", got, want)
+	}
+
+	// Lists are projected per element
+	if got, want := projected(t, p, []projectionItem{item, {Amount: 3}}), `[{"amount":12.5,"category":{"name":"Food"}},{"amount":3}]`; got != want {
+		t.Errorf("Apply() on a list = %s, want %s", got, want)
+	}
+
+	// Selecting an object supersedes selecting its fields
+	p, _ = ParseFields("category.name,category,date", projectionItem{})
+	if got, want := projected(t, p, item), `{"category":{"color":"#fff","name":"Food"},"date":"2024-06-01T00:00:00Z"}`; got != want {
+		t.Errorf("Apply() = %s, want %s", got, want)
+	}
+}
+
+func TestParseFieldsRejectsUnknown(t *testing.T) {
+	for _, raw := range []string{"amount,bogus", "owner.password_hash", "owner.PasswordHash", "secret", "date.year", "tags.name", "note.value"} {
+		_, err := ParseFields(raw, projectionItem{})
+		errs, ok := err.(ValidationErrors)
+		if !ok || len(errs) != 1 || errs[0].Field != FieldsParam {
+			t.Errorf("ParseFields(%q) = %v, want one fields validation error", raw, err)
+		}
+	}
+}
+
+func TestNilProjectionKeepsEverything(t *testing.T) {
+	p, err := ParseFields(" ", projectionItem{})
+	if err != nil || p != nil {
+		t.Fatalf("Expected a nil projection, got %v, %v", p, err)
+	}
+	item := projectionItem{Amount: 1}
+	out, _ := p.Apply(item)
+	if !reflect.DeepEqual(out, item) {
+		t.Errorf("Expected the value unchanged, got %v", out)
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Optional is a tri-state field for PATCH requests that distinguishes a field
+// that was not provided, one explicitly set to null, and one set to a value
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// OptionalValue is implemented by every Optional so callers can inspect one
+// without knowing its type parameter
+type OptionalValue interface {
+	IsSet() bool
+	IsNull() bool
+	Interface() any
+}
+
+// Some returns an Optional holding value
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{Set: true, Value: value}
+}
+
+// Null returns an Optional explicitly set to null
+func Null[T any]() Optional[T] {
+	return Optional[T]{Set: true, Null: true}
+}
+
+// IsSet returns true if the field was present in the request
+func (o Optional[T]) IsSet() bool {
+	return o.Set
+}
+
+// IsNull returns true if the field was present and explicitly null
+func (o Optional[T]) IsNull() bool {
+	return o.Set && o.Null
+}
+
+// HasValue returns true if the field was present with a non-null value
+func (o Optional[T]) HasValue() bool {
+	return o.Set && !o.Null
+}
+
+// Get returns the value and whether one was provided
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.HasValue()
+}
+
+// Ptr returns a pointer to the value, or nil if absent or null
+func (o Optional[T]) Ptr() *T {
+	if !o.HasValue() {
+		return nil
+	}
+	value := o.Value
+	return &value
+}
+
+// Interface returns the value, or nil if absent or null
+func (o Optional[T]) Interface() any {
+	if !o.HasValue() {
+		return nil
+	}
+	return o.Value
+}
+
+// Apply updates target according to the field state: absent leaves it
+// unchanged, null clears it and a value replaces it
+func (o Optional[T]) Apply(target **T) {
+	if o.Set {
+		*target = o.Ptr()
+	}
+}
+
+// UnmarshalJSON is only called when the key is present, which marks the field as set
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		o.Null = true
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	o.Null = false
+	return json.Unmarshal(data, &o.Value)
+}
+
+// MarshalJSON encodes absent and null fields as null
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.HasValue() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// DecodeJSON decodes a single JSON object from r into v, rejecting unknown
+// fields and trailing data. Optional fields are marked set only when their
+// key is present in the payload.
+func DecodeJSON(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("invalid request body: unexpected data after JSON object")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionalUnmarshal(t *testing.T) {
+	type payload struct {
+		EndDate Optional[time.Time] `json:"end_date"`
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		wantSet   bool
+		wantNull  bool
+		wantValue bool
+	}{
+		{"absent", `{}`, false, false, false},
+		{"explicit null", `{"end_date": null}`, true, true, false},
+		{"value", `{"end_date": "2026-01-31T00:00:00Z"}`, true, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p payload
+			if err := DecodeJSON(strings.NewReader(tt.body), &p); err != nil {
+				t.Fatalf("DecodeJSON() error = %v", err)
+			}
+			if p.EndDate.IsSet() != tt.wantSet || p.EndDate.IsNull() != tt.wantNull || p.EndDate.HasValue() != tt.wantValue {
+				t.Errorf("state = set %v null %v value %v, want %v %v %v",
+					p.EndDate.IsSet(), p.EndDate.IsNull(), p.EndDate.HasValue(), tt.wantSet, tt.wantNull, tt.wantValue)
+			}
+			if tt.wantValue {
+				if got, _ := p.EndDate.Get(); !got.Equal(time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("value = %v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionalApply(t *testing.T) {
+	original := "keep"
+
+	var absent Optional[string]
+	target := &original
+	absent.Apply(&target)
+	if target == nil || *target != "keep" {
+		t.Errorf("absent field should leave the target unchanged")
+	}
+
+	Null[string]().Apply(&target)
+	if target != nil {
+		t.Errorf("null field should clear the target")
+	}
+
+	Some("new").Apply(&target)
+	if target == nil || *target != "new" {
+		t.Errorf("value should replace the target")
+	}
+}
+
+func TestOptionalMarshal(t *testing.T) {
+	data, err := json.Marshal(struct {
+		A Optional[int] `json:"a"`
+		B Optional[int] `json:"b"`
+		C Optional[int] `json:"c"`
+	}{B: Null[int](), C: Some(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"a":null,"b":null,"c":3}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
+
+func TestDecodeJSONRejectsInvalidInput(t *testing.T) {
+	var p struct {
+		Name Optional[string] `json:"name"`
+	}
+
+	for _, body := range []string{`{"nme": "x"}`, `{"name": 5}`, `{"name": "x"} {}`} {
+		if err := DecodeJSON(strings.NewReader(body), &p); err == nil {
+			t.Errorf("DecodeJSON(%s) should fail", body)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Pagination defaults and bounds
+const (
+	DefaultPage  = 1
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Pagination is a validated page of a list request. Cursor is passed
+// through for endpoints that page by cursor instead of by page number.
+type Pagination struct {
+	Page   int    `json:"page"`
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// PageMeta describes a page of results for response envelopes
+type PageMeta struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int64 `json:"total_pages"`
+	HasNext    bool  `json:"has_next"`
+}
+
+// ParsePagination reads the page, limit and cursor query parameters. Missing
+// or empty page and limit default to DefaultPage and DefaultLimit, and limits
+// above MaxLimit are capped. Non-numeric or non-positive values are reported
+// together as ValidationErrors.
+func ParsePagination(query url.Values) (Pagination, error) {
+	var errs ValidationErrors
+	page := parsePositiveParam(query, "page", DefaultPage, &errs)
+	limit := min(parsePositiveParam(query, "limit", DefaultLimit, &errs), MaxLimit)
+	if page > math.MaxInt/MaxLimit {
+		errs.Add("page", "page is too large")
+	}
+	if errs.HasErrors() {
+		return Pagination{}, errs
+	}
+	return Pagination{Page: page, Limit: limit, Cursor: strings.TrimSpace(query.Get("cursor"))}, nil
+}
+
+// parsePositiveParam parses a positive integer query parameter, returning
+// defaultValue when it is missing or empty
+func parsePositiveParam(query url.Values, name string, defaultValue int, errs *ValidationErrors) int {
+	raw := strings.TrimSpace(query.Get(name))
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		errs.Add(name, fmt.Sprintf("%s must be a whole number", name))
+		return defaultValue
+	}
+	if value < 1 {
+		errs.Add(name, fmt.Sprintf("%s must be greater than 0", name))
+		return defaultValue
+	}
+	return value
+}
+
+// limit returns the page size, treating an unset limit as DefaultLimit
+func (p Pagination) limit() int {
+	if p.Limit < 1 {
+		return DefaultLimit
+	}
+	return p.Limit
+}
+
+// Offset returns the number of rows before the page
+func (p Pagination) Offset() int {
+	if p.Page < 1 {
+		return 0
+	}
+	return (p.Page - 1) * p.limit()
+}
+
+// PageMeta returns the metadata of the page given the total number of rows
+func (p Pagination) PageMeta(total int64) PageMeta {
+	page, limit := max(p.Page, DefaultPage), p.limit()
+	total = max(total, 0)
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+	return PageMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    int64(page) < totalPages,
+	}
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		want   Pagination
+		fields []string
+	}{
+		{"defaults", "", Pagination{Page: 1, Limit: 20}, nil},
+		{"empty values", "page=&limit=", Pagination{Page: 1, Limit: 20}, nil},
+		{"given", "page=3&limit=50", Pagination{Page: 3, Limit: 50}, nil},
+		{"whitespace", "page=+2+&limit=10", Pagination{Page: 2, Limit: 10}, nil},
+		{"limit at max", "limit=100", Pagination{Page: 1, Limit: 100}, nil},
+		{"limit capped", "limit=500", Pagination{Page: 1, Limit: 100}, nil},
+		{"cursor", "cursor=abc123&limit=5", Pagination{Page: 1, Limit: 5, Cursor: "abc123"}, nil},
+		{"non-numeric page", "page=two", Pagination{}, []string{"page"}},
+		{"non-numeric limit", "limit=10.5", Pagination{}, []string{"limit"}},
+		{"zero page", "page=0", Pagination{}, []string{"page"}},
+		{"negative limit", "limit=-1", Pagination{}, []string{"limit"}},
+		{"both invalid", "page=x&limit=0", Pagination{}, []string{"page", "limit"}},
+		{"page too large", "page=9223372036854775807", Pagination{}, []string{"page"}},
+		{"page overflows", "page=99999999999999999999", Pagination{}, []string{"page"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParsePagination(query)
+			var fields []string
+			var errs ValidationErrors
+			if errors.As(err, &errs) {
+				fields = errs.Fields()
+			} else if err != nil {
+				t.Fatalf("Unexpected error type %T", err)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("ParsePagination(%q) failed fields = %q, want %q", tt.query, fields, tt.fields)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePagination(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginationOffset(t *testing.T) {
+	tests := []struct {
+		pagination Pagination
+		want       int
+	}{
+		{Pagination{Page: 1, Limit: 20}, 0},
+		{Pagination{Page: 2, Limit: 20}, 20},
+		{Pagination{Page: 5, Limit: 100}, 400},
+		{Pagination{}, 0},
+		{Pagination{Page: 3}, 40},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pagination.Offset(); got != tt.want {
+			t.Errorf("%+v.Offset() = %d, want %d", tt.pagination, got, tt.want)
+		}
+	}
+}
+
+func TestPaginationPageMeta(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination Pagination
+		total      int64
+		want       PageMeta
+	}{
+		{"no rows", Pagination{Page: 1, Limit: 20}, 0, PageMeta{Page: 1, Limit: 20, Total: 0, TotalPages: 0, HasNext: false}},
+		{"one partial page", Pagination{Page: 1, Limit: 20}, 7, PageMeta{Page: 1, Limit: 20, Total: 7, TotalPages: 1, HasNext: false}},
+		{"exactly one page", Pagination{Page: 1, Limit: 20}, 20, PageMeta{Page: 1, Limit: 20, Total: 20, TotalPages: 1, HasNext: false}},
+		{"one past a page", Pagination{Page: 1, Limit: 20}, 21, PageMeta{Page: 1, Limit: 20, Total: 21, TotalPages: 2, HasNext: true}},
+		{"last exact page", Pagination{Page: 3, Limit: 10}, 30, PageMeta{Page: 3, Limit: 10, Total: 30, TotalPages: 3, HasNext: false}},
+		{"before last exact page", Pagination{Page: 2, Limit: 10}, 30, PageMeta{Page: 2, Limit: 10, Total: 30, TotalPages: 3, HasNext: true}},
+		{"beyond the last page", Pagination{Page: 9, Limit: 10}, 30, PageMeta{Page: 9, Limit: 10, Total: 30, TotalPages: 3, HasNext: false}},
+		{"zero value", Pagination{}, 45, PageMeta{Page: 1, Limit: 20, Total: 45, TotalPages: 3, HasNext: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pagination.PageMeta(tt.total); got != tt.want {
+				t.Errorf("PageMeta(%d) = %+v, want %+v", tt.total, got, tt.want)
+			}
+		})
+	}
+}
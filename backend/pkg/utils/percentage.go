@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+// PercentageDecimals is the number of decimal places percentages are stored with
+const PercentageDecimals = 4
+
+// Bounds, in percent, of investment interest rates and of the expected
+// returns of investment types. Returns may be negative, as for deflationary
+// assets, but interest rates may not.
+const (
+	MinInterestRate   = 0
+	MaxInterestRate   = 100
+	MinExpectedReturn = -100
+	MaxExpectedReturn = 1000
+)
+
+// ValidatePercentage validates that value, in percent, is between min and
+// max inclusive and has at most PercentageDecimals decimal places. Values
+// far out of range are usually basis points, such as 7000 for 70%.
+func ValidatePercentage(value float64, fieldName string, min, max float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be a finite number", fieldName)}
+	}
+	if value < min || value > max {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be a percentage between %g and %g", fieldName, min, max)}
+	}
+	return ValidateAmountPrecision(value, fieldName, PercentageDecimals)
+}
+
+// ValidateRate validates an interest rate in percent, which must be between
+// MinInterestRate and MaxInterestRate
+func ValidateRate(rate float64, fieldName string) error {
+	return ValidatePercentage(rate, fieldName, MinInterestRate, MaxInterestRate)
+}
+
+// ValidateExpectedReturn validates an expected annual return in percent,
+// which must be between MinExpectedReturn and MaxExpectedReturn
+func ValidateExpectedReturn(expectedReturn float64, fieldName string) error {
+	return ValidatePercentage(expectedReturn, fieldName, MinExpectedReturn, MaxExpectedReturn)
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidatePercentage(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   float64
+		min     float64
+		max     float64
+		wantErr bool
+	}{
+		{"in range", 42.5, 0, 100, false},
+		{"at min", 0, 0, 100, false},
+		{"at max", 100, 0, 100, false},
+		{"below min", -0.0001, 0, 100, true},
+		{"above max", 100.0001, 0, 100, true},
+		{"basis points", 7000, 0, 100, true},
+		{"negative range", -12.25, -50, 50, false},
+		{"four decimals", 3.1416, 0, 100, false},
+		{"five decimals", 3.14159, 0, 100, true},
+		{"NaN", math.NaN(), 0, 100, true},
+		{"infinity", math.Inf(1), 0, math.Inf(1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePercentage(tt.value, "rate", tt.min, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePercentage(%v, %v, %v) error = %v, wantErr %v", tt.value, tt.min, tt.max, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRate(t *testing.T) {
+	tests := []struct {
+		rate    float64
+		wantErr bool
+	}{
+		{MinInterestRate, false},
+		{7.1, false},
+		{MaxInterestRate, false},
+		{-0.5, true},
+		{100.5, true},
+		{7000, true},
+		{4.12345, true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateRate(tt.rate, "interest_rate"); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRate(%v) error = %v, wantErr %v", tt.rate, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateExpectedReturn(t *testing.T) {
+	tests := []struct {
+		expectedReturn float64
+		wantErr        bool
+	}{
+		{MinExpectedReturn, false},
+		{-3.5, false},
+		{0, false},
+		{MaxExpectedReturn, false},
+		{-100.01, true},
+		{1000.01, true},
+		{7000, true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateExpectedReturn(tt.expectedReturn, "expected_return"); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateExpectedReturn(%v) error = %v, wantErr %v", tt.expectedReturn, err, tt.wantErr)
+		}
+	}
+}
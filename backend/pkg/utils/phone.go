@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxE164Digits is the most digits an E.164 number may have, country code
+// included
+const maxE164Digits = 15
+
+// phoneRegion describes how numbers are dialled within a region
+type phoneRegion struct {
+	// countryCode is the ITU calling code, such as "44"
+	countryCode string
+	// trunkPrefix is dropped from national numbers, such as the 0 of 020 in
+	// London; Italy and others keep their leading 0 so have none
+	trunkPrefix string
+	// internationalPrefix dials out of the region, such as 00 or 011
+	internationalPrefix string
+	// minDigits and maxDigits bound the national significant number
+	minDigits, maxDigits int
+}
+
+// phoneRegions maps ISO 3166 region codes to their dialling rules
+var phoneRegions = map[string]phoneRegion{
+	"US": {"1", "1", "011", 10, 10},
+	"CA": {"1", "1", "011", 10, 10},
+	"GB": {"44", "0", "00", 9, 10},
+	"IE": {"353", "0", "00", 7, 9},
+	"IN": {"91", "0", "00", 10, 10},
+	"AU": {"61", "0", "0011", 9, 9},
+	"NZ": {"64", "0", "00", 8, 10},
+	"DE": {"49", "0", "00", 6, 13},
+	"FR": {"33", "0", "00", 9, 9},
+	"ES": {"34", "", "00", 9, 9},
+	"IT": {"39", "", "00", 6, 11},
+	"NL": {"31", "0", "00", 9, 9},
+	"BR": {"55", "0", "00", 10, 11},
+	"MX": {"52", "", "00", 10, 10},
+	"JP": {"81", "0", "010", 9, 10},
+	"CN": {"86", "0", "00", 7, 11},
+	"SG": {"65", "", "000", 8, 8},
+	"ZA": {"27", "0", "00", 9, 9},
+}
+
+// countryCodes are the assigned ITU-T E.164 country calling codes. No code
+// is a prefix of another, so a number starts with at most one of them.
+var countryCodes = toSet(strings.Fields(`
+	1 7
+	20 211 212 213 216 218 220 221 222 223 224 225 226 227 228 229 230 231
+	232 233 234 235 236 237 238 239 240 241 242 243 244 245 246 247 248 249
+	250 251 252 253 254 255 256 257 258 260 261 262 263 264 265 266 267 268
+	269 27 290 291 297 298 299
+	30 31 32 33 34 350 351 352 353 354 355 356 357 358 359 36 370 371 372 373
+	374 375 376 377 378 379 380 381 382 383 385 386 387 389 39
+	40 41 420 421 423 43 44 45 46 47 48 49
+	500 501 502 503 504 505 506 507 508 509 51 52 53 54 55 56 57 58 590 591
+	592 593 594 595 596 597 598 599
+	60 61 62 63 64 65 66 670 672 673 674 675 676 677 678 679 680 681 682 683
+	685 686 687 688 689 690 691 692
+	800 808 81 82 84 850 852 853 855 856 86 870 878 880 881 882 883 886 888
+	90 91 92 93 94 95 960 961 962 963 964 965 966 967 968 970 971 972 973 974
+	975 976 977 979 98 992 993 994 995 996 998
+`))
+
+// countryCodeDigits bounds the national significant number of each calling
+// code with regions in phoneRegions
+var countryCodeDigits = func() map[string][2]int {
+	bounds := make(map[string][2]int)
+	for _, region := range phoneRegions {
+		b, ok := bounds[region.countryCode]
+		if !ok || region.minDigits < b[0] {
+			b[0] = region.minDigits
+		}
+		if region.maxDigits > b[1] {
+			b[1] = region.maxDigits
+		}
+		bounds[region.countryCode] = b
+	}
+	return bounds
+}()
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// PhoneOption changes how ValidatePhone checks a number
+type PhoneOption int
+
+const (
+	// CheckCountryCode verifies that international numbers, those starting
+	// with +, begin with an assigned ITU country calling code
+	CheckCountryCode PhoneOption = iota + 1
+)
+
+// NormalizePhone returns phone in E.164 form, such as +442071838750.
+// Numbers starting with + or the international prefix of defaultRegion are
+// read as international; others are national numbers of defaultRegion, an
+// ISO 3166 code such as "GB", with its trunk prefix removed. Spaces, dots,
+// dashes, slashes and parentheses are ignored. Extensions and letters are
+// rejected.
+func NormalizePhone(phone, defaultRegion string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return "", &ValidationError{Field: "phone", Message: "phone number is required"}
+	}
+
+	rest, international := strings.CutPrefix(phone, "+")
+	var digits strings.Builder
+	for i, r := range rest {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" .-/()", r):
+		case strings.ContainsRune("#;,xX", r) || strings.HasPrefix(strings.ToLower(rest[i:]), "ext"):
+			return "", &ValidationError{Field: "phone", Message: "phone number must not include an extension"}
+		default:
+			return "", &ValidationError{Field: "phone", Message: "phone number may only contain digits, spaces and + ( ) - . /"}
+		}
+	}
+	number := digits.String()
+
+	if !international {
+		region, ok := phoneRegions[strings.ToUpper(strings.TrimSpace(defaultRegion))]
+		if !ok {
+			return "", &ValidationError{Field: "phone", Message: "phone number must start with + and a country code"}
+		}
+		if dialled, ok := strings.CutPrefix(number, region.internationalPrefix); ok {
+			number = dialled
+		} else {
+			if region.trunkPrefix != "" && len(number) > region.minDigits {
+				number = strings.TrimPrefix(number, region.trunkPrefix)
+			}
+			number = region.countryCode + number
+		}
+	}
+
+	if err := validateE164Digits(number); err != nil {
+		return "", err
+	}
+	return "+" + number, nil
+}
+
+// validateE164Digits validates the digits of an E.164 number: a known
+// country code followed by a national significant number of a plausible
+// length that is not one digit repeated
+func validateE164Digits(number string) error {
+	countryCode := ""
+	for n := 1; n <= 3 && n <= len(number); n++ {
+		if countryCodes[number[:n]] {
+			countryCode = number[:n]
+			break
+		}
+	}
+	if countryCode == "" {
+		return &ValidationError{Field: "phone", Message: "phone number has an unknown country code"}
+	}
+	if len(number) > maxE164Digits {
+		return &ValidationError{Field: "phone", Message: fmt.Sprintf("phone number must have at most %d digits including the country code", maxE164Digits)}
+	}
+
+	national := number[len(countryCode):]
+	minDigits, maxDigits := 4, maxE164Digits-len(countryCode)
+	if bounds, ok := countryCodeDigits[countryCode]; ok {
+		minDigits, maxDigits = bounds[0], bounds[1]
+	}
+	if len(national) < minDigits || len(national) > maxDigits {
+		return &ValidationError{Field: "phone", Message: fmt.Sprintf("phone number has the wrong number of digits for country code +%s", countryCode)}
+	}
+	if repeatedDigit(national) {
+		return &ValidationError{Field: "phone", Message: "phone number is not a real number"}
+	}
+	return nil
+}
+
+// repeatedDigit returns true if digits is one digit repeated, such as 0000
+func repeatedDigit(digits string) bool {
+	return digits != "" && strings.Count(digits, digits[:1]) == len(digits)
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		phone   string
+		region  string
+		want    string
+		wantErr bool
+	}{
+		{"US national", "(415) 555-2671", "US", "+14155552671", false},
+		{"US with trunk prefix", "1-415-555-2671", "US", "+14155552671", false},
+		{"US dotted", "415.555.2671", "US", "+14155552671", false},
+		{"US international", "+1 415 555 2671", "GB", "+14155552671", false},
+		{"US dialled from the US", "011 44 20 7183 8750", "US", "+442071838750", false},
+		{"UK landline", "020 7183 8750", "GB", "+442071838750", false},
+		{"UK mobile", "07911 123456", "GB", "+447911123456", false},
+		{"UK international", "+44 (0)20 7183 8750", "US", "", true},
+		{"UK international without trunk", "+44 20 7183 8750", "US", "+442071838750", false},
+		{"UK dialled from the UK", "00 44 20 7183 8750", "GB", "+442071838750", false},
+		{"India mobile", "98765 43210", "IN", "+919876543210", false},
+		{"India with trunk prefix", "098765 43210", "IN", "+919876543210", false},
+		{"India international", "+91 98765 43210", "US", "+919876543210", false},
+		{"region is case insensitive", "020 7183 8750", "gb", "+442071838750", false},
+		{"default region fallback", "415 555 2671", "US", "+14155552671", false},
+		{"default region fallback for another region", "415 555 2671", "IN", "+914155552671", false},
+		{"unknown region without +", "415 555 2671", "", "", true},
+		{"unknown region with +", "+1 415 555 2671", "", "+14155552671", false},
+		{"extension x", "+1 415 555 2671 x123", "US", "", true},
+		{"extension X", "415-555-2671X123", "US", "", true},
+		{"extension ext", "+1 415 555 2671 ext. 5", "US", "", true},
+		{"extension hash", "415 555 2671 #5", "US", "", true},
+		{"letters", "1-800-FLOWERS", "US", "", true},
+		{"unknown country code", "+999 1234 5678", "US", "", true},
+		{"too many digits", "+1 415 555 2671 1234", "US", "", true},
+		{"too few digits", "555 2671", "US", "", true},
+		{"repeated digit", "+1 555 555 5555", "US", "", true},
+		{"all zeros", "000 000 0000", "US", "", true},
+		{"empty", "  ", "US", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizePhone(tt.phone, tt.region)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizePhone(%q, %q) error = %v, wantErr %v", tt.phone, tt.region, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizePhone(%q, %q) = %q, want %q", tt.phone, tt.region, got, tt.want)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "phone") {
+				t.Errorf("Expected a phone validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizePhoneIsIdempotent(t *testing.T) {
+	for _, phone := range []string{"+14155552671", "+442071838750", "+919876543210"} {
+		got, err := NormalizePhone(phone, "US")
+		if err != nil || got != phone {
+			t.Errorf("NormalizePhone(%q) = %q, %v, want it unchanged", phone, got, err)
+		}
+	}
+}
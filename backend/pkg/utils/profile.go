@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Field kinds a validation profile can refer to
+const (
+	FieldKindNumber = "number"
+	FieldKindString = "string"
+	FieldKindDate   = "date"
+)
+
+// ValidationProfile declares which fields a record must have, which it must
+// not have, and bounds on their values. Profiles are stored as JSON so
+// admins can edit them without a deploy.
+type ValidationProfile struct {
+	Required    []string                   `json:"required,omitempty"`
+	Forbidden   []string                   `json:"forbidden,omitempty"`
+	Constraints map[string]FieldConstraint `json:"constraints,omitempty"`
+}
+
+// FieldConstraint bounds the value of a field. Min and Max apply to
+// numbers, MaxLength and Pattern to strings.
+type FieldConstraint struct {
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MaxLength *int     `json:"max_length,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+}
+
+// Validate checks the profile itself. fields maps every field the profile
+// may refer to onto its kind.
+func (p *ValidationProfile) Validate(fields map[string]string) ValidationErrors {
+	var errs ValidationErrors
+
+	required := make(map[string]bool)
+	for _, field := range p.Required {
+		if _, ok := fields[field]; !ok {
+			errs.Add("required", fmt.Sprintf("unknown field %q", field))
+		}
+		if required[field] {
+			errs.Add("required", fmt.Sprintf("field %q is listed twice", field))
+		}
+		required[field] = true
+	}
+	forbidden := make(map[string]bool)
+	for _, field := range p.Forbidden {
+		if _, ok := fields[field]; !ok {
+			errs.Add("forbidden", fmt.Sprintf("unknown field %q", field))
+		}
+		if forbidden[field] {
+			errs.Add("forbidden", fmt.Sprintf("field %q is listed twice", field))
+		}
+		if required[field] {
+			errs.Add("forbidden", fmt.Sprintf("field %q cannot be both required and forbidden", field))
+		}
+		forbidden[field] = true
+	}
+
+	for _, field := range sortedKeys(p.Constraints) {
+		constraint := p.Constraints[field]
+		name := "constraints." + field
+		kind, ok := fields[field]
+		switch {
+		case !ok:
+			errs.Add(name, fmt.Sprintf("unknown field %q", field))
+			continue
+		case forbidden[field]:
+			errs.Add(name, "a forbidden field cannot have constraints")
+		}
+
+		if (constraint.Min != nil || constraint.Max != nil) && kind != FieldKindNumber {
+			errs.Add(name, "min and max only apply to number fields")
+		}
+		if constraint.Min != nil && constraint.Max != nil && *constraint.Min > *constraint.Max {
+			errs.Add(name, "min must not exceed max")
+		}
+		if (constraint.MaxLength != nil || constraint.Pattern != "") && kind != FieldKindString {
+			errs.Add(name, "max_length and pattern only apply to string fields")
+		}
+		if constraint.MaxLength != nil && *constraint.MaxLength < 1 {
+			errs.Add(name, "max_length must be at least 1")
+		}
+		if constraint.Pattern != "" {
+			if _, err := regexp.Compile(constraint.Pattern); err != nil {
+				errs.Add(name, fmt.Sprintf("invalid pattern: %v", err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// Evaluate checks values against the profile. values holds the fields the
+// profile may refer to, with nil for fields that are not set. If changed is
+// non-nil only the fields it contains are checked, so a record created
+// before the profile was tightened can still update its other fields.
+func (p *ValidationProfile) Evaluate(values map[string]any, changed map[string]bool) ValidationErrors {
+	var errs ValidationErrors
+	checked := func(field string) bool { return changed == nil || changed[field] }
+
+	for _, field := range p.Required {
+		if checked(field) && isBlank(values[field]) {
+			errs.Add(field, fmt.Sprintf("%s is required for this type", field))
+		}
+	}
+	for _, field := range p.Forbidden {
+		if checked(field) && !isBlank(values[field]) {
+			errs.Add(field, fmt.Sprintf("%s is not allowed for this type", field))
+		}
+	}
+
+	for _, field := range sortedKeys(p.Constraints) {
+		value := values[field]
+		if !checked(field) || isBlank(value) {
+			continue
+		}
+		constraint := p.Constraints[field]
+
+		switch v := value.(type) {
+		case float64:
+			if constraint.Min != nil && v < *constraint.Min {
+				errs.Add(field, fmt.Sprintf("%s must be at least %g", field, *constraint.Min))
+			}
+			if constraint.Max != nil && v > *constraint.Max {
+				errs.Add(field, fmt.Sprintf("%s must be at most %g", field, *constraint.Max))
+			}
+		case string:
+			if constraint.MaxLength != nil && len([]rune(v)) > *constraint.MaxLength {
+				errs.Add(field, fmt.Sprintf("%s must be at most %d characters", field, *constraint.MaxLength))
+			}
+			if constraint.Pattern != "" {
+				if matched, err := regexp.MatchString(constraint.Pattern, v); err != nil || !matched {
+					errs.Add(field, fmt.Sprintf("%s has an invalid format", field))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// Value stores the profile as JSON
+func (p ValidationProfile) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan reads a profile stored as JSON
+func (p *ValidationProfile) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*p = ValidationProfile{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, p)
+	case string:
+		return json.Unmarshal([]byte(data), p)
+	}
+	return fmt.Errorf("cannot scan %T into ValidationProfile", src)
+}
+
+// isBlank returns true for unset values and empty strings
+func isBlank(value any) bool {
+	if value == nil {
+		return true
+	}
+	if s, ok := value.(string); ok {
+		return strings.TrimSpace(s) == ""
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in order, for deterministic error lists
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var profileFields = map[string]string{
+	"symbol":        FieldKindString,
+	"interest_rate": FieldKindNumber,
+	"end_date":      FieldKindDate,
+}
+
+func floatPtr(v float64) *float64 { return &v }
+func intPtr(v int) *int           { return &v }
+
+func TestValidationProfileEvaluate(t *testing.T) {
+	profile := ValidationProfile{
+		Required:  []string{"interest_rate", "end_date"},
+		Forbidden: []string{"symbol"},
+		Constraints: map[string]FieldConstraint{
+			"interest_rate": {Min: floatPtr(0), Max: floatPtr(25)},
+		},
+	}
+	end := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		values     map[string]any
+		changed    map[string]bool
+		wantFields []string
+	}{
+		{
+			name:   "valid",
+			values: map[string]any{"interest_rate": 7.1, "end_date": end, "symbol": nil},
+		},
+		{
+			name:       "missing required and forbidden present",
+			values:     map[string]any{"interest_rate": nil, "end_date": nil, "symbol": "HDFC"},
+			wantFields: []string{"interest_rate", "end_date", "symbol"},
+		},
+		{
+			name:       "out of range",
+			values:     map[string]any{"interest_rate": 40.0, "end_date": end},
+			wantFields: []string{"interest_rate"},
+		},
+		{
+			name:       "blank string counts as missing",
+			values:     map[string]any{"interest_rate": 7.0, "end_date": end, "symbol": "  "},
+			wantFields: nil,
+		},
+		{
+			name:    "only changed fields are checked",
+			values:  map[string]any{"interest_rate": nil, "end_date": nil, "symbol": "HDFC"},
+			changed: map[string]bool{"notes": true},
+		},
+		{
+			name:       "clearing a required field",
+			values:     map[string]any{"interest_rate": nil, "end_date": nil, "symbol": "HDFC"},
+			changed:    map[string]bool{"end_date": true},
+			wantFields: []string{"end_date"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := profile.Evaluate(tt.values, tt.changed)
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("Evaluate() errors = %v, want fields %v", errs, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidationProfilePattern(t *testing.T) {
+	profile := ValidationProfile{Constraints: map[string]FieldConstraint{
+		"symbol": {MaxLength: intPtr(5), Pattern: `^[A-Z]+$`},
+	}}
+	if errs := profile.Evaluate(map[string]any{"symbol": "AAPL"}, nil); errs.HasErrors() {
+		t.Errorf("Expected AAPL to pass, got %v", errs)
+	}
+	if errs := profile.Evaluate(map[string]any{"symbol": "aapl123"}, nil); len(errs) != 2 {
+		t.Errorf("Expected length and pattern errors, got %v", errs)
+	}
+}
+
+func TestValidationProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		wantErr string
+	}{
+		{name: "valid", profile: `{"required":["symbol"],"constraints":{"symbol":{"max_length":10,"pattern":"^[A-Z]+$"}}}`},
+		{name: "unknown field", profile: `{"required":["ticker"]}`, wantErr: `unknown field "ticker"`},
+		{name: "required and forbidden", profile: `{"required":["symbol"],"forbidden":["symbol"]}`, wantErr: "both required and forbidden"},
+		{name: "constraint on forbidden", profile: `{"forbidden":["interest_rate"],"constraints":{"interest_rate":{"max":5}}}`, wantErr: "forbidden field cannot have constraints"},
+		{name: "min above max", profile: `{"constraints":{"interest_rate":{"min":10,"max":5}}}`, wantErr: "min must not exceed max"},
+		{name: "range on string", profile: `{"constraints":{"symbol":{"min":1}}}`, wantErr: "only apply to number fields"},
+		{name: "pattern on date", profile: `{"constraints":{"end_date":{"pattern":"x"}}}`, wantErr: "only apply to string fields"},
+		{name: "bad pattern", profile: `{"constraints":{"symbol":{"pattern":"("}}}`, wantErr: "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var profile ValidationProfile
+			if err := json.Unmarshal([]byte(tt.profile), &profile); err != nil {
+				t.Fatal(err)
+			}
+			errs := profile.Validate(profileFields)
+			if tt.wantErr == "" {
+				if errs.HasErrors() {
+					t.Errorf("Validate() = %v, want no errors", errs)
+				}
+				return
+			}
+			if !strings.Contains(errs.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidationProfileScan(t *testing.T) {
+	var profile ValidationProfile
+	if err := profile.Scan([]byte(`{"required":["symbol"]}`)); err != nil || len(profile.Required) != 1 {
+		t.Errorf("Scan() = %+v, %v", profile, err)
+	}
+	value, err := profile.Value()
+	if err != nil || string(value.([]byte)) != `{"required":["symbol"]}` {
+		t.Errorf("Value() = %s, %v", value, err)
+	}
+}
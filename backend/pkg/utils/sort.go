@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultSortOrder is used when a request does not give a sort order
+const DefaultSortOrder = "asc"
+
+// SortParams is a validated sort column and direction, safe to use in an
+// ORDER BY clause. Field is one of the allowed columns as written in the
+// allow list and Order is "asc" or "desc".
+type SortParams struct {
+	Field string `json:"field"`
+	Order string `json:"order"`
+}
+
+// OrderBy returns the params as an ORDER BY expression, such as
+// "expense_date DESC"
+func (p SortParams) OrderBy() string {
+	return p.Field + " " + strings.ToUpper(p.Order)
+}
+
+// ValidateSortField validates that field is one of the allowed columns,
+// ignoring case and surrounding whitespace. An empty field is allowed so the
+// caller's default applies.
+func ValidateSortField(field string, allowed []string) error {
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil
+	}
+	if _, ok := allowedSortField(field, allowed); !ok {
+		return &ValidationError{Field: "sort_by", Message: fmt.Sprintf("sort_by must be one of: %s", strings.Join(allowed, ", "))}
+	}
+	return nil
+}
+
+// allowedSortField returns the allowed column matching field
+func allowedSortField(field string, allowed []string) (string, bool) {
+	for _, column := range allowed {
+		if strings.EqualFold(field, column) {
+			return column, true
+		}
+	}
+	return "", false
+}
+
+// ParseSortParams reads the sort_by and sort_order query parameters,
+// validating sort_by against the allowed columns and falling back to
+// defaultField and DefaultSortOrder when they are empty. defaultField comes
+// from the caller, not the request, so it is not checked. Both parameters are
+// checked and any errors returned together as ValidationErrors.
+func ParseSortParams(query url.Values, allowed []string, defaultField string) (SortParams, error) {
+	field := strings.TrimSpace(query.Get("sort_by"))
+	order := strings.ToLower(strings.TrimSpace(query.Get("sort_order")))
+
+	var errs ValidationErrors
+	errs.AddError(ValidateSortField(field, allowed))
+	errs.AddError(ValidateSortOrder(order))
+	if errs.HasErrors() {
+		return SortParams{}, errs
+	}
+
+	if field == "" {
+		field = defaultField
+	}
+	if column, ok := allowedSortField(field, allowed); ok {
+		field = column
+	}
+	if order == "" {
+		order = DefaultSortOrder
+	}
+	return SortParams{Field: field, Order: order}, nil
+}
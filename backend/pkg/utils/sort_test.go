@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestValidateSortField(t *testing.T) {
+	allowed := []string{"expense_date", "amount"}
+	tests := []struct {
+		name    string
+		field   string
+		wantErr bool
+	}{
+		{"allowed", "amount", false},
+		{"any case", "Expense_Date", false},
+		{"whitespace", " amount ", false},
+		{"empty", "", false},
+		{"unknown column", "password_hash", true},
+		{"injection", "amount; DROP TABLE expenses", true},
+		{"prefix", "amoun", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSortField(tt.field, allowed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSortField(%q) error = %v, wantErr %v", tt.field, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSortParams(t *testing.T) {
+	allowed := []string{"expense_date", "amount", "created_at"}
+	tests := []struct {
+		name   string
+		query  string
+		want   SortParams
+		fields []string
+	}{
+		{"given", "sort_by=amount&sort_order=desc", SortParams{"amount", "desc"}, nil},
+		{"case is normalized", "sort_by=AMOUNT&sort_order=DESC", SortParams{"amount", "desc"}, nil},
+		{"whitespace", "sort_by=+amount+&sort_order=+asc", SortParams{"amount", "asc"}, nil},
+		{"empty uses defaults", "", SortParams{"expense_date", "asc"}, nil},
+		{"empty values use defaults", "sort_by=&sort_order=", SortParams{"expense_date", "asc"}, nil},
+		{"default order", "sort_by=created_at", SortParams{"created_at", "asc"}, nil},
+		{"default field", "sort_order=desc", SortParams{"expense_date", "desc"}, nil},
+		{"unknown column", "sort_by=password_hash", SortParams{}, []string{"sort_by"}},
+		{"injection", "sort_by=amount%3B+DROP+TABLE+users", SortParams{}, []string{"sort_by"}},
+		{"bad order", "sort_by=amount&sort_order=sideways", SortParams{}, []string{"sort_order"}},
+		{"both bad", "sort_by=id&sort_order=up", SortParams{}, []string{"sort_by", "sort_order"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseSortParams(query, allowed, "expense_date")
+			var fields []string
+			var errs ValidationErrors
+			if errors.As(err, &errs) {
+				fields = errs.Fields()
+			} else if err != nil {
+				t.Fatalf("Unexpected error type %T", err)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("ParseSortParams(%q) failed fields = %q, want %q", tt.query, fields, tt.fields)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSortParams(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortParamsOrderBy(t *testing.T) {
+	if got := (SortParams{Field: "expense_date", Order: "desc"}).OrderBy(); got != "expense_date DESC" {
+		t.Errorf("OrderBy() = %q", got)
+	}
+}
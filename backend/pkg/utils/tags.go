@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default tag limits used by ValidateTags when its limits are not positive
+const (
+	DefaultMaxTags      = 20
+	DefaultMaxTagLength = 50
+)
+
+// NormalizeTags trims and lowercases tags, dropping empty tags and
+// duplicates while keeping the order in which tags first appear
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ValidateTags normalizes tags with NormalizeTags and validates that there
+// are at most maxCount of at most maxLen characters each, none containing
+// commas or control characters. Non-positive limits use DefaultMaxTags and
+// DefaultMaxTagLength. The error is ValidationErrors naming each bad tag by
+// its position in the normalized list.
+func ValidateTags(tags []string, fieldName string, maxCount, maxLen int) ([]string, error) {
+	if maxCount <= 0 {
+		maxCount = DefaultMaxTags
+	}
+	if maxLen <= 0 {
+		maxLen = DefaultMaxTagLength
+	}
+
+	var errs ValidationErrors
+	for _, tag := range tags {
+		if !utf8.ValidString(tag) {
+			errs.Add(fieldName, "tags must be valid UTF-8")
+			return nil, errs
+		}
+	}
+
+	normalized := NormalizeTags(tags)
+	if len(normalized) > maxCount {
+		errs.Add(fieldName, fmt.Sprintf("%s must have no more than %d tags", fieldName, maxCount))
+	}
+	for i, tag := range normalized {
+		field := fmt.Sprintf("%s[%d]", fieldName, i)
+		switch {
+		case utf8.RuneCountInString(tag) > maxLen:
+			errs.Add(field, fmt.Sprintf("tags must be no more than %d characters long", maxLen))
+		case strings.ContainsRune(tag, ','):
+			errs.Add(field, "tags must not contain commas")
+		case strings.IndexFunc(tag, unicode.IsControl) >= 0:
+			errs.Add(field, "tags must not contain control characters")
+		}
+	}
+	if errs.HasErrors() {
+		return nil, errs
+	}
+	return normalized, nil
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"trims and lowercases", []string{"  Groceries ", "WORK"}, []string{"groceries", "work"}},
+		{"duplicates after normalization keep the first", []string{"Travel", "work", " travel", "TRAVEL ", "work"}, []string{"travel", "work"}},
+		{"drops empty tags", []string{"", "  ", "\t", "food"}, []string{"food"}},
+		{"keeps inner spaces", []string{"Date Night"}, []string{"date night"}},
+		{"unicode", []string{"Café", "CAFÉ", "Ärzte", "食品", "Ελλάδα", "किराना"}, []string{"café", "ärzte", "食品", "ελλάδα", "किराना"}},
+		{"all empty", []string{" ", ""}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeTags(%q) = %q, want %q", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	many := func(n int) []string {
+		tags := make([]string, n)
+		for i := range tags {
+			tags[i] = fmt.Sprintf("tag-%d", i)
+		}
+		return tags
+	}
+
+	tests := []struct {
+		name   string
+		tags   []string
+		want   []string
+		fields []string
+	}{
+		{"valid", []string{"Food", "work "}, []string{"food", "work"}, nil},
+		{"at max count", many(5), many(5), nil},
+		{"over max count", many(6), nil, []string{"tags"}},
+		{"duplicates do not count toward the max", append(many(5), "TAG-0", " tag-4"), many(5), nil},
+		{"at max length", []string{strings.Repeat("a", 10)}, []string{strings.Repeat("a", 10)}, nil},
+		{"over max length", []string{strings.Repeat("a", 11)}, nil, []string{"tags[0]"}},
+		{"length counts characters", []string{strings.Repeat("é", 10), strings.Repeat("食", 10)}, []string{strings.Repeat("é", 10), strings.Repeat("食", 10)}, nil},
+		{"comma", []string{"food", "rent,utilities"}, nil, []string{"tags[1]"}},
+		{"control character", []string{"fo\x00od", "bell\a", "new\nline"}, nil, []string{"tags[0]", "tags[1]", "tags[2]"}},
+		{"invalid UTF-8", []string{"food", "caf\xe9"}, nil, []string{"tags"}},
+		{"unicode", []string{"Café", "食品", "किराना"}, []string{"café", "食品", "किराना"}, nil},
+		{"none", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateTags(tt.tags, "tags", 5, 10)
+			var fields []string
+			var errs ValidationErrors
+			if errors.As(err, &errs) {
+				fields = errs.Fields()
+			} else if err != nil {
+				t.Fatalf("Unexpected error type %T", err)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("ValidateTags(%q) failed fields = %q, want %q", tt.tags, fields, tt.fields)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateTags(%q) = %q, want %q", tt.tags, got, tt.want)
+			}
+		})
+	}
+
+	// Non-positive limits use the defaults
+	if _, err := ValidateTags(many(DefaultMaxTags), "tags", 0, 0); err != nil {
+		t.Errorf("Expected %d tags to be valid by default, got %v", DefaultMaxTags, err)
+	}
+	if _, err := ValidateTags(many(DefaultMaxTags+1), "tags", 0, 0); err == nil {
+		t.Errorf("Expected %d tags to be rejected by default", DefaultMaxTags+1)
+	}
+	if _, err := ValidateTags([]string{strings.Repeat("a", DefaultMaxTagLength+1)}, "tags", -1, -1); err == nil {
+		t.Error("Expected an overlong tag to be rejected by default")
+	}
+}
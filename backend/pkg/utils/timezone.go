@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Embed the IANA database so zones load where the system has none, such
+	// as in minimal containers
+	_ "time/tzdata"
+)
+
+// DefaultTimeZone is the zone of users who have not chosen one
+const DefaultTimeZone = "UTC"
+
+// timeZones caches loaded locations by name
+var timeZones sync.Map
+
+// LoadTimeZone returns the location of an IANA time zone name such as
+// "Asia/Kolkata". Names are case sensitive. "Local" and the empty name are
+// rejected because they depend on the server.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if cached, ok := timeZones.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	if name == "" || name == "Local" || strings.TrimSpace(name) != name {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	timeZones.Store(name, loc)
+	return loc, nil
+}
+
+// ValidateTimeZone validates that name is an IANA time zone name
+func ValidateTimeZone(name, fieldName string) error {
+	if err := ValidateRequired(name, fieldName); err != nil {
+		return err
+	}
+	if _, err := LoadTimeZone(name); err != nil {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be an IANA time zone such as Europe/London", fieldName)}
+	}
+	return nil
+}
+
+// DateInZone returns the calendar date of t in the time zone tz as midnight
+// UTC, so 23:30 UTC on January 31 is February 1 in Asia/Kolkata
+func DateInZone(t time.Time, tz string) (time.Time, error) {
+	loc, err := LoadTimeZone(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return calendarDate(t.In(loc)), nil
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTimeZone(t *testing.T) {
+	tests := []struct {
+		name    string
+		tz      string
+		wantErr bool
+	}{
+		{"UTC", "UTC", false},
+		{"Asia/Kolkata", "Asia/Kolkata", false},
+		{"America/New_York", "America/New_York", false},
+		{"three level name", "America/Argentina/Buenos_Aires", false},
+		{"empty", "", true},
+		{"local", "Local", true},
+		{"wrong case", "asia/kolkata", true},
+		{"unknown", "Mars/Olympus_Mons", true},
+		{"abbreviation", "IST", true},
+		{"offset", "+05:30", true},
+		{"path traversal", "../../etc/passwd", true},
+		{"whitespace", " UTC", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTimeZone(tt.tz, "timezone")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTimeZone(%q) error = %v, wantErr %v", tt.tz, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDateInZone(t *testing.T) {
+	lateJan31 := time.Date(2026, time.January, 31, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		tz   string
+		at   time.Time
+		want time.Time
+	}{
+		{"UTC", lateJan31, time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"Asia/Kolkata", lateJan31, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"America/Los_Angeles", time.Date(2026, time.February, 1, 3, 0, 0, 0, time.UTC), time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"Pacific/Kiritimati", time.Date(2026, time.December, 31, 10, 0, 0, 0, time.UTC), time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := DateInZone(tt.at, tt.tz)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("DateInZone(%v, %q) = %v, %v, want %v", tt.at, tt.tz, got, err, tt.want)
+		}
+	}
+	if _, err := DateInZone(lateJan31, "Nowhere/Special"); err == nil {
+		t.Error("Expected an unknown zone to fail")
+	}
+}
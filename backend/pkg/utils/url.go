@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// DefaultMaxURLLength is the longest URL ValidateURL accepts by default
+const DefaultMaxURLLength = 2048
+
+// URLOptions restricts the URLs ValidateURL accepts
+type URLOptions struct {
+	// AllowedHosts lists the only hosts accepted when set. An entry starting
+	// with "." matches any subdomain, so ".example.com" matches
+	// "cdn.example.com" but not "example.com".
+	AllowedHosts []string
+	// MaxLength overrides DefaultMaxURLLength when positive
+	MaxLength int
+}
+
+// ValidateURL validates a link that will be shown to users, such as a
+// receipt URL, and returns it with surrounding whitespace removed. It must
+// be an absolute http or https URL without a username or password, whose
+// host is a fully qualified ASCII name rather than an IP address. Unicode
+// host names must be given in their punycode form so look-alike characters
+// cannot disguise a host.
+func ValidateURL(value, fieldName string, opts URLOptions) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s is required", fieldName)}
+	}
+
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxURLLength
+	}
+	if len(value) > maxLength {
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s is too long (max %d characters)", fieldName, maxLength)}
+	}
+
+	invalid := &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be an absolute http or https URL", fieldName)}
+	if strings.IndexFunc(value, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return "", invalid
+	}
+	u, err := url.Parse(value)
+	if err != nil || (!strings.EqualFold(u.Scheme, "https") && !strings.EqualFold(u.Scheme, "http")) || u.Opaque != "" {
+		return "", invalid
+	}
+	if u.User != nil {
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must not contain a username or password", fieldName)}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	switch {
+	case host == "":
+		return "", invalid
+	case net.ParseIP(host) != nil || endsInNumber(host):
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must use a host name, not an IP address", fieldName)}
+	case strings.IndexFunc(host, func(r rune) bool { return r > unicode.MaxASCII }) >= 0:
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s host must be ASCII; use the punycode form of international names", fieldName)}
+	case !strings.Contains(host, "."):
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must use a fully qualified host name", fieldName)}
+	}
+
+	if len(opts.AllowedHosts) > 0 && !hostAllowed(host, opts.AllowedHosts) {
+		return "", &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s host %s is not allowed", fieldName, host)}
+	}
+	return value, nil
+}
+
+// endsInNumber returns true if the last label of host is a decimal or hex
+// number, which browsers read as an IPv4 address, as in 0x7f.1
+func endsInNumber(host string) bool {
+	label := host[strings.LastIndex(host, ".")+1:]
+	if hex, ok := strings.CutPrefix(label, "0x"); ok {
+		return strings.Trim(hex, "0123456789abcdef") == ""
+	}
+	return label != "" && strings.Trim(label, "0123456789") == ""
+}
+
+// hostAllowed returns true if host matches one of the allowed hosts
+func hostAllowed(host string, allowed []string) bool {
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if strings.HasPrefix(entry, ".") {
+			if strings.HasSuffix(host, entry) {
+				return true
+			}
+		} else if host == entry {
+			return true
+		}
+	}
+	return false
+}
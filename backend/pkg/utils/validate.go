@@ -0,0 +1,427 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ValidateStruct checks v, a struct or pointer to one, against the validate
+// tags of its fields and returns every failure, named by JSON field. Nested
+// structs are validated too, with dotted names such as "settings.decimal".
+//
+// Supported rules are required, omitempty, email, uuid, len, min, max, gt,
+// gte, lt, lte and oneof. On strings and slices len, min, max, gt, gte, lt and
+// lte apply to the length. Pointer and Optional fields are checked when they
+// hold a value. Unknown or misapplied rules are programming errors and panic;
+// ValidateStructTags reports them as an error instead.
+func ValidateStruct(v interface{}) ValidationErrors {
+	value, err := structValue(v)
+	if err != nil {
+		panic(err)
+	}
+
+	var errs ValidationErrors
+	validateStruct(value, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateStructTags checks that the validate tags of v, a struct or pointer
+// to one, and of its nested structs are well formed
+func ValidateStructTags(v interface{}) error {
+	value, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	_, err = compileStruct(value.Type())
+	return err
+}
+
+// structValue dereferences v to a struct
+func structValue(v interface{}) (reflect.Value, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return reflect.Value{}, fmt.Errorf("utils: cannot validate nil %s", value.Type())
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("utils: cannot validate %s, want a struct", value.Type())
+	}
+	return value, nil
+}
+
+// validationRule is one parsed rule of a validate tag
+type validationRule struct {
+	name  string
+	param string
+	// number is param parsed, for rules comparing numbers or lengths
+	number float64
+	// options are the values allowed by oneof
+	options []string
+}
+
+// fieldValidation holds the rules of a struct field
+type fieldValidation struct {
+	index     int
+	name      string
+	required  bool
+	omitempty bool
+	// indirect is set for pointer and Optional fields, which are empty only
+	// when they hold no value
+	indirect bool
+	rules    []validationRule
+	// nested is set for struct fields whose own fields are validated
+	nested bool
+	// promoted is set for embedded structs whose fields keep their names
+	promoted bool
+}
+
+var (
+	compiledMu sync.Mutex
+	compiled   = make(map[reflect.Type][]fieldValidation)
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	uuidType     = reflect.TypeOf(uuid.UUID{})
+	optionalType = reflect.TypeOf((*OptionalValue)(nil)).Elem()
+)
+
+// compileStruct parses the validate tags of a struct type once and caches them
+func compileStruct(t reflect.Type) ([]fieldValidation, error) {
+	compiledMu.Lock()
+	fields, ok := compiled[t]
+	compiledMu.Unlock()
+	if ok {
+		return fields, nil
+	}
+	return compileStructVisiting(t, make(map[reflect.Type]bool))
+}
+
+func compileStructVisiting(t reflect.Type, visiting map[reflect.Type]bool) ([]fieldValidation, error) {
+	compiledMu.Lock()
+	fields, ok := compiled[t]
+	compiledMu.Unlock()
+	if ok || visiting[t] {
+		return fields, nil
+	}
+	visiting[t] = true
+
+	fields = []fieldValidation{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		field, err := compileField(f)
+		if err != nil {
+			return nil, fmt.Errorf("utils: invalid validate tag on %s.%s: %w", t.Name(), f.Name, err)
+		}
+		field.index = i
+
+		if nested := nestedStruct(f.Type); nested != nil {
+			field.nested = true
+			field.promoted = f.Anonymous && jsonFieldName(f) == f.Name
+			if _, err := compileStructVisiting(nested, visiting); err != nil {
+				return nil, err
+			}
+		}
+		if len(field.rules) > 0 || field.required || field.nested {
+			fields = append(fields, field)
+		}
+	}
+
+	compiledMu.Lock()
+	compiled[t] = fields
+	compiledMu.Unlock()
+	return fields, nil
+}
+
+// compileField parses the validate tag of a field
+func compileField(f reflect.StructField) (fieldValidation, error) {
+	field := fieldValidation{name: jsonFieldName(f), indirect: valueType(f.Type) != f.Type}
+	tag := f.Tag.Get("validate")
+	if tag == "" {
+		return field, nil
+	}
+
+	target := valueType(f.Type)
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		rule := validationRule{name: name, param: param}
+		switch name {
+		case "required":
+			field.required = true
+			continue
+		case "omitempty":
+			field.omitempty = true
+			continue
+		case "email", "uuid":
+			if target.Kind() != reflect.String && !(name == "uuid" && target == uuidType) {
+				return field, fmt.Errorf("%s requires a string, not %s", name, target)
+			}
+		case "len", "min", "max", "gt", "gte", "lt", "lte":
+			number, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return field, fmt.Errorf("%s needs a numeric parameter, got %q", name, param)
+			}
+			if !isNumber(target) && !hasLength(target) {
+				return field, fmt.Errorf("%s does not apply to %s", name, target)
+			}
+			rule.number = number
+		case "oneof":
+			rule.options = strings.Fields(param)
+			if len(rule.options) == 0 {
+				return field, fmt.Errorf("oneof needs at least one value")
+			}
+			if target.Kind() != reflect.String && !isNumber(target) {
+				return field, fmt.Errorf("oneof does not apply to %s", target)
+			}
+		default:
+			return field, fmt.Errorf("unknown rule %q", name)
+		}
+		field.rules = append(field.rules, rule)
+	}
+	return field, nil
+}
+
+// validateStruct checks the fields of a struct value, naming them under prefix
+func validateStruct(value reflect.Value, prefix string, errs *ValidationErrors) {
+	fields, err := compileStruct(value.Type())
+	if err != nil {
+		panic(err)
+	}
+
+	for _, field := range fields {
+		name := field.name
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fieldValue, present := unwrap(value.Field(field.index))
+		if field.required && (!present || isZeroValue(fieldValue)) {
+			errs.Add(name, fmt.Sprintf("%s is required", name))
+			continue
+		}
+		if !present || (field.omitempty && !field.indirect && isZeroValue(fieldValue)) {
+			continue
+		}
+
+		for _, rule := range field.rules {
+			if message := checkRule(rule, fieldValue, name); message != "" {
+				errs.Add(name, message)
+			}
+		}
+
+		if field.nested {
+			if field.promoted {
+				name = prefix
+			}
+			validateNested(fieldValue, name, errs)
+		}
+	}
+}
+
+// validateNested validates a struct or a slice of structs
+func validateNested(value reflect.Value, name string, errs *ValidationErrors) {
+	switch value.Kind() {
+	case reflect.Struct:
+		validateStruct(value, name, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if element, ok := unwrap(value.Index(i)); ok {
+				validateStruct(element, fmt.Sprintf("%s[%d]", name, i), errs)
+			}
+		}
+	}
+}
+
+// checkRule returns the failure message of rule for value, or ""
+func checkRule(rule validationRule, value reflect.Value, name string) string {
+	switch rule.name {
+	case "email":
+		if ValidateEmail(value.String()) != nil {
+			return fmt.Sprintf("%s must be a valid email address", name)
+		}
+	case "uuid":
+		if value.Type() == uuidType {
+			return ""
+		}
+		var invalid *ValidationError
+		if errors.As(ValidateUUID(value.String(), name), &invalid) {
+			return invalid.Message
+		}
+	case "oneof":
+		actual := fmt.Sprint(value.Interface())
+		for _, option := range rule.options {
+			if actual == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%s must be one of: %s", name, strings.Join(rule.options, ", "))
+	default:
+		return checkBound(rule, value, name)
+	}
+	return ""
+}
+
+// checkBound checks the numeric and length rules
+func checkBound(rule validationRule, value reflect.Value, name string) string {
+	if hasLength(value.Type()) {
+		length := float64(value.Len())
+		if value.Kind() == reflect.String {
+			length = float64(utf8.RuneCountInString(value.String()))
+		}
+		if !compare(rule.name, length, rule.number) {
+			return fmt.Sprintf("%s must be %s %s characters long", name, lengthPhrase[rule.name], rule.param)
+		}
+		return ""
+	}
+	if !compare(rule.name, numberOf(value), rule.number) {
+		return fmt.Sprintf("%s must be %s %s", name, numberPhrase[rule.name], rule.param)
+	}
+	return ""
+}
+
+var lengthPhrase = map[string]string{
+	"len": "exactly", "min": "at least", "max": "no more than",
+	"gt": "more than", "gte": "at least", "lt": "fewer than", "lte": "no more than",
+}
+
+var numberPhrase = map[string]string{
+	"len": "equal to", "min": "at least", "max": "at most",
+	"gt": "greater than", "gte": "greater than or equal to", "lt": "less than", "lte": "less than or equal to",
+}
+
+// compare applies a bound rule to actual
+func compare(rule string, actual, bound float64) bool {
+	switch rule {
+	case "len":
+		return actual == bound
+	case "min", "gte":
+		return actual >= bound
+	case "max", "lte":
+		return actual <= bound
+	case "gt":
+		return actual > bound
+	case "lt":
+		return actual < bound
+	}
+	return false
+}
+
+// unwrap dereferences pointers and Optionals; present is false when there
+// is no value
+func unwrap(value reflect.Value) (reflect.Value, bool) {
+	for {
+		if value.Kind() == reflect.Struct && value.Type().Implements(optionalType) {
+			optional := value.Interface().(OptionalValue)
+			if !optional.IsSet() || optional.IsNull() {
+				return reflect.Value{}, false
+			}
+			value = reflect.ValueOf(optional.Interface())
+			continue
+		}
+		if value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+			continue
+		}
+		return value, true
+	}
+}
+
+// isZeroValue returns true for values that do not satisfy required: zero
+// values, blank strings and the zero time
+func isZeroValue(value reflect.Value) bool {
+	switch {
+	case value.Kind() == reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case value.Type() == timeType:
+		return value.Interface().(time.Time).IsZero()
+	case value.Kind() == reflect.Slice || value.Kind() == reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// valueType returns the type rules apply to, looking through pointers and
+// Optionals
+func valueType(t reflect.Type) reflect.Type {
+	for {
+		if t.Kind() == reflect.Struct && t.Implements(optionalType) {
+			if value, ok := t.FieldByName("Value"); ok {
+				t = value.Type
+				continue
+			}
+		}
+		if t.Kind() != reflect.Pointer {
+			return t
+		}
+		t = t.Elem()
+	}
+}
+
+// nestedStruct returns the struct type whose fields are validated for a
+// field of type t, or nil
+func nestedStruct(t reflect.Type) reflect.Type {
+	t = valueType(t)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = valueType(t.Elem())
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func hasLength(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// numberOf returns a numeric value as a float64
+func numberOf(value reflect.Value) float64 {
+	switch {
+	case value.CanInt():
+		return float64(value.Int())
+	case value.CanUint():
+		return float64(value.Uint())
+	}
+	return value.Float()
+}
+
+// jsonFieldName returns the JSON name of a field, or its Go name
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
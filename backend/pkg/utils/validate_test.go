@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type validatedItem struct {
+	Name  string   `json:"name" validate:"required,max=5"`
+	Share *float64 `json:"share,omitempty" validate:"omitempty,gt=0,lte=100"`
+}
+
+type ValidatedBase struct {
+	Reference string `json:"reference" validate:"omitempty,uuid"`
+}
+
+type validatedRequest struct {
+	ValidatedBase
+	Email    string            `json:"email" validate:"required,email"`
+	Password string            `json:"password" validate:"required,min=8"`
+	Currency *string           `json:"currency,omitempty" validate:"omitempty,len=3"`
+	Kind     string            `json:"kind" validate:"required,oneof=a b"`
+	Amount   float64           `json:"amount" validate:"required,gt=0"`
+	Priority int               `json:"priority" validate:"gte=0,lt=10"`
+	Count    int               `json:"count,omitempty" validate:"omitempty,lte=3"`
+	OwnerID  uuid.UUID         `json:"owner_id" validate:"required"`
+	Date     time.Time         `json:"date" validate:"required"`
+	Ratio    Optional[float64] `json:"ratio" validate:"omitempty,gte=0,lte=1"`
+	Note     Optional[string]  `json:"note" validate:"required"`
+	Tags     []string          `json:"tags" validate:"omitempty,max=2"`
+	Items    []validatedItem   `json:"items,omitempty"`
+	Primary  *validatedItem    `json:"primary,omitempty"`
+	Ignored  string            `json:"-" validate:"omitempty,min=2"`
+	internal string
+}
+
+func validRequest() validatedRequest {
+	share := 50.0
+	return validatedRequest{
+		ValidatedBase: ValidatedBase{Reference: uuid.NewString()},
+		Email:         "user@example.com",
+		Password:      "longenough",
+		Kind:          "a",
+		Amount:        12.5,
+		OwnerID:       uuid.New(),
+		Date:          time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		Ratio:         Some(0.5),
+		Note:          Some("hello"),
+		Items:         []validatedItem{{Name: "rent", Share: &share}},
+	}
+}
+
+// failedFields returns the fields named in errs
+func failedFields(errs ValidationErrors) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestValidateStruct(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name   string
+		modify func(r *validatedRequest)
+		fields []string
+	}{
+		{"valid", func(r *validatedRequest) {}, nil},
+		{"pointer to valid", nil, nil},
+		{"blank required string", func(r *validatedRequest) { r.Email = "  " }, []string{"email"}},
+		{"invalid email", func(r *validatedRequest) { r.Email = "not-an-email" }, []string{"email"}},
+		{"short password", func(r *validatedRequest) { r.Password = "short" }, []string{"password"}},
+		{"min counts characters", func(r *validatedRequest) { r.Password = "éééééééé" }, nil},
+		{"currency length", func(r *validatedRequest) { r.Currency = str("EURO") }, []string{"currency"}},
+		{"nil pointer skipped", func(r *validatedRequest) { r.Currency = nil }, nil},
+		{"explicit empty pointer checked", func(r *validatedRequest) { r.Currency = str("") }, []string{"currency"}},
+		{"oneof", func(r *validatedRequest) { r.Kind = "c" }, []string{"kind"}},
+		{"zero required number", func(r *validatedRequest) { r.Amount = 0 }, []string{"amount"}},
+		{"negative gt", func(r *validatedRequest) { r.Amount = -1 }, []string{"amount"}},
+		{"zero without omitempty still checked", func(r *validatedRequest) { r.Priority = -1 }, []string{"priority"}},
+		{"lt", func(r *validatedRequest) { r.Priority = 10 }, []string{"priority"}},
+		{"omitempty zero skipped", func(r *validatedRequest) { r.Count = 0 }, nil},
+		{"omitempty set checked", func(r *validatedRequest) { r.Count = 4 }, []string{"count"}},
+		{"nil uuid", func(r *validatedRequest) { r.OwnerID = uuid.Nil }, []string{"owner_id"}},
+		{"zero time", func(r *validatedRequest) { r.Date = time.Time{} }, []string{"date"}},
+		{"optional out of range", func(r *validatedRequest) { r.Ratio = Some(1.5) }, []string{"ratio"}},
+		{"optional null skipped", func(r *validatedRequest) { r.Ratio = Null[float64]() }, nil},
+		{"required optional absent", func(r *validatedRequest) { r.Note = Optional[string]{} }, []string{"note"}},
+		{"required optional null", func(r *validatedRequest) { r.Note = Null[string]() }, []string{"note"}},
+		{"slice length", func(r *validatedRequest) { r.Tags = []string{"a", "b", "c"} }, []string{"tags"}},
+		{"nested slice", func(r *validatedRequest) { r.Items = append(r.Items, validatedItem{Share: num(0)}) }, []string{"items[1].name", "items[1].share"}},
+		{"nested pointer", func(r *validatedRequest) { r.Primary = &validatedItem{Name: "toolong"} }, []string{"primary.name"}},
+		{"embedded fields keep their names", func(r *validatedRequest) { r.Reference = "nope" }, []string{"reference"}},
+		{"nil uuid string", func(r *validatedRequest) { r.Reference = uuid.Nil.String() }, []string{"reference"}},
+		{"v7 uuid string", func(r *validatedRequest) { r.Reference = uuid.Must(uuid.NewV7()).String() }, nil},
+		{"json dash uses the Go name", func(r *validatedRequest) { r.Ignored = "x" }, []string{"Ignored"}},
+		{"every failure reported", func(r *validatedRequest) {
+			*r = validatedRequest{}
+		}, []string{"email", "password", "kind", "amount", "owner_id", "date", "note"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := validRequest()
+			var errs ValidationErrors
+			if tt.modify == nil {
+				errs = ValidateStruct(&r)
+			} else {
+				tt.modify(&r)
+				errs = ValidateStruct(r)
+			}
+			if got, want := strings.Join(failedFields(errs), ","), strings.Join(tt.fields, ","); got != want {
+				t.Errorf("failed fields = %q, want %q (%v)", got, want, errs)
+			}
+			if len(tt.fields) == 0 && errs != nil {
+				t.Error("valid input should return nil")
+			}
+		})
+	}
+}
+
+func TestValidateStructMessages(t *testing.T) {
+	r := validRequest()
+	r.Email = ""
+	r.Password = "short"
+	r.Kind = "c"
+	r.Amount = -1
+	errs := ValidateStruct(r)
+	want := []string{
+		"email: email is required",
+		"password: password must be at least 8 characters long",
+		"kind: kind must be one of: a, b",
+		"amount: amount must be greater than 0",
+	}
+	if errs.Error() != strings.Join(want, "; ") {
+		t.Errorf("messages = %s", errs.Error())
+	}
+}
+
+func TestValidateStructTagsRejectsProgrammingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"unknown rule", struct {
+			Name string `validate:"required,alpha"`
+		}{}},
+		{"missing parameter", struct {
+			Name string `validate:"min"`
+		}{}},
+		{"non-numeric parameter", struct {
+			Amount float64 `validate:"gt=zero"`
+		}{}},
+		{"bound on a time", struct {
+			At time.Time `validate:"gt=0"`
+		}{}},
+		{"email on a number", struct {
+			Amount int `validate:"email"`
+		}{}},
+		{"empty oneof", struct {
+			Kind string `validate:"oneof="`
+		}{}},
+		{"nested struct", struct {
+			Item struct {
+				Name string `validate:"requird"`
+			}
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateStructTags(tt.v); err == nil {
+				t.Fatal("ValidateStructTags should fail")
+			}
+			defer func() {
+				if recover() == nil {
+					t.Error("ValidateStruct should panic")
+				}
+			}()
+			ValidateStruct(tt.v)
+		})
+	}
+
+	if err := ValidateStructTags(validRequest()); err != nil {
+		t.Errorf("valid tags rejected: %v", err)
+	}
+	if err := ValidateStructTags("not a struct"); err == nil {
+		t.Error("non-struct should fail")
+	}
+}
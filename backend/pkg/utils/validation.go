@@ -1,13 +1,27 @@
 package utils
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
 )
 
+// ErrValidation matches any ValidationError or ValidationErrors with
+// errors.Is, so callers can tell invalid input from other failures
+var ErrValidation = errors.New("validation failed")
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -18,7 +32,13 @@ func (e ValidationError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Field, e.Message)
 }
 
-// ValidationErrors represents multiple validation errors
+// Is reports whether target is ErrValidation
+func (e ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ValidationErrors represents multiple validation errors. It marshals to
+// JSON as an object of field names to their messages.
 type ValidationErrors []ValidationError
 
 func (e ValidationErrors) Error() string {
@@ -43,6 +63,86 @@ func (e *ValidationErrors) Add(field, message string) {
 	*e = append(*e, ValidationError{Field: field, Message: message})
 }
 
+// AddError adds err, such as the result of ValidateDateRange. Validation
+// errors keep their fields and other errors are added without a field.
+// A nil err is ignored.
+func (e *ValidationErrors) AddError(err error) {
+	var many ValidationErrors
+	var one *ValidationError
+	var value ValidationError
+	switch {
+	case err == nil:
+	case errors.As(err, &many):
+		e.Merge(many)
+	case errors.As(err, &one):
+		*e = append(*e, *one)
+	case errors.As(err, &value):
+		*e = append(*e, value)
+	default:
+		e.Add("", err.Error())
+	}
+}
+
+// Merge adds the errors of other after the existing ones
+func (e *ValidationErrors) Merge(other ValidationErrors) {
+	*e = append(*e, other...)
+}
+
+// Is reports whether target is ErrValidation. An empty list is not an error
+// and matches nothing.
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrValidation && len(e) > 0
+}
+
+// Fields returns the names of the fields with errors, each once, in the
+// order they were first added
+func (e ValidationErrors) Fields() []string {
+	var fields []string
+	seen := make(map[string]bool, len(e))
+	for _, err := range e {
+		if !seen[err.Field] {
+			seen[err.Field] = true
+			fields = append(fields, err.Field)
+		}
+	}
+	return fields
+}
+
+// ToMap returns the messages of each field in the order they were added
+func (e ValidationErrors) ToMap() map[string][]string {
+	messages := make(map[string][]string, len(e))
+	for _, err := range e {
+		messages[err.Field] = append(messages[err.Field], err.Message)
+	}
+	return messages
+}
+
+// MarshalJSON encodes the errors as {"field": ["message", ...]}, with
+// fields in the order they were first added
+func (e ValidationErrors) MarshalJSON() ([]byte, error) {
+	messages := e.ToMap()
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range e.Fields() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(messages[field])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
 // Email validation regex pattern
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
@@ -116,6 +216,23 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
+// PasswordChecker rejects passwords that pass ValidatePassword but are still
+// unsafe, such as common or breached passwords
+type PasswordChecker interface {
+	Validate(ctx context.Context, password string) error
+}
+
+// ValidatePasswordWith validates password strength and then the checker
+func ValidatePasswordWith(ctx context.Context, password string, checker PasswordChecker) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+	if err := checker.Validate(ctx, password); err != nil {
+		return &ValidationError{Field: "password", Message: err.Error()}
+	}
+	return nil
+}
+
 // ValidateRequired validates that a field is not empty
 func ValidateRequired(value, fieldName string) error {
 	if strings.TrimSpace(value) == "" {
@@ -126,7 +243,7 @@ func ValidateRequired(value, fieldName string) error {
 
 // ValidateLength validates string length
 func ValidateLength(value, fieldName string, min, max int) error {
-	length := len(strings.TrimSpace(value))
+	length := utf8.RuneCountInString(strings.TrimSpace(value))
 
 	if min > 0 && length < min {
 		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be at least %d characters long", fieldName, min)}
@@ -139,7 +256,14 @@ func ValidateLength(value, fieldName string, min, max int) error {
 	return nil
 }
 
-// ValidateName validates name format (letters, spaces, hyphens, apostrophes)
+// nameRegex allows letters of any script with their combining marks, spaces,
+// hyphens, apostrophes, and periods for suffixes such as "Jr."
+var nameRegex = regexp.MustCompile(`^[\p{L}\p{M}\s\-'’.]+$`)
+
+// nameLetterRegex requires at least one letter so punctuation alone is not a name
+var nameLetterRegex = regexp.MustCompile(`\p{L}`)
+
+// ValidateName validates name format (letters, spaces, hyphens, apostrophes, periods)
 func ValidateName(name, fieldName string) error {
 	if err := ValidateRequired(name, fieldName); err != nil {
 		return err
@@ -149,17 +273,17 @@ func ValidateName(name, fieldName string) error {
 		return err
 	}
 
-	// Allow letters, spaces, hyphens, and apostrophes
-	nameRegex := regexp.MustCompile(`^[a-zA-Z\s\-']+$`)
-	if !nameRegex.MatchString(name) {
-		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s can only contain letters, spaces, hyphens, and apostrophes", fieldName)}
+	if !nameRegex.MatchString(name) || !nameLetterRegex.MatchString(name) {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s can only contain letters, spaces, hyphens, apostrophes, and periods", fieldName)}
 	}
 
 	return nil
 }
 
-// ValidatePhone validates phone number format
-func ValidatePhone(phone string) error {
+// ValidatePhone validates phone number format. Numbers of one repeated digit
+// are rejected, and with CheckCountryCode so are international numbers
+// without a known country code or of the wrong length for it.
+func ValidatePhone(phone string, opts ...PhoneOption) error {
 	if phone == "" {
 		return &ValidationError{Field: "phone", Message: "phone number is required"}
 	}
@@ -171,6 +295,14 @@ func ValidatePhone(phone string) error {
 		return &ValidationError{Field: "phone", Message: "phone number must be between 10 and 15 digits"}
 	}
 
+	for _, opt := range opts {
+		if opt == CheckCountryCode && strings.HasPrefix(strings.TrimSpace(phone), "+") {
+			return validateE164Digits(digits)
+		}
+	}
+	if repeatedDigit(digits) {
+		return &ValidationError{Field: "phone", Message: "phone number is not a real number"}
+	}
 	return nil
 }
 
@@ -184,6 +316,28 @@ func ValidateAmount(amount float64, fieldName string) error {
 		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s is too large (max 999,999,999.99)", fieldName)}
 	}
 
+	return ValidateAmountPrecision(amount, fieldName, DefaultAmountDecimals)
+}
+
+// DefaultAmountDecimals is the number of decimal places amounts are stored with
+const DefaultAmountDecimals = 2
+
+// ValidateAmountPrecision validates that amount has at most maxDecimals
+// decimal places, or DefaultAmountDecimals when maxDecimals is negative.
+// Amounts are checked in their shortest decimal form, so 10.24 passes while
+// 10.239999999 and float artifacts such as 0.1+0.2 do not.
+func ValidateAmountPrecision(amount float64, fieldName string, maxDecimals int) error {
+	if maxDecimals < 0 {
+		maxDecimals = DefaultAmountDecimals
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be a finite number", fieldName)}
+	}
+
+	_, fraction, _ := strings.Cut(strconv.FormatFloat(amount, 'f', -1, 64), ".")
+	if len(fraction) > maxDecimals {
+		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must have at most %d decimal places", fieldName, maxDecimals)}
+	}
 	return nil
 }
 
@@ -207,18 +361,72 @@ func ValidateDate(date, fieldName string) error {
 	return nil
 }
 
-// ValidateUUID validates UUID format
-func ValidateUUID(uuid, fieldName string) error {
-	if err := ValidateRequired(uuid, fieldName); err != nil {
-		return err
+// ValidateUUID validates that id is a UUID in its canonical hyphenated form,
+// in either case, other than the nil UUID. Any version is accepted unless
+// versions are given, in which case it must be one of them.
+func ValidateUUID(id, fieldName string, versions ...uuid.Version) error {
+	_, err := parseUUID(id, fieldName, versions)
+	return err
+}
+
+// ValidateUUIDSlice validates and parses a non-empty list of IDs for bulk
+// endpoints with ValidateUUID. The error is ValidationErrors naming each
+// invalid or repeated ID by its position, such as ids[2].
+func ValidateUUIDSlice(ids []string, fieldName string, versions ...uuid.Version) ([]uuid.UUID, error) {
+	var errs ValidationErrors
+	if len(ids) == 0 {
+		errs.Add(fieldName, fmt.Sprintf("%s must not be empty", fieldName))
+		return nil, errs
+	}
+
+	parsed := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for i, id := range ids {
+		field := fmt.Sprintf("%s[%d]", fieldName, i)
+		value, err := parseUUID(id, field, versions)
+		switch {
+		case err != nil:
+			errs.AddError(err)
+		case seen[value]:
+			errs.Add(field, fmt.Sprintf("%s is a duplicate", field))
+		default:
+			seen[value] = true
+			parsed = append(parsed, value)
+		}
 	}
+	if errs.HasErrors() {
+		return nil, errs
+	}
+	return parsed, nil
+}
 
-	uuidRegex := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[1-5][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
-	if !uuidRegex.MatchString(strings.ToLower(uuid)) {
-		return &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be a valid UUID", fieldName)}
+// parseUUID parses a UUID for ValidateUUID and ValidateUUIDSlice
+func parseUUID(id, fieldName string, versions []uuid.Version) (uuid.UUID, error) {
+	if err := ValidateRequired(id, fieldName); err != nil {
+		return uuid.Nil, err
 	}
 
-	return nil
+	// uuid.Parse also accepts braces, a urn:uuid: prefix and no hyphens
+	value, err := uuid.Parse(id)
+	if err != nil || len(id) != 36 {
+		return uuid.Nil, &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be a valid UUID", fieldName)}
+	}
+	if value == uuid.Nil {
+		return uuid.Nil, &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must not be the nil UUID", fieldName)}
+	}
+	if len(versions) > 0 && !slices.Contains(versions, value.Version()) {
+		return uuid.Nil, &ValidationError{Field: fieldName, Message: fmt.Sprintf("%s must be a version %s UUID", fieldName, joinVersions(versions))}
+	}
+	return value, nil
+}
+
+// joinVersions lists UUID versions as "4" or "4 or 7"
+func joinVersions(versions []uuid.Version) string {
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(names, " or ")
 }
 
 // ValidatePagination validates pagination parameters
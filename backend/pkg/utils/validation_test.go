@@ -1,6 +1,13 @@
 package utils
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -58,6 +65,31 @@ func TestValidatePassword(t *testing.T) {
 	}
 }
 
+type denyChecker map[string]bool
+
+func (d denyChecker) Validate(ctx context.Context, password string) error {
+	if d[password] {
+		return errors.New("password is too common")
+	}
+	return nil
+}
+
+func TestValidatePasswordWith(t *testing.T) {
+	checker := denyChecker{"Password123!": true}
+
+	err := ValidatePasswordWith(context.Background(), "Password123!", checker)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "password" || validationErr.Message != "password is too common" {
+		t.Errorf("Expected password validation error, got %v", err)
+	}
+	if err := ValidatePasswordWith(context.Background(), "weak", checker); err == nil {
+		t.Error("Expected strength rules to still apply")
+	}
+	if err := ValidatePasswordWith(context.Background(), "StrongPass123!", checker); err != nil {
+		t.Errorf("Expected accepted password, got %v", err)
+	}
+}
+
 func TestValidateRequired(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -97,6 +129,11 @@ func TestValidateLength(t *testing.T) {
 		{"too long", "abcdefghijk", "field", 2, 10, true},
 		{"empty with min 0", "", "field", 0, 10, false},
 		{"whitespace with min 0", "   ", "field", 0, 10, false},
+		{"accented counted as characters", "Müllerstraße", "field", 2, 12, false},
+		{"CJK counted as characters", "山田太郎", "field", 2, 4, false},
+		{"CJK too long", "山田太郎子", "field", 2, 4, true},
+		{"CJK too short", "山", "field", 2, 4, true},
+		{"50 Devanagari characters under 100", strings.Repeat("नमस्", 12) + "नम", "field", 2, 100, false},
 	}
 
 	for _, tt := range tests {
@@ -124,6 +161,21 @@ func TestValidateName(t *testing.T) {
 		{"contains numbers", "John123", "name", true},
 		{"contains special chars", "John@Doe", "name", true},
 		{"empty", "", "name", true},
+		{"accented", "José", "name", false},
+		{"umlaut", "Müller", "name", false},
+		{"Polish", "Łukasz", "name", false},
+		{"combining accent", "Jose\u0301", "name", false},
+		{"typographic apostrophe", "D’Angelo", "name", false},
+		{"suffix with period", "Martin Luther King Jr.", "name", false},
+		{"CJK", "山田太郎", "name", false},
+		{"Devanagari", "अनुराग", "name", false},
+		{"Devanagari at 50 characters", strings.Repeat("नमस्", 12) + "नम", "name", false},
+		{"only punctuation", "'.-", "name", true},
+		{"only whitespace", "   ", "name", true},
+		{"punctuation and spaces", "- . '", "name", true},
+		{"emoji", "Ana 😀", "name", true},
+		{"only emoji", "😀😀", "name", true},
+		{"too long in characters", strings.Repeat("é", 101), "name", true},
 	}
 
 	for _, tt := range tests {
@@ -149,6 +201,7 @@ func TestValidatePhone(t *testing.T) {
 		{"too short", "123456789", true},
 		{"too long", "1234567890123456", true},
 		{"non-numeric", "abcdefghij", true},
+		{"repeated digit", "000-000-0000", true},
 	}
 
 	for _, tt := range tests {
@@ -161,7 +214,35 @@ func TestValidatePhone(t *testing.T) {
 	}
 }
 
+func TestValidatePhoneCheckCountryCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		phone   string
+		wantErr bool
+	}{
+		{"US", "+1 415 555 2671", false},
+		{"UK", "+44 20 7183 8750", false},
+		{"India", "+91 98765 43210", false},
+		{"three digit code", "+353 1 234 5678", false},
+		{"unknown country code", "+999 1234 5678", true},
+		{"unassigned two digit code", "+28 1234 5678", true},
+		{"wrong length for code", "+1 415 555 26711", true},
+		{"repeated national digits", "+1 555 555 5555", true},
+		{"national numbers are not checked", "4155552671", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePhone(tt.phone, CheckCountryCode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePhone(%q, CheckCountryCode) error = %v, wantErr %v", tt.phone, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateAmount(t *testing.T) {
+	tenth := 0.1
 	tests := []struct {
 		name      string
 		amount    float64
@@ -174,6 +255,8 @@ func TestValidateAmount(t *testing.T) {
 		{"zero amount", 0, "amount", true},
 		{"negative amount", -100, "amount", true},
 		{"too large", 1000000000, "amount", true},
+		{"too many decimals", 10.239999999, "amount", true},
+		{"float artifact", tenth + 0.2, "amount", true},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +269,43 @@ func TestValidateAmount(t *testing.T) {
 	}
 }
 
+func TestValidateAmountPrecision(t *testing.T) {
+	// Constant expressions are exact, so the artifact needs a variable
+	tenth := 0.1
+	tests := []struct {
+		name        string
+		amount      float64
+		maxDecimals int
+		wantErr     bool
+	}{
+		{"whole", 10, 2, false},
+		{"one decimal", 10.5, 2, false},
+		{"two decimals", 10.24, 2, false},
+		{"three decimals", 10.239, 2, true},
+		{"long tail", 10.239999999, 2, true},
+		{"float artifact", tenth + 0.2, 2, true},
+		{"negative", -10.25, 2, false},
+		{"negative with three decimals", -10.255, 2, true},
+		{"zero decimals", 1234, 0, false},
+		{"zero decimals with fraction", 1234.5, 0, true},
+		{"three decimals allowed", 1.234, 3, false},
+		{"default for negative max", 10.24, -1, false},
+		{"default rejects three decimals", 10.239, -1, true},
+		{"large amount", 999999999.99, 2, false},
+		{"NaN", math.NaN(), 2, true},
+		{"infinity", math.Inf(1), 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAmountPrecision(tt.amount, "amount", tt.maxDecimals)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAmountPrecision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateDate(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -225,6 +345,14 @@ func TestValidateUUID(t *testing.T) {
 		{"invalid format", "550e8400-e29b-41d4-a716-44665544000", "id", true},
 		{"invalid format 2", "550e8400-e29b-41d4-a716-4466554400000", "id", true},
 		{"invalid characters", "550e8400-e29b-41d4-a716-44665544000g", "id", true},
+		{"version 7", "01890a5d-ac96-774b-bcce-b302099a8057", "id", false},
+		{"version 7 uppercase", "01890A5D-AC96-774B-BCCE-B302099A8057", "id", false},
+		{"version 1", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "id", false},
+		{"nil UUID", "00000000-0000-0000-0000-000000000000", "id", true},
+		{"braces", "{550e8400-e29b-41d4-a716-446655440000}", "id", true},
+		{"urn prefix", "urn:uuid:550e8400-e29b-41d4-a716-446655440000", "id", true},
+		{"no hyphens", "550e8400e29b41d4a716446655440000", "id", true},
+		{"whitespace", " 550e8400-e29b-41d4-a716-446655440000", "id", true},
 	}
 
 	for _, tt := range tests {
@@ -235,6 +363,56 @@ func TestValidateUUID(t *testing.T) {
 			}
 		})
 	}
+
+	err := ValidateUUID("00000000-0000-0000-0000-000000000000", "id")
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Message != "id must not be the nil UUID" {
+		t.Errorf("Expected a nil UUID error, got %v", err)
+	}
+}
+
+func TestValidateUUIDVersions(t *testing.T) {
+	v4 := "550e8400-e29b-41d4-a716-446655440000"
+	v7 := "01890a5d-ac96-774b-bcce-b302099a8057"
+
+	if err := ValidateUUID(v7, "id", 7); err != nil {
+		t.Errorf("Expected a v7 UUID to be valid, got %v", err)
+	}
+	if err := ValidateUUID(v4, "id", 7); err == nil || err.Error() != "id: id must be a version 7 UUID" {
+		t.Errorf("Expected a version error, got %v", err)
+	}
+	if err := ValidateUUID(v4, "id", 4, 7); err != nil {
+		t.Errorf("Expected either version to be valid, got %v", err)
+	}
+}
+
+func TestValidateUUIDSlice(t *testing.T) {
+	a := "550e8400-e29b-41d4-a716-446655440000"
+	b := "01890A5D-AC96-774B-BCCE-B302099A8057"
+
+	ids, err := ValidateUUIDSlice([]string{a, b}, "ids")
+	if err != nil || len(ids) != 2 || ids[0].String() != a || ids[1].String() != strings.ToLower(b) {
+		t.Errorf("ValidateUUIDSlice() = %v, %v", ids, err)
+	}
+
+	tests := []struct {
+		name   string
+		ids    []string
+		fields []string
+	}{
+		{"empty", nil, []string{"ids"}},
+		{"invalid and nil", []string{a, "nope", "00000000-0000-0000-0000-000000000000"}, []string{"ids[1]", "ids[2]"}},
+		{"duplicate in another case", []string{b, strings.ToLower(b)}, []string{"ids[1]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := ValidateUUIDSlice(tt.ids, "ids")
+			var errs ValidationErrors
+			if !errors.As(err, &errs) || !reflect.DeepEqual(errs.Fields(), tt.fields) || ids != nil {
+				t.Errorf("ValidateUUIDSlice(%q) = %v, %v, want failed fields %q", tt.ids, ids, err, tt.fields)
+			}
+		})
+	}
 }
 
 func TestValidatePagination(t *testing.T) {
@@ -313,4 +491,107 @@ func TestValidationErrors(t *testing.T) {
 	if errors.Error() != expected {
 		t.Errorf("ValidationErrors.Error() = %v, want %v", errors.Error(), expected)
 	}
+
+	// Repeated fields keep every message in Error and appear once in Fields
+	errors.Add("field1", "error3")
+	expected = "field1: error1; field2: error2; field1: error3"
+	if errors.Error() != expected {
+		t.Errorf("ValidationErrors.Error() = %v, want %v", errors.Error(), expected)
+	}
+	if fields := strings.Join(errors.Fields(), ","); fields != "field1,field2" {
+		t.Errorf("ValidationErrors.Fields() = %v, want field1,field2", fields)
+	}
+	if got := errors.ToMap()["field1"]; len(got) != 2 || got[0] != "error1" || got[1] != "error3" {
+		t.Errorf("ValidationErrors.ToMap()[field1] = %v", got)
+	}
+
+	var more ValidationErrors
+	more.Add("field3", "error4")
+	errors.Merge(more)
+	errors.Merge(nil)
+	if len(errors) != 4 || errors[3].Field != "field3" {
+		t.Errorf("ValidationErrors.Merge() = %v", errors)
+	}
+}
+
+func TestValidationErrorsMarshalJSON(t *testing.T) {
+	var errs ValidationErrors
+	errs.Add("password", "password is required")
+	errs.Add("email", "invalid email format")
+	errs.Add("password", "password must be at least 8 characters long")
+	errs.Add("splits[0].note", `note "x" is too short`)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"fields in first-added order", errs,
+			`{"password":["password is required","password must be at least 8 characters long"],"email":["invalid email format"],"splits[0].note":["note \"x\" is too short"]}`},
+		{"empty", ValidationErrors{}, `{}`},
+		{"nil", ValidationErrors(nil), `{}`},
+		{"nested", map[string]interface{}{"errors": ValidationErrors{{Field: "email", Message: "email is required"}}},
+			`{"errors":{"email":["email is required"]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+
+	// The output decodes as the same map ToMap returns
+	data, _ := json.Marshal(errs)
+	var decoded map[string][]string
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, errs.ToMap()) {
+		t.Errorf("decoded %v, want %v", decoded, errs.ToMap())
+	}
+}
+
+func TestValidationErrorsIsAndAs(t *testing.T) {
+	var errs ValidationErrors
+	errs.Add("amount", "amount must be greater than 0")
+	single := &ValidationError{Field: "email", Message: "email is required"}
+
+	tests := []struct {
+		name       string
+		err        error
+		validation bool
+	}{
+		{"errors", errs, true},
+		{"wrapped errors", fmt.Errorf("create expense: %w", errs), true},
+		{"pointer error", single, true},
+		{"value error", *single, true},
+		{"wrapped pointer error", fmt.Errorf("register: %w", ValidateEmail("")), true},
+		{"empty errors", ValidationErrors{}, false},
+		{"other error", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, ErrValidation); got != tt.validation {
+				t.Errorf("errors.Is(ErrValidation) = %v, want %v", got, tt.validation)
+			}
+		})
+	}
+
+	var many ValidationErrors
+	if !errors.As(fmt.Errorf("wrapped: %w", errs), &many) || len(many) != 1 || many[0].Field != "amount" {
+		t.Errorf("errors.As(ValidationErrors) = %v", many)
+	}
+	var one *ValidationError
+	if !errors.As(fmt.Errorf("wrapped: %w", single), &one) || one.Field != "email" {
+		t.Errorf("errors.As(*ValidationError) = %v", one)
+	}
+	var value ValidationError
+	if !errors.As(fmt.Errorf("wrapped: %w", *single), &value) || value.Field != "email" {
+		t.Errorf("errors.As(ValidationError) = %v", value)
+	}
 }